@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// AdminMiddleware restricts a route to users whose role is "admin"
+// It must run after AuthMiddleware, which places the user ID in the request context
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := r.Context().Value("userID").(string)
+		if !ok || userID == "" {
+			log.Warn("Missing user ID for admin route")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		sqlDB := db.NewQueries().GetDB()
+		if sqlDB == nil {
+			log.Error("Database connection unavailable, cannot verify admin role")
+			http.Error(w, "Database connection unavailable", http.StatusInternalServerError)
+			return
+		}
+
+		// Look up the role on every request so revoking admin access takes effect immediately
+		var role string
+		err := sqlDB.QueryRowContext(r.Context(),
+			`SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+		if err != nil && err != sql.ErrNoRows {
+			log.Error("Failed to look up user role", zap.String("user_id", userID), zap.Error(err))
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if role != "admin" {
+			log.Warn("Non-admin user attempted to access admin route",
+				zap.String("user_id", userID),
+				zap.String("path", r.URL.Path))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
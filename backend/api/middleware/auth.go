@@ -2,15 +2,23 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
 	"go.uber.org/zap"
 )
 
-// AuthMiddleware verifies JWT tokens from Google Sign-In
+// authenticateAPIKey resolves an API key to its owner; tests replace it to avoid a database
+var authenticateAPIKey = func(ctx context.Context, key string) (*services.APIKeyIdentity, error) {
+	return services.NewAPIKeyService(db.NewQueries()).Authenticate(ctx, key)
+}
+
+// AuthMiddleware verifies JWT tokens from Google Sign-In, sent as "Authorization: Bearer <token>",
+// and API keys, sent as "Authorization: ApiKey <key>"
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log := logger.FromContext(r.Context())
@@ -25,7 +33,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		// Extract token
 		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		if len(tokenParts) != 2 || (tokenParts[0] != "Bearer" && tokenParts[0] != "ApiKey") {
 			log.Warn("Invalid Authorization header format")
 			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
 			return
@@ -33,9 +41,33 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		token := tokenParts[1]
 
-		// Verify JWT token and extract user ID
+		// An API key acts as its owner, limited to the scopes it was issued with
+		if tokenParts[0] == "ApiKey" {
+			identity, err := authenticateAPIKey(r.Context(), token)
+			if errors.Is(err, services.ErrInvalidAPIKey) {
+				log.Warn("Invalid API key")
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				log.Error("Failed to authenticate API key", zap.Error(err))
+				http.Error(w, "Failed to authenticate API key", http.StatusInternalServerError)
+				return
+			}
+
+			log.Debug("User authenticated with API key",
+				zap.String("user_id", identity.UserID),
+				zap.String("api_key_id", identity.KeyID))
+			ctx := context.WithValue(r.Context(), "userID", identity.UserID)
+			ctx = context.WithValue(ctx, "userRole", identity.Role)
+			ctx = context.WithValue(ctx, "authScopes", identity.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		// Verify JWT token and extract the user ID and role
 		authService := services.GetAuthService()
-		userID, err := authService.VerifyJWT(token)
+		claims, err := authService.ParseJWT(token)
 		if err != nil {
 			log.Warn("Invalid JWT token", zap.Error(err))
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
@@ -43,8 +75,11 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Add user ID to request context
-		log.Debug("User authenticated", zap.String("user_id", userID))
-		ctx := context.WithValue(r.Context(), "userID", userID)
+		// A signed-in user session carries full scope; RequireScope only narrows API keys
+		log.Debug("User authenticated", zap.String("user_id", claims.UserID))
+		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
+		ctx = context.WithValue(ctx, "userRole", claims.Role)
+		ctx = context.WithValue(ctx, "authScopes", []string{services.ScopeAll})
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestAuthMiddlewareAcceptsGeneratedTokens(t *testing.T) {
+	tests := []struct {
+		name          string
+		signSecret    string
+		verifySecret  string
+		header        func(token string) string
+		wantStatus    int
+		wantUserInCtx bool
+	}{
+		{name: "configured secret", signSecret: "s3cret", verifySecret: "s3cret", header: bearer, wantStatus: http.StatusOK, wantUserInCtx: true},
+		{name: "development secret", header: bearer, wantStatus: http.StatusOK, wantUserInCtx: true},
+		{name: "secret changed since signing", signSecret: "old", verifySecret: "new", header: bearer, wantStatus: http.StatusUnauthorized},
+		{name: "missing header", header: func(string) string { return "" }, wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", header: func(token string) string { return "Token " + token }, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", tt.signSecret)
+			token, err := services.GetAuthService().GenerateJWT("user-1", "ada@example.com", services.RoleUser)
+			if err != nil {
+				t.Fatalf("GenerateJWT returned error: %v", err)
+			}
+			t.Setenv("JWT_SECRET", tt.verifySecret)
+
+			var userID any
+			handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID = r.Context().Value("userID")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/api/repositories", nil)
+			if header := tt.header(token); header != "" {
+				r.Header.Set("Authorization", header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantUserInCtx && userID != "user-1" {
+				t.Errorf("userID in context = %v, want user-1", userID)
+			}
+		})
+	}
+}
+
+// bearer formats a token as an Authorization header value
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+func TestAuthMiddlewareAcceptsAPIKeys(t *testing.T) {
+	// Stand in for the api_keys table with one key, issued for reading repositories
+	original := authenticateAPIKey
+	t.Cleanup(func() { authenticateAPIKey = original })
+	authenticateAPIKey = func(ctx context.Context, key string) (*services.APIKeyIdentity, error) {
+		switch key {
+		case "sast_1234abcd_valid":
+			return &services.APIKeyIdentity{KeyID: "key-1", UserID: "user-1", Role: services.RoleUser, Scopes: []string{services.ScopeRepoRead}}, nil
+		case "sast_1234abcd_broken":
+			return nil, errors.New("database unavailable")
+		}
+		return nil, services.ErrInvalidAPIKey
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		scope      string
+		wantStatus int
+	}{
+		{name: "valid key", header: "ApiKey sast_1234abcd_valid", scope: services.ScopeRepoRead, wantStatus: http.StatusOK},
+		{name: "valid key outside its scopes", header: "ApiKey sast_1234abcd_valid", scope: services.ScopeScanWrite, wantStatus: http.StatusForbidden},
+		{name: "unknown or revoked key", header: "ApiKey sast_1234abcd_revoked", scope: services.ScopeRepoRead, wantStatus: http.StatusUnauthorized},
+		{name: "lookup failure", header: "ApiKey sast_1234abcd_broken", scope: services.ScopeRepoRead, wantStatus: http.StatusInternalServerError},
+		{name: "key sent as a bearer token", header: "Bearer sast_1234abcd_valid", scope: services.ScopeRepoRead, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var userID, role any
+			handler := AuthMiddleware(RequireScope(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID = r.Context().Value("userID")
+				role = r.Context().Value("userRole")
+			})))
+			r := httptest.NewRequest(http.MethodGet, "/repositories", nil)
+			r.Header.Set("Authorization", tt.header)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && (userID != "user-1" || role != services.RoleUser) {
+				t.Errorf("context user = %v with role %v, want the key's owner", userID, role)
+			}
+		})
+	}
+}
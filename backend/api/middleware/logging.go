@@ -5,9 +5,11 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/metrics"
 	"go.uber.org/zap"
 )
 
@@ -33,7 +35,7 @@ func RequestLogger(next http.Handler) http.Handler {
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
-			zap.String("query", r.URL.RawQuery),
+			zap.String("query", logger.RedactQuery(r.URL.RawQuery)),
 			zap.String("user_agent", r.UserAgent()),
 		)
 
@@ -54,9 +56,22 @@ func RequestLogger(next http.Handler) http.Handler {
 		// Get content length
 		contentLength := ww.BytesWritten()
 
+		// Handlers that never call WriteHeader answer 200
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		// Record the request by its route pattern, which chi fills in while routing
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		metrics.ObserveRequest(r.Method, route, status, duration)
+
 		// Log request completion with additional metadata
 		log.Info("Request completed",
-			zap.Int("status", ww.Status()),
+			zap.Int("status", status),
 			zap.Int("content_length", contentLength),
 			zap.String("duration", duration.String()),
 			zap.Duration("duration_ms", duration),
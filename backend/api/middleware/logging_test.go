@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/metrics"
+)
+
+func TestRequestLoggerRecordsTheRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(RequestLogger)
+	router.Get("/api/repositories/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}")) // Never calls WriteHeader
+	})
+	router.Method(http.MethodGet, "/metrics", metrics.Handler())
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/repositories/3f2a", nil))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	// The pattern rather than the raw path keeps one series per route
+	want := `sast_http_requests_total{method="GET",route="/api/repositories/{id}",status="200"}`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("metrics output is missing %s", want)
+	}
+	if strings.Contains(string(body), "3f2a") {
+		t.Error("metrics output contains a raw request path")
+	}
+}
@@ -0,0 +1,358 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Rate limit defaults, used when the RATE_LIMIT_* settings are unset
+const (
+	DefaultPublicRequestsPerMinute = 30
+	DefaultPublicBurst             = 10
+	DefaultUserRequestsPerMinute   = 120
+	DefaultUserBurst               = 30
+)
+
+// rateLimitKeyPrefix namespaces the buckets kept in Redis
+const rateLimitKeyPrefix = "sast:ratelimit:"
+
+// RateLimit is a token bucket holding up to Burst requests, refilled at PerMinute requests a minute
+type RateLimit struct {
+	PerMinute int // 0 disables the limit
+	Burst     int
+}
+
+// Enabled reports whether requests are limited at all
+func (l RateLimit) Enabled() bool {
+	return l.PerMinute > 0
+}
+
+// interval returns how long the bucket takes to refill one token
+func (l RateLimit) interval() time.Duration {
+	return time.Minute / time.Duration(l.PerMinute)
+}
+
+// RateLimitConfig holds the limits of anonymous and signed-in clients
+type RateLimitConfig struct {
+	Public     RateLimit // Public routes, per client IP
+	User       RateLimit // Authenticated routes, per user ID
+	TrustProxy bool      // Take the client IP from X-Forwarded-For, set by a load balancer in front of the server
+}
+
+// RateLimitConfigFromEnv reads the limits from RATE_LIMIT_PUBLIC_PER_MINUTE, RATE_LIMIT_PUBLIC_BURST,
+// RATE_LIMIT_USER_PER_MINUTE, RATE_LIMIT_USER_BURST, and RATE_LIMIT_TRUST_PROXY
+// Unset values use the defaults; a per-minute value of 0 turns that limit off. Other invalid values are an error
+func RateLimitConfigFromEnv() (RateLimitConfig, error) {
+	var config RateLimitConfig
+	var err error
+
+	if config.Public, err = rateLimitFromEnv("RATE_LIMIT_PUBLIC", DefaultPublicRequestsPerMinute, DefaultPublicBurst); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if config.User, err = rateLimitFromEnv("RATE_LIMIT_USER", DefaultUserRequestsPerMinute, DefaultUserBurst); err != nil {
+		return RateLimitConfig{}, err
+	}
+
+	if value := strings.TrimSpace(os.Getenv("RATE_LIMIT_TRUST_PROXY")); value != "" {
+		if config.TrustProxy, err = strconv.ParseBool(value); err != nil {
+			return RateLimitConfig{}, fmt.Errorf("RATE_LIMIT_TRUST_PROXY must be true or false, got %q", value)
+		}
+	}
+
+	return config, nil
+}
+
+// rateLimitFromEnv reads prefix_PER_MINUTE and prefix_BURST
+func rateLimitFromEnv(prefix string, defaultPerMinute, defaultBurst int) (RateLimit, error) {
+	var limit RateLimit
+	var err error
+	if limit.PerMinute, err = nonNegativeIntFromEnv(prefix+"_PER_MINUTE", defaultPerMinute); err != nil {
+		return RateLimit{}, err
+	}
+	if limit.Burst, err = nonNegativeIntFromEnv(prefix+"_BURST", defaultBurst); err != nil {
+		return RateLimit{}, err
+	}
+	if limit.Enabled() && limit.Burst == 0 {
+		return RateLimit{}, fmt.Errorf("%s_BURST must be at least 1 while %s_PER_MINUTE is set", prefix, prefix)
+	}
+
+	return limit, nil
+}
+
+// nonNegativeIntFromEnv parses the named variable, returning def when it is unset
+func nonNegativeIntFromEnv(name string, def int) (int, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return def, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, value)
+	}
+	return parsed, nil
+}
+
+// RateLimitStore keeps the token buckets of every client
+type RateLimitStore interface {
+	// Take removes a token from key's bucket. When the bucket is empty the request is not allowed,
+	// and retryAfter is how long until the next token is added
+	Take(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// RateLimitStoreFromEnv returns a Redis store when REDIS_URL is set, so limits hold across replicas,
+// and an in-memory store for a single instance otherwise
+func RateLimitStoreFromEnv() (RateLimitStore, error) {
+	redisURL := strings.TrimSpace(os.Getenv("REDIS_URL"))
+	if redisURL == "" {
+		return NewMemoryRateLimitStore(), nil
+	}
+
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	return NewRedisRateLimitStore(redis.NewClient(options)), nil
+}
+
+// memoryBucket is one client's bucket in the in-memory store
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	refill  time.Duration // Time an empty bucket takes to fill up
+}
+
+// memoryRateLimitStore keeps buckets in process memory
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitStore creates a store whose limits apply to this instance only
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{
+			tokens:  float64(limit.Burst),
+			updated: now,
+			refill:  time.Duration(limit.Burst) * limit.interval(),
+		}
+		s.buckets[key] = bucket
+	}
+
+	// Refill for the time since the bucket was last used
+	elapsed := now.Sub(bucket.updated)
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+float64(elapsed)/float64(limit.interval()))
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - bucket.tokens) * float64(limit.interval())), nil
+}
+
+// sweep drops buckets idle long enough to have refilled, at most once a minute, so the map doesn't
+// grow with every client ever seen. A dropped bucket is recreated full, which is the state it was in
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > bucket.refill {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// redisTokenBucket refills and takes from a bucket atomically, using the server clock so replicas agree
+// It returns whether a token was taken and, if not, the milliseconds until the next one
+var redisTokenBucket = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) / interval)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * interval)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * interval) + 1000)
+return {allowed, wait}
+`)
+
+// redisRateLimitStore keeps buckets in Redis, shared by every replica
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+// NewRedisRateLimitStore creates a store whose limits hold across every instance using the same Redis
+func NewRedisRateLimitStore(client *redis.Client) RateLimitStore {
+	return &redisRateLimitStore{client: client}
+}
+
+func (s *redisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	intervalMs := float64(limit.interval()) / float64(time.Millisecond)
+	result, err := redisTokenBucket.Run(ctx, s.client, []string{rateLimitKeyPrefix + key}, intervalMs, limit.Burst).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(result) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// RateLimiter throttles clients with a token bucket each
+type RateLimiter struct {
+	store  RateLimitStore
+	config RateLimitConfig
+}
+
+// NewRateLimiter creates a rate limiter keeping its buckets in store
+func NewRateLimiter(store RateLimitStore, config RateLimitConfig) *RateLimiter {
+	return &RateLimiter{store: store, config: config}
+}
+
+// RateLimiterFromEnv creates a rate limiter from RateLimitConfigFromEnv and RateLimitStoreFromEnv
+func RateLimiterFromEnv() (*RateLimiter, error) {
+	config, err := RateLimitConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	store, err := RateLimitStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewRateLimiter(store, config), nil
+}
+
+// ByIP limits requests per client IP with the public limit
+// The client IP is placed in the request context as "clientIP", for handlers that key state by caller
+func (l *RateLimiter) ByIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r, l.config.TrustProxy)
+		if l.allow(w, r, "ip:"+ip, l.config.Public) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "clientIP", ip)))
+		}
+	})
+}
+
+// ByUser limits requests per user with the user limit, placing the client IP in the context like ByIP
+// It must run after AuthMiddleware, which places the user ID in the request context
+func (l *RateLimiter) ByUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r, l.config.TrustProxy)
+		key := "ip:" + ip
+		if userID, ok := r.Context().Value("userID").(string); ok && userID != "" {
+			key = "user:" + userID
+		}
+		if l.allow(w, r, key, l.config.User) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "clientIP", ip)))
+		}
+	})
+}
+
+// TakeByIP takes one more token from the client IP's public bucket without answering the request, for
+// handlers that do the work of several requests in one, such as a batch of scans. When no token is left it
+// returns false and how long until one is added
+func (l *RateLimiter) TakeByIP(r *http.Request) (bool, time.Duration) {
+	return l.take(r, "ip:"+clientIP(r, l.config.TrustProxy), l.config.Public)
+}
+
+// take removes a token from key's bucket, returning false and the wait for the next token when none is left
+// If the store fails the request is let through, so an unreachable Redis doesn't take the API down
+func (l *RateLimiter) take(r *http.Request, key string, limit RateLimit) (bool, time.Duration) {
+	if !limit.Enabled() {
+		return true, 0
+	}
+
+	allowed, retryAfter, err := l.store.Take(r.Context(), key, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Rate limit check failed, allowing request", zap.String("key", key), zap.Error(err))
+		return true, 0
+	}
+	return allowed, retryAfter
+}
+
+// allow takes a token for key, answering 429 with Retry-After when none is left
+func (l *RateLimiter) allow(w http.ResponseWriter, r *http.Request, key string, limit RateLimit) bool {
+	allowed, retryAfter := l.take(r, key, limit)
+	if allowed {
+		return true
+	}
+	log := logger.FromContext(r.Context())
+
+	// Retry-After is in whole seconds, rounded up so the client doesn't retry before a token is added
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	log.Warn("Rate limit exceeded",
+		zap.String("key", key),
+		zap.String("path", r.URL.Path),
+		zap.Int("retry_after_seconds", seconds))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+	return false
+}
+
+// clientIP returns the address of the client making the request
+// With trustProxy the right-most X-Forwarded-For entry is used: it was added by our own load balancer,
+// while entries further left come from the client and can be forged
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			entries := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestRateLimiter returns a limiter over an in-memory store whose clock the test advances
+func newTestRateLimiter(config RateLimitConfig) (*RateLimiter, *time.Time) {
+	now := time.Date(2026, 4, 2, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+	store.now = func() time.Time { return now }
+	return NewRateLimiter(store, config), &now
+}
+
+// serve sends one request from the given address, as the given user when one is set
+func serve(handler http.Handler, remoteAddr, userID string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/scan", nil)
+	r.RemoteAddr = remoteAddr
+	if userID != "" {
+		r = r.WithContext(context.WithValue(r.Context(), "userID", userID))
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRateLimiterByIP(t *testing.T) {
+	limiter, now := newTestRateLimiter(RateLimitConfig{Public: RateLimit{PerMinute: 6, Burst: 3}})
+	handler := limiter.ByIP(okHandler)
+
+	for i := 0; i < 3; i++ {
+		if rec := serve(handler, "203.0.113.7:4100", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200 within the burst", i+1, rec.Code)
+		}
+	}
+
+	rec := serve(handler, "203.0.113.7:4101", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 once the burst is used up", rec.Code)
+	}
+	// One token is added every 10 seconds
+	if got := rec.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q, want 10", got)
+	}
+
+	// Other clients have their own bucket
+	if rec := serve(handler, "198.51.100.2:4100", ""); rec.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", rec.Code)
+	}
+
+	*now = now.Add(10 * time.Second)
+	if rec := serve(handler, "203.0.113.7:4100", ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 once a token is added", rec.Code)
+	}
+	if rec := serve(handler, "203.0.113.7:4100", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429 after using the added token", rec.Code)
+	}
+}
+
+func TestRateLimiterTakeByIPSharesTheRequestBucket(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimitConfig{Public: RateLimit{PerMinute: 6, Burst: 3}})
+
+	// A batch request takes one token through ByIP and one more for each extra item
+	var taken int
+	handler := limiter.ByIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for {
+			allowed, retryAfter := limiter.TakeByIP(r)
+			if !allowed {
+				if retryAfter != 10*time.Second {
+					t.Errorf("retry after %s, want 10s", retryAfter)
+				}
+				return
+			}
+			taken++
+		}
+	}))
+
+	serve(handler, "203.0.113.7:4100", "")
+	if taken != 2 {
+		t.Errorf("took %d extra tokens, want the 2 left after the request's own", taken)
+	}
+	if rec := serve(handler, "203.0.113.7:4100", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429 once the batch used up the bucket", rec.Code)
+	}
+}
+
+func TestRateLimiterByUser(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimitConfig{User: RateLimit{PerMinute: 60, Burst: 2}})
+	handler := limiter.ByUser(okHandler)
+
+	// The user's bucket is shared by every address they connect from
+	serve(handler, "203.0.113.7:4100", "user-1")
+	serve(handler, "198.51.100.2:4100", "user-1")
+	rec := serve(handler, "192.0.2.9:4100", "user-1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 for the user's third request", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	if rec := serve(handler, "203.0.113.7:4100", "user-2"); rec.Code != http.StatusOK {
+		t.Errorf("other user on the same address: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimitConfig{})
+	handler := limiter.ByIP(okHandler)
+
+	for i := 0; i < 100; i++ {
+		if rec := serve(handler, "203.0.113.7:4100", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200 without a limit", i+1, rec.Code)
+		}
+	}
+}
+
+func TestRateLimiterPassesTheClientIPOn(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimitConfig{TrustProxy: true})
+	var got any
+	handler := limiter.ByIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Context().Value("clientIP")
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/scan", nil)
+	r.RemoteAddr = "10.0.0.5:51234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got != "203.0.113.7" {
+		t.Errorf("clientIP in context = %v, want the address the limit was keyed by", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/scan", nil)
+	r.RemoteAddr = "10.0.0.5:51234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.7")
+
+	if got := clientIP(r, false); got != "10.0.0.5" {
+		t.Errorf("clientIP without a trusted proxy = %q, want the remote address", got)
+	}
+	// The client controls every entry but the one our load balancer appended
+	if got := clientIP(r, true); got != "203.0.113.7" {
+		t.Errorf("clientIP behind a trusted proxy = %q, want the last forwarded address", got)
+	}
+}
+
+func TestRateLimitConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    RateLimitConfig
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: RateLimitConfig{
+				Public: RateLimit{PerMinute: DefaultPublicRequestsPerMinute, Burst: DefaultPublicBurst},
+				User:   RateLimit{PerMinute: DefaultUserRequestsPerMinute, Burst: DefaultUserBurst},
+			},
+		},
+		{
+			name: "configured",
+			env: map[string]string{
+				"RATE_LIMIT_PUBLIC_PER_MINUTE": "5", "RATE_LIMIT_PUBLIC_BURST": "2",
+				"RATE_LIMIT_USER_PER_MINUTE": "0", "RATE_LIMIT_TRUST_PROXY": "true",
+			},
+			want: RateLimitConfig{
+				Public:     RateLimit{PerMinute: 5, Burst: 2},
+				User:       RateLimit{PerMinute: 0, Burst: DefaultUserBurst},
+				TrustProxy: true,
+			},
+		},
+		{name: "negative rate", env: map[string]string{"RATE_LIMIT_USER_PER_MINUTE": "-1"}, wantErr: true},
+		{name: "empty burst", env: map[string]string{"RATE_LIMIT_PUBLIC_BURST": "0"}, wantErr: true},
+		{name: "invalid trust proxy", env: map[string]string{"RATE_LIMIT_TRUST_PROXY": "sometimes"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"RATE_LIMIT_PUBLIC_PER_MINUTE", "RATE_LIMIT_PUBLIC_BURST",
+				"RATE_LIMIT_USER_PER_MINUTE", "RATE_LIMIT_USER_BURST", "RATE_LIMIT_TRUST_PROXY"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			got, err := RateLimitConfigFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RateLimitConfigFromEnv() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RateLimitConfigFromEnv returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RateLimitConfigFromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
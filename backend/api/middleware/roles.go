@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// RequireRole restricts a route to users with the given role
+// It must run after AuthMiddleware, which places the role from the user's token in the request context;
+// tokens issued before the role claim existed carry no role and are refused
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, _ := r.Context().Value("userRole").(string)
+			if granted != role {
+				userID, _ := r.Context().Value("userID").(string)
+				logger.FromContext(r.Context()).Warn("User lacks required role",
+					zap.String("user_id", userID),
+					zap.String("required_role", role),
+					zap.String("role", granted),
+					zap.String("path", r.URL.Path))
+				http.Error(w, "Forbidden: requires the "+role+" role", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
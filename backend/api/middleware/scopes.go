@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// RequireScope restricts a route to credentials granted the given scope
+// It must run after AuthMiddleware, which places the credential's scopes in the request context
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, _ := r.Context().Value("authScopes").([]string)
+			if !services.HasScope(granted, scope) {
+				logger.FromContext(r.Context()).Warn("Credential lacks required scope",
+					zap.String("required_scope", scope),
+					zap.Strings("granted_scopes", granted),
+					zap.String("path", r.URL.Path))
+				http.Error(w, "Forbidden: missing scope "+scope, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory for the rest of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// spanAttribute returns the value of one of a span's attributes
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracingCreatesAServerSpanPerRequest(t *testing.T) {
+	recorder := recordSpans(t)
+
+	router := chi.NewRouter()
+	router.Use(Tracing)
+	router.Post("/api/repositories/{id}/scan", func(w http.ResponseWriter, r *http.Request) {
+		// Stands in for the work a handler traces, e.g. starting the scan workflow
+		_, span := tracing.Start(r.Context(), "start scan", tracing.RepositoryIDKey.String(chi.URLParam(r, "id")))
+		span.End()
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/repositories/3f2a/scan", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want the handler's and the server span", len(spans))
+	}
+	child, server := spans[0], spans[1]
+
+	if server.Name() != "POST /api/repositories/{id}/scan" {
+		t.Errorf("server span name = %q, want the route pattern", server.Name())
+	}
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("server span kind = %v, want server", server.SpanKind())
+	}
+	if got, _ := spanAttribute(server, "http.route"); got.AsString() != "/api/repositories/{id}/scan" {
+		t.Errorf("http.route = %q, want the route pattern", got.AsString())
+	}
+	if got, _ := spanAttribute(server, "http.response.status_code"); got.AsInt64() != http.StatusAccepted {
+		t.Errorf("http.response.status_code = %d, want 202", got.AsInt64())
+	}
+
+	// The caller's trace is continued, and spans the handler starts hang off the server span
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span trace ID = %s, want the caller's", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("server span parent = %s, want the caller's span", got)
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("handler span isn't a child of the server span")
+	}
+	if got, _ := spanAttribute(child, tracing.RepositoryIDKey); got.AsString() != "3f2a" {
+		t.Errorf("%s = %q, want 3f2a", tracing.RepositoryIDKey, got.AsString())
+	}
+}
+
+func TestTracingMarksServerErrors(t *testing.T) {
+	recorder := recordSpans(t)
+
+	router := chi.NewRouter()
+	router.Use(Tracing)
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if got := spans[0].Status().Code; got != codes.Error {
+		t.Errorf("span status = %s, want Error for a 503", got)
+	}
+}
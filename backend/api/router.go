@@ -14,6 +14,7 @@ import (
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/handlers"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/metrics"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
 	"go.temporal.io/sdk/client"
 	"go.uber.org/zap"
@@ -31,6 +32,7 @@ func NewRouter(temporalClient client.Client, dbQueries *db.Queries) *chi.Mux {
 
 	// Set up global middleware that will apply to all routes
 	// Middleware is executed in the order it's added
+	router.Use(middleware.Tracing)                      // Server span per request, continuing the caller's trace
 	router.Use(middleware.RequestLogger)                // Custom logger for HTTP requests
 	router.Use(chimiddleware.Recoverer)                 // Recover from panics without crashing the server
 	router.Use(chimiddleware.Timeout(60 * time.Second)) // Set a timeout for all requests
@@ -49,10 +51,11 @@ func NewRouter(temporalClient client.Client, dbQueries *db.Queries) *chi.Mux {
 						zap.String("method", r.Method),
 					)
 
-					w.WriteHeader(http.StatusInternalServerError)
+					// Same shape as the handlers' error responses
 					w.Header().Set("Content-Type", "application/json")
-					json.NewEncoder(w).Encode(map[string]string{
-						"error": "Internal server error",
+					w.WriteHeader(http.StatusInternalServerError)
+					json.NewEncoder(w).Encode(map[string]any{
+						"error": map[string]string{"code": "internal_error", "message": "Internal server error"},
 					})
 				}
 			}()
@@ -99,13 +102,19 @@ func NewRouter(temporalClient client.Client, dbQueries *db.Queries) *chi.Mux {
 	})
 	router.Use(corsMiddleware.Handler)
 
-	// Health check endpoint for monitoring and load balancers
-	// This simple endpoint allows checking if the API is running
-	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		logger.FromContext(r.Context()).Debug("Health check called")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	// Health checks for monitoring and load balancers
+	// /health is the liveness probe and only says the server is up; /health/ready also checks
+	// the database and Temporal, so traffic is held back while either is unreachable
+	healthHandler := handlers.NewHealthHandler(dbQueries, temporalClient)
+	healthHandler.DatabaseHealthy = db.Healthy
+	router.Get("/health", healthHandler.Live)
+	router.Get("/health/ready", healthHandler.Ready)
+
+	// Supported languages, vulnerability types, and severities, for clients building filters
+	router.Get("/meta", handlers.HandleGetMeta)
+
+	// Prometheus metrics for scans, findings, and HTTP traffic
+	router.Method(http.MethodGet, "/metrics", metrics.Handler())
 
 	// Initialize authentication service with database connection
 	services.InitAuthService(dbQueries)
@@ -119,48 +128,111 @@ func NewRouter(temporalClient client.Client, dbQueries *db.Queries) *chi.Mux {
 	// Log successful service initialization
 	logger.Info("Services initialized successfully")
 
+	// Throttle clients so the public scan endpoint can't be used to exhaust the model budget
+	// Public routes are limited per client IP and authenticated routes per user; health checks and /metrics are not limited
+	rateLimiter, err := middleware.RateLimiterFromEnv()
+	if err != nil {
+		logger.Fatal("Invalid rate limit configuration", zap.Error(err))
+	}
+
 	// Authentication routes
 	// These handle OAuth flows and token generation
 	router.Route("/auth", func(r chi.Router) {
-		// Create auth handler with JWT secret
-		jwtSecret := os.Getenv("JWT_SECRET")
-		if jwtSecret == "" {
-			jwtSecret = "default-secret-for-development-only"
-			logger.Warn("JWT_SECRET environment variable not set, using default secret (not secure for production)")
-		}
+		r.Use(rateLimiter.ByIP)
 
-		authHandler := handlers.NewAuthHandler(jwtSecret)
+		// Create auth handler with the same JWT secret the auth service signs with
+		authHandler := handlers.NewAuthHandler(services.JWTSecret(), services.NewRefreshTokenService(dbQueries),
+			services.NewOAuthStateStore(dbQueries))
 
 		r.Get("/google", authHandler.HandleGoogleLogin)          // Initiate Google OAuth flow
 		r.Get("/google/callback", authHandler.HandleGoogleLogin) // OAuth callback from Google
 		r.Post("/token", authHandler.HandleTokenExchange)        // Exchange OAuth code for JWT token
+		r.Post("/register", authHandler.Register)                // Create an email/password account
+		r.Post("/login", authHandler.Login)                      // Log in with email and password
+		r.Post("/refresh", authHandler.Refresh)                  // Exchange a refresh token for a new JWT
+		r.Post("/logout", authHandler.Logout)                    // Revoke a refresh token
 	})
 
 	// Public scanning endpoints - no authentication required
 	// These allow anonymous users to scan public repositories
 	repositoryHandler := handlers.NewRepositoryHandler(githubService, scannerService, openAIService, temporalClient)
-	router.Post("/scan", repositoryHandler.ScanPublicRepository)       // Start a scan for a public repo
-	router.Get("/scan/{id}/status", repositoryHandler.GetScanStatus)   // Check scan status by ID
-	router.Get("/scan/{id}/results", repositoryHandler.GetScanResults) // Get scan results by ID
-	router.Get("/scan/{id}/debug", repositoryHandler.DebugWorkflow)    // Debugging endpoint for workflows
+	repositoryHandler.IdempotencyStore = services.NewIdempotencyStore(dbQueries)
+	repositoryHandler.ScanOwners = services.ScanOwnerAllowlistFromEnv()
+	repositoryHandler.BatchItemRateLimit = rateLimiter.TakeByIP
+	router.Group(func(r chi.Router) {
+		r.Use(rateLimiter.ByIP)
+
+		r.Post("/scan", repositoryHandler.ScanPublicRepository)                 // Start a scan for a public repo
+		r.Post("/scan/batch", repositoryHandler.ScanPublicRepositories)         // Start scans for several public repos
+		r.Post("/scan/preview", repositoryHandler.PreviewScan)                  // List the files a scan would analyze, without scanning
+		r.Get("/scan/{id}/status", repositoryHandler.GetScanStatus)             // Check scan status by ID
+		r.Get("/scan/{id}/results", repositoryHandler.GetScanResults)           // Get scan results by ID
+		r.Get("/scan/{id}/results.csv", repositoryHandler.ExportScanResultsCSV) // Download scan results as CSV
+		r.Get("/scan/{id}/results.pdf", repositoryHandler.ExportScanResultsPDF) // Download scan results as a PDF report
+		r.Get("/scan/{id}/remediation", repositoryHandler.GetScanRemediation)   // Prioritized fix plan for a scan
+		r.Get("/scan/{id}/debug", repositoryHandler.DebugWorkflow)              // Debugging endpoint for workflows
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimiter.ByUser)
+		r.Use(middleware.RequireScope(services.ScopeRepoRead))
+
+		r.Get("/scan/{id}/history", repositoryHandler.GetScanHistory)                 // Sanitized workflow timeline (owner or admin)
+		r.Get("/scan/{id}/file", repositoryHandler.GetScanFileFindings)               // Findings for one file (owner or admin)
+		r.Get("/scan/{id}/vulnerabilities", repositoryHandler.GetScanVulnerabilities) // Findings of a specific, possibly older, scan (owner or admin)
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimiter.ByUser)
+		r.Use(middleware.RequireScope(services.ScopeScanWrite))
+
+		r.Post("/scan/upload", repositoryHandler.ScanUpload) // Scan the source in an uploaded zip or tar.gz archive
+	})
+
+	// GitHub push webhook - authenticated by the GITHUB_WEBHOOK_SECRET signature instead of a user token
+	// It is not rate limited, as GitHub delivers every repository's pushes from a few shared addresses
+	router.Post("/webhooks/github", repositoryHandler.HandleGitHubWebhook)
 
 	// Repository routes - protected by authentication
 	// These endpoints manage repositories and their scans
 	router.Route("/repositories", func(r chi.Router) {
 		// Apply authentication middleware to all routes in this group
 		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimiter.ByUser)
+
+		// Each route also requires a scope, so restricted API keys can only do what they were issued for
+		repoRead := middleware.RequireScope(services.ScopeRepoRead)
+		repoWrite := middleware.RequireScope(services.ScopeRepoWrite)
+		repoDelete := middleware.RequireScope(services.ScopeRepoDelete)
+		scanWrite := middleware.RequireScope(services.ScopeScanWrite)
+
+		r.With(repoWrite).Post("/", repositoryHandler.CreateRepository)                     // Create a new repository
+		r.With(repoRead).Get("/", repositoryHandler.ListRepositories)                       // List all repositories for current user
+		r.With(repoRead).Get("/{id}", repositoryHandler.GetRepository)                      // Get details of a specific repository
+		r.With(repoDelete).Delete("/{id}", repositoryHandler.DeleteRepository)              // Delete a repository and its scan data
+		r.With(scanWrite).Post("/{id}/scan", repositoryHandler.ScanRepository)              // Start a scan for a specific repository
+		r.With(scanWrite).Post("/{id}/scan/cancel-clone", repositoryHandler.CancelClone)    // Abort a scan that is stuck cloning
+		r.With(repoRead).Get("/{id}/vulnerabilities", repositoryHandler.GetVulnerabilities) // Get vulnerabilities for a repository
+		r.With(repoRead).Get("/{id}/scans", repositoryHandler.ListRepositoryScans)          // All scans of a repository, newest first
+		r.With(repoRead).Get("/{id}/scans/diff", repositoryHandler.DiffRepositoryScans)     // Findings added, fixed, or unchanged between two scans
+		r.With(repoRead).Get("/{id}/notify-emails", repositoryHandler.GetNotifyEmails)      // Additional scan result recipients
+		r.With(repoWrite).Put("/{id}/notify-emails", repositoryHandler.UpdateNotifyEmails)  // Replace additional recipients
 
-		r.Post("/", repositoryHandler.CreateRepository)                      // Create a new repository
-		r.Get("/", repositoryHandler.ListRepositories)                       // List all repositories for current user
-		r.Get("/{id}", repositoryHandler.GetRepository)                      // Get details of a specific repository
-		r.Post("/{id}/scan", repositoryHandler.ScanRepository)               // Start a scan for a specific repository
-		r.Get("/{id}/vulnerabilities", repositoryHandler.GetVulnerabilities) // Get vulnerabilities for a repository
+		// Baseline routes - accepted findings are hidden from later results
+		r.With(repoWrite).Post("/{id}/scans/{scanID}/promote-baseline", repositoryHandler.PromoteBaseline) // Accept a scan's findings
+		r.With(repoWrite).Delete("/{id}/baseline", repositoryHandler.ClearBaseline)                        // Report all findings again
+
+		// Suppression routes - findings marked as false positives are left out of results
+		r.With(repoRead).Get("/{id}/suppressions", repositoryHandler.ListSuppressions)                      // Suppressed locations
+		r.With(repoWrite).Post("/{id}/suppressions", repositoryHandler.CreateSuppression)                   // Mark a false positive
+		r.With(repoWrite).Delete("/{id}/suppressions/{suppressionID}", repositoryHandler.DeleteSuppression) // Report it again
 	})
 
 	// Protected API routes - general purpose endpoints that require authentication
 	router.Route("/api", func(r chi.Router) {
 		// Apply authentication middleware to all /api routes
 		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimiter.ByUser)
 
 		// User management routes
 		r.Route("/users", func(r chi.Router) {
@@ -168,6 +240,72 @@ func NewRouter(temporalClient client.Client, dbQueries *db.Queries) *chi.Mux {
 				// Get the authenticated user's profile
 				handlers.HandleGetUserProfile(w, r, dbQueries)
 			})
+			r.Put("/me/notifications", func(w http.ResponseWriter, r *http.Request) {
+				// Choose immediate or digest scan emails, or turn them off
+				handlers.HandleUpdateNotificationPreferences(w, r, dbQueries)
+			})
+		})
+
+		// Scanner capability routes
+		r.Get("/languages", handlers.HandleListLanguages) // List supported languages and extensions
+
+		// Scan comparison routes
+		r.With(middleware.RequireScope(services.ScopeRepoRead)).
+			Get("/scans/compare", repositoryHandler.CompareScans) // Diff findings of two scans, e.g. two models
+
+		// API keys for programmatic access; managing them takes a signed-in session, so a key can't
+		// issue itself broader keys
+		apiKeyHandler := handlers.NewAPIKeyHandler(services.NewAPIKeyService(dbQueries))
+		r.Route("/keys", func(r chi.Router) {
+			r.Use(middleware.RequireScope(services.ScopeAll))
+
+			r.Post("/", apiKeyHandler.CreateAPIKey)       // Issue a key; the response is the only copy of it
+			r.Get("/", apiKeyHandler.ListAPIKeys)         // List keys by prefix, with when they were last used
+			r.Delete("/{id}", apiKeyHandler.RevokeAPIKey) // Revoke a key
+		})
+
+		// Webhook subscriptions for scan status events
+		webhookHandler := handlers.NewWebhookHandler(services.NewWebhookService(dbQueries))
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(middleware.RequireScope(services.ScopeRepoWrite))
+
+			r.Post("/", webhookHandler.CreateWebhook)       // Subscribe to scan events
+			r.Get("/", webhookHandler.ListWebhooks)         // List subscriptions and available events
+			r.Delete("/{id}", webhookHandler.DeleteWebhook) // Remove a subscription
+		})
+
+		// In-app notifications of the authenticated user
+		notificationHandler := handlers.NewNotificationHandler(services.NewNotificationService(dbQueries))
+		r.Route("/notifications", func(r chi.Router) {
+			r.Get("/", notificationHandler.ListNotifications)                 // Newest first, with the unread count
+			r.Post("/read-all", notificationHandler.MarkAllNotificationsRead) // Mark every notification read
+			r.Post("/{id}/read", notificationHandler.MarkNotificationRead)    // Mark one notification read
+		})
+
+		// Admin routes - require the admin role in addition to authentication
+		// The role claim of the token is checked first; AdminMiddleware then rereads the stored role,
+		// so revoking admin access takes effect before the user's token expires
+		adminHandler := handlers.NewAdminHandler(services.NewReindexService(dbQueries), services.NewWorkerControlService(dbQueries),
+			services.NewDirectoryService(dbQueries))
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.RequireScope(services.ScopeAdmin))
+			r.Use(middleware.RequireRole(services.RoleAdmin))
+			r.Use(middleware.AdminMiddleware)
+
+			r.Post("/reindex", adminHandler.Reindex) // Recompute fingerprints, categories, and summaries
+			r.Get("/users", adminHandler.ListUsers)  // Every user account with its role
+			r.Get("/scans", adminHandler.ListScans)  // Every user's scans, newest first
+		})
+
+		// System routes - operator controls, also admin only
+		r.Route("/system", func(r chi.Router) {
+			r.Use(middleware.RequireScope(services.ScopeAdmin))
+			r.Use(middleware.RequireRole(services.RoleAdmin))
+			r.Use(middleware.AdminMiddleware)
+
+			r.Get("/worker", adminHandler.GetWorkerState)       // Whether scan processing is paused
+			r.Post("/worker/pause", adminHandler.PauseWorker)   // Keep new scans queued
+			r.Post("/worker/resume", adminHandler.ResumeWorker) // Start queued scans again
 		})
 	})
 
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestDeletingARepositoryRequiresTheDeleteScope(t *testing.T) {
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+
+	// API keys are authenticated against the global connection
+	db.SetGlobalDB(dbConn)
+	t.Cleanup(func() { db.SetGlobalDB(nil) })
+	queries := db.NewQueries()
+
+	var userID string
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'Test User') RETURNING id`,
+		"router-"+uuid.NewString()+"@example.com").Scan(&userID); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	keys := services.NewAPIKeyService(queries)
+	router := NewRouter(nil, queries)
+
+	tests := []struct {
+		name        string
+		scopes      []string
+		wantRefused bool
+	}{
+		{name: "repo:write key", scopes: []string{services.ScopeRepoRead, services.ScopeRepoWrite}, wantRefused: true},
+		{name: "repo:delete key", scopes: []string{services.ScopeRepoDelete}, wantRefused: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiKey, err := keys.CreateAPIKey(ctx, userID, tt.name, tt.scopes)
+			if err != nil {
+				t.Fatalf("CreateAPIKey returned error: %v", err)
+			}
+
+			r := httptest.NewRequest(http.MethodDelete, "/repositories/"+uuid.NewString(), nil)
+			r.Header.Set("Authorization", "ApiKey "+apiKey.Key)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+
+			// The handler itself answers for an unknown repository; only the scope check names the scope
+			refused := rec.Code == http.StatusForbidden && strings.Contains(rec.Body.String(), "missing scope "+services.ScopeRepoDelete)
+			if refused != tt.wantRefused {
+				t.Errorf("status = %d, want refused for the missing scope = %v: %s", rec.Code, tt.wantRefused, rec.Body.String())
+			}
+		})
+	}
+}
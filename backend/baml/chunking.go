@@ -0,0 +1,113 @@
+package baml
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Defaults for splitting large files into overlapping windows
+const (
+	defaultChunkLines   = 400 // Lines per chunk; files up to this size are sent whole
+	defaultChunkOverlap = 40  // Lines repeated between consecutive chunks so findings at a boundary are seen whole
+	defaultMaxChunks    = 10  // Chunks scanned per file; the rest of a larger file is not scanned
+)
+
+// codeChunk is a window of a file's lines
+type codeChunk struct {
+	startLine int // 1-based line of the file the chunk starts at
+	code      string
+}
+
+// splitIntoChunks splits code into windows of chunkLines lines that overlap by overlap lines
+// Code that fits in one window is returned as a single chunk. At most maxChunks chunks are returned,
+// and truncated reports whether the tail of the file was left out
+func splitIntoChunks(code string, chunkLines, overlap, maxChunks int) (chunks []codeChunk, truncated bool) {
+	lines := strings.Split(code, "\n")
+	if chunkLines <= 0 || len(lines) <= chunkLines {
+		return []codeChunk{{startLine: 1, code: code}}, false
+	}
+	if overlap < 0 || overlap >= chunkLines {
+		overlap = 0
+	}
+	step := chunkLines - overlap
+
+	for start := 0; start < len(lines); start += step {
+		if maxChunks > 0 && len(chunks) == maxChunks {
+			return chunks, true
+		}
+		end := start + chunkLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		chunks = append(chunks, codeChunk{startLine: start + 1, code: strings.Join(lines[start:end], "\n")})
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks, false
+}
+
+// offsetChunkFindings moves chunk-relative line numbers to absolute file lines
+func offsetChunkFindings(vulns []Vulnerability, chunk codeChunk) []Vulnerability {
+	offset := chunk.startLine - 1
+	for i := range vulns {
+		if vulns[i].LineStart > 0 {
+			vulns[i].LineStart += offset
+		}
+		if vulns[i].LineEnd > 0 {
+			vulns[i].LineEnd += offset
+		}
+	}
+	return vulns
+}
+
+// mergeChunkFindings appends a chunk's findings to those already found in the file
+// A finding that overlaps one of the same type from the previous chunk was seen twice in the shared
+// lines, so only the first report is kept
+func mergeChunkFindings(merged, previous, current []Vulnerability) []Vulnerability {
+	for _, vuln := range current {
+		duplicate := false
+		for _, seen := range previous {
+			if seen.VulnerabilityType == vuln.VulnerabilityType && linesOverlap(seen, vuln) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, vuln)
+		}
+	}
+	return merged
+}
+
+// linesOverlap reports whether two findings cover at least one common line
+func linesOverlap(a, b Vulnerability) bool {
+	aEnd, bEnd := a.LineEnd, b.LineEnd
+	if aEnd < a.LineStart {
+		aEnd = a.LineStart
+	}
+	if bEnd < b.LineStart {
+		bEnd = b.LineStart
+	}
+	return a.LineStart <= bEnd && b.LineStart <= aEnd
+}
+
+// positiveIntFromEnv reads a positive integer setting, falling back to def when unset or invalid
+func positiveIntFromEnv(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		logger.Warn("Invalid "+name+" value, using default",
+			zap.String("value", value),
+			zap.Int("default", def))
+		return def
+	}
+	return parsed
+}
@@ -0,0 +1,193 @@
+package baml
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// requestPayload decodes the OpenAI request a client sent
+func requestPayload(t *testing.T, req *http.Request) OpenAIRequestPayload {
+	t.Helper()
+	body, err := req.GetBody()
+	if err != nil {
+		t.Fatalf("read request body: %v", err)
+	}
+	var payload OpenAIRequestPayload
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		t.Fatalf("decode request body: %v", err)
+	}
+	return payload
+}
+
+// queryFindingTransport reports an Injection finding on every line of the scanned code that calls db.Query,
+// numbered relative to the code in the prompt the way the model sees it
+type queryFindingTransport struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (q *queryFindingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	q.mu.Lock()
+	q.requests++
+	q.mu.Unlock()
+
+	var payload OpenAIRequestPayload
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	prompt := payload.Messages[len(payload.Messages)-1].Content
+	code := prompt[strings.Index(prompt, "CODE:\n")+len("CODE:\n") : strings.Index(prompt, "\n\nYour task:")]
+
+	result := CodeScanResult{Vulnerabilities: []Vulnerability{}}
+	for i, line := range strings.Split(code, "\n") {
+		if strings.Contains(line, "db.Query(") {
+			result.Vulnerabilities = append(result.Vulnerabilities, Vulnerability{
+				VulnerabilityType: "Injection",
+				LineStart:         i + 1,
+				LineEnd:           i + 1,
+				Severity:          "High",
+			})
+		}
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(map[string]any{
+		"choices": []map[string]any{{"message": Message{Role: "assistant", Content: string(content)}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(body))),
+		Request:    req,
+	}, nil
+}
+
+func TestScanCodeChunksLargeFilesWithAbsoluteLineNumbers(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("MODEL_CONTEXT_WINDOW", "")
+	transport := &queryFindingTransport{}
+	useTransport(t, transport)
+
+	// Line 380 sits in the overlap of the first two chunks, so both report it
+	queryLines := []int{1, 380, 2500, 5000}
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("total += %d", i+1)
+	}
+	for _, n := range queryLines {
+		lines[n-1] = fmt.Sprintf("rows := db.Query(\"select * from t where id = \" + id%d)", n)
+	}
+
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Model: "gpt-4o", ChunkLines: 400, ChunkOverlap: 40, MaxChunks: 20})
+	result, err := client.ScanCode(context.Background(), strings.Join(lines, "\n"), "Go", "main.go", []string{"Injection"})
+	if err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+
+	// 400 line chunks stepping 360 lines cover 5000 lines in 14 requests
+	if transport.requests != 14 {
+		t.Errorf("sent %d requests, want 14", transport.requests)
+	}
+	var got []int
+	for _, vuln := range result.Vulnerabilities {
+		if vuln.LineEnd != vuln.LineStart {
+			t.Errorf("finding spans lines %d-%d, want a single line", vuln.LineStart, vuln.LineEnd)
+		}
+		got = append(got, vuln.LineStart)
+	}
+	if !reflect.DeepEqual(got, queryLines) {
+		t.Errorf("findings at lines %v, want %v", got, queryLines)
+	}
+}
+
+func TestScanCodeStopsAtTheChunkLimit(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	transport := &queryFindingTransport{}
+	useTransport(t, transport)
+
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = "total++"
+	}
+	lines[999] = "rows := db.Query(q)"
+
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Model: "gpt-4o", ChunkLines: 100, MaxChunks: 3})
+	result, err := client.ScanCode(context.Background(), strings.Join(lines, "\n"), "Go", "main.go", []string{"Injection"})
+	if err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+	if transport.requests != 3 {
+		t.Errorf("sent %d requests, want the 3 chunk limit", transport.requests)
+	}
+	if len(result.Vulnerabilities) != 0 {
+		t.Errorf("found %v past the chunk limit, want nothing", result.Vulnerabilities)
+	}
+}
+
+func TestSplitIntoChunks(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         int
+		chunkLines    int
+		overlap       int
+		maxChunks     int
+		wantStarts    []int
+		wantTruncated bool
+	}{
+		{name: "short file is one chunk", lines: 10, chunkLines: 10, overlap: 2, wantStarts: []int{1}},
+		{name: "overlapping windows", lines: 25, chunkLines: 10, overlap: 2, wantStarts: []int{1, 9, 17}},
+		{name: "invalid overlap is ignored", lines: 25, chunkLines: 10, overlap: 10, wantStarts: []int{1, 11, 21}},
+		{name: "chunk limit truncates the tail", lines: 25, chunkLines: 10, overlap: 2, maxChunks: 2, wantStarts: []int{1, 9}, wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]string, tt.lines)
+			for i := range lines {
+				lines[i] = fmt.Sprintf("line %d", i+1)
+			}
+
+			chunks, truncated := splitIntoChunks(strings.Join(lines, "\n"), tt.chunkLines, tt.overlap, tt.maxChunks)
+			var starts []int
+			for _, chunk := range chunks {
+				starts = append(starts, chunk.startLine)
+				if first := strings.SplitN(chunk.code, "\n", 2)[0]; first != fmt.Sprintf("line %d", chunk.startLine) {
+					t.Errorf("chunk at line %d starts with %q", chunk.startLine, first)
+				}
+			}
+			if !reflect.DeepEqual(starts, tt.wantStarts) || truncated != tt.wantTruncated {
+				t.Errorf("chunks start at %v (truncated %v), want %v (truncated %v)", starts, truncated, tt.wantStarts, tt.wantTruncated)
+			}
+			if last := chunks[len(chunks)-1]; !tt.wantTruncated && !strings.HasSuffix(last.code, fmt.Sprintf("line %d", tt.lines)) {
+				t.Errorf("last chunk doesn't end at line %d", tt.lines)
+			}
+		})
+	}
+}
+
+func TestMergeChunkFindings(t *testing.T) {
+	previous := []Vulnerability{{VulnerabilityType: "Injection", LineStart: 95, LineEnd: 98}}
+	current := []Vulnerability{
+		{VulnerabilityType: "Injection", LineStart: 97, LineEnd: 97},              // Same finding seen again in the overlap
+		{VulnerabilityType: "Cryptographic Failures", LineStart: 96, LineEnd: 96}, // Different type on the same lines
+		{VulnerabilityType: "Injection", LineStart: 120, LineEnd: 120},            // Past the overlap
+	}
+
+	merged := mergeChunkFindings(previous, previous, current)
+	want := []Vulnerability{previous[0], current[1], current[2]}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("mergeChunkFindings = %+v, want %+v", merged, want)
+	}
+}
@@ -1,17 +1,18 @@
 package baml
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -27,6 +28,87 @@ type Vulnerability struct {
 	Description       string `json:"description"`
 	Remediation       string `json:"remediation"`
 	CodeSnippet       string `json:"code_snippet"`
+
+	// Confidence is how sure the model is that the finding is real; nil when it didn't say
+	Confidence *Confidence `json:"confidence,omitempty"`
+
+	// CWE is the weakness the finding is an instance of, e.g. "CWE-89"; empty when the model didn't say
+	CWE CWE `json:"cwe,omitempty"`
+
+	// References are links the model cited for the finding; only http(s) URLs are kept
+	References References `json:"references,omitempty"`
+}
+
+// Confidence is a model's certainty in a finding, from 0.0 to 1.0
+// Models answer with a number, a numeric string, or a Low/Medium/High label; all are accepted
+type Confidence float64
+
+// Values the confidence labels stand for; they are also the thresholds ?min_confidence=low|medium|high uses
+const (
+	ConfidenceLow    Confidence = 0.3
+	ConfidenceMedium Confidence = 0.6
+	ConfidenceHigh   Confidence = 0.9
+)
+
+// ParseConfidence reads a confidence label or a number from 0.0 to 1.0
+// Percentages such as 85 or "85%" are scaled down, and larger numbers are capped at 1.0
+func ParseConfidence(value string) (Confidence, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "low":
+		return ConfidenceLow, nil
+	case "medium":
+		return ConfidenceMedium, nil
+	case "high":
+		return ConfidenceHigh, nil
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || math.IsNaN(number) || number < 0 {
+		return 0, fmt.Errorf("invalid confidence %q: must be low, medium, high, or a number from 0.0 to 1.0", value)
+	}
+	if strings.HasSuffix(value, "%") || (number > 1 && number <= 100) {
+		number /= 100
+	}
+	return Confidence(min(number, 1)), nil
+}
+
+// UnmarshalJSON accepts a number or a string; an unreadable value is treated as not given
+// rather than failing the whole scan result
+func (c *Confidence) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var parsed Confidence
+	var err error
+	switch value := raw.(type) {
+	case float64:
+		parsed, err = ParseConfidence(strconv.FormatFloat(value, 'f', -1, 64))
+	case string:
+		parsed, err = ParseConfidence(value)
+	default:
+		err = fmt.Errorf("unsupported confidence %s", data)
+	}
+	if err != nil {
+		*c = confidenceUnreadable
+		return nil
+	}
+	*c = parsed
+	return nil
+}
+
+// confidenceUnreadable marks a confidence the model gave in a form that couldn't be read
+const confidenceUnreadable Confidence = -1
+
+// Value returns the confidence as a plain number, or nil when the model didn't give a readable one
+func (c *Confidence) Value() *float64 {
+	if c == nil || *c == confidenceUnreadable {
+		return nil
+	}
+	value := float64(*c)
+	return &value
 }
 
 // CodeScanResult represents the result of a code scan
@@ -57,31 +139,192 @@ type OpenAIResponsePayload struct {
 	} `json:"choices"`
 }
 
+// DefaultModel is the OpenAI model used when neither OPENAI_MODEL nor the scan request names one
+const DefaultModel = "gpt-4-turbo"
+
 // CodeScannerClient is a client for the BAML code scanner prompt
 type CodeScannerClient struct {
-	apiKey      string
-	model       string
-	maxTokens   int
-	temperature float64
+	provider      Provider // LLM the prompts are sent to
+	model         string
+	timeout       time.Duration // Per-request timeout for LLM calls
+	maxTokens     int
+	temperature   float64
+	contextWindow int // Tokens one request to the model may hold, prompt and reply together
+	chunkLines    int // Files longer than this are scanned in overlapping chunks
+	chunkOverlap  int // Lines shared by consecutive chunks
+	maxChunks     int // Most chunks scanned per file
 }
 
-// NewCodeScannerClient creates a new code scanner client
+// NewCodeScannerClient creates a new code scanner client configured from the environment
 func NewCodeScannerClient() *CodeScannerClient {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		logger.Warn("OPENAI_API_KEY environment variable not set, BAML scans will fail")
+	return NewCodeScannerClientWithConfig(ConfigFromEnv())
+}
+
+// NewCodeScannerClientWithConfig creates a code scanner client with explicit model settings
+// Zero values in config fall back to the defaults
+func NewCodeScannerClientWithConfig(config CodeScannerConfig) *CodeScannerClient {
+	if config.Model == "" {
+		config.Model = DefaultModel
+	}
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = defaultMaxTokens
+	}
+	if config.ChunkLines <= 0 {
+		config.ChunkLines = defaultChunkLines
+		config.ChunkOverlap = defaultChunkOverlap
+	}
+	if config.MaxChunks <= 0 {
+		config.MaxChunks = defaultMaxChunks
+	}
+	if config.ContextWindow <= 0 {
+		config.ContextWindow = ContextWindowForModel(config.Model)
+	}
+
+	timeout := RequestTimeoutFromEnv()
+	if config.Provider == nil {
+		config.Provider = NewProviderFromEnv(timeout, config.Retry)
 	}
 
 	return &CodeScannerClient{
-		apiKey:      apiKey,
-		model:       "gpt-4-turbo", // Use the model specified in the BAML file
-		maxTokens:   4000,
-		temperature: 0.0,
+		provider:      config.Provider,
+		model:         config.Model,
+		timeout:       timeout,
+		maxTokens:     config.MaxTokens,
+		temperature:   config.Temperature,
+		contextWindow: config.ContextWindow,
+		chunkLines:    config.ChunkLines,
+		chunkOverlap:  config.ChunkOverlap,
+		maxChunks:     config.MaxChunks,
 	}
 }
 
+// defaultRequestTimeout bounds a single OpenAI call when OPENAI_REQUEST_TIMEOUT is not set
+const defaultRequestTimeout = 2 * time.Minute
+
+// RequestTimeoutFromEnv reads the per-request timeout from OPENAI_REQUEST_TIMEOUT (a Go duration)
+func RequestTimeoutFromEnv() time.Duration {
+	value := os.Getenv("OPENAI_REQUEST_TIMEOUT")
+	if value == "" {
+		return defaultRequestTimeout
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		logger.Warn("Invalid OPENAI_REQUEST_TIMEOUT value, using default",
+			zap.String("value", value),
+			zap.Duration("default", defaultRequestTimeout))
+		return defaultRequestTimeout
+	}
+	return timeout
+}
+
+// RequestTimeout returns the longest a single scan request may take
+func (c *CodeScannerClient) RequestTimeout() time.Duration {
+	return c.timeout
+}
+
+// Model returns the model this client sends scan requests to
+func (c *CodeScannerClient) Model() string {
+	return c.model
+}
+
+// ContextWindow returns how many tokens one request to the client's model may hold
+func (c *CodeScannerClient) ContextWindow() int {
+	return c.contextWindow
+}
+
+// WithModel returns a copy of the client that sends scan requests to a different model,
+// sized for that model's context window. An empty model returns the client unchanged
+func (c *CodeScannerClient) WithModel(model string) *CodeScannerClient {
+	if model == "" || model == c.model {
+		return c
+	}
+	clone := *c
+	clone.model = model
+	clone.contextWindow = ContextWindowForModel(model)
+	return &clone
+}
+
 // ScanCode scans code for vulnerabilities using the BAML code scanner prompt
-func (c *CodeScannerClient) ScanCode(ctx context.Context, code, language, filepath string, vulnerabilityTypes []string) (*CodeScanResult, error) {
+// Files longer than the chunk size, or too big for the model's context window, are scanned as overlapping
+// chunks so the tail isn't truncated; findings are reported with absolute line numbers either way.
+// Code whose lines are too long to fit any chunk returns an error wrapping ErrPromptTooLarge
+func (c *CodeScannerClient) ScanCode(ctx context.Context, code, language, filepath string, vulnerabilityTypes []string) (result *CodeScanResult, err error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
+	}
+
+	ctx, span := tracing.Start(ctx, "ScanCode",
+		tracing.FilePathKey.String(filepath),
+		tracing.LanguageKey.String(language),
+		tracing.ModelKey.String(c.model),
+		tracing.TokenEstimateKey.Int(EstimateTokens(ScanSystemPrompt)+EstimateTokens(FormatScanPrompt(code, language, filepath, vulnerabilityTypes))))
+	defer func() {
+		if result != nil {
+			span.SetAttributes(attribute.Int("sast.findings", len(result.Vulnerabilities)))
+		}
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	chunkLines, chunkOverlap := c.chunkSize(code, language, filepath, vulnerabilityTypes)
+	if chunkLines == 0 {
+		return nil, fmt.Errorf("%w: the prompt alone leaves no room for code in %d tokens", ErrPromptTooLarge, c.contextWindow)
+	}
+
+	chunks, truncated := splitIntoChunks(code, chunkLines, chunkOverlap, c.maxChunks)
+	span.SetAttributes(attribute.Int("sast.chunks", len(chunks)))
+	if len(chunks) == 1 {
+		return c.scanPrompt(ctx, code, language, filepath, vulnerabilityTypes)
+	}
+	if truncated {
+		log.Warn("File exceeds the chunk limit, scanning only its first chunks",
+			zap.String("filepath", filepath),
+			zap.Int("max_chunks", c.maxChunks),
+			zap.Int("chunk_lines", chunkLines))
+	}
+
+	merged := []Vulnerability{}
+	var previous []Vulnerability
+	for _, chunk := range chunks {
+		result, err := c.scanPrompt(ctx, chunk.code, language, filepath, vulnerabilityTypes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lines from %d: %w", chunk.startLine, err)
+		}
+
+		current := offsetChunkFindings(result.Vulnerabilities, chunk)
+		merged = mergeChunkFindings(merged, previous, current)
+		previous = current
+	}
+
+	log.Debug("BAML chunked scan completed",
+		zap.String("filepath", filepath),
+		zap.Int("chunks", len(chunks)),
+		zap.Int("vulnerabilities_found", len(merged)))
+
+	return &CodeScanResult{Vulnerabilities: merged}, nil
+}
+
+// chunkSize returns the lines per chunk and the overlap to scan code with
+// Chunks are at most the configured size, and smaller when the model's context window can't hold that
+// many lines of this code next to the prompt and the reply. 0 lines means the code can't be sent at all
+func (c *CodeScannerClient) chunkSize(code, language, filepath string, vulnerabilityTypes []string) (int, int) {
+	overhead := EstimateTokens(ScanSystemPrompt) + EstimateTokens(FormatScanPrompt("", language, filepath, vulnerabilityTypes))
+	chunkLines := fittingChunkLines(code, c.chunkLines, promptTokenBudget(c.contextWindow, c.maxTokens), overhead)
+
+	// A chunk shrunk below the configured overlap keeps the same share of its lines as overlap
+	overlap := c.chunkOverlap
+	if chunkLines < c.chunkLines && c.chunkLines > 0 {
+		overlap = c.chunkOverlap * chunkLines / c.chunkLines
+	}
+	return chunkLines, overlap
+}
+
+// scanPrompt sends one piece of code to the model in a single request
+// Line numbers in the result are relative to the code it was given. A prompt that wouldn't leave room
+// for the reply in the model's context window isn't sent, and returns an error wrapping ErrPromptTooLarge
+func (c *CodeScannerClient) scanPrompt(ctx context.Context, code, language, filepath string, vulnerabilityTypes []string) (*CodeScanResult, error) {
 	log := logger.FromContext(ctx)
 	if log == nil {
 		log = logger.Get()
@@ -92,13 +335,86 @@ func (c *CodeScannerClient) ScanCode(ctx context.Context, code, language, filepa
 		zap.String("language", language),
 		zap.Strings("vulnerability_types", vulnerabilityTypes))
 
-	if c.apiKey == "" {
-		log.Error("OpenAI API key not set, cannot scan code")
-		return &CodeScanResult{Vulnerabilities: []Vulnerability{}}, fmt.Errorf("OpenAI API key not set")
+	userPrompt := FormatScanPrompt(code, language, filepath, vulnerabilityTypes)
+
+	// Refuse requests the model would reject, or truncate, for exceeding its context window
+	promptTokens := EstimateTokens(ScanSystemPrompt) + EstimateTokens(userPrompt)
+	if budget := promptTokenBudget(c.contextWindow, c.maxTokens); promptTokens > budget {
+		return nil, fmt.Errorf("%w: about %d prompt tokens with %d reserved for the reply, in a %d token window",
+			ErrPromptTooLarge, promptTokens, c.maxTokens, c.contextWindow)
 	}
 
-	// Build prompt from code_scanner.baml
-	promptTemplate := `You are a security expert performing an automated code scan for OWASP Top 10 vulnerabilities.
+	// Send the prompt to the configured provider; the reply is parsed the same way for all of them
+	content, err := c.provider.ScanCode(ctx, Prompt{
+		Model:       c.model,
+		System:      ScanSystemPrompt,
+		User:        userPrompt,
+		MaxTokens:   c.maxTokens,
+		Temperature: c.temperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseScanResult(content)
+	if err != nil {
+		// The reply can quote the scanned code, so the logger cuts it to LOG_MAX_FIELD_LENGTH
+		log.Warn("Failed to parse model response, asking the model to reformat it",
+			zap.String("filepath", filepath),
+			zap.String("content", content),
+			zap.Int("content_length", len(content)),
+			zap.Error(err))
+
+		result, err = c.reformatReply(ctx, content, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debug("BAML scan completed",
+		zap.String("filepath", filepath),
+		zap.Int("vulnerabilities_found", len(result.Vulnerabilities)))
+
+	return result, nil
+}
+
+// reformatReply asks the model to rewrite a reply that didn't parse as a scan result
+// A rewrite that still doesn't parse is logged and returned as an error wrapping ErrInvalidScanResult,
+// so an unreadable reply is never mistaken for a file with no vulnerabilities
+func (c *CodeScannerClient) reformatReply(ctx context.Context, content string, parseErr error) (*CodeScanResult, error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
+	}
+
+	reformatted, err := c.provider.ScanCode(ctx, Prompt{
+		Model:       c.model,
+		System:      ScanSystemPrompt,
+		User:        fmt.Sprintf(reformatPromptTemplate, parseErr, content),
+		MaxTokens:   c.maxTokens,
+		Temperature: c.temperature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v; asking the model to reformat it failed: %w", ErrInvalidScanResult, parseErr, err)
+	}
+
+	result, err := ParseScanResult(reformatted)
+	if err != nil {
+		log.Error("Failed to parse reformatted model response",
+			zap.String("content", reformatted),
+			zap.Int("content_length", len(reformatted)),
+			zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// ScanSystemPrompt frames every scan request
+const ScanSystemPrompt = "You are a security expert assistant that analyzes code for vulnerabilities."
+
+// scanPromptTemplate is the prompt from code_scanner.baml; it is filled with the vulnerability types,
+// language, file path, and code, in that order
+const scanPromptTemplate = `You are a security expert performing an automated code scan for OWASP Top 10 vulnerabilities.
 Your task is to identify potential security vulnerabilities in the provided code.
 
 Please analyze the following code for these specific vulnerabilities: %s
@@ -118,6 +434,10 @@ Your task:
    - Severity (Critical, High, Medium, Low)
    - Description of the vulnerability
    - A suggested remediation
+   - Confidence that the finding is a real, exploitable vulnerability, from 0.0 (a guess) to 1.0 (certain);
+     use a low value when exploitability depends on code you cannot see
+   - The CWE identifier of the weakness (e.g. "CWE-89"), if one applies
+   - References: URLs of authoritative pages about the weakness (OWASP, CWE, or vendor documentation)
 
 Provide output in JSON format as follows:
 {
@@ -129,7 +449,10 @@ Provide output in JSON format as follows:
       "severity": "High",
       "description": "SQL injection vulnerability due to unparameterized query",
       "remediation": "Use prepared statements or an ORM",
-      "code_snippet": "select * from users where name = '" + username + "'"
+      "code_snippet": "select * from users where name = '" + username + "'",
+      "confidence": 0.9,
+      "cwe": "CWE-89",
+      "references": ["https://owasp.org/Top10/A03_2021-Injection/", "https://cwe.mitre.org/data/definitions/89.html"]
     }
   ]
 }
@@ -137,96 +460,22 @@ Provide output in JSON format as follows:
 If no vulnerabilities are found, return: {"vulnerabilities": []}
 `
 
-	// Format the prompt with the actual values
-	vulnTypesStr := strings.Join(vulnerabilityTypes, ", ")
-	formattedPrompt := fmt.Sprintf(promptTemplate, vulnTypesStr, language, filepath, code)
-
-	// Build the OpenAI API request
-	payload := OpenAIRequestPayload{
-		Model: c.model,
-		Messages: []Message{
-			{
-				Role:    "system",
-				Content: "You are a security expert assistant that analyzes code for vulnerabilities.",
-			},
-			{
-				Role:    "user",
-				Content: formattedPrompt,
-			},
-		},
-		Temperature: c.temperature,
-		MaxTokens:   c.maxTokens,
-	}
-
-	// Convert the payload to JSON
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
-	}
-
-	// Create the HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+// reformatPromptTemplate asks the model to rewrite a reply that wasn't a valid scan result; it is filled
+// with the parse error and the reply, in that order
+const reformatPromptTemplate = `Your previous reply could not be read as a scan result: %s
 
-	// Set the headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+Rewrite it as a single JSON object in the format below, keeping every complete finding it reported and
+dropping any that were cut off. line_start and line_end must be integers, and severity must be one of
+Critical, High, Medium, or Low. Reply with the JSON object only, without markdown fences or commentary.
+{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": 10, "line_end": 15, "severity": "High", "description": "...", "remediation": "...", "code_snippet": "...", "confidence": 0.9, "cwe": "CWE-89", "references": []}]}
 
-	// Send the request
-	client := &http.Client{
-		Timeout: 10 * time.Minute, // Add a 2-minute timeout for scanning large files
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request to OpenAI: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Check for errors
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API returned non-200 status code: %d, body: %s", resp.StatusCode, string(body))
-	}
+If it reported no vulnerabilities, return: {"vulnerabilities": []}
 
-	// Parse the response
-	var openAIResp OpenAIResponsePayload
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("OpenAI API returned no choices")
-	}
-
-	// Extract the content from the response
-	content := openAIResp.Choices[0].Message.Content
-
-	// Try to extract JSON from the content (the model might return markdown or other text)
-	jsonStart := strings.Index(content, "{")
-	jsonEnd := strings.LastIndex(content, "}")
-	if jsonStart >= 0 && jsonEnd >= 0 && jsonEnd > jsonStart {
-		content = content[jsonStart : jsonEnd+1]
-	}
-
-	// Parse the JSON result
-	var result CodeScanResult
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		log.Error("Failed to parse OpenAI response as JSON",
-			zap.String("content", content),
-			zap.Error(err))
-		return &CodeScanResult{Vulnerabilities: []Vulnerability{}}, nil
-	}
-
-	log.Debug("BAML scan completed",
-		zap.String("filepath", filepath),
-		zap.Int("vulnerabilities_found", len(result.Vulnerabilities)))
+Previous reply:
+%s
+`
 
-	return &result, nil
+// FormatScanPrompt fills the scan prompt with one file (or chunk) and the vulnerability types to look for
+func FormatScanPrompt(code, language, filepath string, vulnerabilityTypes []string) string {
+	return fmt.Sprintf(scanPromptTemplate, strings.Join(vulnerabilityTypes, ", "), language, filepath, code)
 }
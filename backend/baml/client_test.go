@@ -0,0 +1,138 @@
+package baml
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordingTransport answers every model request with no findings and keeps the requests it saw
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	body := `{"choices": [{"message": {"role": "assistant", "content": "{\"vulnerabilities\": []}"}}]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// useTransport sends the model requests made during the test to transport
+func useTransport(t *testing.T, transport http.RoundTripper) {
+	t.Helper()
+	previous := http.DefaultTransport
+	http.DefaultTransport = transport
+	t.Cleanup(func() { http.DefaultTransport = previous })
+}
+
+func TestScanCodeSendsAccountHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		project      string
+	}{
+		{name: "neither configured"},
+		{name: "organization only", organization: "org-123"},
+		{name: "project only", project: "proj_456"},
+		{name: "both configured", organization: "org-123", project: "proj_456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_API_KEY", "test-key")
+			t.Setenv("OPENAI_ORG", tt.organization)
+			t.Setenv("OPENAI_PROJECT", tt.project)
+			transport := &recordingTransport{}
+			useTransport(t, transport)
+
+			if _, err := NewCodeScannerClient().ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"}); err != nil {
+				t.Fatalf("ScanCode returned error: %v", err)
+			}
+			if len(transport.requests) != 1 {
+				t.Fatalf("sent %d requests, want 1", len(transport.requests))
+			}
+
+			header := transport.requests[0].Header
+			for name, want := range map[string]string{"OpenAI-Organization": tt.organization, "OpenAI-Project": tt.project} {
+				values := header.Values(name)
+				switch {
+				case want == "" && len(values) > 0:
+					t.Errorf("%s = %q, want it absent", name, values)
+				case want != "" && (len(values) != 1 || values[0] != want):
+					t.Errorf("%s = %q, want %q", name, values, want)
+				}
+			}
+			if got := header.Get("Authorization"); got != "Bearer test-key" {
+				t.Errorf("Authorization = %q, want the API key", got)
+			}
+		})
+	}
+}
+
+func TestRequestTimeoutFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: defaultRequestTimeout},
+		{value: "45s", want: 45 * time.Second},
+		{value: "0", want: defaultRequestTimeout},
+		{value: "-1m", want: defaultRequestTimeout},
+		{value: "soon", want: defaultRequestTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("OPENAI_REQUEST_TIMEOUT", tt.value)
+			if got := NewCodeScannerClient().RequestTimeout(); got != tt.want {
+				t.Errorf("RequestTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanCodeRecordsASpan(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	useTransport(t, &recordingTransport{})
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	client := NewCodeScannerClient()
+	if _, err := client.ScanCode(context.Background(), "package main\n", "Go", "cmd/main.go", []string{"Injection"}); err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "ScanCode" {
+		t.Fatalf("recorded %d spans, want one ScanCode span", len(spans))
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range spans[0].Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	if got := attrs[tracing.FilePathKey].AsString(); got != "cmd/main.go" {
+		t.Errorf("%s = %q, want cmd/main.go", tracing.FilePathKey, got)
+	}
+	if got := attrs[tracing.ModelKey].AsString(); got != client.Model() {
+		t.Errorf("%s = %q, want %q", tracing.ModelKey, got, client.Model())
+	}
+	if got := attrs[tracing.TokenEstimateKey].AsInt64(); got <= 0 {
+		t.Errorf("%s = %d, want a positive estimate", tracing.TokenEstimateKey, got)
+	}
+}
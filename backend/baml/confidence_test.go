@@ -0,0 +1,74 @@
+package baml
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseConfidence(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Confidence
+		wantErr bool
+	}{
+		{value: "low", want: ConfidenceLow},
+		{value: " Medium ", want: ConfidenceMedium},
+		{value: "HIGH", want: ConfidenceHigh},
+		{value: "0.75", want: 0.75},
+		{value: "1", want: 1},
+		{value: "0", want: 0},
+		{value: "85", want: 0.85},
+		{value: "42%", want: 0.42},
+		{value: "0.5%", want: 0.005},
+		{value: "250", want: 1},
+		{value: "-0.2", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "certain", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseConfidence(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseConfidence(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseConfidence(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestScanResultConfidence(t *testing.T) {
+	var result CodeScanResult
+	err := json.Unmarshal([]byte(`{"vulnerabilities": [
+		{"vulnerability_type": "Injection", "confidence": 0.8},
+		{"vulnerability_type": "Injection", "confidence": "High"},
+		{"vulnerability_type": "Injection", "confidence": "90%"},
+		{"vulnerability_type": "Injection", "confidence": "very sure"},
+		{"vulnerability_type": "Injection", "confidence": [1]},
+		{"vulnerability_type": "Injection"}
+	]}`), &result)
+	if err != nil {
+		t.Fatalf("an unreadable confidence failed the result: %v", err)
+	}
+
+	// Unreadable and missing confidences both come out as not given
+	want := []*float64{ptr(0.8), ptr(float64(ConfidenceHigh)), ptr(0.9), nil, nil, nil}
+	if len(result.Vulnerabilities) != len(want) {
+		t.Fatalf("got %d findings, want %d", len(result.Vulnerabilities), len(want))
+	}
+	for i, vuln := range result.Vulnerabilities {
+		got := vuln.Confidence.Value()
+		switch {
+		case want[i] == nil && got != nil:
+			t.Errorf("finding %d confidence = %v, want none", i, *got)
+		case want[i] != nil && (got == nil || *got != *want[i]):
+			t.Errorf("finding %d confidence = %v, want %v", i, got, *want[i])
+		}
+	}
+}
+
+func ptr(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,100 @@
+package baml
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Defaults used when the OPENAI_* settings are unset
+const (
+	defaultMaxTokens   = 4000
+	defaultTemperature = 0.0
+)
+
+// CodeScannerConfig holds the model settings a code scanner client sends with each request
+type CodeScannerConfig struct {
+	Model       string  // OpenAI model scans run with unless a scan requests another
+	MaxTokens   int     // Upper bound on tokens in each response
+	Temperature float64 // Sampling temperature; 0 keeps findings as repeatable as possible
+
+	// Files longer than ChunkLines are scanned as overlapping windows so no part is truncated
+	ChunkLines   int // Lines per chunk
+	ChunkOverlap int // Lines shared by consecutive chunks
+	MaxChunks    int // Chunks scanned per file, bounding the cost of very large files
+
+	// ContextWindow is the tokens one request may hold, prompt and reply together; 0 looks it up from Model
+	ContextWindow int
+
+	Retry    RetryConfig // Retries of rate-limited and failed requests
+	Provider Provider    // LLM to send prompts to; nil selects one from LLM_PROVIDER
+}
+
+// ConfigFromEnv reads the scanner configuration from OPENAI_MODEL, OPENAI_MAX_TOKENS, OPENAI_TEMPERATURE,
+// SCAN_CHUNK_LINES, SCAN_CHUNK_OVERLAP, SCAN_MAX_CHUNKS_PER_FILE, and the OPENAI_* retry settings
+// Unset or invalid values fall back to the defaults
+func ConfigFromEnv() CodeScannerConfig {
+	config := CodeScannerConfig{
+		Model:        ConfiguredModel(),
+		MaxTokens:    defaultMaxTokens,
+		Temperature:  defaultTemperature,
+		ChunkLines:   positiveIntFromEnv("SCAN_CHUNK_LINES", defaultChunkLines),
+		ChunkOverlap: defaultChunkOverlap,
+		MaxChunks:    positiveIntFromEnv("SCAN_MAX_CHUNKS_PER_FILE", defaultMaxChunks),
+		Retry:        retryConfigFromEnv(),
+	}
+
+	// Zero overlap is valid, so this one can't use positiveIntFromEnv
+	if value := os.Getenv("SCAN_CHUNK_OVERLAP"); value != "" {
+		overlap, err := strconv.Atoi(value)
+		if err != nil || overlap < 0 || overlap >= config.ChunkLines {
+			logger.Warn("Invalid SCAN_CHUNK_OVERLAP value, using default",
+				zap.String("value", value),
+				zap.Int("default", defaultChunkOverlap))
+		} else {
+			config.ChunkOverlap = overlap
+		}
+	}
+
+	if value := os.Getenv("OPENAI_MAX_TOKENS"); value != "" {
+		maxTokens, err := strconv.Atoi(value)
+		if err != nil || maxTokens <= 0 {
+			logger.Warn("Invalid OPENAI_MAX_TOKENS value, using default",
+				zap.String("value", value),
+				zap.Int("default", defaultMaxTokens))
+		} else {
+			config.MaxTokens = maxTokens
+		}
+	}
+
+	if value := os.Getenv("OPENAI_TEMPERATURE"); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil || temperature < 0 || temperature > 2 {
+			logger.Warn("Invalid OPENAI_TEMPERATURE value, using default",
+				zap.String("value", value),
+				zap.Float64("default", defaultTemperature))
+		} else {
+			config.Temperature = temperature
+		}
+	}
+
+	return config
+}
+
+// ConfiguredModel returns the model scans use by default
+// With the Anthropic provider it is ANTHROPIC_MODEL or DefaultAnthropicModel; otherwise OPENAI_MODEL or DefaultModel
+func ConfiguredModel() string {
+	if providerName() == ProviderAnthropic {
+		if model := strings.TrimSpace(os.Getenv("ANTHROPIC_MODEL")); model != "" {
+			return model
+		}
+		return DefaultAnthropicModel
+	}
+	if model := strings.TrimSpace(os.Getenv("OPENAI_MODEL")); model != "" {
+		return model
+	}
+	return DefaultModel
+}
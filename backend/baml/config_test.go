@@ -0,0 +1,140 @@
+package baml
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		model       string
+		maxTokens   string
+		temperature string
+		want        CodeScannerConfig
+	}{
+		{
+			name: "unset uses the defaults",
+			want: CodeScannerConfig{Model: DefaultModel, MaxTokens: defaultMaxTokens, Temperature: defaultTemperature},
+		},
+		{
+			name:        "every setting overridden",
+			model:       " gpt-4o-mini ",
+			maxTokens:   "1500",
+			temperature: "0.2",
+			want:        CodeScannerConfig{Model: "gpt-4o-mini", MaxTokens: 1500, Temperature: 0.2},
+		},
+		{
+			name:        "invalid values fall back to the defaults",
+			maxTokens:   "-5",
+			temperature: "3",
+			want:        CodeScannerConfig{Model: DefaultModel, MaxTokens: defaultMaxTokens, Temperature: defaultTemperature},
+		},
+		{
+			name:        "unparsable values fall back to the defaults",
+			maxTokens:   "lots",
+			temperature: "warm",
+			want:        CodeScannerConfig{Model: DefaultModel, MaxTokens: defaultMaxTokens, Temperature: defaultTemperature},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_MODEL", tt.model)
+			t.Setenv("OPENAI_MAX_TOKENS", tt.maxTokens)
+			t.Setenv("OPENAI_TEMPERATURE", tt.temperature)
+
+			got := ConfigFromEnv()
+			if got.Model != tt.want.Model || got.MaxTokens != tt.want.MaxTokens || got.Temperature != tt.want.Temperature {
+				t.Errorf("ConfigFromEnv() = %s, %d max tokens, temperature %v, want %s, %d, %v",
+					got.Model, got.MaxTokens, got.Temperature, tt.want.Model, tt.want.MaxTokens, tt.want.Temperature)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnvReadsChunking(t *testing.T) {
+	tests := []struct {
+		name                              string
+		chunkLines, overlap, maxChunks    string
+		wantLines, wantOverlap, wantChunk int
+	}{
+		{name: "defaults", wantLines: defaultChunkLines, wantOverlap: defaultChunkOverlap, wantChunk: defaultMaxChunks},
+		{name: "overridden", chunkLines: "200", overlap: "0", maxChunks: "4", wantLines: 200, wantOverlap: 0, wantChunk: 4},
+		{name: "overlap as large as the chunk is ignored", chunkLines: "50", overlap: "50", wantLines: 50, wantOverlap: defaultChunkOverlap, wantChunk: defaultMaxChunks},
+		{name: "invalid values use the defaults", chunkLines: "-1", overlap: "some", maxChunks: "0", wantLines: defaultChunkLines, wantOverlap: defaultChunkOverlap, wantChunk: defaultMaxChunks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCAN_CHUNK_LINES", tt.chunkLines)
+			t.Setenv("SCAN_CHUNK_OVERLAP", tt.overlap)
+			t.Setenv("SCAN_MAX_CHUNKS_PER_FILE", tt.maxChunks)
+
+			got := ConfigFromEnv()
+			if got.ChunkLines != tt.wantLines || got.ChunkOverlap != tt.wantOverlap || got.MaxChunks != tt.wantChunk {
+				t.Errorf("chunking = %d lines, %d overlap, %d chunks, want %d, %d, %d",
+					got.ChunkLines, got.ChunkOverlap, got.MaxChunks, tt.wantLines, tt.wantOverlap, tt.wantChunk)
+			}
+		})
+	}
+}
+
+func TestScanCodeSendsConfiguredSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		client func() *CodeScannerClient
+		want   OpenAIRequestPayload
+	}{
+		{
+			name:   "defaults",
+			client: NewCodeScannerClient,
+			want:   OpenAIRequestPayload{Model: DefaultModel, MaxTokens: defaultMaxTokens, Temperature: defaultTemperature},
+		},
+		{
+			name: "explicit config",
+			client: func() *CodeScannerClient {
+				return NewCodeScannerClientWithConfig(CodeScannerConfig{Model: "gpt-4o", MaxTokens: 2000, Temperature: 0.5})
+			},
+			want: OpenAIRequestPayload{Model: "gpt-4o", MaxTokens: 2000, Temperature: 0.5},
+		},
+		{
+			name: "zero config values use the defaults",
+			client: func() *CodeScannerClient {
+				return NewCodeScannerClientWithConfig(CodeScannerConfig{})
+			},
+			want: OpenAIRequestPayload{Model: DefaultModel, MaxTokens: defaultMaxTokens},
+		},
+		{
+			name: "per-scan model override keeps the other settings",
+			client: func() *CodeScannerClient {
+				return NewCodeScannerClientWithConfig(CodeScannerConfig{MaxTokens: 1000, Temperature: 0.1}).WithModel("gpt-4o-mini")
+			},
+			want: OpenAIRequestPayload{Model: "gpt-4o-mini", MaxTokens: 1000, Temperature: 0.1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_API_KEY", "test-key")
+			t.Setenv("OPENAI_MODEL", "")
+			t.Setenv("OPENAI_MAX_TOKENS", "")
+			t.Setenv("OPENAI_TEMPERATURE", "")
+			transport := &recordingTransport{}
+			useTransport(t, transport)
+
+			if _, err := tt.client().ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"}); err != nil {
+				t.Fatalf("ScanCode returned error: %v", err)
+			}
+			if len(transport.requests) != 1 {
+				t.Fatalf("sent %d requests, want 1", len(transport.requests))
+			}
+
+			payload := requestPayload(t, transport.requests[0])
+			if payload.Model != tt.want.Model || payload.MaxTokens != tt.want.MaxTokens || payload.Temperature != tt.want.Temperature {
+				t.Errorf("sent model %s, max_tokens %d, temperature %v, want %s, %d, %v",
+					payload.Model, payload.MaxTokens, payload.Temperature, tt.want.Model, tt.want.MaxTokens, tt.want.Temperature)
+			}
+		})
+	}
+}
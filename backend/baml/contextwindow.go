@@ -0,0 +1,104 @@
+package baml
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultContextWindow is the context window assumed for models missing from the table
+// It is the smallest window of the supported model families, so an unknown model gets requests
+// it accepts rather than ones it rejects
+const DefaultContextWindow = 8192
+
+// modelContextWindows maps model name prefixes to the tokens one request may hold, prompt and reply together
+// The longest matching prefix wins, so "gpt-4o" is not read as "gpt-4"
+var modelContextWindows = map[string]int{
+	"gpt-4o":            128000,
+	"gpt-4.1":           1047576,
+	"gpt-4-turbo":       128000,
+	"gpt-4-1106":        128000,
+	"gpt-4-0125":        128000,
+	"gpt-4-32k":         32768,
+	"gpt-4":             8192,
+	"gpt-3.5-turbo":     16385,
+	"gpt-3.5-turbo-16k": 16385,
+	"o1":                200000,
+	"o3":                200000,
+	"o4-mini":           200000,
+	"claude-":           200000,
+}
+
+// ErrPromptTooLarge is returned for a prompt that, with room for the reply, doesn't fit the model's context window
+var ErrPromptTooLarge = errors.New("prompt exceeds the model's context window")
+
+// EstimateTokens approximates the prompt tokens for a piece of text (about four bytes per token)
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// ModelEnvSuffix turns a model name into an environment variable suffix: upper case,
+// with non-alphanumerics as underscores, e.g. GPT_4_TURBO for gpt-4-turbo
+func ModelEnvSuffix(model string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(model) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// ContextWindowForModel returns how many tokens one request to the model may hold
+// MODEL_CONTEXT_WINDOW_<MODEL> (e.g. MODEL_CONTEXT_WINDOW_GPT_4O) overrides the table for one model and
+// MODEL_CONTEXT_WINDOW for every model; models in neither get DefaultContextWindow
+func ContextWindowForModel(model string) int {
+	for _, key := range []string{"MODEL_CONTEXT_WINDOW_" + ModelEnvSuffix(model), "MODEL_CONTEXT_WINDOW"} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		window, err := strconv.Atoi(value)
+		if err != nil || window <= 0 {
+			logger.Warn("Invalid "+key+" value, ignoring it", zap.String("value", value))
+			continue
+		}
+		return window
+	}
+
+	model = strings.ToLower(strings.TrimSpace(model))
+	window, matched := DefaultContextWindow, 0
+	for prefix, tokens := range modelContextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > matched {
+			window, matched = tokens, len(prefix)
+		}
+	}
+	return window
+}
+
+// promptTokenBudget is how many prompt tokens fit in a context window once the reply's tokens are set aside
+func promptTokenBudget(contextWindow, maxTokens int) int {
+	return max(contextWindow-maxTokens, 0)
+}
+
+// fittingChunkLines returns how many lines of code a chunk may have so its prompt fits in budget tokens
+// overhead is the estimated tokens of the prompt without any code. The result is at most chunkLines,
+// sized by the code's average line; 0 means not even one line of it fits
+func fittingChunkLines(code string, chunkLines, budget, overhead int) int {
+	available := budget - overhead
+	if available <= 0 {
+		return 0
+	}
+	codeTokens := EstimateTokens(code)
+	if codeTokens <= available {
+		return chunkLines
+	}
+	lines := strings.Count(code, "\n") + 1
+	return min(available*lines/codeTokens, chunkLines)
+}
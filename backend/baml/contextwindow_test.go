@@ -0,0 +1,175 @@
+package baml
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestContextWindowForModel(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		env   map[string]string
+		want  int
+	}{
+		{name: "gpt-4o", model: "gpt-4o", want: 128000},
+		{name: "gpt-4o snapshot is not read as gpt-4", model: "gpt-4o-mini-2024-07-18", want: 128000},
+		{name: "gpt-4 turbo", model: "gpt-4-turbo", want: 128000},
+		{name: "original gpt-4", model: "gpt-4-0613", want: 8192},
+		{name: "anthropic", model: "claude-3-5-sonnet-latest", want: 200000},
+		{name: "case and spaces ignored", model: " GPT-4o ", want: 128000},
+		{name: "unknown model gets the conservative default", model: "my-local-model", want: DefaultContextWindow},
+		{
+			name:  "global override",
+			model: "gpt-4o",
+			env:   map[string]string{"MODEL_CONTEXT_WINDOW": "32000"},
+			want:  32000,
+		},
+		{
+			name:  "model override wins over global",
+			model: "my-local-model",
+			env:   map[string]string{"MODEL_CONTEXT_WINDOW": "32000", "MODEL_CONTEXT_WINDOW_MY_LOCAL_MODEL": "65536"},
+			want:  65536,
+		},
+		{
+			name:  "invalid override is ignored",
+			model: "gpt-4o",
+			env:   map[string]string{"MODEL_CONTEXT_WINDOW": "lots"},
+			want:  128000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MODEL_CONTEXT_WINDOW", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if got := ContextWindowForModel(tt.model); got != tt.want {
+				t.Errorf("ContextWindowForModel(%q) = %d, want %d", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+// sourceLines returns n lines of code, each about width bytes long
+func sourceLines(n, width int) string {
+	line := "x := compute(" + strings.Repeat("a", width-15) + ")"
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestScanCodeSizesChunksToContextWindow(t *testing.T) {
+	tests := []struct {
+		name          string
+		model         string
+		code          string
+		wantTooLarge  bool
+		wantUnchunked bool
+	}{
+		{
+			name:          "large window sends a file under the chunk size whole",
+			model:         "gpt-4o",
+			code:          sourceLines(300, 80),
+			wantUnchunked: true,
+		},
+		{
+			name:  "small window chunks the same file",
+			model: "gpt-4",
+			code:  sourceLines(300, 80),
+		},
+		{
+			name:          "large window sends a single huge line",
+			model:         "gpt-4o",
+			code:          sourceLines(1, 40000),
+			wantUnchunked: true,
+		},
+		{
+			name:         "small window refuses a single huge line",
+			model:        "gpt-4",
+			code:         sourceLines(1, 40000),
+			wantTooLarge: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_API_KEY", "test-key")
+			t.Setenv("MODEL_CONTEXT_WINDOW", "")
+			transport := &recordingTransport{}
+			useTransport(t, transport)
+			client := NewCodeScannerClientWithConfig(CodeScannerConfig{Model: tt.model, MaxTokens: 4000})
+
+			_, err := client.ScanCode(context.Background(), tt.code, "Go", "main.go", []string{"Injection"})
+			if tt.wantTooLarge {
+				if !errors.Is(err, ErrPromptTooLarge) {
+					t.Fatalf("ScanCode error = %v, want ErrPromptTooLarge", err)
+				}
+				if len(transport.requests) != 0 {
+					t.Errorf("sent %d requests for code that doesn't fit, want none", len(transport.requests))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ScanCode returned error: %v", err)
+			}
+
+			if tt.wantUnchunked && len(transport.requests) != 1 {
+				t.Errorf("sent %d requests, want the file in one", len(transport.requests))
+			}
+			if !tt.wantUnchunked && len(transport.requests) < 2 {
+				t.Errorf("sent %d requests, want the file in chunks", len(transport.requests))
+			}
+
+			budget := promptTokenBudget(client.ContextWindow(), 4000)
+			for i, req := range transport.requests {
+				tokens := 0
+				for _, message := range requestPayload(t, req).Messages {
+					tokens += EstimateTokens(message.Content)
+				}
+				if tokens > budget {
+					t.Errorf("request %d has about %d prompt tokens, over the %d token budget", i, tokens, budget)
+				}
+			}
+		})
+	}
+}
+
+func TestFittingChunkLines(t *testing.T) {
+	code := sourceLines(100, 40) // About 1000 tokens, 10 per line
+
+	tests := []struct {
+		name     string
+		budget   int
+		overhead int
+		want     int
+	}{
+		{name: "code fits whole keeps the configured size", budget: 5000, overhead: 500, want: 400},
+		{name: "tight budget shrinks chunks", budget: 1000, overhead: 500, want: 50},
+		{name: "no room for code", budget: 500, overhead: 500, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fittingChunkLines(code, 400, tt.budget, tt.overhead); got != tt.want {
+				t.Errorf("fittingChunkLines = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithModelResizesContextWindow(t *testing.T) {
+	t.Setenv("MODEL_CONTEXT_WINDOW", "")
+	client := NewCodeScannerClient().WithModel("gpt-4")
+	if got := client.ContextWindow(); got != 8192 {
+		t.Fatalf("gpt-4 client context window = %d, want 8192", got)
+	}
+	if got := client.WithModel("gpt-4o").ContextWindow(); got != 128000 {
+		t.Errorf("WithModel(gpt-4o) context window = %d, want 128000", got)
+	}
+}
@@ -0,0 +1,123 @@
+package baml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidScanResult is returned when a model reply can't be read as a scan result, even after repair
+// It is distinct from a reply that lists no vulnerabilities, so callers can mark the file as errored
+var ErrInvalidScanResult = errors.New("invalid scan result")
+
+// scanResultSeverities are the severities a finding may have, lower-cased; "moderate" is read as Medium
+var scanResultSeverities = map[string]bool{"critical": true, "high": true, "medium": true, "moderate": true, "low": true}
+
+// markdownFence matches a markdown code fence line, with or without a language tag
+var markdownFence = regexp.MustCompile("(?m)^[ \t]*```[a-zA-Z]*[ \t]*$")
+
+// trailingComma matches a comma left before a closing bracket, which JSON doesn't allow
+var trailingComma = regexp.MustCompile(`,\s*([}\]])`)
+
+// ParseScanResult reads the findings from a model reply and checks them against the scan result schema
+// Models sometimes wrap the JSON in markdown or prose, so only the outermost object is parsed; a reply that
+// doesn't parse or validate is repaired once, by dropping markdown fences and trailing commas, before it is
+// rejected with an error wrapping ErrInvalidScanResult
+func ParseScanResult(content string) (*CodeScanResult, error) {
+	result, err := parseScanJSON(outermostObject(content))
+	if err == nil {
+		return result, nil
+	}
+	if repaired, repairErr := parseScanJSON(RepairScanJSON(content)); repairErr == nil {
+		return repaired, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrInvalidScanResult, err)
+}
+
+// RepairScanJSON fixes the mistakes models commonly make when replying with JSON: markdown fences around
+// it and trailing commas in its objects and arrays. The outermost object of what is left is returned
+func RepairScanJSON(content string) string {
+	content = markdownFence.ReplaceAllString(content, "")
+	content = outermostObject(content)
+	return trailingComma.ReplaceAllString(content, "$1")
+}
+
+// outermostObject returns content from its first "{" to its last "}", or content unchanged if it has no object
+func outermostObject(content string) string {
+	jsonStart := strings.Index(content, "{")
+	jsonEnd := strings.LastIndex(content, "}")
+	if jsonStart >= 0 && jsonEnd > jsonStart {
+		return content[jsonStart : jsonEnd+1]
+	}
+	return content
+}
+
+// parseScanJSON validates a JSON scan result and decodes it
+func parseScanJSON(content string) (*CodeScanResult, error) {
+	if err := ValidateScanResult([]byte(content)); err != nil {
+		return nil, err
+	}
+
+	var result CodeScanResult
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse scan result: %w", err)
+	}
+	if result.Vulnerabilities == nil {
+		result.Vulnerabilities = []Vulnerability{}
+	}
+	return &result, nil
+}
+
+// ValidateScanResult checks a JSON scan result has the shape the scan prompt asks for: a "vulnerabilities"
+// array whose findings each have a vulnerability_type, integer line_start and line_end, and a severity of
+// Critical, High, Medium, or Low. Other fields are optional and read leniently
+func ValidateScanResult(data []byte) error {
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("failed to parse scan result: %w", err)
+	}
+
+	raw, ok := result["vulnerabilities"]
+	if !ok {
+		return fmt.Errorf(`scan result has no "vulnerabilities" field`)
+	}
+	var findings []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &findings); err != nil || findings == nil {
+		return fmt.Errorf(`"vulnerabilities" must be an array of objects`)
+	}
+
+	for i, finding := range findings {
+		if err := validateFinding(finding); err != nil {
+			return fmt.Errorf("vulnerabilities[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateFinding checks one finding has its required fields with the right types
+func validateFinding(finding map[string]json.RawMessage) error {
+	var vulnType string
+	if err := json.Unmarshal(finding["vulnerability_type"], &vulnType); err != nil || strings.TrimSpace(vulnType) == "" {
+		return fmt.Errorf("vulnerability_type must be a non-empty string")
+	}
+
+	for _, field := range []string{"line_start", "line_end"} {
+		line, err := strconv.Atoi(string(bytes.TrimSpace(finding[field])))
+		if err != nil || line < 0 {
+			return fmt.Errorf("%s must be a non-negative integer", field)
+		}
+	}
+
+	var severity string
+	if err := json.Unmarshal(finding["severity"], &severity); err != nil {
+		return fmt.Errorf("severity must be a string")
+	}
+	if !scanResultSeverities[strings.ToLower(strings.TrimSpace(severity))] {
+		return fmt.Errorf("severity %q is not one of Critical, High, Medium, Low", severity)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package baml
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// replyQueueProvider answers each prompt with the next of its replies and keeps the prompts it was sent
+type replyQueueProvider struct {
+	replies []string
+	prompts []Prompt
+}
+
+func (r *replyQueueProvider) Name() string {
+	return "reply-queue"
+}
+
+func (r *replyQueueProvider) ScanCode(ctx context.Context, prompt Prompt) (string, error) {
+	r.prompts = append(r.prompts, prompt)
+	reply := r.replies[0]
+	if len(r.replies) > 1 {
+		r.replies = r.replies[1:]
+	}
+	return reply, nil
+}
+
+func TestParseScanResult(t *testing.T) {
+	finding := `{"vulnerability_type": "Injection", "line_start": 3, "line_end": 4, "severity": "high"}`
+
+	tests := []struct {
+		name     string
+		content  string
+		findings int
+		invalid  bool
+	}{
+		{name: "valid", content: `{"vulnerabilities": [` + finding + `]}`, findings: 1},
+		{name: "no vulnerabilities", content: `{"vulnerabilities": []}`, findings: 0},
+		{name: "markdown wrapped", content: "Here is what I found:\n```json\n{\"vulnerabilities\": [" + finding + "]}\n```", findings: 1},
+		{name: "trailing commas", content: "```\n{\"vulnerabilities\": [" + finding + ",],}\n```", findings: 1},
+		{name: "moderate severity", content: `{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "Moderate"}]}`, findings: 1},
+		{name: "truncated", content: `{"vulnerabilities": [` + finding + `, {"vulnerability_type": "Inj`, invalid: true},
+		{name: "prose only", content: "I could not find any issues in this file.", invalid: true},
+		{name: "missing vulnerabilities", content: `{"findings": []}`, invalid: true},
+		{name: "vulnerabilities not an array", content: `{"vulnerabilities": "none"}`, invalid: true},
+		{name: "missing type", content: `{"vulnerabilities": [{"line_start": 1, "line_end": 1, "severity": "High"}]}`, invalid: true},
+		{name: "line as string", content: `{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": "3", "line_end": 4, "severity": "High"}]}`, invalid: true},
+		{name: "fractional line", content: `{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": 3.5, "line_end": 4, "severity": "High"}]}`, invalid: true},
+		{name: "missing line", content: `{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": 3, "severity": "High"}]}`, invalid: true},
+		{name: "unknown severity", content: `{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": 3, "line_end": 4, "severity": "Severe"}]}`, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseScanResult(tt.content)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidScanResult) {
+					t.Fatalf("ParseScanResult error = %v, want ErrInvalidScanResult", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseScanResult returned error: %v", err)
+			}
+			// A reply without findings is an empty list, never nil, so it can't be mistaken for a failure
+			if result.Vulnerabilities == nil || len(result.Vulnerabilities) != tt.findings {
+				t.Errorf("findings = %+v, want %d", result.Vulnerabilities, tt.findings)
+			}
+		})
+	}
+}
+
+func TestScanCodeAsksTheModelToReformatAnUnreadableReply(t *testing.T) {
+	broken := `{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": "three", "severity": "High"}]}`
+	provider := &replyQueueProvider{replies: []string{
+		broken,
+		`{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": 3, "line_end": 3, "severity": "High"}]}`,
+	}}
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Model: "gpt-4o", MaxTokens: 500, Provider: provider})
+
+	result, err := client.ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"})
+	if err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+	if len(result.Vulnerabilities) != 1 || result.Vulnerabilities[0].LineStart != 3 {
+		t.Errorf("findings = %+v, want the one from the reformatted reply", result.Vulnerabilities)
+	}
+
+	if len(provider.prompts) != 2 {
+		t.Fatalf("sent %d prompts, want the scan and one reformat request", len(provider.prompts))
+	}
+	if reformat := provider.prompts[1].User; !strings.Contains(reformat, broken) || !strings.Contains(reformat, "line_start") {
+		t.Errorf("reformat prompt = %q, want the unreadable reply and what was wrong with it", reformat)
+	}
+}
+
+func TestScanCodeReportsAReplyThatStaysUnreadable(t *testing.T) {
+	provider := &replyQueueProvider{replies: []string{`{"vulnerabilities": [{"vulnerability_type": "Injection",`}}
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Model: "gpt-4o", MaxTokens: 500, Provider: provider})
+
+	result, err := client.ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"})
+	if !errors.Is(err, ErrInvalidScanResult) {
+		t.Fatalf("ScanCode = %+v, %v, want ErrInvalidScanResult rather than an empty result", result, err)
+	}
+	if len(provider.prompts) != 2 {
+		t.Errorf("sent %d prompts, want the scan and a single reformat request", len(provider.prompts))
+	}
+}
@@ -0,0 +1,269 @@
+package baml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// LLM providers selectable with LLM_PROVIDER
+const (
+	ProviderOpenAI           = "openai"            // api.openai.com (default)
+	ProviderOpenAICompatible = "openai-compatible" // Self-hosted or proxy endpoint speaking the OpenAI chat API at OPENAI_BASE_URL
+	ProviderAnthropic        = "anthropic"         // Anthropic Messages API
+)
+
+// DefaultAnthropicModel is the model used with the Anthropic provider when ANTHROPIC_MODEL is unset
+const DefaultAnthropicModel = "claude-3-5-sonnet-latest"
+
+const (
+	defaultOpenAIBaseURL    = "https://api.openai.com/v1"
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	anthropicAPIVersion     = "2023-06-01"
+)
+
+// Prompt is one scan request as sent to a provider
+type Prompt struct {
+	Model       string
+	System      string // Instructions that frame the request
+	User        string // The scan prompt with the code
+	MaxTokens   int
+	Temperature float64
+}
+
+// Provider sends prompts to an LLM and returns its text reply
+// The reply is parsed into findings by the client, so parsing is the same for every provider
+type Provider interface {
+	// Name identifies the provider in logs and errors
+	Name() string
+
+	// ScanCode sends the prompt and returns the model's reply text
+	ScanCode(ctx context.Context, prompt Prompt) (string, error)
+}
+
+// providerName returns the configured LLM_PROVIDER, defaulting to OpenAI
+func providerName() string {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER")))
+	switch name {
+	case "":
+		return ProviderOpenAI
+	case ProviderOpenAI, ProviderOpenAICompatible, ProviderAnthropic:
+		return name
+	default:
+		logger.Warn("Unknown LLM_PROVIDER, using OpenAI", zap.String("value", name))
+		return ProviderOpenAI
+	}
+}
+
+// NewProviderFromEnv builds the provider selected by LLM_PROVIDER
+// Each request is bounded by timeout and retried according to retry
+func NewProviderFromEnv(timeout time.Duration, retry RetryConfig) Provider {
+	retry = retry.withDefaults()
+
+	switch providerName() {
+	case ProviderAnthropic:
+		apiKey := os.Getenv("ANTHROPIC_API_KEY")
+		if apiKey == "" {
+			logger.Warn("ANTHROPIC_API_KEY environment variable not set, scans will fail")
+		}
+		return &anthropicProvider{
+			baseURL: baseURLFromEnv("ANTHROPIC_BASE_URL", defaultAnthropicBaseURL),
+			apiKey:  apiKey,
+			client:  newHTTPClient(timeout),
+			retry:   retry,
+		}
+
+	case ProviderOpenAICompatible:
+		baseURL := strings.TrimRight(os.Getenv("OPENAI_BASE_URL"), "/")
+		if baseURL == "" {
+			logger.Warn("LLM_PROVIDER is openai-compatible but OPENAI_BASE_URL is not set, scans will fail")
+		}
+		// Self-hosted endpoints often need no key, so a missing one is not an error here
+		return &openAIProvider{
+			name:         ProviderOpenAICompatible,
+			baseURL:      baseURL,
+			apiKey:       os.Getenv("OPENAI_API_KEY"),
+			organization: os.Getenv("OPENAI_ORG"),
+			project:      os.Getenv("OPENAI_PROJECT"),
+			client:       newHTTPClient(timeout),
+			retry:        retry,
+		}
+
+	default:
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			logger.Warn("OPENAI_API_KEY environment variable not set, BAML scans will fail")
+		}
+		return &openAIProvider{
+			name:         ProviderOpenAI,
+			baseURL:      baseURLFromEnv("OPENAI_BASE_URL", defaultOpenAIBaseURL),
+			apiKey:       apiKey,
+			requireKey:   true,
+			organization: os.Getenv("OPENAI_ORG"),
+			project:      os.Getenv("OPENAI_PROJECT"),
+			client:       newHTTPClient(timeout),
+			retry:        retry,
+		}
+	}
+}
+
+// baseURLFromEnv reads an API base URL, without a trailing slash
+func baseURLFromEnv(name, def string) string {
+	if value := strings.TrimRight(strings.TrimSpace(os.Getenv(name)), "/"); value != "" {
+		return value
+	}
+	return def
+}
+
+// openAIProvider talks to the OpenAI chat completions API or an endpoint compatible with it
+type openAIProvider struct {
+	name         string
+	baseURL      string // e.g. https://api.openai.com/v1
+	apiKey       string
+	requireKey   bool   // The official API rejects keyless requests, so fail before sending one
+	organization string // Optional OpenAI-Organization header for billing attribution
+	project      string // Optional OpenAI-Project header for access scoping
+	client       *http.Client
+	retry        RetryConfig
+}
+
+func (p *openAIProvider) Name() string {
+	return p.name
+}
+
+func (p *openAIProvider) ScanCode(ctx context.Context, prompt Prompt) (string, error) {
+	if p.requireKey && p.apiKey == "" {
+		return "", fmt.Errorf("OpenAI API key not set")
+	}
+	if p.baseURL == "" {
+		return "", fmt.Errorf("OPENAI_BASE_URL not set for the %s provider", p.name)
+	}
+
+	payloadBytes, err := json.Marshal(OpenAIRequestPayload{
+		Model: prompt.Model,
+		Messages: []Message{
+			{Role: "system", Content: prompt.System},
+			{Role: "user", Content: prompt.User},
+		},
+		Temperature: prompt.Temperature,
+		MaxTokens:   prompt.MaxTokens,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	body, err := postWithRetry(ctx, p.name, p.client, p.retry, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(payloadBytes))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if p.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		}
+		// Account headers are only sent when configured so single-project accounts keep the default behavior
+		if p.organization != "" {
+			req.Header.Set("OpenAI-Organization", p.organization)
+		}
+		if p.project != "" {
+			req.Header.Set("OpenAI-Project", p.project)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var response OpenAIResponsePayload
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("%s API returned no choices", p.name)
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// anthropicRequestPayload is a request to the Anthropic Messages API
+type anthropicRequestPayload struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+}
+
+// anthropicResponsePayload is the part of an Anthropic Messages API response the scanner reads
+type anthropicResponsePayload struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// anthropicProvider talks to the Anthropic Messages API
+type anthropicProvider struct {
+	baseURL string // e.g. https://api.anthropic.com/v1
+	apiKey  string
+	client  *http.Client
+	retry   RetryConfig
+}
+
+func (p *anthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+func (p *anthropicProvider) ScanCode(ctx context.Context, prompt Prompt) (string, error) {
+	if p.apiKey == "" {
+		return "", fmt.Errorf("Anthropic API key not set")
+	}
+
+	payloadBytes, err := json.Marshal(anthropicRequestPayload{
+		Model:       prompt.Model,
+		System:      prompt.System,
+		Messages:    []Message{{Role: "user", Content: prompt.User}},
+		MaxTokens:   prompt.MaxTokens,
+		Temperature: prompt.Temperature,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	body, err := postWithRetry(ctx, ProviderAnthropic, p.client, p.retry, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/messages", bytes.NewReader(payloadBytes))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", p.apiKey)
+		req.Header.Set("anthropic-version", anthropicAPIVersion)
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var response anthropicResponsePayload
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("anthropic API returned no text content")
+	}
+	return text.String(), nil
+}
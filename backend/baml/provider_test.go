@@ -0,0 +1,232 @@
+package baml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// recordingProvider answers every prompt with reply and keeps the prompts it was sent
+type recordingProvider struct {
+	reply   string
+	err     error
+	prompts []Prompt
+}
+
+func (r *recordingProvider) Name() string {
+	return "recording"
+}
+
+func (r *recordingProvider) ScanCode(ctx context.Context, prompt Prompt) (string, error) {
+	r.prompts = append(r.prompts, prompt)
+	if r.err != nil {
+		return "", r.err
+	}
+	if r.reply == "" {
+		return `{"vulnerabilities": []}`, nil
+	}
+	return r.reply, nil
+}
+
+// capturedRequest is what a fake provider server saw of one request
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// newProviderServer starts a fake provider API that answers every request with reply
+func newProviderServer(t *testing.T, reply string) (*httptest.Server, *[]capturedRequest) {
+	t.Helper()
+	var requests []capturedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, capturedRequest{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	server, requests := newProviderServer(t,
+		`{"choices": [{"message": {"role": "assistant", "content": "{\"vulnerabilities\": []}"}}]}`)
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("OPENAI_BASE_URL", server.URL+"/")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_ORG", "org-123")
+	t.Setenv("OPENAI_PROJECT", "")
+
+	provider := NewProviderFromEnv(time.Second, RetryConfig{})
+	reply, err := provider.ScanCode(context.Background(), Prompt{Model: "gpt-4o", System: "be careful", User: "scan this", MaxTokens: 100})
+	if err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+	if reply != `{"vulnerabilities": []}` {
+		t.Errorf("reply = %q, want the message content", reply)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.path != "/chat/completions" {
+		t.Errorf("path = %s, want /chat/completions", req.path)
+	}
+	if got := req.header.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("Authorization = %q, want the API key", got)
+	}
+	if got := req.header.Get("OpenAI-Organization"); got != "org-123" {
+		t.Errorf("OpenAI-Organization = %q, want org-123", got)
+	}
+	if _, ok := req.header["Openai-Project"]; ok {
+		t.Error("OpenAI-Project sent without being configured")
+	}
+	messages, _ := req.body["messages"].([]any)
+	if req.body["model"] != "gpt-4o" || len(messages) != 2 {
+		t.Errorf("body = %v, want gpt-4o with a system and a user message", req.body)
+	}
+}
+
+func TestOpenAICompatibleProviderNeedsNoKey(t *testing.T) {
+	server, requests := newProviderServer(t,
+		`{"choices": [{"message": {"role": "assistant", "content": "no findings"}}]}`)
+	t.Setenv("LLM_PROVIDER", "openai-compatible")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+	t.Setenv("OPENAI_API_KEY", "")
+
+	provider := NewProviderFromEnv(time.Second, RetryConfig{})
+	if provider.Name() != ProviderOpenAICompatible {
+		t.Errorf("provider = %s, want %s", provider.Name(), ProviderOpenAICompatible)
+	}
+	if _, err := provider.ScanCode(context.Background(), Prompt{Model: "llama3", User: "scan this"}); err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+	if len(*requests) != 1 || (*requests)[0].header.Get("Authorization") != "" {
+		t.Errorf("requests = %+v, want one without an Authorization header", *requests)
+	}
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	server, requests := newProviderServer(t,
+		`{"content": [{"type": "text", "text": "{\"vulnerabilities\":"}, {"type": "tool_use"}, {"type": "text", "text": " []}"}]}`)
+	t.Setenv("LLM_PROVIDER", "anthropic")
+	t.Setenv("ANTHROPIC_BASE_URL", server.URL)
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+
+	provider := NewProviderFromEnv(time.Second, RetryConfig{})
+	reply, err := provider.ScanCode(context.Background(), Prompt{Model: DefaultAnthropicModel, System: "be careful", User: "scan this", MaxTokens: 100})
+	if err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+	if reply != `{"vulnerabilities": []}` {
+		t.Errorf("reply = %q, want the text blocks joined", reply)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.path != "/messages" {
+		t.Errorf("path = %s, want /messages", req.path)
+	}
+	if req.header.Get("x-api-key") != "sk-ant-test" || req.header.Get("anthropic-version") != anthropicAPIVersion {
+		t.Errorf("headers = %v, want the API key and version", req.header)
+	}
+	// The system prompt is a top-level field; only the user turn is a message
+	messages, _ := req.body["messages"].([]any)
+	if req.body["system"] != "be careful" || len(messages) != 1 {
+		t.Errorf("body = %v, want the system prompt apart from one user message", req.body)
+	}
+}
+
+func TestProvidersRefuseToSendWithoutAKey(t *testing.T) {
+	for _, name := range []string{ProviderOpenAI, ProviderAnthropic} {
+		t.Run(name, func(t *testing.T) {
+			server, requests := newProviderServer(t, `{}`)
+			t.Setenv("LLM_PROVIDER", name)
+			t.Setenv("OPENAI_BASE_URL", server.URL)
+			t.Setenv("ANTHROPIC_BASE_URL", server.URL)
+			t.Setenv("OPENAI_API_KEY", "")
+			t.Setenv("ANTHROPIC_API_KEY", "")
+
+			if _, err := NewProviderFromEnv(time.Second, RetryConfig{}).ScanCode(context.Background(), Prompt{User: "scan this"}); err == nil {
+				t.Error("ScanCode succeeded without an API key")
+			}
+			if len(*requests) != 0 {
+				t.Errorf("sent %d requests without an API key, want none", len(*requests))
+			}
+		})
+	}
+}
+
+func TestProviderName(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                  ProviderOpenAI,
+		"OpenAI":            ProviderOpenAI,
+		" anthropic ":       ProviderAnthropic,
+		"openai-compatible": ProviderOpenAICompatible,
+		"gemini":            ProviderOpenAI,
+	} {
+		t.Setenv("LLM_PROVIDER", value)
+		if got := providerName(); got != want {
+			t.Errorf("providerName() with LLM_PROVIDER=%q = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestConfiguredModelFollowsTheProvider(t *testing.T) {
+	t.Setenv("OPENAI_MODEL", "gpt-4o")
+	t.Setenv("ANTHROPIC_MODEL", "")
+
+	t.Setenv("LLM_PROVIDER", "anthropic")
+	if got := ConfiguredModel(); got != DefaultAnthropicModel {
+		t.Errorf("ConfiguredModel() = %s with Anthropic, want %s", got, DefaultAnthropicModel)
+	}
+	t.Setenv("ANTHROPIC_MODEL", "claude-3-opus-latest")
+	if got := ConfiguredModel(); got != "claude-3-opus-latest" {
+		t.Errorf("ConfiguredModel() = %s, want ANTHROPIC_MODEL", got)
+	}
+	t.Setenv("LLM_PROVIDER", "openai")
+	if got := ConfiguredModel(); got != "gpt-4o" {
+		t.Errorf("ConfiguredModel() = %s with OpenAI, want OPENAI_MODEL", got)
+	}
+}
+
+func TestScanCodeParsesEveryProvidersReply(t *testing.T) {
+	provider := &recordingProvider{
+		reply: "Here is what I found:\n```json\n{\"vulnerabilities\": [{\"vulnerability_type\": \"Injection\", \"line_start\": 3, \"line_end\": 4, \"severity\": \"High\"}]}\n```",
+	}
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Model: "claude-3-5-sonnet-latest", MaxTokens: 500, Provider: provider})
+
+	result, err := client.ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"})
+	if err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+	if len(result.Vulnerabilities) != 1 || result.Vulnerabilities[0].LineStart != 3 {
+		t.Errorf("findings = %+v, want the one in the fenced JSON", result.Vulnerabilities)
+	}
+
+	if len(provider.prompts) != 1 {
+		t.Fatalf("sent %d prompts, want 1", len(provider.prompts))
+	}
+	prompt := provider.prompts[0]
+	if prompt.Model != "claude-3-5-sonnet-latest" || prompt.MaxTokens != 500 || prompt.System != ScanSystemPrompt {
+		t.Errorf("prompt = %+v, want the client's model, max tokens, and system prompt", prompt)
+	}
+
+	provider.err = errors.New("provider unavailable")
+	if _, err := client.ScanCode(context.Background(), "package main\n", "Go", "main.go", nil); !errors.Is(err, provider.err) {
+		t.Errorf("ScanCode error = %v, want the provider's error", err)
+	}
+}
@@ -0,0 +1,97 @@
+package baml
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cwePattern matches a CWE identifier as models write it: "CWE-89", "cwe 89", "CWE89", or a bare "89",
+// optionally followed by the weakness name
+var cwePattern = regexp.MustCompile(`(?i)^cwe[\s_-]*(\d{1,5})\b|^(\d{1,5})$`)
+
+// ParseCWE reads a CWE identifier and returns it in canonical form, e.g. "CWE-89"
+func ParseCWE(value string) (string, error) {
+	match := cwePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return "", fmt.Errorf("invalid CWE %q: must look like CWE-89", value)
+	}
+	id := match[1] + match[2]
+	number, err := strconv.Atoi(id)
+	if err != nil || number == 0 {
+		return "", fmt.Errorf("invalid CWE %q: must look like CWE-89", value)
+	}
+	return "CWE-" + strconv.Itoa(number), nil
+}
+
+// CWE is the weakness a finding is an instance of, in canonical form such as "CWE-89"
+type CWE string
+
+// UnmarshalJSON accepts an identifier string or a bare number; an unreadable value is treated as not
+// given rather than failing the whole scan result
+func (c *CWE) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var parsed string
+	var err error
+	switch value := raw.(type) {
+	case float64:
+		parsed, err = ParseCWE(strconv.FormatFloat(value, 'f', -1, 64))
+	case string:
+		parsed, err = ParseCWE(value)
+	default:
+		err = fmt.Errorf("unsupported CWE %s", data)
+	}
+	if err != nil {
+		*c = ""
+		return nil
+	}
+	*c = CWE(parsed)
+	return nil
+}
+
+// References are links a finding cites, such as its OWASP or CWE pages
+type References []string
+
+// UnmarshalJSON accepts a list of URLs or a single one, keeping only absolute http(s) URLs once each
+// Models sometimes cite a document by name instead of linking it, which would render as a broken link
+func (r *References) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var values []any
+	switch value := raw.(type) {
+	case []any:
+		values = value
+	case string:
+		values = []any{value}
+	}
+
+	references := References{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		reference, ok := value.(string)
+		reference = strings.TrimSpace(reference)
+		if !ok || !isReferenceURL(reference) || seen[reference] {
+			continue
+		}
+		seen[reference] = true
+		references = append(references, reference)
+	}
+	*r = references
+	return nil
+}
+
+// isReferenceURL reports whether the value is an absolute http or https URL
+func isReferenceURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
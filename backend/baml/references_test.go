@@ -0,0 +1,75 @@
+package baml
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCWE(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "CWE-89", want: "CWE-89"},
+		{value: " cwe-79 ", want: "CWE-79"},
+		{value: "CWE 22", want: "CWE-22"},
+		{value: "CWE_918", want: "CWE-918"},
+		{value: "CWE89", want: "CWE-89"},
+		{value: "CWE-89: SQL Injection", want: "CWE-89"},
+		{value: "89", want: "CWE-89"},
+		{value: "CWE-0089", want: "CWE-89"},
+		{value: "CWE-0", wantErr: true},
+		{value: "A03:2021 - Injection", wantErr: true},
+		{value: "SQL injection (CWE-89)", wantErr: true},
+		{value: "CWE-", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCWE(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCWE(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCWE(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseScanResultReadsCWEAndReferences(t *testing.T) {
+	result, err := ParseScanResult("Here are the findings:\n```json\n" + `{"vulnerabilities": [
+		{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "High", "cwe": "cwe-89", "references": ["https://owasp.org/Top10/A03_2021-Injection/", "https://cwe.mitre.org/data/definitions/89.html"]},
+		{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "High", "cwe": 79, "references": "https://example.com/xss"},
+		{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "High", "cwe": "not a cwe", "references": ["OWASP Top 10", "javascript:alert(1)", "https://example.com/a", "https://example.com/a"]},
+		{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "High", "cwe": ["CWE-89"], "references": {"url": "https://example.com"}},
+		{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "High"}
+	]}` + "\n```")
+	if err != nil {
+		t.Fatalf("ParseScanResult returned error: %v", err)
+	}
+
+	want := []struct {
+		cwe        CWE
+		references []string
+	}{
+		{cwe: "CWE-89", references: []string{"https://owasp.org/Top10/A03_2021-Injection/", "https://cwe.mitre.org/data/definitions/89.html"}},
+		{cwe: "CWE-79", references: []string{"https://example.com/xss"}},
+		{cwe: "", references: []string{"https://example.com/a"}},
+		{cwe: "", references: []string{}},
+		{cwe: "", references: nil},
+	}
+	if len(result.Vulnerabilities) != len(want) {
+		t.Fatalf("got %d findings, want %d", len(result.Vulnerabilities), len(want))
+	}
+	// Unreadable values come out as not given instead of failing the result
+	for i, vuln := range result.Vulnerabilities {
+		if vuln.CWE != want[i].cwe {
+			t.Errorf("finding %d CWE = %q, want %q", i, vuln.CWE, want[i].cwe)
+		}
+		if !slices.Equal(vuln.References, want[i].references) {
+			t.Errorf("finding %d references = %q, want %q", i, vuln.References, want[i].references)
+		}
+	}
+}
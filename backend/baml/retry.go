@@ -0,0 +1,204 @@
+package baml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Defaults for retrying failed OpenAI requests
+const (
+	defaultMaxAttempts    = 5
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryConfig controls how OpenAI requests that fail transiently are retried
+type RetryConfig struct {
+	MaxAttempts int           // Total attempts per request, including the first
+	BaseDelay   time.Duration // Backoff before the first retry; doubles with each further retry
+	MaxDelay    time.Duration // Longest single wait, including one asked for by Retry-After
+}
+
+// retryConfigFromEnv reads OPENAI_MAX_ATTEMPTS, OPENAI_RETRY_BASE_DELAY, and OPENAI_RETRY_MAX_DELAY
+func retryConfigFromEnv() RetryConfig {
+	return RetryConfig{
+		MaxAttempts: positiveIntFromEnv("OPENAI_MAX_ATTEMPTS", defaultMaxAttempts),
+		BaseDelay:   durationFromEnv("OPENAI_RETRY_BASE_DELAY", defaultRetryBaseDelay),
+		MaxDelay:    durationFromEnv("OPENAI_RETRY_MAX_DELAY", defaultRetryMaxDelay),
+	}
+}
+
+// withDefaults fills unset fields with the defaults
+func (r RetryConfig) withDefaults() RetryConfig {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = defaultMaxAttempts
+	}
+	if r.BaseDelay <= 0 {
+		r.BaseDelay = defaultRetryBaseDelay
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = defaultRetryMaxDelay
+	}
+	return r
+}
+
+// backoff returns the wait before retry number `retry` (1 for the first retry)
+// The delay doubles each time and is jittered so parallel scans don't retry in lockstep
+func (r RetryConfig) backoff(retry int) time.Duration {
+	delay := r.BaseDelay
+	for i := 1; i < retry && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	// Wait between half and all of the delay
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryableStatus reports whether a response status is worth retrying
+// Rate limits and server errors are transient; other client errors will fail the same way again
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+// It returns false when the header is missing or unusable
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// ErrRequestTimeout is wrapped by the error of a model request that ran past OPENAI_REQUEST_TIMEOUT
+var ErrRequestTimeout = errors.New("model request timed out")
+
+// newHTTPClient returns the client a provider sends every request with, so connections are reused
+// The timeout bounds each attempt so one slow call can't consume the whole activity
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout}
+}
+
+// isTimeout reports whether a request error is the client's own timeout rather than a canceled context
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// postWithRetry sends a request built by newRequest, retrying rate limits, server errors, and network failures
+// It returns the body of the first successful response, or the last error once attempts run out.
+// A request that times out is not retried, since it would most likely time out again and each retry would
+// add another full timeout to the file; its error wraps ErrRequestTimeout
+func postWithRetry(ctx context.Context, provider string, client *http.Client, retry RetryConfig,
+	newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= retry.MaxAttempts; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		wait := time.Duration(0)
+		resp, err := client.Do(req)
+		if err != nil {
+			// A canceled scan must not keep retrying
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to send request to %s: %w", provider, err)
+			}
+			if isTimeout(err) {
+				return nil, fmt.Errorf("%s request: %w after %s: %v", provider, ErrRequestTimeout, client.Timeout, err)
+			}
+			lastErr = fmt.Errorf("failed to send request to %s: %w", provider, err)
+		} else {
+			body, readErr := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			switch {
+			case readErr != nil && ctx.Err() == nil && isTimeout(readErr):
+				return nil, fmt.Errorf("%s request: %w after %s: %v", provider, ErrRequestTimeout, client.Timeout, readErr)
+			case readErr != nil:
+				lastErr = fmt.Errorf("failed to read response body: %w", readErr)
+			case resp.StatusCode == http.StatusOK:
+				return body, nil
+			case !retryableStatus(resp.StatusCode):
+				return nil, fmt.Errorf("%s API returned non-200 status code: %d, body: %s", provider, resp.StatusCode, string(body))
+			default:
+				lastErr = fmt.Errorf("%s API returned non-200 status code: %d, body: %s", provider, resp.StatusCode, string(body))
+				// Honor the server's requested wait on rate limits
+				if resp.StatusCode == http.StatusTooManyRequests {
+					if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+						wait = after
+					}
+				}
+			}
+		}
+
+		if attempt == retry.MaxAttempts {
+			break
+		}
+		if wait == 0 {
+			wait = retry.backoff(attempt)
+		}
+		if wait > retry.MaxDelay {
+			wait = retry.MaxDelay
+		}
+
+		log.Warn("LLM request failed, retrying",
+			zap.String("provider", provider),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", retry.MaxAttempts),
+			zap.Duration("wait", wait),
+			zap.Error(lastErr))
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s request canceled while waiting to retry: %w", provider, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	return nil, fmt.Errorf("%s request failed after %d attempts: %w", provider, retry.MaxAttempts, lastErr)
+}
+
+// durationFromEnv reads a positive Go duration setting, falling back to def when unset or invalid
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		logger.Warn("Invalid "+name+" value, using default",
+			zap.String("value", value),
+			zap.Duration("default", def))
+		return def
+	}
+	return parsed
+}
@@ -0,0 +1,226 @@
+package baml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useServer answers the model requests made during the test with handler and counts them
+func useServer(t *testing.T, handler func(w http.ResponseWriter, attempt int)) *atomic.Int32 {
+	t.Helper()
+	t.Setenv("OPENAI_API_KEY", "test-key")
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, int(attempts.Add(1)))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+	return &attempts
+}
+
+// writeNoFindings answers a scan request successfully
+func writeNoFindings(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "{\"vulnerabilities\": []}"}}]}`)
+}
+
+// fastRetries keeps the tests' backoff waits short
+var fastRetries = RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+func TestScanCodeRetriesRateLimits(t *testing.T) {
+	attempts := useServer(t, func(w http.ResponseWriter, attempt int) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"error": {"message": "Rate limit reached"}}`, http.StatusTooManyRequests)
+			return
+		}
+		writeNoFindings(w)
+	})
+
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Retry: fastRetries})
+	result, err := client.ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"})
+	if err != nil {
+		t.Fatalf("ScanCode returned error: %v", err)
+	}
+	if result == nil || len(result.Vulnerabilities) != 0 {
+		t.Errorf("ScanCode = %+v, want an empty result", result)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("sent %d requests, want the rate-limited one and a retry", got)
+	}
+}
+
+func TestScanCodeRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantAttempts int32
+	}{
+		{name: "server errors are retried until attempts run out", status: http.StatusInternalServerError, wantAttempts: 3},
+		{name: "rate limits are retried until attempts run out", status: http.StatusTooManyRequests, wantAttempts: 3},
+		{name: "bad requests are not retried", status: http.StatusBadRequest, wantAttempts: 1},
+		{name: "authentication failures are not retried", status: http.StatusUnauthorized, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := useServer(t, func(w http.ResponseWriter, attempt int) {
+				http.Error(w, "failed", tt.status)
+			})
+
+			client := NewCodeScannerClientWithConfig(CodeScannerConfig{Retry: fastRetries})
+			_, err := client.ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"})
+			if err == nil || !strings.Contains(err.Error(), fmt.Sprint(tt.status)) {
+				t.Errorf("ScanCode error = %v, want one naming status %d", err, tt.status)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("sent %d requests, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestScanCodeStopsRetryingWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := useServer(t, func(w http.ResponseWriter, attempt int) {
+		// Ask for a wait far longer than the test, then cancel during it
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+		cancel()
+	})
+
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Retry: RetryConfig{MaxAttempts: 3, MaxDelay: time.Minute}})
+	_, err := client.ScanCode(ctx, "package main\n", "Go", "main.go", []string{"Injection"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ScanCode error = %v, want context.Canceled", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("sent %d requests, want no retry after cancellation", got)
+	}
+}
+
+func TestScanCodeGivesUpOnSlowRequests(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_REQUEST_TIMEOUT", "100ms")
+
+	// The server never answers; it waits until the client hangs up, which it notices once the body is read
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	client := NewCodeScannerClientWithConfig(CodeScannerConfig{Retry: fastRetries})
+	start := time.Now()
+	_, err := client.ScanCode(context.Background(), "package main\n", "Go", "main.go", []string{"Injection"})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("ScanCode error = %v, want ErrRequestTimeout", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		t.Errorf("ScanCode error = %v, want a timeout distinct from the caller's context", err)
+	}
+	// A request that timed out is not retried, so the file costs one timeout rather than one per attempt
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+	if elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("ScanCode took %v, want about the 100ms request timeout", elapsed)
+	}
+}
+
+func TestProvidersReuseOneHTTPClient(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+
+	for _, name := range []string{ProviderOpenAI, ProviderAnthropic} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("LLM_PROVIDER", name)
+			var client *http.Client
+			switch provider := NewProviderFromEnv(45*time.Second, fastRetries).(type) {
+			case *openAIProvider:
+				client = provider.client
+			case *anthropicProvider:
+				client = provider.client
+			}
+			if client == nil || client.Timeout != 45*time.Second {
+				t.Errorf("provider client = %+v, want one shared client with the 45s request timeout", client)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		header string
+		want   time.Duration
+		wantOK bool
+	}{
+		{header: ""},
+		{header: "soon"},
+		{header: "-3"},
+		{header: "0", wantOK: true},
+		{header: "7", want: 7 * time.Second, wantOK: true},
+		{header: now.Add(20 * time.Second).Format(http.TimeFormat), want: 20 * time.Second, wantOK: true},
+		{header: now.Add(-time.Minute).Format(http.TimeFormat), wantOK: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.header, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBackoffDoublesUpToTheMaximum(t *testing.T) {
+	config := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for retry, full := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		// Jitter keeps each wait between half and all of the full delay
+		for range 20 {
+			if got := config.backoff(retry); got < full/2 || got > full {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", retry, got, full/2, full)
+			}
+		}
+	}
+}
+
+func TestRetryConfigFromEnv(t *testing.T) {
+	t.Setenv("OPENAI_MAX_ATTEMPTS", "")
+	t.Setenv("OPENAI_RETRY_BASE_DELAY", "")
+	t.Setenv("OPENAI_RETRY_MAX_DELAY", "")
+	if got, want := retryConfigFromEnv(), (RetryConfig{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 30 * time.Second}); got != want {
+		t.Errorf("retryConfigFromEnv() = %+v, want the defaults %+v", got, want)
+	}
+
+	t.Setenv("OPENAI_MAX_ATTEMPTS", "2")
+	t.Setenv("OPENAI_RETRY_BASE_DELAY", "250ms")
+	t.Setenv("OPENAI_RETRY_MAX_DELAY", "invalid")
+	if got, want := retryConfigFromEnv(), (RetryConfig{MaxAttempts: 2, BaseDelay: 250 * time.Millisecond, MaxDelay: 30 * time.Second}); got != want {
+		t.Errorf("retryConfigFromEnv() = %+v, want %+v", got, want)
+	}
+}
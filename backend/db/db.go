@@ -2,7 +2,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"sync"
 
@@ -11,52 +13,97 @@ import (
 
 // Queries provides all the database operations
 type Queries struct {
-	db *sql.DB
+	db     *sql.DB
+	global bool // Follow the global connection, so a reconnect reaches instances created before it
 }
 
-// Global database connection instance
+// Global database connection instance and whether its last health check succeeded
 var (
 	globalDB *sql.DB
+	healthy  bool
 	dbMutex  sync.Mutex
 )
 
 // SetGlobalDB sets the global database connection
+// Setting a connection marks the database healthy; setting nil marks it unavailable
 func SetGlobalDB(db *sql.DB) {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
 	globalDB = db
+	healthy = db != nil
 }
 
-// NewQueries creates a new Queries instance
-func NewQueries() *Queries {
+// Healthy reports whether the global connection is set and answered its last health check
+// Handlers use it to skip the database during an outage instead of waiting on a connection that is down
+func Healthy() bool {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+	return globalDB != nil && healthy
+}
+
+// setHealthy records the result of a health check of the global connection
+func setHealthy(ok bool) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+	healthy = ok
+}
+
+// getGlobalDB returns the global database connection
+func getGlobalDB() *sql.DB {
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
-	return &Queries{db: globalDB}
+	return globalDB
+}
+
+// NewQueries creates a Queries instance that uses the global database connection
+// It follows later calls to SetGlobalDB until SetDB gives it a connection of its own
+func NewQueries() *Queries {
+	return &Queries{global: true}
 }
 
 // SetDB sets the database connection
 func (q *Queries) SetDB(db *sql.DB) {
 	q.db = db
+	q.global = false
 }
 
 // GetDB returns the database connection
 func (q *Queries) GetDB() *sql.DB {
+	if q.global {
+		return getGlobalDB()
+	}
 	return q.db
 }
 
 // Close closes the database connection
 func (q *Queries) Close() error {
-	if q.db != nil {
-		return q.db.Close()
+	if db := q.GetDB(); db != nil {
+		return db.Close()
 	}
 	return nil
 }
 
 // Ping checks the database connection
 func (q *Queries) Ping() error {
-	if q.db != nil {
-		return q.db.Ping()
+	if db := q.GetDB(); db != nil {
+		return db.Ping()
 	}
 	log.Println("Warning: No database connection set")
 	return nil
 }
+
+// ErrNoConnection is returned by PingContext when no database connection was set up
+var ErrNoConnection = errors.New("no database connection")
+
+// PingContext checks the database answers within ctx
+// Unlike Ping, a missing connection is an error, so callers can tell the database is unusable
+func (q *Queries) PingContext(ctx context.Context) error {
+	if q == nil {
+		return ErrNoConnection
+	}
+	db := q.GetDB()
+	if db == nil {
+		return ErrNoConnection
+	}
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,11 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Map legacy scan statuses onto the queued -> cloning -> scanning -> terminal lifecycle
+UPDATE scans SET status = 'queued' WHERE status = 'pending';
+UPDATE scans SET status = 'scanning' WHERE status = 'in_progress';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+UPDATE scans SET status = 'in_progress' WHERE status IN ('cloning', 'scanning');
+UPDATE scans SET status = 'pending' WHERE status = 'queued';
@@ -0,0 +1,19 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Derived fields computed from each finding; legacy rows are backfilled by POST /api/admin/reindex
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS fingerprint VARCHAR(64);
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS owasp_category VARCHAR(20);
+CREATE INDEX IF NOT EXISTS idx_vulnerabilities_fingerprint ON vulnerabilities(fingerprint);
+
+-- Per-scan summary of finding counts by severity
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS vulnerability_count INTEGER;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS severity_summary JSONB;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS severity_summary;
+ALTER TABLE scans DROP COLUMN IF EXISTS vulnerability_count;
+DROP INDEX IF EXISTS idx_vulnerabilities_fingerprint;
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS owasp_category;
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS fingerprint;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Numeric severity so "worst first" ordering doesn't depend on alphabetical string order
+-- 4 = Critical, 3 = High, 2 = Medium, 1 = Low, 0 = unrecognized
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS severity_rank SMALLINT NOT NULL DEFAULT 0;
+
+UPDATE vulnerabilities SET severity_rank = CASE LOWER(TRIM(severity))
+    WHEN 'critical' THEN 4
+    WHEN 'high' THEN 3
+    WHEN 'medium' THEN 2
+    WHEN 'low' THEN 1
+    ELSE 0
+END;
+
+CREATE INDEX IF NOT EXISTS idx_vulnerabilities_scan_severity_rank ON vulnerabilities(scan_id, severity_rank DESC);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP INDEX IF EXISTS idx_vulnerabilities_scan_severity_rank;
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS severity_rank;
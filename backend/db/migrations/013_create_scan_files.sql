@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Per-file scan progress, written as each file finishes so an interrupted scan can resume
+-- One row per (scan, file); re-recording a file overwrites its row
+CREATE TABLE IF NOT EXISTS scan_files (
+    scan_id UUID NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    file_path TEXT NOT NULL,
+    vulnerability_count INTEGER NOT NULL DEFAULT 0,
+    completed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (scan_id, file_path)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP TABLE IF EXISTS scan_files;
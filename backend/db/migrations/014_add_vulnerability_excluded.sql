@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Findings in paths matched by SCAN_EXCLUDE_PATHS are kept but hidden from default results and counts
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS excluded BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS excluded;
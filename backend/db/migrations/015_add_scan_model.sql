@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- LLM model each scan ran with, so runs of the same repository under different models can be compared
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS model VARCHAR(100);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS model;
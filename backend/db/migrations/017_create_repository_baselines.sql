@@ -0,0 +1,18 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- The scan whose findings were accepted as the repository's baseline
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS baseline_scan_id UUID REFERENCES scans(id) ON DELETE SET NULL;
+
+-- Fingerprints of accepted findings; later findings with these fingerprints are hidden by default
+CREATE TABLE IF NOT EXISTS baseline_fingerprints (
+    repository_id UUID NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (repository_id, fingerprint)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP TABLE IF EXISTS baseline_fingerprints;
+ALTER TABLE repositories DROP COLUMN IF EXISTS baseline_scan_id;
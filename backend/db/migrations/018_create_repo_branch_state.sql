@@ -0,0 +1,17 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- The last commit successfully scanned on each branch of a repository
+-- Incremental scans diff against this commit, so it must survive restarts
+CREATE TABLE IF NOT EXISTS repo_branch_state (
+    repository_id UUID NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    ref TEXT NOT NULL,
+    last_scanned_sha VARCHAR(64) NOT NULL,
+    last_scan_id UUID REFERENCES scans(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (repository_id, ref)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP TABLE IF EXISTS repo_branch_state;
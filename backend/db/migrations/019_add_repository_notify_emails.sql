@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Extra addresses, such as a security alias, that receive scan results alongside the submitter
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS notify_emails TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE repositories DROP COLUMN IF EXISTS notify_emails;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Branch or tag a scan ran against and the exact commit, so a scan can be reproduced
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS ref TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS commit_sha VARCHAR(40);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS commit_sha;
+ALTER TABLE scans DROP COLUMN IF EXISTS ref;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- bcrypt hash for email/password accounts; NULL for users who only sign in with Google
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Model results per file, keyed by a hash of the file content, language, model, and vulnerability types
+-- Rescanning an unchanged file reuses the stored result instead of calling the model again
+CREATE TABLE IF NOT EXISTS scan_cache (
+    cache_key CHAR(64) PRIMARY KEY,
+    result JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP TABLE IF EXISTS scan_cache;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Commit an incremental scan compared against; NULL for scans of the full tree
+-- Findings of files unchanged since this commit were carried forward from the scan of it
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS base_commit_sha VARCHAR(40);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS base_commit_sha;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Host a repository was added from; existing repositories all came from GitHub
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'github';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE repositories DROP COLUMN IF EXISTS provider;
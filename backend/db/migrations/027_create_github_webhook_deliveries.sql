@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- GitHub webhook deliveries already handled, keyed by the X-GitHub-Delivery ID
+-- GitHub redelivers an event with the same ID, so recording it keeps a redelivered push from scanning twice
+CREATE TABLE IF NOT EXISTS github_webhook_deliveries (
+    delivery_id TEXT PRIMARY KEY,
+    event TEXT NOT NULL,
+    repository_id UUID REFERENCES repositories(id) ON DELETE SET NULL,
+    scan_id UUID REFERENCES scans(id) ON DELETE SET NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP TABLE IF EXISTS github_webhook_deliveries;
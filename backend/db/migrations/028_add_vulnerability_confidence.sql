@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- The model's certainty that a finding is real, from 0.0 to 1.0; NULL when it wasn't reported
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS confidence REAL;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS confidence;
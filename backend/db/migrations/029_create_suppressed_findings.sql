@@ -0,0 +1,20 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Findings a repository's users marked as false positives, keyed by file, line, and type
+-- Matching findings of any scan of the repository are left out of results
+CREATE TABLE IF NOT EXISTS suppressed_findings (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    repository_id UUID NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    file_path TEXT NOT NULL,
+    line_start INTEGER NOT NULL,
+    vulnerability_type VARCHAR(100) NOT NULL,
+    reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (repository_id, file_path, line_start, vulnerability_type)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP TABLE IF EXISTS suppressed_findings;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- How a user hears about finished scans: an email per scan, or a daily or weekly digest
+ALTER TABLE users ADD COLUMN notification_frequency VARCHAR(20) NOT NULL DEFAULT 'immediate'
+    CHECK (notification_frequency IN ('immediate', 'daily', 'weekly'));
+
+-- When the user's last digest was sent; the next one covers scans completed since then
+ALTER TABLE users ADD COLUMN last_digest_sent_at TIMESTAMPTZ;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE users DROP COLUMN IF EXISTS last_digest_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS notification_frequency;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- OAuth state of Google logins in progress, so the callback verifies on any instance
+-- The browser holds the row ID in a cookie; only a SHA-256 hash of the state is stored, and a row is
+-- deleted when its callback arrives or, once expired, when the next login starts
+CREATE TABLE IF NOT EXISTS oauth_states (
+    id VARCHAR(64) PRIMARY KEY,
+    state_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires_at ON oauth_states(expires_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP INDEX IF EXISTS idx_oauth_states_expires_at;
+DROP TABLE IF EXISTS oauth_states;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Files whose findings could not be stored, recorded instead of failing the whole scan
+-- The file is still marked done in scan_files, so a resumed scan doesn't retry it
+CREATE TABLE IF NOT EXISTS scan_errors (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    scan_id UUID NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    file_path TEXT NOT NULL,
+    error TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_errors_scan_id ON scan_errors(scan_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP INDEX IF EXISTS idx_scan_errors_scan_id;
+DROP TABLE IF EXISTS scan_errors;
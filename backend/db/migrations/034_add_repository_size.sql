@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Repository size in KB as reported by the provider (0 when unknown), used to size the clone timeout
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS size_kb BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE repositories DROP COLUMN IF EXISTS size_kb;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- How far a running scan has got ({"scanned", "total", "current_file"}), saved from the scan activity's heartbeats
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS progress JSONB;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS progress;
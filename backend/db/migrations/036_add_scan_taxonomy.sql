@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Vulnerability taxonomy the scan looked for and categorized its findings under
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS taxonomy TEXT NOT NULL DEFAULT 'owasp-2021';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS taxonomy;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Optional URL a finished scan posts its signed result summary to, and how the latest delivery went
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS callback_url TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS callback_status TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS callback_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS callback_error TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS callback_delivered_at TIMESTAMPTZ;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS callback_delivered_at;
+ALTER TABLE scans DROP COLUMN IF EXISTS callback_error;
+ALTER TABLE scans DROP COLUMN IF EXISTS callback_attempts;
+ALTER TABLE scans DROP COLUMN IF EXISTS callback_status;
+ALTER TABLE scans DROP COLUMN IF EXISTS callback_url;
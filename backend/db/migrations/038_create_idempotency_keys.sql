@@ -0,0 +1,24 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- Idempotency-Key headers of requests that start scans, so a retried request returns the scan it already started
+-- Keys are scoped to the endpoint and to the user or client IP that sent them, and only a SHA-256 hash is stored
+-- scan_id stays NULL while the first request is still being handled; expired rows are deleted by later claims,
+-- and deleting the scan frees its key
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    endpoint TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scan_id UUID REFERENCES scans(id) ON DELETE CASCADE,
+    repository_id UUID,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (endpoint, scope, key_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+DROP INDEX IF EXISTS idx_idempotency_keys_expires_at;
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- The weakness a finding is an instance of, e.g. 'CWE-89'; NULL when it isn't known
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS cwe TEXT;
+
+-- Links about the finding: the model's, or the OWASP and CWE pages when it gave none
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS reference_urls TEXT[] NOT NULL DEFAULT '{}';
+
+-- Findings stored before these columns get what the scanner now fills in by default:
+-- the CWE named by a CWE Top 25 type, and the pages of their OWASP category and CWE
+UPDATE vulnerabilities SET cwe = substring(vulnerability_type FROM '^CWE-[0-9]+')
+WHERE cwe IS NULL AND vulnerability_type ~ '^CWE-[0-9]+';
+
+UPDATE vulnerabilities SET reference_urls = array_remove(ARRAY[
+    CASE owasp_category
+        WHEN 'A01:2021' THEN 'https://owasp.org/Top10/A01_2021-Broken_Access_Control/'
+        WHEN 'A02:2021' THEN 'https://owasp.org/Top10/A02_2021-Cryptographic_Failures/'
+        WHEN 'A03:2021' THEN 'https://owasp.org/Top10/A03_2021-Injection/'
+        WHEN 'A04:2021' THEN 'https://owasp.org/Top10/A04_2021-Insecure_Design/'
+        WHEN 'A05:2021' THEN 'https://owasp.org/Top10/A05_2021-Security_Misconfiguration/'
+        WHEN 'A06:2021' THEN 'https://owasp.org/Top10/A06_2021-Vulnerable_and_Outdated_Components/'
+        WHEN 'A07:2021' THEN 'https://owasp.org/Top10/A07_2021-Identification_and_Authentication_Failures/'
+        WHEN 'A08:2021' THEN 'https://owasp.org/Top10/A08_2021-Software_and_Data_Integrity_Failures/'
+        WHEN 'A09:2021' THEN 'https://owasp.org/Top10/A09_2021-Security_Logging_and_Monitoring_Failures/'
+        WHEN 'A10:2021' THEN 'https://owasp.org/Top10/A10_2021-Server-Side_Request_Forgery_%28SSRF%29/'
+    END,
+    'https://cwe.mitre.org/data/definitions/' || substring(cwe FROM '^CWE-([0-9]+)$') || '.html'
+], NULL)
+WHERE reference_urls = '{}';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS reference_urls;
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS cwe;
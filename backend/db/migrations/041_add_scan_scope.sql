@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied
+
+-- The options that chose a scan's files and findings, and whether SCAN_MAX_FILES cut its file list short
+-- Incremental scans only carry findings forward from an untruncated scan with the same scope
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS scan_scope TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS truncated BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back
+ALTER TABLE scans DROP COLUMN IF EXISTS truncated;
+ALTER TABLE scans DROP COLUMN IF EXISTS scan_scope;
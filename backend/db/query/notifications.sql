@@ -0,0 +1,20 @@
+-- name: ListUserNotifications :many
+SELECT * FROM notifications
+WHERE user_id = $1
+ORDER BY created_at DESC, id
+LIMIT $2 OFFSET $3;
+
+-- name: CountUserNotifications :one
+SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE NOT read) AS unread
+FROM notifications
+WHERE user_id = $1;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read = TRUE
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read = TRUE
+WHERE user_id = $1 AND NOT read;
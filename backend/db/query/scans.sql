@@ -20,7 +20,7 @@ RETURNING *;
 UPDATE scans
 SET 
   status = $2,
-  started_at = CASE WHEN $2 = 'in_progress' AND started_at IS NULL THEN NOW() ELSE started_at END,
+  started_at = CASE WHEN $2 IN ('cloning', 'scanning') AND started_at IS NULL THEN NOW() ELSE started_at END,
   completed_at = CASE WHEN $2 IN ('completed', 'failed') THEN NOW() ELSE completed_at END,
   error_message = $3
 WHERE id = $1
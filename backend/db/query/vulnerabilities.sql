@@ -5,7 +5,7 @@ WHERE id = $1 LIMIT 1;
 -- name: ListScanVulnerabilities :many
 SELECT * FROM vulnerabilities
 WHERE scan_id = $1
-ORDER BY severity DESC, vulnerability_type ASC
+ORDER BY severity_rank DESC, vulnerability_type ASC
 LIMIT $2 OFFSET $3;
 
 -- name: CreateVulnerability :one
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ReconnectConfig controls how MaintainConnection checks the global connection and retries a lost one
+type ReconnectConfig struct {
+	CheckInterval  time.Duration // How often a healthy connection is pinged
+	CheckTimeout   time.Duration // How long each ping or connection attempt may take
+	InitialBackoff time.Duration // Wait after the first failed attempt; doubled after each further failure
+	MaxBackoff     time.Duration // Cap on the wait between attempts
+}
+
+// DefaultReconnectConfig checks the connection every 30 seconds and retries a lost one
+// after 1 second, backing off to once a minute
+var DefaultReconnectConfig = ReconnectConfig{
+	CheckInterval:  30 * time.Second,
+	CheckTimeout:   5 * time.Second,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// Connector opens a database connection and checks it answers within ctx
+type Connector func(ctx context.Context) (*sql.DB, error)
+
+// MaintainConnection keeps the global connection usable until ctx is done. While it is healthy it is
+// pinged every CheckInterval; once it is unset or a ping fails, Healthy reports false and connect is
+// retried with a capped exponential backoff until it succeeds and the result is set with SetGlobalDB
+func MaintainConnection(ctx context.Context, connect Connector, config ReconnectConfig) {
+	log := logger.Get()
+	backoff := config.InitialBackoff
+	attempt := 0
+
+	for {
+		wait := config.CheckInterval
+		if Healthy() {
+			if err := pingGlobal(ctx, config.CheckTimeout); err != nil && ctx.Err() == nil {
+				setHealthy(false)
+				log.Warn("Database health check failed, reconnecting", zap.Error(err))
+				wait = 0
+			}
+		} else {
+			attempt++
+			attemptCtx, cancel := context.WithTimeout(ctx, config.CheckTimeout)
+			conn, err := connect(attemptCtx)
+			cancel()
+
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				log.Warn("Failed to reconnect to database, retrying",
+					zap.Error(err),
+					zap.Int("attempt", attempt),
+					zap.Duration("retry_in", backoff))
+				wait = backoff
+				backoff = nextBackoff(backoff, config.MaxBackoff)
+			default:
+				SetGlobalDB(conn)
+				log.Info("Reconnected to database", zap.Int("attempts", attempt))
+				backoff = config.InitialBackoff
+				attempt = 0
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// pingGlobal checks the global connection answers within timeout
+func pingGlobal(ctx context.Context, timeout time.Duration) error {
+	conn := getGlobalDB()
+	if conn == nil {
+		return ErrNoConnection
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return conn.PingContext(pingCtx)
+}
+
+// nextBackoff doubles the wait between reconnection attempts, up to max
+func nextBackoff(current, max time.Duration) time.Duration {
+	if current <= 0 {
+		return max
+	}
+	if next := current * 2; next < max {
+		return next
+	}
+	return max
+}
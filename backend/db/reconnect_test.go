@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeConnector is a driver connector whose connections fail while down is set
+type fakeConnector struct {
+	down atomic.Bool
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return fakeConn{}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver { return nil }
+
+// fakeConn is a connection that can only be pinged
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+// testReconnectConfig retries quickly so the tests don't wait on real backoffs
+var testReconnectConfig = ReconnectConfig{
+	CheckInterval:  5 * time.Millisecond,
+	CheckTimeout:   time.Second,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     4 * time.Millisecond,
+}
+
+// runMaintainConnection runs MaintainConnection until the test ends, restoring the global connection afterwards
+func runMaintainConnection(t *testing.T, connect Connector) {
+	t.Helper()
+	SetGlobalDB(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		MaintainConnection(ctx, connect, testReconnectConfig)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		SetGlobalDB(nil)
+	})
+}
+
+// waitFor polls condition until it holds or a second has passed
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMaintainConnectionReconnectsAfterFailures(t *testing.T) {
+	conn := sql.OpenDB(&fakeConnector{})
+	t.Cleanup(func() { conn.Close() })
+
+	var mu sync.Mutex
+	attempts := 0
+	runMaintainConnection(t, func(ctx context.Context) (*sql.DB, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts <= 3 {
+			return nil, errors.New("connection refused")
+		}
+		return conn, nil
+	})
+
+	waitFor(t, "the reconnect", Healthy)
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 4 {
+		t.Errorf("connected after %d attempts, want 4", attempts)
+	}
+	// Queries created before the reconnect see the new connection
+	if NewQueries().GetDB() != conn {
+		t.Error("NewQueries().GetDB() doesn't return the reconnected database")
+	}
+}
+
+func TestMaintainConnectionNoticesALostConnection(t *testing.T) {
+	connector := &fakeConnector{}
+	conn := sql.OpenDB(connector)
+	t.Cleanup(func() { conn.Close() })
+
+	runMaintainConnection(t, func(ctx context.Context) (*sql.DB, error) {
+		if err := conn.PingContext(ctx); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	})
+	waitFor(t, "the first connection", Healthy)
+
+	// Idle connections would still answer pings, so drop them when the database goes down
+	connector.down.Store(true)
+	conn.SetMaxIdleConns(0)
+	waitFor(t, "the outage to be noticed", func() bool { return !Healthy() })
+
+	connector.down.Store(false)
+	waitFor(t, "the reconnect", Healthy)
+}
+
+func TestQueriesFollowTheGlobalConnection(t *testing.T) {
+	t.Cleanup(func() { SetGlobalDB(nil) })
+	SetGlobalDB(nil)
+	queries := NewQueries()
+	if queries.GetDB() != nil || Healthy() {
+		t.Fatal("queries have a connection before one was set")
+	}
+
+	conn := sql.OpenDB(&fakeConnector{})
+	t.Cleanup(func() { conn.Close() })
+	SetGlobalDB(conn)
+	if queries.GetDB() != conn || !Healthy() {
+		t.Error("queries don't use the connection set after they were created")
+	}
+
+	// An explicitly set connection is kept whatever the global one is
+	own := sql.OpenDB(&fakeConnector{})
+	t.Cleanup(func() { own.Close() })
+	queries.SetDB(own)
+	SetGlobalDB(nil)
+	if queries.GetDB() != own {
+		t.Error("SetDB connection was replaced by the global one")
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		current, want time.Duration
+	}{
+		{current: time.Second, want: 2 * time.Second},
+		{current: 20 * time.Second, want: 30 * time.Second},
+		{current: 30 * time.Second, want: 30 * time.Second},
+		{current: 0, want: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := nextBackoff(tt.current, 30*time.Second); got != tt.want {
+			t.Errorf("nextBackoff(%v) = %v, want %v", tt.current, got, tt.want)
+		}
+	}
+}
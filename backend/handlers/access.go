@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+)
+
+// userCanAccessRepository reports whether the user is linked to or created the repository
+func userCanAccessRepository(ctx context.Context, dbConn *sql.DB, userID, repoID string) (bool, error) {
+	var allowed bool
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 FROM user_repositories WHERE user_id::text = $1 AND repository_id::text = $2
+		) OR EXISTS(
+			SELECT 1 FROM repositories WHERE id::text = $2 AND created_by::text = $1
+		)`,
+		userID, repoID).Scan(&allowed)
+	return allowed, err
+}
+
+// userIsAdmin reports whether the user has the admin role
+func userIsAdmin(ctx context.Context, dbConn *sql.DB, userID string) (bool, error) {
+	var role string
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT role FROM users WHERE id::text = $1`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return role == "admin", err
+}
+
+// scanRepositoryID resolves a /scan/{id} parameter to its repository ID
+// The parameter may be a scan record ID or a repository ID, like the other /scan/{id} endpoints accept
+func scanRepositoryID(ctx context.Context, dbConn *sql.DB, id string) (string, error) {
+	var repoID string
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT repository_id FROM scans WHERE id::text = $1`, id).Scan(&repoID)
+	if err == sql.ErrNoRows {
+		return id, nil
+	}
+	return repoID, err
+}
+
+// userOwnsRepository reports whether the user created the repository
+// Users linked to a repository by adding it again can read it, but only its creator owns it
+func userOwnsRepository(ctx context.Context, dbConn *sql.DB, userID, repoID string) (bool, error) {
+	var owns bool
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM repositories WHERE id::text = $1 AND created_by::text = $2)`,
+		repoID, userID).Scan(&owns)
+	return owns, err
+}
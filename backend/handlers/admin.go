@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// AdminHandler handles administrative API requests
+// These endpoints are only reachable by users with the admin role
+type AdminHandler struct {
+	ReindexService services.ReindexService       // Service for recomputing derived finding fields
+	WorkerControl  services.WorkerControlService // Service for pausing and resuming scan processing
+	Directory      services.DirectoryService     // Service for listing every user and scan
+}
+
+// NewAdminHandler creates a new admin handler with the services it needs
+func NewAdminHandler(reindexService services.ReindexService, workerControl services.WorkerControlService, directory services.DirectoryService) *AdminHandler {
+	return &AdminHandler{
+		ReindexService: reindexService,
+		WorkerControl:  workerControl,
+		Directory:      directory,
+	}
+}
+
+// ReindexRequest represents the optional body of a reindex request
+type ReindexRequest struct {
+	Cursor     string `json:"cursor"`      // Resume after this scan ID (empty starts from the beginning)
+	BatchSize  int    `json:"batch_size"`  // Scans per batch (default 50)
+	MaxBatches int    `json:"max_batches"` // Stop after this many batches (0 runs until done)
+}
+
+// Reindex recomputes fingerprints, OWASP categories, and scan summaries for stored scans
+// Work is done in batches; the response carries a cursor so an interrupted run can be resumed
+func (h *AdminHandler) Reindex(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	var req ReindexRequest
+	if !decodeOptionalJSONBody(w, r, &req) {
+		return
+	}
+
+	log.Info("Starting reindex",
+		zap.String("cursor", req.Cursor),
+		zap.Int("batch_size", req.BatchSize),
+		zap.Int("max_batches", req.MaxBatches))
+
+	// Accumulate progress across batches
+	total := &services.ReindexProgress{NextCursor: req.Cursor}
+	batches := 0
+	for !total.Done && (req.MaxBatches <= 0 || batches < req.MaxBatches) {
+		progress, err := h.ReindexService.ReindexBatch(r.Context(), total.NextCursor, req.BatchSize)
+		if progress != nil {
+			total.ScansProcessed += progress.ScansProcessed
+			total.ScansUpdated += progress.ScansUpdated
+			total.VulnerabilitiesUpdated += progress.VulnerabilitiesUpdated
+			total.NextCursor = progress.NextCursor
+			total.Done = progress.Done
+		}
+		if err != nil {
+			log.Error("Reindex batch failed",
+				zap.String("cursor", total.NextCursor),
+				zap.Error(err))
+			writeErrorBody(w, http.StatusInternalServerError, map[string]any{
+				"error":    errorDetail{Code: "reindex_failed", Message: err.Error()},
+				"progress": total,
+				"batches":  batches,
+			})
+			return
+		}
+		batches++
+	}
+
+	log.Info("Reindex finished",
+		zap.Int("batches", batches),
+		zap.Int("scans_processed", total.ScansProcessed),
+		zap.Bool("done", total.Done))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"progress": total,
+		"batches":  batches,
+	})
+}
+
+// GetWorkerState reports whether scan processing is paused
+func (h *AdminHandler) GetWorkerState(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	state, err := h.WorkerControl.State(r.Context())
+	if err != nil {
+		log.Error("Failed to read worker state", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to read worker state")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(state)
+}
+
+// PauseWorker stops new scans from starting; they stay queued until the worker is resumed
+// Scans that are already cloning or scanning run to completion
+func (h *AdminHandler) PauseWorker(w http.ResponseWriter, r *http.Request) {
+	h.setWorkerPaused(w, r, true)
+}
+
+// ResumeWorker lets queued scans start again
+func (h *AdminHandler) ResumeWorker(w http.ResponseWriter, r *http.Request) {
+	h.setWorkerPaused(w, r, false)
+}
+
+// setWorkerPaused stores the pause state and responds with the result
+func (h *AdminHandler) setWorkerPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	log := logger.FromContext(r.Context())
+	userID, _ := r.Context().Value("userID").(string)
+
+	state, err := h.WorkerControl.SetPaused(r.Context(), paused, userID)
+	if err != nil {
+		log.Error("Failed to update worker state", zap.Bool("paused", paused), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to update worker state")
+		return
+	}
+
+	log.Info("Scan worker state changed", zap.Bool("paused", paused), zap.String("user_id", userID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(state)
+}
+
+// readAdminPage reads ?limit= (default 50, at most 200) and ?offset= for an admin listing
+// It writes the error response itself and returns false when either is invalid
+func readAdminPage(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit, ok = pageParam(r, "limit", services.DefaultAdminListLimit)
+	if !ok || limit < 1 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "limit must be a positive integer")
+		return 0, 0, false
+	}
+	if limit > services.MaxAdminListLimit {
+		limit = services.MaxAdminListLimit
+	}
+
+	offset, ok = pageParam(r, "offset", 0)
+	if !ok || offset < 0 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "offset must be a non-negative integer")
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+// ListUsers returns every user account with its role, oldest first
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := readAdminPage(w, r)
+	if !ok {
+		return
+	}
+
+	users, total, err := h.Directory.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list users", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list users")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// ListScans returns the scans of every user, newest first, with the repository each belongs to
+func (h *AdminHandler) ListScans(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := readAdminPage(w, r)
+	if !ok {
+		return
+	}
+
+	scans, total, err := h.Directory.ListScans(r.Context(), limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list scans", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list scans")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"scans":  scans,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// fakeDirectory serves fixed listings and records the page it was asked for
+type fakeDirectory struct {
+	users         []*services.UserSummary
+	scans         []*services.AdminScanRecord
+	limit, offset int
+}
+
+func (f *fakeDirectory) ListUsers(ctx context.Context, limit, offset int) ([]*services.UserSummary, int, error) {
+	f.limit, f.offset = limit, offset
+	return f.users, len(f.users), nil
+}
+
+func (f *fakeDirectory) ListScans(ctx context.Context, limit, offset int) ([]*services.AdminScanRecord, int, error) {
+	f.limit, f.offset = limit, offset
+	return f.scans, len(f.scans), nil
+}
+
+func TestAdminListings(t *testing.T) {
+	directory := &fakeDirectory{
+		users: []*services.UserSummary{{ID: "user-1", Email: "ada@example.com", Role: services.RoleAdmin}},
+		scans: []*services.AdminScanRecord{{ScanRecord: services.ScanRecord{ID: "scan-1"}, RepositoryName: "shop"}},
+	}
+	h := NewAdminHandler(nil, nil, directory)
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		query      string
+		wantStatus int
+		wantLimit  int
+		wantOffset int
+		wantKey    string
+	}{
+		{name: "users", handler: h.ListUsers, wantStatus: http.StatusOK, wantLimit: services.DefaultAdminListLimit, wantKey: "users"},
+		{name: "scans page", handler: h.ListScans, query: "?limit=10&offset=20", wantStatus: http.StatusOK, wantLimit: 10, wantOffset: 20, wantKey: "scans"},
+		{name: "limit capped", handler: h.ListScans, query: "?limit=5000", wantStatus: http.StatusOK, wantLimit: services.MaxAdminListLimit, wantKey: "scans"},
+		{name: "invalid limit", handler: h.ListUsers, query: "?limit=abc", wantStatus: http.StatusBadRequest},
+		{name: "negative offset", handler: h.ListScans, query: "?offset=-1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*directory = fakeDirectory{users: directory.users, scans: directory.scans}
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, "/api/admin/listing"+tt.query, nil))
+
+			if tt.wantStatus != http.StatusOK {
+				if got := decodeErrorResponse(t, rec, tt.wantStatus); got.Code != "invalid_pagination" {
+					t.Errorf("error code = %q, want invalid_pagination", got.Code)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+			}
+			if directory.limit != tt.wantLimit || directory.offset != tt.wantOffset {
+				t.Errorf("page = limit %d offset %d, want limit %d offset %d", directory.limit, directory.offset, tt.wantLimit, tt.wantOffset)
+			}
+			var body map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if items, _ := body[tt.wantKey].([]any); len(items) != 1 || body["total"] != float64(1) {
+				t.Errorf("response = %v, want one entry in %q with total 1", body, tt.wantKey)
+			}
+		})
+	}
+}
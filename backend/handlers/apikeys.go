@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// APIKeyHandler manages a user's API keys
+type APIKeyHandler struct {
+	APIKeyService services.APIKeyService // Service for issuing and revoking API keys
+}
+
+// NewAPIKeyHandler creates a new API key handler with the services it needs
+func NewAPIKeyHandler(apiKeyService services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKeyRequest represents a request to issue an API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`   // Optional label, e.g. "CI"
+	Scopes []string `json:"scopes"` // e.g. ["repo:read", "scan:write"]; empty grants repo:read only
+}
+
+// CreateAPIKey issues an API key; the response includes the key, which is not shown again
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if err := services.ValidateAPIKeyScopes(req.Scopes); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	}
+	// Admin routes check the role on every request too, but a key shouldn't claim more than its owner has
+	role, _ := r.Context().Value("userRole").(string)
+	if slices.Contains(req.Scopes, services.ScopeAdmin) && role != services.RoleAdmin {
+		respondError(w, http.StatusForbidden, "forbidden_scope", "Only admins can issue keys with the admin scope")
+		return
+	}
+
+	apiKey, err := h.APIKeyService.CreateAPIKey(r.Context(), userID, req.Name, req.Scopes)
+	if err != nil {
+		log.Warn("Failed to create API key", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusBadRequest, "invalid_api_key", err.Error())
+		return
+	}
+
+	log.Info("API key created",
+		zap.String("user_id", userID),
+		zap.String("api_key_id", apiKey.ID),
+		zap.Strings("scopes", apiKey.Scopes))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(apiKey)
+}
+
+// ListAPIKeys returns the user's API keys, identified by prefix, and the scopes keys can be issued with
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	apiKeys, err := h.APIKeyService.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		log.Error("Failed to list API keys", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list API keys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"api_keys":         apiKeys,
+		"available_scopes": services.KnownScopes,
+	})
+}
+
+// RevokeAPIKey deletes one of the user's API keys; requests made with it are refused from then on
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	keyID := chi.URLParam(r, "id")
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	revoked, err := h.APIKeyService.RevokeAPIKey(r.Context(), userID, keyID)
+	if err != nil {
+		log.Error("Failed to revoke API key", zap.String("api_key_id", keyID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to revoke API key")
+		return
+	}
+	if !revoked {
+		respondError(w, http.StatusNotFound, "api_key_not_found", "API key not found")
+		return
+	}
+
+	log.Info("API key revoked", zap.String("user_id", userID), zap.String("api_key_id", keyID))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// fakeAPIKeyService keeps one user's keys in memory
+type fakeAPIKeyService struct {
+	keys []*services.APIKey
+}
+
+func (s *fakeAPIKeyService) CreateAPIKey(_ context.Context, userID, name string, scopes []string) (*services.APIKey, error) {
+	if len(scopes) == 0 {
+		scopes = services.DefaultAPIKeyScopes
+	}
+	apiKey := &services.APIKey{ID: "key-1", Name: name, Prefix: "sast_1234abcd", Key: "sast_1234abcd_secret", Scopes: scopes, CreatedAt: time.Now()}
+	stored := *apiKey
+	stored.Key = ""
+	s.keys = append(s.keys, &stored)
+	return apiKey, nil
+}
+
+func (s *fakeAPIKeyService) ListAPIKeys(context.Context, string) ([]*services.APIKey, error) {
+	return s.keys, nil
+}
+
+func (s *fakeAPIKeyService) RevokeAPIKey(_ context.Context, _, keyID string) (bool, error) {
+	for i, apiKey := range s.keys {
+		if apiKey.ID == keyID {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fakeAPIKeyService) Authenticate(context.Context, string) (*services.APIKeyIdentity, error) {
+	return nil, services.ErrInvalidAPIKey
+}
+
+// newAPIKeyRequest builds a request from a signed-in user with the given role
+func newAPIKeyRequest(method, target, body, role string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	ctx := context.WithValue(r.Context(), "userID", "user-1")
+	ctx = context.WithValue(ctx, "userRole", role)
+	return r.WithContext(ctx)
+}
+
+func TestAPIKeyHandlerLifecycle(t *testing.T) {
+	handler := NewAPIKeyHandler(&fakeAPIKeyService{})
+
+	rec := httptest.NewRecorder()
+	handler.CreateAPIKey(rec, newAPIKeyRequest(http.MethodPost, "/api/keys", `{"name": "CI", "scopes": ["scan:write"]}`, services.RoleUser))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+	var created services.APIKey
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode created key: %v", err)
+	}
+	if created.Key == "" || created.Prefix == "" {
+		t.Errorf("created key = %+v, want the key and its prefix", created)
+	}
+
+	// Listings identify keys by prefix and never include the secret
+	rec = httptest.NewRecorder()
+	handler.ListAPIKeys(rec, newAPIKeyRequest(http.MethodGet, "/api/keys", "", services.RoleUser))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), created.Key) || !strings.Contains(rec.Body.String(), created.Prefix) {
+		t.Errorf("list status = %d, body %s; want the prefix without the key", rec.Code, rec.Body.String())
+	}
+
+	revoke := func(keyID string) int {
+		r := newAPIKeyRequest(http.MethodDelete, "/api/keys/"+keyID, "", services.RoleUser)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", keyID)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+		rec := httptest.NewRecorder()
+		handler.RevokeAPIKey(rec, r)
+		return rec.Code
+	}
+	if status := revoke(created.ID); status != http.StatusNoContent {
+		t.Errorf("revoke status = %d, want 204", status)
+	}
+	if status := revoke(created.ID); status != http.StatusNotFound {
+		t.Errorf("second revoke status = %d, want 404", status)
+	}
+}
+
+func TestCreateAPIKeyValidatesScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		role       string
+		wantStatus int
+	}{
+		{name: "unknown scope", body: `{"scopes": ["repo:everything"]}`, role: services.RoleUser, wantStatus: http.StatusBadRequest},
+		{name: "wildcard scope", body: `{"scopes": ["*"]}`, role: services.RoleAdmin, wantStatus: http.StatusBadRequest},
+		{name: "admin scope for a user", body: `{"scopes": ["admin"]}`, role: services.RoleUser, wantStatus: http.StatusForbidden},
+		{name: "admin scope for an admin", body: `{"scopes": ["admin"]}`, role: services.RoleAdmin, wantStatus: http.StatusCreated},
+		{name: "default scopes", body: `{}`, role: services.RoleUser, wantStatus: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewAPIKeyHandler(&fakeAPIKeyService{}).CreateAPIKey(rec, newAPIKeyRequest(http.MethodPost, "/api/keys", tt.body, tt.role))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
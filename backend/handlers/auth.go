@@ -3,12 +3,13 @@ package handlers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -20,14 +21,18 @@ import (
 // AuthHandler handles authentication-related API requests
 // This includes user login, registration, and token validation
 type AuthHandler struct {
-	JWTSecret string // Secret key used for signing JWT tokens
+	JWTSecret     string                       // Secret key used for signing JWT tokens
+	RefreshTokens services.RefreshTokenService // Issues and rotates refresh tokens
+	OAuthStates   services.OAuthStateStore     // Keeps the state of Google logins in progress
 }
 
 // NewAuthHandler creates a new authentication handler with the provided dependencies
-// It initializes the handler with the authentication service and JWT secret
-func NewAuthHandler(jwtSecret string) *AuthHandler {
+// It initializes the handler with the JWT secret, the refresh token service, and the OAuth state store
+func NewAuthHandler(jwtSecret string, refreshTokens services.RefreshTokenService, oauthStates services.OAuthStateStore) *AuthHandler {
 	return &AuthHandler{
-		JWTSecret: jwtSecret,
+		JWTSecret:     jwtSecret,
+		RefreshTokens: refreshTokens,
+		OAuthStates:   oauthStates,
 	}
 }
 
@@ -55,48 +60,68 @@ type RegisterRequest struct {
 // LoginResponse represents the response body for successful login
 // This contains the authentication token and user information
 type LoginResponse struct {
-	Token     string         `json:"token"`      // JWT token for authentication
-	User      *services.User `json:"user"`       // User information
-	ExpiresAt int64          `json:"expires_at"` // Token expiration timestamp
+	Token            string         `json:"token"`              // JWT token for authentication
+	User             *services.User `json:"user"`               // User information
+	ExpiresAt        int64          `json:"expires_at"`         // Token expiration timestamp
+	RefreshToken     string         `json:"refresh_token"`      // Exchange at /auth/refresh for a new token
+	RefreshExpiresAt int64          `json:"refresh_expires_at"` // Refresh token expiration timestamp
+}
+
+// RefreshRequest carries the refresh token for /auth/refresh and /auth/logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // Login handles user authentication with email and password
 // This endpoint validates credentials and returns a JWT token if successful
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
 	// Validate email and password
 	if req.Email == "" || req.Password == "" {
-		http.Error(w, "Email and password are required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "missing_field", "Email and password are required")
 		return
 	}
 
-	// Email/password login not implemented - only Google Sign-in is supported
-	http.Error(w, "Email/password login not implemented. Please use Google Sign-in.", http.StatusNotImplemented)
-	return
+	log := logger.FromContext(r.Context())
+	authService := services.GetAuthService()
+
+	user, err := authService.AuthenticatePassword(r.Context(), req.Email, req.Password)
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials):
+		respondError(w, http.StatusUnauthorized, "invalid_credentials", err.Error())
+		return
+	case errors.Is(err, services.ErrPasswordNotSet):
+		respondError(w, http.StatusUnauthorized, "password_login_unavailable", "This account has no password. Please use Google Sign-in.")
+		return
+	case err != nil:
+		log.Error("Failed to authenticate user", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to log in")
+		return
+	}
+
+	h.writeLoginResponse(w, r, authService, user, http.StatusOK)
 }
 
 // GoogleLogin handles authentication with Google OAuth
 // This endpoint verifies the Google ID token and creates/updates the user
 func (h *AuthHandler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
 	var req GoogleLoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
 	// Validate ID token
 	if req.IDToken == "" {
-		http.Error(w, "ID token is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "missing_field", "ID token is required")
 		return
 	}
 
 	// Google login not implemented
-	http.Error(w, "Google login not implemented", http.StatusNotImplemented)
+	respondError(w, http.StatusNotImplemented, "not_implemented", "Google login not implemented")
 	return
 }
 
@@ -104,20 +129,130 @@ func (h *AuthHandler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
 // This endpoint creates a new user in the database with the provided information
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
 	// Validate request fields
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = strings.TrimSpace(req.Email)
 	if req.Email == "" || req.Password == "" || req.Name == "" {
-		http.Error(w, "Name, email, and password are required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "missing_field", "Name, email, and password are required")
+		return
+	}
+	if _, err := mail.ParseAddress(req.Email); err != nil || strings.Contains(req.Email, " ") {
+		respondError(w, http.StatusBadRequest, "invalid_email", "Invalid email address")
+		return
+	}
+	if problem := services.ValidatePassword(req.Password); problem != "" {
+		respondError(w, http.StatusBadRequest, "weak_password", problem)
 		return
 	}
 
-	// User registration not implemented - only Google Sign-in is supported
-	http.Error(w, "User registration not implemented. Please use Google Sign-in.", http.StatusNotImplemented)
-	return
+	log := logger.FromContext(r.Context())
+	authService := services.GetAuthService()
+
+	user, err := authService.RegisterWithPassword(r.Context(), req.Name, req.Email, req.Password)
+	if errors.Is(err, services.ErrEmailTaken) {
+		respondError(w, http.StatusConflict, "email_taken", err.Error())
+		return
+	}
+	if err != nil {
+		log.Error("Failed to register user", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to register user")
+		return
+	}
+
+	h.writeLoginResponse(w, r, authService, user, http.StatusCreated)
+}
+
+// writeLoginResponse issues a JWT and a refresh token for the user and writes them with the user's details
+func (h *AuthHandler) writeLoginResponse(w http.ResponseWriter, r *http.Request, authService *services.AuthService, user *services.User, status int) {
+	token, err := authService.GenerateJWT(user.ID, user.Email, user.Role)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to generate JWT", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "token_error", "Failed to generate token")
+		return
+	}
+
+	refresh, err := h.RefreshTokens.Issue(r.Context(), user.ID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to issue refresh token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "token_error", "Failed to generate token")
+		return
+	}
+
+	h.writeTokens(w, token, user, refresh, status)
+}
+
+// writeTokens writes an access token, its user, and a refresh token as a LoginResponse
+func (h *AuthHandler) writeTokens(w http.ResponseWriter, token string, user *services.User, refresh *services.RefreshToken, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(LoginResponse{
+		Token:            token,
+		User:             user,
+		ExpiresAt:        time.Now().Add(services.JWTLifetime).Unix(),
+		RefreshToken:     refresh.Token,
+		RefreshExpiresAt: refresh.ExpiresAt.Unix(),
+	})
+}
+
+// Refresh exchanges a refresh token for a new access token
+// The refresh token is rotated: the one presented stops working and a new one is returned
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "missing_field", "refresh_token is required")
+		return
+	}
+
+	log := logger.FromContext(r.Context())
+
+	user, next, err := h.RefreshTokens.Rotate(r.Context(), req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		respondError(w, http.StatusUnauthorized, "invalid_refresh_token", err.Error())
+		return
+	}
+	if err != nil {
+		log.Error("Failed to rotate refresh token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to refresh token")
+		return
+	}
+
+	token, err := services.GetAuthService().GenerateJWT(user.ID, user.Email, user.Role)
+	if err != nil {
+		log.Error("Failed to generate JWT", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "token_error", "Failed to generate token")
+		return
+	}
+
+	log.Info("Access token refreshed", zap.String("user_id", user.ID))
+	h.writeTokens(w, token, user, next, http.StatusOK)
+}
+
+// Logout revokes a refresh token so it can no longer be used
+// Access tokens already issued stay valid until they expire
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "missing_field", "refresh_token is required")
+		return
+	}
+
+	if err := h.RefreshTokens.Revoke(r.Context(), req.RefreshToken); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to revoke refresh token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to log out")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // AuthMiddleware creates middleware for JWT authentication
@@ -127,7 +262,7 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 		// Get token from Authorization header
 		tokenString := r.Header.Get("Authorization")
 		if tokenString == "" {
-			http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized: No token provided")
 			return
 		}
 
@@ -148,7 +283,7 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 
 		if err != nil || !token.Valid {
 			logger.FromContext(r.Context()).Warn("Invalid authentication token", zap.Error(err))
-			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized: Invalid token")
 			return
 		}
 
@@ -156,7 +291,7 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 		// Get user ID from claims
 		userID, ok := claims["user_id"].(string)
 		if !ok {
-			http.Error(w, "Unauthorized: Invalid user ID", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized: Invalid user ID")
 			return
 		}
 
@@ -173,14 +308,25 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// generateStateToken generates a random state token for OAuth flow
-func generateStateToken() (string, error) {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
-	if err != nil {
-		return "", err
+// oauthStateCookie names the cookie holding the ID of the browser's Google login in progress
+const oauthStateCookie = "oauth_state"
+
+// setOAuthStateCookie stores the ID of a login's OAuth state in the browser, or clears it when id is empty
+// SameSite=Lax still sends the cookie on the top-level redirect back from Google
+func setOAuthStateCookie(w http.ResponseWriter, r *http.Request, id string) {
+	maxAge := int(services.OAuthStateLifetime / time.Second)
+	if id == "" {
+		maxAge = -1
 	}
-	return base64.StdEncoding.EncodeToString(b), nil
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	})
 }
 
 // HandleGoogleLogin processes Google Sign-In requests
@@ -194,61 +340,47 @@ func (h *AuthHandler) HandleGoogleLogin(w http.ResponseWriter, r *http.Request)
 	code := r.URL.Query().Get("code")
 	if code == "" {
 		// This is the initial request, redirect to Google OAuth
-		state, err := generateStateToken()
+		// The state is kept on the server, so the callback verifies on any instance; the cookie only
+		// ties it to this browser
+		state, err := h.OAuthStates.Create(r.Context())
 		if err != nil {
-			log.Error("Failed to generate state token", zap.Error(err))
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			log.Error("Failed to create OAuth state", zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "internal_error", "Internal server error")
 			return
 		}
-
-		// Store state token in session or context for later verification
-		// For simplicity, we're using a cookie here, but a more secure method would be recommended
-		http.SetCookie(w, &http.Cookie{
-			Name:     "oauth_state",
-			Value:    state,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   r.TLS != nil,
-			MaxAge:   int(time.Now().Add(10 * time.Minute).Unix()),
-		})
+		setOAuthStateCookie(w, r, state.ID)
 
 		// Redirect to Google OAuth consent page
-		authURL := authService.GetAuthURL(state)
+		authURL := authService.GetAuthURL(state.State)
 		http.Redirect(w, r, authURL, http.StatusFound)
 		return
 	}
 
-	// This is a callback with code, exchange it for token
-	stateCookie, err := r.Cookie("oauth_state")
-	if err != nil || stateCookie.Value == "" {
-		log.Error("Failed to get state token from cookie", zap.Error(err))
-		http.Error(w, "Failed to verify state token", http.StatusBadRequest)
-		return
+	// This is a callback with code; verify the state to prevent CSRF before exchanging it
+	// The state is deleted whatever the outcome, so it can't be replayed
+	var stateID string
+	if stateCookie, err := r.Cookie(oauthStateCookie); err == nil {
+		stateID = stateCookie.Value
 	}
+	setOAuthStateCookie(w, r, "")
 
-	// Verify state token to prevent CSRF
-	state := r.URL.Query().Get("state")
-	if state == "" || state != stateCookie.Value {
-		log.Error("Invalid state token", zap.String("received", state), zap.String("expected", stateCookie.Value))
-		http.Error(w, "Invalid state token", http.StatusBadRequest)
+	err := h.OAuthStates.Consume(r.Context(), stateID, r.URL.Query().Get("state"))
+	if errors.Is(err, services.ErrInvalidOAuthState) {
+		log.Warn("Rejected OAuth callback with an invalid state", zap.Bool("has_cookie", stateID != ""))
+		respondError(w, http.StatusBadRequest, "invalid_state", "Invalid or expired state token")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to verify OAuth state", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to verify state token")
 		return
 	}
-
-	// Clear state cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "oauth_state",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   r.TLS != nil,
-		MaxAge:   -1,
-	})
 
 	// Exchange code for token
 	token, err := authService.ExchangeCodeForToken(r.Context(), code)
 	if err != nil {
 		log.Error("Failed to exchange code for token", zap.Error(err))
-		http.Error(w, "Failed to exchange code", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "oauth_error", "Failed to exchange code")
 		return
 	}
 
@@ -256,7 +388,7 @@ func (h *AuthHandler) HandleGoogleLogin(w http.ResponseWriter, r *http.Request)
 	userInfo, err := authService.GetUserInfo(r.Context(), token)
 	if err != nil {
 		log.Error("Failed to get user info", zap.Error(err))
-		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get user info")
 		return
 	}
 
@@ -264,15 +396,29 @@ func (h *AuthHandler) HandleGoogleLogin(w http.ResponseWriter, r *http.Request)
 	userID, err := authService.CreateOrUpdateUser(r.Context(), userInfo)
 	if err != nil {
 		log.Error("Failed to process user info", zap.Error(err))
-		http.Error(w, "Failed to process user info", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to process user info")
+		return
+	}
+
+	role, err := authService.UserRole(r.Context(), userID)
+	if err != nil {
+		log.Error("Failed to look up user role", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to process user info")
 		return
 	}
 
 	// Generate JWT token
-	jwtToken, err := authService.GenerateJWT(userID, userInfo.Email)
+	jwtToken, err := authService.GenerateJWT(userID, userInfo.Email, role)
 	if err != nil {
 		log.Error("Failed to generate JWT token", zap.Error(err))
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "token_error", "Failed to generate token")
+		return
+	}
+
+	refresh, err := h.RefreshTokens.Issue(r.Context(), userID)
+	if err != nil {
+		log.Error("Failed to issue refresh token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "token_error", "Failed to generate token")
 		return
 	}
 
@@ -282,7 +428,9 @@ func (h *AuthHandler) HandleGoogleLogin(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token": jwtToken,
+		"token":              jwtToken,
+		"refresh_token":      refresh.Token,
+		"refresh_expires_at": refresh.ExpiresAt.Unix(),
 		"user": map[string]interface{}{
 			"id":      userID,
 			"email":   userInfo.Email,
@@ -303,15 +451,13 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 		TokenType string `json:"token_type"` // Optional - can be "access_token" or "id_token", defaults to "access_token"
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
-		log.Error("Failed to parse request body", zap.Error(err))
-		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &requestBody) {
 		return
 	}
 
 	if requestBody.Token == "" {
 		log.Warn("Missing token in request")
-		http.Error(w, "Token is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "missing_field", "Token is required")
 		return
 	}
 
@@ -322,7 +468,8 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 
 	log.Debug("Received token for exchange",
 		zap.String("token_type", requestBody.TokenType),
-		zap.String("token_prefix", requestBody.Token[:min(10, len(requestBody.Token))]+"..."))
+		zap.String("token", logger.Redact(requestBody.Token)),
+		zap.Int("token_length", len(requestBody.Token)))
 
 	// Determine endpoint based on token type
 	endpoint := "https://www.googleapis.com/oauth2/v2/userinfo"
@@ -357,14 +504,16 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 
 	if err != nil {
 		log.Error("Failed to create request", zap.Error(err))
-		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", "Internal server error: "+err.Error())
 		return
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Error("Failed to send verification request", zap.Error(err))
-		http.Error(w, "Failed to verify token: "+err.Error(), http.StatusInternalServerError)
+		// The error quotes the request URL, which carries an ID token in its query
+		message := logger.Scrub(err.Error(), requestBody.Token)
+		log.Error("Failed to send verification request", zap.String("error", message))
+		respondError(w, http.StatusInternalServerError, "oauth_error", "Failed to verify token: "+message)
 		return
 	}
 	defer resp.Body.Close()
@@ -381,7 +530,7 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 			errorMsg += fmt.Sprintf(" - Details: %s", string(bodyBytes))
 		}
 
-		http.Error(w, errorMsg, http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, "oauth_error", errorMsg)
 		return
 	}
 
@@ -390,7 +539,7 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Error("Failed to read response body", zap.Error(err))
-		http.Error(w, "Failed to read user info: "+err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "oauth_error", "Failed to read user info: "+err.Error())
 		return
 	}
 
@@ -398,7 +547,7 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 
 	if err := json.Unmarshal(bodyBytes, &userInfo); err != nil {
 		log.Error("Failed to parse user info", zap.Error(err), zap.String("body", string(bodyBytes)))
-		http.Error(w, "Failed to process user info: "+err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to process user info: "+err.Error())
 		return
 	}
 
@@ -417,7 +566,7 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 
 	if userInfo.ID == "" || userInfo.Email == "" {
 		log.Error("Incomplete user info from Google", zap.Any("userInfo", userInfo))
-		http.Error(w, "Incomplete user info received from Google", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "oauth_error", "Incomplete user info received from Google")
 		return
 	}
 
@@ -428,22 +577,38 @@ func (h *AuthHandler) HandleTokenExchange(w http.ResponseWriter, r *http.Request
 	userID, err := authService.CreateOrUpdateUser(r.Context(), &userInfo)
 	if err != nil {
 		log.Error("Failed to process user", zap.Error(err))
-		http.Error(w, "Failed to process user: "+err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to process user: "+err.Error())
+		return
+	}
+
+	role, err := authService.UserRole(r.Context(), userID)
+	if err != nil {
+		log.Error("Failed to look up user role", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to process user")
 		return
 	}
 
 	// Generate JWT token
-	jwtToken, err := authService.GenerateJWT(userID, userInfo.Email)
+	jwtToken, err := authService.GenerateJWT(userID, userInfo.Email, role)
 	if err != nil {
 		log.Error("Failed to generate JWT", zap.Error(err))
-		http.Error(w, "Failed to generate token: "+err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "token_error", "Failed to generate token: "+err.Error())
+		return
+	}
+
+	refresh, err := h.RefreshTokens.Issue(r.Context(), userID)
+	if err != nil {
+		log.Error("Failed to issue refresh token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "token_error", "Failed to generate token")
 		return
 	}
 
 	// Return JWT token
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"token": jwtToken,
+	json.NewEncoder(w).Encode(map[string]any{
+		"token":              jwtToken,
+		"refresh_token":      refresh.Token,
+		"refresh_expires_at": refresh.ExpiresAt.Unix(),
 	})
 }
 
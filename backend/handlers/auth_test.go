@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// postJSON calls an auth handler with a JSON body and returns the recorded response
+func postJSON(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec
+}
+
+func TestRegisterRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed body", body: `{"email":`},
+		{name: "missing name", body: `{"email": "ada@example.com", "password": "correcthorse1"}`},
+		{name: "blank name", body: `{"name": "  ", "email": "ada@example.com", "password": "correcthorse1"}`},
+		{name: "invalid email", body: `{"name": "Ada", "email": "not an email", "password": "correcthorse1"}`},
+		{name: "short password", body: `{"name": "Ada", "email": "ada@example.com", "password": "abc1"}`},
+		{name: "password without a digit", body: `{"name": "Ada", "email": "ada@example.com", "password": "correcthorsebattery"}`},
+	}
+
+	// Each request is refused before the handler touches the database
+	handler := &AuthHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := postJSON(handler.Register, "/auth/register", tt.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	queries := db.NewQueries()
+	queries.SetDB(testdb.Open(t))
+	services.InitAuthService(queries)
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	handler := &AuthHandler{}
+	email := "user-" + uuid.NewString() + "@example.com"
+	credentials := `{"email": "` + email + `", "password": "correcthorse1"}`
+
+	rec := postJSON(handler.Register, "/auth/register", `{"name": "Ada", "email": "`+email+`", "password": "correcthorse1"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register status = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+	var registered LoginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&registered); err != nil {
+		t.Fatalf("decode register response: %v", err)
+	}
+	if registered.Token == "" || registered.User == nil || registered.User.Email != email {
+		t.Fatalf("register response = %+v, want a token for %s", registered, email)
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		body       string
+		wantStatus int
+	}{
+		{name: "duplicate email", handler: handler.Register, body: `{"name": "Mallory", "email": "` + strings.ToUpper(email) + `", "password": "otherhorse2"}`, wantStatus: http.StatusConflict},
+		{name: "login", handler: handler.Login, body: credentials, wantStatus: http.StatusOK},
+		{name: "wrong password", handler: handler.Login, body: `{"email": "` + email + `", "password": "wronghorse1"}`, wantStatus: http.StatusUnauthorized},
+		{name: "unknown email", handler: handler.Login, body: `{"email": "nobody-` + uuid.NewString() + `@example.com", "password": "correcthorse1"}`, wantStatus: http.StatusUnauthorized},
+		{name: "missing password", handler: handler.Login, body: `{"email": "` + email + `"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postJSON(tt.handler, "/auth", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var login LoginResponse
+			if err := json.NewDecoder(rec.Body).Decode(&login); err != nil {
+				t.Fatalf("decode login response: %v", err)
+			}
+			userID, err := services.GetAuthService().VerifyJWT(login.Token)
+			if err != nil {
+				t.Fatalf("login token is invalid: %v", err)
+			}
+			if userID != registered.User.ID {
+				t.Errorf("token is for %s, want %s", userID, registered.User.ID)
+			}
+		})
+	}
+}
+
+func TestLoginToAGoogleOnlyAccount(t *testing.T) {
+	sqlDB := testdb.Open(t)
+	queries := db.NewQueries()
+	queries.SetDB(sqlDB)
+	services.InitAuthService(queries)
+
+	email := "user-" + uuid.NewString() + "@example.com"
+	if _, err := sqlDB.Exec(`INSERT INTO users (email, name, google_id) VALUES ($1, 'Grace', $2)`,
+		email, "google-"+uuid.NewString()); err != nil {
+		t.Fatalf("insert Google user: %v", err)
+	}
+
+	// The user is told to sign in with Google rather than that the password is wrong
+	handler := &AuthHandler{}
+	rec := postJSON(handler.Login, "/auth/login", `{"email": "`+email+`", "password": "correcthorse1"}`)
+	if got := decodeErrorResponse(t, rec, http.StatusUnauthorized); got.Code != "password_login_unavailable" {
+		t.Errorf("error code = %q, want password_login_unavailable", got.Code)
+	}
+}
+
+// fakeOAuthStates keeps OAuth states in memory, deleting each when it is consumed
+type fakeOAuthStates struct {
+	states map[string]*services.OAuthState
+	now    time.Time
+}
+
+func (f *fakeOAuthStates) Create(ctx context.Context) (*services.OAuthState, error) {
+	id := uuid.NewString()
+	state := &services.OAuthState{ID: id, State: "state-" + id, ExpiresAt: f.now.Add(services.OAuthStateLifetime)}
+	f.states[id] = state
+	return state, nil
+}
+
+func (f *fakeOAuthStates) Consume(ctx context.Context, id, state string) error {
+	stored, ok := f.states[id]
+	delete(f.states, id)
+	if !ok || stored.State != state || !f.now.Before(stored.ExpiresAt) {
+		return services.ErrInvalidOAuthState
+	}
+	return nil
+}
+
+func TestGoogleLoginOAuthState(t *testing.T) {
+	services.InitAuthService(db.NewQueries())
+
+	tests := []struct {
+		name       string
+		callback   func(login *http.Cookie, state string) *http.Request
+		advance    time.Duration // Time passing between the login and the callback
+		wantStates int           // States left in the store after the callback
+	}{
+		{
+			name: "forged state",
+			callback: func(login *http.Cookie, state string) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/auth/google/callback?code=c&state=forged", nil)
+				r.AddCookie(login)
+				return r
+			},
+		},
+		{
+			name: "missing cookie",
+			callback: func(login *http.Cookie, state string) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/auth/google/callback?code=c&state="+url.QueryEscape(state), nil)
+			},
+			wantStates: 1,
+		},
+		{
+			name: "expired state",
+			callback: func(login *http.Cookie, state string) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/auth/google/callback?code=c&state="+url.QueryEscape(state), nil)
+				r.AddCookie(login)
+				return r
+			},
+			advance: services.OAuthStateLifetime + time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := &fakeOAuthStates{states: map[string]*services.OAuthState{}, now: time.Now()}
+			handler := &AuthHandler{OAuthStates: states}
+
+			// Starting the login stores a state and hands the browser its ID for a proper 10 minutes
+			rec := httptest.NewRecorder()
+			handler.HandleGoogleLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/google", nil))
+			if rec.Code != http.StatusFound {
+				t.Fatalf("login status = %d, want 302", rec.Code)
+			}
+			location, err := url.Parse(rec.Header().Get("Location"))
+			if err != nil {
+				t.Fatalf("parse redirect: %v", err)
+			}
+			cookies := rec.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || cookies[0].MaxAge != 600 {
+				t.Fatalf("login cookies = %+v, want %s with MaxAge 600", cookies, oauthStateCookie)
+			}
+			stored, ok := states.states[cookies[0].Value]
+			if !ok || location.Query().Get("state") != stored.State {
+				t.Fatalf("redirect state %q doesn't match the state stored under the cookie's ID", location.Query().Get("state"))
+			}
+
+			states.now = states.now.Add(tt.advance)
+			rec = httptest.NewRecorder()
+			handler.HandleGoogleLogin(rec, tt.callback(cookies[0], stored.State))
+
+			if got := decodeErrorResponse(t, rec, http.StatusBadRequest); got.Code != "invalid_state" {
+				t.Errorf("error code = %q, want invalid_state", got.Code)
+			}
+			if len(states.states) != tt.wantStates {
+				t.Errorf("%d states left after the callback, want %d", len(states.states), tt.wantStates)
+			}
+			if cleared := rec.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+				t.Errorf("callback cookies = %+v, want the state cookie cleared", cleared)
+			}
+		})
+	}
+}
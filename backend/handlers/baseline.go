@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// PromoteBaseline accepts a scan's findings as the repository baseline
+// Later results hide findings with the same fingerprints unless ?include_baselined=true is passed
+func (h *RepositoryHandler) PromoteBaseline(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+	scanID := chi.URLParam(r, "scanID")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	count, err := services.NewBaselineService(db.NewQueries()).PromoteBaseline(r.Context(), repoID, scanID)
+	if errors.Is(err, services.ErrScanNotInRepository) {
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found for this repository")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to promote baseline",
+			zap.String("repo_id", repoID),
+			zap.String("scan_id", scanID),
+			zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to promote baseline")
+		return
+	}
+
+	log.Info("Promoted scan to repository baseline",
+		zap.String("repo_id", repoID),
+		zap.String("scan_id", scanID),
+		zap.Int("fingerprints", count))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository_id":     repoID,
+		"baseline_scan_id":  scanID,
+		"baseline_findings": count,
+	})
+}
+
+// ClearBaseline removes the repository baseline so all findings are reported again
+func (h *RepositoryHandler) ClearBaseline(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	count, err := services.NewBaselineService(db.NewQueries()).ClearBaseline(r.Context(), repoID)
+	if err != nil {
+		log.Error("Failed to clear baseline", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to clear baseline")
+		return
+	}
+
+	log.Info("Cleared repository baseline", zap.String("repo_id", repoID), zap.Int("fingerprints", count))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository_id":    repoID,
+		"removed_findings": count,
+	})
+}
+
+// authorizeRepository checks that the authenticated user can access the repository
+// It writes the error response and returns false when the request should stop
+func (h *RepositoryHandler) authorizeRepository(w http.ResponseWriter, r *http.Request, repoID string) bool {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return false
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return false
+	}
+
+	allowed, err := userCanAccessRepository(r.Context(), dbConn, userID, repoID)
+	if err != nil {
+		log.Error("Error checking repository access", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
+		return false
+	}
+	if !allowed {
+		log.Warn("User attempted to access unauthorized repository",
+			zap.String("user_id", userID),
+			zap.String("repo_id", repoID))
+		respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// DefaultScanBatchMaxRepositories is how many repositories one POST /scan/batch may list when
+// SCAN_BATCH_MAX_REPOSITORIES is unset
+const DefaultScanBatchMaxRepositories = 20
+
+// batchScanFailed is the status of a batch entry whose scan wasn't started; its error says why
+const batchScanFailed = "failed"
+
+// scanBatchMaxRepositories returns the most repositories one batch scan request may list
+// It is read from SCAN_BATCH_MAX_REPOSITORIES; an invalid value falls back to the default
+func scanBatchMaxRepositories() int {
+	value := strings.TrimSpace(os.Getenv("SCAN_BATCH_MAX_REPOSITORIES"))
+	if value == "" {
+		return DefaultScanBatchMaxRepositories
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		logger.Warn("Invalid SCAN_BATCH_MAX_REPOSITORIES value, using the default",
+			zap.String("value", value),
+			zap.Int("default", DefaultScanBatchMaxRepositories))
+		return DefaultScanBatchMaxRepositories
+	}
+	return limit
+}
+
+// batchScanEntry is the outcome of one repository of a batch scan request
+type batchScanEntry struct {
+	RepoURL      string       `json:"repo_url"`
+	ScanID       string       `json:"scan_id,omitempty"` // Repository ID, which the status endpoints accept, as POST /scan answers
+	ScanRecordID string       `json:"scan_record_id,omitempty"`
+	RunID        string       `json:"run_id,omitempty"`
+	RepositoryID string       `json:"repository_id,omitempty"`
+	Status       string       `json:"status"` // scan_initiated, scan_in_progress, or failed
+	Reused       bool         `json:"reused,omitempty"`
+	Error        *errorDetail `json:"error,omitempty"`
+
+	httpStatus int // Status POST /scan would have answered this entry with
+	retryAfter int // Seconds the entry's Retry-After would have been; 0 for none
+}
+
+// ScanPublicRepositories starts a public scan of every repository in a batch, answering with the outcome of
+// each entry in request order. Entries are checked like POST /scan, each against the caller's rate limit and
+// ALLOWED_SCAN_OWNERS, and one that can't be scanned, such as an invalid URL, fails without stopping the rest.
+// The response is 202 when any scan started; when none did it carries their shared status, or 422 if they differ
+func (h *RepositoryHandler) ScanPublicRepositories(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	var req struct {
+		RepoURLs       []string `json:"repo_urls"`
+		Email          string   `json:"email"`           // Optional email notified as each scan finishes
+		FileExtensions []string `json:"file_extensions"` // Extensions to scan, e.g. "go" or ".js"; empty uses the defaults
+	}
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if len(req.RepoURLs) == 0 {
+		respondError(w, http.StatusBadRequest, "missing_field", "At least one repository URL is required")
+		return
+	}
+
+	severityThreshold := strings.TrimSpace(r.URL.Query().Get("severity_threshold"))
+	var fieldErrors []FieldError
+	if maxRepositories := scanBatchMaxRepositories(); len(req.RepoURLs) > maxRepositories {
+		fieldErrors = append(fieldErrors, FieldError{Field: "repo_urls",
+			Message: fmt.Sprintf("at most %d repositories can be scanned in one batch", maxRepositories)})
+	}
+	if severityThreshold != "" && services.SeverityRank(severityThreshold) == 0 {
+		fieldErrors = append(fieldErrors, FieldError{Field: "severity_threshold", Message: "must be one of low, medium, high, critical"})
+	}
+	fieldErrors = append(fieldErrors, validateFileExtensions(req.FileExtensions)...)
+	if len(fieldErrors) > 0 {
+		writeValidationErrors(w, fieldErrors)
+		return
+	}
+	fileExtensions, _ := services.NormalizeFileExtensions(req.FileExtensions) // Validated above
+	if len(fileExtensions) == 0 {
+		fileExtensions = services.DefaultFileExtensions()
+	}
+
+	log.Info("Handling batch public repository scan request", zap.Int("repositories", len(req.RepoURLs)))
+
+	entries := make([]batchScanEntry, len(req.RepoURLs))
+	started := 0
+	for i, repoURL := range req.RepoURLs {
+		if strings.TrimSpace(repoURL) == "" {
+			entries[i] = batchScanFailure(repoURL, &scanStartError{Status: http.StatusBadRequest, Code: "missing_field",
+				Message: "Repository URL is required"})
+			continue
+		}
+
+		// The request itself paid for the first entry; every further one takes its own token
+		if i > 0 && h.BatchItemRateLimit != nil {
+			if allowed, retryAfter := h.BatchItemRateLimit(r); !allowed {
+				seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+				entries[i] = batchScanFailure(repoURL, &scanStartError{Status: http.StatusTooManyRequests, Code: "rate_limited",
+					Message: fmt.Sprintf("Too many requests, retry in %d seconds", seconds), RetryAfter: seconds})
+				continue
+			}
+		}
+
+		scan, err := h.startPublicScan(r.Context(), publicScanRequest{
+			RepoURL:        repoURL,
+			Email:          req.Email,
+			FileExtensions: fileExtensions,
+			MinSeverity:    strings.ToLower(severityThreshold),
+		})
+		if err != nil {
+			entries[i] = batchScanFailure(repoURL, err)
+			continue
+		}
+		entries[i] = batchScanEntry{
+			RepoURL:      repoURL,
+			ScanID:       scan.RepositoryID,
+			ScanRecordID: scan.ScanID,
+			RunID:        scan.RunID,
+			RepositoryID: scan.RepositoryID,
+			Status:       "scan_initiated",
+			Reused:       scan.Reused,
+		}
+		if scan.Reused {
+			entries[i].Status = "scan_in_progress"
+		}
+		started++
+	}
+
+	log.Info("Batch public repository scan request handled",
+		zap.Int("repositories", len(entries)),
+		zap.Int("started", started),
+		zap.Int("failed", len(entries)-started))
+
+	if started > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"scans": entries})
+		return
+	}
+
+	status, retryAfter := batchFailureStatus(entries)
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	writeErrorBody(w, status, map[string]any{
+		"error": errorDetail{Code: "no_scans_started", Message: "None of the repositories could be scanned"},
+		"scans": entries,
+	})
+}
+
+// batchScanFailure describes a batch entry whose scan wasn't started
+// An entry conflicting with a running scan of its repository carries that scan's IDs, as POST /scan does
+func batchScanFailure(repoURL string, err error) batchScanEntry {
+	var startErr *scanStartError
+	if !errors.As(err, &startErr) {
+		startErr = &scanStartError{Status: http.StatusInternalServerError, Code: "internal_error", Message: err.Error()}
+	}
+
+	entry := batchScanEntry{
+		RepoURL:    repoURL,
+		Status:     batchScanFailed,
+		Error:      &errorDetail{Code: startErr.Code, Message: startErr.Message},
+		httpStatus: startErr.Status,
+		retryAfter: startErr.RetryAfter,
+	}
+	if running := startErr.Running; running != nil {
+		entry.ScanID = running.RepositoryID
+		entry.ScanRecordID = running.ScanID
+		entry.RunID = running.RunID
+		entry.RepositoryID = running.RepositoryID
+		entry.Status = startErr.Code
+	}
+	return entry
+}
+
+// batchFailureStatus returns the status of a batch none of whose scans started: the status every entry
+// failed with, or 422 when they failed differently, and the longest Retry-After among the entries
+func batchFailureStatus(entries []batchScanEntry) (int, int) {
+	status := entries[0].httpStatus
+	retryAfter := 0
+	for _, entry := range entries {
+		if entry.httpStatus != status {
+			status = http.StatusUnprocessableEntity
+		}
+		retryAfter = max(retryAfter, entry.retryAfter)
+	}
+	return status, retryAfter
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxRequestBodyBytes bounds JSON request bodies when MAX_REQUEST_BODY_BYTES is unset
+const defaultMaxRequestBodyBytes = 1 << 20
+
+// maxRequestBodyBytes returns the largest JSON request body accepted, read from MAX_REQUEST_BODY_BYTES
+func maxRequestBodyBytes() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("MAX_REQUEST_BODY_BYTES"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return defaultMaxRequestBodyBytes
+}
+
+// isJSONContentType reports whether a Content-Type header names JSON, e.g. "application/json; charset=utf-8"
+func isJSONContentType(header string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeJSONBody reads a required JSON request body into dst
+// Bodies that aren't JSON answer 415 and bodies over the size limit answer 400, so a client can't stream
+// an unbounded body into the server. It writes the error response itself and returns false on failure
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	return decodeBody(w, r, dst, false)
+}
+
+// decodeOptionalJSONBody is decodeJSONBody for endpoints whose body may be left out entirely
+// An empty body leaves dst unchanged, whatever its Content-Type
+func decodeOptionalJSONBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	return decodeBody(w, r, dst, true)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any, optional bool) bool {
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		respondError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+		return false
+	}
+
+	limit := maxRequestBodyBytes()
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit)).Decode(dst)
+	if err == nil || (optional && errors.Is(err, io.EOF)) {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		respondError(w, http.StatusBadRequest, "request_too_large", fmt.Sprintf("Request body too large: the limit is %d bytes", limit))
+	case errors.Is(err, io.EOF):
+		respondError(w, http.StatusBadRequest, "invalid_request", "Request body is required")
+	default:
+		respondError(w, http.StatusBadRequest, "invalid_json", "Invalid request body: "+err.Error())
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsJSONContentType(t *testing.T) {
+	for header, want := range map[string]bool{
+		"application/json":                  true,
+		"Application/JSON; charset=utf-8":   true,
+		"application/merge-patch+json":      true,
+		"text/plain":                        false,
+		"application/x-www-form-urlencoded": false,
+		"":                                  false,
+		"application/json; =":               false,
+	} {
+		if got := isJSONContentType(header); got != want {
+			t.Errorf("isJSONContentType(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BODY_BYTES", "64")
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		optional    bool
+		wantOK      bool
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "valid", contentType: "application/json", body: `{"name": "Ada"}`, wantOK: true},
+		{name: "wrong content type", contentType: "text/plain", body: `{"name": "Ada"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "no content type", body: `{"name": "Ada"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "oversized", contentType: "application/json", body: `{"name": "` + strings.Repeat("a", 100) + `"}`, wantStatus: http.StatusBadRequest, wantMessage: "the limit is 64 bytes"},
+		{name: "malformed", contentType: "application/json", body: `{"name":`, wantStatus: http.StatusBadRequest, wantMessage: "Invalid request body"},
+		{name: "missing", contentType: "application/json", wantStatus: http.StatusBadRequest, wantMessage: "Request body is required"},
+		{name: "optional and left out", optional: true, wantOK: true},
+		{name: "optional but oversized", optional: true, contentType: "application/json", body: strings.Repeat(" ", 100) + `{}`, wantStatus: http.StatusBadRequest},
+		{name: "optional with the wrong content type", optional: true, contentType: "text/plain", body: `{}`, wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			var dst struct {
+				Name string `json:"name"`
+			}
+			decode := decodeJSONBody
+			if tt.optional {
+				decode = decodeOptionalJSONBody
+			}
+			if ok := decode(rec, r, &dst); ok != tt.wantOK {
+				t.Fatalf("decode = %v, want %v: %s", ok, tt.wantOK, rec.Body.String())
+			}
+			if tt.wantOK {
+				return
+			}
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantMessage) {
+				t.Errorf("response = %d %q, want %d mentioning %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMaxRequestBodyBytes(t *testing.T) {
+	for value, want := range map[string]int64{
+		"":     defaultMaxRequestBodyBytes,
+		"4096": 4096,
+		"0":    defaultMaxRequestBodyBytes,
+		"-1":   defaultMaxRequestBodyBytes,
+		"1MB":  defaultMaxRequestBodyBytes,
+	} {
+		t.Setenv("MAX_REQUEST_BODY_BYTES", value)
+		if got := maxRequestBodyBytes(); got != want {
+			t.Errorf("maxRequestBodyBytes() with %q = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestEndpointsRejectOversizedAndNonJSONBodies(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BODY_BYTES", "128")
+	oversized := `{"repo_url": "https://github.com/octocat/` + strings.Repeat("a", 200) + `"}`
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "public scan, oversized", handler: (&RepositoryHandler{}).ScanPublicRepository, contentType: "application/json", body: oversized, wantStatus: http.StatusBadRequest},
+		{name: "public scan, form body", handler: (&RepositoryHandler{}).ScanPublicRepository, contentType: "application/x-www-form-urlencoded", body: "repo_url=x", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "register, oversized", handler: (&AuthHandler{}).Register, contentType: "application/json", body: `{"name": "` + strings.Repeat("a", 200) + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "login, text body", handler: (&AuthHandler{}).Login, contentType: "text/plain", body: `{"email": "a@example.com"}`, wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	// Each request is refused before the handler touches the database
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			tt.handler(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
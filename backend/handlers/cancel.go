@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.temporal.io/api/serviceerror"
+	"go.uber.org/zap"
+)
+
+// CancelClone cancels a repository scan that has not got past cloning, e.g. a clone stuck on a huge repo
+// The scan is marked canceled only while it is still queued or cloning, and its workflow canceled after;
+// a scan that moved on to scanning in the meantime is left running. The clone activity is interrupted and
+// removes its partial checkout, and the scan activity refuses to start a scan already marked canceled
+func (h *RepositoryHandler) CancelClone(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+
+	var scanID, status string
+	err := dbConn.QueryRowContext(r.Context(),
+		`SELECT id, status FROM scans
+		WHERE repository_id = $1 AND status IN ('queued', 'cloning', 'scanning', 'pending', 'in_progress')
+		ORDER BY created_at DESC LIMIT 1`,
+		repoID).Scan(&scanID, &status)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "no_active_scan", "No active scan for this repository")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to look up active scan", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	// Once files are being analyzed the clone is done, so there is nothing left to abort here
+	status = services.NormalizeScanStatus(status)
+	if status != services.ScanStatusQueued && status != services.ScanStatusCloning {
+		respondError(w, http.StatusConflict, "scan_past_cloning", "Scan has already finished cloning")
+		return
+	}
+
+	// Claim the cancellation before touching the workflow: the scan can finish cloning at any moment,
+	// and once the scan activity has moved it to scanning this update matches nothing
+	result, err := dbConn.ExecContext(r.Context(),
+		`UPDATE scans SET status = $1, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND status IN ('queued', 'cloning', 'pending')`,
+		services.ScanStatusCanceled, scanID)
+	if err != nil {
+		log.Error("Failed to mark cloning scan canceled", zap.String("scan_id", scanID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+	if canceled, _ := result.RowsAffected(); canceled == 0 {
+		respondError(w, http.StatusConflict, "scan_past_cloning", "Scan has already finished cloning")
+		return
+	}
+
+	err = h.TemporalClient.CancelWorkflow(r.Context(), scanWorkflowID(scanID), "")
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		switch {
+		case errors.As(err, &notFound):
+			respondError(w, http.StatusNotFound, "scan_workflow_not_found", "Scan workflow not found")
+		case isTemporalUnavailable(err):
+			writeScanServiceUnavailable(w)
+		default:
+			log.Error("Failed to cancel scan workflow",
+				zap.String("repo_id", repoID),
+				zap.String("scan_id", scanID),
+				zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "internal_error", "Failed to cancel scan")
+		}
+		return
+	}
+
+	log.Info("Requested cancellation of cloning scan",
+		zap.String("repo_id", repoID),
+		zap.String("scan_id", scanID),
+		zap.String("status", status))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository_id":  repoID,
+		"scan_record_id": scanID,
+		"status":         "cancel_requested",
+	})
+}
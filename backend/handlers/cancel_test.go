@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/mocks"
+)
+
+func TestCancelCloneOnlyAbortsScansStillCloning(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string // Status of the repository's active scan; empty for none
+		wantStatus int
+		wantCancel bool
+		wantRecord string // Status left on the scan record; empty when there is none
+	}{
+		{name: "cloning", status: services.ScanStatusCloning, wantStatus: http.StatusAccepted, wantCancel: true, wantRecord: services.ScanStatusCanceled},
+		{name: "queued", status: services.ScanStatusQueued, wantStatus: http.StatusAccepted, wantCancel: true, wantRecord: services.ScanStatusCanceled},
+		{name: "already scanning", status: services.ScanStatusScanning, wantStatus: http.StatusConflict, wantRecord: services.ScanStatusScanning},
+		{name: "no active scan", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbConn := testdb.Open(t)
+			userID, repoID := createTestRepository(t, dbConn)
+			var scanID string
+			if tt.status != "" {
+				if err := dbConn.QueryRow(
+					`INSERT INTO scans (repository_id, status, started_at) VALUES ($1, $2, NOW()) RETURNING id`,
+					repoID, tt.status).Scan(&scanID); err != nil {
+					t.Fatalf("insert scan: %v", err)
+				}
+			}
+
+			// Each scan has its own workflow
+			temporalClient := &mocks.Client{}
+			temporalClient.On("CancelWorkflow", mock.Anything, "scan-workflow-"+scanID, "").Return(nil)
+
+			queries := db.NewQueries()
+			queries.SetDB(dbConn)
+			handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries), TemporalClient: temporalClient}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/repositories/"+repoID+"/scan/cancel-clone", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", repoID)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)
+			r = r.WithContext(context.WithValue(ctx, "userID", userID))
+
+			rec := httptest.NewRecorder()
+			handler.CancelClone(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if canceled := len(temporalClient.Calls) > 0; canceled != tt.wantCancel {
+				t.Errorf("workflow canceled = %v, want %v", canceled, tt.wantCancel)
+			}
+			if tt.wantRecord != "" {
+				var record string
+				if err := dbConn.QueryRow(`SELECT status FROM scans WHERE id = $1`, scanID).Scan(&record); err != nil {
+					t.Fatalf("read scan: %v", err)
+				}
+				if record != tt.wantRecord {
+					t.Errorf("scan record status = %q, want %q", record, tt.wantRecord)
+				}
+			}
+		})
+	}
+}
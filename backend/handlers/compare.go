@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// compareScanInfo describes one side of a scan comparison
+type compareScanInfo struct {
+	ID           string `json:"id"`
+	RepositoryID string `json:"repository_id"`
+	Model        string `json:"model"`
+	Status       string `json:"status"`
+}
+
+// CompareScans diffs the findings of two scans, typically the same repository scanned with two models
+// Usage: GET /api/scans/compare?a={scanID}&b={scanID}[&include_excluded=true]
+func (h *RepositoryHandler) CompareScans(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	scanAID := r.URL.Query().Get("a")
+	scanBID := r.URL.Query().Get("b")
+	if scanAID == "" || scanBID == "" {
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Both a and b scan IDs are required")
+		return
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return
+	}
+
+	progressService := services.NewScanProgressService(db.NewQueries())
+	includeExcluded := includeExcludedFindings(r)
+
+	var infos [2]*compareScanInfo
+	var findings [2][]*services.Vulnerability
+	for i, scanID := range []string{scanAID, scanBID} {
+		info, err := loadCompareScanInfo(r.Context(), dbConn, scanID)
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "scan_not_found", fmt.Sprintf("Scan %s not found", scanID))
+			return
+		}
+		if err != nil {
+			log.Error("Failed to load scan", zap.String("scan_id", scanID), zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "internal_error", "Failed to load scan")
+			return
+		}
+
+		// Report scans the user can't see as missing rather than forbidden
+		allowed, err := userCanAccessRepository(r.Context(), dbConn, userID, info.RepositoryID)
+		if err != nil {
+			log.Error("Error checking repository access", zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
+			return
+		}
+		if !allowed {
+			respondError(w, http.StatusNotFound, "scan_not_found", fmt.Sprintf("Scan %s not found", scanID))
+			return
+		}
+
+		vulns, err := progressService.ScanVulnerabilities(r.Context(), scanID)
+		if err != nil {
+			log.Error("Failed to load scan findings", zap.String("scan_id", scanID), zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "internal_error", "Failed to load scan findings")
+			return
+		}
+		vulns, _ = filterExcludedFindings(vulns, includeExcluded)
+
+		infos[i] = info
+		findings[i] = vulns
+	}
+
+	comparison := services.CompareFindings(findings[0], findings[1])
+
+	log.Info("Compared scan findings",
+		zap.String("scan_a", scanAID),
+		zap.String("scan_b", scanBID),
+		zap.Int("only_in_a", len(comparison.OnlyInA)),
+		zap.Int("only_in_b", len(comparison.OnlyInB)),
+		zap.Int("in_both", len(comparison.InBoth)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"scan_a":          infos[0],
+		"scan_b":          infos[1],
+		"same_repository": infos[0].RepositoryID == infos[1].RepositoryID,
+		"only_in_a":       comparison.OnlyInA,
+		"only_in_b":       comparison.OnlyInB,
+		"in_both":         comparison.InBoth,
+		"summary": map[string]int{
+			"only_in_a": len(comparison.OnlyInA),
+			"only_in_b": len(comparison.OnlyInB),
+			"in_both":   len(comparison.InBoth),
+		},
+	})
+}
+
+// loadCompareScanInfo reads the repository, model, and status of a scan
+func loadCompareScanInfo(ctx context.Context, dbConn *sql.DB, scanID string) (*compareScanInfo, error) {
+	info := &compareScanInfo{ID: scanID}
+	var model sql.NullString
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT repository_id, model, status FROM scans WHERE id::text = $1`,
+		scanID).Scan(&info.RepositoryID, &model, &info.Status)
+	if err != nil {
+		return nil, err
+	}
+	info.Model = model.String
+	info.Status = services.NormalizeScanStatus(info.Status)
+	return info, nil
+}
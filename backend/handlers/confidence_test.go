@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestMinConfidence(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    float64
+		wantSet bool
+		wantErr bool
+	}{
+		{query: ""},
+		{query: "?min_confidence="},
+		{query: "?min_confidence=medium", want: 0.6, wantSet: true},
+		{query: "?min_confidence=0.75", want: 0.75, wantSet: true},
+		{query: "?min_confidence=80", want: 0.8, wantSet: true},
+		{query: "?min_confidence=sure", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := minConfidence(httptest.NewRequest(http.MethodGet, "/results"+tt.query, nil))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("minConfidence(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if (got != nil) != tt.wantSet || (got != nil && *got != tt.want) {
+			t.Errorf("minConfidence(%q) = %v, want %v (set %v)", tt.query, got, tt.want, tt.wantSet)
+		}
+	}
+}
+
+func TestFilterLowConfidenceFindings(t *testing.T) {
+	confidence := func(value float64) *float64 { return &value }
+	// 0.9 stored as a float4 reads back as 0.8999999761581421
+	findings := []*services.Vulnerability{
+		{ID: "unsure", Confidence: confidence(0.2)},
+		{ID: "high", Confidence: confidence(float64(float32(0.9)))},
+		{ID: "unknown"},
+	}
+
+	tests := []struct {
+		name        string
+		minimum     *float64
+		wantIDs     []string
+		wantDropped int
+	}{
+		{name: "no minimum", wantIDs: []string{"unsure", "high", "unknown"}},
+		{name: "high", minimum: confidence(0.9), wantIDs: []string{"high", "unknown"}, wantDropped: 1},
+		{name: "above every finding", minimum: confidence(0.95), wantIDs: []string{"unknown"}, wantDropped: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := filterLowConfidenceFindings(findings, tt.minimum)
+			if dropped != tt.wantDropped || len(kept) != len(tt.wantIDs) {
+				t.Fatalf("kept %d and dropped %d, want %v and %d dropped", len(kept), dropped, tt.wantIDs, tt.wantDropped)
+			}
+			for i, id := range tt.wantIDs {
+				if kept[i].ID != id {
+					t.Errorf("kept[%d] = %s, want %s", i, kept[i].ID, id)
+				}
+			}
+		})
+	}
+}
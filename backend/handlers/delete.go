@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.uber.org/zap"
+)
+
+// DeleteRepository removes a repository together with its scans and findings
+// Only the repository's creator or an admin may delete it, and not while a scan workflow is running
+func (h *RepositoryHandler) DeleteRepository(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	dbConn := h.GitHubService.GetDatabaseConnection()
+
+	owns, err := userOwnsRepository(r.Context(), dbConn, userID, repoID)
+	if err == nil && !owns {
+		owns, err = userIsAdmin(r.Context(), dbConn, userID)
+	}
+	if err != nil {
+		log.Error("Error checking repository ownership", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
+		return
+	}
+	if !owns {
+		log.Warn("User attempted to delete a repository they do not own",
+			zap.String("user_id", userID),
+			zap.String("repo_id", repoID))
+		respondError(w, http.StatusForbidden, "not_repository_owner", "Only the repository owner can delete it")
+		return
+	}
+
+	// A running workflow would keep writing scan rows for a repository that no longer exists
+	// Only one scan of a repository runs at a time, so checking the latest one's workflow is enough
+	scanID, err := latestScanID(r.Context(), dbConn, repoID)
+	if err != nil {
+		log.Error("Failed to look up latest scan", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+	if scanID != "" {
+		resp, err := h.TemporalClient.DescribeWorkflowExecution(r.Context(), scanWorkflowID(scanID), "")
+		if err != nil {
+			var notFound *serviceerror.NotFound
+			switch {
+			case errors.As(err, &notFound):
+				// Never scanned through Temporal, or the history has been purged
+			case isTemporalUnavailable(err):
+				writeScanServiceUnavailable(w)
+				return
+			default:
+				log.Error("Failed to check scan workflow before deleting repository",
+					zap.String("repo_id", repoID),
+					zap.String("scan_id", scanID),
+					zap.Error(err))
+				respondError(w, http.StatusInternalServerError, "internal_error", "Failed to check scan status")
+				return
+			}
+		} else if resp.WorkflowExecutionInfo.Status == enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+			respondError(w, http.StatusConflict, "scan_in_progress", "A scan is in progress for this repository; cancel it or wait for it to finish")
+			return
+		}
+	}
+
+	err = h.GitHubService.DeleteRepository(r.Context(), repoID)
+	if errors.Is(err, services.ErrRepositoryNotFound) {
+		respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to delete repository", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to delete repository")
+		return
+	}
+
+	log.Info("Deleted repository", zap.String("repo_id", repoID), zap.String("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/mocks"
+)
+
+// describedWorkflow is a DescribeWorkflowExecution reply for a workflow in the given status
+func describedWorkflow(status enums.WorkflowExecutionStatus) *workflowservice.DescribeWorkflowExecutionResponse {
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{Status: status},
+	}
+}
+
+func TestDeleteRepository(t *testing.T) {
+	tests := []struct {
+		name        string
+		requester   string // "owner", "linked" (added the repository later), or "stranger"
+		describe    *workflowservice.DescribeWorkflowExecutionResponse
+		describeErr error
+		wantStatus  int
+		wantDeleted bool
+	}{
+		{name: "never scanned", requester: "owner", describeErr: serviceerror.NewNotFound("no workflow"), wantStatus: http.StatusNoContent, wantDeleted: true},
+		{name: "last scan finished", requester: "owner", describe: describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_COMPLETED), wantStatus: http.StatusNoContent, wantDeleted: true},
+		{name: "scan in progress", requester: "owner", describe: describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), wantStatus: http.StatusConflict},
+		{name: "temporal unavailable", requester: "owner", describeErr: serviceerror.NewUnavailable("down"), wantStatus: http.StatusServiceUnavailable},
+		{name: "linked user is not the owner", requester: "linked", describeErr: serviceerror.NewNotFound("no workflow"), wantStatus: http.StatusForbidden},
+		{name: "user without access", requester: "stranger", describeErr: serviceerror.NewNotFound("no workflow"), wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbConn := testdb.Open(t)
+			ctx := context.Background()
+			ownerID, repoID := createTestRepository(t, dbConn)
+			linkedID, _ := createTestRepository(t, dbConn)
+			strangerID, _ := createTestRepository(t, dbConn)
+			if _, err := dbConn.ExecContext(ctx,
+				`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)`, linkedID, repoID); err != nil {
+				t.Fatalf("link repository: %v", err)
+			}
+
+			// A scan with a finding, so deletion has to clear children before their parents
+			var scanID string
+			if err := dbConn.QueryRowContext(ctx,
+				`INSERT INTO scans (repository_id, status) VALUES ($1, 'completed') RETURNING id`, repoID).Scan(&scanID); err != nil {
+				t.Fatalf("insert scan: %v", err)
+			}
+			if _, err := dbConn.ExecContext(ctx,
+				`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description)
+				VALUES ($1, 'Injection', 'main.go', 3, 3, 'High', 'finding')`, scanID); err != nil {
+				t.Fatalf("insert finding: %v", err)
+			}
+			if _, err := dbConn.ExecContext(ctx,
+				`UPDATE repositories SET baseline_scan_id = $2 WHERE id = $1`, repoID, scanID); err != nil {
+				t.Fatalf("set baseline: %v", err)
+			}
+
+			temporalClient := &mocks.Client{}
+			temporalClient.On("DescribeWorkflowExecution", mock.Anything, "scan-workflow-"+scanID, "").
+				Return(tt.describe, tt.describeErr)
+
+			queries := db.NewQueries()
+			queries.SetDB(dbConn)
+			handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries), TemporalClient: temporalClient}
+
+			requester := map[string]string{"owner": ownerID, "linked": linkedID, "stranger": strangerID}[tt.requester]
+			r := httptest.NewRequest(http.MethodDelete, "/api/repositories/"+repoID, nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", repoID)
+			r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), "userID", requester))
+
+			rec := httptest.NewRecorder()
+			handler.DeleteRepository(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			var repos, scans, findings, links int
+			if err := dbConn.QueryRowContext(ctx,
+				`SELECT (SELECT COUNT(*) FROM repositories WHERE id = $1),
+					(SELECT COUNT(*) FROM scans WHERE repository_id = $1),
+					(SELECT COUNT(*) FROM vulnerabilities WHERE scan_id = $2),
+					(SELECT COUNT(*) FROM user_repositories WHERE repository_id = $1)`,
+				repoID, scanID).Scan(&repos, &scans, &findings, &links); err != nil {
+				t.Fatalf("count rows: %v", err)
+			}
+			remaining := repos + scans + findings + links
+			if tt.wantDeleted && remaining != 0 {
+				t.Errorf("%d repository, %d scan, %d finding, and %d link rows remain, want none", repos, scans, findings, links)
+			}
+			if !tt.wantDeleted && (repos != 1 || scans != 1 || findings != 1 || links != 2) {
+				t.Errorf("rows changed without a deletion: %d repository, %d scan, %d finding, %d link", repos, scans, findings, links)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// errorDetail describes what went wrong in an error response
+// Code is stable and machine-readable, e.g. "repository_not_found"; Message is for people and may change
+type errorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// respondError writes an error response as JSON: {"error": {"code": ..., "message": ...}}
+// Every handler error goes through here so clients can parse failures the same way everywhere
+func respondError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorBody(w, status, map[string]any{"error": errorDetail{Code: code, Message: message}})
+}
+
+// writeErrorBody writes an error response whose body carries more than the error itself,
+// e.g. the running scan a request conflicted with; body must include an "error" errorDetail
+func writeErrorBody(w http.ResponseWriter, status int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
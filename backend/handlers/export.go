@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// scanExport is a finished scan's findings together with what a downloadable report names it by
+type scanExport struct {
+	ScanID          string
+	RepositoryName  string
+	CommitSHA       string
+	ScanDate        time.Time
+	Vulnerabilities []*services.Vulnerability
+}
+
+// ExportScanResultsCSV downloads a scan's findings as CSV
+// Like /results it accepts a scan or repository ID and honors ?include_excluded=, ?include_baselined=,
+// and ?min_confidence=
+func (h *RepositoryHandler) ExportScanResultsCSV(w http.ResponseWriter, r *http.Request) {
+	export, ok := h.loadScanExport(w, r)
+	if !ok {
+		return
+	}
+
+	var body bytes.Buffer
+	if err := services.VulnerabilitiesToCSV(&body, export.Vulnerabilities); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to export scan results as CSV", zap.String("scan_id", export.ScanID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to export scan results")
+		return
+	}
+
+	writeExport(w, "text/csv; charset=utf-8", exportFilename(export, "csv"), body.Bytes())
+}
+
+// ExportScanResultsPDF downloads a scan's findings as a formatted PDF report
+func (h *RepositoryHandler) ExportScanResultsPDF(w http.ResponseWriter, r *http.Request) {
+	export, ok := h.loadScanExport(w, r)
+	if !ok {
+		return
+	}
+
+	var body bytes.Buffer
+	err := services.VulnerabilitiesToPDF(&body, export.Vulnerabilities, services.ReportInfo{
+		RepositoryName: export.RepositoryName,
+		ScanID:         export.ScanID,
+		CommitSHA:      export.CommitSHA,
+		ScanDate:       export.ScanDate,
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to export scan results as PDF", zap.String("scan_id", export.ScanID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to export scan results")
+		return
+	}
+
+	writeExport(w, "application/pdf", exportFilename(export, "pdf"), body.Bytes())
+}
+
+// loadScanExport loads the findings of a finished scan for export, writing the error response itself
+// A scan that hasn't finished answers 409, since a report of partial progress would mislead
+func (h *RepositoryHandler) loadScanExport(w http.ResponseWriter, r *http.Request) (*scanExport, bool) {
+	log := logger.FromContext(r.Context())
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
+		return nil, false
+	}
+
+	minimumConfidence, err := minConfidence(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return nil, false
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return nil, false
+	}
+
+	meta, err := latestScanMetadata(r.Context(), dbConn, id)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Error("Failed to look up scan", zap.String("scan_id", id), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return nil, false
+	}
+
+	status := services.NormalizeScanStatus(meta.Status)
+	if !services.HasScanResults(status) {
+		respondError(w, http.StatusConflict, "scan_not_finished", fmt.Sprintf("Scan results are not available (scan is %s)", status))
+		return nil, false
+	}
+
+	vulnerabilities, err := h.GitHubService.GetVulnerabilitiesByScanID(r.Context(), meta.ID)
+	if errors.Is(err, services.ErrScanNotFound) {
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Error("Failed to get scan vulnerabilities", zap.String("scan_id", meta.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get scan results")
+		return nil, false
+	}
+
+	// Reports list what /results reports: excluded and baselined findings only on request,
+	// and low-confidence findings only without ?min_confidence=
+	vulnerabilities, _ = filterExcludedFindings(vulnerabilities, includeExcludedFindings(r))
+	vulnerabilities, _ = filterBaselinedFindings(vulnerabilities, includeBaselinedFindings(r))
+	vulnerabilities, _ = filterLowConfidenceFindings(vulnerabilities, minimumConfidence)
+
+	export := &scanExport{
+		ScanID:          meta.ID,
+		CommitSHA:       meta.CommitSHA.String,
+		ScanDate:        time.Now(),
+		Vulnerabilities: vulnerabilities,
+	}
+	switch {
+	case meta.CompletedAt.Valid:
+		export.ScanDate = meta.CompletedAt.Time
+	case meta.StartedAt.Valid:
+		export.ScanDate = meta.StartedAt.Time
+	}
+
+	// The repository name only labels the report, so a failed lookup falls back to its ID
+	err = dbConn.QueryRowContext(r.Context(),
+		`SELECT r.owner || '/' || r.name FROM repositories r JOIN scans s ON s.repository_id = r.id WHERE s.id::text = $1`,
+		meta.ID).Scan(&export.RepositoryName)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Warn("Failed to look up repository name for export", zap.String("scan_id", meta.ID), zap.Error(err))
+		}
+		export.RepositoryName = meta.ID
+	}
+
+	return export, true
+}
+
+// unsafeFilenameChars matches characters replaced in download file names
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportFilename names a report after its repository and scan date, e.g. sast-report-owner-repo-2024-05-01.pdf
+func exportFilename(export *scanExport, extension string) string {
+	name := strings.Trim(unsafeFilenameChars.ReplaceAllString(export.RepositoryName, "-"), "-")
+	if name == "" {
+		name = "scan"
+	}
+	return fmt.Sprintf("sast-report-%s-%s.%s", name, export.ScanDate.UTC().Format("2006-01-02"), extension)
+}
+
+// writeExport sends a report as a file download
+func writeExport(w http.ResponseWriter, contentType, filename string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestExportFilename(t *testing.T) {
+	date := time.Date(2026, 5, 1, 23, 30, 0, 0, time.FixedZone("EST", -5*3600))
+
+	tests := []struct {
+		repository string
+		want       string
+	}{
+		{repository: "octo/repo", want: "sast-report-octo-repo-2026-05-02.pdf"},
+		{repository: `we"ird/na me`, want: "sast-report-we-ird-na-me-2026-05-02.pdf"},
+		{repository: "/../", want: "sast-report-..-2026-05-02.pdf"},
+		{repository: "///", want: "sast-report-scan-2026-05-02.pdf"},
+	}
+
+	for _, tt := range tests {
+		if got := exportFilename(&scanExport{RepositoryName: tt.repository, ScanDate: date}, "pdf"); got != tt.want {
+			t.Errorf("exportFilename(%q) = %s, want %s", tt.repository, got, tt.want)
+		}
+	}
+}
+
+func TestExportScanResults(t *testing.T) {
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+	userID, repoID := createTestRepository(t, dbConn)
+
+	var scanID, runningScanID string
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, completed_at, created_at) VALUES ($1, 'completed', '2026-05-01T12:00:00Z', NOW() - INTERVAL '1 day') RETURNING id`,
+		repoID).Scan(&scanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+	if _, err := dbConn.ExecContext(ctx,
+		`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description)
+		VALUES ($1, 'Injection', 'main.go', 3, 3, 'High', 'finding')`, scanID); err != nil {
+		t.Fatalf("insert finding: %v", err)
+	}
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status) VALUES ($1, 'scanning') RETURNING id`,
+		repoID).Scan(&runningScanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+
+	queries := db.NewQueries()
+	queries.SetDB(dbConn)
+	handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries)}
+
+	tests := []struct {
+		name            string
+		export          http.HandlerFunc
+		scanID          string
+		wantStatus      int
+		wantContentType string
+		wantExtension   string
+	}{
+		{name: "pdf", export: handler.ExportScanResultsPDF, scanID: scanID, wantStatus: http.StatusOK, wantContentType: "application/pdf", wantExtension: ".pdf"},
+		{name: "csv", export: handler.ExportScanResultsCSV, scanID: scanID, wantStatus: http.StatusOK, wantContentType: "text/csv; charset=utf-8", wantExtension: ".csv"},
+		{name: "scan still running", export: handler.ExportScanResultsPDF, scanID: runningScanID, wantStatus: http.StatusConflict},
+		{name: "unknown scan", export: handler.ExportScanResultsCSV, scanID: "00000000-0000-0000-0000-000000000000", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/scan/"+tt.scanID+"/export", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", tt.scanID)
+			r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), "userID", userID))
+
+			rec := httptest.NewRecorder()
+			tt.export(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %s, want %s", got, tt.wantContentType)
+			}
+			if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="sast-report-test-owner-repo-`) || !strings.HasSuffix(got, `-2026-05-01`+tt.wantExtension+`"`) {
+				t.Errorf("Content-Disposition = %s, want a dated attachment named after the repository", got)
+			}
+
+			body := rec.Body.Bytes()
+			switch tt.wantExtension {
+			case ".pdf":
+				if len(body) == 0 || !bytes.HasPrefix(body, []byte("%PDF-")) {
+					t.Errorf("body = %d bytes, want a non-empty PDF", len(body))
+				}
+			case ".csv":
+				records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+				if err != nil || len(records) != 2 || records[1][3] != "main.go" {
+					t.Errorf("records = %v, %v, want the header and the finding in main.go", records, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// maxFindingPathLength bounds the path query parameter of the per-file findings endpoint
+const maxFindingPathLength = 1024
+
+// normalizeFindingPath turns a user-supplied path into the repository-relative form findings are stored with
+// It returns false for paths that could never name a file in the repository
+func normalizeFindingPath(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > maxFindingPathLength || strings.ContainsRune(raw, 0) {
+		return "", false
+	}
+
+	// Editors on Windows send backslash-separated paths
+	cleaned := path.Clean(strings.ReplaceAll(raw, "\\", "/"))
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// GetScanFileFindings returns a scan's findings for a single file, with snippets and remediation
+// It serves editor integrations that only need the issues of the file currently open
+func (h *RepositoryHandler) GetScanFileFindings(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
+		return
+	}
+
+	filePath, ok := normalizeFindingPath(r.URL.Query().Get("path"))
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", "path must be a relative file path inside the repository")
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return
+	}
+
+	repoID, err := scanRepositoryID(r.Context(), dbConn, id)
+	if err != nil {
+		log.Error("Failed to look up scan", zap.String("scan_id", id), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	allowed, err := userCanAccessRepository(r.Context(), dbConn, userID, repoID)
+	if err == nil && !allowed {
+		allowed, err = userIsAdmin(r.Context(), dbConn, userID)
+	}
+	if err != nil {
+		log.Error("Error checking scan access", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
+		return
+	}
+	if !allowed {
+		log.Warn("User attempted to view findings of an unauthorized scan",
+			zap.String("user_id", userID),
+			zap.String("repo_id", repoID))
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return
+	}
+
+	vulnerabilities, err := h.GitHubService.GetRepositoryVulnerabilities(r.Context(), repoID)
+	if err != nil {
+		log.Error("Failed to get vulnerabilities", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get scan results")
+		return
+	}
+
+	// A path with no findings is a normal answer for an editor, so it is an empty list rather than a 404
+	fileFindings := []*services.Vulnerability{}
+	for _, vuln := range vulnerabilities {
+		if vuln.FilePath == filePath {
+			fileFindings = append(fileFindings, vuln)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"scan_id":               id,
+		"file_path":             filePath,
+		"vulnerabilities_count": countReported(fileFindings),
+		"vulnerabilities":       fileFindings,
+	})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestNormalizeFindingPath(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{raw: "handlers/user.go", want: "handlers/user.go", wantOK: true},
+		{raw: "./handlers//user.go", want: "handlers/user.go", wantOK: true},
+		{raw: `handlers\user.go`, want: "handlers/user.go", wantOK: true},
+		{raw: " main.go ", want: "main.go", wantOK: true},
+		{raw: ""},
+		{raw: "."},
+		{raw: "/etc/passwd"},
+		{raw: "../secrets.go"},
+		{raw: "handlers/../../secrets.go"},
+		{raw: "main.go\x00.txt"},
+	}
+
+	for _, tt := range tests {
+		got, ok := normalizeFindingPath(tt.raw)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("normalizeFindingPath(%q) = %q, %v, want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGetScanFileFindings(t *testing.T) {
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+	userID, repoID := createTestRepository(t, dbConn)
+	outsiderID, _ := createTestRepository(t, dbConn)
+
+	var scanID string
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, started_at, completed_at) VALUES ($1, 'completed', NOW(), NOW()) RETURNING id`,
+		repoID).Scan(&scanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+
+	queries := db.NewQueries()
+	queries.SetDB(dbConn)
+	progress := services.NewScanProgressService(queries)
+	if err := progress.RecordFile(ctx, scanID, "handlers/user.go", []*services.Vulnerability{
+		{Type: services.Injection, FilePath: "handlers/user.go", LineStart: 4, LineEnd: 4, Severity: "High", Description: "first", Remediation: "Use parameters", Code: "db.Query(q)"},
+		{Type: services.Injection, FilePath: "handlers/user.go", LineStart: 9, LineEnd: 9, Severity: "High", Description: "second", Code: "db.Exec(q)"},
+	}); err != nil {
+		t.Fatalf("record handlers/user.go: %v", err)
+	}
+	if err := progress.RecordFile(ctx, scanID, "main.go", []*services.Vulnerability{
+		{Type: services.SecurityMisconfiguration, FilePath: "main.go", LineStart: 1, LineEnd: 1, Severity: "Low", Description: "other file"},
+	}); err != nil {
+		t.Fatalf("record main.go: %v", err)
+	}
+
+	handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries)}
+
+	tests := []struct {
+		name       string
+		userID     string
+		path       string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "matching path returns its findings", userID: userID, path: `handlers\user.go`, wantStatus: http.StatusOK, wantCount: 2},
+		{name: "path without findings is empty", userID: userID, path: "docs/readme.go", wantStatus: http.StatusOK},
+		{name: "path outside the repository", userID: userID, path: "../main.go", wantStatus: http.StatusBadRequest},
+		{name: "another user's scan", userID: outsiderID, path: "main.go", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/scan/"+scanID+"/file?path="+url.QueryEscape(tt.path), nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", scanID)
+			r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), "userID", tt.userID))
+
+			rec := httptest.NewRecorder()
+			handler.GetScanFileFindings(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Vulnerabilities []*services.Vulnerability `json:"vulnerabilities"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if body.Vulnerabilities == nil || len(body.Vulnerabilities) != tt.wantCount {
+				t.Fatalf("returned %d findings, want %d", len(body.Vulnerabilities), tt.wantCount)
+			}
+			for _, vuln := range body.Vulnerabilities {
+				if vuln.FilePath != "handlers/user.go" || vuln.Code == "" {
+					t.Errorf("finding %+v, want one of handlers/user.go with its snippet", vuln)
+				}
+			}
+		})
+	}
+}
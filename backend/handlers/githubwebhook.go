@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/temporal"
+	"go.uber.org/zap"
+)
+
+// maxGitHubWebhookPayload is the largest payload GitHub sends (25 MB); anything bigger isn't from GitHub
+const maxGitHubWebhookPayload = 25 << 20
+
+// HandleGitHubWebhook scans a repository when code is pushed to it
+// The request must be signed with GITHUB_WEBHOOK_SECRET (X-Hub-Signature-256). Push events for a
+// repository already added to the tool start a scan of the pushed branch or tag; other events, branch
+// deletions, and unknown repositories are acknowledged and ignored. Redelivered events (same
+// X-GitHub-Delivery ID) are not scanned again
+func (h *RepositoryHandler) HandleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Warn("GitHub webhook received but GITHUB_WEBHOOK_SECRET is not set")
+		respondError(w, http.StatusServiceUnavailable, "webhooks_not_configured", "GitHub webhooks are not configured")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGitHubWebhookPayload))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body")
+		return
+	}
+
+	if !services.VerifyGitHubSignature(secret, payload, r.Header.Get("X-Hub-Signature-256")) {
+		log.Warn("Rejected GitHub webhook with an invalid signature",
+			zap.String("delivery_id", r.Header.Get("X-GitHub-Delivery")))
+		respondError(w, http.StatusUnauthorized, "invalid_signature", "Invalid signature")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	log = log.With(zap.String("event", event), zap.String("delivery_id", deliveryID))
+
+	// GitHub sends "ping" when the webhook is created; like other non-push events it needs no action
+	if event != "push" {
+		writeGitHubWebhookIgnored(w, "event is not a push")
+		return
+	}
+	if deliveryID == "" {
+		respondError(w, http.StatusBadRequest, "missing_field", "X-GitHub-Delivery header is required")
+		return
+	}
+
+	push, err := services.ParseGitHubPushEvent(payload)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_payload", err.Error())
+		return
+	}
+	if push.IsDeletion() {
+		writeGitHubWebhookIgnored(w, "push deleted "+push.Ref)
+		return
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return
+	}
+
+	repoID, err := githubWebhookRepositoryID(r.Context(), dbConn, push)
+	if err == sql.ErrNoRows {
+		// Pushes to repositories nobody added are not scanned; the tool only scans what users registered
+		log.Info("Ignoring push to a repository that hasn't been added", zap.String("repository", push.Repository.FullName))
+		writeGitHubWebhookIgnored(w, "repository has not been added")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to look up pushed repository", zap.String("repository", push.Repository.FullName), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	// Claim the delivery before starting anything, so concurrent redeliveries can't both scan
+	result, err := dbConn.ExecContext(r.Context(),
+		`INSERT INTO github_webhook_deliveries (delivery_id, event, repository_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (delivery_id) DO NOTHING`,
+		deliveryID, event, repoID)
+	if err != nil {
+		log.Error("Failed to record webhook delivery", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		log.Info("Ignoring redelivered GitHub webhook")
+		writeGitHubWebhookIgnored(w, "delivery already processed")
+		return
+	}
+
+	// A delivery that fails from here on is released, so GitHub's redelivery can retry it
+	scanID, runID, reused, status, err := h.startPushScan(r.Context(), dbConn, repoID, push)
+	if err != nil {
+		if _, releaseErr := dbConn.ExecContext(r.Context(),
+			`DELETE FROM github_webhook_deliveries WHERE delivery_id = $1`, deliveryID); releaseErr != nil {
+			log.Warn("Failed to release webhook delivery", zap.Error(releaseErr))
+		}
+		log.Error("Failed to start push scan", zap.String("repo_id", repoID), zap.Error(err))
+		if status == http.StatusServiceUnavailable {
+			writeScanServiceUnavailable(w)
+			return
+		}
+		respondError(w, status, "scan_start_failed", "Failed to start scan")
+		return
+	}
+
+	if _, err := dbConn.ExecContext(r.Context(),
+		`UPDATE github_webhook_deliveries SET scan_id = $1 WHERE delivery_id = $2`,
+		sql.NullString{String: scanID, Valid: scanID != ""}, deliveryID); err != nil {
+		log.Warn("Failed to link webhook delivery to its scan", zap.String("scan_id", scanID), zap.Error(err))
+	}
+
+	log.Info("Push scan started",
+		zap.String("repo_id", repoID),
+		zap.String("ref", push.Ref),
+		zap.String("commit", push.After),
+		zap.String("scan_id", scanID),
+		zap.Bool("reused", reused))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":         "scan_initiated",
+		"repository_id":  repoID,
+		"scan_record_id": scanID,
+		"ref":            push.Ref,
+		"commit":         push.After,
+		"run_id":         runID,
+		"reused":         reused,
+	})
+}
+
+// githubWebhookRepositoryID finds the stored repository a push event is for
+// Repositories are stored under an ID derived from GitHub's numeric ID, which survives renames;
+// the owner/name match covers repositories stored before that ID was known
+func githubWebhookRepositoryID(ctx context.Context, dbConn *sql.DB, push *services.GitHubPushEvent) (string, error) {
+	var repoID string
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT id FROM repositories
+		WHERE id = $1
+			OR (LOWER(owner) = LOWER($2) AND LOWER(name) = LOWER($3) AND provider = $4)
+		ORDER BY id = $1 DESC
+		LIMIT 1`,
+		services.GitHubRepositoryID(push.Repository.ID), push.OwnerLogin(), push.Repository.Name, services.ProviderGitHub).Scan(&repoID)
+	return repoID, err
+}
+
+// startPushScan queues a scan of the pushed reference and starts its workflow
+// When a scan of the repository is already running it is reused, or canceled with SCAN_DUPLICATE_POLICY=restart.
+// On failure it returns the HTTP status to answer with
+func (h *RepositoryHandler) startPushScan(ctx context.Context, dbConn *sql.DB, repoID string, push *services.GitHubPushEvent) (scanID, runID string, reused bool, status int, err error) {
+	if err := h.checkTemporalAvailable(ctx); err != nil {
+		return "", "", false, http.StatusServiceUnavailable, err
+	}
+
+	repo, err := h.GitHubService.GetRepository(repoID)
+	if err != nil {
+		return "", "", false, http.StatusInternalServerError, err
+	}
+
+	// A push never fails because a scan is running: it reuses that scan unless the policy restarts it
+	activeScanID, activeRunID, err := h.checkRunningScan(ctx, dbConn, repoID, duplicateScanPolicy())
+	if err != nil {
+		if isTemporalUnavailable(err) {
+			return "", "", false, http.StatusServiceUnavailable, err
+		}
+		return "", "", false, http.StatusInternalServerError, err
+	}
+	if activeScanID != "" {
+		return activeScanID, activeRunID, true, 0, nil
+	}
+
+	// The scan belongs to whoever added the repository, so it shows up in their scan list
+	scanID = uuid.New().String()
+	_, err = dbConn.ExecContext(ctx,
+		`INSERT INTO scans (id, repository_id, status, started_at, created_by, ref)
+		SELECT $1, id, $2, NOW(), created_by, $3 FROM repositories WHERE id = $4`,
+		scanID, services.ScanStatusQueued, push.Ref, repoID)
+	if err != nil {
+		return "", "", false, http.StatusInternalServerError, err
+	}
+
+	// The pushed reference is scanned rather than the pushed commit, so a scan that starts after a
+	// later push covers the newer code and the branch's last-scanned commit stays current
+	workflowInput := temporal.ScanWorkflowInput{
+		RepositoryID:   repoID,
+		ScanID:         scanID,
+		Owner:          repo.Owner,
+		Name:           repo.Name,
+		CloneURL:       repo.CloneURL,
+		Ref:            push.Ref,
+		VulnTypes:      owaspTop10VulnTypes,
+		FileExtensions: services.DefaultFileExtensions(),
+		MaxFiles:       temporal.ScanMaxFiles(),
+		Budget:         services.ScanBudgetFromEnv(),
+		Timeouts:       repositoryScanTimeouts(ctx, dbConn, repoID),
+	}
+
+	we, err := h.TemporalClient.ExecuteWorkflow(context.Background(), scanWorkflowOptions(scanID), temporal.ScanWorkflow, workflowInput)
+	if err != nil {
+		discardQueuedScan(ctx, dbConn, scanID)
+		if isTemporalUnavailable(err) {
+			return "", "", false, http.StatusServiceUnavailable, err
+		}
+		return "", "", false, http.StatusInternalServerError, err
+	}
+	return scanID, we.GetRunID(), false, 0, nil
+}
+
+// writeGitHubWebhookIgnored acknowledges a delivery that needs no scan
+// GitHub only needs a 2xx; the reason shows up in the delivery log of the webhook settings
+func writeGitHubWebhookIgnored(w http.ResponseWriter, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "ignored",
+		"reason": reason,
+	})
+}
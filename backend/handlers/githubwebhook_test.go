@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// newGitHubWebhookRequest returns a webhook delivery signed with secret
+func newGitHubWebhookRequest(event, deliveryID, payload, secret string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-GitHub-Delivery", deliveryID)
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+// pushPayload is a push event for the repository owner/name
+func pushPayload(githubID int64, owner, name, after string) string {
+	return `{"ref": "refs/heads/main", "after": "` + after + `", "repository": {"id": ` + strconv.FormatInt(githubID, 10) +
+		`, "name": "` + name + `", "full_name": "` + owner + `/` + name + `", "owner": {"name": "` + owner + `"}}}`
+}
+
+func TestHandleGitHubWebhookBeforeTheDatabase(t *testing.T) {
+	push := pushPayload(42, "octo", "repo", "2222222222222222222222222222222222222222")
+
+	tests := []struct {
+		name       string
+		secret     string // GITHUB_WEBHOOK_SECRET
+		request    *http.Request
+		wantStatus int
+		wantReason string
+	}{
+		{name: "webhooks not configured", request: newGitHubWebhookRequest("push", "d-1", push, ""), wantStatus: http.StatusServiceUnavailable},
+		{name: "invalid signature", secret: testWebhookSecret, request: newGitHubWebhookRequest("push", "d-1", push, "wrong-secret"), wantStatus: http.StatusUnauthorized},
+		{name: "ping", secret: testWebhookSecret, request: newGitHubWebhookRequest("ping", "d-1", `{"zen": "Keep it simple."}`, testWebhookSecret), wantStatus: http.StatusOK, wantReason: "event is not a push"},
+		{name: "missing delivery ID", secret: testWebhookSecret, request: newGitHubWebhookRequest("push", "", push, testWebhookSecret), wantStatus: http.StatusBadRequest},
+		{name: "malformed push", secret: testWebhookSecret, request: newGitHubWebhookRequest("push", "d-1", `{"ref":`, testWebhookSecret), wantStatus: http.StatusBadRequest},
+		{
+			name:       "branch deleted",
+			secret:     testWebhookSecret,
+			request:    newGitHubWebhookRequest("push", "d-1", pushPayload(42, "octo", "repo", "0000000000000000000000000000000000000000"), testWebhookSecret),
+			wantStatus: http.StatusOK,
+			wantReason: "push deleted refs/heads/main",
+		},
+	}
+
+	// None of these reach the database, so the handler needs no services
+	handler := &RepositoryHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_WEBHOOK_SECRET", tt.secret)
+
+			rec := httptest.NewRecorder()
+			handler.HandleGitHubWebhook(rec, tt.request)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantReason == "" {
+				return
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if body["status"] != "ignored" || body["reason"] != tt.wantReason {
+				t.Errorf("response = %v, want ignored because %q", body, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestHandleGitHubWebhookScansPushes(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", testWebhookSecret)
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+	_, repoID := createTestRepository(t, dbConn)
+	var name string
+	if err := dbConn.QueryRowContext(ctx, `SELECT name FROM repositories WHERE id = $1`, repoID).Scan(&name); err != nil {
+		t.Fatalf("look up repository: %v", err)
+	}
+
+	run := &mocks.WorkflowRun{}
+	run.On("GetRunID").Return("run-1")
+	temporalClient := &mocks.Client{}
+	temporalClient.On("CheckHealth", mock.Anything, mock.Anything).Return(&client.CheckHealthResponse{}, nil)
+	temporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(run, nil)
+
+	queries := db.NewQueries()
+	queries.SetDB(dbConn)
+	handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries), TemporalClient: temporalClient}
+
+	// The stored repository predates its GitHub ID, so it is found by owner and name
+	deliveryID := uuid.NewString()
+	push := pushPayload(987654321, "Test-Owner", name, "2222222222222222222222222222222222222222")
+
+	rec := httptest.NewRecorder()
+	handler.HandleGitHubWebhook(rec, newGitHubWebhookRequest("push", deliveryID, push, testWebhookSecret))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body.String())
+	}
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body["repository_id"] != repoID || body["ref"] != "refs/heads/main" || body["run_id"] != "run-1" {
+		t.Errorf("response = %v, want a scan of refs/heads/main in %s", body, repoID)
+	}
+
+	var ref string
+	if err := dbConn.QueryRowContext(ctx, `SELECT ref FROM scans WHERE id = $1`, body["scan_record_id"]).Scan(&ref); err != nil || ref != "refs/heads/main" {
+		t.Errorf("queued scan ref = %q, %v, want refs/heads/main", ref, err)
+	}
+
+	// GitHub redelivering the same event doesn't start a second scan
+	rec = httptest.NewRecorder()
+	handler.HandleGitHubWebhook(rec, newGitHubWebhookRequest("push", deliveryID, push, testWebhookSecret))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "delivery already processed") {
+		t.Errorf("redelivery = %d %s, want it ignored", rec.Code, rec.Body.String())
+	}
+	temporalClient.AssertNumberOfCalls(t, "ExecuteWorkflow", 1)
+
+	// A push to a repository nobody added is acknowledged without scanning
+	rec = httptest.NewRecorder()
+	handler.HandleGitHubWebhook(rec, newGitHubWebhookRequest("push", uuid.NewString(),
+		pushPayload(123, "someone", "unknown-"+uuid.NewString(), "2222222222222222222222222222222222222222"), testWebhookSecret))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "repository has not been added") {
+		t.Errorf("unknown repository = %d %s, want it ignored", rec.Code, rec.Body.String())
+	}
+	temporalClient.AssertNumberOfCalls(t, "ExecuteWorkflow", 1)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+)
+
+// readinessCheckTimeout bounds each dependency check, so a hung dependency fails the probe instead of stalling it
+const readinessCheckTimeout = 2 * time.Second
+
+// Errors for dependencies the server was started without
+var (
+	errDatabaseNotConfigured = errors.New("database is not configured")
+	errDatabaseUnhealthy     = errors.New("database connection is down, reconnecting")
+	errTemporalNotConfigured = errors.New("temporal client is not configured")
+)
+
+// Component statuses reported by the readiness probe
+const (
+	componentOK          = "ok"
+	componentUnavailable = "unavailable"
+)
+
+// databasePinger is the part of the database the readiness probe needs; *db.Queries implements it
+type databasePinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	Database       databasePinger
+	TemporalClient client.Client
+
+	// DatabaseHealthy reports the reconnection loop's view of the database (db.Healthy); while it is
+	// false the probe fails without pinging. Nil leaves the check to the ping alone
+	DatabaseHealthy func() bool
+}
+
+// NewHealthHandler creates a handler that checks the given database and Temporal client
+func NewHealthHandler(database databasePinger, temporalClient client.Client) *HealthHandler {
+	return &HealthHandler{Database: database, TemporalClient: temporalClient}
+}
+
+// componentHealth is one dependency's entry in the readiness response
+type componentHealth struct {
+	Status string `json:"status"` // componentOK or componentUnavailable
+}
+
+// readinessResponse is the body of GET /health/ready
+type readinessResponse struct {
+	Status     string                     `json:"status"` // "ok" when every component is, otherwise "degraded"
+	Components map[string]componentHealth `json:"components"`
+}
+
+// Live answers the liveness probe: the process is up and serving requests
+// It checks no dependencies, so an outage elsewhere doesn't get the server restarted
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context()).Debug("Health check called")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// Ready answers the readiness probe: 200 when the database and Temporal both answer, 503 otherwise
+// The body lists each component's status; failures are logged rather than returned, since the
+// endpoint is public and error messages can name internal hosts
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	checks := map[string]func(context.Context) error{
+		"database": h.pingDatabase,
+		"temporal": h.checkTemporal,
+	}
+
+	response := readinessResponse{Status: componentOK, Components: make(map[string]componentHealth, len(checks))}
+	for name, check := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+		err := check(ctx)
+		cancel()
+
+		if err != nil {
+			log.Warn("Readiness check failed", zap.String("component", name), zap.Error(err))
+			response.Components[name] = componentHealth{Status: componentUnavailable}
+			response.Status = "degraded"
+			continue
+		}
+		response.Components[name] = componentHealth{Status: componentOK}
+	}
+
+	status := http.StatusOK
+	if response.Status != componentOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// pingDatabase checks the database answers a ping
+func (h *HealthHandler) pingDatabase(ctx context.Context) error {
+	if h.Database == nil {
+		return errDatabaseNotConfigured
+	}
+	if h.DatabaseHealthy != nil && !h.DatabaseHealthy() {
+		return errDatabaseUnhealthy
+	}
+	return h.Database.PingContext(ctx)
+}
+
+// checkTemporal checks the Temporal frontend answers a health check; the client connects lazily,
+// so this is the first call that notices Temporal is down
+func (h *HealthHandler) checkTemporal(ctx context.Context) error {
+	if h.TemporalClient == nil {
+		return errTemporalNotConfigured
+	}
+	_, err := h.TemporalClient.CheckHealth(ctx, &client.CheckHealthRequest{})
+	return err
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+// fakeDatabase answers readiness pings with err
+type fakeDatabase struct {
+	err error
+}
+
+func (d fakeDatabase) PingContext(ctx context.Context) error {
+	return d.err
+}
+
+func TestHealthReady(t *testing.T) {
+	tests := []struct {
+		name        string
+		database    databasePinger
+		temporalErr error
+		wantStatus  int
+		want        readinessResponse
+	}{
+		{
+			name:       "healthy",
+			database:   fakeDatabase{},
+			wantStatus: http.StatusOK,
+			want: readinessResponse{Status: "ok", Components: map[string]componentHealth{
+				"database": {Status: "ok"}, "temporal": {Status: "ok"},
+			}},
+		},
+		{
+			name:       "database down",
+			database:   fakeDatabase{err: errors.New("dial tcp 10.0.0.5:5432: connection refused")},
+			wantStatus: http.StatusServiceUnavailable,
+			want: readinessResponse{Status: "degraded", Components: map[string]componentHealth{
+				"database": {Status: "unavailable"}, "temporal": {Status: "ok"},
+			}},
+		},
+		{
+			// main.go keeps serving when the database couldn't be reached at startup
+			name:       "started without a database",
+			database:   (*db.Queries)(nil),
+			wantStatus: http.StatusServiceUnavailable,
+			want: readinessResponse{Status: "degraded", Components: map[string]componentHealth{
+				"database": {Status: "unavailable"}, "temporal": {Status: "ok"},
+			}},
+		},
+		{
+			name:        "temporal unreachable",
+			database:    fakeDatabase{},
+			temporalErr: serviceerror.NewUnavailable("connection refused"),
+			wantStatus:  http.StatusServiceUnavailable,
+			want: readinessResponse{Status: "degraded", Components: map[string]componentHealth{
+				"database": {Status: "ok"}, "temporal": {Status: "unavailable"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			temporalClient := &mocks.Client{}
+			temporalClient.On("CheckHealth", mock.Anything, mock.Anything).Return(&client.CheckHealthResponse{}, tt.temporalErr)
+
+			rec := httptest.NewRecorder()
+			NewHealthHandler(tt.database, temporalClient).Ready(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var got readinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("response isn't JSON: %v", err)
+			}
+			if got.Status != tt.want.Status || len(got.Components) != len(tt.want.Components) {
+				t.Fatalf("response = %+v, want %+v", got, tt.want)
+			}
+			for name, component := range tt.want.Components {
+				if got.Components[name] != component {
+					t.Errorf("%s = %+v, want %+v", name, got.Components[name], component)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthReadyFailsWhileTheDatabaseIsReconnecting(t *testing.T) {
+	temporalClient := &mocks.Client{}
+	temporalClient.On("CheckHealth", mock.Anything, mock.Anything).Return(&client.CheckHealthResponse{}, nil)
+
+	// The ping would answer, but the reconnection loop hasn't restored the connection yet
+	handler := NewHealthHandler(fakeDatabase{}, temporalClient)
+	handler.DatabaseHealthy = func() bool { return false }
+
+	rec := httptest.NewRecorder()
+	handler.Ready(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var got readinessResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("response isn't JSON: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || got.Components["database"].Status != componentUnavailable {
+		t.Errorf("status = %d with database %q, want 503 with database unavailable", rec.Code, got.Components["database"].Status)
+	}
+
+	handler.DatabaseHealthy = func() bool { return true }
+	rec = httptest.NewRecorder()
+	handler.Ready(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status once reconnected = %d, want 200", rec.Code)
+	}
+}
+
+func TestHealthLiveIgnoresDependencies(t *testing.T) {
+	temporalClient := &mocks.Client{}
+	rec := httptest.NewRecorder()
+	NewHealthHandler(fakeDatabase{err: errors.New("down")}, temporalClient).Live(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "OK" {
+		t.Errorf("liveness = %d %q, want 200 OK while the database is down", rec.Code, rec.Body.String())
+	}
+	if calledMethod(temporalClient, "CheckHealth") {
+		t.Error("liveness probe checked Temporal")
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// idempotencyKeyHeader lets clients retry a scan request without starting a second scan
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// publicScanEndpoint scopes the idempotency keys sent to POST /scan
+const publicScanEndpoint = "POST /scan"
+
+// idempotencyScope names the caller an idempotency key belongs to: the signed-in user, else the client IP
+// the rate limiter keyed the request by
+func idempotencyScope(r *http.Request) string {
+	if userID, ok := r.Context().Value("userID").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	if ip, ok := r.Context().Value("clientIP").(string); ok && ip != "" {
+		return "ip:" + ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// idempotencyClaim is an idempotency key held by the request being handled
+type idempotencyClaim struct {
+	store    services.IdempotencyStore
+	endpoint string
+	scope    string
+	key      string
+	scan     *services.IdempotentScan // Set once the request has a scan to return for the key
+}
+
+// claimIdempotencyKey reserves the request's Idempotency-Key header for endpoint
+// It returns false once it has answered the request itself: the key is invalid, a request with it is
+// still running, or an earlier request with it already started a scan, which is returned with 200
+// The claim is nil when the request has no key or the handler doesn't store keys
+func (h *RepositoryHandler) claimIdempotencyKey(w http.ResponseWriter, r *http.Request, endpoint string) (*idempotencyClaim, bool) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if key == "" || h.IdempotencyStore == nil {
+		return nil, true
+	}
+	log := logger.FromContext(r.Context())
+
+	if err := services.ValidateIdempotencyKey(key); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_idempotency_key", err.Error())
+		return nil, false
+	}
+
+	claim := &idempotencyClaim{store: h.IdempotencyStore, endpoint: endpoint, scope: idempotencyScope(r), key: key}
+	scan, err := h.IdempotencyStore.Claim(r.Context(), endpoint, claim.scope, key)
+	if errors.Is(err, services.ErrIdempotencyKeyInProgress) {
+		w.Header().Set("Retry-After", "1")
+		respondError(w, http.StatusConflict, "idempotency_key_in_progress", err.Error())
+		return nil, false
+	}
+	if err != nil {
+		log.Error("Failed to claim idempotency key", zap.String("endpoint", endpoint), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to check the Idempotency-Key header")
+		return nil, false
+	}
+
+	if scan != nil {
+		log.Info("Returning the scan of a repeated idempotency key",
+			zap.String("endpoint", endpoint),
+			zap.String("scan_id", scan.ScanID),
+			zap.String("repository_id", scan.RepositoryID))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]any{
+			"scan_id":        scan.RepositoryID,
+			"scan_record_id": scan.ScanID,
+			"status":         "scan_initiated",
+			"repository_id":  scan.RepositoryID,
+			"replayed":       true,
+		})
+		return nil, false
+	}
+	return claim, true
+}
+
+// succeeded records the scan the request started or was pointed at, which repeated keys return
+func (c *idempotencyClaim) succeeded(repositoryID, scanID string) {
+	if c != nil {
+		c.scan = &services.IdempotentScan{ScanID: scanID, RepositoryID: repositoryID}
+	}
+}
+
+// settle stores the claimed key's scan, or releases the key if the request failed so it can be retried
+// It runs once the response is written, so it isn't cut short when the client disconnects
+func (c *idempotencyClaim) settle(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	if c.scan != nil {
+		if err := c.store.Complete(ctx, c.endpoint, c.scope, c.key, *c.scan); err != nil {
+			logger.FromContext(ctx).Error("Failed to store idempotency key",
+				zap.String("endpoint", c.endpoint),
+				zap.String("scan_id", c.scan.ScanID),
+				zap.Error(err))
+		}
+		return
+	}
+	if err := c.store.Release(ctx, c.endpoint, c.scope, c.key); err != nil {
+		logger.FromContext(ctx).Error("Failed to release idempotency key", zap.String("endpoint", c.endpoint), zap.Error(err))
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// fakeIdempotencyStore keeps keys in memory, keyed by endpoint, scope, and key
+type fakeIdempotencyStore struct {
+	mu      sync.Mutex
+	claimed map[string]*services.IdempotentScan // A nil scan is a claim still in progress
+}
+
+func newFakeIdempotencyStore() *fakeIdempotencyStore {
+	return &fakeIdempotencyStore{claimed: make(map[string]*services.IdempotentScan)}
+}
+
+func (s *fakeIdempotencyStore) Claim(_ context.Context, endpoint, scope, key string) (*services.IdempotentScan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scan, ok := s.claimed[endpoint+"|"+scope+"|"+key]
+	if !ok {
+		s.claimed[endpoint+"|"+scope+"|"+key] = nil
+		return nil, nil
+	}
+	if scan == nil {
+		return nil, services.ErrIdempotencyKeyInProgress
+	}
+	return scan, nil
+}
+
+func (s *fakeIdempotencyStore) Complete(_ context.Context, endpoint, scope, key string, scan services.IdempotentScan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed[endpoint+"|"+scope+"|"+key] = &scan
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Release(_ context.Context, endpoint, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimed[endpoint+"|"+scope+"|"+key] == nil {
+		delete(s.claimed, endpoint+"|"+scope+"|"+key)
+	}
+	return nil
+}
+
+// newPublicScanRequest builds a POST /scan request from clientIP carrying an Idempotency-Key
+func newPublicScanRequest(repoURL, clientIP, key string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(`{"repo_url": "`+repoURL+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(idempotencyKeyHeader, key)
+	return r.WithContext(context.WithValue(r.Context(), "clientIP", clientIP))
+}
+
+func TestScanPublicRepositoryReplaysIdempotencyKeys(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	store.Complete(context.Background(), publicScanEndpoint, "ip:203.0.113.7", "retry-me",
+		services.IdempotentScan{ScanID: "scan-1", RepositoryID: "repo-1"})
+	// No GitHub service or Temporal client: the handler fails if it goes past the key
+	handler := &RepositoryHandler{IdempotencyStore: store}
+
+	rec := httptest.NewRecorder()
+	handler.ScanPublicRepository(rec, newPublicScanRequest("https://github.com/octocat/hello-world", "203.0.113.7", "retry-me"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body["scan_record_id"] != "scan-1" || body["scan_id"] != "repo-1" || body["replayed"] != true {
+		t.Errorf("response = %v, want the original scan replayed", body)
+	}
+}
+
+func TestScanPublicRepositoryIdempotencyKeyConflicts(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	store.Claim(context.Background(), publicScanEndpoint, "ip:203.0.113.7", "in-flight")
+	handler := &RepositoryHandler{IdempotencyStore: store}
+
+	rec := httptest.NewRecorder()
+	handler.ScanPublicRepository(rec, newPublicScanRequest("https://github.com/octocat/hello-world", "203.0.113.7", "in-flight"))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "idempotency_key_in_progress") {
+		t.Errorf("status = %d, body %s; want a 409 while the first request runs", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ScanPublicRepository(rec, newPublicScanRequest("https://github.com/octocat/hello-world", "203.0.113.7", "bad\tkey"))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_idempotency_key") {
+		t.Errorf("status = %d, body %s; want a 400 for an invalid key", rec.Code, rec.Body.String())
+	}
+}
+
+func TestScanPublicRepositoryReleasesIdempotencyKeysOfFailedRequests(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	handler := &RepositoryHandler{IdempotencyStore: store}
+
+	// The key is claimed before the repository URL is resolved, and freed when that fails
+	rec := httptest.NewRecorder()
+	handler.ScanPublicRepository(rec, newPublicScanRequest("https://example.com/octocat/hello-world", "203.0.113.7", "fix-the-url"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 for an unsupported host: %s", rec.Code, rec.Body.String())
+	}
+	if len(store.claimed) != 0 {
+		t.Errorf("keys left after a failed request: %v, want none", store.claimed)
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/scan", nil)
+	r.RemoteAddr = "10.0.0.5:51234"
+	if got := idempotencyScope(r); got != "ip:10.0.0.5" {
+		t.Errorf("scope without context = %q, want the remote address", got)
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), "clientIP", "203.0.113.7"))
+	if got := idempotencyScope(r); got != "ip:203.0.113.7" {
+		t.Errorf("scope behind the rate limiter = %q, want its client IP", got)
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), "userID", "user-1"))
+	if got := idempotencyScope(r); got != "user:user-1" {
+		t.Errorf("scope of a signed-in user = %q, want the user", got)
+	}
+}
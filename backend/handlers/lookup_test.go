@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestWriteRepositoryLookupError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantHandled    bool
+		wantStatus     int
+		wantRetryAfter bool
+	}{
+		{name: "rate limited", err: fmt.Errorf("lookup: %w", &services.RateLimitError{Reset: time.Now().Add(90 * time.Second)}),
+			wantHandled: true, wantStatus: http.StatusTooManyRequests, wantRetryAfter: true},
+		{name: "limit already reset", err: &services.RateLimitError{Reset: time.Now().Add(-time.Minute)},
+			wantHandled: true, wantStatus: http.StatusTooManyRequests, wantRetryAfter: true},
+		{name: "unknown repository", err: fmt.Errorf("lookup: %w", services.ErrRepositoryNotFound), wantHandled: true, wantStatus: http.StatusNotFound},
+		{name: "other failure", err: errors.New("connection reset")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if handled := writeRepositoryLookupError(rec, tt.err); handled != tt.wantHandled {
+				t.Fatalf("handled = %v, want %v", handled, tt.wantHandled)
+			}
+			if !tt.wantHandled {
+				return
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			if tt.wantRetryAfter && (err != nil || retryAfter < 1 || retryAfter > 91) {
+				t.Errorf("Retry-After = %q, want whole seconds until the reset, at least 1", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// HandleListLanguages returns the file extensions the scanner supports
+// Clients use this to build file-filter UIs that stay in sync with the backend
+func HandleListLanguages(w http.ResponseWriter, r *http.Request) {
+	languages := services.SupportedLanguages()
+
+	logger.FromContext(r.Context()).Debug("Listing supported languages")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"languages": languages,
+	})
+}
+
+// HandleGetMeta returns what scans support: file extensions and their languages, vulnerability types with
+// their OWASP codes, severity levels, and taxonomies. Everything comes from the tables the scanner itself
+// uses, so clients building filters and legends can't drift from the backend
+func HandleGetMeta(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context()).Debug("Listing scan metadata")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"owasp_version":       services.OWASPTop10Edition,
+		"languages":           services.SupportedLanguages(),
+		"vulnerability_types": services.SupportedVulnerabilityTypes(),
+		"severities":          services.SupportedSeverityLevels(),
+		"taxonomies":          services.TaxonomyNames(),
+		"default_taxonomy":    services.DefaultTaxonomyName,
+	})
+}
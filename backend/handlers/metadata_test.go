@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestHandleListLanguages(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleListLanguages(rec, httptest.NewRequest(http.MethodGet, "/api/languages", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var body struct {
+		Languages []services.LanguageInfo `json:"languages"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := services.SupportedLanguages()
+	if len(body.Languages) != len(want) {
+		t.Fatalf("response lists %d languages, want %d", len(body.Languages), len(want))
+	}
+	for i := range want {
+		if body.Languages[i] != want[i] {
+			t.Errorf("languages[%d] = %+v, want %+v", i, body.Languages[i], want[i])
+		}
+	}
+}
+
+func TestHandleGetMeta(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleGetMeta(rec, httptest.NewRequest(http.MethodGet, "/meta", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var body struct {
+		OWASPVersion       string                           `json:"owasp_version"`
+		Languages          []services.LanguageInfo          `json:"languages"`
+		VulnerabilityTypes []services.VulnerabilityTypeInfo `json:"vulnerability_types"`
+		Severities         []services.SeverityLevelInfo     `json:"severities"`
+		Taxonomies         []string                         `json:"taxonomies"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if body.OWASPVersion != "2021" {
+		t.Errorf("owasp_version = %q, want 2021", body.OWASPVersion)
+	}
+	if len(body.Languages) != len(services.SupportedLanguages()) {
+		t.Errorf("response lists %d languages, want %d", len(body.Languages), len(services.SupportedLanguages()))
+	}
+
+	// Every OWASP Top 10 2021 category, in order, with its code
+	wantTypes := []services.VulnerabilityTypeInfo{
+		{Name: "Broken Access Control", OWASPCode: "A01:2021"},
+		{Name: "Cryptographic Failures", OWASPCode: "A02:2021"},
+		{Name: "Injection", OWASPCode: "A03:2021"},
+		{Name: "Insecure Design", OWASPCode: "A04:2021"},
+		{Name: "Security Misconfiguration", OWASPCode: "A05:2021"},
+		{Name: "Vulnerable Components", OWASPCode: "A06:2021"},
+		{Name: "Identification and Authentication Failures", OWASPCode: "A07:2021"},
+		{Name: "Software and Data Integrity Failures", OWASPCode: "A08:2021"},
+		{Name: "Security Logging and Monitoring Failures", OWASPCode: "A09:2021"},
+		{Name: "Server-Side Request Forgery", OWASPCode: "A10:2021"},
+	}
+	if len(body.VulnerabilityTypes) != len(wantTypes) {
+		t.Fatalf("response lists %d vulnerability types, want %d: %+v", len(body.VulnerabilityTypes), len(wantTypes), body.VulnerabilityTypes)
+	}
+	for i, want := range wantTypes {
+		if body.VulnerabilityTypes[i] != want {
+			t.Errorf("vulnerability_types[%d] = %+v, want %+v", i, body.VulnerabilityTypes[i], want)
+		}
+	}
+
+	wantSeverities := []services.SeverityLevelInfo{
+		{Name: "Critical", Rank: 4}, {Name: "High", Rank: 3}, {Name: "Medium", Rank: 2}, {Name: "Low", Rank: 1},
+	}
+	if len(body.Severities) != len(wantSeverities) {
+		t.Fatalf("severities = %+v, want %+v", body.Severities, wantSeverities)
+	}
+	for i, want := range wantSeverities {
+		if body.Severities[i] != want {
+			t.Errorf("severities[%d] = %+v, want %+v", i, body.Severities[i], want)
+		}
+	}
+
+	if !slices.Contains(body.Taxonomies, services.DefaultTaxonomyName) {
+		t.Errorf("taxonomies = %v, want the default %s among them", body.Taxonomies, services.DefaultTaxonomyName)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// NotificationHandler serves the authenticated user's in-app notifications
+type NotificationHandler struct {
+	NotificationService services.NotificationService // Service for reading and updating notifications
+}
+
+// NewNotificationHandler creates a new notification handler with the services it needs
+func NewNotificationHandler(notificationService services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		NotificationService: notificationService,
+	}
+}
+
+// ListNotifications returns the user's notifications, newest first, with the unread count
+// Pages are selected with ?limit= (default 20, at most 100) and ?offset=
+func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	limit, ok := pageParam(r, "limit", services.DefaultNotificationLimit)
+	if !ok || limit < 1 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "limit must be a positive integer")
+		return
+	}
+	if limit > services.MaxNotificationLimit {
+		limit = services.MaxNotificationLimit
+	}
+
+	offset, ok := pageParam(r, "offset", 0)
+	if !ok || offset < 0 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "offset must be a non-negative integer")
+		return
+	}
+
+	page, err := h.NotificationService.ListNotifications(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Error("Failed to list notifications", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list notifications")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"notifications": page.Notifications,
+		"unread_count":  page.UnreadCount,
+		"total":         page.Total,
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
+
+// MarkNotificationRead marks one of the user's notifications read
+// Another user's notification answers 404, the same as one that doesn't exist
+func (h *NotificationHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	notificationID := chi.URLParam(r, "id")
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	updated, err := h.NotificationService.MarkRead(r.Context(), userID, notificationID)
+	if err != nil {
+		log.Error("Failed to mark notification read", zap.String("notification_id", notificationID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to mark notification read")
+		return
+	}
+	if !updated {
+		respondError(w, http.StatusNotFound, "notification_not_found", "Notification not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user read
+func (h *NotificationHandler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	updated, err := h.NotificationService.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		log.Error("Failed to mark notifications read", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to mark notifications read")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"marked_read": updated,
+	})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// fakeNotificationService holds one user's notifications in memory
+type fakeNotificationService struct {
+	ownerID       string
+	notifications map[string]bool // Notification ID to whether it is read
+	limit, offset int             // Page last asked for
+}
+
+func (f *fakeNotificationService) ListNotifications(ctx context.Context, userID string, limit, offset int) (*services.NotificationPage, error) {
+	f.limit, f.offset = limit, offset
+	page := &services.NotificationPage{Notifications: []*services.Notification{}}
+	if userID != f.ownerID {
+		return page, nil
+	}
+	for id, read := range f.notifications {
+		page.Notifications = append(page.Notifications, &services.Notification{ID: id, Read: read})
+		page.Total++
+		if !read {
+			page.UnreadCount++
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeNotificationService) MarkRead(ctx context.Context, userID, notificationID string) (bool, error) {
+	if _, ok := f.notifications[notificationID]; !ok || userID != f.ownerID {
+		return false, nil
+	}
+	f.notifications[notificationID] = true
+	return true, nil
+}
+
+func (f *fakeNotificationService) MarkAllRead(ctx context.Context, userID string) (int, error) {
+	updated := 0
+	for id, read := range f.notifications {
+		if !read && userID == f.ownerID {
+			f.notifications[id] = true
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// newNotificationRequest returns a request from the user, with the {id} route parameter when given
+func newNotificationRequest(method, target, userID, notificationID string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	routeContext := chi.NewRouteContext()
+	if notificationID != "" {
+		routeContext.URLParams.Add("id", notificationID)
+	}
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)
+	if userID != "" {
+		ctx = context.WithValue(ctx, "userID", userID)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestListNotifications(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		userID     string
+		wantStatus int
+		wantLimit  int
+		wantOffset int
+	}{
+		{name: "defaults", target: "/api/notifications", userID: "owner", wantStatus: http.StatusOK, wantLimit: services.DefaultNotificationLimit},
+		{name: "page", target: "/api/notifications?limit=5&offset=10", userID: "owner", wantStatus: http.StatusOK, wantLimit: 5, wantOffset: 10},
+		{name: "limit capped", target: "/api/notifications?limit=1000", userID: "owner", wantStatus: http.StatusOK, wantLimit: services.MaxNotificationLimit},
+		{name: "zero limit", target: "/api/notifications?limit=0", userID: "owner", wantStatus: http.StatusBadRequest},
+		{name: "negative offset", target: "/api/notifications?offset=-1", userID: "owner", wantStatus: http.StatusBadRequest},
+		{name: "not signed in", target: "/api/notifications", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeNotificationService{ownerID: "owner", notifications: map[string]bool{"n1": false, "n2": true}}
+			rec := httptest.NewRecorder()
+			NewNotificationHandler(service).ListNotifications(rec, newNotificationRequest(http.MethodGet, tt.target, tt.userID, ""))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			if service.limit != tt.wantLimit || service.offset != tt.wantOffset {
+				t.Errorf("listed limit %d offset %d, want %d and %d", service.limit, service.offset, tt.wantLimit, tt.wantOffset)
+			}
+			var body struct {
+				Notifications []services.Notification `json:"notifications"`
+				UnreadCount   int                     `json:"unread_count"`
+				Total         int                     `json:"total"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(body.Notifications) != 2 || body.UnreadCount != 1 || body.Total != 2 {
+				t.Errorf("response = %+v, want 2 notifications with 1 unread", body)
+			}
+		})
+	}
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		notificationID string
+		wantStatus     int
+	}{
+		{name: "own notification", userID: "owner", notificationID: "n1", wantStatus: http.StatusNoContent},
+		{name: "another user's notification", userID: "intruder", notificationID: "n1", wantStatus: http.StatusNotFound},
+		{name: "missing notification", userID: "owner", notificationID: "n9", wantStatus: http.StatusNotFound},
+		{name: "not signed in", notificationID: "n1", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeNotificationService{ownerID: "owner", notifications: map[string]bool{"n1": false}}
+			rec := httptest.NewRecorder()
+			NewNotificationHandler(service).MarkNotificationRead(rec,
+				newNotificationRequest(http.MethodPost, "/api/notifications/"+tt.notificationID+"/read", tt.userID, tt.notificationID))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if read := service.notifications["n1"]; read != (tt.wantStatus == http.StatusNoContent) {
+				t.Errorf("n1 read = %v after a %d response", read, rec.Code)
+			}
+		})
+	}
+}
+
+func TestMarkAllNotificationsRead(t *testing.T) {
+	service := &fakeNotificationService{ownerID: "owner", notifications: map[string]bool{"n1": false, "n2": false, "n3": true}}
+	handler := NewNotificationHandler(service)
+
+	rec := httptest.NewRecorder()
+	handler.MarkAllNotificationsRead(rec, newNotificationRequest(http.MethodPost, "/api/notifications/read-all", "intruder", ""))
+	if rec.Code != http.StatusOK || service.notifications["n1"] {
+		t.Fatalf("another user's read-all = %d, n1 read %v, want nothing of the owner's changed", rec.Code, service.notifications["n1"])
+	}
+
+	rec = httptest.NewRecorder()
+	handler.MarkAllNotificationsRead(rec, newNotificationRequest(http.MethodPost, "/api/notifications/read-all", "owner", ""))
+	var body map[string]int
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || body["marked_read"] != 2 {
+		t.Errorf("read-all = %d %v, want 2 marked read", rec.Code, body)
+	}
+}
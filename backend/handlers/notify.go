@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// NotifyEmailsRequest replaces the additional recipients of a repository's scan notifications
+type NotifyEmailsRequest struct {
+	NotifyEmails []string `json:"notify_emails"`
+}
+
+// GetNotifyEmails returns the addresses that receive scan results in addition to the submitter
+func (h *RepositoryHandler) GetNotifyEmails(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	emails, err := services.NewRepositoryNotifyService(db.NewQueries()).GetNotifyEmails(r.Context(), repoID)
+	if errors.Is(err, services.ErrRepositoryNotFound) {
+		respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to get notify emails", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get notify emails")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository_id": repoID,
+		"notify_emails": emails,
+	})
+}
+
+// UpdateNotifyEmails replaces the addresses that receive scan results in addition to the submitter
+// An empty list removes all additional recipients
+func (h *RepositoryHandler) UpdateNotifyEmails(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	var req NotifyEmailsRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	// Validate first so bad input is a 400 rather than a storage failure
+	if _, err := services.ValidateNotifyEmails(req.NotifyEmails); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_email", err.Error())
+		return
+	}
+
+	emails, err := services.NewRepositoryNotifyService(db.NewQueries()).SetNotifyEmails(r.Context(), repoID, req.NotifyEmails)
+	if errors.Is(err, services.ErrRepositoryNotFound) {
+		respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to update notify emails", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to update notify emails")
+		return
+	}
+
+	log.Info("Updated repository notify emails",
+		zap.String("repo_id", repoID),
+		zap.Int("recipients", len(emails)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository_id": repoID,
+		"notify_emails": emails,
+	})
+}
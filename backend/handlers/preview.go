@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/temporal"
+	"go.uber.org/zap"
+)
+
+// previewCloneTimeout bounds the clone a preview waits on; previews of larger repositories fail instead of
+// holding the request open
+const previewCloneTimeout = 2 * time.Minute
+
+// ScanPreviewRequest names a public repository and the scan options to preview
+// The options are those of POST /api/repositories/{id}/scan
+type ScanPreviewRequest struct {
+	RepoURL string `json:"repo_url"`
+	ScanRepositoryRequest
+}
+
+// PreviewScan handles POST /scan/preview: it clones a public repository and reports the files a scan with
+// the requested options would analyze, counted by language, with an estimate of the tokens and cost
+// Nothing is sent to the model and nothing is stored; the clone is removed before responding
+func (h *RepositoryHandler) PreviewScan(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	var req ScanPreviewRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.RepoURL == "" {
+		respondError(w, http.StatusBadRequest, "missing_field", "Repository URL is required")
+		return
+	}
+
+	fieldErrors := ValidateScanRequest(&req.ScanRepositoryRequest)
+	// A preview always covers the full tree, since there is no earlier scan to diff against
+	if req.IncrementalSince != "" {
+		fieldErrors = append(fieldErrors, FieldError{Field: "incremental_since", Message: "not supported when previewing a scan"})
+	}
+	if len(fieldErrors) > 0 {
+		writeValidationErrors(w, fieldErrors)
+		return
+	}
+
+	provider, err := services.NewRepoProviders(h.GitHubService).ForURL(req.RepoURL)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_repository_url", err.Error())
+		return
+	}
+	repoRef, err := provider.ParseURL(req.RepoURL)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_repository_url", err.Error())
+		return
+	}
+	if !h.allowPublicScanOwner(w, r, repoRef.Owner) {
+		return
+	}
+	repoInfo, err := provider.FetchRepositoryInfo(r.Context(), repoRef)
+	if err != nil {
+		log.Error("Failed to fetch repository info for scan preview", zap.String("url", req.RepoURL), zap.Error(err))
+		if writeRepositoryLookupError(w, err) {
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to fetch repository info: %v", err))
+		return
+	}
+	if err := services.ValidateCloneURL(repoInfo.CloneURL); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_repository_url", err.Error())
+		return
+	}
+
+	// Clone into a workspace of its own, which the worker's reaper removes if this request never does
+	repoDir := services.ScanWorkspaceDir("preview-" + uuid.New().String())
+	defer func() {
+		if err := os.RemoveAll(repoDir); err != nil {
+			log.Warn("Failed to remove scan preview clone", zap.String("repo_dir", repoDir), zap.Error(err))
+		}
+	}()
+
+	cloneCtx, cancel := context.WithTimeout(r.Context(), previewCloneTimeout)
+	defer cancel()
+	ref := strings.TrimSpace(req.Ref)
+	if _, err := h.GitHubService.CloneRepositoryAtRef(cloneCtx, repoInfo, repoDir, ref, false); err != nil {
+		log.Error("Failed to clone repository for scan preview", zap.String("repo_id", repoInfo.ID), zap.Error(err))
+		if errors.Is(cloneCtx.Err(), context.DeadlineExceeded) {
+			respondError(w, http.StatusGatewayTimeout, "clone_timeout", "Cloning the repository took too long to preview it")
+			return
+		}
+		respondError(w, http.StatusBadGateway, "clone_failed", fmt.Sprintf("Failed to clone repository: %v", err))
+		return
+	}
+
+	// Select files with the options the scan itself would run with
+	fileExtensions, _ := services.NormalizeFileExtensions(req.FileExtensions) // Validated above
+	if len(fileExtensions) == 0 {
+		fileExtensions = services.DefaultFileExtensions()
+	}
+	taxonomy := services.TaxonomyOrDefault(req.Taxonomy)
+	var vulnerabilityTypes []services.VulnerabilityType
+	for _, vulnType := range scanVulnTypes(taxonomy, req.VulnTypes) {
+		vulnerabilityTypes = append(vulnerabilityTypes, services.VulnerabilityType(vulnType))
+	}
+
+	preview, err := services.PreviewScan(r.Context(), repoDir, &services.ScanOptions{
+		VulnerabilityTypes: vulnerabilityTypes,
+		FileExtensions:     fileExtensions,
+		IncludeGlobs:       req.IncludeGlobs,
+		ScanVendored:       req.ScanVendored,
+		ScanSkippedDirs:    req.ScanSkippedDirs,
+		ExcludePatterns:    req.ExcludePatterns,
+		IncludePatterns:    req.IncludePatterns,
+		MaxFiles:           temporal.ScanMaxFiles(),
+		MaxFileSizeBytes:   services.MaxFileSizeBytesFromEnv(),
+		Model:              req.Model,
+		LLMDenylist:        services.LLMDenylistFromEnv(),
+	})
+	if err != nil {
+		log.Error("Failed to preview scan", zap.String("repo_id", repoInfo.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "preview_failed", fmt.Sprintf("Failed to preview scan: %v", err))
+		return
+	}
+
+	log.Info("Previewed scan",
+		zap.String("repo_id", repoInfo.ID),
+		zap.Int("file_count", preview.FileCount),
+		zap.Int("estimated_tokens", preview.EstimatedTokens))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository": repoInfo.Owner + "/" + repoInfo.Name,
+		"ref":        ref,
+		"preview":    preview,
+	})
+}
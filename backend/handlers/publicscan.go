@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/temporal"
+	"go.uber.org/zap"
+)
+
+// publicScanRequest is one repository to scan without GitHub integration, as POST /scan and each
+// entry of POST /scan/batch ask for it; every field but RepoURL has already been validated
+type publicScanRequest struct {
+	RepoURL        string
+	Email          string   // Optional email notified when the scan finishes
+	FileExtensions []string // Normalized extensions to scan
+	MinSeverity    string   // Lower-cased severity threshold; "" keeps every finding
+	CallbackURL    string   // https URL the finished scan's signed summary is posted to; "" for none
+}
+
+// publicScanStarted is the scan a public scan request started, or the running scan it was pointed at
+type publicScanStarted struct {
+	RepositoryID string
+	ScanID       string // ID of the scan record
+	RunID        string
+	Reused       bool // The repository was already being scanned and SCAN_DUPLICATE_POLICY is reuse
+}
+
+// body returns the response describing the scan, keyed the way POST /scan has always answered:
+// "scan_id" is the repository ID, which the status endpoints accept for its latest scan
+func (s *publicScanStarted) body(repoURL string) map[string]any {
+	body := map[string]any{
+		"scan_id":        s.RepositoryID,
+		"scan_record_id": s.ScanID,
+		"status":         "scan_initiated",
+		"run_id":         s.RunID,
+		"repository":     repoURL,
+		"repository_id":  s.RepositoryID,
+	}
+	if s.Reused {
+		body["status"] = "scan_in_progress"
+		body["reused"] = true
+	}
+	return body
+}
+
+// scanStartError is why a public scan wasn't started, with the status and error code it is answered with
+type scanStartError struct {
+	Status     int
+	Code       string
+	Message    string
+	RetryAfter int                // Seconds for the Retry-After header; 0 sends none
+	Running    *publicScanStarted // The running scan a scan_in_progress conflict is with
+}
+
+func (e *scanStartError) Error() string {
+	return e.Message
+}
+
+// errScanServiceUnavailable tells the caller scans cannot start right now and when to try again
+func errScanServiceUnavailable() *scanStartError {
+	return &scanStartError{
+		Status:     http.StatusServiceUnavailable,
+		Code:       "scan_service_unavailable",
+		Message:    "Scan service is temporarily unavailable, please retry later",
+		RetryAfter: 30,
+	}
+}
+
+// writeScanStartError answers a request whose scan of repoURL couldn't be started
+// A conflict with a running scan carries that scan's IDs, so the caller can follow it instead
+func writeScanStartError(w http.ResponseWriter, err error, repoURL string) {
+	var startErr *scanStartError
+	if !errors.As(err, &startErr) {
+		respondError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	if startErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(startErr.RetryAfter))
+	}
+	if startErr.Running != nil {
+		body := startErr.Running.body(repoURL)
+		body["status"] = startErr.Code
+		body["error"] = errorDetail{Code: startErr.Code, Message: startErr.Message}
+		writeErrorBody(w, startErr.Status, body)
+		return
+	}
+	respondError(w, startErr.Status, startErr.Code, startErr.Message)
+}
+
+// startPublicScan looks up the repository, stores it with a queued scan record, and starts the scan's workflow
+// Errors are *scanStartError values describing the response to send; nothing is left stored when one is returned
+func (h *RepositoryHandler) startPublicScan(ctx context.Context, req publicScanRequest) (*publicScanStarted, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("Processing repository URL", zap.String("url", req.RepoURL))
+
+	// Route the URL to the provider hosting it (GitHub or GitLab) and extract owner and repo name
+	provider, err := services.NewRepoProviders(h.GitHubService).ForURL(req.RepoURL)
+	if err != nil {
+		log.Error("Unsupported repository URL", zap.String("url", req.RepoURL), zap.Error(err))
+		return nil, &scanStartError{Status: http.StatusBadRequest, Code: "invalid_repository_url", Message: err.Error()}
+	}
+	repoRef, err := provider.ParseURL(req.RepoURL)
+	if err != nil {
+		log.Error("Invalid repository URL", zap.String("url", req.RepoURL), zap.String("provider", provider.Name()), zap.Error(err))
+		return nil, &scanStartError{Status: http.StatusBadRequest, Code: "invalid_repository_url", Message: err.Error()}
+	}
+	owner, name := repoRef.Owner, repoRef.Name
+
+	log.Debug("Extracted repository details",
+		zap.String("owner", owner),
+		zap.String("name", name))
+
+	// Deployments can limit public scans to their own organizations
+	if err := h.scanOwnerError(ctx, owner); err != nil {
+		return nil, err
+	}
+
+	// Fetch repository details from the provider's API
+	log.Debug("Fetching repository info", zap.String("provider", provider.Name()))
+	repoInfo, err := provider.FetchRepositoryInfo(ctx, repoRef)
+	if err != nil {
+		log.Error("Failed to fetch repository info",
+			zap.String("owner", owner),
+			zap.String("name", name),
+			zap.Error(err))
+		if lookupErr := repositoryLookupError(err); lookupErr != nil {
+			return nil, lookupErr
+		}
+		return nil, &scanStartError{Status: http.StatusInternalServerError, Code: "internal_error",
+			Message: fmt.Sprintf("Failed to fetch repository info: %v", err)}
+	}
+
+	log.Info("Repository info fetched successfully",
+		zap.String("id", repoInfo.ID),
+		zap.String("url", repoInfo.URL))
+
+	// Refuse the scan before writing anything if the workflow engine cannot be reached
+	if err := h.checkTemporalAvailable(ctx); err != nil {
+		log.Error("Temporal is unavailable, not starting scan",
+			zap.String("repository_id", repoInfo.ID),
+			zap.Error(err))
+		return nil, errScanServiceUnavailable()
+	}
+
+	// Store repository information in the database
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is nil, cannot store repository information")
+		return nil, &scanStartError{Status: http.StatusInternalServerError, Code: "database_unavailable",
+			Message: "Internal server error: database connection unavailable"}
+	}
+
+	userID := publicScanUser(ctx, dbConn, req.Email)
+
+	// Anonymous callers can't cancel someone else's scan, so ?force is not honored here
+	policy := duplicateScanPolicy()
+	activeScanID, activeRunID, err := h.checkRunningScan(ctx, dbConn, repoInfo.ID, policy)
+	if err != nil {
+		log.Error("Failed to check for a running scan", zap.String("repository_id", repoInfo.ID), zap.Error(err))
+		if isTemporalUnavailable(err) {
+			return nil, errScanServiceUnavailable()
+		}
+		return nil, &scanStartError{Status: http.StatusInternalServerError, Code: "internal_error", Message: "Failed to check for a running scan"}
+	}
+	if activeScanID != "" {
+		running := &publicScanStarted{RepositoryID: repoInfo.ID, ScanID: activeScanID, RunID: activeRunID}
+		log.Info("Repository already has a running scan",
+			zap.String("repository_id", repoInfo.ID),
+			zap.String("scan_id", activeScanID),
+			zap.String("run_id", activeRunID),
+			zap.String("policy", policy))
+
+		if policy == duplicateScanReuse {
+			running.Reused = true
+			return running, nil
+		}
+		return nil, &scanStartError{Status: http.StatusConflict, Code: "scan_in_progress",
+			Message: "A scan of this repository is already running", Running: running}
+	}
+
+	// Store the repository, its link to the user, and the queued scan record together, so clients can
+	// track the scan before the clone starts; the workflow activities advance it to a terminal state
+	scanID := uuid.New().String()
+	err = storePublicScan(ctx, dbConn, publicScanRecord{
+		Repo:        repoInfo,
+		Owner:       owner,
+		Name:        name,
+		Provider:    provider.Name(),
+		UserID:      userID,
+		ScanID:      scanID,
+		CallbackURL: req.CallbackURL,
+	})
+	if err != nil {
+		log.Error("Failed to store repository and scan records",
+			zap.String("repo_id", repoInfo.ID),
+			zap.String("user_id", userID),
+			zap.Error(err))
+		return nil, &scanStartError{Status: http.StatusInternalServerError, Code: "database_error",
+			Message: "Failed to record the scan; nothing was stored, so the request can be retried"}
+	}
+
+	log.Info("Created queued scan record",
+		zap.String("scan_id", scanID),
+		zap.String("repo_id", repoInfo.ID),
+		zap.String("user_id", userID))
+
+	// Initiate Temporal workflow for repository scanning
+	workflowOptions := scanWorkflowOptions(scanID)
+
+	workflowInput := temporal.ScanWorkflowInput{
+		RepositoryID:   repoInfo.ID,
+		ScanID:         scanID,
+		Owner:          repoInfo.Owner,
+		Name:           repoInfo.Name,
+		CloneURL:       repoInfo.CloneURL,
+		VulnTypes:      owaspTop10VulnTypes,
+		FileExtensions: req.FileExtensions,
+		MinSeverity:    req.MinSeverity,
+		NotifyEmail:    req.Email != "", // Flag to indicate whether to send email
+		Email:          req.Email,       // Pass the email to the workflow
+		CallbackURL:    req.CallbackURL,
+		MaxFiles:       temporal.ScanMaxFiles(),
+		Budget:         services.ScanBudgetFromEnv(),
+		Timeouts:       temporal.ResolveScanTimeouts(repoInfo.SizeKB),
+	}
+
+	log.Debug("Starting Temporal workflow",
+		zap.String("workflow_id", workflowOptions.ID),
+		zap.String("repository_id", repoInfo.ID))
+
+	we, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions, temporal.ScanWorkflow, workflowInput)
+	if err != nil {
+		log.Error("Failed to start scan workflow",
+			zap.String("repository_id", repoInfo.ID),
+			zap.Error(err))
+		// No workflow will ever advance the queued record, so do not leave it behind
+		discardQueuedScan(ctx, dbConn, scanID)
+		if isTemporalUnavailable(err) {
+			return nil, errScanServiceUnavailable()
+		}
+		return nil, &scanStartError{Status: http.StatusInternalServerError, Code: "scan_start_failed",
+			Message: fmt.Sprintf("Failed to start scan workflow: %v", err)}
+	}
+
+	log.Info("Scan workflow initiated successfully",
+		zap.String("run_id", we.GetRunID()),
+		zap.String("scan_id", scanID),
+		zap.String("repository_id", repoInfo.ID))
+
+	return &publicScanStarted{RepositoryID: repoInfo.ID, ScanID: scanID, RunID: we.GetRunID()}, nil
+}
+
+// publicScanRecord is what ScanPublicRepository stores before starting the scan workflow
+type publicScanRecord struct {
+	Repo        *services.Repository
+	Owner       string // Owner and name as parsed from the requested URL, which repositories are keyed by
+	Name        string
+	Provider    string // Name of the provider hosting the repository, e.g. "github"
+	UserID      string // Requesting user, or "" for an anonymous scan
+	ScanID      string
+	CallbackURL string // "" when the scan has no callback
+}
+
+// storePublicScan creates the repository or refreshes its metadata, links it to the user, and creates the
+// queued scan record in one transaction. Any failure rolls back every step, so a repository is never left
+// without its user link and a scan never points at a repository that wasn't stored
+func storePublicScan(ctx context.Context, dbConn *sql.DB, record publicScanRecord) error {
+	log := logger.FromContext(ctx)
+	repo := record.Repo
+	userID := sql.NullString{String: record.UserID, Valid: record.UserID != ""}
+
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin scan creation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existingRepoID string
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM repositories WHERE owner = $1 AND name = $2 AND provider = $3`,
+		record.Owner, record.Name, record.Provider).Scan(&existingRepoID)
+	switch {
+	case err == sql.ErrNoRows:
+		description := repo.Description
+		if description == "" {
+			description = "Repository scanned via AI-powered SAST tool"
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO repositories (id, owner, name, url, clone_url, description, created_by, provider, size_kb)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			repo.ID, record.Owner, record.Name, repo.URL, repo.CloneURL, description, userID, record.Provider, repo.SizeKB)
+		if err != nil {
+			return fmt.Errorf("failed to store repository %s: %w", repo.ID, err)
+		}
+		log.Info("Repository stored in database", zap.String("repo_id", repo.ID))
+	case err != nil:
+		return fmt.Errorf("failed to look up repository %s/%s: %w", record.Owner, record.Name, err)
+	default:
+		_, err = tx.ExecContext(ctx,
+			`UPDATE repositories SET url = $1, clone_url = $2, size_kb = $3, updated_at = NOW() WHERE id = $4`,
+			repo.URL, repo.CloneURL, repo.SizeKB, repo.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update repository %s: %w", repo.ID, err)
+		}
+		log.Info("Repository information updated", zap.String("repo_id", repo.ID))
+	}
+
+	if userID.Valid {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)
+			ON CONFLICT (user_id, repository_id) DO NOTHING`,
+			record.UserID, repo.ID)
+		if err != nil {
+			return fmt.Errorf("failed to associate repository %s with user %s: %w", repo.ID, record.UserID, err)
+		}
+	}
+
+	callbackURL := sql.NullString{String: record.CallbackURL, Valid: record.CallbackURL != ""}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO scans (id, repository_id, status, started_at, created_by, callback_url, callback_status)
+		VALUES ($1, $2, $3, NOW(), $4, $5, $6)`,
+		record.ScanID, repo.ID, services.ScanStatusQueued, userID, callbackURL,
+		sql.NullString{String: services.CallbackStatusPending, Valid: callbackURL.Valid})
+	if err != nil {
+		return fmt.Errorf("failed to create scan record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit scan creation: %w", err)
+	}
+	return nil
+}
+
+// publicScanUser returns the user a public scan is recorded for: the user with the given notification email,
+// created if there is none yet, or without an email the authenticated caller. Lookup failures are logged
+// and the scan is recorded without a user
+func publicScanUser(ctx context.Context, dbConn *sql.DB, email string) string {
+	log := logger.FromContext(ctx)
+
+	if email == "" {
+		// Get the user ID from the context (if authenticated)
+		userID, ok := ctx.Value("userID").(string)
+		if ok {
+			log.Info("Using authenticated user", zap.String("user_id", userID))
+		}
+		return userID
+	}
+
+	log.Info("Email provided for notifications", zap.String("email", email))
+
+	// Check if user with this email already exists
+	var userID string
+	err := dbConn.QueryRowContext(ctx, `SELECT id FROM users WHERE email = $1`, email).Scan(&userID)
+	switch {
+	case err == sql.ErrNoRows:
+		// Create a new user with this email
+		userID = uuid.New().String()
+		_, err = dbConn.ExecContext(ctx,
+			`INSERT INTO users (id, email, name, created_at, updated_at, role, receive_notifications)
+			VALUES ($1, $2, $3, NOW(), NOW(), 'user', true)`,
+			userID, email, fmt.Sprintf("User %s", email[:strings.Index(email, "@")]))
+		if err != nil {
+			log.Error("Failed to create user for notification",
+				zap.String("email", email),
+				zap.Error(err))
+			// Continue without user ID
+			return ""
+		}
+		log.Info("Created new user for notification",
+			zap.String("user_id", userID),
+			zap.String("email", email))
+	case err != nil:
+		log.Error("Error checking for existing user",
+			zap.String("email", email),
+			zap.Error(err))
+		// Continue without user ID
+		return ""
+	default:
+		log.Info("Found existing user for notification",
+			zap.String("user_id", userID),
+			zap.String("email", email))
+	}
+	return userID
+}
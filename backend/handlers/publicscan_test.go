@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// newPublicScanRecord returns a record for a repository that isn't stored yet
+func newPublicScanRecord(userID string) publicScanRecord {
+	name := "repo-" + uuid.NewString()
+	return publicScanRecord{
+		Repo: &services.Repository{
+			ID:       uuid.NewString(),
+			URL:      "https://github.com/test-owner/" + name,
+			CloneURL: "https://github.com/test-owner/" + name + ".git",
+		},
+		Owner:    "test-owner",
+		Name:     name,
+		Provider: "github",
+		UserID:   userID,
+		ScanID:   uuid.NewString(),
+	}
+}
+
+// countRows runs a COUNT(*) query with one argument
+func countRows(t *testing.T, dbConn *sql.DB, query, arg string) int {
+	t.Helper()
+	var count int
+	if err := dbConn.QueryRowContext(context.Background(), query, arg).Scan(&count); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	return count
+}
+
+func TestStorePublicScan(t *testing.T) {
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+	userID, _ := createTestRepository(t, dbConn)
+
+	record := newPublicScanRecord(userID)
+	record.CallbackURL = "https://hooks.example.com/scan"
+	if err := storePublicScan(ctx, dbConn, record); err != nil {
+		t.Fatalf("storePublicScan returned error: %v", err)
+	}
+
+	if got := countRows(t, dbConn, `SELECT COUNT(*) FROM repositories WHERE id = $1`, record.Repo.ID); got != 1 {
+		t.Errorf("stored %d repositories, want 1", got)
+	}
+	if got := countRows(t, dbConn, `SELECT COUNT(*) FROM user_repositories WHERE repository_id = $1`, record.Repo.ID); got != 1 {
+		t.Errorf("stored %d user links, want 1", got)
+	}
+	var status, callbackStatus string
+	if err := dbConn.QueryRowContext(ctx,
+		`SELECT status, callback_status FROM scans WHERE id = $1 AND repository_id = $2`,
+		record.ScanID, record.Repo.ID).Scan(&status, &callbackStatus); err != nil {
+		t.Fatalf("load scan record: %v", err)
+	}
+	if status != services.ScanStatusQueued || callbackStatus != services.CallbackStatusPending {
+		t.Errorf("scan status = %s, callback status = %s; want queued and pending", status, callbackStatus)
+	}
+
+	// A second scan refreshes the stored repository instead of adding another
+	record.Repo.CloneURL += "?refreshed"
+	record.ScanID = uuid.NewString()
+	if err := storePublicScan(ctx, dbConn, record); err != nil {
+		t.Fatalf("storePublicScan of a stored repository returned error: %v", err)
+	}
+	if got := countRows(t, dbConn, `SELECT COUNT(*) FROM repositories WHERE clone_url LIKE '%?refreshed' AND id = $1`, record.Repo.ID); got != 1 {
+		t.Errorf("found %d refreshed repositories, want 1", got)
+	}
+	if got := countRows(t, dbConn, `SELECT COUNT(*) FROM scans WHERE repository_id = $1`, record.Repo.ID); got != 2 {
+		t.Errorf("stored %d scans, want 2", got)
+	}
+}
+
+func TestStorePublicScanRollsBackOnFailure(t *testing.T) {
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+	userID, _ := createTestRepository(t, dbConn)
+
+	tests := []struct {
+		name   string
+		record func() publicScanRecord
+	}{
+		{
+			// The scan insert is the last step, after the repository and its user link
+			name: "scan record fails",
+			record: func() publicScanRecord {
+				record := newPublicScanRecord(userID)
+				record.ScanID = "not-a-uuid"
+				return record
+			},
+		},
+		{
+			name: "user link fails",
+			record: func() publicScanRecord {
+				return newPublicScanRecord(uuid.NewString()) // No such user
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := tt.record()
+			if err := storePublicScan(ctx, dbConn, record); err == nil {
+				t.Fatal("storePublicScan returned no error")
+			}
+
+			if got := countRows(t, dbConn, `SELECT COUNT(*) FROM repositories WHERE id = $1`, record.Repo.ID); got != 0 {
+				t.Errorf("%d repositories left behind, want none", got)
+			}
+			if got := countRows(t, dbConn, `SELECT COUNT(*) FROM user_repositories WHERE repository_id = $1`, record.Repo.ID); got != 0 {
+				t.Errorf("%d user links left behind, want none", got)
+			}
+			if got := countRows(t, dbConn, `SELECT COUNT(*) FROM scans WHERE repository_id = $1`, record.Repo.ID); got != 0 {
+				t.Errorf("%d scans left behind, want none", got)
+			}
+		})
+	}
+
+	t.Run("update of a stored repository", func(t *testing.T) {
+		record := newPublicScanRecord(userID)
+		if err := storePublicScan(ctx, dbConn, record); err != nil {
+			t.Fatalf("storePublicScan returned error: %v", err)
+		}
+
+		failing := record
+		failing.Repo = &services.Repository{ID: record.Repo.ID, URL: record.Repo.URL, CloneURL: "https://example.com/changed.git"}
+		failing.ScanID = "not-a-uuid"
+		if err := storePublicScan(ctx, dbConn, failing); err == nil {
+			t.Fatal("storePublicScan returned no error")
+		}
+
+		if got := countRows(t, dbConn, `SELECT COUNT(*) FROM repositories WHERE id = $1 AND clone_url = '`+record.Repo.CloneURL+`'`, record.Repo.ID); got != 1 {
+			t.Error("the failed scan's repository update was kept, want it rolled back")
+		}
+		if got := countRows(t, dbConn, `SELECT COUNT(*) FROM scans WHERE repository_id = $1`, record.Repo.ID); got != 1 {
+			t.Errorf("stored %d scans, want only the first", got)
+		}
+	})
+}
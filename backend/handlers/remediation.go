@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// GetScanRemediation returns a prioritized "what to fix first" plan for a scan
+// Findings are grouped by type, ranked by severity and frequency, and paired with OWASP guidance
+func (h *RepositoryHandler) GetScanRemediation(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	scanID := chi.URLParam(r, "id")
+	if scanID == "" {
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
+		return
+	}
+
+	vulnerabilities, err := h.GitHubService.GetRepositoryVulnerabilities(r.Context(), scanID)
+	if err != nil {
+		log.Error("Failed to get vulnerabilities for remediation plan",
+			zap.String("scan_id", scanID),
+			zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get scan results")
+		return
+	}
+
+	plan := services.BuildRemediationPlan(vulnerabilities)
+
+	log.Debug("Built remediation plan",
+		zap.String("scan_id", scanID),
+		zap.Int("issues", len(plan)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"scan_id":               scanID,
+		"vulnerabilities_count": countReported(vulnerabilities),
+		"issues":                plan,
+	})
+}
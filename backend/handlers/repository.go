@@ -4,18 +4,24 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/temporal"
 	"go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/client"
 	"go.uber.org/zap"
 )
@@ -23,6 +29,13 @@ import (
 // VulnerabilityType is imported from services package
 type VulnerabilityType = services.VulnerabilityType
 
+// owaspTop10VulnTypes are the categories scanned when the scan isn't configured by a request,
+// as for public and push-triggered scans
+var owaspTop10VulnTypes = []string{"Injection", "Broken Access Control", "Cryptographic Failures",
+	"Insecure Design", "Security Misconfiguration", "Vulnerable Components",
+	"Identification and Authentication Failures", "Software and Data Integrity Failures",
+	"Security Logging and Monitoring Failures", "Server-Side Request Forgery"}
+
 // Import vulnerability type constants
 var (
 	Injection                  = services.Injection
@@ -45,6 +58,13 @@ type RepositoryHandler struct {
 	ScannerService services.ScannerService // Service for vulnerability scanning
 	OpenAIService  services.OpenAIService  // Service for AI-powered analysis
 	TemporalClient client.Client           // Client for Temporal workflow engine
+
+	IdempotencyStore services.IdempotencyStore    // Idempotency-Key headers of public scans; nil ignores the header
+	ScanOwners       *services.ScanOwnerAllowlist // Owners public scans and previews are limited to; nil allows all
+
+	// BatchItemRateLimit takes a rate limit token for each repository of a batch scan after the first,
+	// returning false and the wait for the next token when none is left; nil doesn't limit entries
+	BatchItemRateLimit func(r *http.Request) (allowed bool, retryAfter time.Duration)
 }
 
 // NewRepositoryHandler creates a new repository handler with all required dependencies
@@ -67,307 +87,82 @@ func NewRepositoryHandler(
 
 // ScanPublicRepository handles scanning a public GitHub repository by URL
 // This endpoint doesn't require authentication or GitHub integration
+// A request repeated with the same Idempotency-Key header returns the scan the first one started
 func (h *RepositoryHandler) ScanPublicRepository(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	log.Info("Handling public repository scan request")
 
 	// Parse request body
 	var req struct {
-		RepoURL string `json:"repo_url"`
-		Email   string `json:"email"` // Optional email for notification
+		RepoURL        string   `json:"repo_url"`
+		Email          string   `json:"email"`           // Optional email for notification
+		FileExtensions []string `json:"file_extensions"` // Extensions to scan, e.g. "go" or ".js"; empty uses the defaults
+		CallbackURL    string   `json:"callback_url"`    // Optional https URL the finished scan's signed summary is posted to
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
 	if req.RepoURL == "" {
 		log.Warn("Empty repository URL received")
-		http.Error(w, "Repository URL is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "missing_field", "Repository URL is required")
 		return
 	}
 
-	log.Debug("Processing repository URL", zap.String("url", req.RepoURL))
-
-	// Parse the GitHub URL to extract owner and repo name
-	owner, name, err := parseGitHubRepoURL(req.RepoURL)
-	if err != nil {
-		log.Error("Invalid GitHub URL", zap.String("url", req.RepoURL), zap.Error(err))
-		http.Error(w, fmt.Sprintf("Invalid GitHub URL: %v", err), http.StatusBadRequest)
+	// CI pipelines that only care about serious issues can drop lower-severity findings up front
+	severityThreshold := strings.TrimSpace(r.URL.Query().Get("severity_threshold"))
+	if severityThreshold != "" && services.SeverityRank(severityThreshold) == 0 {
+		writeValidationErrors(w, []FieldError{{Field: "severity_threshold", Message: "must be one of low, medium, high, critical"}})
 		return
 	}
 
-	log.Debug("Extracted repository details",
-		zap.String("owner", owner),
-		zap.String("name", name))
-
-	// Fetch repository details from GitHub API
-	log.Debug("Fetching repository info from GitHub API")
-	repoInfo, err := h.GitHubService.FetchRepositoryInfo(r.Context(), owner, name)
-	if err != nil {
-		log.Error("Failed to fetch repository info",
-			zap.String("owner", owner),
-			zap.String("name", name),
-			zap.Error(err))
-		http.Error(w, fmt.Sprintf("Failed to fetch repository info: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	log.Info("Repository info fetched successfully",
-		zap.String("id", repoInfo.ID),
-		zap.String("url", repoInfo.URL))
-
-	// Store repository information in the database
-	// Get database connection
-	dbConn := h.GitHubService.GetDatabaseConnection()
-	if dbConn == nil {
-		log.Error("Database connection is nil, cannot store repository information")
-		http.Error(w, "Internal server error: database connection unavailable", http.StatusInternalServerError)
-		return
-	}
-
-	// Get or create user based on email if provided
-	var userID string
-	if req.Email != "" {
-		log.Info("Email provided for notifications", zap.String("email", req.Email))
-
-		// Check if user with this email already exists
-		err = dbConn.QueryRowContext(r.Context(),
-			`SELECT id FROM users WHERE email = $1`,
-			req.Email).Scan(&userID)
-
-		if err == sql.ErrNoRows {
-			// Create a new user with this email
-			userID = uuid.New().String()
-			_, err = dbConn.ExecContext(r.Context(),
-				`INSERT INTO users (id, email, name, created_at, updated_at, role, receive_notifications)
-				VALUES ($1, $2, $3, NOW(), NOW(), 'user', true)`,
-				userID, req.Email, fmt.Sprintf("User %s", req.Email[:strings.Index(req.Email, "@")]))
-
-			if err != nil {
-				log.Error("Failed to create user for notification",
-					zap.String("email", req.Email),
-					zap.Error(err))
-				// Continue without user ID
-				userID = ""
-			} else {
-				log.Info("Created new user for notification",
-					zap.String("user_id", userID),
-					zap.String("email", req.Email))
-			}
-		} else if err != nil {
-			log.Error("Error checking for existing user",
-				zap.String("email", req.Email),
-				zap.Error(err))
-			// Continue without user ID
-			userID = ""
-		} else {
-			log.Info("Found existing user for notification",
-				zap.String("user_id", userID),
-				zap.String("email", req.Email))
-		}
-	} else {
-		// Get the user ID from the context (if authenticated)
-		contextUserID, ok := r.Context().Value("userID").(string)
-		if ok {
-			userID = contextUserID
-			log.Info("Using authenticated user", zap.String("user_id", userID))
+	fieldErrors := validateFileExtensions(req.FileExtensions)
+	callbackURL := strings.TrimSpace(req.CallbackURL)
+	if callbackURL != "" {
+		// The worker posts to this URL, so it must not be able to reach the server's own network
+		if services.CallbackSecret() == "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: "callback_url", Message: services.ErrCallbacksDisabled.Error()})
+		} else if err := services.ValidateCallbackURL(r.Context(), callbackURL); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: "callback_url", Message: err.Error()})
 		}
 	}
-
-	// Check if repository already exists
-	var existingRepoID string
-	err = dbConn.QueryRowContext(r.Context(),
-		`SELECT id FROM repositories WHERE owner = $1 AND name = $2`,
-		owner, name).Scan(&existingRepoID)
-
-	if err != nil && err != sql.ErrNoRows {
-		log.Error("Error checking for existing repository",
-			zap.String("owner", owner),
-			zap.String("name", name),
-			zap.Error(err))
-		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+	if len(fieldErrors) > 0 {
+		writeValidationErrors(w, fieldErrors)
 		return
 	}
-
-	if err == sql.ErrNoRows {
-		// Repository doesn't exist, create it
-		description := repoInfo.Description
-		if description == "" {
-			description = "Repository scanned via AI-powered SAST tool"
-		}
-
-		// Create the repository with creator information
-		_, err = dbConn.ExecContext(r.Context(),
-			`INSERT INTO repositories (id, owner, name, url, clone_url, description, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-			repoInfo.ID, owner, name, repoInfo.URL, repoInfo.CloneURL, description, sql.NullString{String: userID, Valid: userID != ""})
-		if err != nil {
-			log.Error("Failed to store repository information",
-				zap.String("repo_id", repoInfo.ID),
-				zap.Error(err))
-			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
-			return
-		}
-		log.Info("Repository stored in database",
-			zap.String("repo_id", repoInfo.ID))
-
-		// If we have a user ID, add an entry to user_repositories table for association
-		if userID != "" {
-			// Check if user_repositories table exists
-			var joinTableExists bool
-			err = dbConn.QueryRowContext(r.Context(), `
-				SELECT EXISTS (
-					SELECT FROM information_schema.tables
-					WHERE table_schema = 'public'
-					AND table_name = 'user_repositories'
-				)
-			`).Scan(&joinTableExists)
-
-			if err != nil {
-				log.Error("Error checking user_repositories table existence", zap.Error(err))
-				// Continue anyway, this is not critical
-			} else if joinTableExists {
-				// Add repository to user_repositories table
-				_, err = dbConn.ExecContext(r.Context(),
-					`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)
-					ON CONFLICT (user_id, repository_id) DO NOTHING`,
-					userID, repoInfo.ID)
-
-				if err != nil {
-					log.Error("Failed to associate repository with user in user_repositories",
-						zap.String("repo_id", repoInfo.ID),
-						zap.String("user_id", userID),
-						zap.Error(err))
-					// Continue anyway, this is not critical
-				} else {
-					log.Info("Repository associated with user in user_repositories",
-						zap.String("repo_id", repoInfo.ID),
-						zap.String("user_id", userID))
-				}
-			}
-		}
-	} else {
-		// Repository exists, update it
-		_, err = dbConn.ExecContext(r.Context(),
-			`UPDATE repositories SET url = $1, clone_url = $2, updated_at = NOW() WHERE id = $3`,
-			repoInfo.URL, repoInfo.CloneURL, repoInfo.ID)
-		if err != nil {
-			log.Error("Failed to update repository information",
-				zap.String("repo_id", repoInfo.ID),
-				zap.Error(err))
-			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
-			return
-		}
-		log.Info("Repository information updated",
-			zap.String("repo_id", repoInfo.ID))
-
-		// If we have a user ID, ensure association in user_repositories table
-		if userID != "" {
-			// Check if user_repositories table exists
-			var joinTableExists bool
-			err = dbConn.QueryRowContext(r.Context(), `
-				SELECT EXISTS (
-					SELECT FROM information_schema.tables
-					WHERE table_schema = 'public'
-					AND table_name = 'user_repositories'
-				)
-			`).Scan(&joinTableExists)
-
-			if err != nil {
-				log.Error("Error checking user_repositories table existence", zap.Error(err))
-				// Continue anyway, this is not critical
-			} else if joinTableExists {
-				// Add repository to user_repositories table if not already there
-				_, err = dbConn.ExecContext(r.Context(),
-					`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)
-					ON CONFLICT (user_id, repository_id) DO NOTHING`,
-					userID, repoInfo.ID)
-
-				if err != nil {
-					log.Error("Failed to associate repository with user in user_repositories",
-						zap.String("repo_id", repoInfo.ID),
-						zap.String("user_id", userID),
-						zap.Error(err))
-					// Continue anyway, this is not critical
-				} else {
-					log.Info("Repository associated with user in user_repositories",
-						zap.String("repo_id", repoInfo.ID),
-						zap.String("user_id", userID))
-				}
-			}
-		}
-	}
-
-	// If we have a user ID, make sure to associate them with this repository as creator
-	if userID != "" && existingRepoID == "" {
-		// Create a scan record with the user as creator
-		scanID := uuid.New().String()
-		_, err = dbConn.ExecContext(r.Context(),
-			`INSERT INTO scans (id, repository_id, status, started_at, created_by)
-			VALUES ($1, $2, $3, NOW(), $4)`,
-			scanID, repoInfo.ID, "pending", userID)
-
-		if err != nil {
-			log.Error("Failed to create scan record with user association",
-				zap.String("repo_id", repoInfo.ID),
-				zap.String("user_id", userID),
-				zap.Error(err))
-			// Continue anyway
-		} else {
-			log.Info("Created scan record with user association",
-				zap.String("scan_id", scanID),
-				zap.String("repo_id", repoInfo.ID),
-				zap.String("user_id", userID))
-		}
+	fileExtensions, _ := services.NormalizeFileExtensions(req.FileExtensions) // Validated above
+	if len(fileExtensions) == 0 {
+		fileExtensions = services.DefaultFileExtensions()
 	}
 
-	// Initiate Temporal workflow for repository scanning
-	workflowOptions := client.StartWorkflowOptions{
-		ID:        "scan-workflow-" + repoInfo.ID,
-		TaskQueue: "SCAN_TASK_QUEUE",
+	// A retried request is answered with the scan the first one started; the key is released if this one fails
+	idempotency, ok := h.claimIdempotencyKey(w, r, publicScanEndpoint)
+	if !ok {
+		return
 	}
-
-	workflowInput := temporal.ScanWorkflowInput{
-		RepositoryID: repoInfo.ID,
-		Owner:        repoInfo.Owner,
-		Name:         repoInfo.Name,
-		CloneURL:     repoInfo.CloneURL,
-		VulnTypes: []string{"Injection", "Broken Access Control", "Cryptographic Failures",
-			"Insecure Design", "Security Misconfiguration", "Vulnerable Components",
-			"Identification and Authentication Failures", "Software and Data Integrity Failures",
-			"Security Logging and Monitoring Failures", "Server-Side Request Forgery"},
-		FileExtensions: []string{".go", ".js", ".py", ".java", ".php", ".html", ".css", ".ts", ".jsx", ".tsx"},
-		NotifyEmail:    req.Email != "", // Flag to indicate whether to send email
-		Email:          req.Email,       // Pass the email to the workflow
-	}
-
-	log.Debug("Starting Temporal workflow",
-		zap.String("workflow_id", workflowOptions.ID),
-		zap.String("repository_id", repoInfo.ID))
-
-	we, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions, temporal.ScanWorkflow, workflowInput)
+	defer idempotency.settle(r.Context())
+
+	started, err := h.startPublicScan(r.Context(), publicScanRequest{
+		RepoURL:        req.RepoURL,
+		Email:          req.Email,
+		FileExtensions: fileExtensions,
+		MinSeverity:    strings.ToLower(severityThreshold),
+		CallbackURL:    callbackURL,
+	})
 	if err != nil {
-		log.Error("Failed to start scan workflow",
-			zap.String("repository_id", repoInfo.ID),
-			zap.Error(err))
-		http.Error(w, fmt.Sprintf("Failed to start scan workflow: %v", err), http.StatusInternalServerError)
+		writeScanStartError(w, err, req.RepoURL)
 		return
 	}
-
-	log.Info("Scan workflow initiated successfully",
-		zap.String("run_id", we.GetRunID()),
-		zap.String("scan_id", repoInfo.ID))
+	idempotency.succeeded(started.RepositoryID, started.ScanID)
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{
-		"scan_id":       repoInfo.ID,
-		"status":        "scan_initiated",
-		"run_id":        we.GetRunID(),
-		"repository":    req.RepoURL,
-		"repository_id": repoInfo.ID,
-	})
+	if started.Reused {
+		// Point the caller at the scan already in flight instead of failing
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(started.body(req.RepoURL))
 }
 
 // GetScanStatus handles getting the status of a scan
@@ -377,7 +172,7 @@ func (h *RepositoryHandler) GetScanStatus(w http.ResponseWriter, r *http.Request
 	scanID := chi.URLParam(r, "id")
 	if scanID == "" {
 		log.Warn("Missing scan ID in request")
-		http.Error(w, "Scan ID is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
 		return
 	}
 
@@ -386,16 +181,24 @@ func (h *RepositoryHandler) GetScanStatus(w http.ResponseWriter, r *http.Request
 	// Initialize default values
 	var resultsAvailable bool = false
 	var status string = "unknown"
-
-	// First check if results are available in the database
+	var dbStatus string
+	var progressJSON []byte // Progress saved by the scan activity; NULL until its first heartbeat
+
+	// First check the latest scan record in the database for its lifecycle status
+	// The ID may be a scan record ID or a repository ID, which stands for its latest scan
+	// The record's own ID names its workflow; without a record the ID may name a repository
+	recordID := scanID
+	repoID := scanID
 	dbQueries := db.NewQueries()
 	dbConn := dbQueries.GetDB()
 
-	// Check if we have a valid database connection
-	if dbConn != nil {
-		// Query the database for results availability
+	// Check if we have a valid database connection; during an outage the workflow alone answers
+	if dbConn != nil && db.Healthy() {
+		// Query the database for results availability and the recorded status
 		err := dbConn.QueryRowContext(r.Context(),
-			"SELECT results_available FROM scans WHERE id = $1", scanID).Scan(&resultsAvailable)
+			`SELECT id, repository_id, results_available, status, progress FROM scans
+			WHERE id::text = $1 OR repository_id::text = $1
+			ORDER BY created_at DESC LIMIT 1`, scanID).Scan(&recordID, &repoID, &resultsAvailable, &dbStatus, &progressJSON)
 
 		if err != nil && err != sql.ErrNoRows {
 			log.Error("Failed to query scan status from database",
@@ -403,21 +206,37 @@ func (h *RepositoryHandler) GetScanStatus(w http.ResponseWriter, r *http.Request
 				zap.Error(err))
 		}
 	} else {
-		log.Warn("No database connection available", zap.String("scan_id", scanID))
+		log.Warn("Database is unavailable, checking the workflow only", zap.String("scan_id", scanID))
 	}
 
-	// Query the Temporal workflow execution
-	workflowID := "scan-workflow-" + scanID
+	var progress *services.ScanProgress
+	if len(progressJSON) > 0 {
+		progress = &services.ScanProgress{}
+		if err := json.Unmarshal(progressJSON, progress); err != nil {
+			log.Warn("Failed to decode scan progress", zap.String("scan_id", scanID), zap.Error(err))
+			progress = nil
+		}
+	}
 
-	// Check if workflow is running
-	log.Debug("Querying workflow execution", zap.String("workflow_id", workflowID))
-	resp, err := h.TemporalClient.DescribeWorkflowExecution(r.Context(), workflowID, "")
+	// A finished scan's record is final, so its status is answered without Temporal
+	// This keeps status checks working while Temporal is unreachable
+	if services.IsTerminalScanStatus(dbStatus) {
+		log.Debug("Scan status served from database",
+			zap.String("scan_id", scanID),
+			zap.String("status", dbStatus))
+		writeScanStatus(w, scanID, dbStatus, resultsAvailable, progress)
+		return
+	}
+
+	// Only a scan that is still running or has no record yet needs its workflow checked
+	log.Debug("Querying workflow execution", zap.String("scan_id", recordID))
+	resp, workflowID, err := h.describeScanWorkflow(r.Context(), recordID, repoID)
 	if err != nil {
 		log.Error("Failed to get workflow status",
 			zap.String("scan_id", scanID),
 			zap.String("workflow_id", workflowID),
 			zap.Error(err))
-		http.Error(w, fmt.Sprintf("Failed to get workflow status: %v", err), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to get workflow status: %v", err))
 		return
 	}
 
@@ -427,18 +246,19 @@ func (h *RepositoryHandler) GetScanStatus(w http.ResponseWriter, r *http.Request
 		zap.String("scan_id", scanID))
 
 	switch workflowStatus {
-	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING:
-		status = "in_progress"
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING, enums.WORKFLOW_EXECUTION_STATUS_CONTINUED_AS_NEW:
+		status = runningScanStatus(dbStatus)
 	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
-		status = "completed"
+		status = services.ScanStatusCompleted
+		if services.IsPartialScanStatus(dbStatus) {
+			status = dbStatus
+		}
 	case enums.WORKFLOW_EXECUTION_STATUS_FAILED:
-		status = "failed"
+		status = services.ScanStatusFailed
 	case enums.WORKFLOW_EXECUTION_STATUS_CANCELED:
 		status = "canceled"
 	case enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT:
 		status = "timed_out"
-	case enums.WORKFLOW_EXECUTION_STATUS_CONTINUED_AS_NEW:
-		status = "in_progress"
 	case enums.WORKFLOW_EXECUTION_STATUS_TERMINATED:
 		status = "terminated"
 	}
@@ -447,13 +267,24 @@ func (h *RepositoryHandler) GetScanStatus(w http.ResponseWriter, r *http.Request
 		zap.String("scan_id", scanID),
 		zap.String("status", status))
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeScanStatus(w, scanID, status, resultsAvailable, progress)
+}
+
+// writeScanStatus writes the GetScanStatus response
+// progress is left out until the scan activity has saved some
+func writeScanStatus(w http.ResponseWriter, scanID, status string, resultsAvailable bool, progress *services.ScanProgress) {
+	body := map[string]interface{}{
 		"scan_id":           scanID,
 		"status":            status,
 		"results_available": resultsAvailable,
-	})
+	}
+	if progress != nil {
+		body["progress"] = progress
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(body)
 }
 
 // GetScanResults handles getting the results of a scan
@@ -463,28 +294,52 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 	scanID := chi.URLParam(r, "id")
 	if scanID == "" {
 		log.Warn("Missing scan ID in request")
-		http.Error(w, "Scan ID is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
 		return
 	}
 
-	log.Debug("Getting scan results", zap.String("scan_id", scanID))
+	groupBy, err := findingsGrouping(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	// ?min_confidence= hides findings the model was unsure of
+	minimumConfidence, err := minConfidence(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
 
-	// Define workflowID here so it's available throughout the function
-	workflowID := "scan-workflow-" + scanID
+	// ?fail_on= turns the results into a CI gate on findings at or above that severity
+	failOn := strings.TrimSpace(r.URL.Query().Get("fail_on"))
+	if failOn != "" && services.SeverityRank(failOn) == 0 {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", "fail_on must be one of low, medium, high, critical")
+		return
+	}
+
+	log.Debug("Getting scan results", zap.String("scan_id", scanID))
 
 	// Initialize default values
 	var resultsAvailable bool = false
 	var scanStatus string = "unknown"
 
+	// The ID may be a scan record ID or a repository ID, which stands for its latest scan
+	// The record's own ID names its workflow; without a record the ID may name a repository
+	recordID := scanID
+	repoID := scanID
+
 	// First, try to check results availability in the database
 	dbQueries := db.NewQueries()
 	dbConn := dbQueries.GetDB()
 
-	// Check if we have a valid database connection
-	if dbConn != nil {
+	// Check if we have a valid database connection; during an outage the workflow alone answers
+	if dbConn != nil && db.Healthy() {
 		// Query the database for results availability
 		err := dbConn.QueryRowContext(r.Context(),
-			"SELECT results_available, status FROM scans WHERE id = $1", scanID).Scan(&resultsAvailable, &scanStatus)
+			`SELECT id, repository_id, results_available, status FROM scans
+			WHERE id::text = $1 OR repository_id::text = $1
+			ORDER BY created_at DESC LIMIT 1`, scanID).Scan(&recordID, &repoID, &resultsAvailable, &scanStatus)
 
 		if err != nil {
 			if err != sql.ErrNoRows {
@@ -498,19 +353,22 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 			// Keep using default values
 		}
 	} else {
-		log.Warn("No database connection available", zap.String("scan_id", scanID))
+		log.Warn("Database is unavailable, checking the workflow only", zap.String("scan_id", scanID))
 	}
+	workflowID := scanWorkflowID(recordID)
 
 	// If results are not available in DB, check the workflow status
 	if !resultsAvailable {
 		// Check workflow execution status
-		resp, err := h.TemporalClient.DescribeWorkflowExecution(r.Context(), workflowID, "")
+		var resp *workflowservice.DescribeWorkflowExecutionResponse
+		var err error
+		resp, workflowID, err = h.describeScanWorkflow(r.Context(), recordID, repoID)
 		if err != nil {
 			log.Error("Failed to get workflow status",
 				zap.String("scan_id", scanID),
 				zap.String("workflow_id", workflowID),
 				zap.Error(err))
-			http.Error(w, fmt.Sprintf("Failed to get scan results: %v", err), http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to get scan results: %v", err))
 			return
 		}
 
@@ -522,7 +380,7 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 			w.WriteHeader(http.StatusOK)
 			json.NewEncoder(w).Encode(map[string]any{
 				"scan_id":                     scanID,
-				"status":                      "in_progress",
+				"status":                      runningScanStatus(scanStatus),
 				"message":                     "Scan is still in progress, results not available yet",
 				"vulnerabilities_count":       0,
 				"vulnerabilities_by_category": map[string][]any{},
@@ -530,8 +388,9 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
-		// Update scanStatus based on workflow status if it's still "unknown"
-		if scanStatus == "unknown" {
+		// Update scanStatus based on workflow status if it's still "unknown" or the record
+		// was left in an active stage by a workflow that has since finished
+		if scanStatus == "unknown" || services.IsActiveScanStatus(scanStatus) {
 			switch workflowStatus {
 			case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
 				scanStatus = "completed"
@@ -547,7 +406,8 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 
 	// If we reach here, either results are available or workflow has completed
 	// So we can try to get vulnerabilities from database
-	if scanStatus == "completed" {
+	// Scans that hit their time or cost budget still have (partial) results to return
+	if services.HasScanResults(scanStatus) {
 		// Query the workflow for its result
 		var result temporal.ScanWorkflowOutput
 		response, queryErr := h.TemporalClient.QueryWorkflow(r.Context(), workflowID, "", "scan_result")
@@ -567,8 +427,9 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 				zap.Error(queryErr))
 		}
 
-		// Query the scan results from the GitHubService
-		vulnerabilities, err := h.GitHubService.GetRepositoryVulnerabilities(r.Context(), scanID)
+		// Query the scan results from the GitHubService, for the resolved scan rather than whatever
+		// scan of the repository is latest, which may be a newer one still running
+		vulnerabilities, err := h.GitHubService.GetVulnerabilitiesByScanID(r.Context(), recordID)
 		if err != nil {
 			log.Error("Failed to get scan results from database",
 				zap.String("scan_id", scanID),
@@ -583,7 +444,7 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 		// Update results_available flag if the workflow is complete and we have vulnerabilities
 		if !resultsAvailable && (len(vulnerabilities) > 0 || len(result.Vulnerabilities) > 0) && dbConn != nil {
 			_, err := dbConn.ExecContext(r.Context(),
-				"UPDATE scans SET results_available = true WHERE id = $1", scanID)
+				"UPDATE scans SET results_available = true WHERE id = $1", recordID)
 			if err != nil {
 				log.Error("Failed to update results_available flag",
 					zap.String("scan_id", scanID),
@@ -595,29 +456,72 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 			}
 		}
 
-		// Group vulnerabilities by OWASP category
-		categorizedVulns := make(map[string][]*services.Vulnerability)
-		for _, vuln := range vulnerabilities {
-			category := string(vuln.Type)
-			if category == "" {
-				category = "Unknown"
-			}
-			categorizedVulns[category] = append(categorizedVulns[category], vuln)
-		}
+		// Hide findings in excluded paths or accepted in the baseline unless the caller asks for them
+		vulnerabilities, excludedCount := filterExcludedFindings(vulnerabilities, includeExcludedFindings(r))
+		vulnerabilities, baselinedCount := filterBaselinedFindings(vulnerabilities, includeBaselinedFindings(r))
+		vulnerabilities, lowConfidenceCount := filterLowConfidenceFindings(vulnerabilities, minimumConfidence)
 
 		log.Info("Retrieved scan results successfully",
 			zap.String("scan_id", scanID),
 			zap.Int("vulnerability_count", len(vulnerabilities)))
 
+		results := map[string]any{
+			"scan_id":               scanID,
+			"status":                scanStatus,
+			"vulnerabilities_count": countReported(vulnerabilities),
+			"excluded_count":        excludedCount,
+			"baselined_count":       baselinedCount,
+			"low_confidence_count":  lowConfidenceCount,
+			"results_available":     true,
+		}
+
+		// Record which commit was scanned and when, so results can be reproduced
+		if dbConn != nil {
+			meta, err := latestScanMetadata(r.Context(), dbConn, recordID)
+			if err == nil {
+				meta.addTo(results)
+			} else if err != sql.ErrNoRows {
+				log.Error("Failed to load scan metadata",
+					zap.String("scan_id", scanID),
+					zap.Error(err))
+			}
+		}
+
+		// Files whose findings couldn't be stored; more than SCAN_MAX_INSERT_FAILURES marks the scan completed_with_errors
+		if dbConn != nil {
+			failures, err := services.NewScanProgressService(dbQueries).InsertFailures(r.Context(), recordID)
+			if err == nil {
+				results["insert_failures"] = failures
+			} else {
+				log.Error("Failed to count insert failures",
+					zap.String("scan_id", scanID),
+					zap.Error(err))
+			}
+		}
+
+		if groupBy == groupByFile {
+			// File-centric view for code review: findings nested under each path with per-file summaries
+			results["vulnerabilities_by_file"] = services.GroupFindingsByFile(vulnerabilities)
+		} else {
+			// Group vulnerabilities by OWASP category
+			results["vulnerabilities_by_category"] = groupFindingsByCategory(vulnerabilities)
+		}
+
+		// A failed gate answers 422 so CI steps can fail the build on the status code alone
+		statusCode := http.StatusOK
+		if failOn != "" {
+			gateCount := services.CountFindingsAtOrAbove(vulnerabilities, failOn)
+			results["fail_on"] = services.NormalizeSeverity(failOn)
+			results["gate_failed"] = gateCount > 0
+			results["gate_findings_count"] = gateCount
+			if gateCount > 0 {
+				statusCode = http.StatusUnprocessableEntity
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]any{
-			"scan_id":                     scanID,
-			"status":                      "completed",
-			"vulnerabilities_count":       len(vulnerabilities),
-			"vulnerabilities_by_category": categorizedVulns,
-			"results_available":           true,
-		})
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(results)
 		return
 	}
 
@@ -656,6 +560,28 @@ func (h *RepositoryHandler) GetScanResults(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// groupFindingsByCategory keys findings by their vulnerability type, with "Unknown" for untyped ones
+func groupFindingsByCategory(vulns []*services.Vulnerability) map[string][]*services.Vulnerability {
+	categorized := make(map[string][]*services.Vulnerability)
+	for _, vuln := range vulns {
+		category := string(vuln.Type)
+		if category == "" {
+			category = "Unknown"
+		}
+		categorized[category] = append(categorized[category], vuln)
+	}
+	return categorized
+}
+
+// runningScanStatus reports the lifecycle stage of a scan whose workflow is still running
+// The stage comes from the scan record; a workflow without one yet is reported as queued
+func runningScanStatus(dbStatus string) string {
+	if services.IsActiveScanStatus(dbStatus) {
+		return services.NormalizeScanStatus(dbStatus)
+	}
+	return services.ScanStatusQueued
+}
+
 // CreateRepositoryRequest represents a request to create a new repository
 type CreateRepositoryRequest struct {
 	Owner string `json:"owner"`
@@ -668,22 +594,24 @@ func (h *RepositoryHandler) CreateRepository(w http.ResponseWriter, r *http.Requ
 	var req struct {
 		RepoURL string `json:"repo_url"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID, ok := r.Context().Value("userID").(string)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 		return
 	}
 
 	// Add repository for the user
 	repo, err := h.GitHubService.AddUserRepository(r.Context(), userID, req.RepoURL)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if writeRepositoryLookupError(w, err) {
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "internal_error", err.Error())
 		return
 	}
 
@@ -696,7 +624,7 @@ func (h *RepositoryHandler) ListRepositories(w http.ResponseWriter, r *http.Requ
 	// Get user ID from context
 	userID, ok := r.Context().Value("userID").(string)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 		return
 	}
 
@@ -706,7 +634,7 @@ func (h *RepositoryHandler) ListRepositories(w http.ResponseWriter, r *http.Requ
 	repositories, err := h.GitHubService.ListRepositories(userID)
 	if err != nil {
 		log.Error("Error listing repositories", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", err.Error())
 		return
 	}
 
@@ -728,7 +656,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 	// Get user ID from context
 	userID, ok := r.Context().Value("userID").(string)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 		return
 	}
 
@@ -738,7 +666,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 	dbConn := h.GitHubService.GetDatabaseConnection()
 	if dbConn == nil {
 		log.Error("Database connection is unavailable")
-		http.Error(w, "Database connection unavailable", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
 		return
 	}
 
@@ -754,7 +682,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 
 	if err != nil {
 		log.Error("Error checking user_repositories table existence", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 		return
 	}
 
@@ -770,7 +698,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 
 		if err != nil {
 			log.Error("Error checking repository access", zap.Error(err))
-			http.Error(w, "Error checking repository access", http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
 			return
 		}
 
@@ -778,7 +706,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 			log.Warn("User attempted to access unauthorized repository",
 				zap.String("user_id", userID),
 				zap.String("repo_id", id))
-			http.Error(w, "Repository not found", http.StatusNotFound)
+			respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
 			return
 		}
 	} else {
@@ -795,7 +723,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 
 		if err != nil {
 			log.Error("Error checking created_by column", zap.Error(err))
-			http.Error(w, "Database error", http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 			return
 		}
 
@@ -810,7 +738,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 
 			if err != nil {
 				log.Error("Error checking repository owner", zap.Error(err))
-				http.Error(w, "Error checking repository access", http.StatusInternalServerError)
+				respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
 				return
 			}
 
@@ -818,7 +746,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 				log.Warn("User attempted to access unauthorized repository",
 					zap.String("user_id", userID),
 					zap.String("repo_id", id))
-				http.Error(w, "Repository not found", http.StatusNotFound)
+				respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
 				return
 			}
 		}
@@ -829,7 +757,7 @@ func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request
 	repo, err := h.GitHubService.GetRepository(id)
 	if err != nil {
 		log.Error("Error fetching repository", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", err.Error())
 		return
 	}
 
@@ -845,15 +773,36 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 	// Get user ID from context
 	userID, ok := r.Context().Value("userID").(string)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 		return
 	}
 
+	// The body is optional; it may pick the model to scan with, e.g. to compare models on the same code
+	var req ScanRepositoryRequest
+	if !decodeOptionalJSONBody(w, r, &req) {
+		return
+	}
+	if fieldErrors := ValidateScanRequest(&req); len(fieldErrors) > 0 {
+		writeValidationErrors(w, fieldErrors)
+		return
+	}
+	model := req.Model
+	if model == "" {
+		model = baml.ConfiguredModel()
+	}
+	// Validation already checked the taxonomy is registered
+	taxonomy := services.TaxonomyOrDefault(req.Taxonomy)
+	vulnTypes := scanVulnTypes(taxonomy, req.VulnTypes)
+	fileExtensions, _ := services.NormalizeFileExtensions(req.FileExtensions) // Validated above
+	if len(fileExtensions) == 0 {
+		fileExtensions = services.DefaultFileExtensions()
+	}
+
 	// Check if repository belongs to this user
 	dbConn := h.GitHubService.GetDatabaseConnection()
 	if dbConn == nil {
 		log.Error("Database connection is unavailable")
-		http.Error(w, "Database connection unavailable", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
 		return
 	}
 
@@ -869,7 +818,7 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 
 	if err != nil {
 		log.Error("Error checking user_repositories table existence", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 		return
 	}
 
@@ -885,7 +834,7 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 
 		if err != nil {
 			log.Error("Error checking repository access", zap.Error(err))
-			http.Error(w, "Error checking repository access", http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
 			return
 		}
 
@@ -893,7 +842,7 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 			log.Warn("User attempted to scan unauthorized repository",
 				zap.String("user_id", userID),
 				zap.String("repo_id", id))
-			http.Error(w, "Repository not found", http.StatusNotFound)
+			respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
 			return
 		}
 	} else {
@@ -910,7 +859,7 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 
 		if err != nil {
 			log.Error("Error checking created_by column", zap.Error(err))
-			http.Error(w, "Database error", http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 			return
 		}
 
@@ -925,7 +874,7 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 
 			if err != nil {
 				log.Error("Error checking repository owner", zap.Error(err))
-				http.Error(w, "Error checking repository access", http.StatusInternalServerError)
+				respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
 				return
 			}
 
@@ -933,7 +882,7 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 				log.Warn("User attempted to scan unauthorized repository",
 					zap.String("user_id", userID),
 					zap.String("repo_id", id))
-				http.Error(w, "Repository not found", http.StatusNotFound)
+				respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
 				return
 			}
 		}
@@ -944,7 +893,13 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 	repo, err := h.GitHubService.GetRepository(id)
 	if err != nil {
 		log.Error("Failed to get repository info", zap.String("repo_id", id), zap.Error(err))
-		http.Error(w, fmt.Sprintf("Failed to get repository info: %v", err), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to get repository info: %v", err))
+		return
+	}
+
+	// An uploaded archive's files are gone once its scan ends; scanning it again takes a new upload
+	if repo.Provider == services.ProviderUpload {
+		respondError(w, http.StatusConflict, "upload_not_rescannable", "Uploaded archives can't be scanned again; upload the archive to POST /scan/upload instead")
 		return
 	}
 
@@ -952,21 +907,70 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 	dbConn = h.GitHubService.GetDatabaseConnection()
 	if dbConn == nil {
 		log.Error("Database connection is unavailable, cannot create scan record", zap.String("repo_id", id))
-		http.Error(w, "Database connection unavailable", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
 		return
 	}
 
-	// Create a scan record first
-	scanID := id // Using the repository ID as the scan ID for simplicity
+	// Refuse the scan before writing anything if the workflow engine cannot be reached
+	if err := h.checkTemporalAvailable(r.Context()); err != nil {
+		log.Error("Temporal is unavailable, not starting scan", zap.String("repo_id", id), zap.Error(err))
+		writeScanServiceUnavailable(w)
+		return
+	}
+
+	// Only one scan of a repository runs at a time: a second one is refused unless ?force=true
+	// (or SCAN_DUPLICATE_POLICY=restart) cancels the running scan first
+	policy := duplicateScanPolicy()
+	if forceScan(r) {
+		policy = duplicateScanRestart
+	}
+	activeScanID, activeRunID, err := h.checkRunningScan(r.Context(), dbConn, id, policy)
+	if err != nil {
+		log.Error("Failed to check for a running scan", zap.String("repo_id", id), zap.Error(err))
+		if isTemporalUnavailable(err) {
+			writeScanServiceUnavailable(w)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to check for a running scan")
+		return
+	}
+	if activeScanID != "" {
+		body := map[string]any{
+			"id":             id,
+			"scan_record_id": activeScanID,
+			"status":         "scan_in_progress",
+			"run_id":         activeRunID,
+		}
+		log.Info("Repository already has a running scan",
+			zap.String("repo_id", id),
+			zap.String("scan_id", activeScanID),
+			zap.String("run_id", activeRunID),
+			zap.String("policy", policy))
+
+		if policy == duplicateScanReuse {
+			// Point the caller at the scan already in flight instead of failing
+			body["reused"] = true
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(body)
+			return
+		}
+		body["error"] = errorDetail{Code: "scan_in_progress", Message: "A scan of this repository is already running; retry with ?force=true to cancel it"}
+		writeErrorBody(w, http.StatusConflict, body)
+		return
+	}
+
+	// Create a queued scan record first; the workflow activities advance its status
+	scanID := uuid.New().String()
 	_, err = dbConn.ExecContext(r.Context(),
-		`INSERT INTO scans (id, repository_id, status, started_at)
-		VALUES ($1, $2, $3, NOW())`,
-		scanID, id, "in_progress")
+		`INSERT INTO scans (id, repository_id, status, started_at, created_by, model, taxonomy)
+		VALUES ($1, $2, $3, NOW(), $4, $5, $6)`,
+		scanID, id, services.ScanStatusQueued, userID, model, taxonomy.Name)
 	if err != nil {
 		log.Error("Failed to create scan record",
 			zap.String("repo_id", id),
 			zap.Error(err))
-		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to create scan record")
 		return
 	}
 
@@ -984,24 +988,43 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Initiate Temporal workflow for repository scanning
-	workflowOptions := client.StartWorkflowOptions{
-		ID:        "scan-workflow-" + id,
-		TaskQueue: "SCAN_TASK_QUEUE",
-	}
+	workflowOptions := scanWorkflowOptions(scanID)
 
 	workflowInput := temporal.ScanWorkflowInput{
-		RepositoryID:   id,
-		Owner:          repo.Owner,
-		Name:           repo.Name,
-		CloneURL:       repo.CloneURL,
-		VulnTypes:      []string{"Injection", "Broken Access Control", "Cryptographic Failures", "Insecure Design", "Security Misconfiguration"},
-		FileExtensions: []string{".go", ".js", ".py", ".java", ".php", ".html", ".css", ".ts", ".jsx", ".tsx"},
+		RepositoryID:    id,
+		ScanID:          scanID,
+		Owner:           repo.Owner,
+		Name:            repo.Name,
+		CloneURL:        repo.CloneURL,
+		Ref:             strings.TrimSpace(req.Ref),
+		VulnTypes:       vulnTypes,
+		Taxonomy:        taxonomy.Name,
+		FileExtensions:  fileExtensions,
+		IncludeGlobs:    req.IncludeGlobs,
+		MinSeverity:     strings.ToLower(req.MinSeverity),
+		ScanVendored:    req.ScanVendored,
+		ScanSkippedDirs: req.ScanSkippedDirs,
+		ExcludePatterns: req.ExcludePatterns,
+		IncludePatterns: req.IncludePatterns,
+		DisableCache:    req.DisableCache,
+		Model:           model,
+		MaxFiles:        temporal.ScanMaxFiles(),
+		Budget:          services.ScanBudgetFromEnv(),
+		Timeouts:        repositoryScanTimeouts(r.Context(), dbConn, id),
+
+		IncrementalSince: strings.TrimSpace(req.IncrementalSince),
 	}
 
 	we, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions, temporal.ScanWorkflow, workflowInput)
 	if err != nil {
 		log.Error("Failed to start scan workflow", zap.String("repo_id", id), zap.Error(err))
-		http.Error(w, fmt.Sprintf("Failed to start scan workflow: %v", err), http.StatusInternalServerError)
+		// No workflow will ever advance the queued record, so do not leave it behind
+		discardQueuedScan(r.Context(), dbConn, scanID)
+		if isTemporalUnavailable(err) {
+			writeScanServiceUnavailable(w)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "scan_start_failed", fmt.Sprintf("Failed to start scan workflow: %v", err))
 		return
 	}
 
@@ -1010,20 +1033,135 @@ func (h *RepositoryHandler) ScanRepository(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{
-		"id":     id,
-		"status": "scan_initiated",
-		"run_id": we.GetRunID(),
+		"id":             id,
+		"scan_record_id": scanID,
+		"status":         "scan_initiated",
+		"run_id":         we.GetRunID(),
+		"model":          model,
 	})
 }
 
+// ScanRepositoryRequest holds the optional settings for scanning a stored repository
+type ScanRepositoryRequest struct {
+	Model          string   `json:"model"`           // LLM model to scan with; empty uses the default
+	Ref            string   `json:"ref"`             // Branch, tag, or commit SHA to scan; empty scans the default branch
+	IncludeGlobs   []string `json:"include_globs"`   // Scan only files matching one of these globs; empty scans by extension
+	VulnTypes      []string `json:"vuln_types"`      // Categories of the taxonomy to look for; empty scans for the defaults
+	Taxonomy       string   `json:"taxonomy"`        // Registered taxonomy the categories come from; empty is OWASP Top 10 2021
+	FileExtensions []string `json:"file_extensions"` // Extensions to scan; empty uses the defaults
+	MinSeverity    string   `json:"min_severity"`    // Drop findings below this severity (low, medium, high, critical)
+
+	// Normally skipped dependency directories are left out unless re-enabled here
+	ScanVendored    bool     `json:"scan_vendored"`     // Scan vendor/ directories holding first-party code
+	ScanSkippedDirs []string `json:"scan_skipped_dirs"` // Other skipped directory names to scan, e.g. "lib"
+
+	// The repository's .gitignore is respected; these globs adjust what is walked
+	ExcludePatterns []string `json:"exclude_patterns"` // Also skip paths matching these globs, e.g. "generated/**"
+	IncludePatterns []string `json:"include_patterns"` // Scan matching paths even if skipped, ignored, or excluded
+
+	DisableCache bool `json:"disable_cache"` // Send every file to the model instead of reusing results for unchanged files
+
+	// Commit SHA of an earlier completed scan; only files changed since then are analyzed
+	// and that scan's findings are kept for the rest
+	IncrementalSince string `json:"incremental_since"`
+}
+
+// scanVulnTypes returns the categories a scan looks for: the requested ones, or when none were requested the
+// most common OWASP categories, or every category of a non-default taxonomy
+func scanVulnTypes(taxonomy *services.Taxonomy, requested []string) []string {
+	if len(requested) > 0 {
+		return requested
+	}
+	if taxonomy.Name != services.DefaultTaxonomyName {
+		return taxonomy.CategoryNames()
+	}
+	return []string{"Injection", "Broken Access Control", "Cryptographic Failures", "Insecure Design", "Security Misconfiguration"}
+}
+
+// repositoryScanTimeouts resolves the activity timeouts for scanning a stored repository from its recorded size
+// A size that can't be read is treated as unknown, which gives the default timeouts
+func repositoryScanTimeouts(ctx context.Context, dbConn *sql.DB, repoID string) temporal.ScanTimeouts {
+	var sizeKB int64
+	if err := dbConn.QueryRowContext(ctx,
+		`SELECT size_kb FROM repositories WHERE id::text = $1`, repoID).Scan(&sizeKB); err != nil {
+		logger.FromContext(ctx).Warn("Failed to read repository size, using default scan timeouts",
+			zap.String("repo_id", repoID),
+			zap.Error(err))
+	}
+	return temporal.ResolveScanTimeouts(sizeKB)
+}
+
+// discardQueuedScan deletes a queued scan record that never got a workflow attached
+// Failures are only logged, since the caller is already reporting its own outcome
+func discardQueuedScan(ctx context.Context, dbConn *sql.DB, queuedScanID string) {
+	if _, err := dbConn.ExecContext(ctx, `DELETE FROM scans WHERE id = $1`, queuedScanID); err != nil {
+		logger.FromContext(ctx).Warn("Failed to remove unused queued scan record",
+			zap.String("scan_id", queuedScanID),
+			zap.Error(err))
+	}
+}
+
+// temporalHealthCheckTimeout bounds how long a scan request waits to learn whether Temporal is reachable
+const temporalHealthCheckTimeout = 5 * time.Second
+
+// checkTemporalAvailable verifies the Temporal frontend answers before any scan records are written
+// The client is created lazily, so without this check an outage only surfaces at ExecuteWorkflow
+func (h *RepositoryHandler) checkTemporalAvailable(ctx context.Context) error {
+	if h.TemporalClient == nil {
+		return errTemporalNotConfigured
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, temporalHealthCheckTimeout)
+	defer cancel()
+
+	_, err := h.TemporalClient.CheckHealth(checkCtx, &client.CheckHealthRequest{})
+	return err
+}
+
+// isTemporalUnavailable reports whether err means the Temporal service could not be reached,
+// as opposed to Temporal rejecting the request
+func isTemporalUnavailable(err error) bool {
+	var unavailable *serviceerror.Unavailable
+	var deadlineExceeded *serviceerror.DeadlineExceeded
+	return errors.As(err, &unavailable) ||
+		errors.As(err, &deadlineExceeded) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// writeScanServiceUnavailable tells the caller scans cannot start right now and when to try again
+func writeScanServiceUnavailable(w http.ResponseWriter) {
+	writeScanStartError(w, errScanServiceUnavailable(), "")
+}
+
 // GetVulnerabilities handles getting vulnerabilities for a repository
 func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	groupBy, err := findingsGrouping(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	// ?min_confidence= hides findings the model was unsure of
+	minimumConfidence, err := minConfidence(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	filter, ok := readVulnerabilityFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.MinConfidence = minimumConfidence
+	filter.IncludeExcluded = includeExcludedFindings(r)
+	filter.IncludeBaselined = includeBaselinedFindings(r)
+
 	// Get user ID from context
 	userID, ok := r.Context().Value("userID").(string)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 		return
 	}
 
@@ -1033,13 +1171,13 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 	dbConn := h.GitHubService.GetDatabaseConnection()
 	if dbConn == nil {
 		log.Error("Database connection is unavailable")
-		http.Error(w, "Database connection unavailable", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
 		return
 	}
 
 	// First check if the user_repositories table exists
 	var joinTableExists bool
-	err := dbConn.QueryRowContext(r.Context(), `
+	err = dbConn.QueryRowContext(r.Context(), `
 		SELECT EXISTS (
 			SELECT FROM information_schema.tables
 			WHERE table_schema = 'public'
@@ -1049,7 +1187,7 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 
 	if err != nil {
 		log.Error("Error checking user_repositories table existence", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 		return
 	}
 
@@ -1065,7 +1203,7 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 
 		if err != nil {
 			log.Error("Error checking repository access", zap.Error(err))
-			http.Error(w, "Error checking repository access", http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
 			return
 		}
 
@@ -1073,7 +1211,7 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 			log.Warn("User attempted to access unauthorized vulnerabilities",
 				zap.String("user_id", userID),
 				zap.String("repo_id", id))
-			http.Error(w, "Repository not found", http.StatusNotFound)
+			respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
 			return
 		}
 	} else {
@@ -1090,7 +1228,7 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 
 		if err != nil {
 			log.Error("Error checking created_by column", zap.Error(err))
-			http.Error(w, "Database error", http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 			return
 		}
 
@@ -1105,7 +1243,7 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 
 			if err != nil {
 				log.Error("Error checking repository owner", zap.Error(err))
-				http.Error(w, "Error checking repository access", http.StatusInternalServerError)
+				respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
 				return
 			}
 
@@ -1113,20 +1251,33 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 				log.Warn("User attempted to access unauthorized vulnerabilities",
 					zap.String("user_id", userID),
 					zap.String("repo_id", id))
-				http.Error(w, "Repository not found", http.StatusNotFound)
+				respondError(w, http.StatusNotFound, "repository_not_found", "Repository not found")
 				return
 			}
 		}
 		// If neither table exists, skip the authorization check (temporary fallback)
 	}
 
-	// Get vulnerabilities from GitHub service
-	vulnerabilities, err := h.GitHubService.GetRepositoryVulnerabilities(r.Context(), id)
+	// Filtering and paging happen in the query; excluded and baselined findings are hidden unless asked for
+	page, err := h.GitHubService.QueryRepositoryVulnerabilities(r.Context(), id, filter)
 	if err != nil {
 		log.Error("Error fetching vulnerabilities", zap.Error(err))
-		http.Error(w, fmt.Sprintf("Failed to get vulnerabilities: %v", err), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to get vulnerabilities: %v", err))
 		return
 	}
+	vulnerabilities := page.Vulnerabilities
+
+	// Report the latest scan's real status, commit, and timestamps
+	meta, metaErr := latestScanMetadata(r.Context(), dbConn, id)
+	if metaErr != nil && metaErr != sql.ErrNoRows {
+		log.Error("Error finding latest scan", zap.Error(metaErr))
+	}
+
+	// Findings are grouped by OWASP category under the taxonomy the scan used
+	taxonomy := services.OWASP2021Taxonomy
+	if metaErr == nil {
+		taxonomy = services.TaxonomyOrDefault(meta.Taxonomy)
+	}
 
 	// Organize vulnerabilities by OWASP category
 	categorizedVulns := make(map[string][]interface{})
@@ -1134,129 +1285,367 @@ func (h *RepositoryHandler) GetVulnerabilities(w http.ResponseWriter, r *http.Re
 	// Process each vulnerability
 	for _, vuln := range vulnerabilities {
 		// Determine the appropriate OWASP Top 10 category based on vulnerability type
-		owaspCategory := mapVulnerabilityTypeToOWASP(vuln.Type)
+		owaspCategory := mapVulnerabilityTypeToOWASP(taxonomy, vuln.Type)
 
 		if categorizedVulns[owaspCategory] == nil {
 			categorizedVulns[owaspCategory] = []interface{}{}
 		}
 
-		categorizedVulns[owaspCategory] = append(categorizedVulns[owaspCategory], map[string]interface{}{
-			"id":             vuln.ID,
-			"description":    vuln.Description,
-			"severity":       vuln.Severity,
-			"file_path":      vuln.FilePath,
-			"line_number":    vuln.LineStart,
-			"code_snippet":   vuln.Code,
-			"recommendation": vuln.Remediation,
-		})
+		categorizedVulns[owaspCategory] = append(categorizedVulns[owaspCategory], vulnerabilitySummary(vuln))
 	}
 
-	// Find latest scan ID for this repository (if not already known)
-	var scanID string
-	err = dbConn.QueryRowContext(r.Context(),
-		`SELECT id FROM scans WHERE repository_id = $1 ORDER BY created_at DESC LIMIT 1`,
-		id).Scan(&scanID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			scanID = "unknown"
-		} else {
-			log.Error("Error finding latest scan", zap.Error(err))
+	response := map[string]interface{}{
+		"scan_id":               "unknown",
+		"repository_id":         id,
+		"status":                "completed",
+		"commit_sha":            nil,
+		"ref":                   nil,
+		"scan_started_at":       nil,
+		"scan_completed_at":     nil,
+		"vulnerabilities_count": page.ReportedCount,
+		"excluded_count":        page.ExcludedCount,
+		"baselined_count":       page.BaselinedCount,
+		"low_confidence_count":  page.LowConfidenceCount,
+		"results_available":     true,
+		"taxonomy":              taxonomy.Name,
+		"total":                 page.Total,
+		"limit":                 filter.Limit,
+		"offset":                filter.Offset,
+		"summary":               vulnerabilityPageSummary(taxonomy, page),
+	}
+	if metaErr == nil {
+		response["scan_id"] = meta.ID
+		response["status"] = meta.Status
+		meta.addTo(response)
+	}
+
+	if groupBy == groupByFile {
+		// Same findings nested under their file paths, each with a per-file severity summary
+		fileGroups := []map[string]interface{}{}
+		for _, group := range services.GroupFindingsByFile(vulnerabilities) {
+			findings := make([]interface{}, 0, len(group.Vulnerabilities))
+			for _, vuln := range group.Vulnerabilities {
+				findings = append(findings, vulnerabilitySummary(vuln))
+			}
+			fileGroups = append(fileGroups, map[string]interface{}{
+				"file_path":           group.FilePath,
+				"vulnerability_count": group.VulnerabilityCount,
+				"severity_summary":    group.SeveritySummary,
+				"vulnerabilities":     findings,
+			})
 		}
+		response["vulnerabilities_by_file"] = fileGroups
+	} else {
+		response["vulnerabilities_by_category"] = categorizedVulns
 	}
 
 	// Return a properly formatted response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"scan_id":                     scanID,
-		"repository_id":               id,
-		"status":                      "completed",
-		"scan_started_at":             time.Now().Add(-1 * time.Hour).Format(time.RFC3339),
-		"scan_completed_at":           time.Now().Format(time.RFC3339),
-		"vulnerabilities_count":       len(vulnerabilities),
-		"vulnerabilities_by_category": categorizedVulns,
-		"results_available":           true,
-	})
+	json.NewEncoder(w).Encode(response)
+}
+
+// readVulnerabilityFilter reads the paging and ?severity=, ?type=, and ?file_path= filters of the repository
+// vulnerabilities endpoint; severity and type take comma-separated lists. It answers 400 and returns false
+// for an invalid value
+func readVulnerabilityFilter(w http.ResponseWriter, r *http.Request) (services.VulnerabilityFilter, bool) {
+	var filter services.VulnerabilityFilter
+
+	limit, ok := pageParam(r, "limit", services.DefaultVulnerabilityPageLimit)
+	if !ok || limit < 1 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "limit must be a positive integer")
+		return filter, false
+	}
+	filter.Limit = min(limit, services.MaxVulnerabilityPageLimit)
+
+	filter.Offset, ok = pageParam(r, "offset", 0)
+	if !ok || filter.Offset < 0 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "offset must be a non-negative integer")
+		return filter, false
+	}
+
+	for _, severity := range queryList(r, "severity") {
+		if services.SeverityRank(severity) == 0 {
+			respondError(w, http.StatusBadRequest, "invalid_parameter",
+				fmt.Sprintf("unknown severity %q: expected critical, high, medium, or low", severity))
+			return filter, false
+		}
+		filter.Severities = append(filter.Severities, severity)
+	}
+	filter.Types = queryList(r, "type")
+	filter.FilePath = services.NormalizeFindingPath(r.URL.Query().Get("file_path"))
+	return filter, true
+}
+
+// queryList reads a query parameter given as a comma-separated list, repeated, or both, skipping empty entries
+func queryList(r *http.Request, name string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[name] {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				values = append(values, entry)
+			}
+		}
+	}
+	return values
+}
+
+// vulnerabilityPageSummary counts every finding the filter shows, not just the page, by OWASP category
+// under the scan's taxonomy and by severity
+func vulnerabilityPageSummary(taxonomy *services.Taxonomy, page *services.VulnerabilityPage) map[string]interface{} {
+	byCategory := make(map[string]int)
+	for vulnType, count := range page.TypeCounts {
+		byCategory[mapVulnerabilityTypeToOWASP(taxonomy, VulnerabilityType(vulnType))] += count
+	}
+	return map[string]interface{}{
+		"by_category": byCategory,
+		"by_severity": page.SeverityCounts,
+	}
+}
+
+// vulnerabilitySummary is the shape a finding takes in the repository vulnerabilities response
+func vulnerabilitySummary(vuln *services.Vulnerability) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             vuln.ID,
+		"description":    vuln.Description,
+		"severity":       vuln.Severity,
+		"file_path":      vuln.FilePath,
+		"line_number":    vuln.LineStart,
+		"code_snippet":   vuln.Code,
+		"recommendation": vuln.Remediation,
+		"excluded":       vuln.Excluded,
+		"baselined":      vuln.Baselined,
+		"confidence":     vuln.Confidence,
+		"cwe":            vuln.CWE,
+		"references":     findingReferences(vuln),
+	}
+}
+
+// findingReferences returns a finding's reference links, as an empty list rather than null when it has none
+func findingReferences(vuln *services.Vulnerability) []string {
+	if vuln.References == nil {
+		return []string{}
+	}
+	return vuln.References
 }
 
-// Helper function to map vulnerability types to OWASP categories
-func mapVulnerabilityTypeToOWASP(vulnType VulnerabilityType) string {
-	switch vulnType {
-	case Injection:
-		return "A03:2021"
-	case BrokenAccessControl:
-		return "A01:2021"
-	case CryptographicFailures:
-		return "A02:2021"
-	case InsecureDesign:
-		return "A04:2021"
-	case SecurityMisconfiguration:
-		return "A05:2021"
-	case VulnerableComponents:
-		return "A06:2021"
-	case IdentificationAuthFailures:
-		return "A07:2021"
-	case SoftwareIntegrityFailures:
-		return "A08:2021"
-	case SecurityLoggingFailures:
-		return "A09:2021"
-	case ServerSideRequestForgery:
-		return "A10:2021"
+// Values accepted by the group_by query parameter of the findings endpoints
+const (
+	groupByCategory = "category"
+	groupByFile     = "file"
+)
+
+// findingsGrouping reads ?group_by= from the request; findings are grouped by category unless it says otherwise
+func findingsGrouping(r *http.Request) (string, error) {
+	switch groupBy := strings.ToLower(r.URL.Query().Get("group_by")); groupBy {
+	case "", groupByCategory:
+		return groupByCategory, nil
+	case groupByFile:
+		return groupByFile, nil
 	default:
-		return "Other"
+		return "", fmt.Errorf("invalid group_by %q: must be %q or %q", groupBy, groupByCategory, groupByFile)
 	}
 }
 
-// parseGitHubRepoURL parses a GitHub URL to extract owner and repo name
-func parseGitHubRepoURL(url string) (owner, name string, err error) {
-	// Log the parsing attempt
-	log := logger.Get()
-	log.Debug("Parsing GitHub URL", zap.String("url", url))
+// includeExcludedFindings reports whether the request asked for findings in excluded paths (?include_excluded=true)
+func includeExcludedFindings(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_excluded"))
+	return include
+}
 
-	// Normalize URL by trimming whitespace and trailing slashes
-	url = strings.TrimSpace(url)
-	url = strings.TrimSuffix(url, "/")
+// includeBaselinedFindings reports whether the request asked for findings accepted in the baseline (?include_baselined=true)
+func includeBaselinedFindings(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_baselined"))
+	return include
+}
 
-	// GitHub URL formats:
-	// - https://github.com/owner/repo
-	// - https://github.com/owner/repo.git
-	// - https://github.com/owner/repo/
-	// - git@github.com:owner/repo.git
-	// - github.com/owner/repo
+// filterExcludedFindings drops findings marked excluded unless includeExcluded is set
+// It returns the findings to show and how many excluded findings the scan has in total
+func filterExcludedFindings(vulns []*services.Vulnerability, includeExcluded bool) ([]*services.Vulnerability, int) {
+	return filterFindings(vulns, includeExcluded, func(vuln *services.Vulnerability) bool { return vuln.Excluded })
+}
 
-	// Handle github.com/owner/repo format (without https://)
-	if strings.HasPrefix(url, "github.com/") {
-		url = "https://" + url
+// filterBaselinedFindings drops findings accepted in the repository baseline unless includeBaselined is set
+// It returns the findings to show and how many baselined findings the scan has in total
+func filterBaselinedFindings(vulns []*services.Vulnerability, includeBaselined bool) ([]*services.Vulnerability, int) {
+	return filterFindings(vulns, includeBaselined, func(vuln *services.Vulnerability) bool { return vuln.Baselined })
+}
+
+// minConfidence reads ?min_confidence= (low, medium, high, or a number from 0.0 to 1.0); nil when it isn't set
+func minConfidence(r *http.Request) (*float64, error) {
+	value := strings.TrimSpace(r.URL.Query().Get("min_confidence"))
+	if value == "" {
+		return nil, nil
+	}
+	confidence, err := baml.ParseConfidence(value)
+	if err != nil {
+		return nil, err
 	}
+	minimum := float64(confidence)
+	return &minimum, nil
+}
+
+// filterLowConfidenceFindings drops findings the model was less sure of than minimum, when one is set
+// Findings without a recorded confidence are kept, since there is nothing to judge them by.
+// It returns the findings to show and how many were dropped
+func filterLowConfidenceFindings(vulns []*services.Vulnerability, minimum *float64) ([]*services.Vulnerability, int) {
+	return filterFindings(vulns, false, func(vuln *services.Vulnerability) bool {
+		// Stored confidences are single precision, so 0.9 reads back a hair below the 0.9 threshold
+		return minimum != nil && vuln.Confidence != nil && *vuln.Confidence < *minimum-1e-6
+	})
+}
 
-	if strings.HasPrefix(url, "https://github.com/") {
-		parts := strings.Split(strings.TrimPrefix(url, "https://github.com/"), "/")
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid GitHub URL format")
+// filterFindings drops findings for which hidden returns true unless include is set
+func filterFindings(vulns []*services.Vulnerability, include bool, hidden func(*services.Vulnerability) bool) ([]*services.Vulnerability, int) {
+	hiddenCount := 0
+	for _, vuln := range vulns {
+		if hidden(vuln) {
+			hiddenCount++
 		}
-		owner = parts[0]
-		name = strings.TrimSuffix(parts[1], ".git")
-		return owner, name, nil
-	} else if strings.HasPrefix(url, "http://github.com/") {
-		parts := strings.Split(strings.TrimPrefix(url, "http://github.com/"), "/")
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid GitHub URL format")
+	}
+	if include || hiddenCount == 0 {
+		return vulns, hiddenCount
+	}
+
+	visible := make([]*services.Vulnerability, 0, len(vulns)-hiddenCount)
+	for _, vuln := range vulns {
+		if !hidden(vuln) {
+			visible = append(visible, vuln)
 		}
-		owner = parts[0]
-		name = strings.TrimSuffix(parts[1], ".git")
-		return owner, name, nil
-	} else if strings.HasPrefix(url, "git@github.com:") {
-		parts := strings.Split(strings.TrimPrefix(url, "git@github.com:"), "/")
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid GitHub URL format")
+	}
+	return visible, hiddenCount
+}
+
+// countReported counts findings that are neither path-excluded nor accepted in the baseline
+func countReported(vulns []*services.Vulnerability) int {
+	count := 0
+	for _, vuln := range vulns {
+		if !vuln.Excluded && !vuln.Baselined {
+			count++
 		}
-		owner = parts[0]
-		name = strings.TrimSuffix(parts[1], ".git")
-		return owner, name, nil
 	}
+	return count
+}
+
+// Helper function to map vulnerability types to OWASP categories under the scan's taxonomy
+func mapVulnerabilityTypeToOWASP(taxonomy *services.Taxonomy, vulnType VulnerabilityType) string {
+	return taxonomy.OWASPCategory(vulnType)
+}
 
-	log.Error("Unsupported GitHub URL format", zap.String("url", url))
-	return "", "", fmt.Errorf("unsupported GitHub URL format: %s (expected 'https://github.com/owner/repo')", url)
+// writeRepositoryLookupError answers a failed repository metadata lookup that has a specific status
+// A rate-limited lookup returns 429 with Retry-After so clients back off until the limit resets;
+// an unknown repository returns 404 and a refused URL 400. It returns false for other errors, which the caller reports
+func writeRepositoryLookupError(w http.ResponseWriter, err error) bool {
+	lookupErr := repositoryLookupError(err)
+	if lookupErr == nil {
+		return false
+	}
+	writeScanStartError(w, lookupErr, "")
+	return true
+}
+
+// repositoryLookupError returns the response for a failed repository lookup whose cause the caller
+// can act on, or nil for other failures
+func repositoryLookupError(err error) *scanStartError {
+	var rateLimited *services.RateLimitError
+	switch {
+	case errors.As(err, &rateLimited):
+		retryAfter := int(time.Until(rateLimited.Reset).Seconds()) + 1
+		return &scanStartError{Status: http.StatusTooManyRequests, Code: "github_rate_limited", Message: err.Error(), RetryAfter: max(retryAfter, 1)}
+	case errors.Is(err, services.ErrRepositoryNotFound):
+		return &scanStartError{Status: http.StatusNotFound, Code: "repository_not_found", Message: err.Error()}
+	case errors.Is(err, services.ErrInvalidRepositoryURL):
+		return &scanStartError{Status: http.StatusBadRequest, Code: "invalid_repository_url", Message: err.Error()}
+	default:
+		return nil
+	}
+}
+
+// GetScanHistory returns a sanitized timeline of a scan's workflow: clone and scan starts and finishes,
+// retries, and failures with their messages. Unlike DebugWorkflow it exposes no raw Temporal payloads.
+// Only the repository's owner or an admin may view it.
+func (h *RepositoryHandler) GetScanHistory(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return
+	}
+
+	// Accept either a scan record ID or a repository ID, like the other /scan/{id} endpoints
+	repoID, err := scanRepositoryID(r.Context(), dbConn, id)
+	if err != nil {
+		log.Error("Failed to look up scan", zap.String("scan_id", id), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	allowed, err := userCanAccessRepository(r.Context(), dbConn, userID, repoID)
+	if err == nil && !allowed {
+		allowed, err = userIsAdmin(r.Context(), dbConn, userID)
+	}
+	if err != nil {
+		log.Error("Error checking scan history access", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
+		return
+	}
+	if !allowed {
+		log.Warn("User attempted to view history of an unauthorized scan",
+			zap.String("user_id", userID),
+			zap.String("repo_id", repoID))
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return
+	}
+
+	// Read the history of the scan's workflow; a repository ID stands for its latest scan
+	scanID, err := latestScanID(r.Context(), dbConn, id)
+	if err != nil {
+		log.Error("Failed to look up scan", zap.String("scan_id", id), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+	if scanID == "" {
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return
+	}
+	workflowID := scanWorkflowID(scanID)
+	iter := h.TemporalClient.GetWorkflowHistory(r.Context(), workflowID, "", false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+
+	var events []*historypb.HistoryEvent
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			log.Error("Failed to read workflow history",
+				zap.String("workflow_id", workflowID),
+				zap.Error(err))
+			respondError(w, http.StatusNotFound, "scan_workflow_not_found", "Scan workflow history not found")
+			return
+		}
+		events = append(events, event)
+	}
+
+	timeline := temporal.BuildScanTimeline(events)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"scan_id":       scanID,
+		"repository_id": repoID,
+		"events":        timeline,
+	})
 }
 
 // DebugWorkflow provides detailed information about a Temporal workflow
@@ -1264,18 +1653,27 @@ func (h *RepositoryHandler) DebugWorkflow(w http.ResponseWriter, r *http.Request
 	log := logger.FromContext(r.Context())
 	scanID := chi.URLParam(r, "id")
 	if scanID == "" {
-		http.Error(w, "Scan ID is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
 		return
 	}
 
-	workflowID := "scan-workflow-" + scanID
+	// A repository ID stands for its latest scan
+	if dbConn := h.GitHubService.GetDatabaseConnection(); dbConn != nil {
+		if latest, err := latestScanID(r.Context(), dbConn, scanID); err != nil {
+			log.Warn("Failed to look up scan", zap.String("scan_id", scanID), zap.Error(err))
+		} else if latest != "" {
+			scanID = latest
+		}
+	}
+
+	workflowID := scanWorkflowID(scanID)
 	log.Info("Debugging workflow", zap.String("workflow_id", workflowID))
 
 	// Get workflow description
 	resp, err := h.TemporalClient.DescribeWorkflowExecution(r.Context(), workflowID, "")
 	if err != nil {
 		log.Error("Failed to get workflow description", zap.Error(err))
-		http.Error(w, "Failed to get workflow information: "+err.Error(), http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get workflow information: "+err.Error())
 		return
 	}
 
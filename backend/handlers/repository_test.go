@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+// createTestRepository inserts a user and a repository linked to them, returning both IDs
+func createTestRepository(t *testing.T, dbConn *sql.DB) (userID, repoID string) {
+	t.Helper()
+	ctx := context.Background()
+	name := "repo-" + uuid.NewString()
+
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'Test User') RETURNING id`,
+		"user-"+uuid.NewString()+"@example.com").Scan(&userID); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO repositories (owner, name, url, clone_url, created_by) VALUES ('test-owner', $1, $2, $3, $4) RETURNING id`,
+		name, "https://github.com/test-owner/"+name, "https://github.com/test-owner/"+name+".git", userID).Scan(&repoID); err != nil {
+		t.Fatalf("insert repository: %v", err)
+	}
+	if _, err := dbConn.ExecContext(ctx,
+		`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)`, userID, repoID); err != nil {
+		t.Fatalf("link repository: %v", err)
+	}
+	return userID, repoID
+}
+
+// newScanRequest returns a POST /api/repositories/{id}/scan request from the user
+func newScanRequest(userID, repoID string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/repositories/"+repoID+"/scan", nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", repoID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)
+	return r.WithContext(context.WithValue(ctx, "userID", userID))
+}
+
+// withJSONBody gives a request a JSON body
+func withJSONBody(r *http.Request, body string) *http.Request {
+	r.Body = io.NopCloser(strings.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestRunningScanStatusFollowsTheLifecycle(t *testing.T) {
+	// The statuses a scan record passes through while its workflow runs, in order
+	tests := []struct {
+		dbStatus string
+		want     string
+	}{
+		{dbStatus: "", want: services.ScanStatusQueued},
+		{dbStatus: "pending", want: services.ScanStatusQueued},
+		{dbStatus: services.ScanStatusQueued, want: services.ScanStatusQueued},
+		{dbStatus: services.ScanStatusCloning, want: services.ScanStatusCloning},
+		{dbStatus: services.ScanStatusScanning, want: services.ScanStatusScanning},
+		{dbStatus: "in_progress", want: services.ScanStatusScanning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dbStatus, func(t *testing.T) {
+			if got := runningScanStatus(tt.dbStatus); got != tt.want {
+				t.Errorf("runningScanStatus(%q) = %q, want %q", tt.dbStatus, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateScanPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{policy: "", want: duplicateScanReject},
+		{policy: "reject", want: duplicateScanReject},
+		{policy: "reuse", want: duplicateScanReuse},
+		{policy: "Restart", want: duplicateScanRestart},
+		{policy: "sometimes", want: duplicateScanReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Setenv("SCAN_DUPLICATE_POLICY", tt.policy)
+			if got := duplicateScanPolicy(); got != tt.want {
+				t.Errorf("duplicateScanPolicy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanWorkflowOptionsAreUniquePerScan(t *testing.T) {
+	// The workflow is named after the scan, so scans of one repository never share a workflow ID
+	first, second := scanWorkflowOptions("scan-1"), scanWorkflowOptions("scan-2")
+	if first.ID != "scan-workflow-scan-1" || second.ID != "scan-workflow-scan-2" {
+		t.Errorf("workflow IDs = %q and %q, want scan-workflow-<scan ID>", first.ID, second.ID)
+	}
+}
+
+func TestScanRepositoryGuardsARunningScan(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		query        string
+		workflow     enums.WorkflowExecutionStatus // Status of the active scan's workflow
+		legacy       bool                          // The workflow runs under the repository's ID, as scans did before per-scan IDs
+		wantStatus   int
+		wantReused   bool
+		wantCanceled bool
+		wantStarted  bool
+	}{
+		{name: "rejected by default", workflow: enums.WORKFLOW_EXECUTION_STATUS_RUNNING, wantStatus: http.StatusConflict},
+		{name: "reused", policy: "reuse", workflow: enums.WORKFLOW_EXECUTION_STATUS_RUNNING, wantStatus: http.StatusOK, wantReused: true},
+		{name: "forced", query: "?force=true", workflow: enums.WORKFLOW_EXECUTION_STATUS_RUNNING,
+			wantStatus: http.StatusAccepted, wantCanceled: true, wantStarted: true},
+		{name: "restart policy", policy: "restart", workflow: enums.WORKFLOW_EXECUTION_STATUS_RUNNING,
+			wantStatus: http.StatusAccepted, wantCanceled: true, wantStarted: true},
+		// A record left active by a workflow that has ended doesn't block new scans
+		{name: "stale record", workflow: enums.WORKFLOW_EXECUTION_STATUS_FAILED, wantStatus: http.StatusAccepted, wantStarted: true},
+		// A scan started before each scan had its own workflow is still guarded until it finishes
+		{name: "legacy workflow rejected", legacy: true, workflow: enums.WORKFLOW_EXECUTION_STATUS_RUNNING, wantStatus: http.StatusConflict},
+		{name: "legacy workflow forced", legacy: true, query: "?force=true", workflow: enums.WORKFLOW_EXECUTION_STATUS_RUNNING,
+			wantStatus: http.StatusAccepted, wantCanceled: true, wantStarted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCAN_DUPLICATE_POLICY", tt.policy)
+			dbConn := testdb.Open(t)
+			ctx := context.Background()
+			userID, repoID := createTestRepository(t, dbConn)
+
+			var activeScanID string
+			if err := dbConn.QueryRowContext(ctx,
+				`INSERT INTO scans (repository_id, status, started_at) VALUES ($1, $2, NOW()) RETURNING id`,
+				repoID, services.ScanStatusScanning).Scan(&activeScanID); err != nil {
+				t.Fatalf("insert running scan: %v", err)
+			}
+
+			run := &mocks.WorkflowRun{}
+			run.On("GetRunID").Return("run-2")
+			temporalClient := &mocks.Client{}
+			temporalClient.On("CheckHealth", mock.Anything, mock.Anything).Return(&client.CheckHealthResponse{}, nil)
+			workflowID, otherID := "scan-workflow-"+activeScanID, "scan-workflow-"+repoID
+			if tt.legacy {
+				workflowID, otherID = otherID, workflowID
+			}
+			temporalClient.On("DescribeWorkflowExecution", mock.Anything, workflowID, "").Return(describedWorkflow(tt.workflow), nil)
+			temporalClient.On("CancelWorkflow", mock.Anything, workflowID, "").Return(nil)
+			temporalClient.On("DescribeWorkflowExecution", mock.Anything, otherID, "").Return(nil, serviceerror.NewNotFound("no workflow"))
+			temporalClient.On("CancelWorkflow", mock.Anything, otherID, "").Return(serviceerror.NewNotFound("no workflow"))
+			var started client.StartWorkflowOptions
+			temporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { started = args.Get(1).(client.StartWorkflowOptions) }).
+				Return(run, nil)
+
+			queries := db.NewQueries()
+			queries.SetDB(dbConn)
+			handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries), TemporalClient: temporalClient}
+
+			r := newScanRequest(userID, repoID)
+			r.URL.RawQuery = strings.TrimPrefix(tt.query, "?")
+			rec := httptest.NewRecorder()
+			handler.ScanRepository(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			var body map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+
+			if tt.wantStarted {
+				// The new scan gets its own record and a workflow named after it
+				newScanID, _ := body["scan_record_id"].(string)
+				if newScanID == "" || newScanID == activeScanID {
+					t.Errorf("scan_record_id = %v, want a new scan", body["scan_record_id"])
+				}
+				if started.ID != "scan-workflow-"+newScanID {
+					t.Errorf("started workflow %q, want scan-workflow-%s", started.ID, newScanID)
+				}
+			} else {
+				if body["scan_record_id"] != activeScanID || body["status"] != "scan_in_progress" {
+					t.Errorf("response = %v, want the running scan %s", body, activeScanID)
+				}
+				if (body["reused"] == true) != tt.wantReused {
+					t.Errorf("reused = %v, want %v", body["reused"], tt.wantReused)
+				}
+				temporalClient.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			if canceled := calledMethod(temporalClient, "CancelWorkflow"); canceled != tt.wantCanceled {
+				t.Errorf("running scan canceled = %v, want %v", canceled, tt.wantCanceled)
+			}
+			if tt.wantCanceled {
+				temporalClient.AssertCalled(t, "CancelWorkflow", mock.Anything, workflowID, "")
+			}
+			var activeStatus string
+			if err := dbConn.QueryRowContext(ctx, `SELECT status FROM scans WHERE id = $1`, activeScanID).Scan(&activeStatus); err != nil {
+				t.Fatalf("read running scan: %v", err)
+			}
+			if wantStatus := map[bool]string{true: services.ScanStatusCanceled, false: services.ScanStatusScanning}[tt.wantCanceled]; activeStatus != wantStatus {
+				t.Errorf("running scan record status = %q, want %q", activeStatus, wantStatus)
+			}
+		})
+	}
+}
+
+// calledMethod reports whether the mock client received a call to the method
+func calledMethod(temporalClient *mocks.Client, method string) bool {
+	for _, call := range temporalClient.Calls {
+		if call.Method == method {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFilterExcludedFindings(t *testing.T) {
+	vulns := []*services.Vulnerability{
+		{FilePath: "main.go"},
+		{FilePath: "third_party/lib.go", Excluded: true},
+		{FilePath: "api.go"},
+		{FilePath: "api.pb.go", Excluded: true},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantShown int
+	}{
+		{name: "default hides excluded findings", query: "", wantShown: 2},
+		{name: "include_excluded=true shows them", query: "?include_excluded=true", wantShown: 4},
+		{name: "invalid value keeps the default", query: "?include_excluded=maybe", wantShown: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/repositories/repo-1/vulnerabilities"+tt.query, nil)
+			shown, excludedCount := filterExcludedFindings(vulns, includeExcludedFindings(r))
+			if len(shown) != tt.wantShown {
+				t.Errorf("%d findings shown, want %d", len(shown), tt.wantShown)
+			}
+			if excludedCount != 2 {
+				t.Errorf("excluded count = %d, want 2", excludedCount)
+			}
+			// The reported count never includes excluded findings
+			if got := countReported(shown); got != 2 {
+				t.Errorf("vulnerabilities_count = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestScanRepositoryRejectsInvalidModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "shell characters", body: `{"model": "gpt-4; rm -rf /"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "path", body: `{"model": "../../etc/passwd"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "too long", body: `{"model": "` + strings.Repeat("a", 101) + `"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", body: `{"model":`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := withJSONBody(newScanRequest("user-1", "repo-1"), tt.body)
+
+			// The request is refused before the handler touches the database or Temporal
+			rec := httptest.NewRecorder()
+			(&RepositoryHandler{}).ScanRepository(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFilterBaselinedFindings(t *testing.T) {
+	vulns := []*services.Vulnerability{
+		{FilePath: "main.go"},
+		{FilePath: "db.go", Baselined: true},
+		{FilePath: "third_party/lib.go", Excluded: true, Baselined: true},
+		{FilePath: "api.go"},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/repositories/repo-1/vulnerabilities", nil)
+	shown, excludedCount := filterExcludedFindings(vulns, includeExcludedFindings(r))
+	shown, baselinedCount := filterBaselinedFindings(shown, includeBaselinedFindings(r))
+	if len(shown) != 2 || excludedCount != 1 || baselinedCount != 1 {
+		t.Errorf("default view shows %d findings with %d excluded and %d baselined, want 2, 1, 1",
+			len(shown), excludedCount, baselinedCount)
+	}
+
+	// Clearing the baseline is the same as asking for baselined findings: they are reported again
+	r = httptest.NewRequest(http.MethodGet, "/api/repositories/repo-1/vulnerabilities?include_baselined=true", nil)
+	shown, _ = filterExcludedFindings(vulns, includeExcludedFindings(r))
+	shown, baselinedCount = filterBaselinedFindings(shown, includeBaselinedFindings(r))
+	if len(shown) != 3 || baselinedCount != 1 {
+		t.Errorf("include_baselined view shows %d findings with %d baselined, want 3, 1", len(shown), baselinedCount)
+	}
+	if got := countReported(shown); got != 2 {
+		t.Errorf("vulnerabilities_count = %d, want baselined findings left out", got)
+	}
+}
+
+func TestIsTemporalUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unavailable", err: serviceerror.NewUnavailable("connection refused"), want: true},
+		{name: "wrapped unavailable", err: fmt.Errorf("start workflow: %w", serviceerror.NewUnavailable("down")), want: true},
+		{name: "server deadline", err: serviceerror.NewDeadlineExceeded("slow"), want: true},
+		{name: "client deadline", err: context.DeadlineExceeded, want: true},
+		{name: "rejected request", err: serviceerror.NewInvalidArgument("bad input")},
+		{name: "already started", err: serviceerror.NewWorkflowExecutionAlreadyStarted("running", "req-1", "run-1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTemporalUnavailable(tt.err); got != tt.want {
+				t.Errorf("isTemporalUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTemporalAvailableWithoutAClient(t *testing.T) {
+	if err := (&RepositoryHandler{}).checkTemporalAvailable(context.Background()); err == nil {
+		t.Error("checkTemporalAvailable succeeded without a Temporal client")
+	}
+}
+
+func TestScanRepositoryReturns503WhenTemporalIsDown(t *testing.T) {
+	tests := []struct {
+		name           string
+		healthErr      error
+		executeErr     error
+		wantExecuteRun bool
+	}{
+		{name: "health check fails", healthErr: serviceerror.NewUnavailable("connection refused")},
+		{name: "workflow start fails", executeErr: serviceerror.NewUnavailable("connection reset"), wantExecuteRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbConn := testdb.Open(t)
+			userID, repoID := createTestRepository(t, dbConn)
+
+			temporalClient := &mocks.Client{}
+			temporalClient.On("CheckHealth", mock.Anything, mock.Anything).Return(&client.CheckHealthResponse{}, tt.healthErr)
+			temporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(nil, tt.executeErr)
+
+			queries := db.NewQueries()
+			queries.SetDB(dbConn)
+			handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries), TemporalClient: temporalClient}
+
+			rec := httptest.NewRecorder()
+			handler.ScanRepository(rec, newScanRequest(userID, repoID))
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body.String())
+			}
+			if rec.Header().Get("Retry-After") == "" {
+				t.Error("503 response has no Retry-After header")
+			}
+			if ran := len(temporalClient.Calls) > 1; ran != tt.wantExecuteRun {
+				t.Errorf("ExecuteWorkflow called = %v, want %v", ran, tt.wantExecuteRun)
+			}
+
+			var scans int
+			if err := dbConn.QueryRow(`SELECT COUNT(*) FROM scans WHERE repository_id = $1`, repoID).Scan(&scans); err != nil {
+				t.Fatalf("count scans: %v", err)
+			}
+			if scans != 0 {
+				t.Errorf("repository has %d scans, want no orphaned scan record", scans)
+			}
+		})
+	}
+}
+
+func TestFindingsGrouping(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{query: "", want: groupByCategory},
+		{query: "?group_by=category", want: groupByCategory},
+		{query: "?group_by=file", want: groupByFile},
+		{query: "?group_by=FILE", want: groupByFile},
+		{query: "?group_by=severity", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/scan/scan-1/results"+tt.query, nil)
+			got, err := findingsGrouping(r)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("findingsGrouping = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetVulnerabilitiesRejectsUnknownGrouping(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/repositories/repo-1/vulnerabilities?group_by=severity", nil)
+	rec := httptest.NewRecorder()
+	(&RepositoryHandler{}).GetVulnerabilities(rec, r)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetVulnerabilitiesRejectsInvalidFilters(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantCode string
+	}{
+		{query: "?limit=0", wantCode: "invalid_pagination"},
+		{query: "?limit=ten", wantCode: "invalid_pagination"},
+		{query: "?offset=-1", wantCode: "invalid_pagination"},
+		{query: "?severity=high,urgent", wantCode: "invalid_parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/repositories/repo-1/vulnerabilities"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			(&RepositoryHandler{}).GetVulnerabilities(rec, r)
+
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("status = %d, body %s, want a 400 with %s", rec.Code, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestReadVulnerabilityFilter(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet,
+		"/api/repositories/repo-1/vulnerabilities?severity=Critical,%20high&severity=&type=Injection&type=XSS,&file_path=./src/api/&limit=1000&offset=20", nil)
+	rec := httptest.NewRecorder()
+	filter, ok := readVulnerabilityFilter(rec, r)
+	if !ok {
+		t.Fatalf("readVulnerabilityFilter rejected the request: %s", rec.Body.String())
+	}
+
+	if !slices.Equal(filter.Severities, []string{"Critical", "high"}) {
+		t.Errorf("severities = %v, want [Critical high]", filter.Severities)
+	}
+	if !slices.Equal(filter.Types, []string{"Injection", "XSS"}) {
+		t.Errorf("types = %v, want [Injection XSS]", filter.Types)
+	}
+	if filter.FilePath != "src/api" {
+		t.Errorf("file path = %q, want src/api", filter.FilePath)
+	}
+	if filter.Limit != services.MaxVulnerabilityPageLimit || filter.Offset != 20 {
+		t.Errorf("limit, offset = %d, %d; want %d, 20", filter.Limit, filter.Offset, services.MaxVulnerabilityPageLimit)
+	}
+}
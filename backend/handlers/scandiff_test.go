@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDiffRepositoryScansRequiresBothScans(t *testing.T) {
+	for _, query := range []string{"", "?from=scan-1", "?to=scan-2", "?from=&to=scan-2"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/repositories/repo-1/scans/diff"+query, nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "repo-1")
+		r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), "userID", "user-1"))
+
+		rec := httptest.NewRecorder()
+		(&RepositoryHandler{}).DiffRepositoryScans(rec, r)
+		if got := decodeErrorResponse(t, rec, http.StatusBadRequest); got.Code != "scan_id_required" {
+			t.Errorf("%q: error code = %q, want scan_id_required", query, got.Code)
+		}
+	}
+}
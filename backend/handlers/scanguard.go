@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/temporal"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+)
+
+// What to do when a repository is scanned while an earlier scan of it is still running,
+// set with SCAN_DUPLICATE_POLICY
+const (
+	duplicateScanReject  = "reject"  // Refuse the new scan with 409 Conflict (default)
+	duplicateScanReuse   = "reuse"   // Point the caller at the running scan
+	duplicateScanRestart = "restart" // Cancel the running scan and start the new one
+)
+
+// duplicateScanPolicy returns the configured SCAN_DUPLICATE_POLICY, defaulting to reject
+func duplicateScanPolicy() string {
+	policy := strings.ToLower(strings.TrimSpace(os.Getenv("SCAN_DUPLICATE_POLICY")))
+	switch policy {
+	case duplicateScanReject, duplicateScanReuse, duplicateScanRestart:
+		return policy
+	case "":
+		return duplicateScanReject
+	default:
+		logger.Warn("Invalid SCAN_DUPLICATE_POLICY value, rejecting duplicate scans", zap.String("value", policy))
+		return duplicateScanReject
+	}
+}
+
+// forceScan reports whether the request asked, with ?force=true, to cancel a running scan of the repository
+func forceScan(r *http.Request) bool {
+	force, err := strconv.ParseBool(r.URL.Query().Get("force"))
+	return err == nil && force
+}
+
+// scanWorkflowID returns the Temporal workflow ID of a scan
+// Every scan gets its own workflow, so a new scan never collides with the history of an earlier one
+func scanWorkflowID(scanID string) string {
+	return "scan-workflow-" + scanID
+}
+
+// legacyScanWorkflowID returns the workflow ID scans ran under before each scan had its own workflow
+// Scans a worker was still running when per-scan IDs were deployed keep that ID until they finish, so the
+// guard looks for it too; it can be dropped once no such scan is left
+func legacyScanWorkflowID(repoID string) string {
+	return "scan-workflow-" + repoID
+}
+
+// scanWorkflowOptions builds the start options for a scan's workflow
+func scanWorkflowOptions(scanID string) client.StartWorkflowOptions {
+	return client.StartWorkflowOptions{
+		ID:        scanWorkflowID(scanID),
+		TaskQueue: temporal.ScanTaskQueue(),
+	}
+}
+
+// latestScanID resolves an ID given to a /scan/{id} endpoint to a scan record ID
+// The ID may be a scan record ID, or a repository ID for its most recent scan; "" means no scan matches
+func latestScanID(ctx context.Context, dbConn *sql.DB, id string) (string, error) {
+	var scanID string
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT id FROM scans WHERE id::text = $1 OR repository_id::text = $1
+		ORDER BY created_at DESC LIMIT 1`, id).Scan(&scanID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return scanID, err
+}
+
+// runningScan returns the scan of the repository whose workflow is still running, with the workflow's run ID
+// It returns "" when there is none; records left active by a workflow that has since ended don't count
+func (h *RepositoryHandler) runningScan(ctx context.Context, dbConn *sql.DB, repoID string) (scanID, runID string, err error) {
+	rows, err := dbConn.QueryContext(ctx,
+		`SELECT id FROM scans
+		WHERE repository_id = $1 AND status IN ('queued', 'cloning', 'scanning', 'pending', 'in_progress')
+		ORDER BY created_at DESC`,
+		repoID)
+	if err != nil {
+		return "", "", err
+	}
+	var activeScanIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return "", "", err
+		}
+		activeScanIDs = append(activeScanIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+
+	for _, id := range activeScanIDs {
+		runID, err := h.runningWorkflow(ctx, scanWorkflowID(id))
+		if err != nil || runID != "" {
+			return id, runID, err
+		}
+	}
+
+	// A scan started under its repository's workflow ID is the most recent active record
+	if len(activeScanIDs) > 0 {
+		runID, err := h.runningWorkflow(ctx, legacyScanWorkflowID(repoID))
+		if err != nil || runID != "" {
+			return activeScanIDs[0], runID, err
+		}
+	}
+	return "", "", nil
+}
+
+// runningWorkflow returns the run ID of the workflow if it is running, or "" if it has ended or doesn't exist
+func (h *RepositoryHandler) runningWorkflow(ctx context.Context, workflowID string) (string, error) {
+	resp, err := h.TemporalClient.DescribeWorkflowExecution(ctx, workflowID, "")
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	info := resp.GetWorkflowExecutionInfo()
+	if info.GetStatus() != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		return "", nil
+	}
+	return info.GetExecution().GetRunId(), nil
+}
+
+// describeScanWorkflow describes the workflow of a scan, returning it with the workflow ID it was found under
+// A scan without its own workflow is looked for under its repository's legacy ID
+func (h *RepositoryHandler) describeScanWorkflow(ctx context.Context, scanID, repoID string) (*workflowservice.DescribeWorkflowExecutionResponse, string, error) {
+	workflowID := scanWorkflowID(scanID)
+	resp, err := h.TemporalClient.DescribeWorkflowExecution(ctx, workflowID, "")
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) && legacyScanWorkflowID(repoID) != workflowID {
+		workflowID = legacyScanWorkflowID(repoID)
+		resp, err = h.TemporalClient.DescribeWorkflowExecution(ctx, workflowID, "")
+	}
+	return resp, workflowID, err
+}
+
+// cancelScan asks a running scan's workflow to stop and marks its record canceled, so the repository
+// is free to scan again straight away. A scan without its own workflow is looked for under the legacy ID
+func (h *RepositoryHandler) cancelScan(ctx context.Context, dbConn *sql.DB, repoID, scanID string) error {
+	err := h.TemporalClient.CancelWorkflow(ctx, scanWorkflowID(scanID), "")
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		err = h.TemporalClient.CancelWorkflow(ctx, legacyScanWorkflowID(repoID), "")
+	}
+	if err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+
+	_, err = dbConn.ExecContext(ctx,
+		`UPDATE scans SET status = $1, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND status IN ('queued', 'cloning', 'scanning', 'pending', 'in_progress')`,
+		services.ScanStatusCanceled, scanID)
+	return err
+}
+
+// checkRunningScan applies the duplicate scan policy before a new scan of the repository is queued
+// It returns the repository's running scan, if any, for the caller to reject or reuse; with the restart
+// policy the running scan is canceled instead and "" is returned
+// Two requests arriving together can both find no running scan; each then gets its own workflow
+func (h *RepositoryHandler) checkRunningScan(ctx context.Context, dbConn *sql.DB, repoID, policy string) (scanID, runID string, err error) {
+	scanID, runID, err = h.runningScan(ctx, dbConn, repoID)
+	if err != nil || scanID == "" || policy != duplicateScanRestart {
+		return scanID, runID, err
+	}
+
+	if err := h.cancelScan(ctx, dbConn, repoID, scanID); err != nil {
+		return "", "", err
+	}
+	logger.FromContext(ctx).Info("Canceled running scan to start a new one",
+		zap.String("repo_id", repoID),
+		zap.String("canceled_scan_id", scanID),
+		zap.String("run_id", runID))
+	return "", "", nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// ListRepositoryScans returns every scan of a repository, newest first
+// Pages are selected with ?limit= (default 20, at most 100) and ?offset=
+func (h *RepositoryHandler) ListRepositoryScans(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	limit, ok := pageParam(r, "limit", services.DefaultScanHistoryLimit)
+	if !ok || limit < 1 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "limit must be a positive integer")
+		return
+	}
+	if limit > services.MaxScanHistoryLimit {
+		limit = services.MaxScanHistoryLimit
+	}
+
+	offset, ok := pageParam(r, "offset", 0)
+	if !ok || offset < 0 {
+		respondError(w, http.StatusBadRequest, "invalid_pagination", "offset must be a non-negative integer")
+		return
+	}
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	scans, total, err := services.NewScanHistoryService(db.NewQueries()).ListRepositoryScans(r.Context(), repoID, limit, offset)
+	if err != nil {
+		log.Error("Failed to list repository scans", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list scans")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository_id": repoID,
+		"scans":         scans,
+		"total":         total,
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
+
+// pageParam reads an integer query parameter, returning the default when it is absent
+// The second result is false when the value is present but not an integer
+func pageParam(r *http.Request, name string, defaultValue int) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, true
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// newScanListRequest returns a GET /api/repositories/{id}/scans request from the user
+func newScanListRequest(userID, repoID, query string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/repositories/"+repoID+"/scans"+query, nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", repoID)
+	return r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), "userID", userID))
+}
+
+func TestListRepositoryScansRejectsBadPages(t *testing.T) {
+	handler := &RepositoryHandler{}
+	for _, query := range []string{"?limit=0", "?limit=ten", "?offset=-1", "?offset=1.5"} {
+		rec := httptest.NewRecorder()
+		handler.ListRepositoryScans(rec, newScanListRequest("user-1", "repo-1", query))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestListRepositoryScansChecksOwnership(t *testing.T) {
+	dbConn := testdb.Open(t)
+	userID, repoID := createTestRepository(t, dbConn)
+	otherUserID, _ := createTestRepository(t, dbConn)
+
+	queries := db.NewQueries()
+	queries.SetDB(dbConn)
+	handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries)}
+
+	rec := httptest.NewRecorder()
+	handler.ListRepositoryScans(rec, newScanListRequest(otherUserID, repoID, ""))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("another user's request: status = %d, want 404", rec.Code)
+	}
+
+	// The handler lists scans through the global connection
+	db.SetGlobalDB(dbConn)
+	t.Cleanup(func() { db.SetGlobalDB(nil) })
+
+	rec = httptest.NewRecorder()
+	handler.ListRepositoryScans(rec, newScanListRequest(userID, repoID, "?limit=500"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("owner's request: status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Scans []map[string]any `json:"scans"`
+		Total int              `json:"total"`
+		Limit int              `json:"limit"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.Limit != services.MaxScanHistoryLimit || body.Total != 0 || len(body.Scans) != 0 {
+		t.Errorf("response = %+v, want an empty page capped at %d", body, services.MaxScanHistoryLimit)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// scanMetadata is what the scans table records about when and against what a scan ran
+type scanMetadata struct {
+	ID          string
+	Status      string
+	StartedAt   sql.NullTime
+	CompletedAt sql.NullTime
+	Ref         sql.NullString
+	CommitSHA   sql.NullString
+	Taxonomy    string // Taxonomy the scan's findings are categorized under
+}
+
+// latestScanMetadata loads the scan record for a scan ID, or the latest scan of a repository ID
+// It returns sql.ErrNoRows when there is no such scan
+func latestScanMetadata(ctx context.Context, dbConn *sql.DB, id string) (*scanMetadata, error) {
+	meta := &scanMetadata{}
+	err := dbConn.QueryRowContext(ctx,
+		`SELECT id::text, status, started_at, completed_at, ref, commit_sha, taxonomy FROM scans
+		WHERE id::text = $1 OR repository_id::text = $1
+		ORDER BY created_at DESC LIMIT 1`,
+		id).Scan(&meta.ID, &meta.Status, &meta.StartedAt, &meta.CompletedAt, &meta.Ref, &meta.CommitSHA, &meta.Taxonomy)
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// addTo sets the scan's commit and timestamps on a results response
+// Values the scan didn't record are null rather than guessed
+func (m *scanMetadata) addTo(response map[string]any) {
+	response["commit_sha"] = nullString(m.CommitSHA)
+	response["ref"] = nullString(m.Ref)
+	response["scan_started_at"] = nullTimestamp(m.StartedAt)
+	response["scan_completed_at"] = nullTimestamp(m.CompletedAt)
+}
+
+// nullString returns the string, or nil so it encodes as JSON null
+func nullString(value sql.NullString) any {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	return value.String
+}
+
+// nullTimestamp returns the time as RFC 3339, or nil so it encodes as JSON null
+func nullTimestamp(value sql.NullTime) any {
+	if !value.Valid {
+		return nil
+	}
+	return value.Time.UTC().Format(time.RFC3339)
+}
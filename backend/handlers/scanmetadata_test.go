@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestScanMetadataAddTo(t *testing.T) {
+	started := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	response := map[string]any{}
+	(&scanMetadata{
+		StartedAt: sql.NullTime{Time: started, Valid: true},
+		Ref:       sql.NullString{String: "refs/heads/main", Valid: true},
+		CommitSHA: sql.NullString{String: "", Valid: true},
+	}).addTo(response)
+
+	want := map[string]any{
+		"commit_sha":        nil, // Recorded but empty is as good as unknown
+		"ref":               "refs/heads/main",
+		"scan_started_at":   "2026-03-01T08:30:00Z",
+		"scan_completed_at": nil,
+	}
+	for key, value := range want {
+		if response[key] != value {
+			t.Errorf("%s = %v, want %v", key, response[key], value)
+		}
+	}
+}
+
+func TestGetVulnerabilitiesReportsStoredScanMetadata(t *testing.T) {
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+	userID, repoID := createTestRepository(t, dbConn)
+	_, emptyRepoID := createTestRepository(t, dbConn)
+	if _, err := dbConn.ExecContext(ctx,
+		`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)`, userID, emptyRepoID); err != nil {
+		t.Fatalf("link repository: %v", err)
+	}
+
+	started := time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC)
+	completed := started.Add(7 * time.Minute)
+	if _, err := dbConn.ExecContext(ctx,
+		`INSERT INTO scans (repository_id, status, started_at, completed_at, created_at)
+		VALUES ($1, 'completed', $2, $3, $2 - INTERVAL '1 day')`,
+		repoID, started.Add(-24*time.Hour), completed.Add(-24*time.Hour)); err != nil {
+		t.Fatalf("insert older scan: %v", err)
+	}
+	var scanID string
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, started_at, completed_at, created_at, ref, commit_sha)
+		VALUES ($1, 'completed', $2, $3, $2, 'refs/heads/main', 'a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2') RETURNING id`,
+		repoID, started, completed).Scan(&scanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+
+	queries := db.NewQueries()
+	queries.SetDB(dbConn)
+	handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries)}
+
+	tests := []struct {
+		name   string
+		repoID string
+		want   map[string]any
+	}{
+		{
+			name:   "latest scan's stored values",
+			repoID: repoID,
+			want: map[string]any{
+				"scan_id":           scanID,
+				"commit_sha":        "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2",
+				"ref":               "refs/heads/main",
+				"scan_started_at":   "2026-02-03T10:00:00Z",
+				"scan_completed_at": "2026-02-03T10:07:00Z",
+			},
+		},
+		{
+			name:   "repository never scanned",
+			repoID: emptyRepoID,
+			want: map[string]any{
+				"scan_id":           "unknown",
+				"commit_sha":        nil,
+				"ref":               nil,
+				"scan_started_at":   nil,
+				"scan_completed_at": nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/repositories/"+tt.repoID+"/vulnerabilities", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", tt.repoID)
+			r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), "userID", userID))
+
+			rec := httptest.NewRecorder()
+			handler.GetVulnerabilities(rec, r)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+			}
+
+			var body map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			for key, value := range tt.want {
+				if got, ok := body[key]; !ok || got != value {
+					t.Errorf("%s = %v, want %v", key, got, value)
+				}
+			}
+		})
+	}
+}
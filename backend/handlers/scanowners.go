@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// allowPublicScanOwner answers 403 and returns false when ALLOWED_SCAN_OWNERS doesn't list the repository's owner
+// It runs on the parsed URL, before any provider API call, so a refused request costs nothing
+func (h *RepositoryHandler) allowPublicScanOwner(w http.ResponseWriter, r *http.Request, owner string) bool {
+	if err := h.scanOwnerError(r.Context(), owner); err != nil {
+		respondError(w, err.Status, err.Code, err.Message)
+		return false
+	}
+	return true
+}
+
+// scanOwnerError returns the 403 a public scan of owner's repository is refused with, or nil when it is allowed
+func (h *RepositoryHandler) scanOwnerError(ctx context.Context, owner string) *scanStartError {
+	if h.ScanOwners.Allows(owner) {
+		return nil
+	}
+	logger.FromContext(ctx).Warn("Repository owner is not on the public scan allowlist", zap.String("owner", owner))
+	return &scanStartError{Status: http.StatusForbidden, Code: "repository_not_allowed",
+		Message: fmt.Sprintf("Repositories owned by %q can't be scanned on this server", owner)}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// lookupRecordingGitHubService records repository lookups and answers that the repository doesn't exist
+type lookupRecordingGitHubService struct {
+	services.GitHubService
+	lookups []string
+}
+
+func (s *lookupRecordingGitHubService) FetchRepositoryInfo(ctx context.Context, owner, repo string) (*services.Repository, error) {
+	s.lookups = append(s.lookups, owner+"/"+repo)
+	return nil, services.ErrRepositoryNotFound
+}
+
+func TestPublicScansAreLimitedToAllowedOwners(t *testing.T) {
+	allowlist := services.NewScanOwnerAllowlist([]string{"acme", "widgets-*"})
+
+	tests := []struct {
+		name       string
+		path       string
+		repoURL    string
+		wantStatus int
+	}{
+		{name: "scan of an allowed owner", path: "/scan", repoURL: "https://github.com/acme/api", wantStatus: http.StatusNotFound},
+		{name: "owner matched case-insensitively", path: "/scan", repoURL: "https://github.com/ACME/api", wantStatus: http.StatusNotFound},
+		{name: "owner matched by wildcard", path: "/scan", repoURL: "https://github.com/widgets-labs/api", wantStatus: http.StatusNotFound},
+		{name: "scan of another owner", path: "/scan", repoURL: "https://github.com/octocat/hello-world", wantStatus: http.StatusForbidden},
+		{name: "preview of an allowed owner", path: "/scan/preview", repoURL: "https://github.com/acme/api", wantStatus: http.StatusNotFound},
+		{name: "preview of another owner", path: "/scan/preview", repoURL: "https://github.com/octocat/hello-world", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			github := &lookupRecordingGitHubService{}
+			handler := &RepositoryHandler{GitHubService: github, ScanOwners: allowlist}
+			serve := handler.ScanPublicRepository
+			if tt.path == "/scan/preview" {
+				serve = handler.PreviewScan
+			}
+
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{"repo_url": "`+tt.repoURL+`"}`))
+			r.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			serve(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				var body map[string]map[string]string
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["error"]["code"] != "repository_not_allowed" {
+					t.Errorf("body = %v, want error code repository_not_allowed", body)
+				}
+				// Refused before GitHub was asked about the repository
+				if len(github.lookups) != 0 {
+					t.Errorf("looked up %v for a refused owner", github.lookups)
+				}
+			} else if len(github.lookups) != 1 {
+				t.Errorf("looked up %v, want the allowed repository once", github.lookups)
+			}
+		})
+	}
+}
+
+func TestPublicScansAreUnrestrictedWithoutAnAllowlist(t *testing.T) {
+	github := &lookupRecordingGitHubService{}
+	handler := &RepositoryHandler{GitHubService: github}
+
+	r := httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(`{"repo_url": "https://github.com/octocat/hello-world"}`))
+	r.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ScanPublicRepository(rec, r)
+
+	if rec.Code == http.StatusForbidden || len(github.lookups) != 1 {
+		t.Errorf("status = %d after %d lookups, want the repository looked up", rec.Code, len(github.lookups))
+	}
+}
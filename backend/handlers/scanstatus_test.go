@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/mocks"
+)
+
+// getScanStatus calls GetScanStatus for the ID and decodes a 200 response
+func getScanStatus(t *testing.T, handler *RepositoryHandler, id string) (int, map[string]any) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/api/scans/"+id+"/status", nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", id)
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+
+	rec := httptest.NewRecorder()
+	handler.GetScanStatus(rec, r)
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return rec.Code, body
+}
+
+func TestGetScanStatusAsksTemporalWithoutARecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		describe    *workflowservice.DescribeWorkflowExecutionResponse
+		describeErr error
+		wantCode    int
+		wantStatus  string
+	}{
+		{name: "running", describe: describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), wantCode: http.StatusOK, wantStatus: services.ScanStatusQueued},
+		{name: "completed", describe: describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_COMPLETED), wantCode: http.StatusOK, wantStatus: services.ScanStatusCompleted},
+		{name: "failed", describe: describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_FAILED), wantCode: http.StatusOK, wantStatus: services.ScanStatusFailed},
+		{name: "temporal unavailable", describeErr: serviceerror.NewUnavailable("down"), wantCode: http.StatusInternalServerError},
+	}
+
+	// Without a database there is no record, so every answer comes from the workflow
+	db.SetGlobalDB(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			temporalClient := &mocks.Client{}
+			temporalClient.On("DescribeWorkflowExecution", mock.Anything, "scan-workflow-scan-1", "").Return(tt.describe, tt.describeErr)
+
+			code, body := getScanStatus(t, &RepositoryHandler{TemporalClient: temporalClient}, "scan-1")
+			if code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d", code, tt.wantCode)
+			}
+			if code == http.StatusOK && (body["status"] != tt.wantStatus || body["scan_id"] != "scan-1" || body["results_available"] != false) {
+				t.Errorf("response = %v, want status %s", body, tt.wantStatus)
+			}
+			temporalClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetScanStatusServesFinishedScansFromTheDatabase(t *testing.T) {
+	dbConn := testdb.Open(t)
+	db.SetGlobalDB(dbConn)
+	t.Cleanup(func() { db.SetGlobalDB(nil) })
+	_, repoID := createTestRepository(t, dbConn)
+
+	tests := []struct {
+		dbStatus     string
+		wantTemporal bool
+		wantStatus   string
+	}{
+		{dbStatus: services.ScanStatusCompleted, wantStatus: services.ScanStatusCompleted},
+		{dbStatus: services.ScanStatusFailed, wantStatus: services.ScanStatusFailed},
+		{dbStatus: services.ScanStatusCanceled, wantStatus: services.ScanStatusCanceled},
+		{dbStatus: services.ScanStatusTimeBudgetReached, wantStatus: services.ScanStatusTimeBudgetReached},
+		{dbStatus: services.ScanStatusScanning, wantTemporal: true, wantStatus: services.ScanStatusScanning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dbStatus, func(t *testing.T) {
+			var scanID string
+			if err := dbConn.QueryRowContext(context.Background(),
+				`INSERT INTO scans (repository_id, status, results_available) VALUES ($1, $2, $3) RETURNING id`,
+				repoID, tt.dbStatus, tt.dbStatus == services.ScanStatusCompleted).Scan(&scanID); err != nil {
+				t.Fatalf("insert scan: %v", err)
+			}
+
+			// Temporal is down: only a scan that is still running needs it
+			temporalClient := &mocks.Client{}
+			temporalClient.On("DescribeWorkflowExecution", mock.Anything, mock.Anything, mock.Anything).
+				Return(describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), nil)
+
+			code, body := getScanStatus(t, &RepositoryHandler{TemporalClient: temporalClient}, scanID)
+			if code != http.StatusOK {
+				t.Fatalf("status code = %d, want 200", code)
+			}
+			if body["status"] != tt.wantStatus || body["results_available"] != (tt.dbStatus == services.ScanStatusCompleted) {
+				t.Errorf("response = %v, want status %s", body, tt.wantStatus)
+			}
+			if asked := len(temporalClient.Calls) > 0; asked != tt.wantTemporal {
+				t.Errorf("asked Temporal = %v, want %v", asked, tt.wantTemporal)
+			}
+		})
+	}
+}
+
+func TestGetScanStatusReportsSavedProgress(t *testing.T) {
+	dbConn := testdb.Open(t)
+	db.SetGlobalDB(dbConn)
+	t.Cleanup(func() { db.SetGlobalDB(nil) })
+	_, repoID := createTestRepository(t, dbConn)
+	ctx := context.Background()
+
+	var scanID string
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status) VALUES ($1, $2) RETURNING id`,
+		repoID, services.ScanStatusScanning).Scan(&scanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+	temporalClient := &mocks.Client{}
+	temporalClient.On("DescribeWorkflowExecution", mock.Anything, mock.Anything, mock.Anything).
+		Return(describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), nil)
+	handler := &RepositoryHandler{TemporalClient: temporalClient}
+
+	// Until the scan activity's first heartbeat there is nothing to report
+	if _, body := getScanStatus(t, handler, scanID); body["progress"] != nil {
+		t.Errorf("progress = %v before any was saved, want none", body["progress"])
+	}
+
+	progress := services.ScanProgress{Scanned: 7, Total: 20, CurrentFile: "routes/login.js"}
+	if err := services.NewScanProgressService(db.NewQueries()).SaveProgress(ctx, scanID, progress); err != nil {
+		t.Fatalf("SaveProgress returned error: %v", err)
+	}
+	_, body := getScanStatus(t, handler, scanID)
+	got, _ := body["progress"].(map[string]any)
+	if got["scanned"] != 7.0 || got["total"] != 20.0 || got["current_file"] != "routes/login.js" {
+		t.Errorf("progress = %v, want 7 of 20 at routes/login.js", body["progress"])
+	}
+}
+
+func TestGetScanStatusFindsScansUnderTheLegacyWorkflowID(t *testing.T) {
+	dbConn := testdb.Open(t)
+	db.SetGlobalDB(dbConn)
+	t.Cleanup(func() { db.SetGlobalDB(nil) })
+	_, repoID := createTestRepository(t, dbConn)
+
+	var scanID string
+	if err := dbConn.QueryRowContext(context.Background(),
+		`INSERT INTO scans (repository_id, status) VALUES ($1, $2) RETURNING id`,
+		repoID, services.ScanStatusScanning).Scan(&scanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+
+	// The scan started before each scan had its own workflow, so it runs under its repository's ID
+	temporalClient := &mocks.Client{}
+	temporalClient.On("DescribeWorkflowExecution", mock.Anything, scanWorkflowID(scanID), "").
+		Return(nil, serviceerror.NewNotFound("workflow not found"))
+	temporalClient.On("DescribeWorkflowExecution", mock.Anything, legacyScanWorkflowID(repoID), "").
+		Return(describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), nil)
+	handler := &RepositoryHandler{TemporalClient: temporalClient}
+
+	for _, id := range []string{scanID, repoID} {
+		code, body := getScanStatus(t, handler, id)
+		if code != http.StatusOK || body["status"] != services.ScanStatusScanning {
+			t.Errorf("status of %s = %d %v, want 200 scanning", id, code, body)
+		}
+	}
+
+	// An ID that isn't a UUID matches no record rather than failing the lookup
+	temporalClient.On("DescribeWorkflowExecution", mock.Anything, scanWorkflowID("not-a-uuid"), "").
+		Return(describedWorkflow(enums.WORKFLOW_EXECUTION_STATUS_COMPLETED), nil)
+	if code, body := getScanStatus(t, handler, "not-a-uuid"); code != http.StatusOK || body["status"] != services.ScanStatusCompleted {
+		t.Errorf("status of a non-UUID ID = %d %v, want 200 completed from its workflow", code, body)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// GetScanVulnerabilities returns the findings stored for one scan, even after newer scans have run
+// Unlike /scan/{id}/results the ID must be a scan ID, so older scans of a repository stay reachable
+// and two scans can be compared side by side
+func (h *RepositoryHandler) GetScanVulnerabilities(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	scanID := chi.URLParam(r, "id")
+	if scanID == "" {
+		respondError(w, http.StatusBadRequest, "scan_id_required", "Scan ID is required")
+		return
+	}
+
+	groupBy, err := findingsGrouping(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	// ?min_confidence= hides findings the model was unsure of
+	minimumConfidence, err := minConfidence(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return
+	}
+
+	meta, err := latestScanMetadata(r.Context(), dbConn, scanID)
+	if err == nil && meta.ID != scanID {
+		// The ID matched a repository rather than a scan
+		err = sql.ErrNoRows
+	}
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to look up scan", zap.String("scan_id", scanID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	repoID, err := scanRepositoryID(r.Context(), dbConn, scanID)
+	if err != nil {
+		log.Error("Failed to look up scan repository", zap.String("scan_id", scanID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	allowed, err := userCanAccessRepository(r.Context(), dbConn, userID, repoID)
+	if err == nil && !allowed {
+		allowed, err = userIsAdmin(r.Context(), dbConn, userID)
+	}
+	if err != nil {
+		log.Error("Error checking scan access", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Error checking repository access")
+		return
+	}
+	if !allowed {
+		log.Warn("User attempted to view findings of an unauthorized scan",
+			zap.String("user_id", userID),
+			zap.String("scan_id", scanID))
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return
+	}
+
+	vulnerabilities, err := h.GitHubService.GetVulnerabilitiesByScanID(r.Context(), scanID)
+	if errors.Is(err, services.ErrScanNotFound) {
+		respondError(w, http.StatusNotFound, "scan_not_found", "Scan not found")
+		return
+	}
+	if err != nil {
+		log.Error("Failed to get scan vulnerabilities", zap.String("scan_id", scanID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get scan results")
+		return
+	}
+
+	// Hide findings in excluded paths or accepted in the baseline unless the caller asks for them
+	vulnerabilities, excludedCount := filterExcludedFindings(vulnerabilities, includeExcludedFindings(r))
+	vulnerabilities, baselinedCount := filterBaselinedFindings(vulnerabilities, includeBaselinedFindings(r))
+	vulnerabilities, lowConfidenceCount := filterLowConfidenceFindings(vulnerabilities, minimumConfidence)
+
+	response := map[string]any{
+		"scan_id":               scanID,
+		"repository_id":         repoID,
+		"status":                services.NormalizeScanStatus(meta.Status),
+		"vulnerabilities_count": countReported(vulnerabilities),
+		"excluded_count":        excludedCount,
+		"baselined_count":       baselinedCount,
+		"low_confidence_count":  lowConfidenceCount,
+	}
+	meta.addTo(response)
+
+	if groupBy == groupByFile {
+		response["vulnerabilities_by_file"] = services.GroupFindingsByFile(vulnerabilities)
+	} else {
+		response["vulnerabilities_by_category"] = groupFindingsByCategory(vulnerabilities)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
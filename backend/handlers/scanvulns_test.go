@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+func TestGroupFindingsByCategory(t *testing.T) {
+	grouped := groupFindingsByCategory([]*services.Vulnerability{
+		{ID: "1", Type: services.Injection},
+		{ID: "2"},
+		{ID: "3", Type: services.Injection},
+	})
+
+	if len(grouped) != 2 || len(grouped[string(services.Injection)]) != 2 || len(grouped["Unknown"]) != 1 {
+		t.Errorf("grouped = %v, want two injections and one unknown", grouped)
+	}
+}
+
+func TestGetScanVulnerabilities(t *testing.T) {
+	dbConn := testdb.Open(t)
+	ctx := context.Background()
+	userID, repoID := createTestRepository(t, dbConn)
+	otherUserID, _ := createTestRepository(t, dbConn)
+
+	var findingsScanID, emptyScanID string
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, created_at) VALUES ($1, 'completed', NOW() - INTERVAL '1 day') RETURNING id`,
+		repoID).Scan(&findingsScanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+	if _, err := dbConn.ExecContext(ctx,
+		`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description)
+		VALUES ($1, 'Injection', 'main.go', 3, 3, 'High', 'finding')`, findingsScanID); err != nil {
+		t.Fatalf("insert finding: %v", err)
+	}
+	if err := dbConn.QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status) VALUES ($1, 'completed') RETURNING id`,
+		repoID).Scan(&emptyScanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+
+	queries := db.NewQueries()
+	queries.SetDB(dbConn)
+	handler := &RepositoryHandler{GitHubService: services.NewGitHubService(queries)}
+
+	tests := []struct {
+		name       string
+		userID     string
+		scanID     string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "older scan with findings", userID: userID, scanID: findingsScanID, wantStatus: http.StatusOK, wantCount: 1},
+		{name: "latest scan without findings", userID: userID, scanID: emptyScanID, wantStatus: http.StatusOK},
+		{name: "unknown scan", userID: userID, scanID: "00000000-0000-0000-0000-000000000000", wantStatus: http.StatusNotFound},
+		{name: "repository ID instead of a scan ID", userID: userID, scanID: repoID, wantStatus: http.StatusNotFound},
+		{name: "another user's scan", userID: otherUserID, scanID: findingsScanID, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/scan/"+tt.scanID+"/vulnerabilities", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", tt.scanID)
+			r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), "userID", tt.userID))
+
+			rec := httptest.NewRecorder()
+			handler.GetScanVulnerabilities(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				ScanID     string                      `json:"scan_id"`
+				Count      int                         `json:"vulnerabilities_count"`
+				ByCategory map[string][]map[string]any `json:"vulnerabilities_by_category"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if body.ScanID != tt.scanID || body.Count != tt.wantCount || len(body.ByCategory["Injection"]) != tt.wantCount {
+				t.Errorf("response = %+v, want %d findings of scan %s", body, tt.wantCount, tt.scanID)
+			}
+		})
+	}
+}
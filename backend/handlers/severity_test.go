@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestScanPublicRepositoryRejectsUnknownSeverityThreshold(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/scan?severity_threshold=urgent",
+		strings.NewReader(`{"repo_url": "https://github.com/octocat/hello-world"}`))
+	r.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	(&RepositoryHandler{}).ScanPublicRepository(rec, r)
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "severity_threshold") {
+		t.Errorf("status = %d, body %s, want a 422 naming severity_threshold", rec.Code, rec.Body.String())
+	}
+}
+
+func TestScanPublicRepositoryRejectsUnsupportedFileExtensions(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/scan",
+		strings.NewReader(`{"repo_url": "https://github.com/octocat/hello-world", "file_extensions": ["go", ".exe"]}`))
+	r.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	(&RepositoryHandler{}).ScanPublicRepository(rec, r)
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "file_extensions[1]") ||
+		strings.Contains(rec.Body.String(), "file_extensions[0]") {
+		t.Errorf("status = %d, body %s, want a 422 naming only file_extensions[1]", rec.Code, rec.Body.String())
+	}
+}
+
+func TestScanPublicRepositoryValidatesTheCallbackURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		secret      string
+		callbackURL string
+		wantMessage string
+	}{
+		{name: "callbacks not configured", callbackURL: "https://93.184.216.34/hook", wantMessage: "not configured"},
+		{name: "plain http", secret: "callback-secret", callbackURL: "http://93.184.216.34/hook", wantMessage: "https"},
+		{name: "private address", secret: "callback-secret", callbackURL: "https://10.0.0.5/hook", wantMessage: "not a public address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCAN_CALLBACK_SECRET", tt.secret)
+			r := httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(
+				`{"repo_url": "https://github.com/octocat/hello-world", "callback_url": "`+tt.callbackURL+`"}`))
+			r.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			(&RepositoryHandler{}).ScanPublicRepository(rec, r)
+
+			body := rec.Body.String()
+			if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(body, "callback_url") || !strings.Contains(body, tt.wantMessage) {
+				t.Errorf("status = %d, body %s, want a 422 for callback_url mentioning %q", rec.Code, body, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestGetScanResultsRejectsUnknownFailOn(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/scan/scan-1/results?fail_on=blocker", nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", "scan-1")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+
+	rec := httptest.NewRecorder()
+	(&RepositoryHandler{}).GetScanResults(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestPreviewScanRejectsInvalidOptions(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/scan/preview", strings.NewReader(
+		`{"repo_url": "https://github.com/octocat/hello-world", "file_extensions": [".exe"], "incremental_since": "abc1234"}`))
+	r.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	(&RepositoryHandler{}).PreviewScan(rec, r)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(body, "file_extensions[0]") || !strings.Contains(body, "incremental_since") {
+		t.Errorf("status = %d, body %s, want a 422 naming file_extensions[0] and incremental_since", rec.Code, body)
+	}
+}
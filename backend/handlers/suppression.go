@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// CreateSuppressionRequest marks a false positive, either by finding ID or by location
+type CreateSuppressionRequest struct {
+	VulnerabilityID   string `json:"vulnerability_id"`   // Suppress the location of this stored finding
+	FilePath          string `json:"file_path"`          // Otherwise the location: file path,
+	LineStart         int    `json:"line_start"`         // start line,
+	VulnerabilityType string `json:"vulnerability_type"` // and vulnerability type of the findings to hide
+	Reason            string `json:"reason"`             // Why the finding is a false positive
+}
+
+// CreateSuppression hides findings at a location from every scan of the repository
+func (h *RepositoryHandler) CreateSuppression(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+	userID, _ := r.Context().Value("userID").(string)
+
+	var req CreateSuppressionRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	suppressionService := services.NewSuppressionService(db.NewQueries())
+	var suppression *services.Suppression
+	var err error
+	if req.VulnerabilityID != "" {
+		suppression, err = suppressionService.SuppressFinding(r.Context(), repoID, userID, req.VulnerabilityID, req.Reason)
+		if errors.Is(err, services.ErrFindingNotInRepository) {
+			respondError(w, http.StatusNotFound, "finding_not_found", "Finding not found for this repository")
+			return
+		}
+	} else {
+		location := services.Suppression{
+			FilePath:          req.FilePath,
+			LineStart:         req.LineStart,
+			VulnerabilityType: req.VulnerabilityType,
+			Reason:            req.Reason,
+		}
+		if err := services.ValidateSuppression(location); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid_suppression", err.Error())
+			return
+		}
+		suppression, err = suppressionService.AddSuppression(r.Context(), repoID, userID, location)
+	}
+	if err != nil {
+		log.Error("Failed to suppress finding", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to suppress finding")
+		return
+	}
+
+	log.Info("Suppressed finding",
+		zap.String("repo_id", repoID),
+		zap.String("suppression_id", suppression.ID),
+		zap.String("file", suppression.FilePath),
+		zap.Int("line", suppression.LineStart),
+		zap.String("type", suppression.VulnerabilityType))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(suppression)
+}
+
+// ListSuppressions returns the repository's suppressed finding locations
+func (h *RepositoryHandler) ListSuppressions(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	suppressions, err := services.NewSuppressionService(db.NewQueries()).ListSuppressions(r.Context(), repoID)
+	if err != nil {
+		log.Error("Failed to list suppressions", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list suppressions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"repository_id": repoID,
+		"suppressions":  suppressions,
+	})
+}
+
+// DeleteSuppression reports a suppressed location's findings again
+func (h *RepositoryHandler) DeleteSuppression(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	repoID := chi.URLParam(r, "id")
+	suppressionID := chi.URLParam(r, "suppressionID")
+
+	if !h.authorizeRepository(w, r, repoID) {
+		return
+	}
+
+	deleted, err := services.NewSuppressionService(db.NewQueries()).RemoveSuppression(r.Context(), repoID, suppressionID)
+	if err != nil {
+		log.Error("Failed to delete suppression", zap.String("suppression_id", suppressionID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to delete suppression")
+		return
+	}
+	if !deleted {
+		respondError(w, http.StatusNotFound, "suppression_not_found", "Suppression not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/temporal"
+	"go.uber.org/zap"
+)
+
+// defaultMaxUploadBytes bounds uploaded archives when SCAN_UPLOAD_MAX_BYTES is unset
+const defaultMaxUploadBytes = 50 << 20
+
+// uploadFormField is the multipart form field carrying the archive
+const uploadFormField = "file"
+
+// errMissingUpload is returned when a multipart request has no archive in its file field
+var errMissingUpload = errors.New(`the archive must be sent in the "file" form field`)
+
+// maxUploadBytes returns the largest upload request accepted, read from SCAN_UPLOAD_MAX_BYTES
+func maxUploadBytes() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("SCAN_UPLOAD_MAX_BYTES"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return defaultMaxUploadBytes
+}
+
+// uploadArchiveLimits returns how far an uploaded archive may expand, with the total size read from
+// SCAN_UPLOAD_MAX_EXTRACTED_BYTES
+func uploadArchiveLimits() services.ArchiveLimits {
+	var limits services.ArchiveLimits
+	if value, err := strconv.ParseInt(os.Getenv("SCAN_UPLOAD_MAX_EXTRACTED_BYTES"), 10, 64); err == nil && value > 0 {
+		limits.MaxExtractedBytes = value
+	}
+	return limits
+}
+
+// uploadName derives the repository name of an upload from the archive's file name, e.g. "shop" for "shop.tar.gz"
+func uploadName(filename string) string {
+	name := filepath.Base(strings.ReplaceAll(filename, `\`, "/"))
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			name = name[:len(name)-len(ext)]
+			break
+		}
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "upload"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// saveUpload streams the archive in the request's file field to a temporary file
+// The form is read part by part rather than with ParseMultipartForm, so the archive is never held in memory.
+// It returns the temporary file's path, which the caller removes, and the archive's file name
+func saveUpload(r *http.Request) (archivePath, filename string, err error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return "", "", errMissingUpload
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if part.FormName() != uploadFormField {
+			part.Close()
+			continue
+		}
+
+		file, err := os.CreateTemp("", "scan-upload-*")
+		if err != nil {
+			part.Close()
+			return "", "", err
+		}
+		_, err = io.Copy(file, part)
+		part.Close()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(file.Name())
+			return "", "", err
+		}
+		return file.Name(), part.FileName(), nil
+	}
+}
+
+// storeUploadRepository creates the repository an upload is scanned under, or reuses the one from an
+// earlier upload of the same name by the same user, so repeated uploads build up one scan history
+func storeUploadRepository(ctx context.Context, dbConn *sql.DB, userID, name string) (string, error) {
+	repoID := uuid.NewSHA1(uuid.NameSpaceURL, []byte("upload://"+userID+"/"+name)).String()
+	_, err := dbConn.ExecContext(ctx,
+		`INSERT INTO repositories (id, owner, name, url, clone_url, created_by, provider)
+		VALUES ($1, $2, $3, $4, '', $5, $6)
+		ON CONFLICT (id) DO UPDATE SET updated_at = NOW()`,
+		repoID, userID, name, "upload://"+name, userID, services.ProviderUpload)
+	if err != nil {
+		return "", fmt.Errorf("failed to store repository: %w", err)
+	}
+	_, err = dbConn.ExecContext(ctx,
+		`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, repository_id) DO NOTHING`,
+		userID, repoID)
+	if err != nil {
+		return "", fmt.Errorf("failed to associate repository with user: %w", err)
+	}
+	return repoID, nil
+}
+
+// ScanUpload handles POST /scan/upload: it scans the source tree in an uploaded zip or tar.gz archive
+// The archive, sent as multipart form field "file", is extracted and scanned in place of a clone; the
+// response carries a scan record ID to poll like any other scan. The extracted files are removed when
+// the scan ends
+func (h *RepositoryHandler) ScanUpload(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "multipart/form-data" {
+		respondError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be multipart/form-data")
+		return
+	}
+
+	limit := maxUploadBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	archivePath, filename, err := saveUpload(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			respondError(w, http.StatusRequestEntityTooLarge, "upload_too_large", fmt.Sprintf("Upload too large: the limit is %d bytes", limit))
+		case errors.Is(err, errMissingUpload):
+			respondError(w, http.StatusBadRequest, "missing_field", "The archive must be sent in the \"file\" form field")
+		default:
+			log.Warn("Failed to read uploaded archive", zap.Error(err))
+			respondError(w, http.StatusBadRequest, "invalid_request", "Failed to read the upload: "+err.Error())
+		}
+		return
+	}
+	defer os.Remove(archivePath)
+
+	// Each scan gets its own extraction directory, removed by the workflow once the scan ends
+	scanID := uuid.New().String()
+	uploadDir := filepath.Join(services.UploadsDir(), scanID)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		log.Error("Failed to create upload directory", zap.String("upload_dir", uploadDir), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to store the upload")
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			os.RemoveAll(uploadDir)
+		}
+	}()
+
+	if err := services.ExtractArchive(archivePath, uploadDir, uploadArchiveLimits()); err != nil {
+		switch {
+		case errors.Is(err, services.ErrArchiveTooLarge):
+			respondError(w, http.StatusRequestEntityTooLarge, "archive_too_large", err.Error())
+		case errors.Is(err, services.ErrUnsafeArchivePath), errors.Is(err, services.ErrUnsupportedArchive):
+			log.Warn("Rejected uploaded archive", zap.String("filename", filename), zap.Error(err))
+			respondError(w, http.StatusBadRequest, "invalid_archive", err.Error())
+		default:
+			log.Error("Failed to extract uploaded archive", zap.String("filename", filename), zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "internal_error", "Failed to extract the archive")
+		}
+		return
+	}
+
+	if err := h.checkTemporalAvailable(r.Context()); err != nil {
+		log.Error("Temporal is unavailable, not starting scan", zap.Error(err))
+		writeScanServiceUnavailable(w)
+		return
+	}
+
+	dbConn := h.GitHubService.GetDatabaseConnection()
+	if dbConn == nil {
+		log.Error("Database connection is unavailable")
+		respondError(w, http.StatusInternalServerError, "database_unavailable", "Database connection unavailable")
+		return
+	}
+
+	name := uploadName(filename)
+	repoID, err := storeUploadRepository(r.Context(), dbConn, userID, name)
+	if err != nil {
+		log.Error("Failed to store uploaded repository", zap.String("name", name), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to store the repository")
+		return
+	}
+
+	model := baml.ConfiguredModel()
+	_, err = dbConn.ExecContext(r.Context(),
+		`INSERT INTO scans (id, repository_id, status, started_at, created_by, model)
+		VALUES ($1, $2, $3, NOW(), $4, $5)`,
+		scanID, repoID, services.ScanStatusQueued, userID, model)
+	if err != nil {
+		log.Error("Failed to create scan record", zap.String("repo_id", repoID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to create scan record")
+		return
+	}
+
+	workflowInput := temporal.ScanWorkflowInput{
+		RepositoryID:   repoID,
+		ScanID:         scanID,
+		Owner:          userID,
+		Name:           name,
+		UploadDir:      uploadDir,
+		VulnTypes:      owaspTop10VulnTypes,
+		FileExtensions: services.DefaultFileExtensions(),
+		Model:          model,
+		MaxFiles:       temporal.ScanMaxFiles(),
+		Budget:         services.ScanBudgetFromEnv(),
+		Timeouts:       temporal.ResolveScanTimeouts(0), // Nothing to clone, so only the scan timeouts matter
+	}
+
+	we, err := h.TemporalClient.ExecuteWorkflow(context.Background(), scanWorkflowOptions(scanID), temporal.ScanWorkflow, workflowInput)
+	if err != nil {
+		log.Error("Failed to start scan workflow", zap.String("repo_id", repoID), zap.Error(err))
+		discardQueuedScan(r.Context(), dbConn, scanID)
+		if isTemporalUnavailable(err) {
+			writeScanServiceUnavailable(w)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "scan_start_failed", fmt.Sprintf("Failed to start scan workflow: %v", err))
+		return
+	}
+	started = true
+
+	log.Info("Upload scan workflow initiated",
+		zap.String("repo_id", repoID),
+		zap.String("scan_id", scanID),
+		zap.String("run_id", we.GetRunID()))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":             repoID,
+		"scan_record_id": scanID,
+		"status":         "scan_initiated",
+		"run_id":         we.GetRunID(),
+		"model":          model,
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// newUploadRequest returns a POST /scan/upload request from user-1 carrying a zip of files in field
+func newUploadRequest(t *testing.T, field string, files map[string]string) *http.Request {
+	t.Helper()
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile(field, "shop.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(archive.Bytes())
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/scan/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r.WithContext(context.WithValue(r.Context(), "userID", "user-1"))
+}
+
+func TestScanUploadRejectsBadUploads(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    func(t *testing.T) *http.Request
+		maxBytes   string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name: "zip slip",
+			request: func(t *testing.T) *http.Request {
+				return newUploadRequest(t, "file", map[string]string{"main.go": "package main", "../../evil.go": "package evil"})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_archive",
+		},
+		{
+			name: "absolute path",
+			request: func(t *testing.T) *http.Request {
+				return newUploadRequest(t, "file", map[string]string{"/etc/cron.d/evil": "* * * * * root true"})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_archive",
+		},
+		{
+			name: "archive in another field",
+			request: func(t *testing.T) *http.Request {
+				return newUploadRequest(t, "archive", map[string]string{"main.go": "package main"})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "missing_field",
+		},
+		{
+			name: "upload over the limit",
+			request: func(t *testing.T) *http.Request {
+				return newUploadRequest(t, "file", map[string]string{"main.go": "package main"})
+			},
+			maxBytes:   "64",
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "upload_too_large",
+		},
+		{
+			name: "not multipart",
+			request: func(t *testing.T) *http.Request {
+				r := newUploadRequest(t, "file", map[string]string{"main.go": "package main"})
+				r.Header.Set("Content-Type", "application/zip")
+				return r
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantCode:   "unsupported_media_type",
+		},
+		{
+			name: "without a user",
+			request: func(t *testing.T) *http.Request {
+				return newUploadRequest(t, "file", map[string]string{"main.go": "package main"}).WithContext(context.Background())
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Keep extraction inside the test's own directory so leftovers can be checked
+			t.Setenv("TMPDIR", t.TempDir())
+			t.Setenv("SCAN_UPLOAD_MAX_BYTES", tt.maxBytes)
+
+			rec := httptest.NewRecorder()
+			(&RepositoryHandler{}).ScanUpload(rec, tt.request(t))
+
+			if got := decodeErrorResponse(t, rec, tt.wantStatus); got.Code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", got.Code, tt.wantCode)
+			}
+			entries, _ := os.ReadDir(services.UploadsDir())
+			if len(entries) != 0 {
+				t.Errorf("rejected upload left %d extraction directories behind", len(entries))
+			}
+		})
+	}
+}
+
+func TestUploadName(t *testing.T) {
+	tests := map[string]string{
+		"shop.zip":             "shop",
+		"shop.TAR.GZ":          "shop",
+		"shop-1.2.tgz":         "shop-1.2",
+		`C:\Users\dev\app.zip`: "app",
+		"../../etc/passwd.zip": "passwd",
+		"":                     "upload",
+		".zip":                 "upload",
+	}
+	for filename, want := range tests {
+		if got := uploadName(filename); got != want {
+			t.Errorf("uploadName(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
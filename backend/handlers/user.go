@@ -8,6 +8,7 @@ import (
 
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
 	"go.uber.org/zap"
 )
 
@@ -33,7 +34,7 @@ func HandleGetUserProfile(w http.ResponseWriter, r *http.Request, dbQueries *db.
 	userID, ok := r.Context().Value("userID").(string)
 	if !ok {
 		log.Warn("User ID not found in context")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 		return
 	}
 
@@ -41,7 +42,7 @@ func HandleGetUserProfile(w http.ResponseWriter, r *http.Request, dbQueries *db.
 	// The dbQueries parameter is expected to contain an initialized database connection
 	if dbQueries == nil || dbQueries.GetDB() == nil {
 		log.Error("Database connection not initialized")
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 		return
 	}
 
@@ -61,13 +62,13 @@ func HandleGetUserProfile(w http.ResponseWriter, r *http.Request, dbQueries *db.
 		if err == sql.ErrNoRows {
 			// No user found with the provided ID
 			log.Warn("User not found", zap.String("user_id", userID))
-			http.Error(w, "User not found", http.StatusNotFound)
+			respondError(w, http.StatusNotFound, "user_not_found", "User not found")
 			return
 		}
 
 		// Other database errors
 		log.Error("Database error", zap.String("user_id", userID), zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
 		return
 	}
 
@@ -77,3 +78,74 @@ func HandleGetUserProfile(w http.ResponseWriter, r *http.Request, dbQueries *db.
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
 }
+
+// NotificationPreferences controls which scan emails a user receives
+type NotificationPreferences struct {
+	ReceiveNotifications  bool   `json:"receive_notifications"`  // Whether scan emails are sent at all
+	NotificationFrequency string `json:"notification_frequency"` // immediate, daily, or weekly
+}
+
+// HandleUpdateNotificationPreferences updates how the authenticated user is emailed about finished scans
+// Fields left out of the body keep their current value; the updated preferences are returned
+func HandleUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request, dbQueries *db.Queries) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	var req struct {
+		ReceiveNotifications  *bool   `json:"receive_notifications"`
+		NotificationFrequency *string `json:"notification_frequency"`
+	}
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.NotificationFrequency != nil && !services.ValidNotificationFrequency(*req.NotificationFrequency) {
+		respondError(w, http.StatusBadRequest, "invalid_parameter", "notification_frequency must be immediate, daily, or weekly")
+		return
+	}
+
+	if dbQueries == nil || dbQueries.GetDB() == nil {
+		log.Error("Database connection not initialized")
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	// Switching to a digest starts its period now, so the first digest doesn't repeat emails already sent
+	var prefs NotificationPreferences
+	err := dbQueries.GetDB().QueryRowContext(
+		r.Context(),
+		`UPDATE users SET
+			receive_notifications = COALESCE($2, receive_notifications),
+			last_digest_sent_at = CASE
+				WHEN $3::text IS NOT NULL AND $3 <> notification_frequency THEN NOW()
+				ELSE last_digest_sent_at
+			END,
+			notification_frequency = COALESCE($3, notification_frequency),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING receive_notifications, notification_frequency`,
+		userID, req.ReceiveNotifications, req.NotificationFrequency,
+	).Scan(&prefs.ReceiveNotifications, &prefs.NotificationFrequency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "user_not_found", "User not found")
+			return
+		}
+		log.Error("Failed to update notification preferences", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "database_error", "Database error")
+		return
+	}
+
+	log.Info("Notification preferences updated",
+		zap.String("user_id", userID),
+		zap.Bool("receive_notifications", prefs.ReceiveNotifications),
+		zap.String("notification_frequency", prefs.NotificationFrequency))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(prefs)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+)
+
+// newPreferencesRequest returns a PUT /api/users/me/notifications request, from the user when one is given
+func newPreferencesRequest(userID, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/api/users/me/notifications", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		r = r.WithContext(context.WithValue(r.Context(), "userID", userID))
+	}
+	return r
+}
+
+func TestUpdateNotificationPreferencesRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		body       string
+		wantStatus int
+	}{
+		{name: "not signed in", body: `{"notification_frequency": "daily"}`, wantStatus: http.StatusUnauthorized},
+		{name: "unknown frequency", userID: "user-1", body: `{"notification_frequency": "hourly"}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed body", userID: "user-1", body: `{"receive_notifications":`, wantStatus: http.StatusBadRequest},
+	}
+
+	// Each request is refused before the handler touches the database
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			HandleUpdateNotificationPreferences(rec, newPreferencesRequest(tt.userID, tt.body), nil)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	dbConn := testdb.Open(t)
+	userID, _ := createTestRepository(t, dbConn)
+	queries := db.NewQueries()
+	queries.SetDB(dbConn)
+
+	update := func(body string) NotificationPreferences {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		HandleUpdateNotificationPreferences(rec, newPreferencesRequest(userID, body), queries)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+		}
+		var prefs NotificationPreferences
+		if err := json.NewDecoder(rec.Body).Decode(&prefs); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return prefs
+	}
+
+	if prefs := update(`{"notification_frequency": "weekly"}`); !prefs.ReceiveNotifications || prefs.NotificationFrequency != "weekly" {
+		t.Errorf("preferences = %+v, want weekly digests", prefs)
+	}
+	// Switching to a digest starts its period, so the first one doesn't repeat earlier emails
+	var lastSentSet bool
+	if err := dbConn.QueryRow(`SELECT last_digest_sent_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&lastSentSet); err != nil || !lastSentSet {
+		t.Errorf("last_digest_sent_at set = %v, %v, want it set on switching", lastSentSet, err)
+	}
+
+	// Fields left out keep their value
+	if prefs := update(`{"receive_notifications": false}`); prefs.ReceiveNotifications || prefs.NotificationFrequency != "weekly" {
+		t.Errorf("preferences = %+v, want notifications off and the frequency kept", prefs)
+	}
+}
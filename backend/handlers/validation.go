@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+)
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the field, with an index for list entries, e.g. "vuln_types[1]"
+	Message string `json:"message"` // What is wrong with it
+}
+
+// maxIncludeGlobs caps how many globs one scan request may carry in each glob list
+const maxIncludeGlobs = 50
+
+// validModelName limits model names to the characters OpenAI model IDs use
+var validModelName = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,100}$`)
+
+// ValidateScanRequest checks every option of a scan request and reports all problems at once
+// An empty result means the request is valid; empty fields are valid and fall back to defaults
+func ValidateScanRequest(req *ScanRepositoryRequest) []FieldError {
+	var errs []FieldError
+
+	if req.Model != "" {
+		if !validModelName.MatchString(req.Model) {
+			errs = append(errs, FieldError{Field: "model", Message: "must be 1-100 letters, digits, or . _ : -"})
+		} else if !modelAllowed(req.Model) {
+			errs = append(errs, FieldError{Field: "model", Message: fmt.Sprintf("model %q is not allowed", req.Model)})
+		}
+	}
+
+	// Vulnerability types must come from the selected taxonomy; an unknown taxonomy has none to check against
+	taxonomy, ok := services.LookupTaxonomy(req.Taxonomy)
+	if !ok {
+		errs = append(errs, FieldError{
+			Field:   "taxonomy",
+			Message: fmt.Sprintf("unknown taxonomy %q; must be one of %s", req.Taxonomy, strings.Join(services.TaxonomyNames(), ", ")),
+		})
+	} else {
+		for i, vulnType := range req.VulnTypes {
+			if !taxonomy.HasCategory(vulnType) {
+				errs = append(errs, FieldError{
+					Field:   fmt.Sprintf("vuln_types[%d]", i),
+					Message: fmt.Sprintf("unknown vulnerability type %q in taxonomy %s", vulnType, taxonomy.Name),
+				})
+			}
+		}
+	}
+
+	errs = append(errs, validateFileExtensions(req.FileExtensions)...)
+
+	if req.MinSeverity != "" && services.SeverityRank(req.MinSeverity) == 0 {
+		errs = append(errs, FieldError{Field: "min_severity", Message: "must be one of low, medium, high, critical"})
+	}
+
+	if req.Ref != "" && !validGitRef(strings.TrimSpace(req.Ref)) {
+		errs = append(errs, FieldError{Field: "ref", Message: "must be a branch, tag, or commit SHA"})
+	}
+
+	if req.IncrementalSince != "" && !services.IsCommitRef(strings.TrimSpace(req.IncrementalSince)) {
+		errs = append(errs, FieldError{Field: "incremental_since", Message: "must be a commit SHA"})
+	}
+
+	errs = append(errs, validateGlobList("include_globs", req.IncludeGlobs)...)
+	errs = append(errs, validateGlobList("exclude_patterns", req.ExcludePatterns)...)
+	errs = append(errs, validateGlobList("include_patterns", req.IncludePatterns)...)
+
+	for i, dir := range req.ScanSkippedDirs {
+		if !services.IsOverridableSkipDir(dir) {
+			errs = append(errs, FieldError{
+				Field:   fmt.Sprintf("scan_skipped_dirs[%d]", i),
+				Message: fmt.Sprintf("%q is not a directory skipped by default", dir),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateFileExtensions checks that every requested extension normalizes to a supported one
+func validateFileExtensions(exts []string) []FieldError {
+	var errs []FieldError
+	for i, ext := range exts {
+		if _, err := services.NormalizeFileExtension(ext); err != nil {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("file_extensions[%d]", i), Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+// validateGlobList checks the count and syntax of a list of path globs
+func validateGlobList(field string, globs []string) []FieldError {
+	var errs []FieldError
+	if len(globs) > maxIncludeGlobs {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf("at most %d globs are allowed", maxIncludeGlobs)})
+	}
+	for i, glob := range globs {
+		if message := globProblem(glob); message != "" {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("%s[%d]", field, i), Message: message})
+		}
+	}
+	return errs
+}
+
+// validGitRef rejects refs git itself would refuse, so a bad ref fails fast instead of during the clone
+func validGitRef(ref string) bool {
+	if ref == "" || len(ref) > 255 || strings.HasPrefix(ref, "-") || strings.HasPrefix(ref, "/") ||
+		strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".lock") || strings.Contains(ref, "..") ||
+		strings.Contains(ref, "@{") {
+		return false
+	}
+	for _, c := range ref {
+		if c <= ' ' || c == 0x7f || strings.ContainsRune(`~^:?*[\`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// globProblem explains why a path glob is unusable, or returns "" for a valid glob
+func globProblem(glob string) string {
+	if strings.TrimSpace(glob) == "" {
+		return "glob must not be empty"
+	}
+	if len(glob) > 500 {
+		return "glob must be at most 500 characters"
+	}
+	// Each segment must be a valid path.Match pattern; "**" is handled by the matcher itself
+	if _, err := path.Match(strings.ReplaceAll(glob, "**", "*"), ""); err != nil {
+		return fmt.Sprintf("malformed glob %q: %v", glob, err)
+	}
+	return ""
+}
+
+// modelAllowed checks the model against the comma-separated SCAN_ALLOWED_MODELS; when unset any model is allowed
+func modelAllowed(model string) bool {
+	allowed := os.Getenv("SCAN_ALLOWED_MODELS")
+	if strings.TrimSpace(allowed) == "" {
+		return true
+	}
+	for _, candidate := range strings.Split(allowed, ",") {
+		if strings.TrimSpace(candidate) == model {
+			return true
+		}
+	}
+	return false
+}
+
+// writeValidationErrors responds with 422 and every field error, so clients can fix them in one round trip
+func writeValidationErrors(w http.ResponseWriter, errs []FieldError) {
+	writeErrorBody(w, http.StatusUnprocessableEntity, map[string]any{
+		"error":  errorDetail{Code: "validation_failed", Message: "invalid scan request"},
+		"errors": errs,
+	})
+}
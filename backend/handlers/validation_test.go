@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateScanRequest(t *testing.T) {
+	tooManyGlobs := make([]string, maxIncludeGlobs+1)
+	for i := range tooManyGlobs {
+		tooManyGlobs[i] = "*.go"
+	}
+
+	tests := []struct {
+		name       string
+		req        ScanRepositoryRequest
+		wantFields []string
+	}{
+		{name: "empty request uses defaults"},
+		{
+			name: "fully valid request",
+			req: ScanRepositoryRequest{
+				Model:          "gpt-4o-mini",
+				IncludeGlobs:   []string{"**/*.sql", "handlers/**", "cmd/?/main.go"},
+				VulnTypes:      []string{"Injection", "Server-Side Request Forgery"},
+				FileExtensions: []string{".go", ".py"},
+				MinSeverity:    "High",
+			},
+		},
+		{name: "malformed model", req: ScanRepositoryRequest{Model: "gpt-4; rm -rf /"}, wantFields: []string{"model"}},
+		{name: "unknown vulnerability type", req: ScanRepositoryRequest{VulnTypes: []string{"Injection", "Buffer Overflow"}}, wantFields: []string{"vuln_types[1]"}},
+		{name: "categories of another taxonomy", req: ScanRepositoryRequest{Taxonomy: "cwe-top-25", VulnTypes: []string{"CWE-89: SQL Injection"}}},
+		{name: "category outside the selected taxonomy", req: ScanRepositoryRequest{Taxonomy: "cwe-top-25", VulnTypes: []string{"Injection"}}, wantFields: []string{"vuln_types[0]"}},
+		{name: "unknown taxonomy", req: ScanRepositoryRequest{Taxonomy: "iso-27001", VulnTypes: []string{"Injection"}}, wantFields: []string{"taxonomy"}},
+		{name: "unsupported extension", req: ScanRepositoryRequest{FileExtensions: []string{".exe"}}, wantFields: []string{"file_extensions[0]"}},
+		{name: "extensions needing normalization", req: ScanRepositoryRequest{FileExtensions: []string{"go", ".JS", "*.py"}}},
+		{name: "wildcard extension", req: ScanRepositoryRequest{FileExtensions: []string{".go", "*"}}, wantFields: []string{"file_extensions[1]"}},
+		{name: "bad severity", req: ScanRepositoryRequest{MinSeverity: "urgent"}, wantFields: []string{"min_severity"}},
+		{name: "blank glob", req: ScanRepositoryRequest{IncludeGlobs: []string{" "}}, wantFields: []string{"include_globs[0]"}},
+		{name: "malformed glob", req: ScanRepositoryRequest{IncludeGlobs: []string{"src/[a-.go"}}, wantFields: []string{"include_globs[0]"}},
+		{name: "oversized glob", req: ScanRepositoryRequest{IncludeGlobs: []string{strings.Repeat("a", 501)}}, wantFields: []string{"include_globs[0]"}},
+		{name: "too many globs", req: ScanRepositoryRequest{IncludeGlobs: tooManyGlobs}, wantFields: []string{"include_globs"}},
+		{name: "branch ref", req: ScanRepositoryRequest{Ref: "release/2.0"}},
+		{name: "commit ref", req: ScanRepositoryRequest{Ref: "a1b2c3d4e5f6"}},
+		{name: "ref with a revision range", req: ScanRepositoryRequest{Ref: "main..feature"}, wantFields: []string{"ref"}},
+		{name: "ref that looks like an option", req: ScanRepositoryRequest{Ref: "--upload-pack=evil"}, wantFields: []string{"ref"}},
+		{name: "ref with a space", req: ScanRepositoryRequest{Ref: "my branch"}, wantFields: []string{"ref"}},
+		{name: "incremental base commit", req: ScanRepositoryRequest{IncrementalSince: "a1b2c3d4e5f6"}},
+		{name: "incremental base that is a branch", req: ScanRepositoryRequest{IncrementalSince: "main"}, wantFields: []string{"incremental_since"}},
+		{name: "re-enabled skipped directory", req: ScanRepositoryRequest{ScanVendored: true, ScanSkippedDirs: []string{"node_modules", "lib"}}},
+		{name: "directory that is not skipped", req: ScanRepositoryRequest{ScanSkippedDirs: []string{"src"}}, wantFields: []string{"scan_skipped_dirs[0]"}},
+		{name: "git metadata cannot be re-enabled", req: ScanRepositoryRequest{ScanSkippedDirs: []string{".git"}}, wantFields: []string{"scan_skipped_dirs[0]"}},
+		{
+			name: "every problem is reported at once",
+			req: ScanRepositoryRequest{
+				Model:          "../model",
+				VulnTypes:      []string{"Nope"},
+				FileExtensions: []string{".exe"},
+				MinSeverity:    "urgent",
+				IncludeGlobs:   []string{"["},
+			},
+			wantFields: []string{"model", "vuln_types[0]", "file_extensions[0]", "min_severity", "include_globs[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, fieldErr := range ValidateScanRequest(&tt.req) {
+				if fieldErr.Message == "" {
+					t.Errorf("field %s has no message", fieldErr.Field)
+				}
+				fields = append(fields, fieldErr.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("invalid fields = %q, want %q", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidateScanRequestHonorsModelAllowlist(t *testing.T) {
+	t.Setenv("SCAN_ALLOWED_MODELS", "gpt-4o-mini, gpt-4o")
+
+	if errs := ValidateScanRequest(&ScanRepositoryRequest{Model: "gpt-4o"}); len(errs) != 0 {
+		t.Errorf("allowed model rejected: %v", errs)
+	}
+	if errs := ValidateScanRequest(&ScanRepositoryRequest{Model: "gpt-3.5-turbo"}); len(errs) != 1 || errs[0].Field != "model" {
+		t.Errorf("ValidateScanRequest(gpt-3.5-turbo) = %v, want a model error", errs)
+	}
+}
+
+func TestScanRepositoryReportsEveryFieldError(t *testing.T) {
+	r := withJSONBody(newScanRequest("user-1", "repo-1"), `{"vuln_types": ["Nope"], "min_severity": "urgent"}`)
+
+	// The request is refused before the handler touches the database or Temporal
+	rec := httptest.NewRecorder()
+	(&RepositoryHandler{}).ScanRepository(rec, r)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	var body struct {
+		Errors []FieldError `json:"errors"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Errors) != 2 {
+		t.Errorf("errors = %v, want one per invalid field", body.Errors)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.uber.org/zap"
+)
+
+// WebhookHandler manages a user's scan event webhook subscriptions
+type WebhookHandler struct {
+	WebhookService services.WebhookService // Service for storing and delivering webhooks
+}
+
+// NewWebhookHandler creates a new webhook handler with the services it needs
+func NewWebhookHandler(webhookService services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		WebhookService: webhookService,
+	}
+}
+
+// CreateWebhookRequest represents a request to subscribe to scan events
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`    // Endpoint that receives signed POSTs
+	Events []string `json:"events"` // e.g. ["scan.cloning", "scan.completed"]; empty selects terminal states only
+}
+
+// CreateWebhook registers a webhook; the response includes the signing secret, which is not shown again
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	var req CreateWebhookRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if err := services.ValidateWebhookURL(r.Context(), req.URL); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_webhook_url", err.Error())
+		return
+	}
+
+	webhook, err := h.WebhookService.CreateWebhook(r.Context(), userID, req.URL, req.Events)
+	if err != nil {
+		log.Warn("Failed to create webhook", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusBadRequest, "invalid_webhook", err.Error())
+		return
+	}
+
+	log.Info("Webhook created",
+		zap.String("user_id", userID),
+		zap.String("webhook_id", webhook.ID),
+		zap.Strings("events", webhook.Events))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// ListWebhooks returns the user's webhooks and the events they can subscribe to
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	webhooks, err := h.WebhookService.ListWebhooks(r.Context(), userID)
+	if err != nil {
+		log.Error("Failed to list webhooks", zap.String("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"webhooks":         webhooks,
+		"available_events": services.WebhookEvents,
+	})
+}
+
+// DeleteWebhook removes one of the user's webhooks
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	webhookID := chi.URLParam(r, "id")
+
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	deleted, err := h.WebhookService.DeleteWebhook(r.Context(), userID, webhookID)
+	if err != nil {
+		log.Error("Failed to delete webhook", zap.String("webhook_id", webhookID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "internal_error", "Failed to delete webhook")
+		return
+	}
+	if !deleted {
+		respondError(w, http.StatusNotFound, "webhook_not_found", "Webhook not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -61,15 +61,26 @@ func Init() {
 			config.Encoding = "console"
 		}
 
+		// Mask credentials and cut long values, such as source code, before anything is written
+		maxFieldLength, validMaxFieldLength := maxFieldLengthFromEnv()
+
 		// Create the logger
-		logger, err := config.Build()
+		logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return NewRedactingCore(core, maxFieldLength)
+		}))
 		if err != nil {
 			panic("Failed to initialize logger: " + err.Error())
 		}
 
 		logger.Info("Logger initialized",
 			zap.Bool("development", isDevelopment),
-			zap.String("level", logLevel.String()))
+			zap.String("level", logLevel.String()),
+			zap.Int("max_field_length", maxFieldLength))
+		if !validMaxFieldLength {
+			logger.Warn("Invalid LOG_MAX_FIELD_LENGTH value, using default",
+				zap.String("value", os.Getenv("LOG_MAX_FIELD_LENGTH")),
+				zap.Int("default", DefaultMaxFieldLength))
+		}
 
 		log = logger
 	})
@@ -89,10 +100,13 @@ func With(fields ...zapcore.Field) *zap.Logger {
 }
 
 // WithRequest returns a logger with HTTP request details
+// Sensitive query parameters, such as OAuth codes, are masked in the logged URL
 func WithRequest(r *http.Request) *zap.Logger {
+	redactedURL := *r.URL
+	redactedURL.RawQuery = RedactQuery(r.URL.RawQuery)
 	return With(
 		zap.String("method", r.Method),
-		zap.String("url", r.URL.String()),
+		zap.String("url", redactedURL.String()),
 		zap.String("user_agent", r.UserAgent()),
 		zap.String("remote_addr", r.RemoteAddr),
 		zap.String("request_id", r.Header.Get("X-Request-ID")),
@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedValue replaces the values of sensitive fields
+const RedactedValue = "[REDACTED]"
+
+// DefaultMaxFieldLength is the longest string field logged in full when LOG_MAX_FIELD_LENGTH is unset
+// Longer values, such as source code or model replies, are cut to this many bytes
+const DefaultMaxFieldLength = 2048
+
+// sensitiveKeySuffixes mark fields holding credentials: a key is sensitive when, lower-cased and with dashes
+// as underscores, it ends in one of them. Suffixes keep "refresh_token" sensitive while "token_type" is not
+var sensitiveKeySuffixes = []string{
+	"token", "password", "secret", "authorization", "api_key", "apikey", "private_key", "cookie", "credentials", "signature",
+}
+
+// sensitiveQueryParams are query parameters that carry one-time credentials, such as OAuth redirects' code and state
+var sensitiveQueryParams = map[string]bool{"code": true, "state": true}
+
+// IsSensitiveKey reports whether a field or parameter named key holds a credential
+func IsSensitiveKey(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, suffix := range sensitiveKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact masks a secret, keeping empty values empty so a missing credential still shows in the log
+func Redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// Scrub masks every occurrence of the secrets in s, for text such as error messages that may quote a credential
+func Scrub(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, RedactedValue)
+		}
+	}
+	return s
+}
+
+// Truncate cuts value to at most maxLength bytes on a character boundary, noting how much was left out
+// A maxLength of 0 or less keeps the value whole
+func Truncate(value string, maxLength int) string {
+	if maxLength <= 0 || len(value) <= maxLength {
+		return value
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...[truncated %d bytes]", value[:cut], len(value)-cut)
+}
+
+// RedactQuery masks the values of sensitive parameters in a raw query string, keeping the rest as sent
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, _, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && (IsSensitiveKey(name) || sensitiveQueryParams[strings.ToLower(name)]) {
+			params[i] = key + "=" + RedactedValue
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// maxFieldLengthFromEnv reads LOG_MAX_FIELD_LENGTH; 0 turns truncation off
+// The second result is false when the value is invalid and the default is used instead
+func maxFieldLengthFromEnv() (int, bool) {
+	value := strings.TrimSpace(os.Getenv("LOG_MAX_FIELD_LENGTH"))
+	if value == "" {
+		return DefaultMaxFieldLength, true
+	}
+	maxLength, err := strconv.Atoi(value)
+	if err != nil || maxLength < 0 {
+		return DefaultMaxFieldLength, false
+	}
+	return maxLength, true
+}
+
+// NewRedactingCore wraps a core so every field it writes is made safe to log: values of sensitive keys are
+// masked and longer strings are cut to maxLength bytes (0 keeps them whole). Fields added with With are
+// redacted too, so a child logger can't carry a secret into later entries
+func NewRedactingCore(core zapcore.Core, maxLength int) zapcore.Core {
+	return &redactingCore{Core: core, maxLength: maxLength}
+}
+
+// redactingCore implements the core returned by NewRedactingCore
+type redactingCore struct {
+	zapcore.Core
+	maxLength int
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), maxLength: c.maxLength}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+// redact returns the fields with sensitive and oversized values replaced, copying only when one changes
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, field := range fields {
+		safe, changed := c.redactField(field)
+		if !changed {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]zapcore.Field(nil), fields...)
+		}
+		redacted[i] = safe
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// redactField masks a sensitive field whatever its value, except numbers and flags such as token counts,
+// and truncates a long string
+func (c *redactingCore) redactField(field zapcore.Field) (zapcore.Field, bool) {
+	if IsSensitiveKey(field.Key) {
+		switch field.Type {
+		case zapcore.BoolType, zapcore.DurationType, zapcore.Float64Type, zapcore.Float32Type,
+			zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type,
+			zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type,
+			zapcore.TimeType, zapcore.TimeFullType, zapcore.SkipType:
+		case zapcore.StringType:
+			return zap.String(field.Key, Redact(field.String)), field.String != ""
+		default:
+			return zap.String(field.Key, RedactedValue), true
+		}
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		if c.maxLength > 0 && len(field.String) > c.maxLength {
+			return zap.String(field.Key, Truncate(field.String, c.maxLength)), true
+		}
+	case zapcore.ByteStringType:
+		if value, ok := field.Interface.([]byte); ok && c.maxLength > 0 && len(value) > c.maxLength {
+			return zap.String(field.Key, Truncate(string(value), c.maxLength)), true
+		}
+	}
+	return field, false
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	sensitive := []string{"token", "refresh_token", "Authorization", "password", "client_secret", "api_key", "X-Api-Key", "apikey", "cookie", "signature"}
+	for _, key := range sensitive {
+		if !IsSensitiveKey(key) {
+			t.Errorf("IsSensitiveKey(%q) = false, want true", key)
+		}
+	}
+	safe := []string{"token_type", "estimated_tokens", "user_id", "repo_url", "content", "idempotency_key"}
+	for _, key := range safe {
+		if IsSensitiveKey(key) {
+			t.Errorf("IsSensitiveKey(%q) = true, want false", key)
+		}
+	}
+}
+
+func TestRedactingCore(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(NewRedactingCore(observed, 16))
+
+	log.With(zap.String("authorization", "Bearer abc.def")).Info("request",
+		zap.String("refresh_token", "rt-secret"),
+		zap.String("password", ""),
+		zap.Strings("api_key", []string{"k1", "k2"}),
+		zap.Int("prompt_token", 1200),
+		zap.String("code", strings.Repeat("x", 40)),
+		zap.ByteString("body", []byte(strings.Repeat("y", 40))),
+		zap.String("token_type", "access_token"))
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+
+	want := map[string]any{
+		"authorization": RedactedValue,
+		"refresh_token": RedactedValue,
+		"password":      "", // An empty value stays visible as missing
+		"api_key":       RedactedValue,
+		"prompt_token":  int64(1200),
+		"code":          strings.Repeat("x", 16) + "...[truncated 24 bytes]",
+		"body":          strings.Repeat("y", 16) + "...[truncated 24 bytes]",
+		"token_type":    "access_token",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %#v, want %#v", key, fields[key], value)
+		}
+	}
+}
+
+func TestRedactingCoreKeepsLongValuesWithoutALimit(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	zap.New(NewRedactingCore(observed, 0)).Info("scan", zap.String("content", strings.Repeat("x", 5000)))
+
+	if got := logs.All()[0].ContextMap()["content"]; got != strings.Repeat("x", 5000) {
+		t.Errorf("content was changed without a length limit")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("short", 10); got != "short" {
+		t.Errorf("Truncate of a short value = %q, want it unchanged", got)
+	}
+	// The cut backs up to the start of the character it would split
+	if got := Truncate("abé", 3); got != "ab...[truncated 2 bytes]" {
+		t.Errorf("Truncate across a character = %q", got)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := map[string]string{
+		"":                               "",
+		"page=2&limit=10":                "page=2&limit=10",
+		"code=4%2F0Ab&state=xyz&scope=a": "code=" + RedactedValue + "&state=" + RedactedValue + "&scope=a",
+		"access_token=abc&flag":          "access_token=" + RedactedValue + "&flag",
+		"api%5Fkey=abc":                  "api%5Fkey=" + RedactedValue,
+	}
+	for query, want := range tests {
+		if got := RedactQuery(query); got != want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestScrub(t *testing.T) {
+	err := `Get "https://oauth2.googleapis.com/tokeninfo?id_token=eyJ.secret": dial tcp: timeout`
+	if got := Scrub(err, "eyJ.secret", ""); strings.Contains(got, "eyJ.secret") || !strings.Contains(got, RedactedValue) {
+		t.Errorf("Scrub = %q, want the token masked", got)
+	}
+}
+
+func TestMaxFieldLengthFromEnv(t *testing.T) {
+	tests := []struct {
+		value     string
+		want      int
+		wantValid bool
+	}{
+		{value: "", want: DefaultMaxFieldLength, wantValid: true},
+		{value: "512", want: 512, wantValid: true},
+		{value: "0", want: 0, wantValid: true},
+		{value: "-1", want: DefaultMaxFieldLength},
+		{value: "lots", want: DefaultMaxFieldLength},
+	}
+	for _, tt := range tests {
+		t.Setenv("LOG_MAX_FIELD_LENGTH", tt.value)
+		if got, valid := maxFieldLengthFromEnv(); got != tt.want || valid != tt.wantValid {
+			t.Errorf("LOG_MAX_FIELD_LENGTH=%q: got %d, %v; want %d, %v", tt.value, got, valid, tt.want, tt.wantValid)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// backend/internal/metrics/metrics.go
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metric names all share the sast_ prefix so they are easy to find in a shared Prometheus
+var (
+	scansStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sast_scans_started_total",
+		Help: "Repository scans that started analyzing files.",
+	})
+
+	scansCompleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sast_scans_completed_total",
+		Help: "Repository scans that finished, by final status (completed, time_budget_reached, budget_exceeded, or completed_with_errors).",
+	}, []string{"status"})
+
+	scansFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sast_scans_failed_total",
+		Help: "Repository scans that failed once Temporal stopped retrying them.",
+	})
+
+	scanDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "sast_scan_duration_seconds",
+		Help: "Wall-clock time from the start of file analysis to the end of a finished scan.",
+		// Scans range from seconds for small repositories to the better part of an hour
+		Buckets: []float64{5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	})
+
+	vulnerabilitiesFound = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sast_vulnerabilities_found_total",
+		Help: "Reported findings of finished scans, by severity.",
+	}, []string{"severity"})
+
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sast_http_requests_total",
+		Help: "HTTP requests served, by method, route pattern, and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sast_http_request_duration_seconds",
+		Help:    "HTTP request latency, by method and route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Handler serves the registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ScanStarted records a scan beginning to analyze files
+func ScanStarted() {
+	scansStarted.Inc()
+}
+
+// ScanFailed records a scan that failed for good; attempts Temporal will retry aren't counted
+func ScanFailed() {
+	scansFailed.Inc()
+}
+
+// ScanFinished records a finished scan with its final status, duration, and reported findings per severity
+func ScanFinished(status string, duration time.Duration, findingsBySeverity map[string]int) {
+	scansCompleted.WithLabelValues(status).Inc()
+	scanDuration.Observe(duration.Seconds())
+	for severity, count := range findingsBySeverity {
+		vulnerabilitiesFound.WithLabelValues(severity).Add(float64(count))
+	}
+}
+
+// ObserveRequest records one served HTTP request
+// route should be the matched route pattern, not the raw path, to keep the number of series bounded
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerExposesScanAndRequestMetrics(t *testing.T) {
+	// A simulated scan and request, so every vector has a series to show
+	ScanStarted()
+	ScanFailed()
+	ScanFinished("completed", 42*time.Second, map[string]int{"high": 2, "low": 1})
+	ObserveRequest(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+
+	for _, want := range []string{
+		"sast_scans_started_total",
+		`sast_scans_completed_total{status="completed"}`,
+		"sast_scans_failed_total",
+		"sast_scan_duration_seconds_bucket",
+		`sast_vulnerabilities_found_total{severity="high"} 2`,
+		`sast_http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`sast_http_request_duration_seconds_bucket{method="GET",route="unmatched"`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// backend/internal/testdb/testdb.go
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+)
+
+// migrationLockID keys the advisory lock that keeps test packages from migrating the same database at once
+const migrationLockID = 4242001
+
+// Open connects to the database in TEST_DATABASE_URL, migrated to the latest schema, and closes it when
+// the test ends. The test is skipped when TEST_DATABASE_URL is unset
+// Tests share the database, so they should use their own rows (e.g. unique emails) rather than empty tables
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	migrate(t, dbURL)
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// migrate applies the migrations in db/migrations, holding an advisory lock on the one connection goose uses
+func migrate(t testing.TB, dbURL string) {
+	t.Helper()
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		t.Fatalf("lock test database: %v", err)
+	}
+	defer db.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("set migration dialect: %v", err)
+	}
+	if err := goose.UpContext(ctx, db, migrationsDir()); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+}
+
+// migrationsDir returns db/migrations, found from this file so it works from any package's tests
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "db", "migrations")
+}
@@ -0,0 +1,89 @@
+// backend/internal/tracing/tracing.go
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the backend's own spans
+const instrumentationName = "github.com/ritikarora108/ai-powered-sast-tool/backend"
+
+// defaultServiceName is reported as service.name unless OTEL_SERVICE_NAME overrides it
+const defaultServiceName = "ai-sast-backend"
+
+// Span attribute keys shared by the HTTP, Temporal, and model spans
+const (
+	RepositoryIDKey  = attribute.Key("sast.repository.id")
+	ScanIDKey        = attribute.Key("sast.scan.id")
+	FilePathKey      = attribute.Key("sast.file.path")
+	LanguageKey      = attribute.Key("sast.file.language")
+	ModelKey         = attribute.Key("sast.model")
+	TokenEstimateKey = attribute.Key("sast.tokens.estimated")
+)
+
+// Init sets up trace export to the OTLP/HTTP collector at OTEL_EXPORTER_OTLP_ENDPOINT
+// Without an endpoint tracing stays a no-op and spans cost next to nothing. The returned function
+// flushes buffered spans and must be called before the process exits
+func Init(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	// W3C trace context lets callers and the Temporal workflow headers continue our traces
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")) == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// The exporter reads the endpoint, headers, and TLS settings from the standard OTEL_EXPORTER_OTLP_* variables
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	// Detectors run in order, so OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for the backend's spans
+// It is looked up on every call so a provider installed later, e.g. by a test, takes effect
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span as a child of any span already in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError marks the span failed with err; a nil err leaves it untouched
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -15,25 +16,55 @@ import (
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/api"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/tracing"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/temporal"
+	"go.opentelemetry.io/otel"
 	"go.temporal.io/sdk/client"
+	temporalotel "go.temporal.io/sdk/contrib/opentelemetry"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/worker"
 	"go.uber.org/zap"
 )
 
-// startScanWorker initializes and starts a Temporal worker to process tasks from the SCAN_TASK_QUEUE
+// databasePingTimeout bounds each attempt to reach the database at startup
+const databasePingTimeout = 5 * time.Second
+
+// pingDatabase checks the database answers within databasePingTimeout
+func pingDatabase(ctx context.Context, sqlDB *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, databasePingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// shutdownGracePeriod bounds the whole shutdown: draining HTTP requests and then stopping the worker
+const shutdownGracePeriod = 30 * time.Second
+
+// startScanWorker initializes and starts a Temporal worker to process tasks from the scan task queue
 // This worker will execute the scan workflows and activities asynchronously
-func startScanWorker(c client.Client) error {
-	logger.Info("Creating Temporal worker for SCAN_TASK_QUEUE")
+// The caller stops the returned worker when the server shuts down
+func startScanWorker(c client.Client) (worker.Worker, error) {
+	// Concurrency limits keep the worker from overloading the machine; they are sized per deployment
+	config, err := temporal.WorkerConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid worker configuration: %w", err)
+	}
+
+	logger.Info("Creating Temporal worker",
+		zap.String("task_queue", config.TaskQueue),
+		zap.Int("max_activities", config.MaxActivities),
+		zap.Int("max_workflow_tasks", config.MaxWorkflowTasks))
 
-	// Create worker options with concurrency limits to prevent overloading the system
 	workerOptions := worker.Options{
-		MaxConcurrentActivityExecutionSize:     5,  // Limit concurrent activities
-		MaxConcurrentWorkflowTaskExecutionSize: 10, // Limit concurrent workflows
+		MaxConcurrentActivityExecutionSize:     config.MaxActivities,    // Limit concurrent activities
+		MaxConcurrentWorkflowTaskExecutionSize: config.MaxWorkflowTasks, // Limit concurrent workflows
+		// On shutdown running activities get the grace period to finish; after that their context is
+		// canceled, so they stop heartbeating and Temporal retries them on another worker
+		WorkerStopTimeout: shutdownGracePeriod,
 	}
 
-	// Create a new worker connected to the SCAN_TASK_QUEUE
-	w := worker.New(c, "SCAN_TASK_QUEUE", workerOptions)
+	// Create a new worker connected to the scan task queue
+	w := worker.New(c, config.TaskQueue, workerOptions)
 
 	// Register workflow and activities with the worker
 	// These define what code will be executed when tasks are received
@@ -41,11 +72,101 @@ func startScanWorker(c client.Client) error {
 	w.RegisterWorkflow(temporal.ScanWorkflow)
 	w.RegisterActivity(temporal.CloneRepositoryActivity)
 	w.RegisterActivity(temporal.ScanRepositoryActivity)
+	w.RegisterActivity(temporal.NotifyScanStatusActivity)
+	w.RegisterActivity(temporal.CheckWorkerPausedActivity)
+	w.RegisterActivity(temporal.RemoveUploadActivity)
+	w.RegisterActivity(temporal.RemoveWorkspaceActivity)
+	w.RegisterActivity(temporal.SendScanCallbackActivity)
+	w.RegisterWorkflow(temporal.DigestWorkflow)
+	w.RegisterActivity(temporal.SendScanDigestsActivity)
 
 	// Start the worker (non-blocking)
 	// This will run in the background listening for tasks
 	logger.Info("Starting Temporal worker")
-	return w.Start()
+	if err := w.Start(); err != nil {
+		return nil, err
+	}
+
+	// Remove clones left behind by scans whose worker crashed or was killed before their workflow cleaned up
+	// Scans don't wait on it, so it runs in the background
+	if ttl := durationFromEnv("SCAN_WORKSPACE_TTL", services.DefaultWorkspaceTTL); ttl > 0 {
+		go func() {
+			root := services.WorkspaceDir()
+			removed, err := services.ReapWorkspaces(root, ttl)
+			if err != nil {
+				logger.Warn("Failed to remove stale scan workspaces", zap.String("workspace_dir", root), zap.Error(err))
+			}
+			if removed > 0 {
+				logger.Info("Removed stale scan workspaces", zap.String("workspace_dir", root), zap.Int("removed", removed))
+			}
+		}()
+	}
+
+	// Schedule digest emails for users who chose them over an email per scan
+	// Scans don't depend on it, so a failure here is logged rather than stopping the server
+	if err := temporal.StartDigestWorkflow(context.Background(), c, config.TaskQueue); err != nil {
+		logger.Warn("Failed to schedule scan digest workflow", zap.Error(err))
+	}
+	return w, nil
+}
+
+// httpServer is the part of *http.Server that shutdown uses, so tests can fake it
+type httpServer interface {
+	Shutdown(ctx context.Context) error
+}
+
+// stoppableWorker is the part of worker.Worker that shutdown uses, so tests can fake it
+type stoppableWorker interface {
+	Stop()
+}
+
+// shutdown drains the HTTP server and then stops the Temporal worker, both before ctx's deadline
+// The server goes first so requests still in flight can start their scans. The worker is stopped even if
+// draining failed, and gets whatever time is left: Stop waits for running activities up to the worker's
+// stop timeout, while a deadline reached first returns an error without waiting further
+func shutdown(ctx context.Context, server httpServer, w stoppableWorker) error {
+	logger.Info("Shutting down server gracefully")
+	serverErr := server.Shutdown(ctx)
+	if serverErr != nil {
+		serverErr = fmt.Errorf("server shutdown failed: %w", serverErr)
+	}
+	if w == nil {
+		return serverErr
+	}
+
+	logger.Info("Stopping Temporal worker")
+	stopped := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		logger.Info("Temporal worker stopped")
+		return serverErr
+	case <-ctx.Done():
+		return errors.Join(serverErr, fmt.Errorf("temporal worker did not stop in time: %w", ctx.Err()))
+	}
+}
+
+// durationFromEnv reads a Go duration such as "30s" from the named environment variable
+// It falls back to the default when the variable is unset or invalid; 0 disables the timeout
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		logger.Warn("Invalid duration in environment, using default",
+			zap.String("name", name),
+			zap.String("value", value),
+			zap.Duration("default", defaultValue))
+		return defaultValue
+	}
+
+	return parsed
 }
 
 // main is the entry point for the application
@@ -63,6 +184,30 @@ func main() {
 
 	logger.Info("Starting AI-powered SAST tool backend")
 
+	// Export traces when OTEL_EXPORTER_OTLP_ENDPOINT is set; without it spans are no-ops
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		logger.Fatal("Unable to set up tracing", zap.Error(err))
+	}
+	defer func() {
+		// Flush the spans still buffered; bounded so an unreachable collector can't hold up exit
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("Failed to flush traces", zap.Error(err))
+		}
+	}()
+
+	// Refuse to run in production with the development JWT secret
+	if err := services.ValidateJWTSecret(); err != nil {
+		logger.Fatal("Invalid JWT configuration", zap.Error(err))
+	}
+
+	// Register the custom vulnerability taxonomies scans may select; the server and worker need the same set
+	if err := services.LoadTaxonomiesFromEnv(); err != nil {
+		logger.Fatal("Invalid taxonomy configuration", zap.Error(err))
+	}
+
 	// Connect to PostgreSQL database - extract connection parameters from environment variables
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
@@ -92,14 +237,12 @@ func main() {
 	sqlDB.SetMaxIdleConns(5)                  // Maximum number of connections in the idle connection pool
 	sqlDB.SetConnMaxLifetime(time.Minute * 5) // Maximum amount of time a connection may be reused
 
-	// Test database connection with a timeout to ensure it's working
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	// Add retry logic for database connection in case of temporary issues
+	// Each attempt gets its own timeout, and the wait between attempts doubles
 	maxRetries := 3
+	retryDelay := 2 * time.Second
 	for i := 0; i < maxRetries; i++ {
-		err = sqlDB.PingContext(ctx)
+		err = pingDatabase(context.Background(), sqlDB)
 		if err == nil {
 			logger.Info("Successfully connected to PostgreSQL database")
 			break
@@ -111,22 +254,34 @@ func main() {
 			zap.Int("max_attempts", maxRetries))
 
 		if i < maxRetries-1 {
-			time.Sleep(time.Second * 2)
+			time.Sleep(retryDelay)
+			retryDelay *= 2
 		}
 	}
 
 	// Continue even if database connection failed
-	// This allows the application to start without a database
-	// and will use in-memory storage or fail gracefully for database operations
+	// This allows the application to start without a database; database operations fail gracefully
+	// until the reconnection loop below reaches it and sets the global connection
 	if err != nil {
 		logger.Error("Failed to connect to database after multiple attempts", zap.Error(err))
-		logger.Warn("Continuing without database connection - some features may not work")
+		logger.Warn("Continuing without database connection - some features may not work until it reconnects")
+		db.SetGlobalDB(nil)
+	} else {
+		// Set the global database connection for use throughout the application
+		db.SetGlobalDB(sqlDB)
 	}
-
-	// Set the global database connection for use throughout the application
-	db.SetGlobalDB(sqlDB)
 	defer sqlDB.Close()
 
+	// Keep checking the connection, and reconnect with backoff whenever it is lost
+	reconnectCtx, stopReconnecting := context.WithCancel(context.Background())
+	defer stopReconnecting()
+	go db.MaintainConnection(reconnectCtx, func(ctx context.Context) (*sql.DB, error) {
+		if err := pingDatabase(ctx, sqlDB); err != nil {
+			return nil, err
+		}
+		return sqlDB, nil
+	}, db.DefaultReconnectConfig)
+
 	// Initialize database queries wrapper
 	dbQueries := db.NewQueries()
 	defer dbQueries.Close()
@@ -134,8 +289,17 @@ func main() {
 	// Initialize Temporal client for workflow orchestration
 	// Temporal is used for managing long-running scan workflows
 	logger.Info("Initializing Temporal client")
+	// The tracing interceptor carries the trace of the request that started a scan into its workflow
+	// and activities, and gives each of them a span
+	tracingInterceptor, err := temporalotel.NewTracingInterceptor(temporalotel.TracerOptions{
+		TextMapPropagator: otel.GetTextMapPropagator(),
+	})
+	if err != nil {
+		logger.Fatal("Unable to create Temporal tracing interceptor", zap.Error(err))
+	}
 	temporalClient, err := client.NewLazyClient(client.Options{
-		HostPort: os.Getenv("TEMPORAL_HOST"),
+		HostPort:     os.Getenv("TEMPORAL_HOST"),
+		Interceptors: []interceptor.ClientInterceptor{tracingInterceptor},
 	})
 	if err != nil {
 		logger.Fatal("Unable to create Temporal client", zap.Error(err))
@@ -145,7 +309,7 @@ func main() {
 	// Start Temporal worker for scan workflows
 	// This worker will execute the repository scanning tasks asynchronously
 	logger.Info("Starting Temporal worker for scan workflows")
-	err = startScanWorker(temporalClient)
+	scanWorker, err := startScanWorker(temporalClient)
 	if err != nil {
 		logger.Fatal("Unable to start Temporal worker", zap.Error(err))
 	}
@@ -162,11 +326,23 @@ func main() {
 	}
 	serverAddr := ":" + port
 
+	// Bound how long a client may hold a connection so slow or stalled clients can't exhaust the server
+	// Write timeout must stay above the slowest legitimate handler; set it to 0 to disable it for streaming
 	server := &http.Server{
-		Addr:    serverAddr,
-		Handler: router,
+		Addr:              serverAddr,
+		Handler:           router,
+		ReadHeaderTimeout: durationFromEnv("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+		ReadTimeout:       durationFromEnv("HTTP_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      durationFromEnv("HTTP_WRITE_TIMEOUT", 120*time.Second),
+		IdleTimeout:       durationFromEnv("HTTP_IDLE_TIMEOUT", 120*time.Second),
 	}
 
+	logger.Info("HTTP server timeouts configured",
+		zap.Duration("read_header_timeout", server.ReadHeaderTimeout),
+		zap.Duration("read_timeout", server.ReadTimeout),
+		zap.Duration("write_timeout", server.WriteTimeout),
+		zap.Duration("idle_timeout", server.IdleTimeout))
+
 	// Setup server context for graceful shutdown
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
@@ -179,22 +355,13 @@ func main() {
 		logger.Info("Received shutdown signal", zap.String("signal", s.String()))
 
 		// Shutdown signal with grace period of 30 seconds
-		// This allows ongoing requests to complete
-		shutdownCtx, shutdownCancel := context.WithTimeout(serverCtx, 30*time.Second)
+		// This allows ongoing requests and scan activities to complete
+		shutdownCtx, shutdownCancel := context.WithTimeout(serverCtx, shutdownGracePeriod)
 		defer shutdownCancel()
 
-		go func() {
-			<-shutdownCtx.Done()
-			if shutdownCtx.Err() == context.DeadlineExceeded {
-				logger.Fatal("Graceful shutdown timed out... forcing exit")
-			}
-		}()
-
-		// Trigger graceful shutdown of the HTTP server
-		logger.Info("Shutting down server gracefully")
-		err := server.Shutdown(shutdownCtx)
-		if err != nil {
-			logger.Fatal("Server shutdown failed", zap.Error(err))
+		// Drain the HTTP server, then stop the worker
+		if err := shutdown(shutdownCtx, server, scanWorker); err != nil {
+			logger.Fatal("Graceful shutdown failed... forcing exit", zap.Error(err))
 		}
 		serverStopCtx()
 	}()
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDurationFromEnv(t *testing.T) {
+	const defaultValue = 30 * time.Second
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "unset uses the default", want: defaultValue},
+		{name: "configured duration", value: "2m", want: 2 * time.Minute},
+		{name: "zero disables the timeout", value: "0", want: 0},
+		{name: "negative uses the default", value: "-1s", want: defaultValue},
+		{name: "invalid uses the default", value: "thirty seconds", want: defaultValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HTTP_READ_TIMEOUT", tt.value)
+			if got := durationFromEnv("HTTP_READ_TIMEOUT", defaultValue); got != tt.want {
+				t.Errorf("durationFromEnv with %q = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// shutdownRecorder records the order the fakes below are stopped in
+type shutdownRecorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *shutdownRecorder) record(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+// fakeServer records its shutdown and returns err
+type fakeServer struct {
+	recorder *shutdownRecorder
+	err      error
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.recorder.record("server")
+	return s.err
+}
+
+// fakeWorker records its stop, first blocking for delay like a worker waiting on running activities
+type fakeWorker struct {
+	recorder *shutdownRecorder
+	delay    time.Duration
+}
+
+func (w *fakeWorker) Stop() {
+	time.Sleep(w.delay)
+	w.recorder.record("worker")
+}
+
+func TestShutdownDrainsTheServerBeforeStoppingTheWorker(t *testing.T) {
+	recorder := &shutdownRecorder{}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := shutdown(ctx, &fakeServer{recorder: recorder}, &fakeWorker{recorder: recorder, delay: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("shutdown returned error: %v", err)
+	}
+	if len(recorder.steps) != 2 || recorder.steps[0] != "server" || recorder.steps[1] != "worker" {
+		t.Errorf("shutdown order = %v, want [server worker]", recorder.steps)
+	}
+}
+
+func TestShutdownStopsTheWorkerWhenDrainingFails(t *testing.T) {
+	recorder := &shutdownRecorder{}
+	drainErr := errors.New("connections still open")
+
+	err := shutdown(context.Background(), &fakeServer{recorder: recorder, err: drainErr}, &fakeWorker{recorder: recorder})
+	if !errors.Is(err, drainErr) {
+		t.Errorf("shutdown = %v, want the server's error", err)
+	}
+	if len(recorder.steps) != 2 || recorder.steps[1] != "worker" {
+		t.Errorf("shutdown steps = %v, want the worker stopped after the server", recorder.steps)
+	}
+}
+
+func TestShutdownGivesUpOnTheWorkerAtTheDeadline(t *testing.T) {
+	recorder := &shutdownRecorder{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := shutdown(ctx, &fakeServer{recorder: recorder}, &fakeWorker{recorder: recorder, delay: time.Second})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("shutdown = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("shutdown waited %v for the worker, want it to return at the deadline", elapsed)
+	}
+}
+
+func TestShutdownWithoutAWorker(t *testing.T) {
+	recorder := &shutdownRecorder{}
+	if err := shutdown(context.Background(), &fakeServer{recorder: recorder}, nil); err != nil {
+		t.Errorf("shutdown returned error: %v", err)
+	}
+}
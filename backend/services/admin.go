@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+const (
+	// DefaultAdminListLimit is the page size of admin listings when the caller does not pass one
+	DefaultAdminListLimit = 50
+
+	// MaxAdminListLimit caps the page size of admin listings
+	MaxAdminListLimit = 200
+)
+
+// UserSummary describes an account as listed to admins
+type UserSummary struct {
+	ID              string    `json:"id"`
+	Email           string    `json:"email"`
+	Name            string    `json:"name"`
+	Role            string    `json:"role"`
+	CreatedAt       time.Time `json:"created_at"`
+	RepositoryCount int       `json:"repository_count"` // Repositories the user has added
+	ScanCount       int       `json:"scan_count"`       // Scans the user has started
+}
+
+// AdminScanRecord is a scan as listed to admins, with the repository and user it belongs to
+type AdminScanRecord struct {
+	ScanRecord
+	RepositoryID    string  `json:"repository_id"`
+	RepositoryOwner string  `json:"repository_owner"`
+	RepositoryName  string  `json:"repository_name"`
+	CreatedBy       *string `json:"created_by"` // User who started the scan; null for public scans
+}
+
+// DirectoryService lists users and scans across every account, for admins
+type DirectoryService interface {
+	// ListUsers returns one page of users, oldest account first, with the total number of users
+	ListUsers(ctx context.Context, limit, offset int) ([]*UserSummary, int, error)
+
+	// ListScans returns one page of every user's scans, newest first, with the total number of scans
+	ListScans(ctx context.Context, limit, offset int) ([]*AdminScanRecord, int, error)
+}
+
+// NewDirectoryService creates a new directory service instance
+func NewDirectoryService(dbQueries *db.Queries) DirectoryService {
+	return &directoryService{
+		db: dbQueries,
+	}
+}
+
+// directoryService implements the DirectoryService interface
+type directoryService struct {
+	db *db.Queries
+}
+
+// adminPage clamps a requested page to the admin listing limits
+func adminPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultAdminListLimit
+	}
+	if limit > MaxAdminListLimit {
+		limit = MaxAdminListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func (s *directoryService) ListUsers(ctx context.Context, limit, offset int) ([]*UserSummary, int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, 0, fmt.Errorf("database connection not available")
+	}
+	limit, offset = adminPage(limit, offset)
+
+	var total int
+	if err := sqlDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT u.id::text, u.email, u.name, u.role, u.created_at,
+			(SELECT COUNT(*) FROM repositories r WHERE r.created_by = u.id),
+			(SELECT COUNT(*) FROM scans s WHERE s.created_by = u.id)
+		FROM users u
+		ORDER BY u.created_at, u.id
+		LIMIT $1 OFFSET $2`,
+		limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*UserSummary{}
+	for rows.Next() {
+		user := &UserSummary{}
+		var name sql.NullString
+		if err := rows.Scan(&user.ID, &user.Email, &name, &user.Role, &user.CreatedAt,
+			&user.RepositoryCount, &user.ScanCount); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		user.Name = name.String
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error while iterating over user rows: %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (s *directoryService) ListScans(ctx context.Context, limit, offset int) ([]*AdminScanRecord, int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, 0, fmt.Errorf("database connection not available")
+	}
+	limit, offset = adminPage(limit, offset)
+
+	var total int
+	if err := sqlDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM scans`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scans: %w", err)
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT s.id::text, s.status, s.ref, s.commit_sha, s.base_commit_sha, s.model, s.created_at, s.started_at, s.completed_at,
+			(SELECT COUNT(*) FROM vulnerabilities v WHERE v.scan_id = s.id AND NOT v.excluded),
+			r.id::text, r.owner, r.name, s.created_by::text
+		FROM scans s
+		JOIN repositories r ON r.id = s.repository_id
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT $1 OFFSET $2`,
+		limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list scans: %w", err)
+	}
+	defer rows.Close()
+
+	scans := []*AdminScanRecord{}
+	for rows.Next() {
+		scan := &AdminScanRecord{}
+		var ref, commitSHA, baseCommitSHA, model, createdBy sql.NullString
+		var startedAt, completedAt sql.NullTime
+
+		if err := rows.Scan(&scan.ID, &scan.Status, &ref, &commitSHA, &baseCommitSHA, &model, &scan.CreatedAt,
+			&startedAt, &completedAt, &scan.VulnerabilityCount,
+			&scan.RepositoryID, &scan.RepositoryOwner, &scan.RepositoryName, &createdBy); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan scan row: %w", err)
+		}
+
+		scan.Ref = stringOrNil(ref)
+		scan.CommitSHA = stringOrNil(commitSHA)
+		scan.BaseCommitSHA = stringOrNil(baseCommitSHA)
+		scan.Model = stringOrNil(model)
+		scan.CreatedBy = stringOrNil(createdBy)
+		if startedAt.Valid {
+			scan.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			scan.CompletedAt = &completedAt.Time
+		}
+
+		scans = append(scans, scan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error while iterating over scan rows: %w", err)
+	}
+
+	return scans, total, nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognize and search for
+const APIKeyPrefix = "sast_"
+
+// MaxAPIKeyNameLength is the longest name a key can be given
+const MaxAPIKeyNameLength = 100
+
+// ErrInvalidAPIKey is returned for API keys that are malformed, unknown, or revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey is a user's credential for programmatic access
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`        // Identifies the key; it is the start of the key itself
+	Key        string     `json:"key,omitempty"` // Only returned when the key is created
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// APIKeyIdentity is who an authenticated API key acts as, and what it may do
+type APIKeyIdentity struct {
+	KeyID  string
+	UserID string
+	Role   string // The owner's current role
+	Scopes []string
+}
+
+// APIKeyService manages API keys and authenticates requests made with them
+type APIKeyService interface {
+	// CreateAPIKey issues a key for a user; empty scopes select DefaultAPIKeyScopes
+	// The returned key is the only copy of its secret
+	CreateAPIKey(ctx context.Context, userID, name string, scopes []string) (*APIKey, error)
+
+	// ListAPIKeys returns a user's keys, newest first, without their secrets
+	ListAPIKeys(ctx context.Context, userID string) ([]*APIKey, error)
+
+	// RevokeAPIKey deletes one of the user's keys; it returns false if none matched
+	RevokeAPIKey(ctx context.Context, userID, keyID string) (bool, error)
+
+	// Authenticate resolves a key to its owner and scopes and records that it was used
+	// It returns ErrInvalidAPIKey if the key can't be used
+	Authenticate(ctx context.Context, key string) (*APIKeyIdentity, error)
+}
+
+// NewAPIKeyService creates a new API key service instance
+func NewAPIKeyService(dbQueries *db.Queries) APIKeyService {
+	return &apiKeyService{
+		db: dbQueries,
+	}
+}
+
+// apiKeyService implements the APIKeyService interface
+type apiKeyService struct {
+	db *db.Queries
+}
+
+// hashAPIKey returns the stored form of an API key
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKey generates a key of the form sast_<8 hex characters>_<secret>, returning it with its prefix
+func newAPIKey() (key, prefix string, err error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
+
+// apiKeyPrefix returns the prefix a key is looked up by, or false if the key isn't in the issued format
+func apiKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", false
+	}
+	prefix, secret, found := strings.Cut(key[len(APIKeyPrefix):], "_")
+	if !found || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	return APIKeyPrefix + prefix, true
+}
+
+// ValidateAPIKeyScopes checks that every requested scope can be granted to a key
+func ValidateAPIKeyScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !IsKnownScope(scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID, name string, scopes []string) (*APIKey, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) > MaxAPIKeyNameLength {
+		return nil, fmt.Errorf("API key name must be at most %d characters", MaxAPIKeyNameLength)
+	}
+	if len(scopes) == 0 {
+		scopes = DefaultAPIKeyScopes
+	}
+	if err := ValidateAPIKeyScopes(scopes); err != nil {
+		return nil, err
+	}
+
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := &APIKey{Name: name, Prefix: prefix, Key: key, Scopes: scopes}
+
+	err = sqlDB.QueryRowContext(ctx,
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		userID, apiKey.Name, apiKey.Prefix, hashAPIKey(key), pq.Array(apiKey.Scopes)).Scan(&apiKey.ID, &apiKey.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return apiKey, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID string) ([]*APIKey, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id, name, prefix, scopes, created_at, last_used_at FROM api_keys
+		WHERE user_id = $1 ORDER BY created_at DESC, id`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	apiKeys := []*APIKey{}
+	for rows.Next() {
+		apiKey := &APIKey{}
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&apiKey.ID, &apiKey.Name, &apiKey.Prefix, pq.Array(&apiKey.Scopes),
+			&apiKey.CreatedAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key row: %w", err)
+		}
+		if lastUsedAt.Valid {
+			apiKey.LastUsedAt = &lastUsedAt.Time
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over API key rows: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, keyID string) (bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return false, fmt.Errorf("database connection not available")
+	}
+
+	result, err := sqlDB.ExecContext(ctx,
+		`DELETE FROM api_keys WHERE id::text = $1 AND user_id = $2`,
+		keyID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, nil
+	}
+	return affected > 0, nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (*APIKeyIdentity, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	identity := &APIKeyIdentity{}
+	var storedHash string
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT k.id, k.user_id, u.role, k.scopes, k.key_hash
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1`,
+		prefix).Scan(&identity.KeyID, &identity.UserID, &identity.Role, pq.Array(&identity.Scopes), &storedHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	// The prefix is not secret, so the rest of the key is compared in constant time
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(storedHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// A failure to record the use shouldn't reject a valid key
+	if _, err := sqlDB.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, identity.KeyID); err != nil {
+		logger.FromContext(ctx).Warn("Failed to record API key use", zap.String("api_key_id", identity.KeyID), zap.Error(err))
+	}
+
+	return identity, nil
+}
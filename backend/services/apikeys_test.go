@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyPrefix(t *testing.T) {
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		t.Fatalf("newAPIKey returned error: %v", err)
+	}
+	if !strings.HasPrefix(key, prefix+"_") {
+		t.Fatalf("key %q does not start with its prefix %q", key, prefix)
+	}
+	if got, ok := apiKeyPrefix(key); !ok || got != prefix {
+		t.Errorf("apiKeyPrefix(issued key) = %q, %v; want %q", got, ok, prefix)
+	}
+
+	for _, malformed := range []string{"", "sast_", "sast_1234abcd", "sast_1234abcd_", "sast_12_secret", "ghp_1234abcd_secret"} {
+		if _, ok := apiKeyPrefix(malformed); ok {
+			t.Errorf("apiKeyPrefix(%q) accepted a malformed key", malformed)
+		}
+	}
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	queries := newTestQueries(t)
+	service := NewAPIKeyService(queries)
+	ctx := context.Background()
+	userID := createTestUser(t, queries)
+
+	apiKey, err := service.CreateAPIKey(ctx, userID, "CI", []string{ScopeRepoRead, ScopeScanWrite})
+	if err != nil {
+		t.Fatalf("CreateAPIKey returned error: %v", err)
+	}
+	if apiKey.Key == "" || !strings.HasPrefix(apiKey.Key, apiKey.Prefix) {
+		t.Fatalf("created key = %+v, want the secret returned once", apiKey)
+	}
+
+	identity, err := service.Authenticate(ctx, apiKey.Key)
+	if err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+	if identity.UserID != userID || identity.Role != RoleUser || !HasScope(identity.Scopes, ScopeScanWrite) || HasScope(identity.Scopes, ScopeRepoDelete) {
+		t.Errorf("identity = %+v, want the owner with the key's scopes", identity)
+	}
+
+	// A key with the right prefix but another secret is refused
+	forged := apiKey.Prefix + "_forged"
+	if _, err := service.Authenticate(ctx, forged); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Authenticate with a forged secret = %v, want ErrInvalidAPIKey", err)
+	}
+
+	keys, err := service.ListAPIKeys(ctx, userID)
+	if err != nil {
+		t.Fatalf("ListAPIKeys returned error: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Prefix != apiKey.Prefix || keys[0].Key != "" {
+		t.Fatalf("ListAPIKeys = %+v, want the key without its secret", keys)
+	}
+	if keys[0].LastUsedAt == nil {
+		t.Error("last_used_at was not recorded when the key authenticated")
+	}
+
+	// Another user can't revoke the key
+	if revoked, err := service.RevokeAPIKey(ctx, createTestUser(t, queries), apiKey.ID); err != nil || revoked {
+		t.Errorf("RevokeAPIKey by another user = %v, %v; want nothing revoked", revoked, err)
+	}
+	if revoked, err := service.RevokeAPIKey(ctx, userID, apiKey.ID); err != nil || !revoked {
+		t.Fatalf("RevokeAPIKey = %v, %v; want the key revoked", revoked, err)
+	}
+	if _, err := service.Authenticate(ctx, apiKey.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Authenticate after revocation = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestCreateAPIKeyDefaultsAndValidation(t *testing.T) {
+	queries := newTestQueries(t)
+	service := NewAPIKeyService(queries)
+	ctx := context.Background()
+	userID := createTestUser(t, queries)
+
+	apiKey, err := service.CreateAPIKey(ctx, userID, "", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey returned error: %v", err)
+	}
+	if len(apiKey.Scopes) != len(DefaultAPIKeyScopes) || apiKey.Scopes[0] != DefaultAPIKeyScopes[0] {
+		t.Errorf("scopes = %v, want the defaults %v", apiKey.Scopes, DefaultAPIKeyScopes)
+	}
+
+	if _, err := service.CreateAPIKey(ctx, userID, "", []string{ScopeAll}); err == nil {
+		t.Error("CreateAPIKey granted the wildcard scope")
+	}
+	if _, err := service.CreateAPIKey(ctx, userID, strings.Repeat("n", MaxAPIKeyNameLength+1), nil); err == nil {
+		t.Error("CreateAPIKey accepted an overlong name")
+	}
+}
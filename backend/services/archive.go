@@ -0,0 +1,233 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Errors returned for archives that can't be extracted; the wrapping error names the offending entry
+var (
+	ErrUnsupportedArchive = errors.New("unsupported archive format, expected .zip or .tar.gz")
+	ErrUnsafeArchivePath  = errors.New("archive entry escapes the extraction directory")
+	ErrArchiveTooLarge    = errors.New("archive is too large when extracted")
+)
+
+// Default limits on what an uploaded archive may expand to, so a small compressed upload can't fill the disk
+const (
+	DefaultArchiveMaxFiles          = 50000
+	DefaultArchiveMaxExtractedBytes = 500 << 20 // 500 MiB
+)
+
+// ArchiveLimits bounds the contents of an archive being extracted
+type ArchiveLimits struct {
+	MaxFiles          int   // Most entries the archive may hold; 0 uses DefaultArchiveMaxFiles
+	MaxExtractedBytes int64 // Most bytes its files may add up to; 0 uses DefaultArchiveMaxExtractedBytes
+}
+
+// UploadsDir returns the directory uploaded archives are extracted under, one subdirectory per scan
+func UploadsDir() string {
+	return filepath.Join(os.TempDir(), "uploads")
+}
+
+// IsUploadDir reports whether dir is an extraction directory under UploadsDir, so cleanup never
+// removes anything else
+func IsUploadDir(dir string) bool {
+	rel, err := filepath.Rel(UploadsDir(), filepath.Clean(dir))
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel)
+}
+
+// ExtractArchive extracts a zip or gzip-compressed tar archive into destDir, which must exist
+// The format is detected from the archive's contents rather than its name. Entries with absolute
+// paths or ".." segments fail the whole extraction with ErrUnsafeArchivePath; symbolic and hard links
+// are skipped, since a link could point a later entry outside destDir. Devices and other special
+// files are skipped too
+func ExtractArchive(archivePath, destDir string, limits ArchiveLimits) error {
+	if limits.MaxFiles <= 0 {
+		limits.MaxFiles = DefaultArchiveMaxFiles
+	}
+	if limits.MaxExtractedBytes <= 0 {
+		limits.MaxExtractedBytes = DefaultArchiveMaxExtractedBytes
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	magic, err := bufio.NewReader(file).Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	extractor := &archiveExtractor{destDir: filepath.Clean(destDir), limits: limits}
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		return extractor.extractZip(file, info.Size())
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return extractor.extractTarGz(file)
+	default:
+		return ErrUnsupportedArchive
+	}
+}
+
+// archiveExtractor writes archive entries under destDir while keeping count against the limits
+type archiveExtractor struct {
+	destDir string
+	limits  ArchiveLimits
+	files   int
+	written int64
+}
+
+// targetPath returns where an entry is extracted to, refusing names that would land outside destDir
+// Backslashes are treated as separators, since archives made on Windows may use them
+func (e *archiveExtractor) targetPath(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	if slashed == "" || path.IsAbs(slashed) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" ||
+		(len(slashed) >= 2 && slashed[1] == ':') {
+		return "", fmt.Errorf("%w: %q is an absolute path", ErrUnsafeArchivePath, name)
+	}
+	for _, segment := range strings.Split(slashed, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %q contains \"..\"", ErrUnsafeArchivePath, name)
+		}
+	}
+
+	target := filepath.Join(e.destDir, filepath.FromSlash(path.Clean(slashed)))
+	if target != e.destDir && !strings.HasPrefix(target, e.destDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeArchivePath, name)
+	}
+	return target, nil
+}
+
+// countEntry counts one more entry against MaxFiles
+func (e *archiveExtractor) countEntry() error {
+	e.files++
+	if e.files > e.limits.MaxFiles {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, e.limits.MaxFiles)
+	}
+	return nil
+}
+
+// writeFile copies one entry's contents to target, stopping once the extracted total passes the limit
+// The declared size isn't trusted: the copy itself is bounded
+func (e *archiveExtractor) writeFile(target string, contents io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	remaining := e.limits.MaxExtractedBytes - e.written
+	n, err := io.Copy(out, io.LimitReader(contents, remaining+1))
+	e.written += n
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract file: %w", err)
+	}
+	if n > remaining {
+		return fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, e.limits.MaxExtractedBytes)
+	}
+	return nil
+}
+
+// extractZip extracts the entries of a zip archive
+func (e *archiveExtractor) extractZip(r io.ReaderAt, size int64) error {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedArchive, err)
+	}
+
+	for _, entry := range reader.File {
+		if err := e.countEntry(); err != nil {
+			return err
+		}
+		target, err := e.targetPath(entry.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		case mode.IsRegular():
+			contents, err := entry.Open()
+			if err != nil {
+				return fmt.Errorf("failed to read %q: %w", entry.Name, err)
+			}
+			err = e.writeFile(target, contents)
+			contents.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// extractTarGz extracts the entries of a gzip-compressed tar archive
+func (e *archiveExtractor) extractTarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedArchive, err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		// PAX global headers carry metadata only
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		if err := e.countEntry(); err != nil {
+			return err
+		}
+		target, err := e.targetPath(header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := e.writeFile(target, reader); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testArchiveEntry is one entry of an archive built by a test
+type testArchiveEntry struct {
+	name    string
+	body    string
+	symlink string // Link target; when set the entry is a symbolic link
+}
+
+// writeTestZip writes a zip archive holding entries and returns its path
+func writeTestZip(t *testing.T, entries []testArchiveEntry) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "upload.zip")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		body := entry.body
+		if entry.symlink != "" {
+			header.SetMode(os.ModeSymlink | 0777)
+			body = entry.symlink
+		}
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+// writeTestTarGz writes a gzip-compressed tar archive holding entries and returns its path
+func writeTestTarGz(t *testing.T, entries []testArchiveEntry) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "upload.tar.gz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	writer := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.body)), Typeflag: tar.TypeReg}
+		if entry.symlink != "" {
+			header = &tar.Header{Name: entry.name, Mode: 0777, Linkname: entry.symlink, Typeflag: tar.TypeSymlink}
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if entry.symlink == "" {
+			if _, err := writer.Write([]byte(entry.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+var testArchiveFormats = map[string]func(*testing.T, []testArchiveEntry) string{
+	"zip":    writeTestZip,
+	"tar.gz": writeTestTarGz,
+}
+
+func TestExtractArchiveRejectsPathTraversal(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{name: "parent directory", entry: "../evil.go"},
+		{name: "nested parent directory", entry: "src/../../evil.go"},
+		{name: "parent directory at the end", entry: "src/.."},
+		{name: "absolute path", entry: "/tmp/evil.go"},
+		{name: "backslash parent directory", entry: `..\evil.go`},
+		{name: "windows absolute path", entry: `C:\evil.go`},
+		{name: "windows drive relative path", entry: "C:evil.go"},
+	}
+
+	for format, write := range testArchiveFormats {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				// The extraction directory sits inside a parent the test can inspect for escaped files
+				parent := t.TempDir()
+				dest := filepath.Join(parent, "extracted")
+				if err := os.Mkdir(dest, 0755); err != nil {
+					t.Fatal(err)
+				}
+				archivePath := write(t, []testArchiveEntry{
+					{name: "main.go", body: "package main"},
+					{name: tt.entry, body: "package evil"},
+				})
+
+				err := ExtractArchive(archivePath, dest, ArchiveLimits{})
+				if !errors.Is(err, ErrUnsafeArchivePath) {
+					t.Fatalf("ExtractArchive error = %v, want ErrUnsafeArchivePath", err)
+				}
+				if _, err := os.Stat(filepath.Join(parent, "evil.go")); err == nil {
+					t.Error("entry was written outside the extraction directory")
+				}
+			})
+		}
+	}
+}
+
+func TestExtractArchiveSkipsLinks(t *testing.T) {
+	for format, write := range testArchiveFormats {
+		t.Run(format, func(t *testing.T) {
+			dest := t.TempDir()
+			archivePath := write(t, []testArchiveEntry{
+				{name: "escape", symlink: "/etc"},
+				{name: "main.go", body: "package main"},
+			})
+
+			if err := ExtractArchive(archivePath, dest, ArchiveLimits{}); err != nil {
+				t.Fatalf("ExtractArchive: %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(dest, "escape")); !os.IsNotExist(err) {
+				t.Errorf("link entry was extracted (err = %v), want it skipped", err)
+			}
+			if _, err := os.Stat(filepath.Join(dest, "main.go")); err != nil {
+				t.Errorf("regular file was not extracted: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveExtractsTheTree(t *testing.T) {
+	entries := []testArchiveEntry{
+		{name: "main.go", body: "package main"},
+		{name: "handlers/login.go", body: "package handlers"},
+		{name: "./web/app.js", body: "console.log(1)"},
+	}
+
+	for format, write := range testArchiveFormats {
+		t.Run(format, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := ExtractArchive(write(t, entries), dest, ArchiveLimits{}); err != nil {
+				t.Fatalf("ExtractArchive: %v", err)
+			}
+			for _, entry := range entries {
+				got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(entry.name)))
+				if err != nil {
+					t.Fatalf("read %s: %v", entry.name, err)
+				}
+				if string(got) != entry.body {
+					t.Errorf("%s = %q, want %q", entry.name, got, entry.body)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractArchiveEnforcesLimits(t *testing.T) {
+	entries := []testArchiveEntry{
+		{name: "a.go", body: strings.Repeat("a", 600)},
+		{name: "b.go", body: strings.Repeat("b", 600)},
+	}
+
+	for format, write := range testArchiveFormats {
+		t.Run(format+"/bytes", func(t *testing.T) {
+			err := ExtractArchive(write(t, entries), t.TempDir(), ArchiveLimits{MaxExtractedBytes: 1000})
+			if !errors.Is(err, ErrArchiveTooLarge) {
+				t.Errorf("ExtractArchive error = %v, want ErrArchiveTooLarge", err)
+			}
+		})
+		t.Run(format+"/files", func(t *testing.T) {
+			err := ExtractArchive(write(t, entries), t.TempDir(), ArchiveLimits{MaxFiles: 1})
+			if !errors.Is(err, ErrArchiveTooLarge) {
+				t.Errorf("ExtractArchive error = %v, want ErrArchiveTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveRejectsOtherFormats(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "upload.zip")
+	if err := os.WriteFile(archivePath, []byte("not an archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractArchive(archivePath, t.TempDir(), ArchiveLimits{}); !errors.Is(err, ErrUnsupportedArchive) {
+		t.Errorf("ExtractArchive error = %v, want ErrUnsupportedArchive", err)
+	}
+}
+
+func TestIsUploadDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{dir: filepath.Join(UploadsDir(), "scan-1"), want: true},
+		{dir: UploadsDir(), want: false},
+		{dir: filepath.Join(UploadsDir(), "..", "repos"), want: false},
+		{dir: "/etc", want: false},
+		{dir: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsUploadDir(tt.dir); got != tt.want {
+			t.Errorf("IsUploadDir(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -27,8 +28,15 @@ type User struct {
 	Email     string `json:"email"`
 	Name      string `json:"name"`
 	AvatarURL string `json:"picture"`
+	Role      string `json:"role"` // RoleUser or RoleAdmin
 }
 
+// Roles stored in users.role and carried in the JWT role claim
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // GoogleUserInfo represents information from Google's userinfo endpoint
 type GoogleUserInfo struct {
 	ID               string `json:"id"`             // Standard user ID
@@ -56,6 +64,7 @@ type GoogleTokenResponse struct {
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"` // The user's role when the token was issued
 	jwt.RegisteredClaims
 }
 
@@ -290,15 +299,52 @@ func (s *AuthService) CreateOrUpdateUser(ctx context.Context, userInfo *GoogleUs
 	// User exists, update them
 	logger.Info("Updating existing user", zap.String("email", userInfo.Email))
 
-	_, err = db.ExecContext(ctx,
-		"UPDATE users SET name = $1, google_id = $2, avatar_url = $3, updated_at = NOW() WHERE id = $4",
-		userInfo.Name, userInfo.ID, userInfo.Picture, userID)
-
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
+		logger.Error("Failed to begin user update", zap.Error(err))
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// Registration doesn't prove the caller owns the email, so a password account Google has never signed in to
+	// may belong to someone else. Google has verified the owner: the password is cleared as the account is claimed,
+	// along with the refresh tokens and API keys issued under it
+	var unclaimedPassword bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT google_id IS NULL AND password_hash IS NOT NULL FROM users WHERE id = $1 FOR UPDATE",
+		userID).Scan(&unclaimedPassword); err != nil {
+		logger.Error("Failed to lock user", zap.Error(err))
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET name = $1, google_id = $2, avatar_url = $3,
+			password_hash = CASE WHEN $5 THEN NULL ELSE password_hash END, updated_at = NOW()
+		WHERE id = $4`,
+		userInfo.Name, userInfo.ID, userInfo.Picture, userID, unclaimedPassword); err != nil {
 		logger.Error("Failed to update user", zap.Error(err))
 		return "", err
 	}
 
+	if unclaimedPassword {
+		for _, query := range []string{
+			"DELETE FROM refresh_tokens WHERE user_id = $1",
+			"DELETE FROM api_keys WHERE user_id = $1",
+		} {
+			if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+				logger.Error("Failed to revoke credentials of claimed user", zap.Error(err))
+				return "", err
+			}
+		}
+		logger.Warn("Google Sign-In claimed a password account; its password and credentials were revoked",
+			zap.String("user_id", userID))
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Failed to commit user update", zap.Error(err))
+		return "", err
+	}
+
 	logger.Info("User updated successfully", zap.String("user_id", userID))
 	return userID, nil
 }
@@ -310,21 +356,52 @@ func ProcessUserInfo(user *User) error {
 	return nil
 }
 
-// GenerateJWT generates a JWT token for the user
-func (s *AuthService) GenerateJWT(userID, email string) (string, error) {
-	// Get the JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "default-secret-key-change-in-production" // Fallback secret
-		logger.Warn("Using default JWT secret, consider setting JWT_SECRET environment variable")
+// JWTLifetime is how long a token issued by GenerateJWT stays valid
+const JWTLifetime = 24 * time.Hour
+
+// developmentJWTSecret signs tokens when JWT_SECRET is unset outside production
+const developmentJWTSecret = "default-secret-key-change-in-production"
+
+// ErrJWTSecretNotSet is returned by ValidateJWTSecret when production runs without JWT_SECRET
+var ErrJWTSecretNotSet = errors.New("JWT_SECRET must be set when APP_ENV=production")
+
+// warnDevelopmentSecret makes sure the fallback secret warning is logged only once
+var warnDevelopmentSecret sync.Once
+
+// JWTSecret returns the key used to sign and verify every JWT
+// All token paths read it from here so a token signed by one always verifies in the others
+func JWTSecret() string {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return secret
 	}
 
+	warnDevelopmentSecret.Do(func() {
+		logger.Warn("JWT_SECRET environment variable not set, using default secret (not secure for production)")
+	})
+	return developmentJWTSecret
+}
+
+// ValidateJWTSecret checks that a real secret is configured in production
+// It is called at startup so the server never runs in production with the development secret
+func ValidateJWTSecret() error {
+	if os.Getenv("APP_ENV") == "production" && os.Getenv("JWT_SECRET") == "" {
+		return ErrJWTSecretNotSet
+	}
+	return nil
+}
+
+// GenerateJWT generates a JWT token for the user
+// The role is a snapshot: a role change reaches the user's tokens when they are next issued
+func (s *AuthService) GenerateJWT(userID, email, role string) (string, error) {
+	jwtSecret := JWTSecret()
+
 	// Create claims with user information
-	expirationTime := time.Now().Add(24 * time.Hour) // Token expires in 24 hours
+	expirationTime := time.Now().Add(JWTLifetime)
 
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -349,11 +426,7 @@ func (s *AuthService) GenerateJWT(userID, email string) (string, error) {
 func GenerateSessionToken(user *User) (string, error) {
 	// This function is kept for backward compatibility
 
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "dev-secret-key" // Default for development, should be properly set in production
-	}
+	jwtSecret := JWTSecret()
 
 	// Set expiration time
 	expirationTime := time.Now().Add(24 * time.Hour) // Token valid for 24 hours
@@ -362,6 +435,7 @@ func GenerateSessionToken(user *User) (string, error) {
 	claims := &Claims{
 		UserID: user.ID,
 		Email:  user.Email,
+		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -385,11 +459,16 @@ func GenerateSessionToken(user *User) (string, error) {
 
 // VerifyJWT verifies a JWT token and returns the user ID
 func (s *AuthService) VerifyJWT(tokenString string) (string, error) {
-	// Get the JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "default-secret-key-change-in-production" // Fallback secret
+	claims, err := s.ParseJWT(tokenString)
+	if err != nil {
+		return "", err
 	}
+	return claims.UserID, nil
+}
+
+// ParseJWT verifies a JWT token and returns its claims
+func (s *AuthService) ParseJWT(tokenString string) (*Claims, error) {
+	jwtSecret := JWTSecret()
 
 	// Parse and validate the token
 	claims := &Claims{}
@@ -402,13 +481,27 @@ func (s *AuthService) VerifyJWT(tokenString string) (string, error) {
 
 	if err != nil {
 		logger.Warn("Failed to parse JWT token", zap.Error(err))
-		return "", err
+		return nil, err
 	}
 
 	if !token.Valid {
 		logger.Warn("Invalid JWT token")
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
-	return claims.UserID, nil
+	return claims, nil
+}
+
+// UserRole returns the role stored for a user
+func (s *AuthService) UserRole(ctx context.Context, userID string) (string, error) {
+	if s.dbConn == nil || s.dbConn.GetDB() == nil {
+		return "", errors.New("database connection not initialized")
+	}
+
+	var role string
+	err := s.dbConn.GetDB().QueryRowContext(ctx, `SELECT role FROM users WHERE id::text = $1`, userID).Scan(&role)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up user role: %w", err)
+	}
+	return role, nil
 }
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// ErrScanNotInRepository is returned when promoting a scan that belongs to another repository
+var ErrScanNotInRepository = fmt.Errorf("scan does not belong to repository")
+
+// BaselineService manages a repository's accepted-findings baseline
+// Findings whose fingerprint is in the baseline are hidden from results by default,
+// so later scans surface only new findings
+type BaselineService interface {
+	// PromoteBaseline replaces the repository's baseline with the fingerprints of one scan's findings
+	// It returns the number of fingerprints in the new baseline
+	PromoteBaseline(ctx context.Context, repoID, scanID string) (int, error)
+
+	// ClearBaseline removes the repository's baseline so every finding is shown again
+	// It returns the number of fingerprints removed
+	ClearBaseline(ctx context.Context, repoID string) (int, error)
+}
+
+// baselinedColumnSQL selects whether finding v is in its repository's baseline
+// Queries that use it must alias the vulnerabilities table as v
+const baselinedColumnSQL = `EXISTS (
+			SELECT 1 FROM baseline_fingerprints b JOIN scans bs ON bs.repository_id = b.repository_id
+			WHERE bs.id = v.scan_id AND b.fingerprint = v.fingerprint
+		) AS baselined`
+
+// NewBaselineService creates a new baseline service instance
+func NewBaselineService(dbQueries *db.Queries) BaselineService {
+	return &baselineService{
+		db: dbQueries,
+	}
+}
+
+// baselineService implements the BaselineService interface
+type baselineService struct {
+	db *db.Queries
+}
+
+func (s *baselineService) PromoteBaseline(ctx context.Context, repoID, scanID string) (int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	var scanRepoID string
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT repository_id::text FROM scans WHERE id::text = $1`, scanID).Scan(&scanRepoID)
+	if err == sql.ErrNoRows || (err == nil && scanRepoID != repoID) {
+		return 0, ErrScanNotInRepository
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up scan %s: %w", scanID, err)
+	}
+
+	// Swap the baseline in one transaction so readers never see a half-written one
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin baseline transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM baseline_fingerprints WHERE repository_id::text = $1`, repoID); err != nil {
+		return 0, fmt.Errorf("failed to clear previous baseline: %w", err)
+	}
+
+	// Legacy findings without a fingerprint are skipped; run the admin reindex to backfill them first
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO baseline_fingerprints (repository_id, fingerprint)
+		SELECT DISTINCT s.repository_id, v.fingerprint
+		FROM vulnerabilities v JOIN scans s ON s.id = v.scan_id
+		WHERE v.scan_id::text = $1 AND v.fingerprint IS NOT NULL`,
+		scanID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store baseline fingerprints: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE repositories SET baseline_scan_id = $1, updated_at = NOW() WHERE id::text = $2`,
+		scanID, repoID); err != nil {
+		return 0, fmt.Errorf("failed to record baseline scan: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit baseline: %w", err)
+	}
+
+	stored, _ := result.RowsAffected()
+	return int(stored), nil
+}
+
+func (s *baselineService) ClearBaseline(ctx context.Context, repoID string) (int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin baseline transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM baseline_fingerprints WHERE repository_id::text = $1`, repoID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear baseline: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE repositories SET baseline_scan_id = NULL, updated_at = NOW() WHERE id::text = $1`,
+		repoID); err != nil {
+		return 0, fmt.Errorf("failed to clear baseline scan: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit baseline removal: %w", err)
+	}
+
+	removed, _ := result.RowsAffected()
+	return int(removed), nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPromotedBaselineHidesItsFindingsInTheNextScan(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, firstScanID := createTestScan(t, queries)
+	progress := NewScanProgressService(queries)
+	baselines := NewBaselineService(queries)
+
+	accepted := &Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 3, LineEnd: 3, Severity: "High", Description: "accepted", Code: "db.Query(q)"}
+	if err := progress.RecordFile(ctx, firstScanID, "db.go", []*Vulnerability{accepted}); err != nil {
+		t.Fatalf("record first scan: %v", err)
+	}
+
+	count, err := baselines.PromoteBaseline(ctx, repoID, firstScanID)
+	if err != nil {
+		t.Fatalf("PromoteBaseline returned error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("baseline holds %d fingerprints, want 1", count)
+	}
+
+	// The next scan finds the accepted finding again plus a new one
+	var nextScanID string
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, started_at, completed_at) VALUES ($1, 'completed', NOW(), NOW()) RETURNING id`,
+		repoID).Scan(&nextScanID); err != nil {
+		t.Fatalf("insert next scan: %v", err)
+	}
+	again := *accepted
+	fresh := &Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 9, LineEnd: 9, Severity: "High", Description: "new", Code: "db.Exec(q)"}
+	if err := progress.RecordFile(ctx, nextScanID, "db.go", []*Vulnerability{&again, fresh}); err != nil {
+		t.Fatalf("record next scan: %v", err)
+	}
+
+	baselined := func() map[string]bool {
+		t.Helper()
+		vulns, err := progress.ScanVulnerabilities(ctx, nextScanID)
+		if err != nil {
+			t.Fatalf("ScanVulnerabilities returned error: %v", err)
+		}
+		byDescription := make(map[string]bool)
+		for _, vuln := range vulns {
+			byDescription[vuln.Description] = vuln.Baselined
+		}
+		return byDescription
+	}
+
+	if got := baselined(); !got["accepted"] || got["new"] {
+		t.Errorf("baselined = %v, want only the accepted finding hidden", got)
+	}
+
+	removed, err := baselines.ClearBaseline(ctx, repoID)
+	if err != nil {
+		t.Fatalf("ClearBaseline returned error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("cleared %d fingerprints, want 1", removed)
+	}
+	if got := baselined(); got["accepted"] || got["new"] {
+		t.Errorf("baselined = %v after clearing, want every finding reported", got)
+	}
+}
+
+func TestPromoteBaselineRejectsAnotherRepositorysScan(t *testing.T) {
+	queries := newTestQueries(t)
+	repoID, _ := createTestScan(t, queries)
+	_, otherScanID := createTestScan(t, queries)
+
+	_, err := NewBaselineService(queries).PromoteBaseline(context.Background(), repoID, otherScanID)
+	if !errors.Is(err, ErrScanNotInRepository) {
+		t.Errorf("PromoteBaseline error = %v, want ErrScanNotInRepository", err)
+	}
+}
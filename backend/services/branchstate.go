@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// BranchStateService remembers the last commit successfully scanned on each branch of a repository
+// Incremental and diff scans use it as their base, so the state is kept in the database rather than in memory
+type BranchStateService interface {
+	// LastScannedCommit returns the last scanned commit of a branch
+	// found is false for a branch that has never completed a scan; callers should then scan the full tree
+	LastScannedCommit(ctx context.Context, repoID, ref string) (sha string, found bool, err error)
+
+	// RecordScannedCommit stores the commit a scan of the branch completed on
+	// It should only be called once a scan of the default scope finished successfully without reaching
+	// SCAN_MAX_FILES, so a failed, narrowed, or truncated scan never moves the base
+	RecordScannedCommit(ctx context.Context, repoID, ref, sha, scanID string) error
+
+	// CompletedScanAtCommit returns the most recent fully completed scan of a commit with the given scope key
+	// Incremental scans carry that scan's findings forward for files that haven't changed since, so a scan
+	// with other options, or whose file list SCAN_MAX_FILES cut short, is never returned
+	CompletedScanAtCommit(ctx context.Context, repoID, sha, scopeKey string) (scanID string, found bool, err error)
+}
+
+// NewBranchStateService creates a new branch state service instance
+func NewBranchStateService(dbQueries *db.Queries) BranchStateService {
+	return &branchStateService{
+		db: dbQueries,
+	}
+}
+
+// branchStateService implements the BranchStateService interface
+type branchStateService struct {
+	db *db.Queries
+}
+
+func (s *branchStateService) LastScannedCommit(ctx context.Context, repoID, ref string) (string, bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return "", false, fmt.Errorf("database connection not available")
+	}
+
+	var sha string
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT last_scanned_sha FROM repo_branch_state WHERE repository_id = $1 AND ref = $2`,
+		repoID, ref).Scan(&sha)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read branch state for %s: %w", ref, err)
+	}
+
+	return sha, true, nil
+}
+
+func (s *branchStateService) RecordScannedCommit(ctx context.Context, repoID, ref, sha, scanID string) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	// First-seen branches get a row; known branches move their base forward
+	_, err := sqlDB.ExecContext(ctx,
+		`INSERT INTO repo_branch_state (repository_id, ref, last_scanned_sha, last_scan_id, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NOW())
+		ON CONFLICT (repository_id, ref) DO UPDATE
+		SET last_scanned_sha = EXCLUDED.last_scanned_sha, last_scan_id = EXCLUDED.last_scan_id, updated_at = NOW()`,
+		repoID, ref, sha, scanID)
+	if err != nil {
+		return fmt.Errorf("failed to record branch state for %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+func (s *branchStateService) CompletedScanAtCommit(ctx context.Context, repoID, sha, scopeKey string) (string, bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return "", false, fmt.Errorf("database connection not available")
+	}
+
+	// Scans stopped by the time budget, truncated at the file limit, or narrowed to other files are skipped:
+	// files they never reached would look clean
+	var scanID string
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT id::text FROM scans
+		WHERE repository_id = $1 AND commit_sha = $2 AND status = $3 AND scan_scope = $4 AND NOT truncated
+		ORDER BY completed_at DESC NULLS LAST
+		LIMIT 1`,
+		repoID, sha, ScanStatusCompleted, scopeKey).Scan(&scanID)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up scan of commit %s: %w", sha, err)
+	}
+
+	return scanID, true, nil
+}
+
+// HeadCommit returns the checked-out ref and commit of a cloned repository
+func HeadCommit(repoDir string) (ref, sha string, err error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	return head.Name().String(), head.Hash().String(), nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestBranchStateIsReadAndWrittenAcrossScans(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, firstScanID := createTestScan(t, queries)
+	_, secondScanID := createTestScan(t, queries)
+	state := NewBranchStateService(queries)
+
+	// A first-seen branch has no base, so the first scan covers the full tree
+	if _, found, err := state.LastScannedCommit(ctx, repoID, "refs/heads/main"); err != nil || found {
+		t.Fatalf("LastScannedCommit of a new branch = found %v, error %v; want not found", found, err)
+	}
+
+	if err := state.RecordScannedCommit(ctx, repoID, "refs/heads/main", "aaa111", firstScanID); err != nil {
+		t.Fatalf("RecordScannedCommit returned error: %v", err)
+	}
+	// The next run reads the base the previous run wrote, then moves it forward
+	sha, found, err := state.LastScannedCommit(ctx, repoID, "refs/heads/main")
+	if err != nil || !found || sha != "aaa111" {
+		t.Fatalf("LastScannedCommit = %q, %v, %v; want aaa111", sha, found, err)
+	}
+	if err := state.RecordScannedCommit(ctx, repoID, "refs/heads/main", "bbb222", secondScanID); err != nil {
+		t.Fatalf("RecordScannedCommit returned error: %v", err)
+	}
+	if sha, _, _ := state.LastScannedCommit(ctx, repoID, "refs/heads/main"); sha != "bbb222" {
+		t.Errorf("base after the second scan = %q, want bbb222", sha)
+	}
+
+	// Branches are tracked separately
+	if _, found, _ := state.LastScannedCommit(ctx, repoID, "refs/heads/feature"); found {
+		t.Error("another branch picked up main's base")
+	}
+}
+
+func TestCompletedScanAtCommitNeedsAnUntruncatedScanOfTheSameScope(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, _ := createTestScan(t, queries)
+	state := NewBranchStateService(queries)
+
+	fullScope := DefaultScanScope().Key()
+	narrowScope := ScanScope{FileExtensions: DefaultFileExtensions(), IncludeGlobs: []string{"handlers/**"}}.Key()
+
+	addScan := func(scope string, truncated bool) string {
+		t.Helper()
+		var scanID string
+		if err := queries.GetDB().QueryRowContext(ctx,
+			`INSERT INTO scans (repository_id, status, started_at, completed_at, commit_sha, scan_scope, truncated)
+			VALUES ($1, 'completed', NOW(), NOW(), 'ccc333', $2, $3) RETURNING id`,
+			repoID, scope, truncated).Scan(&scanID); err != nil {
+			t.Fatalf("insert scan: %v", err)
+		}
+		return scanID
+	}
+
+	addScan(narrowScope, false)
+	addScan(fullScope, true)
+	if scanID, found, err := state.CompletedScanAtCommit(ctx, repoID, "ccc333", fullScope); err != nil || found {
+		t.Fatalf("CompletedScanAtCommit = %q, found %v, error %v; want no base from a narrowed or truncated scan", scanID, found, err)
+	}
+
+	fullScanID := addScan(fullScope, false)
+	scanID, found, err := state.CompletedScanAtCommit(ctx, repoID, "ccc333", fullScope)
+	if err != nil || !found || scanID != fullScanID {
+		t.Errorf("CompletedScanAtCommit = %q, found %v, error %v; want the untruncated full-scope scan %s", scanID, found, err, fullScanID)
+	}
+}
+
+func TestHeadCommit(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("init repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := worktree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	ref, sha, err := HeadCommit(dir)
+	if err != nil {
+		t.Fatalf("HeadCommit returned error: %v", err)
+	}
+	if ref != "refs/heads/master" || sha != commit.String() {
+		t.Errorf("HeadCommit = %s at %s, want refs/heads/master at %s", ref, sha, commit)
+	}
+
+	if _, _, err := HeadCommit(t.TempDir()); err == nil {
+		t.Error("HeadCommit succeeded outside a repository")
+	}
+}
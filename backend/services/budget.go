@@ -0,0 +1,107 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ScanBudget caps the model usage of one scan across all of its batches
+// Usage is estimated from the prompts sent, since providers only report tokens after the fact; 0 means no limit
+type ScanBudget struct {
+	MaxTotalTokens      int     // Most estimated prompt tokens the scan may send
+	MaxEstimatedCostUSD float64 // Most estimated spend in US dollars
+}
+
+// Exceeded reports whether the given usage has reached either limit
+func (b ScanBudget) Exceeded(tokens int, costUSD float64) bool {
+	return (b.MaxTotalTokens > 0 && tokens >= b.MaxTotalTokens) ||
+		(b.MaxEstimatedCostUSD > 0 && costUSD >= b.MaxEstimatedCostUSD)
+}
+
+// ScanBudgetFromEnv reads the scan budget from SCAN_MAX_TOTAL_TOKENS and SCAN_MAX_ESTIMATED_COST_USD
+// Unset, zero, or invalid values leave that limit off; invalid ones are logged
+func ScanBudgetFromEnv() ScanBudget {
+	budget := ScanBudget{}
+
+	if value := strings.TrimSpace(os.Getenv("SCAN_MAX_TOTAL_TOKENS")); value != "" {
+		tokens, err := strconv.Atoi(value)
+		if err != nil || tokens < 0 {
+			logger.Warn("Invalid SCAN_MAX_TOTAL_TOKENS value, ignoring it", zap.String("value", value))
+		} else {
+			budget.MaxTotalTokens = tokens
+		}
+	}
+
+	if value := strings.TrimSpace(os.Getenv("SCAN_MAX_ESTIMATED_COST_USD")); value != "" {
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil || cost < 0 {
+			logger.Warn("Invalid SCAN_MAX_ESTIMATED_COST_USD value, ignoring it", zap.String("value", value))
+		} else {
+			budget.MaxEstimatedCostUSD = cost
+		}
+	}
+
+	return budget
+}
+
+// defaultCostPer1KTokens is the prompt price assumed for models missing from the table
+// It is on the expensive side, so a budget on an unknown model stops the scan early rather than late
+const defaultCostPer1KTokens = 0.01
+
+// modelCostsPer1KTokens maps model name prefixes to their prompt price in US dollars per 1,000 tokens
+// The longest matching prefix wins, so "gpt-4o-mini" is not priced as "gpt-4o"
+var modelCostsPer1KTokens = map[string]float64{
+	"gpt-4o-mini":   0.00015,
+	"gpt-4o":        0.0025,
+	"gpt-4.1-mini":  0.0004,
+	"gpt-4.1":       0.002,
+	"gpt-4-turbo":   0.01,
+	"gpt-4":         0.03,
+	"gpt-3.5-turbo": 0.0005,
+	"o1":            0.015,
+	"o3":            0.002,
+	"o4-mini":       0.0011,
+	"claude-":       0.003,
+}
+
+// CostPer1KTokens returns the prompt price of a model in US dollars per 1,000 tokens
+// MODEL_COST_PER_1K_TOKENS_<MODEL> (e.g. MODEL_COST_PER_1K_TOKENS_GPT_4O) overrides the table for one model and
+// MODEL_COST_PER_1K_TOKENS for every model, e.g. for negotiated or self-hosted pricing
+func CostPer1KTokens(model string) float64 {
+	for _, key := range []string{"MODEL_COST_PER_1K_TOKENS_" + baml.ModelEnvSuffix(model), "MODEL_COST_PER_1K_TOKENS"} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil || cost < 0 {
+			logger.Warn("Invalid "+key+" value, ignoring it", zap.String("value", value))
+			continue
+		}
+		return cost
+	}
+
+	model = strings.ToLower(strings.TrimSpace(model))
+	cost, matched := defaultCostPer1KTokens, 0
+	for prefix, price := range modelCostsPer1KTokens {
+		if strings.HasPrefix(model, prefix) && len(prefix) > matched {
+			cost, matched = price, len(prefix)
+		}
+	}
+	return cost
+}
+
+// EstimateCostUSD returns the estimated price of sending tokens prompt tokens to the model
+func EstimateCostUSD(model string, tokens int) float64 {
+	return float64(tokens) / 1000 * CostPer1KTokens(model)
+}
+
+// estimateScanTokens approximates the prompt tokens of scanning one file, from its character count
+func estimateScanTokens(code, language, relPath string, vulnTypeStrings []string) int {
+	return baml.EstimateTokens(baml.ScanSystemPrompt) + baml.EstimateTokens(baml.FormatScanPrompt(code, language, relPath, vulnTypeStrings))
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScanBudgetExceeded(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  ScanBudget
+		tokens  int
+		costUSD float64
+		want    bool
+	}{
+		{name: "no limits", tokens: 1_000_000, costUSD: 100},
+		{name: "under the token limit", budget: ScanBudget{MaxTotalTokens: 1000}, tokens: 999},
+		{name: "at the token limit", budget: ScanBudget{MaxTotalTokens: 1000}, tokens: 1000, want: true},
+		{name: "under the cost limit", budget: ScanBudget{MaxEstimatedCostUSD: 0.5}, costUSD: 0.49},
+		{name: "over the cost limit", budget: ScanBudget{MaxEstimatedCostUSD: 0.5}, costUSD: 0.51, want: true},
+		{name: "either limit stops the scan", budget: ScanBudget{MaxTotalTokens: 1000, MaxEstimatedCostUSD: 5}, tokens: 1200, costUSD: 0.01, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.Exceeded(tt.tokens, tt.costUSD); got != tt.want {
+				t.Errorf("Exceeded(%d, %v) = %v, want %v", tt.tokens, tt.costUSD, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanBudgetFromEnv(t *testing.T) {
+	t.Setenv("SCAN_MAX_TOTAL_TOKENS", "200000")
+	t.Setenv("SCAN_MAX_ESTIMATED_COST_USD", "2.50")
+	if got := ScanBudgetFromEnv(); got != (ScanBudget{MaxTotalTokens: 200000, MaxEstimatedCostUSD: 2.5}) {
+		t.Errorf("ScanBudgetFromEnv() = %+v, want both limits", got)
+	}
+
+	// Invalid values leave the limit off rather than blocking every scan
+	t.Setenv("SCAN_MAX_TOTAL_TOKENS", "lots")
+	t.Setenv("SCAN_MAX_ESTIMATED_COST_USD", "-1")
+	if got := ScanBudgetFromEnv(); got != (ScanBudget{}) {
+		t.Errorf("ScanBudgetFromEnv() = %+v, want no limits", got)
+	}
+}
+
+func TestCostPer1KTokens(t *testing.T) {
+	t.Setenv("MODEL_COST_PER_1K_TOKENS", "")
+	t.Setenv("MODEL_COST_PER_1K_TOKENS_GPT_4O", "")
+
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{model: "gpt-4o", want: 0.0025},
+		{model: "gpt-4o-mini-2024-07-18", want: 0.00015}, // Longest prefix wins
+		{model: "GPT-4-Turbo", want: 0.01},
+		{model: "claude-3-5-sonnet-latest", want: 0.003},
+		{model: "llama-3-70b", want: defaultCostPer1KTokens},
+	}
+	for _, tt := range tests {
+		if got := CostPer1KTokens(tt.model); got != tt.want {
+			t.Errorf("CostPer1KTokens(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+
+	t.Setenv("MODEL_COST_PER_1K_TOKENS_GPT_4O", "0.001")
+	t.Setenv("MODEL_COST_PER_1K_TOKENS", "0.002")
+	if got := CostPer1KTokens("gpt-4o"); got != 0.001 {
+		t.Errorf("CostPer1KTokens with a per-model override = %v, want 0.001", got)
+	}
+	if got := CostPer1KTokens("llama-3-70b"); got != 0.002 {
+		t.Errorf("CostPer1KTokens with a global override = %v, want 0.002", got)
+	}
+	if got := EstimateCostUSD("llama-3-70b", 5000); math.Abs(got-0.01) > 1e-9 {
+		t.Errorf("EstimateCostUSD = %v, want 0.01 for 5,000 tokens at $0.002 per 1K", got)
+	}
+}
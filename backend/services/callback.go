@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// Delivery states recorded in scans.callback_status
+const (
+	CallbackStatusPending   = "pending"   // The scan hasn't finished, or no attempt has completed yet
+	CallbackStatusDelivered = "delivered" // The callback URL accepted the payload
+	CallbackStatusFailed    = "failed"    // The latest attempt failed; it is retried until attempts run out
+)
+
+// ErrCallbacksDisabled is returned when a callback is requested but SCAN_CALLBACK_SECRET is not set
+var ErrCallbacksDisabled = errors.New("scan callbacks are not configured on this server")
+
+// ErrInvalidCallbackURL is wrapped by every callback URL validation error
+var ErrInvalidCallbackURL = errors.New("invalid callback URL")
+
+// CallbackSecret returns the key callback payloads are signed with, from SCAN_CALLBACK_SECRET
+// The API server and the worker must share it; without it callbacks are refused
+func CallbackSecret() string {
+	return os.Getenv("SCAN_CALLBACK_SECRET")
+}
+
+// ScanResultsURL returns the public results endpoint of a scan, under API_PUBLIC_URL
+func ScanResultsURL(scanID string) string {
+	baseURL := strings.TrimRight(os.Getenv("API_PUBLIC_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	return fmt.Sprintf("%s/scan/%s/results", baseURL, scanID)
+}
+
+// callbackResolver resolves callback and webhook hosts during validation; tests replace it to avoid real lookups
+var callbackResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+} = net.DefaultResolver
+
+// sharedAddressSpace is the carrier-grade NAT range, private in practice though net.IP doesn't say so
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether an address is routable on the internet, so posting to it can't reach
+// the server's own network
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// invalidCallbackURL formats a validation error that matches ErrInvalidCallbackURL
+func invalidCallbackURL(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCallbackURL, fmt.Sprintf(format, args...))
+}
+
+// ValidateCallbackURL checks that a callback URL is an https URL whose host resolves only to public addresses
+// The delivery refuses private addresses again when it connects, since DNS answers can change in between
+func ValidateCallbackURL(ctx context.Context, callbackURL string) error {
+	return validatePublicHTTPSURL(ctx, callbackURL, invalidCallbackURL)
+}
+
+// validatePublicHTTPSURL checks that a URL the server will post to is an https URL whose host resolves only to
+// public addresses, formatting any problem with invalid
+func validatePublicHTTPSURL(ctx context.Context, rawURL string, invalid func(format string, args ...any) error) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return invalid("must be an absolute URL")
+	}
+	if parsed.Scheme != "https" {
+		return invalid("must use https")
+	}
+	if parsed.User != nil {
+		return invalid("must not contain credentials")
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return invalid("%s is not a public address", host)
+		}
+		return nil
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return invalid("%s is not a public host", host)
+	}
+
+	addrs, err := callbackResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return invalid("host %s could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return invalid("host %s resolves to %s, which is not a public address", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// refusePrivateDial returns a net.Dialer Control function that stops connections to non-public addresses,
+// formatting the refusal with invalid
+func refusePrivateDial(invalid func(format string, args ...any) error) func(network, address string, _ syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+			return invalid("refusing to connect to %s", host)
+		}
+		return nil
+	}
+}
+
+// newCallbackHTTPClient returns a client that only connects to public addresses and doesn't follow redirects
+func newCallbackHTTPClient() *http.Client {
+	return newPublicHTTPClient(15*time.Second, invalidCallbackURL)
+}
+
+// newPublicHTTPClient returns a client that only connects to public addresses and doesn't follow redirects,
+// refusing other addresses with invalid. It ignores proxy settings, since a proxy would make the connection
+// and skip the address check
+func newPublicHTTPClient(timeout time.Duration, invalid func(format string, args ...any) error) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: refusePrivateDial(invalid)}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// ScanCallbackSummary counts a finished scan's findings, leaving out excluded ones
+type ScanCallbackSummary struct {
+	Total      int            `json:"total"`
+	BySeverity map[string]int `json:"by_severity"`
+}
+
+// ScanCallbackPayload is the JSON posted to a scan's callback URL once the scan has finished
+type ScanCallbackPayload struct {
+	Event        string              `json:"event"` // "scan." followed by the final status, e.g. "scan.completed"
+	ScanID       string              `json:"scan_id"`
+	RepositoryID string              `json:"repository_id"`
+	Status       string              `json:"status"`
+	Message      string              `json:"message,omitempty"`
+	Summary      ScanCallbackSummary `json:"summary"`
+	ResultsURL   string              `json:"results_url"`
+	Timestamp    time.Time           `json:"timestamp"`
+}
+
+// ScanCallbackService delivers finished scans to the callback URLs they were started with
+type ScanCallbackService interface {
+	// Summary counts the scan's findings by severity
+	Summary(ctx context.Context, scanID string) (ScanCallbackSummary, error)
+
+	// Deliver posts the payload to the callback URL, signed in the X-SAST-Signature header like webhooks
+	// Any response other than 2xx is an error
+	Deliver(ctx context.Context, callbackURL string, payload *ScanCallbackPayload) error
+
+	// RecordDelivery stores the outcome of a delivery attempt on the scan; a nil deliveryErr means delivered
+	RecordDelivery(ctx context.Context, scanID string, attempt int, deliveryErr error) error
+}
+
+// NewScanCallbackService creates a callback service that signs with CallbackSecret
+func NewScanCallbackService(dbQueries *db.Queries) ScanCallbackService {
+	return &scanCallbackService{
+		db:         dbQueries,
+		httpClient: newCallbackHTTPClient(),
+		secret:     CallbackSecret(),
+	}
+}
+
+// scanCallbackService implements the ScanCallbackService interface
+type scanCallbackService struct {
+	db         *db.Queries
+	httpClient *http.Client
+	secret     string
+}
+
+func (s *scanCallbackService) Summary(ctx context.Context, scanID string) (ScanCallbackSummary, error) {
+	summary := ScanCallbackSummary{BySeverity: map[string]int{}}
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return summary, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT severity, COUNT(*) FROM vulnerabilities WHERE scan_id = $1 AND NOT excluded GROUP BY severity`,
+		scanID)
+	if err != nil {
+		return summary, fmt.Errorf("failed to count vulnerabilities for scan %s: %w", scanID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var severity string
+		var count int
+		if err := rows.Scan(&severity, &count); err != nil {
+			return summary, fmt.Errorf("failed to scan severity count: %w", err)
+		}
+		summary.BySeverity[severity] = count
+		summary.Total += count
+	}
+	if err := rows.Err(); err != nil {
+		return summary, fmt.Errorf("error while iterating over severity counts: %w", err)
+	}
+	return summary, nil
+}
+
+func (s *scanCallbackService) Deliver(ctx context.Context, callbackURL string, payload *ScanCallbackPayload) error {
+	if s.secret == "" {
+		return ErrCallbacksDisabled
+	}
+	parsed, err := url.Parse(callbackURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return invalidCallbackURL("must be an absolute https URL")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode callback payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build callback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SAST-Event", payload.Event)
+	req.Header.Set("X-SAST-Signature", SignWebhookPayload(s.secret, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *scanCallbackService) RecordDelivery(ctx context.Context, scanID string, attempt int, deliveryErr error) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	status := CallbackStatusDelivered
+	var errorMessage *string
+	if deliveryErr != nil {
+		status = CallbackStatusFailed
+		message := deliveryErr.Error()
+		errorMessage = &message
+	}
+
+	_, err := sqlDB.ExecContext(ctx,
+		`UPDATE scans SET callback_status = $2, callback_attempts = $3, callback_error = $4,
+			callback_delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE callback_delivered_at END,
+			updated_at = NOW()
+		WHERE id = $1`,
+		scanID, status, attempt, errorMessage)
+	if err != nil {
+		return fmt.Errorf("failed to record callback delivery for scan %s: %w", scanID, err)
+	}
+	return nil
+}
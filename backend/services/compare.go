@@ -0,0 +1,58 @@
+package services
+
+// FindingMatch pairs the same finding as reported by two scans
+type FindingMatch struct {
+	Fingerprint string         `json:"fingerprint"`
+	A           *Vulnerability `json:"a"`
+	B           *Vulnerability `json:"b"`
+}
+
+// FindingComparison is a three-way diff of two scans' findings keyed by fingerprint
+type FindingComparison struct {
+	OnlyInA []*Vulnerability `json:"only_in_a"`
+	OnlyInB []*Vulnerability `json:"only_in_b"`
+	InBoth  []FindingMatch   `json:"in_both"`
+}
+
+// CompareFindings diffs two sets of findings by fingerprint
+// Findings that share a fingerprint within one scan are matched one-to-one, so duplicates aren't collapsed
+func CompareFindings(a, b []*Vulnerability) *FindingComparison {
+	return matchFindings(a, b, (*Vulnerability).Fingerprint)
+}
+
+// matchFindings diffs two sets of findings, pairing findings whose keys are equal one-to-one
+func matchFindings(a, b []*Vulnerability, key func(*Vulnerability) string) *FindingComparison {
+	comparison := &FindingComparison{
+		OnlyInA: []*Vulnerability{},
+		OnlyInB: []*Vulnerability{},
+		InBoth:  []FindingMatch{},
+	}
+
+	// Index B by key, keeping order so the output is stable
+	unmatchedB := make(map[string][]*Vulnerability)
+	for _, vuln := range b {
+		k := key(vuln)
+		unmatchedB[k] = append(unmatchedB[k], vuln)
+	}
+
+	for _, vuln := range a {
+		k := key(vuln)
+		if candidates := unmatchedB[k]; len(candidates) > 0 {
+			comparison.InBoth = append(comparison.InBoth, FindingMatch{Fingerprint: k, A: vuln, B: candidates[0]})
+			unmatchedB[k] = candidates[1:]
+			continue
+		}
+		comparison.OnlyInA = append(comparison.OnlyInA, vuln)
+	}
+
+	// Whatever B findings were not consumed above exist only in B
+	for _, vuln := range b {
+		k := key(vuln)
+		if candidates := unmatchedB[k]; len(candidates) > 0 && candidates[0] == vuln {
+			comparison.OnlyInB = append(comparison.OnlyInB, vuln)
+			unmatchedB[k] = candidates[1:]
+		}
+	}
+
+	return comparison
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// modelFindingsTransport answers each model request with the findings stubbed for the requested model
+type modelFindingsTransport struct {
+	findings map[string]string // model -> JSON array of vulnerabilities
+}
+
+func (m *modelFindingsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(`{"vulnerabilities": ` + m.findings[payload.Model] + `}`)
+	if err != nil {
+		return nil, err
+	}
+	body := `{"choices": [{"message": {"role": "assistant", "content": ` + string(content) + `}}]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestCompareFindingsAcrossTwoModelRuns(t *testing.T) {
+	root := writeFixtureTree(t, []string{"main.go", "handler.go"})
+	useModelTransport(t, &modelFindingsTransport{findings: map[string]string{
+		"model-a": `[{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "High"},
+			{"vulnerability_type": "Broken Access Control", "line_start": 2, "line_end": 2, "severity": "Medium"}]`,
+		"model-b": `[{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "Critical"},
+			{"vulnerability_type": "Cryptographic Failures", "line_start": 3, "line_end": 3, "severity": "Low"}]`,
+	}})
+
+	scanner := NewScannerService(nil)
+	runs := make(map[string][]*Vulnerability)
+	for _, model := range []string{"model-a", "model-b"} {
+		result, err := scanner.ScanRepository(context.Background(), root, &ScanOptions{
+			VulnerabilityTypes: []VulnerabilityType{Injection, BrokenAccessControl, CryptographicFailures},
+			FileExtensions:     []string{".go"},
+			Model:              model,
+		})
+		if err != nil {
+			t.Fatalf("ScanRepository with %s returned error: %v", model, err)
+		}
+		runs[model] = result.Vulnerabilities
+	}
+
+	comparison := CompareFindings(runs["model-a"], runs["model-b"])
+
+	if len(comparison.InBoth) != 2 {
+		t.Fatalf("%d findings in both, want the Injection finding in each file", len(comparison.InBoth))
+	}
+	for _, match := range comparison.InBoth {
+		if match.A.Type != Injection || match.B.Type != Injection || match.A.FilePath != match.B.FilePath {
+			t.Errorf("matched %s in %s with %s in %s", match.A.Type, match.A.FilePath, match.B.Type, match.B.FilePath)
+		}
+		if match.A.Severity != "High" || match.B.Severity != "Critical" {
+			t.Errorf("match kept severities %s/%s, want each model's own", match.A.Severity, match.B.Severity)
+		}
+	}
+	assertAllOfType(t, "only in A", comparison.OnlyInA, BrokenAccessControl)
+	assertAllOfType(t, "only in B", comparison.OnlyInB, CryptographicFailures)
+}
+
+func TestCompareFindingsMatchesDuplicatesOneToOne(t *testing.T) {
+	finding := func() *Vulnerability {
+		return &Vulnerability{Type: Injection, FilePath: "main.go", LineStart: 4, LineEnd: 4, Code: "db.Query(q)"}
+	}
+	a := []*Vulnerability{finding(), finding()}
+	b := []*Vulnerability{finding()}
+
+	comparison := CompareFindings(a, b)
+	if len(comparison.InBoth) != 1 || len(comparison.OnlyInA) != 1 || len(comparison.OnlyInB) != 0 {
+		t.Errorf("got %d in both, %d only in A, %d only in B; want 1, 1, 0",
+			len(comparison.InBoth), len(comparison.OnlyInA), len(comparison.OnlyInB))
+	}
+
+	empty := CompareFindings(nil, nil)
+	if empty.OnlyInA == nil || empty.OnlyInB == nil || empty.InBoth == nil {
+		t.Error("an empty comparison should encode as empty lists, not null")
+	}
+}
+
+// assertAllOfType checks that a side of a comparison holds one finding per fixture file, all of one type
+func assertAllOfType(t *testing.T, side string, vulns []*Vulnerability, want VulnerabilityType) {
+	t.Helper()
+	if len(vulns) != 2 {
+		t.Fatalf("%d findings %s, want 2", len(vulns), side)
+	}
+	for _, vuln := range vulns {
+		if vuln.Type != want {
+			t.Errorf("%s finding in %s has type %s, want %s", side, vuln.FilePath, vuln.Type, want)
+		}
+	}
+}
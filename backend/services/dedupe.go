@@ -0,0 +1,159 @@
+package services
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultDedupLineTolerance is how many lines apart two findings' ranges may be and still count as the same
+// issue; 0 means they must share a line
+const DefaultDedupLineTolerance = 0
+
+// maxDedupLineTolerance stops a misconfigured tolerance from collapsing every finding of a type in a file
+const maxDedupLineTolerance = 50
+
+// DedupLineToleranceFromEnv reads the overlap tolerance from SCAN_DEDUP_LINE_TOLERANCE
+// Unset or invalid values give DefaultDedupLineTolerance; larger values are capped
+func DedupLineToleranceFromEnv() int {
+	value := os.Getenv("SCAN_DEDUP_LINE_TOLERANCE")
+	if value == "" {
+		return DefaultDedupLineTolerance
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		logger.Warn("Invalid SCAN_DEDUP_LINE_TOLERANCE value, using default",
+			zap.String("value", value), zap.Int("default", DefaultDedupLineTolerance))
+		return DefaultDedupLineTolerance
+	}
+	return min(parsed, maxDedupLineTolerance)
+}
+
+// DedupeFindings collapses findings that report the same issue twice: same file, same type, and line ranges
+// that overlap or lie within tolerance lines of each other. Chains of overlapping findings collapse into one.
+// The merged finding keeps the ID and snippet of its most severe report, spans the union of the line ranges,
+// and carries every distinct description, remediation, and reference. The result is ordered by file, line, and type
+func DedupeFindings(vulns []*Vulnerability, tolerance int) []*Vulnerability {
+	if len(vulns) < 2 {
+		return vulns
+	}
+	tolerance = max(tolerance, 0)
+
+	sorted := make([]*Vulnerability, len(vulns))
+	copy(sorted, vulns)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.LineStart < b.LineStart
+	})
+
+	var deduped []*Vulnerability
+	var group []*Vulnerability
+	groupEnd := 0
+	for _, vuln := range sorted {
+		if len(group) > 0 {
+			first := group[0]
+			if first.FilePath == vuln.FilePath && first.Type == vuln.Type && vuln.LineStart <= groupEnd+tolerance {
+				group = append(group, vuln)
+				groupEnd = max(groupEnd, findingEnd(vuln))
+				continue
+			}
+			deduped = append(deduped, mergeFindings(group))
+		}
+		group = []*Vulnerability{vuln}
+		groupEnd = findingEnd(vuln)
+	}
+	deduped = append(deduped, mergeFindings(group))
+
+	sort.SliceStable(deduped, func(i, j int) bool {
+		a, b := deduped[i], deduped[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.LineStart != b.LineStart {
+			return a.LineStart < b.LineStart
+		}
+		return a.Type < b.Type
+	})
+	return deduped
+}
+
+// findingEnd returns the last line of a finding, treating an end before the start as a single line
+func findingEnd(vuln *Vulnerability) int {
+	return max(vuln.LineEnd, vuln.LineStart)
+}
+
+// mergeFindings combines reports of the same issue into one finding, built on the most severe report
+// (the first of them on a tie). The reports themselves are left unchanged
+func mergeFindings(group []*Vulnerability) *Vulnerability {
+	if len(group) == 1 {
+		return group[0]
+	}
+
+	kept := group[0]
+	for _, vuln := range group[1:] {
+		if SeverityRank(vuln.Severity) > SeverityRank(kept.Severity) {
+			kept = vuln
+		}
+	}
+
+	merged := *kept
+	var descriptions, remediations, references []string
+	for _, vuln := range group {
+		merged.LineStart = min(merged.LineStart, vuln.LineStart)
+		merged.LineEnd = max(merged.LineEnd, findingEnd(vuln))
+		if vuln.Confidence != nil && (merged.Confidence == nil || *vuln.Confidence > *merged.Confidence) {
+			merged.Confidence = vuln.Confidence
+		}
+		descriptions = appendDistinct(descriptions, vuln.Description)
+		remediations = appendDistinct(remediations, vuln.Remediation)
+		for _, reference := range vuln.References {
+			references = appendDistinct(references, reference)
+		}
+		if merged.CWE == "" {
+			merged.CWE = vuln.CWE
+		}
+	}
+	merged.References = references
+	// Lead with the kept report's own text, which describes the most severe reading of the issue
+	merged.Description = strings.Join(leadWith(descriptions, kept.Description), "\n\n")
+	merged.Remediation = strings.Join(leadWith(remediations, kept.Remediation), "\n\n")
+	return &merged
+}
+
+// appendDistinct appends the trimmed text unless it is empty or already present
+func appendDistinct(texts []string, text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return texts
+	}
+	for _, existing := range texts {
+		if existing == text {
+			return texts
+		}
+	}
+	return append(texts, text)
+}
+
+// leadWith moves first to the front of texts, keeping the order of the rest
+func leadWith(texts []string, first string) []string {
+	first = strings.TrimSpace(first)
+	ordered := make([]string, 0, len(texts))
+	for _, text := range texts {
+		if text == first {
+			ordered = append([]string{text}, ordered...)
+		} else {
+			ordered = append(ordered, text)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDedupeFindings(t *testing.T) {
+	confidence := func(value float64) *float64 { return &value }
+	tests := []struct {
+		name      string
+		tolerance int
+		findings  []*Vulnerability
+		want      []*Vulnerability
+	}{
+		{
+			name: "exact duplicates",
+			findings: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High", Description: "SQL built from input"},
+				{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High", Description: "SQL built from input"},
+			},
+			want: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High", Description: "SQL built from input"},
+			},
+		},
+		{
+			name: "overlap keeps the highest severity and merges the text",
+			findings: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "Medium", Description: "String concatenation in query",
+					Remediation: "Use placeholders", Confidence: confidence(0.9)},
+				{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 12, LineEnd: 15, Severity: "Critical", Description: "SQL injection via id",
+					Remediation: "Use placeholders", Code: "db.Query(q)", Confidence: confidence(0.6)},
+			},
+			want: []*Vulnerability{
+				{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 15, Severity: "Critical",
+					Description: "SQL injection via id\n\nString concatenation in query", Remediation: "Use placeholders",
+					Code: "db.Query(q)", Confidence: confidence(0.9)},
+			},
+		},
+		{
+			name: "chain of overlaps collapses into one",
+			findings: []*Vulnerability{
+				{ID: "c", Type: Injection, FilePath: "db.go", LineStart: 14, LineEnd: 20, Severity: "Low"},
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 1, LineEnd: 8, Severity: "Low"},
+				{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 8, LineEnd: 14, Severity: "Low"},
+			},
+			want: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 1, LineEnd: 20, Severity: "Low"},
+			},
+		},
+		{
+			name: "adjacent lines are separate issues without tolerance",
+			findings: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 10, Severity: "High"},
+				{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 12, LineEnd: 12, Severity: "High"},
+			},
+			want: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 10, Severity: "High"},
+				{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 12, LineEnd: 12, Severity: "High"},
+			},
+		},
+		{
+			name:      "near duplicates within the tolerance",
+			tolerance: 2,
+			findings: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 10, Severity: "High"},
+				{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 12, LineEnd: 12, Severity: "High"},
+				{ID: "c", Type: Injection, FilePath: "db.go", LineStart: 20, LineEnd: 20, Severity: "High"},
+			},
+			want: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High"},
+				{ID: "c", Type: Injection, FilePath: "db.go", LineStart: 20, LineEnd: 20, Severity: "High"},
+			},
+		},
+		{
+			name: "different types and files are kept apart",
+			findings: []*Vulnerability{
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High"},
+				{ID: "b", Type: CryptographicFailures, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High"},
+				{ID: "c", Type: Injection, FilePath: "api.go", LineStart: 10, LineEnd: 12, Severity: "High"},
+			},
+			want: []*Vulnerability{
+				{ID: "c", Type: Injection, FilePath: "api.go", LineStart: 10, LineEnd: 12, Severity: "High"},
+				{ID: "b", Type: CryptographicFailures, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High"},
+				{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 12, Severity: "High"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DedupeFindings(tt.findings, tt.tolerance)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d findings, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.ID != w.ID || g.Type != w.Type || g.FilePath != w.FilePath || g.LineStart != w.LineStart ||
+					g.LineEnd != w.LineEnd || g.Severity != w.Severity || g.Description != w.Description ||
+					g.Remediation != w.Remediation || g.Code != w.Code {
+					t.Errorf("finding %d = %+v, want %+v", i, *g, *w)
+				}
+				if (g.Confidence == nil) != (w.Confidence == nil) || (g.Confidence != nil && *g.Confidence != *w.Confidence) {
+					t.Errorf("finding %d confidence = %v, want %v", i, g.Confidence, w.Confidence)
+				}
+			}
+		})
+	}
+}
+
+func TestDedupeFindingsLeavesTheReportsUnchanged(t *testing.T) {
+	first := &Vulnerability{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 1, LineEnd: 2, Severity: "Low", Description: "one"}
+	second := &Vulnerability{ID: "b", Type: Injection, FilePath: "db.go", LineStart: 2, LineEnd: 3, Severity: "High", Description: "two"}
+	DedupeFindings([]*Vulnerability{first, second}, 0)
+	if second.LineStart != 2 || second.Description != "two" || first.Description != "one" {
+		t.Errorf("inputs were modified: %+v, %+v", *first, *second)
+	}
+}
+
+func TestDedupLineToleranceFromEnv(t *testing.T) {
+	tests := map[string]int{"": 0, "3": 3, "0": 0, "-1": 0, "lots": 0, "1000": maxDedupLineTolerance}
+	for value, want := range tests {
+		t.Setenv("SCAN_DEDUP_LINE_TOLERANCE", value)
+		if got := DedupLineToleranceFromEnv(); got != want {
+			t.Errorf("DedupLineToleranceFromEnv() with %q = %d, want %d", value, got, want)
+		}
+	}
+}
+
+// duplicateModelTransport answers every model request with the same issue reported twice and one other issue
+type duplicateModelTransport struct{}
+
+func (duplicateModelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := `{"choices": [{"message": {"role": "assistant", "content": ` +
+		`"{\"vulnerabilities\": [` +
+		`{\"vulnerability_type\": \"Injection\", \"line_start\": 1, \"line_end\": 1, \"severity\": \"High\", \"description\": \"Query built from input\"}, ` +
+		`{\"vulnerability_type\": \"Injection\", \"line_start\": 1, \"line_end\": 1, \"severity\": \"Critical\", \"description\": \"SQL injection\"}, ` +
+		`{\"vulnerability_type\": \"Broken Access Control\", \"line_start\": 1, \"line_end\": 1, \"severity\": \"Low\"}]}"}}]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestScanRepositoryMergesDuplicateFindings(t *testing.T) {
+	root := writeFixtureTree(t, []string{"db.go"})
+	useModelTransport(t, duplicateModelTransport{})
+
+	var recorded []*Vulnerability
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection, BrokenAccessControl},
+		FileExtensions:     []string{".go"},
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			recorded = vulnerabilities
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	if len(result.Vulnerabilities) != 2 || result.DuplicatesMerged != 1 {
+		t.Fatalf("got %d findings with %d merged, want 2 with 1 merged", len(result.Vulnerabilities), result.DuplicatesMerged)
+	}
+	// What gets stored is deduplicated too, so the scan's finding count matches
+	if len(recorded) != 2 {
+		t.Errorf("recorded %d findings, want 2", len(recorded))
+	}
+	for _, vuln := range result.Vulnerabilities {
+		if vuln.Type == Injection && (vuln.Severity != "Critical" || vuln.Description != "SQL injection\n\nQuery built from input") {
+			t.Errorf("merged finding = %s: %q, want Critical with both descriptions", vuln.Severity, vuln.Description)
+		}
+	}
+}
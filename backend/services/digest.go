@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// Notification frequencies stored in users.notification_frequency
+const (
+	NotificationFrequencyImmediate = "immediate" // An email as each scan finishes (default)
+	NotificationFrequencyDaily     = "daily"     // One digest of the day's scans
+	NotificationFrequencyWeekly    = "weekly"    // One digest of the week's scans
+)
+
+// ValidNotificationFrequency reports whether frequency is one a user may choose
+func ValidNotificationFrequency(frequency string) bool {
+	switch frequency {
+	case NotificationFrequencyImmediate, NotificationFrequencyDaily, NotificationFrequencyWeekly:
+		return true
+	default:
+		return false
+	}
+}
+
+// DigestPeriod returns how often a digest is sent at the given frequency; 0 for immediate emails
+func DigestPeriod(frequency string) time.Duration {
+	switch frequency {
+	case NotificationFrequencyDaily:
+		return 24 * time.Hour
+	case NotificationFrequencyWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// ScanSummary is one finished scan as listed in a digest email
+type ScanSummary struct {
+	ScanID         string
+	RepositoryID   string
+	RepositoryName string // owner/name
+	Status         string // completed, or time_budget_reached, budget_exceeded, or completed_with_errors for partial results
+	VulnCount      int    // Findings not excluded by path rules or suppressed
+	CompletedAt    time.Time
+}
+
+// DigestRecipient is a user whose digest is due, with the scans it covers
+type DigestRecipient struct {
+	UserID string
+	Email  string
+	Since  time.Time // Scans completed after this are included
+	Scans  []ScanSummary
+}
+
+// DigestService finds which users are due a scan digest
+type DigestService interface {
+	// DueDigests returns the users on a daily or weekly digest whose period has passed as of now
+	// Users who turned notifications off are left out. Each digest covers the scans of the user's
+	// repositories completed since the previous digest, or over the last period for a first digest
+	DueDigests(ctx context.Context, now time.Time) ([]*DigestRecipient, error)
+
+	// MarkDigestSent records that a user's digest was handled, so the next one starts from sentAt
+	MarkDigestSent(ctx context.Context, userID string, sentAt time.Time) error
+}
+
+// NewDigestService creates a new digest service instance
+func NewDigestService(dbQueries *db.Queries) DigestService {
+	return &digestService{
+		db: dbQueries,
+	}
+}
+
+// digestService implements the DigestService interface
+type digestService struct {
+	db *db.Queries
+}
+
+func (s *digestService) DueDigests(ctx context.Context, now time.Time) ([]*DigestRecipient, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id::text, email, notification_frequency, last_digest_sent_at FROM users
+		WHERE receive_notifications AND notification_frequency IN ($1, $2)
+			AND email IS NOT NULL AND email != ''`,
+		NotificationFrequencyDaily, NotificationFrequencyWeekly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest users: %w", err)
+	}
+
+	var due []*DigestRecipient
+	for rows.Next() {
+		var recipient DigestRecipient
+		var frequency string
+		var lastSent sql.NullTime
+		if err := rows.Scan(&recipient.UserID, &recipient.Email, &frequency, &lastSent); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan digest user row: %w", err)
+		}
+
+		period := DigestPeriod(frequency)
+		if lastSent.Valid {
+			if now.Sub(lastSent.Time) < period {
+				continue
+			}
+			recipient.Since = lastSent.Time
+		} else {
+			recipient.Since = now.Add(-period)
+		}
+		due = append(due, &recipient)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over digest user rows: %w", err)
+	}
+
+	for _, recipient := range due {
+		if recipient.Scans, err = s.completedScans(ctx, sqlDB, recipient.UserID, recipient.Since, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return due, nil
+}
+
+// completedScans lists the finished scans of a user's repositories completed in (since, until]
+func (s *digestService) completedScans(ctx context.Context, sqlDB *sql.DB, userID string, since, until time.Time) ([]ScanSummary, error) {
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT s.id::text, r.id::text, r.owner || '/' || r.name, s.status, s.completed_at,
+			(SELECT COUNT(*) FROM vulnerabilities v WHERE v.scan_id = s.id AND NOT v.excluded AND `+notSuppressedSQL+`)
+		FROM scans s JOIN repositories r ON r.id = s.repository_id
+		WHERE r.created_by::text = $1 AND s.status IN ($2, $3, $4, $5)
+			AND s.completed_at > $6 AND s.completed_at <= $7
+		ORDER BY s.completed_at`,
+		userID, ScanStatusCompleted, ScanStatusTimeBudgetReached, ScanStatusBudgetExceeded, ScanStatusCompletedWithErrors, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completed scans for user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var scans []ScanSummary
+	for rows.Next() {
+		var scan ScanSummary
+		if err := rows.Scan(&scan.ScanID, &scan.RepositoryID, &scan.RepositoryName, &scan.Status,
+			&scan.CompletedAt, &scan.VulnCount); err != nil {
+			return nil, fmt.Errorf("failed to scan digest scan row: %w", err)
+		}
+		scans = append(scans, scan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over digest scan rows: %w", err)
+	}
+
+	return scans, nil
+}
+
+func (s *digestService) MarkDigestSent(ctx context.Context, userID string, sentAt time.Time) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	_, err := sqlDB.ExecContext(ctx,
+		`UPDATE users SET last_digest_sent_at = $1 WHERE id::text = $2`,
+		sentAt, userID)
+	if err != nil {
+		return fmt.Errorf("failed to record digest for user %s: %w", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderDigestEmail(t *testing.T) {
+	completed := time.Date(2026, 4, 2, 9, 30, 0, 0, time.FixedZone("CEST", 2*3600))
+	html, err := RenderDigestEmail([]ScanSummary{
+		{RepositoryID: "repo-1", RepositoryName: "octo/api", Status: ScanStatusCompleted, VulnCount: 3, CompletedAt: completed},
+		{RepositoryID: "repo-2", RepositoryName: "octo/<web>", Status: ScanStatusTimeBudgetReached, VulnCount: 1, CompletedAt: completed.Add(time.Hour)},
+		{RepositoryID: "repo-3", RepositoryName: "octo/cli", Status: ScanStatusCompleted, CompletedAt: completed.Add(2 * time.Hour)},
+	}, "https://sast.example.com")
+	if err != nil {
+		t.Fatalf("RenderDigestEmail returned error: %v", err)
+	}
+
+	for _, want := range []string{
+		"3 scans of your repositories finished",
+		"<strong>4 potential security issues</strong>",
+		"octo/api",
+		"octo/&lt;web&gt; (partial)",
+		"octo/cli",
+		`href="https://sast.example.com/dashboard/repos/repo-1"`,
+		`href="https://sast.example.com/dashboard/repos/repo-3"`,
+		"Apr 2, 2026 07:30 UTC",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("digest lacks %q", want)
+		}
+	}
+	if strings.Contains(html, "octo/<web>") {
+		t.Error("repository name is not HTML-escaped")
+	}
+	// Scans are listed in the order given
+	if strings.Index(html, "octo/api") > strings.Index(html, "octo/cli") {
+		t.Error("scans are out of order")
+	}
+
+	single, err := RenderDigestEmail([]ScanSummary{{RepositoryName: "octo/api", VulnCount: 1}}, "https://sast.example.com")
+	if err != nil {
+		t.Fatalf("RenderDigestEmail returned error: %v", err)
+	}
+	if !strings.Contains(single, "1 scan of your repositories") || !strings.Contains(single, "1 potential security issue</strong>") {
+		t.Error("single-scan digest isn't worded in the singular")
+	}
+}
+
+func TestSendDigestEmail(t *testing.T) {
+	t.Setenv("DASHBOARD_URL", "https://sast.example.com")
+	sender := &recordingSender{}
+	service := NewEmailServiceWithSender(sender, "scanner@example.com", nil)
+
+	if err := service.SendDigestEmail("dev@example.com", nil); err == nil {
+		t.Error("SendDigestEmail sent a digest without scans")
+	}
+	scans := []ScanSummary{{RepositoryID: "repo-1", RepositoryName: "octo/api"}, {RepositoryID: "repo-2", RepositoryName: "octo/web"}}
+	if err := service.SendDigestEmail("dev@example.com", scans); err != nil {
+		t.Fatalf("SendDigestEmail returned error: %v", err)
+	}
+
+	if len(sender.messages) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sender.messages))
+	}
+	message := sender.messages[0]
+	if message.Subject != "Security Scan Digest - 2 scans" || message.To[0] != "dev@example.com" {
+		t.Errorf("message = %+v, want a 2-scan digest for the user", message)
+	}
+	if !strings.Contains(message.HTML, "https://sast.example.com/dashboard/repos/repo-2") {
+		t.Error("digest doesn't link into DASHBOARD_URL")
+	}
+}
+
+func TestNotificationFrequencies(t *testing.T) {
+	tests := []struct {
+		frequency  string
+		wantValid  bool
+		wantPeriod time.Duration
+	}{
+		{frequency: NotificationFrequencyImmediate, wantValid: true},
+		{frequency: NotificationFrequencyDaily, wantValid: true, wantPeriod: 24 * time.Hour},
+		{frequency: NotificationFrequencyWeekly, wantValid: true, wantPeriod: 7 * 24 * time.Hour},
+		{frequency: "hourly"},
+		{frequency: "Daily"},
+	}
+
+	for _, tt := range tests {
+		if got := ValidNotificationFrequency(tt.frequency); got != tt.wantValid {
+			t.Errorf("ValidNotificationFrequency(%q) = %v, want %v", tt.frequency, got, tt.wantValid)
+		}
+		if got := DigestPeriod(tt.frequency); got != tt.wantPeriod {
+			t.Errorf("DigestPeriod(%q) = %v, want %v", tt.frequency, got, tt.wantPeriod)
+		}
+	}
+}
+
+func TestDueDigests(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// createDigestUser adds a user with a repository scanned once, an hour ago, with one finding
+	createDigestUser := func(frequency string, receive bool, lastSent any) (userID, scanID string) {
+		t.Helper()
+		userID = createTestUser(t, queries)
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`UPDATE users SET notification_frequency = $2, receive_notifications = $3, last_digest_sent_at = $4 WHERE id = $1`,
+			userID, frequency, receive, lastSent); err != nil {
+			t.Fatalf("set preferences: %v", err)
+		}
+		repoID, scanID := createTestScan(t, queries)
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`UPDATE repositories SET created_by = $1 WHERE id = $2`, userID, repoID); err != nil {
+			t.Fatalf("set repository owner: %v", err)
+		}
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`UPDATE scans SET completed_at = $1 WHERE id = $2`, now.Add(-time.Hour), scanID); err != nil {
+			t.Fatalf("set completion time: %v", err)
+		}
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description)
+			VALUES ($1, 'Injection', 'main.go', 1, 1, 'High', 'finding')`, scanID); err != nil {
+			t.Fatalf("insert finding: %v", err)
+		}
+		return userID, scanID
+	}
+
+	firstDaily, firstDailyScan := createDigestUser(NotificationFrequencyDaily, true, nil)
+	dueWeekly, _ := createDigestUser(NotificationFrequencyWeekly, true, now.Add(-8*24*time.Hour))
+	notYetWeekly, _ := createDigestUser(NotificationFrequencyWeekly, true, now.Add(-2*24*time.Hour))
+	immediate, _ := createDigestUser(NotificationFrequencyImmediate, true, nil)
+	optedOut, _ := createDigestUser(NotificationFrequencyDaily, false, nil)
+
+	service := NewDigestService(queries)
+	due, err := service.DueDigests(ctx, now)
+	if err != nil {
+		t.Fatalf("DueDigests returned error: %v", err)
+	}
+
+	// Other tests' users may also be due, so only this test's users are checked
+	byUser := map[string]*DigestRecipient{}
+	for _, recipient := range due {
+		byUser[recipient.UserID] = recipient
+	}
+	for _, userID := range []string{notYetWeekly, immediate, optedOut} {
+		if byUser[userID] != nil {
+			t.Errorf("user %s got a digest, want none", userID)
+		}
+	}
+	if byUser[dueWeekly] == nil {
+		t.Error("weekly user whose week has passed got no digest")
+	}
+	first := byUser[firstDaily]
+	if first == nil {
+		t.Fatal("daily user without a previous digest got no digest")
+	}
+	if !first.Since.Equal(now.Add(-24 * time.Hour)) {
+		t.Errorf("first digest covers scans since %v, want the last day", first.Since)
+	}
+	if len(first.Scans) != 1 || first.Scans[0].ScanID != firstDailyScan || first.Scans[0].VulnCount != 1 {
+		t.Errorf("first digest scans = %+v, want the one scan with its finding", first.Scans)
+	}
+
+	// Once sent, the digest isn't due again until its next period
+	if err := service.MarkDigestSent(ctx, firstDaily, now); err != nil {
+		t.Fatalf("MarkDigestSent returned error: %v", err)
+	}
+	due, err = service.DueDigests(ctx, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DueDigests returned error: %v", err)
+	}
+	for _, recipient := range due {
+		if recipient.UserID == firstDaily {
+			t.Error("digest is due again an hour after it was sent")
+		}
+	}
+}
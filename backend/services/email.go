@@ -2,11 +2,10 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
-	"net/smtp"
 	"os"
-	"strings"
 
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
@@ -15,23 +14,33 @@ import (
 
 // EmailService handles sending email notifications
 type EmailService struct {
-	smtpServer   string
-	smtpPort     string
-	smtpUsername string
-	smtpPassword string
-	fromEmail    string
-	dbQueries    *db.Queries
+	sender    EmailSender // Provider the email is delivered through
+	fromEmail string
+	dbQueries *db.Queries
 }
 
 // NewEmailService creates a new instance of EmailService
-func NewEmailService(dbQueries *db.Queries) *EmailService {
+// The provider is chosen by EMAIL_PROVIDER (SMTP by default); an incomplete configuration is an error
+func NewEmailService(dbQueries *db.Queries) (*EmailService, error) {
+	fromEmail := os.Getenv("FROM_EMAIL")
+	if fromEmail == "" {
+		return nil, fmt.Errorf("email service is not configured: FROM_EMAIL is required")
+	}
+
+	sender, err := EmailSenderFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("email service is not configured: %w", err)
+	}
+
+	return NewEmailServiceWithSender(sender, fromEmail, dbQueries), nil
+}
+
+// NewEmailServiceWithSender creates an EmailService that delivers through the given sender
+func NewEmailServiceWithSender(sender EmailSender, fromEmail string, dbQueries *db.Queries) *EmailService {
 	return &EmailService{
-		smtpServer:   os.Getenv("SMTP_SERVER"),
-		smtpPort:     os.Getenv("SMTP_PORT"),
-		smtpUsername: os.Getenv("SMTP_USERNAME"),
-		smtpPassword: os.Getenv("SMTP_PASSWORD"),
-		fromEmail:    os.Getenv("FROM_EMAIL"),
-		dbQueries:    dbQueries,
+		sender:    sender,
+		fromEmail: fromEmail,
+		dbQueries: dbQueries,
 	}
 }
 
@@ -46,11 +55,6 @@ type ScanCompletionEmailData struct {
 func (s *EmailService) SendScanCompletionEmail(userEmail, repositoryName, repositoryID string, vulnCount int) error {
 	log := logger.Get()
 
-	if s.smtpServer == "" || s.smtpPort == "" || s.smtpUsername == "" ||
-		s.smtpPassword == "" || s.fromEmail == "" {
-		return fmt.Errorf("email service is not properly configured")
-	}
-
 	// Create email data
 	dashboardURL := os.Getenv("DASHBOARD_URL")
 	if dashboardURL == "" {
@@ -164,29 +168,13 @@ func (s *EmailService) SendScanCompletionEmail(userEmail, repositoryName, reposi
 		return err
 	}
 
-	// Compose email
-	to := []string{userEmail}
 	subject := fmt.Sprintf("Security Scan Results Available - %s", repositoryName)
-
-	headers := make(map[string]string)
-	headers["From"] = s.fromEmail
-	headers["To"] = userEmail
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
-
-	var message bytes.Buffer
-	for k, v := range headers {
-		message.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
-	message.WriteString("\r\n")
-	message.Write(body.Bytes())
-
-	// Connect to SMTP server and send email
-	addr := fmt.Sprintf("%s:%s", s.smtpServer, s.smtpPort)
-	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpServer)
-
-	err = smtp.SendMail(addr, auth, s.fromEmail, to, message.Bytes())
+	err = s.sender.Send(context.Background(), &EmailMessage{
+		From:    s.fromEmail,
+		To:      []string{userEmail},
+		Subject: subject,
+		HTML:    body.String(),
+	})
 	if err != nil {
 		log.Error("Failed to send email",
 			zap.String("to", userEmail),
@@ -210,11 +198,6 @@ func (s *EmailService) SendBulkScanCompletionEmail(userEmails []string, reposito
 		return fmt.Errorf("no recipients specified")
 	}
 
-	if s.smtpServer == "" || s.smtpPort == "" || s.smtpUsername == "" ||
-		s.smtpPassword == "" || s.fromEmail == "" {
-		return fmt.Errorf("email service is not properly configured")
-	}
-
 	// Create email data
 	dashboardURL := os.Getenv("DASHBOARD_URL")
 	if dashboardURL == "" {
@@ -328,32 +311,15 @@ func (s *EmailService) SendBulkScanCompletionEmail(userEmails []string, reposito
 		return err
 	}
 
-	// Compose email with BCC for multiple recipients
+	// The recipients are sent as BCC so they don't see each other's addresses
 	subject := fmt.Sprintf("Security Scan Results Available - %s", repositoryName)
-
-	headers := make(map[string]string)
-	headers["From"] = s.fromEmail
-	headers["To"] = s.fromEmail                     // Set the main recipient as the from email
-	headers["Bcc"] = strings.Join(userEmails, ", ") // Add all recipients as BCC
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
-
-	var message bytes.Buffer
-	for k, v := range headers {
-		message.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
-	message.WriteString("\r\n")
-	message.Write(body.Bytes())
-
-	// Connect to SMTP server and send email
-	addr := fmt.Sprintf("%s:%s", s.smtpServer, s.smtpPort)
-	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpServer)
-
-	// For BCC, we need to include the from address as the recipient in the SMTP call
-	// but the actual recipients will be those in the BCC header
-	recipientList := append([]string{s.fromEmail}, userEmails...)
-	err = smtp.SendMail(addr, auth, s.fromEmail, recipientList, message.Bytes())
+	err = s.sender.Send(context.Background(), &EmailMessage{
+		From:    s.fromEmail,
+		To:      []string{s.fromEmail},
+		Bcc:     userEmails,
+		Subject: subject,
+		HTML:    body.String(),
+	})
 	if err != nil {
 		log.Error("Failed to send bulk email",
 			zap.Strings("to", userEmails),
@@ -368,3 +334,165 @@ func (s *EmailService) SendBulkScanCompletionEmail(userEmails []string, reposito
 
 	return nil
 }
+
+// DigestEmailData contains data needed for the scan digest email template
+type DigestEmailData struct {
+	Scans      []DigestEmailScan
+	TotalVulns int
+}
+
+// DigestEmailScan is one row of the digest email
+type DigestEmailScan struct {
+	RepositoryName string
+	VulnCount      int
+	CompletedAt    string
+	DashboardURL   string
+	Partial        bool // The scan's results are incomplete, e.g. it stopped at its time budget
+}
+
+// digestEmailTemplate lists every scan in a digest with a link to its repository
+const digestEmailTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Security Scan Digest</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 600px;
+            margin: 0 auto;
+            padding: 20px;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0, 0, 0, 0.1);
+            padding: 30px;
+        }
+        h1 {
+            color: #2563eb;
+            font-size: 24px;
+            margin-bottom: 15px;
+            text-align: center;
+        }
+        table {
+            width: 100%;
+            border-collapse: collapse;
+            margin: 20px 0;
+        }
+        th, td {
+            text-align: left;
+            padding: 8px;
+            border-bottom: 1px solid #e5e7eb;
+        }
+        a {
+            color: #2563eb;
+        }
+        .footer {
+            margin-top: 30px;
+            text-align: center;
+            font-size: 14px;
+            color: #6b7280;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Security Scan Digest</h1>
+        <p>Hello,</p>
+        <p>{{len .Scans}} {{if eq (len .Scans) 1}}scan{{else}}scans{{end}} of your repositories finished since the last digest,
+            with <strong>{{.TotalVulns}} potential security {{if eq .TotalVulns 1}}issue{{else}}issues{{end}}</strong> in total.</p>
+        <table>
+            <tr><th>Repository</th><th>Issues</th><th>Completed</th><th></th></tr>
+            {{range .Scans}}
+            <tr>
+                <td>{{.RepositoryName}}{{if .Partial}} (partial){{end}}</td>
+                <td>{{.VulnCount}}</td>
+                <td>{{.CompletedAt}}</td>
+                <td><a href="{{.DashboardURL}}">View results</a></td>
+            </tr>
+            {{end}}
+        </table>
+        <div class="footer">
+            <p>You receive this digest instead of an email per scan. This is an automated message, please do not reply to this email.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+// RenderDigestEmail renders the digest email body for the given scans
+// dashboardURL is the base URL of the dashboard each repository link points into
+func RenderDigestEmail(scans []ScanSummary, dashboardURL string) (string, error) {
+	data := DigestEmailData{}
+	for _, scan := range scans {
+		data.TotalVulns += scan.VulnCount
+		data.Scans = append(data.Scans, DigestEmailScan{
+			RepositoryName: scan.RepositoryName,
+			VulnCount:      scan.VulnCount,
+			CompletedAt:    scan.CompletedAt.UTC().Format("Jan 2, 2006 15:04 MST"),
+			DashboardURL:   fmt.Sprintf("%s/dashboard/repos/%s", dashboardURL, scan.RepositoryID),
+			Partial:        IsPartialScanStatus(scan.Status),
+		})
+	}
+
+	tmpl, err := template.New("digestEmail").Parse(digestEmailTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse digest email template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", fmt.Errorf("failed to execute digest email template: %w", err)
+	}
+	return body.String(), nil
+}
+
+// SendDigestEmail sends one email summarizing several finished scans
+func (s *EmailService) SendDigestEmail(userEmail string, scans []ScanSummary) error {
+	log := logger.Get()
+
+	if len(scans) == 0 {
+		return fmt.Errorf("no scans to include in the digest")
+	}
+
+	dashboardURL := os.Getenv("DASHBOARD_URL")
+	if dashboardURL == "" {
+		dashboardURL = "http://localhost:3000"
+	}
+
+	body, err := RenderDigestEmail(scans, dashboardURL)
+	if err != nil {
+		log.Error("Failed to render digest email", zap.Error(err))
+		return err
+	}
+
+	subject := fmt.Sprintf("Security Scan Digest - %d scans", len(scans))
+	if len(scans) == 1 {
+		subject = "Security Scan Digest - 1 scan"
+	}
+
+	err = s.sender.Send(context.Background(), &EmailMessage{
+		From:    s.fromEmail,
+		To:      []string{userEmail},
+		Subject: subject,
+		HTML:    body,
+	})
+	if err != nil {
+		log.Error("Failed to send digest email",
+			zap.String("to", userEmail),
+			zap.Int("scans", len(scans)),
+			zap.Error(err))
+		return err
+	}
+
+	log.Info("Scan digest email sent successfully",
+		zap.String("to", userEmail),
+		zap.Int("scans", len(scans)))
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Email providers selectable with EMAIL_PROVIDER
+const (
+	EmailProviderSMTP     = "smtp"     // Any SMTP server (default)
+	EmailProviderSendGrid = "sendgrid" // SendGrid v3 HTTP API
+)
+
+// SMTP connection security selectable with SMTP_TLS_MODE
+const (
+	SMTPTLSModeNone     = "none"     // Plain connection; for local relays only
+	SMTPTLSModeStartTLS = "starttls" // Plain connection upgraded with STARTTLS, which the server must support
+	SMTPTLSModeTLS      = "tls"      // Implicit TLS from the first byte, usually port 465
+
+	// smtpTLSModeOpportunistic upgrades with STARTTLS only when the server offers it
+	// It is used when SMTP_TLS_MODE is unset, matching how the service always sent mail
+	smtpTLSModeOpportunistic = ""
+)
+
+// emailSendTimeout bounds one delivery attempt, whichever provider is used
+const emailSendTimeout = 30 * time.Second
+
+// EmailMessage is one HTML email ready to be sent
+type EmailMessage struct {
+	From    string
+	To      []string // Recipients shown in the To header
+	Bcc     []string // Recipients that receive the message without being listed in it
+	Subject string
+	HTML    string
+}
+
+// Recipients returns every address the message is delivered to
+func (m *EmailMessage) Recipients() []string {
+	return append(append([]string{}, m.To...), m.Bcc...)
+}
+
+// Bytes renders the message in RFC 5322 form for SMTP
+// Bcc recipients are left out of the headers so they stay hidden from each other
+func (m *EmailMessage) Bytes() []byte {
+	var message bytes.Buffer
+	writeHeader := func(name, value string) {
+		// Header values never span lines, so a crafted value can't inject headers
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		message.WriteString(name + ": " + value + "\r\n")
+	}
+
+	writeHeader("From", m.From)
+	writeHeader("To", strings.Join(m.To, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "text/html; charset=UTF-8")
+	message.WriteString("\r\n")
+	message.WriteString(m.HTML)
+
+	return message.Bytes()
+}
+
+// EmailSender delivers email through one provider
+type EmailSender interface {
+	// Name identifies the provider in logs and errors
+	Name() string
+
+	// Send delivers the message to every recipient
+	Send(ctx context.Context, message *EmailMessage) error
+}
+
+// EmailSenderFromEnv builds the sender selected by EMAIL_PROVIDER from its environment settings
+// Missing or invalid settings are an error, so a misconfiguration is reported before any email is attempted
+func EmailSenderFromEnv() (EmailSender, error) {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("EMAIL_PROVIDER")))
+	switch provider {
+	case "", EmailProviderSMTP:
+		return NewSMTPSender(SMTPConfig{
+			Host:     os.Getenv("SMTP_SERVER"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			TLSMode:  os.Getenv("SMTP_TLS_MODE"),
+		})
+	case EmailProviderSendGrid:
+		return NewSendGridSender(os.Getenv("SENDGRID_API_KEY"))
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_PROVIDER %q: use %q or %q", provider, EmailProviderSMTP, EmailProviderSendGrid)
+	}
+}
+
+// SMTPConfig holds the connection settings of an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string // Optional; without it mail is sent unauthenticated
+	Password string
+	TLSMode  string // none, starttls, or tls; empty upgrades with STARTTLS when the server offers it
+}
+
+// NewSMTPSender creates a sender for an SMTP server after checking its configuration
+func NewSMTPSender(config SMTPConfig) (EmailSender, error) {
+	config.TLSMode = strings.ToLower(strings.TrimSpace(config.TLSMode))
+
+	switch {
+	case config.Host == "":
+		return nil, fmt.Errorf("SMTP_SERVER is required")
+	case config.Port == "":
+		return nil, fmt.Errorf("SMTP_PORT is required")
+	case config.Username != "" && config.Password == "":
+		return nil, fmt.Errorf("SMTP_PASSWORD is required when SMTP_USERNAME is set")
+	}
+
+	switch config.TLSMode {
+	case SMTPTLSModeNone, SMTPTLSModeStartTLS, SMTPTLSModeTLS:
+	case smtpTLSModeOpportunistic:
+		// Port 465 only speaks implicit TLS, so a plain connection there would just hang
+		if config.Port == "465" {
+			config.TLSMode = SMTPTLSModeTLS
+		}
+	default:
+		return nil, fmt.Errorf("invalid SMTP_TLS_MODE %q: use %q, %q, or %q",
+			config.TLSMode, SMTPTLSModeNone, SMTPTLSModeStartTLS, SMTPTLSModeTLS)
+	}
+
+	return &smtpSender{config: config}, nil
+}
+
+// smtpSender delivers email through an SMTP server
+type smtpSender struct {
+	config SMTPConfig
+}
+
+func (s *smtpSender) Name() string {
+	return EmailProviderSMTP
+}
+
+func (s *smtpSender) Send(ctx context.Context, message *EmailMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, emailSendTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.config.Host, s.config.Port)
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+
+	var conn net.Conn
+	var err error
+	if s.config.TLSMode == SMTPTLSModeTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	switch s.config.TLSMode {
+	case SMTPTLSModeStartTLS, smtpTLSModeOpportunistic:
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS with %s failed: %w", addr, err)
+			}
+		} else if s.config.TLSMode == SMTPTLSModeStartTLS {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+	}
+
+	if s.config.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection to a remote host
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication with %s failed: %w", addr, err)
+		}
+	}
+
+	if err := client.Mail(message.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %w", message.From, err)
+	}
+	for _, recipient := range message.Recipients() {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", recipient, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP message: %w", err)
+	}
+	if _, err := writer.Write(message.Bytes()); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to write SMTP message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP server did not accept the message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// sendGridAPIURL is the SendGrid v3 send endpoint
+const sendGridAPIURL = "https://api.sendgrid.com/v3/mail/send"
+
+// NewSendGridSender creates a sender for the SendGrid HTTP API
+func NewSendGridSender(apiKey string) (EmailSender, error) {
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, fmt.Errorf("SENDGRID_API_KEY is required when EMAIL_PROVIDER is %q", EmailProviderSendGrid)
+	}
+	return &sendGridSender{
+		apiKey:     apiKey,
+		url:        sendGridAPIURL,
+		httpClient: &http.Client{Timeout: emailSendTimeout},
+	}, nil
+}
+
+// sendGridSender delivers email through the SendGrid v3 API
+type sendGridSender struct {
+	apiKey     string
+	url        string
+	httpClient *http.Client
+}
+
+// sendGridAddress is an email address in a SendGrid request
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+// sendGridPersonalization lists the recipients of a SendGrid message
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+// sendGridContent is one body of a SendGrid message
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// sendGridRequest is the body of a SendGrid send request
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+func (s *sendGridSender) Name() string {
+	return EmailProviderSendGrid
+}
+
+func (s *sendGridSender) Send(ctx context.Context, message *EmailMessage) error {
+	personalization := sendGridPersonalization{}
+	for _, to := range message.To {
+		personalization.To = append(personalization.To, sendGridAddress{Email: to})
+	}
+	for _, bcc := range message.Bcc {
+		personalization.Bcc = append(personalization.Bcc, sendGridAddress{Email: bcc})
+	}
+	request := sendGridRequest{
+		Personalizations: []sendGridPersonalization{personalization},
+		From:             sendGridAddress{Email: message.From},
+		Subject:          message.Subject,
+		Content:          []sendGridContent{{Type: "text/html", Value: message.HTML}},
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SendGrid request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create SendGrid request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call SendGrid: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// SendGrid answers 202 once the message is queued
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("SendGrid returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recordingSender keeps the messages it is asked to send
+type recordingSender struct {
+	messages []*EmailMessage
+}
+
+func (r *recordingSender) Name() string {
+	return "recording"
+}
+
+func (r *recordingSender) Send(ctx context.Context, message *EmailMessage) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func TestEmailMessageBytes(t *testing.T) {
+	message := &EmailMessage{
+		From:    "scanner@example.com",
+		To:      []string{"a@example.com", "b@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		Subject: "Résultats\r\nBcc: attacker@example.com",
+		HTML:    "<p>done</p>",
+	}
+
+	rendered := string(message.Bytes())
+	headers, body, found := strings.Cut(rendered, "\r\n\r\n")
+	if !found || body != "<p>done</p>" {
+		t.Fatalf("message = %q, want headers, a blank line, and the HTML body", rendered)
+	}
+	for _, want := range []string{"From: scanner@example.com", "To: a@example.com, b@example.com", "Content-Type: text/html; charset=UTF-8", "MIME-Version: 1.0"} {
+		if !strings.Contains(headers+"\r\n", want+"\r\n") {
+			t.Errorf("headers %q lack %q", headers, want)
+		}
+	}
+	if strings.Contains(headers, "hidden@example.com") {
+		t.Error("Bcc recipient is listed in the headers")
+	}
+	// The subject is encoded, so neither its accent nor its line break reaches the headers raw
+	if strings.Contains(headers, "\r\nBcc:") || !strings.Contains(headers, "Subject: =?utf-8?q?") {
+		t.Errorf("headers = %q, want an encoded subject without an injected header", headers)
+	}
+
+	if got := message.Recipients(); !slices.Equal(got, []string{"a@example.com", "b@example.com", "hidden@example.com"}) {
+		t.Errorf("Recipients() = %v, want the To and Bcc addresses", got)
+	}
+}
+
+func TestEmailSenderFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantName    string
+		wantTLSMode string
+		wantErr     string
+	}{
+		{name: "smtp by default", env: map[string]string{"SMTP_SERVER": "mail.example.com", "SMTP_PORT": "587"}, wantName: EmailProviderSMTP, wantTLSMode: smtpTLSModeOpportunistic},
+		{name: "port 465 uses implicit TLS", env: map[string]string{"SMTP_SERVER": "mail.example.com", "SMTP_PORT": "465"}, wantName: EmailProviderSMTP, wantTLSMode: SMTPTLSModeTLS},
+		{name: "explicit STARTTLS", env: map[string]string{"EMAIL_PROVIDER": "SMTP", "SMTP_SERVER": "mail.example.com", "SMTP_PORT": "587", "SMTP_TLS_MODE": " StartTLS "}, wantName: EmailProviderSMTP, wantTLSMode: SMTPTLSModeStartTLS},
+		{name: "plain relay on 465", env: map[string]string{"SMTP_SERVER": "relay", "SMTP_PORT": "465", "SMTP_TLS_MODE": "none"}, wantName: EmailProviderSMTP, wantTLSMode: SMTPTLSModeNone},
+		{name: "sendgrid", env: map[string]string{"EMAIL_PROVIDER": "sendgrid", "SENDGRID_API_KEY": "SG.key"}, wantName: EmailProviderSendGrid},
+		{name: "missing server", env: map[string]string{"SMTP_PORT": "587"}, wantErr: "SMTP_SERVER"},
+		{name: "missing port", env: map[string]string{"SMTP_SERVER": "mail.example.com"}, wantErr: "SMTP_PORT"},
+		{name: "username without password", env: map[string]string{"SMTP_SERVER": "mail.example.com", "SMTP_PORT": "587", "SMTP_USERNAME": "user"}, wantErr: "SMTP_PASSWORD"},
+		{name: "unknown TLS mode", env: map[string]string{"SMTP_SERVER": "mail.example.com", "SMTP_PORT": "587", "SMTP_TLS_MODE": "ssl"}, wantErr: "SMTP_TLS_MODE"},
+		{name: "sendgrid without a key", env: map[string]string{"EMAIL_PROVIDER": "sendgrid"}, wantErr: "SENDGRID_API_KEY"},
+		{name: "unknown provider", env: map[string]string{"EMAIL_PROVIDER": "pigeon"}, wantErr: "EMAIL_PROVIDER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"EMAIL_PROVIDER", "SMTP_SERVER", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "SMTP_TLS_MODE", "SENDGRID_API_KEY"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			sender, err := EmailSenderFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("EmailSenderFromEnv() error = %v, want one naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EmailSenderFromEnv() returned error: %v", err)
+			}
+			if sender.Name() != tt.wantName {
+				t.Errorf("sender = %s, want %s", sender.Name(), tt.wantName)
+			}
+			if smtp, ok := sender.(*smtpSender); ok && smtp.config.TLSMode != tt.wantTLSMode {
+				t.Errorf("TLS mode = %q, want %q", smtp.config.TLSMode, tt.wantTLSMode)
+			}
+		})
+	}
+}
+
+// fakeSMTPServer accepts one SMTP session and records the envelope and data it received
+// It never offers STARTTLS
+type fakeSMTPServer struct {
+	addr       string
+	done       chan struct{}
+	from       string
+	recipients []string
+	data       string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTPServer{addr: listener.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(server.done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 fake ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				reply("250 fake")
+			case "MAIL":
+				server.from = strings.Trim(strings.TrimPrefix(command, "MAIL FROM:"), "<>")
+				reply("250 OK")
+			case "RCPT":
+				server.recipients = append(server.recipients, strings.Trim(strings.TrimPrefix(command, "RCPT TO:"), "<>"))
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				server.data = data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return server
+}
+
+func TestSMTPSenderSendsTheMessage(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+
+	// The server offers no STARTTLS, so the opportunistic default sends in plain text
+	sender, err := NewSMTPSender(SMTPConfig{Host: host, Port: port})
+	if err != nil {
+		t.Fatalf("NewSMTPSender returned error: %v", err)
+	}
+	err = sender.Send(context.Background(), &EmailMessage{
+		From:    "scanner@example.com",
+		To:      []string{"team@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		Subject: "Scan finished",
+		HTML:    "<p>done</p>",
+	})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	<-server.done
+
+	if server.from != "scanner@example.com" {
+		t.Errorf("MAIL FROM = %q, want the sender", server.from)
+	}
+	if !slices.Equal(server.recipients, []string{"team@example.com", "hidden@example.com"}) {
+		t.Errorf("RCPT TO = %v, want the To and Bcc recipients", server.recipients)
+	}
+	if !strings.Contains(server.data, "Subject: Scan finished\r\n") || !strings.HasSuffix(server.data, "<p>done</p>\r\n") || strings.Contains(server.data, "hidden@example.com") {
+		t.Errorf("DATA = %q, want the message without its Bcc recipient", server.data)
+	}
+}
+
+func TestSMTPSenderRequiresSTARTTLSWhenConfigured(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+
+	sender, err := NewSMTPSender(SMTPConfig{Host: host, Port: port, TLSMode: SMTPTLSModeStartTLS})
+	if err != nil {
+		t.Fatalf("NewSMTPSender returned error: %v", err)
+	}
+	err = sender.Send(context.Background(), &EmailMessage{From: "scanner@example.com", To: []string{"team@example.com"}})
+	if err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+		t.Errorf("Send error = %v, want STARTTLS refused rather than sending in plain text", err)
+	}
+	<-server.done
+	if server.from != "" {
+		t.Errorf("message sent from %q without STARTTLS", server.from)
+	}
+}
+
+func TestSendGridSender(t *testing.T) {
+	var got sendGridRequest
+	var authorization string
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+		if status != http.StatusAccepted {
+			w.Write([]byte(`{"errors": [{"message": "bad key"}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sender, err := NewSendGridSender("SG.key")
+	if err != nil {
+		t.Fatalf("NewSendGridSender returned error: %v", err)
+	}
+	sender.(*sendGridSender).url = server.URL
+
+	message := &EmailMessage{From: "scanner@example.com", To: []string{"team@example.com"}, Bcc: []string{"hidden@example.com"}, Subject: "Scan finished", HTML: "<p>done</p>"}
+	if err := sender.Send(context.Background(), message); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if authorization != "Bearer SG.key" {
+		t.Errorf("Authorization = %q, want the API key", authorization)
+	}
+	if len(got.Personalizations) != 1 || got.Personalizations[0].To[0].Email != "team@example.com" || got.Personalizations[0].Bcc[0].Email != "hidden@example.com" {
+		t.Errorf("personalizations = %+v, want the To and Bcc recipients", got.Personalizations)
+	}
+	if got.From.Email != "scanner@example.com" || got.Subject != "Scan finished" || got.Content[0].Value != "<p>done</p>" {
+		t.Errorf("request = %+v, want the message", got)
+	}
+
+	status = http.StatusUnauthorized
+	if err := sender.Send(context.Background(), message); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Send error = %v, want SendGrid's status", err)
+	}
+}
+
+func TestEmailServiceSendsThroughItsSender(t *testing.T) {
+	sender := &recordingSender{}
+	service := NewEmailServiceWithSender(sender, "scanner@example.com", nil)
+
+	if err := service.SendScanCompletionEmail("dev@example.com", "octo/repo", "repo-1", 3); err != nil {
+		t.Fatalf("SendScanCompletionEmail returned error: %v", err)
+	}
+	if err := service.SendBulkScanCompletionEmail([]string{"a@example.com", "b@example.com"}, "octo/repo", "repo-1", 0); err != nil {
+		t.Fatalf("SendBulkScanCompletionEmail returned error: %v", err)
+	}
+	if err := service.SendBulkScanCompletionEmail(nil, "octo/repo", "repo-1", 0); err == nil {
+		t.Error("SendBulkScanCompletionEmail accepted no recipients")
+	}
+
+	if len(sender.messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(sender.messages))
+	}
+	single, bulk := sender.messages[0], sender.messages[1]
+	if !slices.Equal(single.To, []string{"dev@example.com"}) || single.From != "scanner@example.com" || !strings.Contains(single.HTML, "3 potential security issues") {
+		t.Errorf("single message = %+v, want it addressed to the user with the finding count", single)
+	}
+	// Bulk recipients are hidden from each other
+	if !slices.Equal(bulk.To, []string{"scanner@example.com"}) || !slices.Equal(bulk.Bcc, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("bulk message To %v Bcc %v, want the recipients in Bcc", bulk.To, bulk.Bcc)
+	}
+}
+
+func TestNewEmailServiceRequiresAFromAddress(t *testing.T) {
+	t.Setenv("FROM_EMAIL", "")
+	t.Setenv("EMAIL_PROVIDER", "sendgrid")
+	t.Setenv("SENDGRID_API_KEY", "SG.key")
+	if _, err := NewEmailService(nil); err == nil || !strings.Contains(err.Error(), "FROM_EMAIL") {
+		t.Errorf("NewEmailService error = %v, want one naming FROM_EMAIL", err)
+	}
+
+	t.Setenv("FROM_EMAIL", "scanner@example.com")
+	if _, err := NewEmailService(nil); err != nil {
+		t.Errorf("NewEmailService returned error: %v", err)
+	}
+}
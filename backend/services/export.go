@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// csvExportHeader lists the columns of a CSV export, in order
+var csvExportHeader = []string{"category", "severity", "confidence", "file", "line_start", "line_end", "description", "remediation", "cwe", "references"}
+
+// VulnerabilitiesToCSV writes findings as CSV with a header row, worst severity first
+// An empty list produces just the header, so the file still opens cleanly in a spreadsheet
+func VulnerabilitiesToCSV(w io.Writer, vulnerabilities []*Vulnerability) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvExportHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, vuln := range sortedForReport(vulnerabilities) {
+		record := []string{
+			csvCell(string(vuln.Type)),
+			csvCell(vuln.Severity),
+			formatConfidence(vuln.Confidence),
+			csvCell(vuln.FilePath),
+			fmt.Sprint(vuln.LineStart),
+			fmt.Sprint(vuln.LineEnd),
+			csvCell(vuln.Description),
+			csvCell(vuln.Remediation),
+			csvCell(vuln.CWE),
+			csvCell(strings.Join(vuln.References, " ")),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatConfidence writes a confidence with two decimals, or nothing when it wasn't reported
+func formatConfidence(confidence *float64) string {
+	if confidence == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*confidence, 'f', 2, 64)
+}
+
+// csvCell neutralizes text a spreadsheet would run as a formula
+// Findings quote scanned code and model output, so a cell like "=HYPERLINK(...)" must stay text
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ReportInfo describes the scan a PDF report covers
+type ReportInfo struct {
+	RepositoryName string
+	ScanID         string
+	CommitSHA      string    // Empty when the scan didn't record one
+	ScanDate       time.Time // When the scan completed, or started if it never did
+}
+
+// reportSeverities are the rows of the PDF summary table, worst first
+var reportSeverities = []string{"Critical", "High", "Medium", "Low"}
+
+// VulnerabilitiesToPDF writes a formatted report: scan details, a per-severity summary table,
+// and one section per finding with its location, description, remediation, and code
+func VulnerabilitiesToPDF(w io.Writer, vulnerabilities []*Vulnerability, info ReportInfo) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Security scan report: "+info.RepositoryName, true)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	// The core fonts only cover cp1252, so other characters are translated or dropped
+	text := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, "Security Scan Report", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, text("Repository: "+info.RepositoryName), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Scan: "+info.ScanID, "", 1, "L", false, 0, "")
+	if info.CommitSHA != "" {
+		pdf.CellFormat(0, 6, "Commit: "+info.CommitSHA, "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(0, 6, "Date: "+info.ScanDate.UTC().Format("2006-01-02 15:04 MST"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	// Summary table of findings per severity
+	counts := map[string]int{}
+	for _, vuln := range vulnerabilities {
+		counts[NormalizeSeverity(vuln.Severity)]++
+	}
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, "Summary", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(50, 7, "Severity", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(30, 7, "Findings", "1", 1, "R", true, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	counted := 0
+	for _, severity := range reportSeverities {
+		pdf.CellFormat(50, 7, severity, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 7, fmt.Sprint(counts[severity]), "1", 1, "R", false, 0, "")
+		counted += counts[severity]
+	}
+	if other := len(vulnerabilities) - counted; other > 0 {
+		pdf.CellFormat(50, 7, "Other", "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 7, fmt.Sprint(other), "1", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(50, 7, "Total", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(30, 7, fmt.Sprint(len(vulnerabilities)), "1", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	if len(vulnerabilities) == 0 {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.MultiCell(0, 6, "No vulnerabilities were found in this scan.", "", "L", false)
+	}
+
+	// One section per finding, worst first
+	for i, vuln := range sortedForReport(vulnerabilities) {
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.MultiCell(0, 7, text(fmt.Sprintf("%d. [%s] %s", i+1, vuln.Severity, vuln.Type)), "", "L", false)
+
+		pdf.SetFont("Helvetica", "", 9)
+		location := fmt.Sprintf("%s, lines %d-%d", vuln.FilePath, vuln.LineStart, vuln.LineEnd)
+		if vuln.Confidence != nil {
+			location += ", confidence " + formatConfidence(vuln.Confidence)
+		}
+		if vuln.CWE != "" {
+			location += ", " + vuln.CWE
+		}
+		pdf.MultiCell(0, 5, text(location), "", "L", false)
+		pdf.Ln(1)
+
+		writeReportField(pdf, text, "Description", vuln.Description, "Helvetica")
+		writeReportField(pdf, text, "Remediation", vuln.Remediation, "Helvetica")
+		writeReportField(pdf, text, "Code", vuln.Code, "Courier")
+		writeReportField(pdf, text, "References", strings.Join(vuln.References, "\n"), "Helvetica")
+		pdf.Ln(4)
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return nil
+}
+
+// writeReportField writes a labeled block of text, skipping fields the finding doesn't have
+func writeReportField(pdf *gofpdf.Fpdf, text func(string) string, label, value, font string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(0, 6, label, "", 1, "L", false, 0, "")
+	pdf.SetFont(font, "", 9)
+	pdf.MultiCell(0, 5, text(strings.ReplaceAll(value, "\t", "    ")), "", "L", false)
+	pdf.Ln(1)
+}
+
+// sortedForReport returns the findings ordered by severity (worst first), then file and line
+// The input order is left untouched
+func sortedForReport(vulnerabilities []*Vulnerability) []*Vulnerability {
+	sorted := append([]*Vulnerability(nil), vulnerabilities...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if rankA, rankB := SeverityRank(a.Severity), SeverityRank(b.Severity); rankA != rankB {
+			return rankA > rankB
+		}
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.LineStart < b.LineStart
+	})
+	return sorted
+}
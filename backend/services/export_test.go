@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func TestVulnerabilitiesToCSV(t *testing.T) {
+	confidence := 0.8
+	var out bytes.Buffer
+	err := VulnerabilitiesToCSV(&out, []*Vulnerability{
+		{Type: Injection, Severity: "Low", FilePath: "b.go", LineStart: 9, LineEnd: 9, Description: "logs, \"quoted\"\nacross lines"},
+		{Type: BrokenAccessControl, Severity: "Critical", FilePath: "a.go", LineStart: 2, LineEnd: 4, Description: "=HYPERLINK(\"http://evil\")", Remediation: "-check", Confidence: &confidence},
+	})
+	if err != nil {
+		t.Fatalf("VulnerabilitiesToCSV returned error: %v", err)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want a header and two rows", len(records))
+	}
+	if got := records[0]; len(got) != len(csvExportHeader) || got[0] != "category" || got[7] != "remediation" {
+		t.Errorf("header = %v, want %v", got, csvExportHeader)
+	}
+
+	// The critical finding sorts first, and its formula-like cells stay text
+	critical := records[1]
+	if critical[1] != "Critical" || critical[2] != "0.80" || critical[3] != "a.go" || critical[4] != "2" || critical[5] != "4" {
+		t.Errorf("first row = %v, want the critical finding in a.go at lines 2-4 with its confidence", critical)
+	}
+	if critical[6] != "'=HYPERLINK(\"http://evil\")" || critical[7] != "'-check" {
+		t.Errorf("formula cells = %q, %q, want them prefixed with a quote", critical[6], critical[7])
+	}
+	if low := records[2]; low[2] != "" || low[6] != "logs, \"quoted\"\nacross lines" || low[7] != "" {
+		t.Errorf("second row = %v, want no confidence and the description round-tripped", low)
+	}
+}
+
+func TestVulnerabilitiesToCSVWithoutFindings(t *testing.T) {
+	var out bytes.Buffer
+	if err := VulnerabilitiesToCSV(&out, nil); err != nil {
+		t.Fatalf("VulnerabilitiesToCSV returned error: %v", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil || len(records) != 1 {
+		t.Errorf("records = %v, %v, want just the header", records, err)
+	}
+}
+
+func TestVulnerabilitiesToPDF(t *testing.T) {
+	info := ReportInfo{RepositoryName: "octo/repo", ScanID: "scan-1", CommitSHA: "abc123", ScanDate: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name            string
+		vulnerabilities []*Vulnerability
+	}{
+		{name: "no findings"},
+		{name: "findings", vulnerabilities: []*Vulnerability{
+			{Type: Injection, Severity: "High", FilePath: "main.go", LineStart: 3, LineEnd: 5, Description: "SQL built from input – naïve", Code: "db.Query(\"SELECT \" + name)\n\treturn"},
+			{Type: CryptographicFailures, Severity: "informational", FilePath: "util.go", LineStart: 1, LineEnd: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := VulnerabilitiesToPDF(&out, tt.vulnerabilities, info); err != nil {
+				t.Fatalf("VulnerabilitiesToPDF returned error: %v", err)
+			}
+			if !bytes.HasPrefix(out.Bytes(), []byte("%PDF-")) {
+				t.Errorf("output starts with %q, want a PDF header", out.Bytes()[:min(out.Len(), 8)])
+			}
+		})
+	}
+}
+
+func TestSortedForReport(t *testing.T) {
+	input := []*Vulnerability{
+		{ID: "low", Severity: "Low", FilePath: "a.go"},
+		{ID: "high-b", Severity: "High", FilePath: "b.go"},
+		{ID: "high-a-9", Severity: "High", FilePath: "a.go", LineStart: 9},
+		{ID: "high-a-1", Severity: "high", FilePath: "a.go", LineStart: 1},
+	}
+
+	sorted := sortedForReport(input)
+	want := []string{"high-a-1", "high-a-9", "high-b", "low"}
+	for i, id := range want {
+		if sorted[i].ID != id {
+			t.Errorf("sorted[%d] = %s, want %s", i, sorted[i].ID, id)
+		}
+	}
+	if input[0].ID != "low" {
+		t.Error("sortedForReport reordered its input")
+	}
+}
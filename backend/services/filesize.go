@@ -0,0 +1,51 @@
+package services
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxFileSizeBytes is the largest file a scan reads when ScanOptions.MaxFileSizeBytes is unset
+// Bigger files are usually generated, minified, or data, and would cost many model requests for little
+const DefaultMaxFileSizeBytes int64 = 256 << 10
+
+// MaxFileSizeBytesFromEnv reads the file size limit from SCAN_MAX_FILE_SIZE_BYTES
+// Unset or invalid values give DefaultMaxFileSizeBytes; a negative value disables the limit
+func MaxFileSizeBytesFromEnv() int64 {
+	value := os.Getenv("SCAN_MAX_FILE_SIZE_BYTES")
+	if value == "" {
+		return DefaultMaxFileSizeBytes
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed == 0 {
+		logger.Warn("Invalid SCAN_MAX_FILE_SIZE_BYTES value, using default",
+			zap.String("value", value), zap.Int64("default", DefaultMaxFileSizeBytes))
+		return DefaultMaxFileSizeBytes
+	}
+	return parsed
+}
+
+// maxFileSize returns the size limit a scan applies for a MaxFileSizeBytes option: the default when unset, 0 when disabled
+func maxFileSize(maxFileSizeBytes int64) int64 {
+	switch {
+	case maxFileSizeBytes == 0:
+		return DefaultMaxFileSizeBytes
+	case maxFileSizeBytes < 0:
+		return 0
+	default:
+		return maxFileSizeBytes
+	}
+}
+
+// exceedsMaxFileSize stats the file and reports its size and whether it is over limit (0 = no limit)
+// The size is checked before the file is read so oversized files are never loaded into memory
+func exceedsMaxFileSize(filePath string, limit int64) (int64, bool, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0, false, err
+	}
+	return info.Size(), limit > 0 && info.Size() > limit, nil
+}
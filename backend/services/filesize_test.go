@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxFileSizeBytesFromEnv(t *testing.T) {
+	tests := map[string]int64{"": DefaultMaxFileSizeBytes, "1024": 1024, "-1": -1, "0": DefaultMaxFileSizeBytes, "lots": DefaultMaxFileSizeBytes}
+	for value, want := range tests {
+		t.Setenv("SCAN_MAX_FILE_SIZE_BYTES", value)
+		if got := MaxFileSizeBytesFromEnv(); got != want {
+			t.Errorf("MaxFileSizeBytesFromEnv() with %q = %d, want %d", value, got, want)
+		}
+	}
+}
+
+// writeLargeFixture replaces the fixture file at relPath with one of size bytes
+func writeLargeFixture(t *testing.T, root, relPath string, size int) {
+	t.Helper()
+	content := "package fixture\n" + strings.Repeat("x", size-len("package fixture\n"))
+	if err := os.WriteFile(filepath.Join(root, relPath), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", relPath, err)
+	}
+}
+
+func TestScanRepositorySkipsFilesOverTheMaxFileSize(t *testing.T) {
+	root := writeFixtureTree(t, []string{"small.go", "large.go"})
+	writeLargeFixture(t, root, "large.go", 2048)
+	transport := &slowModelTransport{}
+	useModelTransport(t, transport)
+
+	recorded := map[string]int{}
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		MaxFileSizeBytes:   1024,
+		Concurrency:        1,
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			recorded[relPath] = len(vulnerabilities)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	if result.FilesTooLarge != 1 || result.FilesScanned != 2 {
+		t.Errorf("%d files too large of %d scanned, want 1 of 2", result.FilesTooLarge, result.FilesScanned)
+	}
+	if transport.requests != 1 {
+		t.Errorf("sent %d model requests, want 1 for the file under the limit", transport.requests)
+	}
+	// The large file is still recorded as done so later batches don't pick it up again
+	if count, ok := recorded["large.go"]; !ok || count != 0 {
+		t.Errorf("large.go recorded = %d, %v; want recorded with no findings", count, ok)
+	}
+	if len(result.Vulnerabilities) != 1 || result.Vulnerabilities[0].FilePath != "small.go" {
+		t.Errorf("got findings %v, want one in small.go", result.Vulnerabilities)
+	}
+}
+
+func TestScanRepositoryMaxFileSizeDefaultsAndCanBeDisabled(t *testing.T) {
+	root := writeFixtureTree(t, []string{"small.go", "large.go"})
+	writeLargeFixture(t, root, "large.go", int(DefaultMaxFileSizeBytes)+1)
+
+	tests := []struct {
+		name         string
+		maxFileSize  int64
+		wantTooLarge int
+		wantRequests int
+	}{
+		{name: "unset uses the default", maxFileSize: 0, wantTooLarge: 1, wantRequests: 1},
+		{name: "negative disables the limit", maxFileSize: -1, wantTooLarge: 0, wantRequests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &slowModelTransport{}
+			useModelTransport(t, transport)
+
+			result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+				VulnerabilityTypes: []VulnerabilityType{Injection},
+				FileExtensions:     []string{".go"},
+				MaxFileSizeBytes:   tt.maxFileSize,
+			})
+			if err != nil {
+				t.Fatalf("ScanRepository returned error: %v", err)
+			}
+			if result.FilesTooLarge != tt.wantTooLarge || transport.requests != tt.wantRequests {
+				t.Errorf("%d files too large and %d model requests, want %d and %d",
+					result.FilesTooLarge, transport.requests, tt.wantTooLarge, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestPreviewScanCountsFilesOverTheMaxFileSize(t *testing.T) {
+	root := writeFixtureTree(t, []string{"small.go", "large.go"})
+	writeLargeFixture(t, root, "large.go", 2048)
+
+	preview, err := PreviewScan(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		MaxFileSizeBytes:   1024,
+	})
+	if err != nil {
+		t.Fatalf("PreviewScan returned error: %v", err)
+	}
+
+	if preview.FileCount != 2 || preview.TooLargeFiles != 1 {
+		t.Errorf("%d files with %d too large, want 2 with 1", preview.FileCount, preview.TooLargeFiles)
+	}
+	// Only the file under the limit would be sent to the model
+	wantTokens := estimateScanTokens("package fixture\n", DetectLanguage("small.go", "package fixture\n"), "small.go", []string{string(Injection)})
+	if preview.EstimatedTokens != wantTokens {
+		t.Errorf("EstimatedTokens = %d, want %d", preview.EstimatedTokens, wantTokens)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// FileChanges lists the files that differ between a base commit and the checked-out commit
+type FileChanges struct {
+	BaseSHA string   // Full SHA of the base commit
+	Paths   []string // Slash-separated paths added, modified, or deleted; a rename lists both names
+}
+
+// ChangedFiles compares the checked-out commit of a cloned repository against a base commit
+// The clone needs enough history to contain the base; ErrRefNotFound is returned when it doesn't
+func ChangedFiles(repoDir, baseRef string) (*FileChanges, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	baseHash, err := repo.ResolveRevision(plumbing.Revision(baseRef))
+	if err != nil {
+		return nil, fmt.Errorf("base commit %s: %w (%v)", baseRef, ErrRefNotFound, err)
+	}
+	baseTree, err := commitTree(repo, *baseHash)
+	if err != nil {
+		return nil, fmt.Errorf("base commit %s: %w (%v)", baseRef, ErrRefNotFound, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	headTree, err := commitTree(repo, head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("HEAD: %w", err)
+	}
+
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s against HEAD: %w", baseRef, err)
+	}
+
+	// An added file only has a new name and a deleted one only an old name
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				seen[name] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return &FileChanges{BaseSHA: baseHash.String(), Paths: paths}, nil
+}
+
+// commitTree returns the root tree of a commit
+func commitTree(repo *git.Repository, hash plumbing.Hash) (*object.Tree, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commit: %w", err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tree: %w", err)
+	}
+	return tree, nil
+}
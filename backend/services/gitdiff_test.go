@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes the files into the repository's worktree, deletes those mapped to "", and commits
+func commitFiles(t *testing.T, repo *git.Repository, dir string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if content == "" {
+			if _, err := worktree.Remove(name); err != nil {
+				t.Fatalf("remove %s: %v", name, err)
+			}
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := worktree.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	return hash
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("init repository: %v", err)
+	}
+
+	base := commitFiles(t, repo, dir, map[string]string{
+		"main.go":   "package main\n",
+		"db.go":     "package main\n\nvar query = \"SELECT 1\"\n",
+		"legacy.go": "package main\n",
+	})
+	onlyDB := commitFiles(t, repo, dir, map[string]string{"db.go": "package main\n\nvar query = \"SELECT \" + input\n"})
+	commitFiles(t, repo, dir, map[string]string{"legacy.go": "", "api.go": "package main\n"})
+
+	tests := []struct {
+		name string
+		base string
+		want []string
+	}{
+		{name: "one file changed since the base", base: base.String(), want: []string{"api.go", "db.go", "legacy.go"}},
+		{name: "abbreviated base", base: onlyDB.String()[:10], want: []string{"api.go", "legacy.go"}},
+		{name: "base is HEAD", base: "HEAD", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := ChangedFiles(dir, tt.base)
+			if err != nil {
+				t.Fatalf("ChangedFiles returned error: %v", err)
+			}
+			if strings.Join(changes.Paths, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("changed files = %v, want %v", changes.Paths, tt.want)
+			}
+			if !strings.HasPrefix(changes.BaseSHA, strings.TrimSuffix(tt.base, "HEAD")) || len(changes.BaseSHA) != 40 {
+				t.Errorf("base SHA = %s, want the full SHA of %s", changes.BaseSHA, tt.base)
+			}
+		})
+	}
+
+	if _, err := ChangedFiles(dir, "0123456789abcdef"); !errors.Is(err, ErrRefNotFound) {
+		t.Errorf("unknown base: error = %v, want ErrRefNotFound", err)
+	}
+}
+
+func TestChangedFilesInAFullHistoryClone(t *testing.T) {
+	fixture := newGitFixture(t)
+
+	full := t.TempDir()
+	if _, _, err := cloneAtRef(context.Background(), full, fixture.url, "", true, nil); err != nil {
+		t.Fatalf("full clone: %v", err)
+	}
+	changes, err := ChangedFiles(full, fixture.tagged.String())
+	if err != nil {
+		t.Fatalf("full clone: ChangedFiles returned error: %v", err)
+	}
+	if strings.Join(changes.Paths, ",") != "later.go" {
+		t.Errorf("changed files = %v, want only later.go", changes.Paths)
+	}
+}
+
+func TestScanRepositoryOnlyScansChangedFiles(t *testing.T) {
+	root := writeFixtureTree(t, []string{"main.go", "db.go", "api/handler.go"})
+	useModelTransport(t, &slowModelTransport{})
+
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		OnlyFiles:          map[string]bool{"db.go": true, "deleted.go": true},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+	if len(result.Vulnerabilities) != 1 || result.Vulnerabilities[0].FilePath != "db.go" {
+		t.Errorf("findings = %+v, want one in db.go only", result.Vulnerabilities)
+	}
+}
@@ -4,22 +4,24 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/go-git/go-git/v5"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
 	"go.uber.org/zap"
 )
 
-// Repository represents a GitHub repository
+// Repository represents a repository hosted on GitHub or GitLab
 type Repository struct {
 	ID          string
 	Name        string
@@ -31,8 +33,30 @@ type Repository struct {
 	UpdatedAt   string
 	LastScanAt  *string
 	Status      string
+	Provider    string // ProviderGitHub or ProviderGitLab
+	SizeKB      int64  // Size reported by the provider's API; 0 when unknown
 }
 
+// ErrRateLimited is matched (with errors.Is) by every *RateLimitError
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
+// RateLimitError is returned when GitHub refuses a request because the rate limit is used up
+// Reset is when requests are allowed again, so callers can back off until then
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v; resets at %s", ErrRateLimited, e.Reset.UTC().Format(time.RFC3339))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// ErrScanNotFound is returned when looking up a scan ID that has no scan record
+var ErrScanNotFound = errors.New("scan not found")
+
 // GitHubService defines the interface for GitHub operations
 type GitHubService interface {
 	// FetchRepositoryInfo retrieves repository metadata
@@ -41,16 +65,36 @@ type GitHubService interface {
 	// CloneRepository clones a GitHub repository to the local filesystem
 	CloneRepository(ctx context.Context, repo *Repository, targetDir string) error
 
-	// ListFiles lists files in a repository with optional filtering
-	ListFiles(ctx context.Context, repoDir string, extensions []string) ([]string, error)
+	// CloneRepositoryAtRef clones a repository with a branch, tag, or commit checked out
+	// An empty ref clones the default branch. It returns the full reference name checked out,
+	// which is empty for the default branch and for commits.
+	// Branches and the default branch are cloned shallow unless fullHistory is set
+	CloneRepositoryAtRef(ctx context.Context, repo *Repository, targetDir, ref string, fullHistory bool) (string, error)
+
+	// ListFiles lists the files under repoDir with one of the extensions, or every file when none are given
+	// It walks subdirectories, skipping the dependency directories a scan skips, and returns slash-separated
+	// paths relative to repoDir in lexical order
+	ListFiles(ctx context.Context, repoDir string, extensions []string, options ListFilesOptions) ([]string, error)
 
 	CreateRepository(owner, name, url string) (string, error)
+
+	// DeleteRepository removes a repository with its scans, findings, and user links in one transaction
+	// It returns ErrRepositoryNotFound when there is no such repository
+	DeleteRepository(ctx context.Context, repoID string) error
 	ListRepositories(userID string) ([]*Repository, error)
 	GetRepository(id string) (*Repository, error)
 
 	// GetRepositoryVulnerabilities retrieves vulnerabilities for a repository
 	GetRepositoryVulnerabilities(ctx context.Context, repoID string) ([]*Vulnerability, error)
 
+	// QueryRepositoryVulnerabilities returns one page of the findings of a repository's latest scan that match
+	// the filter, with counts across every page
+	QueryRepositoryVulnerabilities(ctx context.Context, repoID string, filter VulnerabilityFilter) (*VulnerabilityPage, error)
+
+	// GetVulnerabilitiesByScanID retrieves the vulnerabilities stored for one scan, latest or not
+	// It returns ErrScanNotFound when there is no scan with that ID
+	GetVulnerabilitiesByScanID(ctx context.Context, scanID string) ([]*Vulnerability, error)
+
 	// AddUserRepository adds a repository for a user
 	AddUserRepository(ctx context.Context, userID string, repoURL string) (*Repository, error)
 
@@ -75,24 +119,29 @@ type gitHubService struct {
 }
 
 func (s *gitHubService) FetchRepositoryInfo(ctx context.Context, owner, repo string) (*Repository, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s/%s", owner, repo), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/repos/%s/%s", s.apiURL, owner, repo), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	// Authenticated requests get 5000 requests an hour instead of 60, and can see private repositories
+	if githubToken := os.Getenv("GITHUB_TOKEN"); githubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+githubToken)
+	}
 
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch repository info: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if err := gitHubResponseError(resp, owner, repo); err != nil {
+		return nil, err
 	}
 
 	var repoInfo struct {
-		ID          int    `json:"id"`
+		ID          int64  `json:"id"`
 		Name        string `json:"name"`
 		Description string `json:"description"`
 		Owner       struct {
@@ -100,28 +149,75 @@ func (s *gitHubService) FetchRepositoryInfo(ctx context.Context, owner, repo str
 		} `json:"owner"`
 		HTMLURL  string `json:"html_url"`
 		CloneURL string `json:"clone_url"`
+		Size     int64  `json:"size"` // In KB
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&repoInfo); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Generate a UUID v5 from the repository ID
-	// This creates a consistent UUID based on the GitHub repo ID
-	repoIDStr := fmt.Sprintf("github-repo-%d", repoInfo.ID)
-	repoUUID := uuid.NewSHA1(uuid.NameSpaceOID, []byte(repoIDStr))
-
 	return &Repository{
-		ID:          repoUUID.String(),
+		ID:          GitHubRepositoryID(repoInfo.ID),
 		Name:        repoInfo.Name,
 		Owner:       repoInfo.Owner.Login,
 		URL:         repoInfo.HTMLURL,
 		CloneURL:    repoInfo.CloneURL,
 		Description: repoInfo.Description,
+		SizeKB:      repoInfo.Size,
 	}, nil
 }
 
+// GitHubRepositoryID returns the stored ID of a GitHub repository: a UUID v5 of GitHub's numeric ID,
+// so it stays the same across renames and transfers
+func GitHubRepositoryID(githubID int64) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("github-repo-%d", githubID))).String()
+}
+
+// gitHubResponseError turns a non-200 GitHub API response into a descriptive error
+// 403 and 429 responses with an exhausted rate limit become a *RateLimitError
+func gitHubResponseError(resp *http.Response, owner, repo string) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		// GitHub also answers 404 for private repositories the token can't see
+		return fmt.Errorf("GitHub repository %s/%s: %w (or it is private and GITHUB_TOKEN has no access)", owner, repo, ErrRepositoryNotFound)
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if reset, limited := gitHubRateLimitReset(resp.Header, time.Now()); limited {
+			return &RateLimitError{Reset: reset}
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return &RateLimitError{Reset: time.Now().Add(time.Minute)}
+		}
+		return fmt.Errorf("access to GitHub repository %s/%s was denied (status 403); check that GITHUB_TOKEN can read it", owner, repo)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("GitHub rejected the credentials (status 401); check GITHUB_TOKEN")
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// gitHubRateLimitReset reports whether a response was refused for rate limiting and when the limit resets
+// The primary limit sets X-RateLimit-Remaining to 0 with a Unix reset time; secondary limits send Retry-After
+func gitHubRateLimitReset(header http.Header, now time.Time) (time.Time, bool) {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}, false
+	}
+	if resetUnix, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return time.Unix(resetUnix, 0), true
+	}
+	return now.Add(time.Minute), true
+}
+
 func (s *gitHubService) CloneRepository(ctx context.Context, repo *Repository, targetDir string) error {
+	_, err := s.CloneRepositoryAtRef(ctx, repo, targetDir, "", false)
+	return err
+}
+
+func (s *gitHubService) CloneRepositoryAtRef(ctx context.Context, repo *Repository, targetDir, ref string, fullHistory bool) (string, error) {
 	log := logger.FromContext(ctx)
 	if log == nil {
 		log = logger.Get()
@@ -129,13 +225,13 @@ func (s *gitHubService) CloneRepository(ctx context.Context, repo *Repository, t
 
 	// Create target directory if it doesn't exist
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("failed to create target directory: %w", err)
+		return "", fmt.Errorf("failed to create target directory: %w", err)
 	}
 
 	// Check if directory is empty, if not, remove contents
 	files, err := os.ReadDir(targetDir)
 	if err != nil {
-		return fmt.Errorf("failed to read target directory: %w", err)
+		return "", fmt.Errorf("failed to read target directory: %w", err)
 	}
 
 	if len(files) > 0 {
@@ -149,6 +245,12 @@ func (s *gitHubService) CloneRepository(ctx context.Context, repo *Repository, t
 		}
 	}
 
+	// SSH clone URLs authenticate with a key; a missing or locked key won't improve on retry
+	auth, err := cloneAuth(repo.CloneURL, sshKeySourceFromEnv())
+	if err != nil {
+		return "", err
+	}
+
 	// Check for GitHub token for authentication
 	githubToken := os.Getenv("GITHUB_TOKEN")
 
@@ -161,9 +263,15 @@ func (s *gitHubService) CloneRepository(ctx context.Context, repo *Repository, t
 	var lastError error
 
 	for i := 0; i < maxRetries; i++ {
+		// Stop retrying as soon as the scan is canceled; a retry would start a fresh clone
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("clone canceled: %w", err)
+		}
+
 		log.Info("Cloning repository",
 			zap.String("url", repo.CloneURL),
 			zap.String("target", targetDir),
+			zap.String("ref", ref),
 			zap.Int("attempt", i+1))
 
 		// Remove .git directory if it exists and we're retrying
@@ -186,11 +294,7 @@ func (s *gitHubService) CloneRepository(ctx context.Context, repo *Repository, t
 		}
 
 		// Clone with or without authentication
-		r, err := git.PlainCloneContext(ctx, targetDir, false, &git.CloneOptions{
-			URL:      cloneURL,
-			Progress: os.Stdout,
-			Depth:    1, // Shallow clone to save time and space
-		})
+		r, checkedOut, err := cloneAtRef(ctx, targetDir, cloneURL, ref, fullHistory, auth)
 
 		if err == nil {
 			// Verify the repository was cloned successfully
@@ -198,13 +302,19 @@ func (s *gitHubService) CloneRepository(ctx context.Context, repo *Repository, t
 			if err == nil {
 				log.Info("Successfully cloned repository",
 					zap.String("repo", repo.Name),
-					zap.String("owner", repo.Owner))
-				return nil
+					zap.String("owner", repo.Owner),
+					zap.String("ref", ref))
+				return checkedOut, nil
 			}
 			lastError = fmt.Errorf("failed to get worktree: %w", err)
 		} else {
 			lastError = fmt.Errorf("failed to clone repository: %w", err)
 
+			// A clone interrupted by cancellation, or of a ref that doesn't exist, is not worth retrying
+			if ctx.Err() != nil || errors.Is(err, ErrRefNotFound) {
+				return "", lastError
+			}
+
 			// If this is an authentication error, try without auth on next attempt
 			if strings.Contains(err.Error(), "authentication") {
 				cloneURL = repo.CloneURL
@@ -218,29 +328,63 @@ func (s *gitHubService) CloneRepository(ctx context.Context, repo *Repository, t
 			zap.Error(err))
 
 		if i < maxRetries-1 {
-			time.Sleep(time.Second * 2)
+			select {
+			case <-ctx.Done():
+				return "", fmt.Errorf("clone canceled: %w", ctx.Err())
+			case <-time.After(time.Second * 2):
+			}
 		}
 	}
 
-	return lastError
+	return "", lastError
 }
 
-func (s *gitHubService) ListFiles(ctx context.Context, repoDir string, extensions []string) ([]string, error) {
-	files, err := os.ReadDir(repoDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %w", err)
-	}
+// ListFilesOptions bounds the walk of ListFiles
+type ListFilesOptions struct {
+	// MaxDepth is how many directory levels are listed: 1 lists only the files directly in repoDir,
+	// 2 also the files of its subdirectories, and so on. 0 means no limit
+	MaxDepth int
+}
 
+func (s *gitHubService) ListFiles(ctx context.Context, repoDir string, extensions []string, options ListFilesOptions) ([]string, error) {
 	var result []string
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	err := filepath.WalkDir(repoDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		relPath, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
 		}
-		ext := filepath.Ext(file.Name())
-		if len(extensions) > 0 && !slices.Contains(extensions, ext) {
-			continue
+		if relPath == "." {
+			return nil
 		}
-		result = append(result, filepath.Join(repoDir, file.Name()))
+		depth := strings.Count(filepath.ToSlash(relPath), "/") + 1
+
+		if entry.IsDir() {
+			// Dependency directories are skipped as in a scan, and a directory at the depth
+			// limit has no files that could be listed
+			if dirsToSkip[entry.Name()] || (options.MaxDepth > 0 && depth >= options.MaxDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := filepath.Ext(entry.Name())
+		if len(extensions) > 0 && !slices.ContainsFunc(extensions, func(want string) bool {
+			return strings.EqualFold(want, ext)
+		}) {
+			return nil
+		}
+		result = append(result, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
 	return result, nil
 }
@@ -295,7 +439,7 @@ func (s *gitHubService) ListRepositories(userID string) ([]*Repository, error) {
 		// If user_repositories table exists, use it to filter repositories by user
 		logger.Get().Info("Using user_repositories table to filter repositories", zap.String("user_id", userID))
 		rows, err = db.QueryContext(ctx, `
-			SELECT r.id, r.name, r.owner, r.url, r.clone_url, r.created_at, r.updated_at, r.last_scan_at, r.status
+			SELECT r.id, r.name, r.owner, r.url, r.clone_url, r.created_at, r.updated_at, r.last_scan_at, r.status, r.provider
 			FROM repositories r
 			JOIN user_repositories ur ON r.id = ur.repository_id
 			WHERE ur.user_id = $1
@@ -323,7 +467,7 @@ func (s *gitHubService) ListRepositories(userID string) ([]*Repository, error) {
 		if createdByExists {
 			logger.Get().Info("Filtering repositories by created_by", zap.String("user_id", userID))
 			rows, err = db.QueryContext(ctx, `
-				SELECT id, name, owner, url, clone_url, created_at, updated_at, last_scan_at, status
+				SELECT id, name, owner, url, clone_url, created_at, updated_at, last_scan_at, status, provider
 				FROM repositories
 				WHERE created_by = $1
 				ORDER BY updated_at DESC
@@ -332,7 +476,7 @@ func (s *gitHubService) ListRepositories(userID string) ([]*Repository, error) {
 			// If neither user_repositories table nor created_by column exists, return all repositories
 			logger.Get().Warn("No way to filter repositories by user, returning all repositories")
 			rows, err = db.QueryContext(ctx, `
-				SELECT id, name, owner, url, clone_url, created_at, updated_at, last_scan_at, status
+				SELECT id, name, owner, url, clone_url, created_at, updated_at, last_scan_at, status, provider
 				FROM repositories
 				ORDER BY updated_at DESC
 			`)
@@ -361,6 +505,7 @@ func (s *gitHubService) ListRepositories(userID string) ([]*Repository, error) {
 			&repo.UpdatedAt,
 			&lastScanAt,
 			&status,
+			&repo.Provider,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan repository row: %w", err)
@@ -391,14 +536,19 @@ func (s *gitHubService) ListRepositories(userID string) ([]*Repository, error) {
 }
 
 func (s *gitHubService) AddUserRepository(ctx context.Context, userID string, repoURL string) (*Repository, error) {
-	// Parse the GitHub URL to extract owner and repo name
-	owner, name, err := parseGitHubURL(repoURL)
+	// Parse the URL with the provider hosting it to extract owner and repo name
+	provider, err := NewRepoProviders(s).ForURL(repoURL)
 	if err != nil {
 		return nil, err
 	}
+	ref, err := provider.ParseURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	owner, name := ref.Owner, ref.Name
 
-	// Fetch repository details from GitHub API
-	repoInfo, err := s.FetchRepositoryInfo(ctx, owner, name)
+	// Fetch repository details from the provider's API
+	repoInfo, err := provider.FetchRepositoryInfo(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
@@ -412,8 +562,8 @@ func (s *gitHubService) AddUserRepository(ctx context.Context, userID string, re
 	// Check if repository already exists
 	var existingRepoID string
 	err = db.QueryRowContext(ctx,
-		`SELECT id FROM repositories WHERE owner = $1 AND name = $2`,
-		owner, name).Scan(&existingRepoID)
+		`SELECT id FROM repositories WHERE owner = $1 AND name = $2 AND provider = $3`,
+		owner, name, provider.Name()).Scan(&existingRepoID)
 
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("error checking for existing repository: %w", err)
@@ -422,10 +572,10 @@ func (s *gitHubService) AddUserRepository(ctx context.Context, userID string, re
 	if err == sql.ErrNoRows {
 		// Repository doesn't exist, create it
 		_, err = db.ExecContext(ctx,
-			`INSERT INTO repositories (id, owner, name, url, clone_url, description, created_at, updated_at, status, created_by)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			`INSERT INTO repositories (id, owner, name, url, clone_url, description, created_at, updated_at, status, created_by, provider, size_kb)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
 			repoInfo.ID, owner, name, repoInfo.URL, repoInfo.CloneURL, repoInfo.Description,
-			time.Now().Format(time.RFC3339), time.Now().Format(time.RFC3339), "pending", userID)
+			time.Now().Format(time.RFC3339), time.Now().Format(time.RFC3339), "pending", userID, provider.Name(), repoInfo.SizeKB)
 		if err != nil {
 			return nil, fmt.Errorf("failed to store repository information: %w", err)
 		}
@@ -440,8 +590,8 @@ func (s *gitHubService) AddUserRepository(ctx context.Context, userID string, re
 	} else {
 		// Repository exists, update it
 		_, err = db.ExecContext(ctx,
-			`UPDATE repositories SET url = $1, clone_url = $2, updated_at = $3 WHERE id = $4`,
-			repoInfo.URL, repoInfo.CloneURL, time.Now().Format(time.RFC3339), existingRepoID)
+			`UPDATE repositories SET url = $1, clone_url = $2, updated_at = $3, size_kb = $4 WHERE id = $5`,
+			repoInfo.URL, repoInfo.CloneURL, time.Now().Format(time.RFC3339), repoInfo.SizeKB, existingRepoID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to update repository information: %w", err)
 		}
@@ -501,7 +651,7 @@ func (s *gitHubService) GetRepository(id string) (*Repository, error) {
 	var status sql.NullString
 
 	err = db.QueryRowContext(ctx, `
-		SELECT id, name, owner, url, clone_url, created_at, updated_at, last_scan_at, status
+		SELECT id, name, owner, url, clone_url, created_at, updated_at, last_scan_at, status, provider
 		FROM repositories
 		WHERE id = $1
 	`, id).Scan(
@@ -514,6 +664,7 @@ func (s *gitHubService) GetRepository(id string) (*Repository, error) {
 		&repo.UpdatedAt,
 		&lastScanAt,
 		&status,
+		&repo.Provider,
 	)
 
 	if err != nil {
@@ -547,6 +698,19 @@ func (s *gitHubService) GetRepositoryVulnerabilities(ctx context.Context, repoID
 		return nil, fmt.Errorf("database connection not available")
 	}
 
+	scanID, err := latestScanWithResults(ctx, db, repoID)
+	if err != nil {
+		return nil, err
+	}
+	if scanID == "" {
+		return []*Vulnerability{}, nil
+	}
+	return scanVulnerabilities(ctx, db, scanID)
+}
+
+// latestScanWithResults returns the ID of the repository's latest scan, or "" when it has never been scanned
+// Along the way it marks the scan's results available if findings were stored without the flag being set
+func latestScanWithResults(ctx context.Context, db *sql.DB, repoID string) (string, error) {
 	// Check if necessary tables exist
 	var tablesExist bool
 	err := db.QueryRowContext(ctx, `
@@ -562,8 +726,8 @@ func (s *gitHubService) GetRepositoryVulnerabilities(ctx context.Context, repoID
 	`).Scan(&tablesExist)
 
 	if err != nil || !tablesExist {
-		// If tables don't exist, return empty list
-		return []*Vulnerability{}, nil
+		// If tables don't exist, there is nothing to report
+		return "", nil
 	}
 
 	// First, find the latest scan for this repository
@@ -574,9 +738,9 @@ func (s *gitHubService) GetRepositoryVulnerabilities(ctx context.Context, repoID
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// No scans found for this repository
-			return []*Vulnerability{}, nil
+			return "", nil
 		}
-		return nil, fmt.Errorf("failed to find latest scan: %w", err)
+		return "", fmt.Errorf("failed to find latest scan: %w", err)
 	}
 
 	// Ensure results_available flag is set if we have vulnerabilities
@@ -618,21 +782,56 @@ func (s *gitHubService) GetRepositoryVulnerabilities(ctx context.Context, repoID
 		}
 	}
 
-	// Query the vulnerabilities for this scan
+	return scanID, nil
+}
+
+func (s *gitHubService) GetVulnerabilitiesByScanID(ctx context.Context, scanID string) ([]*Vulnerability, error) {
+	db := s.db.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	var exists bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM scans WHERE id::text = $1)`, scanID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up scan %s: %w", scanID, err)
+	}
+	if !exists {
+		return nil, ErrScanNotFound
+	}
+
+	return scanVulnerabilities(ctx, db, scanID)
+}
+
+// scanVulnerabilities loads the findings stored for a scan, most severe first
+// Findings the repository suppressed are left out
+func scanVulnerabilities(ctx context.Context, db *sql.DB, scanID string) ([]*Vulnerability, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT id, vulnerability_type, file_path, line_start, line_end, severity, description,
-		remediation, code_snippet FROM vulnerabilities WHERE scan_id = $1`,
+		`SELECT v.id, v.vulnerability_type, v.file_path, v.line_start, v.line_end, v.severity, v.description,
+		v.remediation, v.code_snippet, v.excluded, `+baselinedColumnSQL+`, v.confidence, v.cwe, v.reference_urls
+		FROM vulnerabilities v WHERE v.scan_id = $1 AND `+notSuppressedSQL+`
+		ORDER BY v.severity_rank DESC, v.file_path, v.line_start`,
 		scanID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query vulnerabilities: %w", err)
 	}
+	return scanVulnerabilityRows(rows)
+}
+
+// scanVulnerabilityRows reads findings selected with the columns scanVulnerabilities lists, in that order,
+// and closes the rows
+func scanVulnerabilityRows(rows *sql.Rows) ([]*Vulnerability, error) {
 	defer rows.Close()
 
-	var vulnerabilities []*Vulnerability
+	vulnerabilities := []*Vulnerability{}
 	for rows.Next() {
 		vuln := &Vulnerability{}
 		var vulnerabilityType string
 		var remediation, codeSnippet sql.NullString
+		var confidence sql.NullFloat64
+		var cwe sql.NullString
+		var references pq.StringArray
 
 		err := rows.Scan(
 			&vuln.ID,
@@ -644,6 +843,11 @@ func (s *gitHubService) GetRepositoryVulnerabilities(ctx context.Context, repoID
 			&vuln.Description,
 			&remediation,
 			&codeSnippet,
+			&vuln.Excluded,
+			&vuln.Baselined,
+			&confidence,
+			&cwe,
+			&references,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan vulnerability row: %w", err)
@@ -657,45 +861,22 @@ func (s *gitHubService) GetRepositoryVulnerabilities(ctx context.Context, repoID
 		if codeSnippet.Valid {
 			vuln.Code = codeSnippet.String
 		}
+		if confidence.Valid {
+			vuln.Confidence = &confidence.Float64
+		}
+		vuln.CWE = cwe.String
+		vuln.References = references
 
 		vulnerabilities = append(vulnerabilities, vuln)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error while iterating over vulnerability rows: %w", err)
 	}
 
 	return vulnerabilities, nil
 }
 
-// Helper function to parse GitHub URLs
-func parseGitHubURL(url string) (owner, name string, err error) {
-	// GitHub URL formats:
-	// - https://github.com/owner/repo
-	// - https://github.com/owner/repo.git
-	// - git@github.com:owner/repo.git
-
-	if strings.HasPrefix(url, "https://github.com/") {
-		parts := strings.Split(strings.TrimPrefix(url, "https://github.com/"), "/")
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid GitHub URL format")
-		}
-		owner = parts[0]
-		name = strings.TrimSuffix(parts[1], ".git")
-		return owner, name, nil
-	} else if strings.HasPrefix(url, "git@github.com:") {
-		parts := strings.Split(strings.TrimPrefix(url, "git@github.com:"), "/")
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid GitHub URL format")
-		}
-		owner = parts[0]
-		name = strings.TrimSuffix(parts[1], ".git")
-		return owner, name, nil
-	}
-
-	return "", "", fmt.Errorf("unsupported GitHub URL format")
-}
-
 func (s *gitHubService) CreateRepository(owner, name, url string) (string, error) {
 	// Get the database connection
 	db := s.db.GetDB()
@@ -725,6 +906,51 @@ func (s *gitHubService) CreateRepository(owner, name, url string) (string, error
 	return repoID, nil
 }
 
+func (s *gitHubService) DeleteRepository(ctx context.Context, repoID string) error {
+	db := s.db.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin repository deletion: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Children are removed before their parents, since vulnerabilities, scans, and user links
+	// reference their parent rows without ON DELETE CASCADE. Scan files, baseline fingerprints,
+	// and branch state cascade from the rows deleted here.
+	steps := []struct {
+		what  string
+		query string
+	}{
+		{"vulnerabilities", `DELETE FROM vulnerabilities WHERE scan_id IN (SELECT id FROM scans WHERE repository_id::text = $1)`},
+		{"baseline scan", `UPDATE repositories SET baseline_scan_id = NULL WHERE id::text = $1`},
+		{"scans", `DELETE FROM scans WHERE repository_id::text = $1`},
+		{"user links", `DELETE FROM user_repositories WHERE repository_id::text = $1`},
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, repoID); err != nil {
+			return fmt.Errorf("failed to delete %s of repository %s: %w", step.what, repoID, err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM repositories WHERE id::text = $1`, repoID)
+	if err != nil {
+		return fmt.Errorf("failed to delete repository %s: %w", repoID, err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return ErrRepositoryNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit repository deletion: %w", err)
+	}
+
+	return nil
+}
+
 // GetDatabaseConnection returns the database connection
 func (s *gitHubService) GetDatabaseConnection() *sql.DB {
 	if s.db != nil {
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGetRepositoryVulnerabilitiesListsWorstFirst(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, scanID := createTestScan(t, queries)
+
+	// Inserted in an order that sorts wrongly both alphabetically and by insertion
+	for i, severity := range []string{"Low", "Critical", "Medium", "High", "Low", "Critical"} {
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description, severity_rank)
+			VALUES ($1, 'Injection', 'main.go', $2, $2, $3, 'finding', $4)`,
+			scanID, i+1, severity, SeverityRank(severity)); err != nil {
+			t.Fatalf("insert finding: %v", err)
+		}
+	}
+
+	vulnerabilities, err := NewGitHubService(queries).GetRepositoryVulnerabilities(ctx, repoID)
+	if err != nil {
+		t.Fatalf("GetRepositoryVulnerabilities returned error: %v", err)
+	}
+
+	want := []string{"Critical", "Critical", "High", "Medium", "Low", "Low"}
+	if len(vulnerabilities) != len(want) {
+		t.Fatalf("got %d findings, want %d", len(vulnerabilities), len(want))
+	}
+	for i, vuln := range vulnerabilities {
+		if vuln.Severity != want[i] {
+			t.Errorf("finding %d is %s, want %s", i, vuln.Severity, want[i])
+		}
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	root := writeFixtureTree(t, []string{
+		"main.go",
+		"README.md",
+		"cmd/server/main.go",
+		"src/app/handler.PY",
+		"src/app/models/user.py",
+		"src/util.js",
+		"node_modules/left-pad/index.js",
+		"vendor/github.com/pkg/errors/errors.go",
+		".git/hooks/pre-commit.py",
+	})
+
+	tests := []struct {
+		name       string
+		extensions []string
+		maxDepth   int
+		want       []string
+	}{
+		{
+			name: "all files without limits",
+			want: []string{
+				"README.md",
+				"cmd/server/main.go",
+				"main.go",
+				"src/app/handler.PY",
+				"src/app/models/user.py",
+				"src/util.js",
+			},
+		},
+		{
+			name:       "extension filter reaches nested files",
+			extensions: []string{".go"},
+			want:       []string{"cmd/server/main.go", "main.go"},
+		},
+		{
+			name:       "extension filter ignores case",
+			extensions: []string{".py"},
+			want:       []string{"src/app/handler.PY", "src/app/models/user.py"},
+		},
+		{
+			name:     "depth one lists the root only",
+			maxDepth: 1,
+			want:     []string{"README.md", "main.go"},
+		},
+		{
+			name:     "depth two lists one level of subdirectories",
+			maxDepth: 2,
+			want:     []string{"README.md", "main.go", "src/util.js"},
+		},
+		{
+			name:       "depth three reaches files two directories down",
+			extensions: []string{".go", ".py"},
+			maxDepth:   3,
+			want:       []string{"cmd/server/main.go", "main.go", "src/app/handler.PY"},
+		},
+	}
+
+	service := &gitHubService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.ListFiles(context.Background(), root, tt.extensions, ListFilesOptions{MaxDepth: tt.maxDepth})
+			if err != nil {
+				t.Fatalf("ListFiles returned error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListFiles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListFilesErrors(t *testing.T) {
+	service := &gitHubService{}
+
+	if _, err := service.ListFiles(context.Background(), filepath.Join(t.TempDir(), "missing"), nil, ListFilesOptions{}); err == nil {
+		t.Error("ListFiles of a missing directory returned no error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	root := writeFixtureTree(t, []string{"main.go"})
+	if _, err := service.ListFiles(ctx, root, nil, ListFilesOptions{}); err == nil {
+		t.Error("ListFiles with a canceled context returned no error")
+	}
+}
+
+func TestGetVulnerabilitiesByScanID(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, olderScanID := createTestScan(t, queries)
+
+	if _, err := queries.GetDB().ExecContext(ctx,
+		`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description, severity_rank)
+		VALUES ($1, 'Injection', 'main.go', 3, 3, 'High', 'finding', $2)`,
+		olderScanID, SeverityRank("High")); err != nil {
+		t.Fatalf("insert finding: %v", err)
+	}
+	// A newer scan without findings becomes the repository's latest
+	var newerScanID string
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, created_at) VALUES ($1, 'completed', NOW() + INTERVAL '1 minute') RETURNING id`,
+		repoID).Scan(&newerScanID); err != nil {
+		t.Fatalf("insert newer scan: %v", err)
+	}
+
+	service := NewGitHubService(queries)
+	tests := []struct {
+		name      string
+		scanID    string
+		wantCount int
+		wantErr   error
+	}{
+		{name: "older scan keeps its findings", scanID: olderScanID, wantCount: 1},
+		{name: "scan without findings", scanID: newerScanID, wantCount: 0},
+		{name: "unknown scan", scanID: "00000000-0000-0000-0000-000000000000", wantErr: ErrScanNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vulnerabilities, err := service.GetVulnerabilitiesByScanID(ctx, tt.scanID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetVulnerabilitiesByScanID error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// An empty scan is an empty list, not null, in the JSON response
+			if vulnerabilities == nil || len(vulnerabilities) != tt.wantCount {
+				t.Errorf("got %v, want %d findings", vulnerabilities, tt.wantCount)
+			}
+		})
+	}
+}
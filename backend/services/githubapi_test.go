@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newFakeGitHub starts a GitHub API stand-in that answers with respond and records the Authorization header
+func newFakeGitHub(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) (*gitHubService, *string) {
+	t.Helper()
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return &gitHubService{client: server.Client(), apiURL: server.URL}, &authorization
+}
+
+func TestFetchRepositoryInfoSendsTheToken(t *testing.T) {
+	service, authorization := newFakeGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/octocat/hello-world" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"id": 1296269, "name": "hello-world", "owner": {"login": "octocat"},
+			"html_url": "https://github.com/octocat/hello-world", "clone_url": "https://github.com/octocat/hello-world.git"}`)
+	})
+
+	t.Setenv("GITHUB_TOKEN", "ghp-test")
+	repo, err := service.FetchRepositoryInfo(context.Background(), "octocat", "hello-world")
+	if err != nil {
+		t.Fatalf("FetchRepositoryInfo returned error: %v", err)
+	}
+	if *authorization != "Bearer ghp-test" {
+		t.Errorf("Authorization = %q, want the GITHUB_TOKEN bearer", *authorization)
+	}
+	if repo.Name != "hello-world" || repo.Owner != "octocat" {
+		t.Errorf("repository = %+v, want octocat/hello-world", repo)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	if _, err := service.FetchRepositoryInfo(context.Background(), "octocat", "hello-world"); err != nil {
+		t.Fatalf("FetchRepositoryInfo without a token returned error: %v", err)
+	}
+	if *authorization != "" {
+		t.Errorf("Authorization = %q sent without GITHUB_TOKEN", *authorization)
+	}
+}
+
+func TestFetchRepositoryInfoReportsGitHubErrors(t *testing.T) {
+	reset := time.Now().Add(20 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name          string
+		status        int
+		header        map[string]string
+		wantErr       error
+		wantReset     time.Time // Checked for rate-limit errors
+		wantResetSoon bool      // The reset is estimated rather than reported
+	}{
+		{name: "primary rate limit", status: http.StatusForbidden,
+			header:  map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+			wantErr: ErrRateLimited, wantReset: reset},
+		{name: "secondary rate limit", status: http.StatusForbidden, header: map[string]string{"Retry-After": "60"},
+			wantErr: ErrRateLimited, wantResetSoon: true},
+		{name: "429 without headers", status: http.StatusTooManyRequests, wantErr: ErrRateLimited, wantResetSoon: true},
+		{name: "forbidden with requests left", status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Remaining": "42"}},
+		{name: "not found", status: http.StatusNotFound, wantErr: ErrRepositoryNotFound},
+		{name: "bad credentials", status: http.StatusUnauthorized},
+		{name: "server error", status: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newFakeGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+				for key, value := range tt.header {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(tt.status)
+			})
+
+			_, err := service.FetchRepositoryInfo(context.Background(), "octocat", "hello-world")
+			if err == nil {
+				t.Fatal("FetchRepositoryInfo returned no error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (errors.Is(err, ErrRateLimited) || errors.Is(err, ErrRepositoryNotFound)) {
+				t.Fatalf("error = %v, want neither a rate limit nor a missing repository", err)
+			}
+
+			var rateLimited *RateLimitError
+			if !errors.As(err, &rateLimited) {
+				return
+			}
+			if !tt.wantReset.IsZero() && !rateLimited.Reset.Equal(tt.wantReset) {
+				t.Errorf("reset = %v, want %v", rateLimited.Reset, tt.wantReset)
+			}
+			if tt.wantResetSoon {
+				if wait := time.Until(rateLimited.Reset); wait <= 0 || wait > 2*time.Minute {
+					t.Errorf("reset in %v, want within a couple of minutes", wait)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// VerifyGitHubSignature reports whether an X-Hub-Signature-256 header is the HMAC-SHA256 of the payload
+// under the webhook secret. The comparison is constant-time
+func VerifyGitHubSignature(secret string, payload []byte, signatureHeader string) bool {
+	if secret == "" {
+		return false
+	}
+	hexDigest, found := strings.CutPrefix(signatureHeader, "sha256=")
+	if !found {
+		return false
+	}
+	signature, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// GitHubPushEvent is the part of a GitHub push event payload needed to scan the pushed commit
+type GitHubPushEvent struct {
+	Ref     string `json:"ref"`     // Pushed reference, e.g. refs/heads/main or refs/tags/v1.0
+	Before  string `json:"before"`  // Commit the reference pointed at before the push
+	After   string `json:"after"`   // Commit the reference points at now; all zeros when deleted
+	Deleted bool   `json:"deleted"` // The push deleted the reference
+
+	Repository struct {
+		ID       int64  `json:"id"`        // GitHub's numeric repository ID
+		Name     string `json:"name"`      // Repository name
+		FullName string `json:"full_name"` // owner/name
+		HTMLURL  string `json:"html_url"`
+		CloneURL string `json:"clone_url"`
+		Owner    struct {
+			Login string `json:"login"`
+			Name  string `json:"name"` // Push payloads name the owner here rather than in login
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// OwnerLogin returns the repository owner's login, whichever field the payload put it in
+func (e *GitHubPushEvent) OwnerLogin() string {
+	if e.Repository.Owner.Login != "" {
+		return e.Repository.Owner.Login
+	}
+	if e.Repository.Owner.Name != "" {
+		return e.Repository.Owner.Name
+	}
+	owner, _, _ := strings.Cut(e.Repository.FullName, "/")
+	return owner
+}
+
+// ParseGitHubPushEvent decodes a push event payload
+func ParseGitHubPushEvent(payload []byte) (*GitHubPushEvent, error) {
+	var event GitHubPushEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid push event payload: %w", err)
+	}
+	if event.Ref == "" || event.Repository.ID == 0 {
+		return nil, fmt.Errorf("invalid push event payload: missing ref or repository")
+	}
+	return &event, nil
+}
+
+// IsDeletion reports whether the push removed its reference rather than adding commits
+func (e *GitHubPushEvent) IsDeletion() bool {
+	return e.Deleted || strings.Trim(e.After, "0") == ""
+}
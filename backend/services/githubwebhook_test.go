@@ -0,0 +1,104 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// signGitHubPayload returns the X-Hub-Signature-256 header GitHub would send for payload
+func signGitHubPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	payload := []byte(`{"ref": "refs/heads/main"}`)
+	valid := signGitHubPayload("webhook-secret", payload)
+
+	tests := []struct {
+		name      string
+		secret    string
+		payload   []byte
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: "webhook-secret", payload: payload, signature: valid, want: true},
+		{name: "wrong secret", secret: "other-secret", payload: payload, signature: valid},
+		{name: "tampered payload", secret: "webhook-secret", payload: []byte(`{"ref": "refs/heads/evil"}`), signature: valid},
+		{name: "missing prefix", secret: "webhook-secret", payload: payload, signature: valid[len("sha256="):]},
+		{name: "sha1 signature", secret: "webhook-secret", payload: payload, signature: "sha1=" + valid[len("sha256="):]},
+		{name: "not hex", secret: "webhook-secret", payload: payload, signature: "sha256=zz"},
+		{name: "empty signature", secret: "webhook-secret", payload: payload},
+		{name: "no secret configured", payload: payload, signature: signGitHubPayload("", payload)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyGitHubSignature(tt.secret, tt.payload, tt.signature); got != tt.want {
+				t.Errorf("VerifyGitHubSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGitHubPushEvent(t *testing.T) {
+	event, err := ParseGitHubPushEvent([]byte(`{
+		"ref": "refs/heads/main",
+		"before": "1111111111111111111111111111111111111111",
+		"after": "2222222222222222222222222222222222222222",
+		"repository": {"id": 42, "name": "repo", "full_name": "octo/repo", "owner": {"name": "octo"}}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseGitHubPushEvent returned error: %v", err)
+	}
+	if event.Ref != "refs/heads/main" || event.Repository.ID != 42 || event.IsDeletion() {
+		t.Errorf("event = %+v, want a push to main of repository 42", event)
+	}
+	if got := event.OwnerLogin(); got != "octo" {
+		t.Errorf("OwnerLogin() = %s, want the owner name", got)
+	}
+
+	for name, payload := range map[string]string{
+		"malformed":          `{"ref":`,
+		"missing ref":        `{"repository": {"id": 42}}`,
+		"missing repository": `{"ref": "refs/heads/main"}`,
+	} {
+		if _, err := ParseGitHubPushEvent([]byte(payload)); err == nil {
+			t.Errorf("ParseGitHubPushEvent accepted a %s payload", name)
+		}
+	}
+}
+
+func TestGitHubPushEventIsDeletion(t *testing.T) {
+	tests := []struct {
+		name  string
+		event GitHubPushEvent
+		want  bool
+	}{
+		{name: "new commits", event: GitHubPushEvent{After: "2222222222222222222222222222222222222222"}},
+		{name: "deleted flag", event: GitHubPushEvent{After: "2222222222222222222222222222222222222222", Deleted: true}, want: true},
+		{name: "zero after", event: GitHubPushEvent{After: "0000000000000000000000000000000000000000"}, want: true},
+		{name: "no after", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.event.IsDeletion(); got != tt.want {
+			t.Errorf("%s: IsDeletion() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubPushEventOwnerLogin(t *testing.T) {
+	var event GitHubPushEvent
+	event.Repository.FullName = "octo/repo"
+	if got := event.OwnerLogin(); got != "octo" {
+		t.Errorf("OwnerLogin() = %s, want the owner from full_name", got)
+	}
+	event.Repository.Owner.Login = "octo-login"
+	if got := event.OwnerLogin(); got != "octo-login" {
+		t.Errorf("OwnerLogin() = %s, want the login", got)
+	}
+}
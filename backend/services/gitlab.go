@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewGitLabProvider creates a provider for gitlab.com and self-hosted GitLab instances
+// apiURL overrides the REST API base (e.g. a test server); empty derives it from each repository's host.
+// GITLAB_TOKEN, when set, is sent to the API and used to clone private projects
+func NewGitLabProvider(apiURL string) RepoProvider {
+	return &gitLabProvider{
+		client: &http.Client{Timeout: 30 * time.Second},
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+	}
+}
+
+// gitLabProvider implements RepoProvider with the GitLab REST API v4
+type gitLabProvider struct {
+	client *http.Client
+	apiURL string
+}
+
+func (p *gitLabProvider) Name() string {
+	return ProviderGitLab
+}
+
+// ParseURL accepts https://host/group/[subgroup/]project[.git], git@host:group/project.git,
+// and host/group/project; links into a project such as /-/tree/main are trimmed to the project
+// The host is taken as given; ForURL is what limits it to configured GitLab instances
+func (p *gitLabProvider) ParseURL(repoURL string) (*RepoRef, error) {
+	parts, err := splitRepoURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	path := parts.path
+	for i, segment := range path {
+		if segment == "-" {
+			path = path[:i]
+			break
+		}
+	}
+	if len(path) < 2 {
+		return nil, invalidRepoURL("expected a group and project, e.g. https://gitlab.com/group/project")
+	}
+	path[len(path)-1] = strings.TrimSuffix(path[len(path)-1], ".git")
+	if err := validateGitLabPath(path); err != nil {
+		return nil, err
+	}
+
+	// SSH URLs map to the instance's web UI, which serves the API
+	scheme := parts.scheme
+	if scheme == "ssh" {
+		scheme = "https"
+	}
+	return &RepoRef{
+		Provider: ProviderGitLab,
+		BaseURL:  scheme + "://" + parts.host,
+		Owner:    strings.Join(path[:len(path)-1], "/"),
+		Name:     path[len(path)-1],
+	}, nil
+}
+
+func (p *gitLabProvider) FetchRepositoryInfo(ctx context.Context, ref *RepoRef) (*Repository, error) {
+	apiURL := p.apiURL
+	if apiURL == "" {
+		apiURL = ref.BaseURL + "/api/v4"
+	}
+
+	// Projects can be looked up by their URL-encoded full path instead of the numeric ID
+	projectPath := url.PathEscape(ref.Owner + "/" + ref.Name)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL+"/projects/"+projectPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch repository info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// GitLab also answers 404 for private projects the token can't see
+		return nil, fmt.Errorf("GitLab project %s/%s: %w (or it is private and GITLAB_TOKEN has no access)", ref.Owner, ref.Name, ErrRepositoryNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var project struct {
+		ID            int    `json:"id"`
+		Path          string `json:"path"`
+		Description   string `json:"description"`
+		WebURL        string `json:"web_url"`
+		HTTPURLToRepo string `json:"http_url_to_repo"`
+		Namespace     struct {
+			FullPath string `json:"full_path"`
+		} `json:"namespace"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Project IDs are only unique per instance, so the host is part of the stable repository ID
+	host := strings.TrimPrefix(strings.TrimPrefix(ref.BaseURL, "https://"), "http://")
+	repoUUID := uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("gitlab-repo-%s-%d", host, project.ID)))
+
+	return &Repository{
+		ID:          repoUUID.String(),
+		Name:        project.Path,
+		Owner:       project.Namespace.FullPath,
+		URL:         project.WebURL,
+		CloneURL:    project.HTTPURLToRepo,
+		Description: project.Description,
+		Provider:    ProviderGitLab,
+	}, nil
+}
+
+// AuthenticatedCloneURL puts GITLAB_TOKEN into an HTTPS clone URL; GitLab accepts any user name with a token
+func (p *gitLabProvider) AuthenticatedCloneURL(cloneURL string) (string, error) {
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("repository requires authentication but GITLAB_TOKEN environment variable is not set")
+	}
+
+	parsed, err := url.Parse(cloneURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return "", fmt.Errorf("repository requires authentication but URL format is not supported for authentication")
+	}
+	parsed.User = url.UserPassword("oauth2", token)
+	return parsed.String(), nil
+}
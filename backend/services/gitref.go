@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// ErrRefNotFound is returned when a requested branch, tag, or commit doesn't exist in the repository
+var ErrRefNotFound = errors.New("ref not found")
+
+// commitRefPattern matches abbreviated and full commit SHAs
+var commitRefPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// IsCommitRef reports whether a requested ref names a commit rather than a branch or tag
+// Any 7-40 character hex string is treated as a commit, matching what users copy from git log
+func IsCommitRef(ref string) bool {
+	return commitRefPattern.MatchString(ref)
+}
+
+// candidateReferenceNames lists the references a branch or tag name may refer to, in lookup order
+// Fully qualified names ("refs/heads/main", "refs/tags/v1.0") are used as given
+func candidateReferenceNames(ref string) []plumbing.ReferenceName {
+	if strings.HasPrefix(ref, "refs/") {
+		return []plumbing.ReferenceName{plumbing.ReferenceName(ref)}
+	}
+	return []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)}
+}
+
+// cloneAtRef clones cloneURL into targetDir with ref checked out
+// An empty ref shallow-clones the default branch. Branches and tags are shallow-cloned directly;
+// a commit needs the full history so it can be checked out after the clone.
+// It returns the repository and the full reference name checked out, which is empty for the
+// default branch and for commits.
+// fullHistory disables shallow clones, for incremental scans that diff against an older commit.
+// auth is nil for URLs that need no separate credentials
+func cloneAtRef(ctx context.Context, targetDir, cloneURL, ref string, fullHistory bool, auth transport.AuthMethod) (*git.Repository, string, error) {
+	depth := 1 // Shallow clone to save time and space
+	if fullHistory {
+		depth = 0
+	}
+
+	if ref == "" {
+		r, err := git.PlainCloneContext(ctx, targetDir, false, &git.CloneOptions{
+			URL:      cloneURL,
+			Auth:     auth,
+			Progress: os.Stdout,
+			Depth:    depth,
+		})
+		return r, "", err
+	}
+
+	if IsCommitRef(ref) {
+		r, err := git.PlainCloneContext(ctx, targetDir, false, &git.CloneOptions{
+			URL:      cloneURL,
+			Auth:     auth,
+			Progress: os.Stdout,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		hash, err := r.ResolveRevision(plumbing.Revision(ref))
+		if err != nil {
+			return nil, "", fmt.Errorf("commit %s: %w (%v)", ref, ErrRefNotFound, err)
+		}
+		worktree, err := r.Worktree()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get worktree: %w", err)
+		}
+		if err := worktree.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+			return nil, "", fmt.Errorf("failed to check out commit %s: %w", ref, err)
+		}
+		return r, "", nil
+	}
+
+	// Try the name as a branch first, then as a tag
+	var lastErr error
+	for _, name := range candidateReferenceNames(ref) {
+		r, err := git.PlainCloneContext(ctx, targetDir, false, &git.CloneOptions{
+			URL:           cloneURL,
+			Auth:          auth,
+			Progress:      os.Stdout,
+			ReferenceName: name,
+			SingleBranch:  true,
+			Depth:         depth,
+		})
+		if err == nil {
+			return r, name.String(), nil
+		}
+		if !errors.Is(err, git.NoMatchingRefSpecError{}) {
+			return nil, "", err
+		}
+
+		// The failed attempt may leave git metadata behind, which would block the next clone
+		os.RemoveAll(filepath.Join(targetDir, ".git"))
+		lastErr = err
+	}
+
+	return nil, "", fmt.Errorf("%q is not a branch or tag: %w (%v)", ref, ErrRefNotFound, lastErr)
+}
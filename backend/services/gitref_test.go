@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// gitFixture is a local repository with a second branch and a tag, cloned over file://
+type gitFixture struct {
+	url      string
+	tagged   plumbing.Hash // First commit on master, tagged v1.0
+	feature  plumbing.Hash // Tip of the feature branch, adding feature.go
+	headMain plumbing.Hash // Tip of master, adding later.go
+}
+
+// newGitFixture builds the fixture repository:
+// master: main.go (tagged v1.0) -> later.go; feature branches from the tag and adds feature.go
+func newGitFixture(t *testing.T) *gitFixture {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("init repository: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile := func(name string) plumbing.Hash {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := worktree.Commit("add "+name, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("commit %s: %v", name, err)
+		}
+		return hash
+	}
+
+	fixture := &gitFixture{url: "file://" + dir}
+	fixture.tagged = commitFile("main.go")
+	if _, err := repo.CreateTag("v1.0", fixture.tagged, nil); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}); err != nil {
+		t.Fatalf("create feature branch: %v", err)
+	}
+	fixture.feature = commitFile("feature.go")
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}); err != nil {
+		t.Fatalf("check out master: %v", err)
+	}
+	fixture.headMain = commitFile("later.go")
+	return fixture
+}
+
+func TestCloneAtRef(t *testing.T) {
+	fixture := newGitFixture(t)
+
+	tests := []struct {
+		name        string
+		ref         string
+		wantRef     string
+		wantCommit  plumbing.Hash
+		wantFile    string // Only present at the requested ref
+		missingFile string // Absent at the requested ref
+	}{
+		{name: "default branch", wantCommit: fixture.headMain, wantFile: "later.go", missingFile: "feature.go"},
+		{name: "branch", ref: "feature", wantRef: "refs/heads/feature", wantCommit: fixture.feature, wantFile: "feature.go", missingFile: "later.go"},
+		{name: "tag", ref: "v1.0", wantRef: "refs/tags/v1.0", wantCommit: fixture.tagged, wantFile: "main.go", missingFile: "later.go"},
+		{name: "fully qualified branch", ref: "refs/heads/feature", wantRef: "refs/heads/feature", wantCommit: fixture.feature, wantFile: "feature.go"},
+		{name: "abbreviated commit", ref: fixture.tagged.String()[:10], wantCommit: fixture.tagged, wantFile: "main.go", missingFile: "later.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := t.TempDir()
+			repo, checkedOut, err := cloneAtRef(context.Background(), target, fixture.url, tt.ref, false, nil)
+			if err != nil {
+				t.Fatalf("cloneAtRef(%q) returned error: %v", tt.ref, err)
+			}
+			if checkedOut != tt.wantRef {
+				t.Errorf("checked out %q, want %q", checkedOut, tt.wantRef)
+			}
+
+			head, err := repo.Head()
+			if err != nil {
+				t.Fatalf("read HEAD: %v", err)
+			}
+			if head.Hash() != tt.wantCommit {
+				t.Errorf("HEAD is at %s, want %s", head.Hash(), tt.wantCommit)
+			}
+			if _, err := os.Stat(filepath.Join(target, tt.wantFile)); err != nil {
+				t.Errorf("%s missing from the clone: %v", tt.wantFile, err)
+			}
+			if tt.missingFile != "" {
+				if _, err := os.Stat(filepath.Join(target, tt.missingFile)); err == nil {
+					t.Errorf("%s is in the clone, want it absent at %q", tt.missingFile, tt.ref)
+				}
+			}
+		})
+	}
+}
+
+func TestCloneAtRefReportsUnknownRefs(t *testing.T) {
+	fixture := newGitFixture(t)
+
+	for _, ref := range []string{"no-such-branch", "0123456789abcdef"} {
+		if _, _, err := cloneAtRef(context.Background(), t.TempDir(), fixture.url, ref, false, nil); !errors.Is(err, ErrRefNotFound) {
+			t.Errorf("cloneAtRef(%q) error = %v, want ErrRefNotFound", ref, err)
+		}
+	}
+}
+
+func TestIsCommitRef(t *testing.T) {
+	tests := map[string]bool{
+		"a1b2c3d": true,
+		"A1B2C3D4E5F60718293a4b5c6d7e8f9012345678": true,
+		"a1b2c3":    false, // Too short to be unambiguous
+		"main":      false,
+		"v1.0":      false,
+		"deadbeefg": false,
+	}
+	for ref, want := range tests {
+		if got := IsCommitRef(ref); got != want {
+			t.Errorf("IsCommitRef(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import "sort"
+
+// FileFindings is the findings of one file together with a per-file severity summary
+type FileFindings struct {
+	FilePath           string           `json:"file_path"`
+	VulnerabilityCount int              `json:"vulnerability_count"` // Findings that are neither excluded nor baselined
+	SeveritySummary    map[string]int   `json:"severity_summary"`    // Reported findings per severity
+	Vulnerabilities    []*Vulnerability `json:"vulnerabilities"`
+}
+
+// GroupFindingsByFile nests findings under the file they were reported in
+// Files are ordered by path and keep the incoming finding order, so the view is stable across requests.
+// Excluded and baselined findings are listed if present but left out of the count and summary,
+// matching how scan-level counts are reported
+func GroupFindingsByFile(vulns []*Vulnerability) []*FileFindings {
+	byPath := make(map[string]*FileFindings)
+	for _, vuln := range vulns {
+		group, ok := byPath[vuln.FilePath]
+		if !ok {
+			group = &FileFindings{
+				FilePath:        vuln.FilePath,
+				SeveritySummary: map[string]int{},
+				Vulnerabilities: []*Vulnerability{},
+			}
+			byPath[vuln.FilePath] = group
+		}
+
+		group.Vulnerabilities = append(group.Vulnerabilities, vuln)
+		if !vuln.Excluded && !vuln.Baselined {
+			group.VulnerabilityCount++
+			group.SeveritySummary[vuln.Severity]++
+		}
+	}
+
+	groups := make([]*FileFindings, 0, len(byPath))
+	for _, group := range byPath {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].FilePath < groups[j].FilePath })
+
+	return groups
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGroupFindingsByFile(t *testing.T) {
+	vulns := []*Vulnerability{
+		{ID: "1", FilePath: "handlers/user.go", Severity: "High"},
+		{ID: "2", FilePath: "db/query.go", Severity: "Critical"},
+		{ID: "3", FilePath: "handlers/user.go", Severity: "Low"},
+		{ID: "4", FilePath: "handlers/user.go", Severity: "High"},
+		{ID: "5", FilePath: "db/query.go", Severity: "Medium", Baselined: true},
+		{ID: "6", FilePath: "third_party/x.go", Severity: "High", Excluded: true},
+	}
+
+	groups := GroupFindingsByFile(vulns)
+
+	want := []struct {
+		path    string
+		ids     []string
+		count   int
+		summary map[string]int
+	}{
+		{path: "db/query.go", ids: []string{"2", "5"}, count: 1, summary: map[string]int{"Critical": 1}},
+		{path: "handlers/user.go", ids: []string{"1", "3", "4"}, count: 3, summary: map[string]int{"High": 2, "Low": 1}},
+		{path: "third_party/x.go", ids: []string{"6"}, count: 0, summary: map[string]int{}},
+	}
+
+	if len(groups) != len(want) {
+		t.Fatalf("got %d file groups, want %d", len(groups), len(want))
+	}
+	for i, w := range want {
+		group := groups[i]
+		if group.FilePath != w.path {
+			t.Errorf("group %d is %s, want %s", i, group.FilePath, w.path)
+			continue
+		}
+
+		var ids []string
+		for _, vuln := range group.Vulnerabilities {
+			ids = append(ids, vuln.ID)
+		}
+		if toJSON(t, ids) != toJSON(t, w.ids) {
+			t.Errorf("%s nests findings %v, want %v", w.path, ids, w.ids)
+		}
+		if group.VulnerabilityCount != w.count || toJSON(t, group.SeveritySummary) != toJSON(t, w.summary) {
+			t.Errorf("%s summary = %d %v, want %d %v", w.path, group.VulnerabilityCount, group.SeveritySummary, w.count, w.summary)
+		}
+	}
+
+	if empty := GroupFindingsByFile(nil); empty == nil || len(empty) != 0 {
+		t.Errorf("GroupFindingsByFile(nil) = %#v, want an empty list", empty)
+	}
+}
+
+// toJSON encodes a value so maps and slices compare by content
+func toJSON(t *testing.T, value any) string {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("encode %v: %v", value, err)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unicode"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultIdempotencyKeyTTL is how long a key keeps returning its scan when IDEMPOTENCY_KEY_TTL is unset
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyClaimTimeout is how long a claimed key waits for its request to finish before it can be
+	// claimed again, so a server that dies mid-request doesn't hold the key for the whole TTL
+	idempotencyClaimTimeout = 5 * time.Minute
+
+	// MaxIdempotencyKeyLength is the longest Idempotency-Key accepted
+	MaxIdempotencyKeyLength = 255
+
+	// idempotencyClaimAttempts bounds the retries when a conflicting key disappears before it can be read
+	idempotencyClaimAttempts = 3
+)
+
+// ErrIdempotencyKeyInProgress is returned when another request with the same key hasn't finished yet
+var ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+
+// ErrInvalidIdempotencyKey is returned for keys that are too long or contain non-printable characters
+var ErrInvalidIdempotencyKey = fmt.Errorf("idempotency key must be 1 to %d printable ASCII characters", MaxIdempotencyKeyLength)
+
+// IdempotentScan is the scan a request with an idempotency key started or was pointed at
+type IdempotentScan struct {
+	ScanID       string
+	RepositoryID string
+}
+
+// IdempotencyStore remembers the scans started by requests that carried an Idempotency-Key, so a
+// retried request gets the original scan instead of starting another. Keys are scoped to an endpoint
+// and to a scope naming the caller, such as "user:<id>" or "ip:<address>"
+type IdempotencyStore interface {
+	// Claim reserves a key for the calling request. It returns nil when the caller now holds the key
+	// and should handle the request, the stored scan when an earlier request with the key finished,
+	// or ErrIdempotencyKeyInProgress while that request is still running
+	Claim(ctx context.Context, endpoint, scope, key string) (*IdempotentScan, error)
+
+	// Complete stores the scan of a claimed key, which later claims return until the key expires
+	Complete(ctx context.Context, endpoint, scope, key string, scan IdempotentScan) error
+
+	// Release gives up a claimed key whose request failed, so a retry can use it
+	Release(ctx context.Context, endpoint, scope, key string) error
+}
+
+// NewIdempotencyStore creates an idempotency store backed by the idempotency_keys table
+func NewIdempotencyStore(dbQueries *db.Queries) IdempotencyStore {
+	return &idempotencyStore{
+		db:  dbQueries,
+		ttl: IdempotencyKeyTTL(),
+		now: time.Now,
+	}
+}
+
+// idempotencyStore implements the IdempotencyStore interface
+type idempotencyStore struct {
+	db  *db.Queries
+	ttl time.Duration
+	now func() time.Time // Replaced in tests to expire keys
+}
+
+// IdempotencyKeyTTL reads IDEMPOTENCY_KEY_TTL (a Go duration such as "24h")
+func IdempotencyKeyTTL() time.Duration {
+	value := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if value == "" {
+		return defaultIdempotencyKeyTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		logger.Warn("Invalid IDEMPOTENCY_KEY_TTL value, using default",
+			zap.String("value", value),
+			zap.Duration("default", defaultIdempotencyKeyTTL))
+		return defaultIdempotencyKeyTTL
+	}
+	return ttl
+}
+
+// ValidateIdempotencyKey checks that a key is short printable ASCII, as header values should be
+func ValidateIdempotencyKey(key string) error {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return ErrInvalidIdempotencyKey
+	}
+	for _, r := range key {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return ErrInvalidIdempotencyKey
+		}
+	}
+	return nil
+}
+
+// hashIdempotencyKey returns the stored form of an idempotency key
+func hashIdempotencyKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *idempotencyStore) Claim(ctx context.Context, endpoint, scope, key string) (*IdempotentScan, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+	keyHash := hashIdempotencyKey(key)
+
+	// Expired keys would otherwise block their own reuse, and keys are rarely sent twice
+	if _, err := sqlDB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, s.now()); err != nil {
+		logger.Warn("Failed to prune expired idempotency keys", zap.Error(err))
+	}
+
+	// The primary key settles concurrent claims: exactly one insert wins, and the others read its row.
+	// A row can be released or expire between the two statements, in which case the claim starts over
+	for attempt := 0; attempt < idempotencyClaimAttempts; attempt++ {
+		result, err := sqlDB.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (endpoint, scope, key_hash, expires_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (endpoint, scope, key_hash) DO NOTHING`,
+			endpoint, scope, keyHash, s.now().Add(idempotencyClaimTimeout).UTC())
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+		if inserted, err := result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		} else if inserted == 1 {
+			return nil, nil
+		}
+
+		var scanID, repositoryID sql.NullString
+		var expiresAt time.Time
+		err = sqlDB.QueryRowContext(ctx,
+			`SELECT scan_id, repository_id, expires_at FROM idempotency_keys
+			WHERE endpoint = $1 AND scope = $2 AND key_hash = $3`,
+			endpoint, scope, keyHash).Scan(&scanID, &repositoryID, &expiresAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+
+		if !s.now().Before(expiresAt) {
+			// Abandoned by a request that never finished; only the row that was read is removed
+			if _, err := sqlDB.ExecContext(ctx,
+				`DELETE FROM idempotency_keys WHERE endpoint = $1 AND scope = $2 AND key_hash = $3 AND expires_at = $4`,
+				endpoint, scope, keyHash, expiresAt); err != nil {
+				return nil, fmt.Errorf("failed to remove expired idempotency key: %w", err)
+			}
+			continue
+		}
+		if !scanID.Valid {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		return &IdempotentScan{ScanID: scanID.String, RepositoryID: repositoryID.String}, nil
+	}
+	return nil, fmt.Errorf("failed to claim idempotency key after %d attempts", idempotencyClaimAttempts)
+}
+
+func (s *idempotencyStore) Complete(ctx context.Context, endpoint, scope, key string, scan IdempotentScan) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	// The TTL starts once the scan exists, however long the request took
+	_, err := sqlDB.ExecContext(ctx,
+		`UPDATE idempotency_keys SET scan_id = $4, repository_id = $5, expires_at = $6
+		WHERE endpoint = $1 AND scope = $2 AND key_hash = $3`,
+		endpoint, scope, hashIdempotencyKey(key), scan.ScanID, scan.RepositoryID, s.now().Add(s.ttl).UTC())
+	if err != nil {
+		return fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, endpoint, scope, key string) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	// A completed key is never released, so a late failure can't free a key whose scan was started
+	_, err := sqlDB.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE endpoint = $1 AND scope = $2 AND key_hash = $3 AND scan_id IS NULL`,
+		endpoint, scope, hashIdempotencyKey(key))
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
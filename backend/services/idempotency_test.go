@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: uuid.NewString()},
+		{key: "retry-1 of scan"},
+		{key: strings.Repeat("k", MaxIdempotencyKeyLength)},
+		{key: "", wantErr: true},
+		{key: strings.Repeat("k", MaxIdempotencyKeyLength+1), wantErr: true},
+		{key: "tab\there", wantErr: true},
+		{key: "clé", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateIdempotencyKey(tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateIdempotencyKey(%q) = %v, want error: %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIdempotencyKeyLifecycle(t *testing.T) {
+	queries := newTestQueries(t)
+	store := NewIdempotencyStore(queries)
+	ctx := context.Background()
+	repoID, scanID := createTestScan(t, queries)
+	scope, key := "ip:"+uuid.NewString(), uuid.NewString()
+
+	if scan, err := store.Claim(ctx, "POST /scan", scope, key); err != nil || scan != nil {
+		t.Fatalf("first Claim = %v, %v; want the key claimed", scan, err)
+	}
+	if _, err := store.Claim(ctx, "POST /scan", scope, key); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Errorf("Claim while the first request runs = %v, want ErrIdempotencyKeyInProgress", err)
+	}
+
+	// Keys are scoped per endpoint and per caller
+	if scan, err := store.Claim(ctx, "POST /scan", "ip:"+uuid.NewString(), key); err != nil || scan != nil {
+		t.Errorf("Claim from another caller = %v, %v; want a claim of its own", scan, err)
+	}
+	if scan, err := store.Claim(ctx, "POST /other", scope, key); err != nil || scan != nil {
+		t.Errorf("Claim on another endpoint = %v, %v; want a claim of its own", scan, err)
+	}
+
+	if err := store.Complete(ctx, "POST /scan", scope, key, IdempotentScan{ScanID: scanID, RepositoryID: repoID}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	scan, err := store.Claim(ctx, "POST /scan", scope, key)
+	if err != nil {
+		t.Fatalf("Claim after Complete: %v", err)
+	}
+	if scan == nil || scan.ScanID != scanID || scan.RepositoryID != repoID {
+		t.Errorf("Claim after Complete = %+v, want scan %s of repository %s", scan, scanID, repoID)
+	}
+
+	// A completed key is kept even if a late failure tries to release it
+	if err := store.Release(ctx, "POST /scan", scope, key); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if scan, err := store.Claim(ctx, "POST /scan", scope, key); err != nil || scan == nil {
+		t.Errorf("Claim after releasing a completed key = %v, %v; want the stored scan", scan, err)
+	}
+}
+
+func TestIdempotencyKeyReleaseAndExpiry(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, scanID := createTestScan(t, queries)
+
+	t.Run("released key can be claimed again", func(t *testing.T) {
+		store := NewIdempotencyStore(queries)
+		scope, key := "ip:"+uuid.NewString(), uuid.NewString()
+		if _, err := store.Claim(ctx, "POST /scan", scope, key); err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		if err := store.Release(ctx, "POST /scan", scope, key); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+		if scan, err := store.Claim(ctx, "POST /scan", scope, key); err != nil || scan != nil {
+			t.Errorf("Claim after Release = %v, %v; want the key claimed", scan, err)
+		}
+	})
+
+	t.Run("abandoned claim times out", func(t *testing.T) {
+		store := NewIdempotencyStore(queries).(*idempotencyStore)
+		scope, key := "ip:"+uuid.NewString(), uuid.NewString()
+		if _, err := store.Claim(ctx, "POST /scan", scope, key); err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		store.now = func() time.Time { return time.Now().Add(idempotencyClaimTimeout + time.Second) }
+		if scan, err := store.Claim(ctx, "POST /scan", scope, key); err != nil || scan != nil {
+			t.Errorf("Claim after the claim timed out = %v, %v; want the key claimed", scan, err)
+		}
+	})
+
+	t.Run("completed key expires after the TTL", func(t *testing.T) {
+		store := NewIdempotencyStore(queries).(*idempotencyStore)
+		store.ttl = time.Hour
+		scope, key := "ip:"+uuid.NewString(), uuid.NewString()
+		if _, err := store.Claim(ctx, "POST /scan", scope, key); err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		if err := store.Complete(ctx, "POST /scan", scope, key, IdempotentScan{ScanID: scanID, RepositoryID: repoID}); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		store.now = func() time.Time { return time.Now().Add(time.Hour + time.Second) }
+		if scan, err := store.Claim(ctx, "POST /scan", scope, key); err != nil || scan != nil {
+			t.Errorf("Claim after the TTL = %v, %v; want the key claimed anew", scan, err)
+		}
+	})
+}
+
+func TestIdempotencyKeyConcurrentClaims(t *testing.T) {
+	store := NewIdempotencyStore(newTestQueries(t))
+	ctx := context.Background()
+	scope, key := "ip:"+uuid.NewString(), uuid.NewString()
+
+	const requests = 8
+	var wg sync.WaitGroup
+	results := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Claim(ctx, "POST /scan", scope, key)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	claimed := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			claimed++
+		case !errors.Is(err, ErrIdempotencyKeyInProgress):
+			t.Errorf("Claim = %v, want success or ErrIdempotencyKeyInProgress", err)
+		}
+	}
+	if claimed != 1 {
+		t.Errorf("%d concurrent claims succeeded, want exactly 1", claimed)
+	}
+}
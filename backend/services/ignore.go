@@ -0,0 +1,83 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// ignoreRules collects the .gitignore patterns of a repository while it is walked
+// Each file's patterns only apply below the directory it was found in, as in git
+type ignoreRules struct {
+	patterns []gitignore.Pattern
+}
+
+// load reads the .gitignore of a directory, if it has one
+// relDir is slash-separated and relative to the repository root ("." for the root)
+func (r *ignoreRules) load(repoDir, relDir string) {
+	file, err := os.Open(filepath.Join(repoDir, filepath.FromSlash(relDir), ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	var domain []string
+	if relDir != "." {
+		domain = strings.Split(relDir, "/")
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r.patterns = append(r.patterns, gitignore.ParsePattern(line, domain))
+	}
+}
+
+// ignored reports whether git would ignore the slash-separated relative path
+// A file inside an ignored directory counts as ignored too
+func (r *ignoreRules) ignored(relPath string, isDir bool) bool {
+	if len(r.patterns) == 0 {
+		return false
+	}
+	return gitignore.NewMatcher(r.patterns).Match(strings.Split(relPath, "/"), isDir)
+}
+
+// mayMatchBelow reports whether any of the globs could match a path inside the directory
+// A skipped directory is still walked when this is true, so include patterns can reach into it
+func mayMatchBelow(globs []string, relDir string) bool {
+	dirPrefix := relDir + "/"
+	for _, pattern := range globs {
+		pattern = strings.TrimPrefix(pattern, "./")
+
+		// Bare file-name globs apply at any depth
+		if !strings.Contains(pattern, "/") {
+			return true
+		}
+
+		// Compare the literal part of the glob before its first wildcard
+		literal := pattern
+		if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+			literal = pattern[:i]
+		}
+		if strings.HasPrefix(literal, dirPrefix) || strings.HasPrefix(dirPrefix, literal) {
+			return true
+		}
+	}
+	return false
+}
+
+// underAny reports whether the slash-separated path lies inside one of the directories
+func underAny(dirs []string, relPath string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(relPath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFindFilesToScanRespectsIgnoreRules(t *testing.T) {
+	root := writeFixtureTree(t, []string{
+		"main.go",
+		"gen/api.go",
+		"api/service.go",
+		"api/service.pb.go",
+		"web/app.js",
+		"web/out/bundle.js",
+		"out/keep.js",
+		"internal/legacy/old.go",
+		"internal/auth/login.go",
+		"node_modules/pkg/index.js",
+	})
+	for dir, rules := range map[string]string{
+		".":   "# generated code\ngen/\n*.pb.go\n",
+		"web": "out/\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, dir, ".gitignore"), []byte(rules), 0o644); err != nil {
+			t.Fatalf("write %s/.gitignore: %v", dir, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		exclude []string
+		include []string
+		want    []string
+	}{
+		{
+			name: "gitignore files apply below their own directory",
+			want: []string{"api/service.go", "internal/auth/login.go", "internal/legacy/old.go", "main.go", "out/keep.js", "web/app.js"},
+		},
+		{
+			name:    "exclude patterns add to the defaults",
+			exclude: []string{"internal/legacy/**", "out", "*.js"},
+			want:    []string{"api/service.go", "internal/auth/login.go", "main.go"},
+		},
+		{
+			name:    "include patterns reach into ignored and skipped paths",
+			include: []string{"gen/**", "**/*.pb.go", "node_modules/pkg/index.js"},
+			want: []string{"api/service.go", "api/service.pb.go", "gen/api.go", "internal/auth/login.go",
+				"internal/legacy/old.go", "main.go", "node_modules/pkg/index.js", "out/keep.js", "web/app.js"},
+		},
+		{
+			name:    "include patterns win over exclude patterns",
+			exclude: []string{"internal/**"},
+			include: []string{"internal/legacy/old.go"},
+			want:    []string{"api/service.go", "internal/legacy/old.go", "main.go", "out/keep.js", "web/app.js"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, _, err := findFilesToScan(zap.NewNop(), root, &ScanOptions{
+				FileExtensions:  []string{".go", ".js"},
+				ExcludePatterns: tt.exclude,
+				IncludePatterns: tt.include,
+			})
+			if err != nil {
+				t.Fatalf("findFilesToScan returned error: %v", err)
+			}
+
+			var found []string
+			for _, file := range files {
+				relPath, _ := filepath.Rel(root, file)
+				found = append(found, filepath.ToSlash(relPath))
+			}
+			sort.Strings(found)
+			if strings.Join(found, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("found %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindFilesToScanReportsTruncation(t *testing.T) {
+	root := writeFixtureTree(t, []string{"a.go", "b.go", "docs/readme.md", "src/c.go"})
+
+	tests := []struct {
+		name          string
+		maxFiles      int
+		wantFiles     int
+		wantTruncated bool
+	}{
+		{name: "no limit", maxFiles: 0, wantFiles: 3},
+		{name: "limit fits every file", maxFiles: 3, wantFiles: 3},
+		{name: "limit leaves a file out", maxFiles: 2, wantFiles: 2, wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, truncated, err := findFilesToScan(zap.NewNop(), root, &ScanOptions{
+				FileExtensions: []string{".go"},
+				MaxFiles:       tt.maxFiles,
+			})
+			if err != nil {
+				t.Fatalf("findFilesToScan returned error: %v", err)
+			}
+			if len(files) != tt.wantFiles || truncated != tt.wantTruncated {
+				t.Errorf("found %d files, truncated %v; want %d, truncated %v", len(files), truncated, tt.wantFiles, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestMayMatchBelow(t *testing.T) {
+	tests := []struct {
+		globs  []string
+		relDir string
+		want   bool
+	}{
+		{globs: []string{"*.go"}, relDir: "vendor", want: true},
+		{globs: []string{"vendor/example.com/**"}, relDir: "vendor", want: true},
+		{globs: []string{"vendor/example.com/**"}, relDir: "vendor/example.com/internal", want: true},
+		{globs: []string{"vendor/example.com/**"}, relDir: "node_modules", want: false},
+		{globs: []string{"src/*/gen.go"}, relDir: "src/api", want: true},
+		{relDir: "vendor", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := mayMatchBelow(tt.globs, tt.relDir); got != tt.want {
+			t.Errorf("mayMatchBelow(%v, %q) = %v, want %v", tt.globs, tt.relDir, got, tt.want)
+		}
+	}
+}
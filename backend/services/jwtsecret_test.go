@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateJWTSecret(t *testing.T) {
+	tests := []struct {
+		env     string
+		secret  string
+		wantErr error
+	}{
+		{env: "production", wantErr: ErrJWTSecretNotSet},
+		{env: "production", secret: "s3cret"},
+		{env: "development"},
+		{env: ""},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("APP_ENV", tt.env)
+		t.Setenv("JWT_SECRET", tt.secret)
+		if err := ValidateJWTSecret(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateJWTSecret() with APP_ENV=%q, JWT_SECRET=%q = %v, want %v", tt.env, tt.secret, err, tt.wantErr)
+		}
+	}
+}
+
+func TestJWTSecretIsSharedByEveryTokenPath(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	user := &User{ID: "user-1", Email: "ada@example.com"}
+
+	// A session token verifies with the same key GenerateJWT and VerifyJWT use
+	token, err := GenerateSessionToken(user)
+	if err != nil {
+		t.Fatalf("GenerateSessionToken returned error: %v", err)
+	}
+	userID, err := (&AuthService{}).VerifyJWT(token)
+	if err != nil {
+		t.Fatalf("VerifyJWT rejected a session token: %v", err)
+	}
+	if userID != user.ID {
+		t.Errorf("VerifyJWT() = %s, want %s", userID, user.ID)
+	}
+}
+
+func TestJWTCarriesTheUserRole(t *testing.T) {
+	t.Setenv("JWT_SECRET", "s3cret")
+	auth := &AuthService{}
+
+	token, err := auth.GenerateJWT("user-1", "ada@example.com", RoleAdmin)
+	if err != nil {
+		t.Fatalf("GenerateJWT returned error: %v", err)
+	}
+	claims, err := auth.ParseJWT(token)
+	if err != nil {
+		t.Fatalf("ParseJWT rejected the token: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.Role != RoleAdmin {
+		t.Errorf("claims = %s with role %q, want user-1 with role %q", claims.UserID, claims.Role, RoleAdmin)
+	}
+}
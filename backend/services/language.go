@@ -0,0 +1,228 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// unknownLanguage is the label for files whose language can't be determined
+const unknownLanguage = "Unknown"
+
+// supportedExtensions lists every file extension the scanner recognizes, in display order
+// extensionLanguages must have a language label for each of these
+var supportedExtensions = []string{
+	".go", ".js", ".jsx", ".ts", ".tsx", ".py", ".java", ".php", ".html", ".css",
+	".rb", ".rs", ".c", ".h", ".cpp", ".cc", ".hpp", ".cs", ".kt", ".kts", ".swift",
+	".sql", ".sh", ".bash", ".yml", ".yaml",
+}
+
+// extensionLanguages maps file extensions to the language label sent to the model
+var extensionLanguages = map[string]string{
+	".go":    "Go",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".py":    "Python",
+	".java":  "Java",
+	".php":   "PHP",
+	".html":  "HTML",
+	".css":   "CSS",
+	".rb":    "Ruby",
+	".rs":    "Rust",
+	".c":     "C",
+	".h":     "C",
+	".cpp":   "C++",
+	".cc":    "C++",
+	".hpp":   "C++",
+	".cs":    "C#",
+	".kt":    "Kotlin",
+	".kts":   "Kotlin",
+	".swift": "Swift",
+	".sql":   "SQL",
+	".sh":    "Shell",
+	".bash":  "Shell",
+	".yml":   "YAML",
+	".yaml":  "YAML",
+}
+
+// filenameLanguages maps well-known file names to their language or file type
+// These files usually have no extension, or one that says nothing about their content
+var filenameLanguages = map[string]string{
+	"dockerfile":    "Dockerfile",
+	"containerfile": "Dockerfile",
+	"makefile":      "Makefile",
+	"gnumakefile":   "Makefile",
+	".env":          "Dotenv",
+	"gemfile":       "Ruby",
+	"rakefile":      "Ruby",
+	"jenkinsfile":   "Groovy",
+	".bashrc":       "Shell",
+	".bash_profile": "Shell",
+	".profile":      "Shell",
+	".zshrc":        "Shell",
+}
+
+// shebangLanguages maps script interpreters, without any version suffix, to their language
+var shebangLanguages = map[string]string{
+	"sh":      "Shell",
+	"bash":    "Shell",
+	"zsh":     "Shell",
+	"ksh":     "Shell",
+	"dash":    "Shell",
+	"python":  "Python",
+	"node":    "JavaScript",
+	"deno":    "TypeScript",
+	"ts-node": "TypeScript",
+	"ruby":    "Ruby",
+	"perl":    "Perl",
+	"php":     "PHP",
+}
+
+// defaultFileExtensions are the extensions scanned when a request doesn't specify any
+var defaultFileExtensions = []string{".go", ".js", ".py", ".java", ".php", ".html", ".css", ".ts", ".jsx", ".tsx"}
+
+// LanguageInfo describes a file extension supported by the scanner
+type LanguageInfo struct {
+	Extension        string `json:"extension"`          // File extension including the leading dot
+	Language         string `json:"language"`           // Human-readable language label
+	EnabledByDefault bool   `json:"enabled_by_default"` // Whether the extension is scanned when none are requested
+}
+
+// DefaultFileExtensions returns a copy of the extensions scanned by default
+func DefaultFileExtensions() []string {
+	return append([]string(nil), defaultFileExtensions...)
+}
+
+// IsSupportedExtension reports whether the scanner can analyze files with the extension
+func IsSupportedExtension(ext string) bool {
+	for _, supported := range supportedExtensions {
+		if supported == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeFileExtension turns a requested extension into the form filepath.Ext returns: "go", ".GO",
+// and "*.go" all become ".go". It fails for anything that isn't a single supported extension
+func NormalizeFileExtension(ext string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(ext))
+	normalized = strings.TrimPrefix(normalized, "*")
+	if !strings.HasPrefix(normalized, ".") {
+		normalized = "." + normalized
+	}
+	if normalized == "." || strings.ContainsAny(normalized[1:], `.*?[]/\ `) {
+		return "", fmt.Errorf("invalid file extension %q; use a single extension such as \".go\"", ext)
+	}
+	if !IsSupportedExtension(normalized) {
+		return "", fmt.Errorf("unsupported file extension %q; see GET /api/languages", ext)
+	}
+	return normalized, nil
+}
+
+// NormalizeFileExtensions normalizes each requested extension, dropping repeats
+// It fails on the first extension NormalizeFileExtension rejects
+func NormalizeFileExtensions(exts []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		ext, err := NormalizeFileExtension(ext)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[ext] {
+			seen[ext] = true
+			normalized = append(normalized, ext)
+		}
+	}
+	return normalized, nil
+}
+
+// SupportedLanguages returns the extensions the scanner can analyze and their language labels
+func SupportedLanguages() []LanguageInfo {
+	enabled := make(map[string]bool, len(defaultFileExtensions))
+	for _, ext := range defaultFileExtensions {
+		enabled[ext] = true
+	}
+
+	languages := make([]LanguageInfo, 0, len(supportedExtensions))
+	for _, ext := range supportedExtensions {
+		languages = append(languages, LanguageInfo{
+			Extension:        ext,
+			Language:         getLanguageFromExt(ext),
+			EnabledByDefault: enabled[ext],
+		})
+	}
+	return languages
+}
+
+// Helper function to determine language from file extension
+func getLanguageFromExt(ext string) string {
+	if language, ok := extensionLanguages[strings.ToLower(ext)]; ok {
+		return language
+	}
+	return unknownLanguage
+}
+
+// DetectLanguage returns the language label of a file from its name, extension, or content
+// Well-known file names (Dockerfile, Makefile, .env) come first, since their extension, if any,
+// is misleading; extensionless scripts fall back to the interpreter named in their shebang
+func DetectLanguage(path, code string) string {
+	if language := getLanguageFromFilename(filepath.Base(path)); language != "" {
+		return language
+	}
+	if language := getLanguageFromExt(filepath.Ext(path)); language != unknownLanguage {
+		return language
+	}
+	if language := getLanguageFromShebang(code); language != "" {
+		return language
+	}
+	return unknownLanguage
+}
+
+// getLanguageFromFilename matches well-known file names, including variants such as
+// Dockerfile.prod or .env.local; it returns "" for any other name
+func getLanguageFromFilename(name string) string {
+	name = strings.ToLower(name)
+	if language, ok := filenameLanguages[name]; ok {
+		return language
+	}
+	switch {
+	case strings.HasPrefix(name, "dockerfile."), strings.HasSuffix(name, ".dockerfile"):
+		return "Dockerfile"
+	case strings.HasPrefix(name, ".env."):
+		return "Dotenv"
+	}
+	return ""
+}
+
+// getLanguageFromShebang returns the language of the interpreter named on a script's first line,
+// e.g. "#!/bin/bash" or "#!/usr/bin/env python3"; it returns "" when there is no known shebang
+func getLanguageFromShebang(code string) string {
+	firstLine, _, _ := strings.Cut(code, "\n")
+	if !strings.HasPrefix(firstLine, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env's own flags and variable assignments, e.g. "#!/usr/bin/env -S python3 -u"
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+
+	// python3.12 and python3 are both python
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	return shebangLanguages[interpreter]
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSupportedLanguagesMatchTheExtensionMap(t *testing.T) {
+	want := map[string]string{
+		".go":    "Go",
+		".js":    "JavaScript",
+		".jsx":   "JavaScript",
+		".ts":    "TypeScript",
+		".tsx":   "TypeScript",
+		".py":    "Python",
+		".java":  "Java",
+		".php":   "PHP",
+		".html":  "HTML",
+		".css":   "CSS",
+		".rb":    "Ruby",
+		".rs":    "Rust",
+		".c":     "C",
+		".h":     "C",
+		".cpp":   "C++",
+		".cc":    "C++",
+		".hpp":   "C++",
+		".cs":    "C#",
+		".kt":    "Kotlin",
+		".kts":   "Kotlin",
+		".swift": "Swift",
+		".sql":   "SQL",
+		".sh":    "Shell",
+		".bash":  "Shell",
+		".yml":   "YAML",
+		".yaml":  "YAML",
+	}
+
+	languages := SupportedLanguages()
+	if len(languages) != len(want) {
+		t.Errorf("SupportedLanguages() lists %d extensions, want %d", len(languages), len(want))
+	}
+	defaults := map[string]bool{}
+	for _, ext := range DefaultFileExtensions() {
+		defaults[ext] = true
+	}
+
+	seen := map[string]bool{}
+	for _, language := range languages {
+		if seen[language.Extension] {
+			t.Errorf("%s listed twice", language.Extension)
+		}
+		seen[language.Extension] = true
+
+		if language.Language != want[language.Extension] || language.Language != getLanguageFromExt(language.Extension) {
+			t.Errorf("%s is listed as %q, want %q", language.Extension, language.Language, want[language.Extension])
+		}
+		if language.EnabledByDefault != defaults[language.Extension] {
+			t.Errorf("%s EnabledByDefault = %v, want %v", language.Extension, language.EnabledByDefault, defaults[language.Extension])
+		}
+	}
+	for ext := range defaults {
+		if !seen[ext] {
+			t.Errorf("default extension %s is not listed as supported", ext)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		code string
+		want string
+	}{
+		{name: "extension", path: "cmd/main.go", code: "package main\n", want: "Go"},
+		{name: "upper-case extension", path: "lib/Util.RB", want: "Ruby"},
+		{name: "Dockerfile", path: "deploy/Dockerfile", code: "FROM alpine\n", want: "Dockerfile"},
+		{name: "Dockerfile variant", path: "Dockerfile.prod", want: "Dockerfile"},
+		{name: "named Dockerfile", path: "build/api.dockerfile", want: "Dockerfile"},
+		{name: "Makefile", path: "Makefile", want: "Makefile"},
+		{name: "dotenv", path: ".env", want: "Dotenv"},
+		{name: "dotenv variant", path: "config/.env.local", want: "Dotenv"},
+		// The file name wins over a shebang, which a Makefile can't have anyway
+		{name: "file name before extension", path: "Dockerfile.sh", code: "#!/bin/bash\n", want: "Dockerfile"},
+		{name: "extension before shebang", path: "tool.py", code: "#!/bin/sh\n", want: "Python"},
+		{name: "shell shebang", path: "scripts/deploy", code: "#!/bin/bash\nset -e\n", want: "Shell"},
+		{name: "env shebang", path: "bin/manage", code: "#!/usr/bin/env python3\nimport sys\n", want: "Python"},
+		{name: "env shebang with flags", path: "bin/serve", code: "#!/usr/bin/env -S node --no-warnings\n", want: "JavaScript"},
+		{name: "versioned interpreter", path: "bin/tool", code: "#!/usr/local/bin/python3.12\n", want: "Python"},
+		{name: "shebang with arguments", path: "bin/report", code: "#!/usr/bin/perl -w\n", want: "Perl"},
+		{name: "unknown interpreter", path: "bin/run", code: "#!/usr/bin/env awk -f\n", want: "Unknown"},
+		{name: "shebang not on the first line", path: "bin/run", code: "\n#!/bin/sh\n", want: "Unknown"},
+		{name: "no clues", path: "LICENSE", code: "MIT License\n", want: "Unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.path, tt.code); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFileExtension(t *testing.T) {
+	tests := []struct {
+		ext     string
+		want    string
+		wantErr bool
+	}{
+		{ext: ".go", want: ".go"},
+		{ext: "go", want: ".go"},
+		{ext: ".JS", want: ".js"},
+		{ext: " Tsx ", want: ".tsx"},
+		{ext: "*.py", want: ".py"},
+		{ext: ".exe", wantErr: true},
+		{ext: "docx", wantErr: true},
+		{ext: "", wantErr: true},
+		{ext: ".", wantErr: true},
+		{ext: "*", wantErr: true},
+		{ext: ".g*", wantErr: true},
+		{ext: ".tar.gz", wantErr: true},
+		{ext: "src/.go", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeFileExtension(tt.ext)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeFileExtension(%q) = %q, want an error", tt.ext, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeFileExtension(%q) = %q, %v, want %q", tt.ext, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeFileExtensionsDropsRepeats(t *testing.T) {
+	got, err := NormalizeFileExtensions([]string{"go", ".GO", ".js", "*.go"})
+	if err != nil {
+		t.Fatalf("NormalizeFileExtensions returned error: %v", err)
+	}
+	if len(got) != 2 || got[0] != ".go" || got[1] != ".js" {
+		t.Errorf("NormalizeFileExtensions = %v, want [.go .js]", got)
+	}
+
+	if _, err := NormalizeFileExtensions([]string{".go", ".exe"}); err == nil || !strings.Contains(err.Error(), ".exe") {
+		t.Errorf("NormalizeFileExtensions error = %v, want one naming .exe", err)
+	}
+}
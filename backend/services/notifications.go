@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+const (
+	// DefaultNotificationLimit is the page size when the caller does not pass one
+	DefaultNotificationLimit = 20
+
+	// MaxNotificationLimit caps the page size of a notification listing
+	MaxNotificationLimit = 100
+)
+
+// Notification is an in-app message for a user, such as a finished scan
+type Notification struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"` // e.g. "scan_completed"
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationPage is one page of a user's notifications
+type NotificationPage struct {
+	Notifications []*Notification `json:"notifications"`
+	Total         int             `json:"total"`        // All of the user's notifications
+	UnreadCount   int             `json:"unread_count"` // Unread notifications, across every page
+}
+
+// NotificationService reads and updates a user's notifications
+// Every method is scoped to one user, so a user can never see or change another user's notifications
+type NotificationService interface {
+	// ListNotifications returns one page of the user's notifications, newest first
+	ListNotifications(ctx context.Context, userID string, limit, offset int) (*NotificationPage, error)
+
+	// MarkRead marks one of the user's notifications read; it returns false if the user has no such notification
+	MarkRead(ctx context.Context, userID, notificationID string) (bool, error)
+
+	// MarkAllRead marks every unread notification of the user read and returns how many changed
+	MarkAllRead(ctx context.Context, userID string) (int, error)
+}
+
+// NewNotificationService creates a new notification service instance
+func NewNotificationService(dbQueries *db.Queries) NotificationService {
+	return &notificationService{
+		db: dbQueries,
+	}
+}
+
+// notificationService implements the NotificationService interface
+type notificationService struct {
+	db *db.Queries
+}
+
+func (s *notificationService) ListNotifications(ctx context.Context, userID string, limit, offset int) (*NotificationPage, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	if limit <= 0 {
+		limit = DefaultNotificationLimit
+	}
+	if limit > MaxNotificationLimit {
+		limit = MaxNotificationLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	page := &NotificationPage{Notifications: []*Notification{}}
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE NOT read) FROM notifications WHERE user_id::text = $1`,
+		userID).Scan(&page.Total, &page.UnreadCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	// The id tiebreak keeps pages stable when two notifications share a creation time
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id, type, title, message, read, created_at
+		FROM notifications WHERE user_id::text = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3`,
+		userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		notification := &Notification{}
+		if err := rows.Scan(&notification.ID, &notification.Type, &notification.Title,
+			&notification.Message, &notification.Read, &notification.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification row: %w", err)
+		}
+		page.Notifications = append(page.Notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over notification rows: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID, notificationID string) (bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return false, fmt.Errorf("database connection not available")
+	}
+
+	// Matching on the owner too means another user's notification looks the same as a missing one
+	result, err := sqlDB.ExecContext(ctx,
+		`UPDATE notifications SET read = TRUE WHERE id::text = $1 AND user_id::text = $2`,
+		notificationID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, nil
+	}
+	return affected > 0, nil
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID string) (int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	result, err := sqlDB.ExecContext(ctx,
+		`UPDATE notifications SET read = TRUE WHERE user_id::text = $1 AND NOT read`,
+		userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	updated, _ := result.RowsAffected()
+	return int(updated), nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNotifications(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	userID := createTestUser(t, queries)
+	otherUserID := createTestUser(t, queries)
+
+	insert := func(userID, title string, age time.Duration) string {
+		t.Helper()
+		var id string
+		if err := queries.GetDB().QueryRowContext(ctx,
+			`INSERT INTO notifications (user_id, type, title, message, created_at)
+			VALUES ($1, 'scan_completed', $2, 'done', NOW() - $3 * INTERVAL '1 second') RETURNING id`,
+			userID, title, age.Seconds()).Scan(&id); err != nil {
+			t.Fatalf("insert notification: %v", err)
+		}
+		return id
+	}
+	oldest := insert(userID, "oldest", 3*time.Hour)
+	insert(userID, "middle", 2*time.Hour)
+	insert(userID, "newest", time.Hour)
+	othersNotification := insert(otherUserID, "someone else's", 0)
+
+	service := NewNotificationService(queries)
+
+	page, err := service.ListNotifications(ctx, userID, 2, 0)
+	if err != nil {
+		t.Fatalf("ListNotifications returned error: %v", err)
+	}
+	if page.Total != 3 || page.UnreadCount != 3 || len(page.Notifications) != 2 {
+		t.Fatalf("page = %+v, want 2 of 3 unread notifications", page)
+	}
+	if page.Notifications[0].Title != "newest" || page.Notifications[1].Title != "middle" {
+		t.Errorf("page = %s, %s, want newest first", page.Notifications[0].Title, page.Notifications[1].Title)
+	}
+	if page, err := service.ListNotifications(ctx, userID, 2, 2); err != nil || len(page.Notifications) != 1 || page.Notifications[0].ID != oldest {
+		t.Errorf("second page = %+v, %v, want the oldest notification", page, err)
+	}
+
+	// Another user's notification can't be marked read, and looks the same as a missing one
+	if updated, err := service.MarkRead(ctx, userID, othersNotification); err != nil || updated {
+		t.Errorf("MarkRead of another user's notification = %v, %v, want nothing updated", updated, err)
+	}
+	if updated, err := service.MarkRead(ctx, userID, "00000000-0000-0000-0000-000000000000"); err != nil || updated {
+		t.Errorf("MarkRead of a missing notification = %v, %v, want nothing updated", updated, err)
+	}
+
+	if updated, err := service.MarkRead(ctx, userID, oldest); err != nil || !updated {
+		t.Fatalf("MarkRead = %v, %v, want it updated", updated, err)
+	}
+	if page, err := service.ListNotifications(ctx, userID, 0, 0); err != nil || page.UnreadCount != 2 || len(page.Notifications) != 3 {
+		t.Errorf("after MarkRead page = %+v, %v, want 2 unread", page, err)
+	}
+
+	if updated, err := service.MarkAllRead(ctx, userID); err != nil || updated != 2 {
+		t.Errorf("MarkAllRead = %d, %v, want the 2 unread notifications", updated, err)
+	}
+	if page, err := service.ListNotifications(ctx, otherUserID, 0, 0); err != nil || page.UnreadCount != 1 {
+		t.Errorf("other user's page = %+v, %v, want their notification still unread", page, err)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// MaxNotifyEmails caps how many additional recipients a repository may configure
+const MaxNotifyEmails = 20
+
+// ErrRepositoryNotFound is returned when a repository setting is read or written for an unknown repository
+var ErrRepositoryNotFound = fmt.Errorf("repository not found")
+
+// RepositoryNotifyService manages the additional recipients of a repository's scan notifications
+type RepositoryNotifyService interface {
+	// GetNotifyEmails returns the repository's additional recipients
+	GetNotifyEmails(ctx context.Context, repoID string) ([]string, error)
+
+	// SetNotifyEmails validates and replaces the repository's additional recipients
+	// It returns the normalized list that was stored
+	SetNotifyEmails(ctx context.Context, repoID string, emails []string) ([]string, error)
+}
+
+// NewRepositoryNotifyService creates a new repository notification settings service instance
+func NewRepositoryNotifyService(dbQueries *db.Queries) RepositoryNotifyService {
+	return &repositoryNotifyService{
+		db: dbQueries,
+	}
+}
+
+// repositoryNotifyService implements the RepositoryNotifyService interface
+type repositoryNotifyService struct {
+	db *db.Queries
+}
+
+func (s *repositoryNotifyService) GetNotifyEmails(ctx context.Context, repoID string) ([]string, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	emails := []string{}
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT notify_emails FROM repositories WHERE id = $1`, repoID).Scan(pq.Array(&emails))
+	if err == sql.ErrNoRows {
+		return nil, ErrRepositoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notify emails for repository %s: %w", repoID, err)
+	}
+
+	return emails, nil
+}
+
+func (s *repositoryNotifyService) SetNotifyEmails(ctx context.Context, repoID string, emails []string) ([]string, error) {
+	normalized, err := ValidateNotifyEmails(emails)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	result, err := sqlDB.ExecContext(ctx,
+		`UPDATE repositories SET notify_emails = $1, updated_at = NOW() WHERE id = $2`,
+		pq.Array(normalized), repoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store notify emails for repository %s: %w", repoID, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, ErrRepositoryNotFound
+	}
+
+	return normalized, nil
+}
+
+// ValidateNotifyEmails checks that every entry is a bare email address
+// Addresses are lower-cased and de-duplicated so the stored list is canonical
+func ValidateNotifyEmails(emails []string) ([]string, error) {
+	if len(emails) > MaxNotifyEmails {
+		return nil, fmt.Errorf("at most %d notify emails are allowed", MaxNotifyEmails)
+	}
+
+	normalized := make([]string, 0, len(emails))
+	seen := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+
+		// Reject display names and anything else ParseAddress would rewrite
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != email {
+			return nil, fmt.Errorf("invalid email address %q", email)
+		}
+
+		email = strings.ToLower(email)
+		if !seen[email] {
+			seen[email] = true
+			normalized = append(normalized, email)
+		}
+	}
+
+	return normalized, nil
+}
+
+// ScanNotificationRecipients combines the submitter with a repository's additional recipients
+// Empty entries are dropped and an address listed twice is only mailed once
+func ScanNotificationRecipients(submitter string, additional []string) []string {
+	var recipients []string
+	seen := make(map[string]bool)
+	for _, email := range append([]string{submitter}, additional...) {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		recipients = append(recipients, strings.TrimSpace(email))
+	}
+	return recipients
+}
+
+// ScanEmailsDisabled reports whether scan notification emails are turned off globally via DISABLE_SCAN_EMAILS
+func ScanEmailsDisabled() bool {
+	disabled, _ := strconv.ParseBool(os.Getenv("DISABLE_SCAN_EMAILS"))
+	return disabled
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidateNotifyEmails(t *testing.T) {
+	tooMany := make([]string, MaxNotifyEmails+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("user%d@example.com", i)
+	}
+
+	tests := []struct {
+		name    string
+		emails  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty list clears recipients", emails: []string{}, want: []string{}},
+		{name: "normalizes case and whitespace", emails: []string{" Sec@Example.com "}, want: []string{"sec@example.com"}},
+		{name: "drops duplicates", emails: []string{"a@example.com", "A@example.com"}, want: []string{"a@example.com"}},
+		{name: "rejects display names", emails: []string{"Security <sec@example.com>"}, wantErr: true},
+		{name: "rejects malformed addresses", emails: []string{"not-an-email"}, wantErr: true},
+		{name: "rejects more than the maximum", emails: tooMany, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateNotifyEmails(tt.emails)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ValidateNotifyEmails(%q) = %q, want an error", tt.emails, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateNotifyEmails(%q) returned error: %v", tt.emails, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateNotifyEmails(%q) = %q, want %q", tt.emails, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanNotificationRecipients(t *testing.T) {
+	tests := []struct {
+		name       string
+		submitter  string
+		additional []string
+		want       []string
+	}{
+		{
+			name:       "additional recipients are mailed alongside the submitter",
+			submitter:  "dev@example.com",
+			additional: []string{"sec@example.com", "lead@example.com"},
+			want:       []string{"dev@example.com", "sec@example.com", "lead@example.com"},
+		},
+		{
+			name:       "submitter listed again is only mailed once",
+			submitter:  "Dev@Example.com",
+			additional: []string{"dev@example.com", "sec@example.com"},
+			want:       []string{"Dev@Example.com", "sec@example.com"},
+		},
+		{
+			name:       "additional recipients without a submitter",
+			submitter:  "",
+			additional: []string{"sec@example.com"},
+			want:       []string{"sec@example.com"},
+		},
+		{
+			name:      "no recipients at all",
+			submitter: " ",
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScanNotificationRecipients(tt.submitter, tt.additional)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScanNotificationRecipients(%q, %q) = %q, want %q", tt.submitter, tt.additional, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanEmailsDisabled(t *testing.T) {
+	t.Setenv("DISABLE_SCAN_EMAILS", "")
+	if ScanEmailsDisabled() {
+		t.Error("ScanEmailsDisabled() = true with DISABLE_SCAN_EMAILS unset")
+	}
+
+	t.Setenv("DISABLE_SCAN_EMAILS", "true")
+	if !ScanEmailsDisabled() {
+		t.Error("ScanEmailsDisabled() = false with DISABLE_SCAN_EMAILS=true")
+	}
+}
+
+func TestRepositoryNotifyEmailsAreStoredNormalized(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, _ := createTestScan(t, queries)
+	service := NewRepositoryNotifyService(queries)
+
+	stored, err := service.SetNotifyEmails(ctx, repoID, []string{"Sec@Example.com", "lead@example.com", "sec@example.com"})
+	if err != nil {
+		t.Fatalf("SetNotifyEmails returned error: %v", err)
+	}
+	want := []string{"sec@example.com", "lead@example.com"}
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("SetNotifyEmails stored %q, want %q", stored, want)
+	}
+
+	got, err := service.GetNotifyEmails(ctx, repoID)
+	if err != nil {
+		t.Fatalf("GetNotifyEmails returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNotifyEmails = %q, want %q", got, want)
+	}
+
+	const unknownRepo = "00000000-0000-0000-0000-000000000000"
+	if _, err := service.GetNotifyEmails(ctx, unknownRepo); !errors.Is(err, ErrRepositoryNotFound) {
+		t.Errorf("GetNotifyEmails(unknown) error = %v, want ErrRepositoryNotFound", err)
+	}
+	if _, err := service.SetNotifyEmails(ctx, unknownRepo, want); !errors.Is(err, ErrRepositoryNotFound) {
+		t.Errorf("SetNotifyEmails(unknown) error = %v, want ErrRepositoryNotFound", err)
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// OAuthStateLifetime is how long a user has to come back from Google's consent page
+const OAuthStateLifetime = 10 * time.Minute
+
+// ErrInvalidOAuthState is returned for OAuth states that are unknown, expired, already used, or don't match
+var ErrInvalidOAuthState = errors.New("invalid or expired OAuth state")
+
+// OAuthState is a login in progress: ID goes in the browser's cookie and State in the redirect to Google
+type OAuthState struct {
+	ID        string
+	State     string
+	ExpiresAt time.Time
+}
+
+// OAuthStateStore keeps the state of OAuth logins on the server, so the callback can be verified
+// by whichever instance it reaches. Each state can be used once
+type OAuthStateStore interface {
+	// Create stores the state of a login that is being started
+	Create(ctx context.Context) (*OAuthState, error)
+
+	// Consume deletes the state stored under id and checks the callback's state against it
+	// It returns ErrInvalidOAuthState if the state can't be used
+	Consume(ctx context.Context, id, state string) error
+}
+
+// NewOAuthStateStore creates an OAuth state store backed by the oauth_states table
+func NewOAuthStateStore(dbQueries *db.Queries) OAuthStateStore {
+	return &oauthStateStore{
+		db:  dbQueries,
+		now: time.Now,
+	}
+}
+
+// oauthStateStore implements the OAuthStateStore interface
+type oauthStateStore struct {
+	db  *db.Queries
+	now func() time.Time // Replaced in tests to expire states
+}
+
+// randomOAuthValue returns 32 random bytes encoded for use in a cookie or URL
+func randomOAuthValue() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashOAuthState returns the stored form of an OAuth state
+func hashOAuthState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkOAuthState compares a callback's state with a stored one that has not expired
+func checkOAuthState(storedHash string, expiresAt time.Time, state string, now time.Time) error {
+	if !now.Before(expiresAt) {
+		return ErrInvalidOAuthState
+	}
+	// The state is as secret as a password until it is used, so compare in constant time
+	if subtle.ConstantTimeCompare([]byte(hashOAuthState(state)), []byte(storedHash)) != 1 {
+		return ErrInvalidOAuthState
+	}
+	return nil
+}
+
+func (s *oauthStateStore) Create(ctx context.Context) (*OAuthState, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	id, err := randomOAuthValue()
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomOAuthValue()
+	if err != nil {
+		return nil, err
+	}
+	created := &OAuthState{ID: id, State: state, ExpiresAt: s.now().Add(OAuthStateLifetime).UTC()}
+
+	_, err = sqlDB.ExecContext(ctx,
+		`INSERT INTO oauth_states (id, state_hash, expires_at) VALUES ($1, $2, $3)`,
+		created.ID, hashOAuthState(created.State), created.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store OAuth state: %w", err)
+	}
+
+	// Logins that were abandoned on Google's consent page leave their state behind
+	if _, err := sqlDB.ExecContext(ctx, `DELETE FROM oauth_states WHERE expires_at <= $1`, s.now()); err != nil {
+		logger.Warn("Failed to prune expired OAuth states", zap.Error(err))
+	}
+
+	return created, nil
+}
+
+func (s *oauthStateStore) Consume(ctx context.Context, id, state string) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+	if id == "" || state == "" {
+		return ErrInvalidOAuthState
+	}
+
+	// Deleting in the same statement that reads the state means it can only be used once,
+	// even by two callbacks arriving together
+	var storedHash string
+	var expiresAt time.Time
+	err := sqlDB.QueryRowContext(ctx,
+		`DELETE FROM oauth_states WHERE id = $1 RETURNING state_hash, expires_at`, id).Scan(&storedHash, &expiresAt)
+	if err == sql.ErrNoRows {
+		return ErrInvalidOAuthState
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up OAuth state: %w", err)
+	}
+
+	return checkOAuthState(storedHash, expiresAt, state, s.now())
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckOAuthState(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	stored := hashOAuthState("state-1")
+
+	tests := []struct {
+		name      string
+		state     string
+		expiresAt time.Time
+		wantErr   error
+	}{
+		{name: "matching state", state: "state-1", expiresAt: now.Add(time.Minute)},
+		{name: "other state", state: "state-2", expiresAt: now.Add(time.Minute), wantErr: ErrInvalidOAuthState},
+		{name: "empty state", state: "", expiresAt: now.Add(time.Minute), wantErr: ErrInvalidOAuthState},
+		{name: "expired", state: "state-1", expiresAt: now.Add(-time.Second), wantErr: ErrInvalidOAuthState},
+		{name: "expiring now", state: "state-1", expiresAt: now, wantErr: ErrInvalidOAuthState},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkOAuthState(stored, tt.expiresAt, tt.state, now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkOAuthState() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOAuthStateLifecycle(t *testing.T) {
+	store := NewOAuthStateStore(newTestQueries(t))
+	ctx := context.Background()
+
+	state, err := store.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if state.ID == "" || state.State == "" || state.ID == state.State {
+		t.Fatalf("Create() = %+v, want distinct random ID and state", state)
+	}
+	if lifetime := time.Until(state.ExpiresAt); lifetime <= 0 || lifetime > OAuthStateLifetime {
+		t.Errorf("state expires in %v, want within %v", lifetime, OAuthStateLifetime)
+	}
+
+	if err := store.Consume(ctx, state.ID, state.State); err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	// A state is deleted on use, so the callback can't be replayed
+	if err := store.Consume(ctx, state.ID, state.State); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("second Consume = %v, want ErrInvalidOAuthState", err)
+	}
+}
+
+func TestOAuthStateRejectsMismatchesAndExpiry(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+
+	t.Run("wrong state burns the login", func(t *testing.T) {
+		store := NewOAuthStateStore(queries)
+		state, err := store.Create(ctx)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := store.Consume(ctx, state.ID, "forged"); !errors.Is(err, ErrInvalidOAuthState) {
+			t.Errorf("Consume with a forged state = %v, want ErrInvalidOAuthState", err)
+		}
+		if err := store.Consume(ctx, state.ID, state.State); !errors.Is(err, ErrInvalidOAuthState) {
+			t.Errorf("Consume after a forged attempt = %v, want ErrInvalidOAuthState", err)
+		}
+	})
+
+	t.Run("expired state", func(t *testing.T) {
+		store := NewOAuthStateStore(queries).(*oauthStateStore)
+		state, err := store.Create(ctx)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		store.now = func() time.Time { return time.Now().Add(OAuthStateLifetime + time.Second) }
+		if err := store.Consume(ctx, state.ID, state.State); !errors.Is(err, ErrInvalidOAuthState) {
+			t.Errorf("Consume after expiry = %v, want ErrInvalidOAuthState", err)
+		}
+	})
+
+	t.Run("expired states are pruned", func(t *testing.T) {
+		store := NewOAuthStateStore(queries).(*oauthStateStore)
+		abandoned, err := store.Create(ctx)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		store.now = func() time.Time { return time.Now().Add(OAuthStateLifetime + time.Second) }
+		if _, err := store.Create(ctx); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		var count int
+		if err := queries.GetDB().QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM oauth_states WHERE id = $1`, abandoned.ID).Scan(&count); err != nil {
+			t.Fatalf("count states: %v", err)
+		}
+		if count != 0 {
+			t.Error("expired state was kept after the next login started")
+		}
+	})
+
+	t.Run("unknown ID", func(t *testing.T) {
+		if err := NewOAuthStateStore(queries).Consume(ctx, "unknown", "state"); !errors.Is(err, ErrInvalidOAuthState) {
+			t.Errorf("Consume of an unknown ID = %v, want ErrInvalidOAuthState", err)
+		}
+	})
+}
@@ -1,16 +1,26 @@
-/*
- * DEPRECATED: This OpenAI service is no longer used and has been replaced by BAML orchestration.
- * It will be removed in a future release.
- */
-
 package services
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultAnalysisConcurrency is how many analyses AnalyzeMultipleFiles runs at once when OPENAI_MAX_CONCURRENCY is unset
+	DefaultAnalysisConcurrency = 4
+
+	// DefaultAnalysisRequestsPerMinute paces model requests when OPENAI_REQUESTS_PER_MINUTE is unset
+	DefaultAnalysisRequestsPerMinute = 60
 )
 
 // AnalysisRequest represents a request to analyze code for vulnerabilities
@@ -27,83 +37,208 @@ type AnalysisResponse struct {
 	RawResponse     string // Raw response from OpenAI for debugging
 }
 
+// AnalysisResult is the outcome of one request in a batch
+// Exactly one of Response and Err is set
+type AnalysisResult struct {
+	Response *AnalysisResponse
+	Err      error
+}
+
 // OpenAIService defines the interface for OpenAI operations
 type OpenAIService interface {
 	// AnalyzeCode analyzes code for vulnerabilities using OpenAI
 	AnalyzeCode(ctx context.Context, req *AnalysisRequest) (*AnalysisResponse, error)
 
-	// AnalyzeMultipleFiles analyzes multiple files in batch
-	AnalyzeMultipleFiles(ctx context.Context, requests []*AnalysisRequest) ([]*AnalysisResponse, error)
+	// AnalyzeMultipleFiles analyzes multiple files concurrently
+	// The results line up with requests by index; a failed request leaves its Err set and does not stop the others
+	// The returned error joins every per-request error, and is nil only if all of them succeeded
+	AnalyzeMultipleFiles(ctx context.Context, requests []*AnalysisRequest) ([]AnalysisResult, error)
+}
+
+// OpenAIConfig holds the model settings and request limits of the OpenAI service
+type OpenAIConfig struct {
+	Model       string
+	MaxTokens   int
+	Temperature float64
+
+	Concurrency       int // Analyses run at once by AnalyzeMultipleFiles
+	RequestsPerMinute int // Sustained model request rate shared by every caller of the service
+
+	Provider baml.Provider // LLM to send prompts to; nil selects one from LLM_PROVIDER
 }
 
-// NewOpenAIService creates a new OpenAI service instance
+// NewOpenAIService creates a new OpenAI service instance configured from the environment
+// Model settings are the scanner's; OPENAI_MAX_CONCURRENCY and OPENAI_REQUESTS_PER_MINUTE bound batch analyses
 func NewOpenAIService() OpenAIService {
-	return &openAIService{}
+	scannerConfig := baml.ConfigFromEnv()
+	return NewOpenAIServiceWithConfig(OpenAIConfig{
+		Model:             scannerConfig.Model,
+		MaxTokens:         scannerConfig.MaxTokens,
+		Temperature:       scannerConfig.Temperature,
+		Concurrency:       positiveIntFromEnv("OPENAI_MAX_CONCURRENCY", DefaultAnalysisConcurrency),
+		RequestsPerMinute: positiveIntFromEnv("OPENAI_REQUESTS_PER_MINUTE", DefaultAnalysisRequestsPerMinute),
+		Provider:          baml.NewProviderFromEnv(baml.RequestTimeoutFromEnv(), scannerConfig.Retry),
+	})
+}
+
+// NewOpenAIServiceWithConfig creates an OpenAI service with explicit settings
+// Zero values in config fall back to the defaults
+func NewOpenAIServiceWithConfig(config OpenAIConfig) OpenAIService {
+	if config.Model == "" {
+		config.Model = baml.DefaultModel
+	}
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = 4000
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultAnalysisConcurrency
+	}
+	if config.RequestsPerMinute <= 0 {
+		config.RequestsPerMinute = DefaultAnalysisRequestsPerMinute
+	}
+	if config.Provider == nil {
+		config.Provider = baml.NewProviderFromEnv(baml.RequestTimeoutFromEnv(), baml.ConfigFromEnv().Retry)
+	}
+
+	// A token bucket refilled at the per-minute rate; the burst lets every worker start at once
+	limit := rate.Limit(float64(config.RequestsPerMinute) / 60)
+	return &openAIService{
+		provider:    config.Provider,
+		model:       config.Model,
+		maxTokens:   config.MaxTokens,
+		temperature: config.Temperature,
+		concurrency: config.Concurrency,
+		limiter:     rate.NewLimiter(limit, min(config.Concurrency, config.RequestsPerMinute)),
+	}
+}
+
+// positiveIntFromEnv reads a positive integer setting, falling back to def when it is unset or invalid
+func positiveIntFromEnv(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		logger.Warn("Invalid "+name+" value, using default", zap.String("value", value), zap.Int("default", def))
+		return def
+	}
+	return parsed
 }
 
 // openAIService implements the OpenAIService interface
 type openAIService struct {
-	// Configuration fields
-	apiKey      string
+	provider    baml.Provider
 	model       string
 	maxTokens   int
 	temperature float64
+	concurrency int           // Most analyses in flight per AnalyzeMultipleFiles call
+	limiter     *rate.Limiter // Paces model requests across all calls
 }
 
 func (s *openAIService) AnalyzeCode(ctx context.Context, req *AnalysisRequest) (*AnalysisResponse, error) {
-	// Implement OpenAI API call for code analysis
-	client := &http.Client{}
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"model":       s.model,
-		"prompt":      req.Code,
-		"max_tokens":  s.maxTokens,
-		"temperature": s.temperature,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	if req == nil {
+		return nil, fmt.Errorf("analysis request is nil")
 	}
 
-	httpReq, err := http.NewRequest("POST", "https://api.openai.com/v1/engines/"+s.model+"/completions", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	vulnTypes := make([]string, 0, len(req.VulnTypes))
+	for _, vt := range req.VulnTypes {
+		vulnTypes = append(vulnTypes, string(vt))
+	}
+
+	// Wait for a token so a large batch can't exceed the provider's rate limit
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("waiting for rate limiter: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+s.apiKey)
 
-	resp, err := client.Do(httpReq)
+	content, err := s.provider.ScanCode(ctx, baml.Prompt{
+		Model:       s.model,
+		System:      baml.ScanSystemPrompt,
+		User:        baml.FormatScanPrompt(req.Code, req.Language, req.FilePath, vulnTypes),
+		MaxTokens:   s.maxTokens,
+		Temperature: s.temperature,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to call OpenAI API: %w", err)
+		return nil, fmt.Errorf("failed to call %s: %w", s.provider.Name(), err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	result, err := baml.ParseScanResult(content)
+	if err != nil {
+		return nil, err
 	}
 
-	var apiResponse struct {
-		Choices []struct {
-			Text string `json:"text"`
-		} `json:"choices"`
+	// Convert the model's findings the same way the scanner does
+	response := &AnalysisResponse{
+		Vulnerabilities: make([]*Vulnerability, 0, len(result.Vulnerabilities)),
+		RawResponse:     content,
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	for _, v := range result.Vulnerabilities {
+		response.Vulnerabilities = append(response.Vulnerabilities, &Vulnerability{
+			ID:          uuid.New().String(),
+			Type:        VulnerabilityType(v.VulnerabilityType),
+			FilePath:    req.FilePath,
+			LineStart:   v.LineStart,
+			LineEnd:     v.LineEnd,
+			Severity:    v.Severity,
+			Description: v.Description,
+			Remediation: v.Remediation,
+			Code:        v.CodeSnippet,
+			Confidence:  v.Confidence.Value(),
+			CWE:         string(v.CWE),
+			References:  v.References,
+		})
 	}
+	normalizeSeverities(response.Vulnerabilities)
 
-	// This will be replaced with BAML orchestration
-	return &AnalysisResponse{
-		Vulnerabilities: []*Vulnerability{}, // Placeholder for actual vulnerabilities
-		RawResponse:     apiResponse.Choices[0].Text,
-	}, nil
+	return response, nil
 }
 
-func (s *openAIService) AnalyzeMultipleFiles(ctx context.Context, requests []*AnalysisRequest) ([]*AnalysisResponse, error) {
-	var responses []*AnalysisResponse
-	for _, req := range requests {
-		response, err := s.AnalyzeCode(ctx, req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to analyze code for request: %w", err)
+func (s *openAIService) AnalyzeMultipleFiles(ctx context.Context, requests []*AnalysisRequest) ([]AnalysisResult, error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
+	}
+
+	results := make([]AnalysisResult, len(requests))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, s.concurrency)
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req *AnalysisRequest) {
+			defer wg.Done()
+
+			// Hold a slot so at most concurrency requests are in flight
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+				return
+			}
+
+			// Each goroutine writes only its own index, so the slice needs no lock
+			results[i].Response, results[i].Err = s.AnalyzeCode(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		filePath := ""
+		if requests[i] != nil {
+			filePath = requests[i].FilePath
 		}
-		responses = append(responses, response)
+		errs = append(errs, fmt.Errorf("request %d (%s): %w", i, filePath, result.Err))
 	}
-	return responses, nil
+	if len(errs) > 0 {
+		log.Warn("Some analyses failed",
+			zap.Int("requests", len(requests)),
+			zap.Int("failed", len(errs)))
+	}
+
+	return results, errors.Join(errs...)
 }
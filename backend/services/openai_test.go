@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+)
+
+// concurrencyProvider answers each prompt after a delay and records the most prompts it handled at once
+// Prompts for files whose path contains "fail" get an error
+type concurrencyProvider struct {
+	delay    time.Duration
+	inFlight atomic.Int32
+	peak     atomic.Int32
+	calls    atomic.Int32
+}
+
+func (p *concurrencyProvider) Name() string {
+	return "concurrency"
+}
+
+func (p *concurrencyProvider) ScanCode(ctx context.Context, prompt baml.Prompt) (string, error) {
+	p.calls.Add(1)
+	current := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if current <= peak || p.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if strings.Contains(prompt.User, "File path: fail") {
+		return "", errors.New("model unavailable")
+	}
+	return `{"vulnerabilities": [{"vulnerability_type": "Injection", "line_start": 1, "line_end": 1, "severity": "high"}]}`, nil
+}
+
+// analysisRequests returns one request per file path
+func analysisRequests(paths ...string) []*AnalysisRequest {
+	requests := make([]*AnalysisRequest, len(paths))
+	for i, path := range paths {
+		requests[i] = &AnalysisRequest{Code: "package main\n", Language: "Go", FilePath: path, VulnTypes: []VulnerabilityType{Injection}}
+	}
+	return requests
+}
+
+func TestAnalyzeMultipleFilesBoundsConcurrency(t *testing.T) {
+	provider := &concurrencyProvider{delay: 20 * time.Millisecond}
+	service := NewOpenAIServiceWithConfig(OpenAIConfig{Concurrency: 3, RequestsPerMinute: 60000, Provider: provider})
+
+	paths := make([]string, 12)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file%d.go", i)
+	}
+	results, err := service.AnalyzeMultipleFiles(context.Background(), analysisRequests(paths...))
+	if err != nil {
+		t.Fatalf("AnalyzeMultipleFiles returned error: %v", err)
+	}
+
+	if got := provider.peak.Load(); got != 3 {
+		t.Errorf("at most %d requests ran at once, want the concurrency limit of 3", got)
+	}
+	for i, result := range results {
+		if result.Err != nil || len(result.Response.Vulnerabilities) != 1 {
+			t.Fatalf("result %d = %+v, want one finding", i, result)
+		}
+		// Results line up with requests, and findings are normalized like the scanner's
+		if vuln := result.Response.Vulnerabilities[0]; vuln.FilePath != paths[i] || vuln.Severity != "High" {
+			t.Errorf("result %d finding = %+v, want a High finding in %s", i, vuln, paths[i])
+		}
+	}
+}
+
+func TestAnalyzeMultipleFilesCollectsPartialErrors(t *testing.T) {
+	provider := &concurrencyProvider{}
+	service := NewOpenAIServiceWithConfig(OpenAIConfig{Concurrency: 2, RequestsPerMinute: 60000, Provider: provider})
+
+	requests := analysisRequests("ok1.go", "fail1.go", "ok2.go", "fail2.go")
+	requests = append(requests, nil)
+	results, err := service.AnalyzeMultipleFiles(context.Background(), requests)
+	if err == nil {
+		t.Fatal("AnalyzeMultipleFiles returned no error with failing requests")
+	}
+	for _, path := range []string{"fail1.go", "fail2.go"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("error %q doesn't name %s", err, path)
+		}
+	}
+
+	// The failures don't stop the other requests
+	wantFailed := []bool{false, true, false, true, true}
+	for i, result := range results {
+		if failed := result.Err != nil; failed != wantFailed[i] {
+			t.Errorf("result %d error = %v, want failed %v", i, result.Err, wantFailed[i])
+		}
+		if (result.Response != nil) == wantFailed[i] {
+			t.Errorf("result %d response = %+v, want exactly one of response and error", i, result.Response)
+		}
+	}
+	if got := provider.calls.Load(); got != 4 {
+		t.Errorf("sent %d prompts, want one for each non-nil request", got)
+	}
+}
+
+func TestAnalyzeMultipleFilesIsRateLimited(t *testing.T) {
+	provider := &concurrencyProvider{}
+	// 600 a minute is one request every 100ms once the burst of 2 is spent
+	service := NewOpenAIServiceWithConfig(OpenAIConfig{Concurrency: 2, RequestsPerMinute: 600, Provider: provider})
+
+	start := time.Now()
+	if _, err := service.AnalyzeMultipleFiles(context.Background(), analysisRequests("a.go", "b.go", "c.go", "d.go")); err != nil {
+		t.Fatalf("AnalyzeMultipleFiles returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("4 requests took %v, want the last two paced about 100ms apart", elapsed)
+	}
+}
+
+func TestAnalyzeMultipleFilesStopsWhenCanceled(t *testing.T) {
+	provider := &concurrencyProvider{delay: time.Minute}
+	service := NewOpenAIServiceWithConfig(OpenAIConfig{Concurrency: 1, RequestsPerMinute: 60000, Provider: provider})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results, err := service.AnalyzeMultipleFiles(ctx, analysisRequests("a.go", "b.go", "c.go"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AnalyzeMultipleFiles error = %v, want the deadline", err)
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, context.DeadlineExceeded) {
+			t.Errorf("result %d error = %v, want the deadline", i, result.Err)
+		}
+	}
+}
+
+func TestAnalyzeCodeUsesChatCompletions(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "Found:\n{\"vulnerabilities\": [{\"vulnerability_type\": \"Injection\", \"line_start\": 2, \"line_end\": 3, \"severity\": \"Critical\", \"confidence\": \"high\"}]}"}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+	t.Setenv("OPENAI_API_KEY", "test-key")
+
+	service := NewOpenAIServiceWithConfig(OpenAIConfig{Model: "gpt-4o"})
+	response, err := service.AnalyzeCode(context.Background(), analysisRequests("main.go")[0])
+	if err != nil {
+		t.Fatalf("AnalyzeCode returned error: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/chat/completions" {
+		t.Errorf("requested %v, want the chat completions endpoint", paths)
+	}
+	if len(response.Vulnerabilities) != 1 {
+		t.Fatalf("findings = %+v, want one", response.Vulnerabilities)
+	}
+	vuln := response.Vulnerabilities[0]
+	if vuln.LineStart != 2 || vuln.Severity != "Critical" || vuln.Confidence == nil || *vuln.Confidence != float64(baml.ConfidenceHigh) {
+		t.Errorf("finding = %+v, want the model's finding with its confidence", vuln)
+	}
+
+	if _, err := service.AnalyzeCode(context.Background(), nil); err == nil {
+		t.Error("AnalyzeCode accepted a nil request")
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password policy for email/password accounts
+const (
+	MinPasswordLength = 10
+	maxPasswordBytes  = 72 // bcrypt ignores anything longer
+)
+
+var (
+	// ErrEmailTaken is returned when registering an email that already has an account
+	ErrEmailTaken = errors.New("an account with this email already exists")
+
+	// ErrInvalidCredentials is returned for an unknown email or a wrong password
+	// The two are not distinguished so login can't be used to discover accounts
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	// ErrPasswordNotSet is returned when the account signs in with Google only
+	// Registering the email already reports that it is taken, so saying so here reveals nothing more
+	ErrPasswordNotSet = errors.New("this account uses Google Sign-In and has no password")
+)
+
+// ValidatePassword checks a password against the password policy
+// It returns a message describing the first rule broken, or "" when the password is acceptable
+func ValidatePassword(password string) string {
+	if len(password) < MinPasswordLength {
+		return fmt.Sprintf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Sprintf("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	var hasLetter, hasDigit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLetter(c):
+			hasLetter = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return "password must contain at least one letter and one digit"
+	}
+	return ""
+}
+
+// RegisterWithPassword creates an email/password account
+// Emails are compared case-insensitively, so an existing account under any casing returns ErrEmailTaken
+func (s *AuthService) RegisterWithPassword(ctx context.Context, name, email, password string) (*User, error) {
+	if s.dbConn == nil || s.dbConn.GetDB() == nil {
+		return nil, errors.New("database connection not initialized")
+	}
+	db := s.dbConn.GetDB()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	email = strings.TrimSpace(email)
+	user := &User{Email: email, Name: name}
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO users (email, name, password_hash)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))
+		RETURNING id, role`,
+		email, name, string(hash)).Scan(&user.ID, &user.Role)
+	if err == sql.ErrNoRows {
+		return nil, ErrEmailTaken
+	}
+	// A concurrent registration of the same email trips the unique constraint instead
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	logger.Info("User registered with password", zap.String("user_id", user.ID))
+	return user, nil
+}
+
+// AuthenticatePassword verifies an email/password login and returns the user
+// It returns ErrInvalidCredentials for an unknown email or wrong password, and ErrPasswordNotSet
+// for accounts that only sign in with Google
+func (s *AuthService) AuthenticatePassword(ctx context.Context, email, password string) (*User, error) {
+	if s.dbConn == nil || s.dbConn.GetDB() == nil {
+		return nil, errors.New("database connection not initialized")
+	}
+	db := s.dbConn.GetDB()
+
+	user := &User{}
+	var name, avatarURL, passwordHash sql.NullString
+	err := db.QueryRowContext(ctx,
+		`SELECT id, email, name, avatar_url, role, password_hash FROM users WHERE LOWER(email) = LOWER($1)`,
+		strings.TrimSpace(email)).Scan(&user.ID, &user.Email, &name, &avatarURL, &user.Role, &passwordHash)
+	if err == sql.ErrNoRows {
+		// Still spend a hash comparison so response time doesn't reveal whether the email exists
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	user.Name = name.String
+	user.AvatarURL = avatarURL.String
+
+	if !passwordHash.Valid || passwordHash.String == "" {
+		return nil, ErrPasswordNotSet
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash.String), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// dummyPasswordHash is compared against when the email is unknown
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("timing-equalizer-password-1"), bcrypt.DefaultCost)
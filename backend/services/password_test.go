@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newTestAuthService returns an auth service on the test database
+func newTestAuthService(t *testing.T) *AuthService {
+	return &AuthService{dbConn: newTestQueries(t)}
+}
+
+// uniqueEmail returns an address no other test uses
+func uniqueEmail() string {
+	return "user-" + uuid.NewString() + "@example.com"
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantOK   bool
+	}{
+		{name: "letters and digits", password: "correcthorse1", wantOK: true},
+		{name: "too short", password: "abc123"},
+		{name: "too long for bcrypt", password: strings.Repeat("a1", 40)},
+		{name: "no digit", password: "correcthorsebattery"},
+		{name: "no letter", password: "12345678901"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidatePassword(tt.password); (got == "") != tt.wantOK {
+				t.Errorf("ValidatePassword(%q) = %q, want ok %v", tt.password, got, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegisterAndLoginWithPassword(t *testing.T) {
+	service := newTestAuthService(t)
+	ctx := context.Background()
+	email := uniqueEmail()
+
+	registered, err := service.RegisterWithPassword(ctx, "Ada", email, "correcthorse1")
+	if err != nil {
+		t.Fatalf("RegisterWithPassword returned error: %v", err)
+	}
+	if registered.ID == "" || registered.Email != email {
+		t.Fatalf("registered user = %+v, want an ID and email %s", registered, email)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "right password", email: email, password: "correcthorse1"},
+		{name: "email in another case", email: strings.ToUpper(email), password: "correcthorse1"},
+		{name: "wrong password", email: email, password: "wronghorse1", wantErr: ErrInvalidCredentials},
+		{name: "unknown email", email: uniqueEmail(), password: "correcthorse1", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := service.AuthenticatePassword(ctx, tt.email, tt.password)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("AuthenticatePassword error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticatePassword returned error: %v", err)
+			}
+			if user.ID != registered.ID {
+				t.Errorf("logged in as %s, want %s", user.ID, registered.ID)
+			}
+		})
+	}
+}
+
+func TestRegisterWithPasswordRefusesTakenEmail(t *testing.T) {
+	service := newTestAuthService(t)
+	ctx := context.Background()
+	email := uniqueEmail()
+
+	if _, err := service.RegisterWithPassword(ctx, "Ada", email, "correcthorse1"); err != nil {
+		t.Fatalf("first registration returned error: %v", err)
+	}
+
+	for _, again := range []string{email, strings.ToUpper(email), "  " + email + " "} {
+		if _, err := service.RegisterWithPassword(ctx, "Mallory", again, "otherhorse2"); !errors.Is(err, ErrEmailTaken) {
+			t.Errorf("registering %q again: error = %v, want ErrEmailTaken", again, err)
+		}
+	}
+
+	// The first password still logs in; the refused registrations changed nothing
+	if _, err := service.AuthenticatePassword(ctx, email, "correcthorse1"); err != nil {
+		t.Errorf("original password no longer logs in: %v", err)
+	}
+	if _, err := service.AuthenticatePassword(ctx, email, "otherhorse2"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("refused registration's password: error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestAuthenticatePasswordGoogleOnlyAccount(t *testing.T) {
+	service := newTestAuthService(t)
+	ctx := context.Background()
+	email := uniqueEmail()
+
+	if _, err := service.dbConn.GetDB().ExecContext(ctx,
+		`INSERT INTO users (email, name, google_id) VALUES ($1, 'Grace', $2)`,
+		email, "google-"+uuid.NewString()); err != nil {
+		t.Fatalf("insert Google user: %v", err)
+	}
+
+	if _, err := service.AuthenticatePassword(ctx, email, "correcthorse1"); !errors.Is(err, ErrPasswordNotSet) {
+		t.Errorf("AuthenticatePassword error = %v, want ErrPasswordNotSet", err)
+	}
+}
+
+func TestGoogleSignInClaimsAPasswordAccount(t *testing.T) {
+	service := newTestAuthService(t)
+	ctx := context.Background()
+	email := uniqueEmail()
+
+	// Someone registers the email before its owner ever signs in
+	squatter, err := service.RegisterWithPassword(ctx, "Mallory", email, "correcthorse1")
+	if err != nil {
+		t.Fatalf("RegisterWithPassword returned error: %v", err)
+	}
+	sqlDB := service.dbConn.GetDB()
+	if _, err := sqlDB.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, NOW() + INTERVAL '1 day')`,
+		squatter.ID, "hash-"+uuid.NewString()); err != nil {
+		t.Fatalf("insert refresh token: %v", err)
+	}
+
+	userID, err := service.CreateOrUpdateUser(ctx, &GoogleUserInfo{ID: "google-" + uuid.NewString(), Email: email, Name: "Ada"})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateUser returned error: %v", err)
+	}
+	if userID != squatter.ID {
+		t.Fatalf("Google Sign-In created user %s, want it to claim %s", userID, squatter.ID)
+	}
+
+	if _, err := service.AuthenticatePassword(ctx, email, "correcthorse1"); !errors.Is(err, ErrPasswordNotSet) {
+		t.Errorf("squatter's password after the claim: error = %v, want ErrPasswordNotSet", err)
+	}
+	var refreshTokens int
+	if err := sqlDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM refresh_tokens WHERE user_id = $1`, userID).Scan(&refreshTokens); err != nil {
+		t.Fatalf("count refresh tokens: %v", err)
+	}
+	if refreshTokens != 0 {
+		t.Errorf("%d refresh tokens survived the claim, want 0", refreshTokens)
+	}
+}
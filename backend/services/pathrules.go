@@ -0,0 +1,117 @@
+package services
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PathRules decide which findings come from code the user doesn't own, such as vendored or generated files
+// Findings in excluded paths are kept but marked, so they can be shown on request without inflating counts
+type PathRules struct {
+	Include []string // Globs that are never excluded, even if an exclude glob also matches
+	Exclude []string // Globs whose findings are marked excluded
+}
+
+// PathRulesFromEnv reads comma-separated globs from SCAN_EXCLUDE_PATHS and SCAN_INCLUDE_PATHS
+// Globs use "/" separators; "*" matches within one path segment and "**" across segments.
+// A glob without a "/" is also matched against the file name alone, e.g. "*.pb.go"
+func PathRulesFromEnv() *PathRules {
+	return &PathRules{
+		Include: splitGlobList(os.Getenv("SCAN_INCLUDE_PATHS")),
+		Exclude: splitGlobList(os.Getenv("SCAN_EXCLUDE_PATHS")),
+	}
+}
+
+// Excluded reports whether findings in the given repository-relative path should be marked excluded
+func (r *PathRules) Excluded(filePath string) bool {
+	if r == nil || len(r.Exclude) == 0 {
+		return false
+	}
+
+	// Include rules win over exclude rules
+	if MatchesAnyGlob(r.Include, filePath) {
+		return false
+	}
+	return MatchesAnyGlob(r.Exclude, filePath)
+}
+
+// LLMDenylistFromEnv reads the globs of files that must never be sent to the LLM from SCAN_LLM_DENYLIST
+// When the variable is unset, secrets directories and key/certificate files are denied by default;
+// setting it (even to an empty value) replaces the defaults
+func LLMDenylistFromEnv() []string {
+	value, ok := os.LookupEnv("SCAN_LLM_DENYLIST")
+	if !ok {
+		return []string{"**/secrets/**", "*.key", "*.pem", "*.p12", "*.pfx"}
+	}
+	return splitGlobList(value)
+}
+
+// MatchesAnyGlob reports whether the repository-relative path matches any of the globs
+func MatchesAnyGlob(globs []string, filePath string) bool {
+	filePath = strings.TrimPrefix(strings.ReplaceAll(filePath, "\\", "/"), "./")
+	for _, pattern := range globs {
+		if matchPathGlob(pattern, filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitGlobList parses a comma-separated list of globs, ignoring blanks
+func splitGlobList(value string) []string {
+	var globs []string
+	for _, glob := range strings.Split(value, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			globs = append(globs, glob)
+		}
+	}
+	return globs
+}
+
+// matchPathGlob matches a slash-separated path against a glob that may contain "**"
+func matchPathGlob(pattern, filePath string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	// Bare file-name globs apply at any depth, like .gitignore entries
+	if !strings.Contains(pattern, "/") {
+		if matched, _ := path.Match(pattern, path.Base(filePath)); matched {
+			return true
+		}
+	}
+
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		return false
+	}
+	return re.MatchString(filePath)
+}
+
+// globToRegexp translates a glob into an anchored regular expression
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			// "**/" matches zero or more leading directories
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPathRulesExcluded(t *testing.T) {
+	rules := &PathRules{
+		Include: []string{"third_party/ours/**"},
+		Exclude: []string{"third_party/**", "*.pb.go", "**/generated/*.go", "docs/?.js"},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "main.go", want: false},
+		{path: "third_party/lib/lib.go", want: true},
+		{path: "third_party/ours/patched.go", want: false},
+		{path: "api/v1/service.pb.go", want: true},
+		{path: "service.pb.go", want: true},
+		{path: "internal/generated/models.go", want: true},
+		{path: "generated/models.go", want: true},
+		{path: "internal/generated/nested/models.go", want: false},
+		{path: "docs/a.js", want: true},
+		{path: "docs/ab.js", want: false},
+		{path: "./third_party/lib/lib.go", want: true},
+		{path: `third_party\lib\lib.go`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := rules.Excluded(tt.path); got != tt.want {
+				t.Errorf("Excluded(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	var none *PathRules
+	if none.Excluded("third_party/lib/lib.go") {
+		t.Error("nil rules excluded a path")
+	}
+}
+
+func TestPathRulesFromEnv(t *testing.T) {
+	t.Setenv("SCAN_EXCLUDE_PATHS", " third_party/** ,, *.pb.go ")
+	t.Setenv("SCAN_INCLUDE_PATHS", "")
+
+	rules := PathRulesFromEnv()
+	if len(rules.Exclude) != 2 || rules.Exclude[0] != "third_party/**" || rules.Exclude[1] != "*.pb.go" {
+		t.Errorf("Exclude = %q, want [third_party/** *.pb.go]", rules.Exclude)
+	}
+	if len(rules.Include) != 0 {
+		t.Errorf("Include = %q, want none", rules.Include)
+	}
+}
+
+func TestScanRepositoryMarksFindingsInExcludedPaths(t *testing.T) {
+	root := writeFixtureTree(t, []string{"main.go", "third_party/proto/proto.go", "api/service.pb.go"})
+	useModelTransport(t, &slowModelTransport{})
+
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		PathRules:          &PathRules{Exclude: []string{"third_party/**", "*.pb.go"}},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	// Every file still reports its finding, marked rather than dropped
+	if len(result.Vulnerabilities) != 3 {
+		t.Fatalf("got %d findings, want one per file", len(result.Vulnerabilities))
+	}
+	for _, vuln := range result.Vulnerabilities {
+		if want := vuln.FilePath != "main.go"; vuln.Excluded != want {
+			t.Errorf("finding in %s Excluded = %v, want %v", vuln.FilePath, vuln.Excluded, want)
+		}
+	}
+}
+
+// bodyRecordingTransport keeps the body of every model request and answers with no findings
+type bodyRecordingTransport struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (b *bodyRecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	b.bodies = append(b.bodies, string(body))
+	b.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body: io.NopCloser(strings.NewReader(
+			`{"choices": [{"message": {"role": "assistant", "content": "{\"vulnerabilities\": []}"}}]}`)),
+		Request: req,
+	}, nil
+}
+
+func TestScanRepositoryNeverSendsDeniedFilesToTheModel(t *testing.T) {
+	root := writeFixtureTree(t, []string{"main.go"})
+	secretPath := filepath.Join(root, "config", "secrets", "token.go")
+	if err := os.MkdirAll(filepath.Dir(secretPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secretPath, []byte("package secrets\n\nconst token = \"do-not-send-me\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &bodyRecordingTransport{}
+	useModelTransport(t, transport)
+
+	// A local detector still sees denied files
+	var locallyScanned []string
+	detectToken := func(relPath, code string) []*Vulnerability {
+		locallyScanned = append(locallyScanned, relPath)
+		if !strings.Contains(code, "do-not-send-me") {
+			return nil
+		}
+		return []*Vulnerability{{Type: CryptographicFailures, LineStart: 3, LineEnd: 3, Severity: "High"}}
+	}
+
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		LLMDenylist:        []string{"**/secrets/**"},
+		LocalDetectors:     []LocalDetector{detectToken},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	if len(transport.bodies) != 1 {
+		t.Fatalf("model received %d requests, want only main.go", len(transport.bodies))
+	}
+	if strings.Contains(transport.bodies[0], "do-not-send-me") || strings.Contains(transport.bodies[0], "secrets/token.go") {
+		t.Error("the denied file reached the model")
+	}
+	if len(locallyScanned) != 2 {
+		t.Errorf("local detector saw %q, want both files", locallyScanned)
+	}
+	if len(result.Vulnerabilities) != 1 || result.Vulnerabilities[0].FilePath != "config/secrets/token.go" || result.Vulnerabilities[0].ID == "" {
+		t.Errorf("findings = %+v, want the local finding in the denied file", result.Vulnerabilities)
+	}
+}
+
+func TestLLMDenylistFromEnv(t *testing.T) {
+	if got := LLMDenylistFromEnv(); !MatchesAnyGlob(got, "deploy/secrets/prod.env") || !MatchesAnyGlob(got, "certs/server.key") {
+		t.Errorf("default denylist %q misses secrets and keys", got)
+	}
+
+	t.Setenv("SCAN_LLM_DENYLIST", "")
+	if got := LLMDenylistFromEnv(); len(got) != 0 {
+		t.Errorf("an empty SCAN_LLM_DENYLIST gave %q, want the defaults replaced", got)
+	}
+
+	t.Setenv("SCAN_LLM_DENYLIST", "licensed/**")
+	if got := LLMDenylistFromEnv(); len(got) != 1 || !MatchesAnyGlob(got, "licensed/codec.c") {
+		t.Errorf("denylist = %q, want [licensed/**]", got)
+	}
+}
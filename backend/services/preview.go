@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+)
+
+// ScanPreview describes what a scan of a repository would analyze and roughly what it would cost
+// Building one reads the files but sends nothing to the model
+type ScanPreview struct {
+	Files            []string       `json:"files"`              // Repository-relative paths the scan would analyze, in scan order
+	FileCount        int            `json:"file_count"`         // Number of files listed
+	Languages        map[string]int `json:"languages"`          // Files per detected language
+	LLMDeniedFiles   int            `json:"llm_denied_files"`   // Listed files only local detectors would see
+	TooLargeFiles    int            `json:"too_large_files"`    // Listed files the scan would skip unread for their size
+	Model            string         `json:"model"`              // Model the estimate is priced for
+	EstimatedTokens  int            `json:"estimated_tokens"`   // Prompt tokens the model requests would add up to, before cache hits
+	EstimatedCostUSD float64        `json:"estimated_cost_usd"` // Estimated cost of those tokens
+}
+
+// PreviewScan lists the files a scan of repoDir with these options would analyze, counted by language,
+// with the tokens and cost the scan's budget would charge for them. Files are selected by
+// CollectFilesToScan, exactly as ScanRepository selects them; cached results aren't accounted for, so the
+// estimate is an upper bound for a rescan
+func PreviewScan(ctx context.Context, repoDir string, options *ScanOptions) (*ScanPreview, error) {
+	filesToScan, err := CollectFilesToScan(ctx, repoDir, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var vulnTypeStrings []string
+	for _, vt := range options.VulnerabilityTypes {
+		vulnTypeStrings = append(vulnTypeStrings, string(vt))
+	}
+	model := options.Model
+	if model == "" {
+		model = baml.ConfiguredModel()
+	}
+
+	preview := &ScanPreview{
+		Files:     make([]string, 0, len(filesToScan)),
+		Languages: make(map[string]int),
+		Model:     model,
+	}
+	for _, filePath := range filesToScan {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("scan preview aborted: %w", err)
+		}
+
+		relPath, err := filepath.Rel(repoDir, filePath)
+		if err != nil {
+			relPath = filePath
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		// The scan skips oversized files without reading them, so they add no tokens
+		limit := maxFileSize(options.MaxFileSizeBytes)
+		if _, tooLarge, err := exceedsMaxFileSize(filePath, limit); err == nil && tooLarge {
+			preview.Files = append(preview.Files, relPath)
+			preview.Languages[DetectLanguage(relPath, "")]++
+			preview.TooLargeFiles++
+			continue
+		}
+
+		// The scan skips unreadable files, but they are still part of its selection
+		codeBytes, err := os.ReadFile(filePath)
+		if err != nil {
+			codeBytes = nil
+		}
+		code := NormalizeSource(string(codeBytes))
+		language := DetectLanguage(relPath, code)
+
+		preview.Files = append(preview.Files, relPath)
+		preview.Languages[language]++
+		if MatchesAnyGlob(options.LLMDenylist, relPath) {
+			preview.LLMDeniedFiles++
+			continue
+		}
+		preview.EstimatedTokens += estimateScanTokens(code, language, relPath, vulnTypeStrings)
+	}
+	preview.FileCount = len(preview.Files)
+	preview.EstimatedCostUSD = EstimateCostUSD(model, preview.EstimatedTokens)
+	return preview, nil
+}
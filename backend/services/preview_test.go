@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPreviewScanListsTheFilesTheScanAnalyzes(t *testing.T) {
+	root := writeFixtureTree(t, []string{
+		"main.go",
+		"handlers/login.go",
+		"handlers/login_test.go",
+		"web/app.js",
+		"web/app.min.js",
+		"web/index.html",
+		"scripts/deploy.sh",
+		"vendor/example.com/lib/lib.go",
+		"node_modules/pkg/index.js",
+		"generated/api.go",
+		"docs/example.go",
+		"secrets/keys.go",
+		"README.md",
+	})
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("generated/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options ScanOptions
+	}{
+		{name: "default extensions", options: ScanOptions{FileExtensions: DefaultFileExtensions()}},
+		{name: "requested extensions", options: ScanOptions{FileExtensions: []string{".go", ".sh"}}},
+		{name: "skip rules adjusted", options: ScanOptions{
+			FileExtensions:  []string{".go", ".js"},
+			ScanVendored:    true,
+			ExcludePatterns: []string{"docs/**"},
+			IncludePatterns: []string{"generated/**"},
+		}},
+		{name: "include globs", options: ScanOptions{FileExtensions: []string{".go"}, IncludeGlobs: []string{"**/*.md", "handlers/**"}}},
+		{name: "file limit", options: ScanOptions{FileExtensions: DefaultFileExtensions(), MaxFiles: 2}},
+		{name: "llm denylist", options: ScanOptions{FileExtensions: []string{".go"}, LLMDenylist: []string{"secrets/**"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useModelTransport(t, &slowModelTransport{})
+
+			options := tt.options
+			options.VulnerabilityTypes = []VulnerabilityType{Injection}
+			preview, err := PreviewScan(context.Background(), root, &options)
+			if err != nil {
+				t.Fatalf("PreviewScan returned error: %v", err)
+			}
+
+			var mu sync.Mutex
+			var scanned []string
+			scanOptions := tt.options
+			scanOptions.VulnerabilityTypes = []VulnerabilityType{Injection}
+			scanOptions.OnFileScanned = func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+				mu.Lock()
+				defer mu.Unlock()
+				scanned = append(scanned, filepath.ToSlash(relPath))
+				return nil
+			}
+			if _, err := NewScannerService(nil).ScanRepository(context.Background(), root, &scanOptions); err != nil {
+				t.Fatalf("ScanRepository returned error: %v", err)
+			}
+
+			previewed := append([]string(nil), preview.Files...)
+			sort.Strings(previewed)
+			sort.Strings(scanned)
+			if len(scanned) == 0 || strings.Join(previewed, ",") != strings.Join(scanned, ",") {
+				t.Errorf("preview lists %v, want the scanned files %v", previewed, scanned)
+			}
+			if preview.FileCount != len(preview.Files) {
+				t.Errorf("FileCount = %d, want %d", preview.FileCount, len(preview.Files))
+			}
+			languageTotal := 0
+			for _, count := range preview.Languages {
+				languageTotal += count
+			}
+			if languageTotal != preview.FileCount {
+				t.Errorf("languages %v count %d files, want %d", preview.Languages, languageTotal, preview.FileCount)
+			}
+		})
+	}
+}
+
+func TestPreviewScanEstimatesWithoutCallingTheModel(t *testing.T) {
+	root := writeFixtureTree(t, []string{"main.go", "handlers/login.go", "web/app.js", "secrets/keys.go"})
+	transport := &slowModelTransport{}
+	useModelTransport(t, transport)
+
+	preview, err := PreviewScan(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go", ".js"},
+		LLMDenylist:        []string{"secrets/**"},
+		Model:              "gpt-4o-mini",
+	})
+	if err != nil {
+		t.Fatalf("PreviewScan returned error: %v", err)
+	}
+	if transport.requests != 0 {
+		t.Errorf("preview sent %d model requests, want none", transport.requests)
+	}
+
+	if preview.Languages["Go"] != 3 || preview.Languages["JavaScript"] != 1 {
+		t.Errorf("languages = %v, want 3 Go and 1 JavaScript", preview.Languages)
+	}
+	if preview.LLMDeniedFiles != 1 {
+		t.Errorf("LLMDeniedFiles = %d, want 1", preview.LLMDeniedFiles)
+	}
+
+	// Denied files never reach the model, so only the other three count toward the estimate
+	wantTokens := 0
+	for _, relPath := range []string{"main.go", "handlers/login.go", "web/app.js"} {
+		wantTokens += estimateScanTokens("package fixture\n", DetectLanguage(relPath, "package fixture\n"), relPath, []string{string(Injection)})
+	}
+	if preview.EstimatedTokens != wantTokens {
+		t.Errorf("EstimatedTokens = %d, want %d", preview.EstimatedTokens, wantTokens)
+	}
+	if preview.Model != "gpt-4o-mini" || preview.EstimatedCostUSD != EstimateCostUSD("gpt-4o-mini", wantTokens) {
+		t.Errorf("estimate = $%v for %s, want $%v for gpt-4o-mini", preview.EstimatedCostUSD, preview.Model, EstimateCostUSD("gpt-4o-mini", wantTokens))
+	}
+}
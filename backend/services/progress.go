@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// ScanProgressService persists per-file scan progress in the scan_files table
+// A scan that is interrupted and retried uses it to skip files that already finished
+type ScanProgressService interface {
+	// CompletedFiles returns the relative paths of files already recorded for a scan
+	CompletedFiles(ctx context.Context, scanID string) (map[string]bool, error)
+
+	// RecordFile stores the findings for one file and marks the file complete in a single transaction
+	// Recording the same file again replaces its findings, and it is safe to call from concurrent workers
+	RecordFile(ctx context.Context, scanID, filePath string, vulnerabilities []*Vulnerability) error
+
+	// RecordFileFailure dead-letters a file whose findings RecordFile could not store, or whose model request
+	// failed or reply could not be parsed: the error goes to scan_errors and the file is marked complete in the same
+	// transaction, so the scan moves on without it. Findings already recorded for the file are kept
+	RecordFileFailure(ctx context.Context, scanID, filePath string, cause error) error
+
+	// InsertFailures returns how many files of a scan were dead-lettered by RecordFileFailure
+	InsertFailures(ctx context.Context, scanID string) (int, error)
+
+	// SaveProgress stores how far a running scan has got in the progress column of its scans row
+	SaveProgress(ctx context.Context, scanID string, progress ScanProgress) error
+
+	// ScanVulnerabilities returns the findings stored for a scan, including those from earlier attempts;
+	// findings the repository suppressed are left out
+	ScanVulnerabilities(ctx context.Context, scanID string) ([]*Vulnerability, error)
+
+	// CarryForwardFindings copies the base scan's findings for files outside changedPaths into the scan
+	// It is used by incremental scans, which only analyze changed files. Calling it again replaces the
+	// copies made by an earlier call, so a retried activity doesn't duplicate them. It returns the number copied
+	CarryForwardFindings(ctx context.Context, scanID, baseScanID string, changedPaths []string) (int, error)
+}
+
+// ScanProgress is how far a running scan has got, as reported by its heartbeats and the status endpoint
+type ScanProgress struct {
+	Scanned     int    `json:"scanned"`      // Files finished, including those of earlier batches and attempts
+	Total       int    `json:"total"`        // Files the whole scan covers
+	CurrentFile string `json:"current_file"` // Relative path of the file most recently started
+}
+
+// NewScanProgressService creates a new scan progress service instance
+func NewScanProgressService(dbQueries *db.Queries) ScanProgressService {
+	return &scanProgressService{
+		db: dbQueries,
+	}
+}
+
+// scanProgressService implements the ScanProgressService interface
+type scanProgressService struct {
+	db *db.Queries
+}
+
+func (s *scanProgressService) CompletedFiles(ctx context.Context, scanID string) (map[string]bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT file_path FROM scan_files WHERE scan_id = $1`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed files for scan %s: %w", scanID, err)
+	}
+	defer rows.Close()
+
+	completed := make(map[string]bool)
+	for rows.Next() {
+		var filePath string
+		if err := rows.Scan(&filePath); err != nil {
+			return nil, fmt.Errorf("failed to scan completed file row: %w", err)
+		}
+		completed[filePath] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over completed files: %w", err)
+	}
+
+	return completed, nil
+}
+
+func (s *scanProgressService) RecordFile(ctx context.Context, scanID, filePath string, vulnerabilities []*Vulnerability) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	// Findings and the completion marker commit together, so a file is either fully recorded or not at all
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for %s: %w", filePath, err)
+	}
+	defer tx.Rollback()
+
+	// Clear findings left by an earlier attempt at this file so a retry doesn't duplicate them
+	// Each (scan, file) pair belongs to one worker, so concurrent workers never touch the same rows
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM vulnerabilities WHERE scan_id = $1 AND file_path = $2`,
+		scanID, filePath); err != nil {
+		return fmt.Errorf("failed to clear previous findings for %s: %w", filePath, err)
+	}
+
+	taxonomy, err := scanTaxonomy(ctx, tx, scanID)
+	if err != nil {
+		return err
+	}
+	if err := insertVulnerabilities(ctx, tx, scanID, taxonomy, vulnerabilities, vulnerabilityInsertBatchSize()); err != nil {
+		return fmt.Errorf("failed to insert findings for %s: %w", filePath, err)
+	}
+
+	// Upsert the completion marker; a concurrent or repeated write for the same file just overwrites it
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO scan_files (scan_id, file_path, vulnerability_count, completed_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (scan_id, file_path) DO UPDATE
+		SET vulnerability_count = EXCLUDED.vulnerability_count, completed_at = EXCLUDED.completed_at`,
+		scanID, filePath, len(vulnerabilities)); err != nil {
+		return fmt.Errorf("failed to record progress for %s: %w", filePath, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit progress for %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+func (s *scanProgressService) RecordFileFailure(ctx context.Context, scanID, filePath string, cause error) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for %s: %w", filePath, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO scan_errors (scan_id, file_path, error) VALUES ($1, $2, $3)`,
+		scanID, filePath, cause.Error()); err != nil {
+		return fmt.Errorf("failed to record error for %s: %w", filePath, err)
+	}
+
+	// Mark the file done so resumed attempts and later batches don't keep retrying it
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO scan_files (scan_id, file_path, vulnerability_count, completed_at)
+		VALUES ($1, $2, 0, NOW())
+		ON CONFLICT (scan_id, file_path) DO UPDATE
+		SET completed_at = EXCLUDED.completed_at`,
+		scanID, filePath); err != nil {
+		return fmt.Errorf("failed to record progress for %s: %w", filePath, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit error for %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+func (s *scanProgressService) InsertFailures(ctx context.Context, scanID string) (int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	var count int
+	if err := sqlDB.QueryRowContext(ctx,
+		`SELECT COUNT(DISTINCT file_path) FROM scan_errors WHERE scan_id = $1`, scanID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count insert failures for scan %s: %w", scanID, err)
+	}
+	return count, nil
+}
+
+func (s *scanProgressService) SaveProgress(ctx context.Context, scanID string, progress ScanProgress) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	encoded, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to encode progress for scan %s: %w", scanID, err)
+	}
+	if _, err := sqlDB.ExecContext(ctx,
+		`UPDATE scans SET progress = $2, updated_at = NOW() WHERE id = $1`, scanID, encoded); err != nil {
+		return fmt.Errorf("failed to save progress for scan %s: %w", scanID, err)
+	}
+	return nil
+}
+
+// vulnerabilityInsertColumns is the number of bind parameters each inserted finding uses
+const vulnerabilityInsertColumns = 17
+
+// maxVulnerabilityInsertBatch keeps one statement under PostgreSQL's 65535 bind parameter limit
+const maxVulnerabilityInsertBatch = 65535 / vulnerabilityInsertColumns
+
+// vulnerabilityInsertBatchSize reads how many findings go into one INSERT from VULNERABILITY_INSERT_BATCH_SIZE
+func vulnerabilityInsertBatchSize() int {
+	if value, err := strconv.Atoi(os.Getenv("VULNERABILITY_INSERT_BATCH_SIZE")); err == nil && value > 0 {
+		return min(value, maxVulnerabilityInsertBatch)
+	}
+	return 500
+}
+
+// insertVulnerabilities stores findings with multi-row INSERTs of up to batchSize rows each, categorized
+// under the scan's taxonomy. All batches run in the caller's transaction, so a failure part-way leaves none behind
+func insertVulnerabilities(ctx context.Context, tx *sql.Tx, scanID string, taxonomy *Taxonomy, vulnerabilities []*Vulnerability, batchSize int) error {
+	for start := 0; start < len(vulnerabilities); start += batchSize {
+		batch := vulnerabilities[start:min(start+batchSize, len(vulnerabilities))]
+
+		var query strings.Builder
+		query.WriteString(`INSERT INTO vulnerabilities (
+				id, scan_id, vulnerability_type, file_path,
+				line_start, line_end, severity, description,
+				remediation, code_snippet, fingerprint, owasp_category,
+				severity_rank, excluded, confidence, cwe, reference_urls, created_at, updated_at
+			) VALUES `)
+
+		args := make([]any, 0, len(batch)*vulnerabilityInsertColumns)
+		for i, vuln := range batch {
+			if vuln.ID == "" {
+				vuln.ID = uuid.New().String()
+			}
+
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(")
+			for column := 1; column <= vulnerabilityInsertColumns; column++ {
+				fmt.Fprintf(&query, "$%d, ", len(args)+column)
+			}
+			query.WriteString("NOW(), NOW())")
+
+			// The category is the scan taxonomy's, so it picks the reference a finding gets by default
+			owaspCategory := taxonomy.OWASPCategory(vuln.Type)
+			applyReferenceDefaults(vuln, owaspCategory)
+
+			args = append(args,
+				vuln.ID, scanID, string(vuln.Type), vuln.FilePath,
+				vuln.LineStart, vuln.LineEnd, vuln.Severity, vuln.Description,
+				vuln.Remediation, vuln.Code, vuln.Fingerprint(), owaspCategory,
+				SeverityRank(vuln.Severity), vuln.Excluded, vuln.Confidence,
+				sql.NullString{String: vuln.CWE, Valid: vuln.CWE != ""}, pq.Array(vuln.References))
+		}
+
+		if _, err := tx.ExecContext(ctx, query.String(), args...); err != nil {
+			return fmt.Errorf("failed to insert batch of %d findings: %w", len(batch), err)
+		}
+	}
+
+	return nil
+}
+
+func (s *scanProgressService) ScanVulnerabilities(ctx context.Context, scanID string) ([]*Vulnerability, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT v.id, v.vulnerability_type, v.file_path, v.line_start, v.line_end, v.severity, v.description,
+			v.remediation, v.code_snippet, v.excluded, `+baselinedColumnSQL+`, v.confidence, v.cwe, v.reference_urls
+		FROM vulnerabilities v WHERE v.scan_id = $1 AND `+notSuppressedSQL+`
+		ORDER BY v.severity_rank DESC, v.file_path, v.line_start`,
+		scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vulnerabilities for scan %s: %w", scanID, err)
+	}
+	defer rows.Close()
+
+	var vulnerabilities []*Vulnerability
+	for rows.Next() {
+		vuln := &Vulnerability{}
+		var vulnerabilityType string
+		var remediation, codeSnippet, cwe sql.NullString
+		var confidence sql.NullFloat64
+		var references pq.StringArray
+
+		if err := rows.Scan(&vuln.ID, &vulnerabilityType, &vuln.FilePath, &vuln.LineStart, &vuln.LineEnd,
+			&vuln.Severity, &vuln.Description, &remediation, &codeSnippet, &vuln.Excluded, &vuln.Baselined,
+			&confidence, &cwe, &references); err != nil {
+			return nil, fmt.Errorf("failed to scan vulnerability row: %w", err)
+		}
+
+		vuln.Type = VulnerabilityType(vulnerabilityType)
+		vuln.Remediation = remediation.String
+		vuln.Code = codeSnippet.String
+		if confidence.Valid {
+			vuln.Confidence = &confidence.Float64
+		}
+		vuln.CWE = cwe.String
+		vuln.References = references
+		vulnerabilities = append(vulnerabilities, vuln)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over vulnerability rows: %w", err)
+	}
+
+	return vulnerabilities, nil
+}
+
+func (s *scanProgressService) CarryForwardFindings(ctx context.Context, scanID, baseScanID string, changedPaths []string) (int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction for scan %s: %w", scanID, err)
+	}
+	defer tx.Rollback()
+
+	// The scan's own findings are all in changed files, so anything else was copied by an earlier call
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM vulnerabilities WHERE scan_id = $1 AND NOT (file_path = ANY($2))`,
+		scanID, pq.Array(changedPaths)); err != nil {
+		return 0, fmt.Errorf("failed to clear carried-forward findings of scan %s: %w", scanID, err)
+	}
+
+	// Copies get their own IDs; derived columns come along unchanged
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO vulnerabilities (
+			scan_id, vulnerability_type, file_path, line_start, line_end, severity, description,
+			remediation, code_snippet, fingerprint, owasp_category, severity_rank, excluded,
+			confidence, cwe, reference_urls, created_at, updated_at
+		)
+		SELECT $1, vulnerability_type, file_path, line_start, line_end, severity, description,
+			remediation, code_snippet, fingerprint, owasp_category, severity_rank, excluded,
+			confidence, cwe, reference_urls, NOW(), NOW()
+		FROM vulnerabilities
+		WHERE scan_id = $2 AND NOT (file_path = ANY($3))`,
+		scanID, baseScanID, pq.Array(changedPaths))
+	if err != nil {
+		return 0, fmt.Errorf("failed to carry findings forward from scan %s: %w", baseScanID, err)
+	}
+	copied, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit carried-forward findings of scan %s: %w", scanID, err)
+	}
+
+	return int(copied), nil
+}
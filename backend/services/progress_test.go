@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRecordFileIsSafeToRepeatAndRunConcurrently(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	_, scanID := createTestScan(t, queries)
+	progress := NewScanProgressService(queries)
+
+	finding := func(path string, line int) *Vulnerability {
+		return &Vulnerability{Type: Injection, FilePath: path, LineStart: line, LineEnd: line, Severity: "High", Description: "finding"}
+	}
+
+	// Workers record different files at the same time
+	const files = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, files)
+	for i := 0; i < files; i++ {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			errs <- progress.RecordFile(ctx, scanID, path, []*Vulnerability{finding(path, 1), finding(path, 2)})
+		}(fmt.Sprintf("file%d.go", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("RecordFile returned error: %v", err)
+		}
+	}
+
+	// A retried attempt records one file again with different findings
+	if err := progress.RecordFile(ctx, scanID, "file0.go", []*Vulnerability{finding("file0.go", 5)}); err != nil {
+		t.Fatalf("RecordFile again returned error: %v", err)
+	}
+
+	completed, err := progress.CompletedFiles(ctx, scanID)
+	if err != nil {
+		t.Fatalf("CompletedFiles returned error: %v", err)
+	}
+	if len(completed) != files {
+		t.Errorf("%d files completed, want %d", len(completed), files)
+	}
+
+	vulnerabilities, err := progress.ScanVulnerabilities(ctx, scanID)
+	if err != nil {
+		t.Fatalf("ScanVulnerabilities returned error: %v", err)
+	}
+	// Two findings for each file, except the re-recorded one whose findings were replaced
+	if want := 2*(files-1) + 1; len(vulnerabilities) != want {
+		t.Errorf("scan has %d findings, want %d", len(vulnerabilities), want)
+	}
+	for _, vuln := range vulnerabilities {
+		if vuln.FilePath == "file0.go" && vuln.LineStart != 5 {
+			t.Errorf("file0.go kept a finding from the earlier attempt at line %d", vuln.LineStart)
+		}
+	}
+}
+
+func TestVulnerabilityInsertBatchSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{value: "", want: 500},
+		{value: "100", want: 100},
+		{value: "0", want: 500},
+		{value: "lots", want: 500},
+		{value: "1000000", want: maxVulnerabilityInsertBatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("VULNERABILITY_INSERT_BATCH_SIZE", tt.value)
+			if got := vulnerabilityInsertBatchSize(); got != tt.want {
+				t.Errorf("vulnerabilityInsertBatchSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// manyFindings returns n distinct findings in one file
+func manyFindings(path string, n int) []*Vulnerability {
+	vulns := make([]*Vulnerability, n)
+	for i := range vulns {
+		vulns[i] = &Vulnerability{Type: Injection, FilePath: path, LineStart: i + 1, LineEnd: i + 1, Severity: "High", Description: "finding"}
+	}
+	return vulns
+}
+
+func TestRecordFileStoresLargeFindingSetsInBatches(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	_, scanID := createTestScan(t, queries)
+
+	// A batch size that doesn't divide the finding count leaves a short final batch
+	t.Setenv("VULNERABILITY_INSERT_BATCH_SIZE", "7")
+	const findings = 2503
+	if err := NewScanProgressService(queries).RecordFile(ctx, scanID, "big.go", manyFindings("big.go", findings)); err != nil {
+		t.Fatalf("RecordFile returned error: %v", err)
+	}
+
+	var stored, distinctLines int
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(DISTINCT line_start) FROM vulnerabilities WHERE scan_id = $1`, scanID).Scan(&stored, &distinctLines); err != nil {
+		t.Fatalf("count findings: %v", err)
+	}
+	if stored != findings || distinctLines != findings {
+		t.Errorf("stored %d findings on %d lines, want %d", stored, distinctLines, findings)
+	}
+}
+
+func BenchmarkRecordFile(b *testing.B) {
+	queries := newTestQueries(b)
+	ctx := context.Background()
+
+	for _, batchSize := range []string{"1", "500"} {
+		b.Run("batch="+batchSize, func(b *testing.B) {
+			b.Setenv("VULNERABILITY_INSERT_BATCH_SIZE", batchSize)
+			// Each run records into its own scan so earlier runs don't skew the timing
+			_, scanID := createTestScan(b, queries)
+
+			progress := NewScanProgressService(queries)
+			vulns := manyFindings("big.go", 2000)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := progress.RecordFile(ctx, scanID, "big.go", vulns); err != nil {
+					b.Fatalf("RecordFile returned error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCarryForwardFindings(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, baseScanID := createTestScan(t, queries)
+	progress := NewScanProgressService(queries)
+
+	for _, path := range []string{"main.go", "db.go", "api.go"} {
+		if err := progress.RecordFile(ctx, baseScanID, path, manyFindings(path, 2)); err != nil {
+			t.Fatalf("record base findings: %v", err)
+		}
+	}
+
+	var scanID string
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status) VALUES ($1, 'scanning') RETURNING id`, repoID).Scan(&scanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+	// The incremental scan rescanned the one changed file and found one issue
+	if err := progress.RecordFile(ctx, scanID, "db.go", manyFindings("db.go", 1)); err != nil {
+		t.Fatalf("record changed file: %v", err)
+	}
+
+	// A retried activity carries forward again without duplicating
+	for attempt := 1; attempt <= 2; attempt++ {
+		copied, err := progress.CarryForwardFindings(ctx, scanID, baseScanID, []string{"db.go"})
+		if err != nil {
+			t.Fatalf("attempt %d: CarryForwardFindings returned error: %v", attempt, err)
+		}
+		if copied != 4 {
+			t.Errorf("attempt %d: copied %d findings, want the 4 in unchanged files", attempt, copied)
+		}
+	}
+
+	vulnerabilities, err := progress.ScanVulnerabilities(ctx, scanID)
+	if err != nil {
+		t.Fatalf("ScanVulnerabilities returned error: %v", err)
+	}
+	perFile := make(map[string]int)
+	for _, vuln := range vulnerabilities {
+		perFile[vuln.FilePath]++
+	}
+	if perFile["main.go"] != 2 || perFile["api.go"] != 2 || perFile["db.go"] != 1 {
+		t.Errorf("findings per file = %v, want 2 carried forward per unchanged file and the 1 new one in db.go", perFile)
+	}
+}
+
+func TestRecordFileFailureDeadLettersTheFile(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	_, scanID := createTestScan(t, queries)
+	progress := NewScanProgressService(queries)
+
+	// PostgreSQL rejects NUL bytes in text, so this file's findings won't insert
+	bad := &Vulnerability{Type: Injection, FilePath: "bad.go", LineStart: 1, LineEnd: 1, Severity: "High",
+		Description: "finding", Code: "query(\x00)"}
+	insertErr := progress.RecordFile(ctx, scanID, "bad.go", []*Vulnerability{bad})
+	if insertErr == nil {
+		t.Fatal("RecordFile stored a finding with a NUL byte")
+	}
+
+	if err := progress.RecordFileFailure(ctx, scanID, "bad.go", insertErr); err != nil {
+		t.Fatalf("RecordFileFailure returned error: %v", err)
+	}
+	if err := progress.RecordFile(ctx, scanID, "good.go", nil); err != nil {
+		t.Fatalf("RecordFile returned error: %v", err)
+	}
+
+	// The failed file counts as done, so a resumed scan doesn't retry it
+	completed, err := progress.CompletedFiles(ctx, scanID)
+	if err != nil {
+		t.Fatalf("CompletedFiles returned error: %v", err)
+	}
+	if !completed["bad.go"] || !completed["good.go"] {
+		t.Errorf("completed files = %v, want bad.go and good.go", completed)
+	}
+
+	var filePath, message string
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`SELECT file_path, error FROM scan_errors WHERE scan_id = $1`, scanID).Scan(&filePath, &message); err != nil {
+		t.Fatalf("read scan error: %v", err)
+	}
+	if filePath != "bad.go" || message != insertErr.Error() {
+		t.Errorf("scan error = %s: %q, want bad.go: %q", filePath, message, insertErr.Error())
+	}
+
+	failures, err := progress.InsertFailures(ctx, scanID)
+	if err != nil {
+		t.Fatalf("InsertFailures returned error: %v", err)
+	}
+	if failures != 1 {
+		t.Errorf("InsertFailures = %d, want 1", failures)
+	}
+}
+
+func TestSaveProgress(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	_, scanID := createTestScan(t, queries)
+	progress := NewScanProgressService(queries)
+
+	for _, saved := range []ScanProgress{
+		{Scanned: 0, Total: 40},
+		{Scanned: 12, Total: 40, CurrentFile: "handlers/auth.go"},
+	} {
+		if err := progress.SaveProgress(ctx, scanID, saved); err != nil {
+			t.Fatalf("SaveProgress returned error: %v", err)
+		}
+	}
+
+	var stored []byte
+	if err := queries.GetDB().QueryRowContext(ctx, `SELECT progress FROM scans WHERE id = $1`, scanID).Scan(&stored); err != nil {
+		t.Fatalf("read progress: %v", err)
+	}
+	var got ScanProgress
+	if err := json.Unmarshal(stored, &got); err != nil {
+		t.Fatalf("decode progress %s: %v", stored, err)
+	}
+	if want := (ScanProgress{Scanned: 12, Total: 40, CurrentFile: "handlers/auth.go"}); got != want {
+		t.Errorf("stored progress = %+v, want %+v", got, want)
+	}
+}
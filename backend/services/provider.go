@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Names of the supported repository hosts, as stored in repositories.provider
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+
+	// ProviderUpload marks a repository created for an uploaded archive; it has nothing to clone
+	ProviderUpload = "upload"
+)
+
+// RepoRef identifies a repository on its host, as parsed from a URL
+type RepoRef struct {
+	Provider string // ProviderGitHub or ProviderGitLab
+	BaseURL  string // Scheme and host of the web UI, e.g. https://gitlab.example.com
+	Owner    string // User or organization; for GitLab the full group path, e.g. "group/subgroup"
+	Name     string // Repository (project) name without .git
+}
+
+// RepoProvider is a git hosting service that repositories can be scanned from
+type RepoProvider interface {
+	// Name returns the provider name stored with the repository
+	Name() string
+
+	// ParseURL extracts the repository a web, HTTPS clone, or SSH URL points to
+	ParseURL(repoURL string) (*RepoRef, error)
+
+	// FetchRepositoryInfo retrieves repository metadata from the provider's API
+	FetchRepositoryInfo(ctx context.Context, ref *RepoRef) (*Repository, error)
+
+	// AuthenticatedCloneURL returns a clone URL carrying the provider's access token,
+	// for retrying a clone that failed with an authentication error
+	AuthenticatedCloneURL(cloneURL string) (string, error)
+}
+
+// RepoProviders picks the provider for a repository URL
+type RepoProviders struct {
+	github RepoProvider
+	gitlab RepoProvider
+}
+
+// NewRepoProviders creates the set of supported providers
+// GitHub metadata is fetched through the given service so its behavior stays in one place
+func NewRepoProviders(gitHubService GitHubService) *RepoProviders {
+	return &RepoProviders{
+		github: &gitHubProvider{service: gitHubService},
+		gitlab: NewGitLabProvider(""),
+	}
+}
+
+// ForURL returns the provider hosting a repository URL, detected from its host
+// Only configured hosts are accepted, so a URL can't send API requests or clones anywhere else:
+// github.com is GitHub, and gitlab.com and the hosts listed in GITLAB_HOSTS (comma-separated,
+// for self-hosted instances) are GitLab
+func (p *RepoProviders) ForURL(repoURL string) (RepoProvider, error) {
+	host := repoURLHost(repoURL)
+	switch providerForHost(host) {
+	case ProviderGitHub:
+		return p.github, nil
+	case ProviderGitLab:
+		return p.gitlab, nil
+	}
+	if host == "" {
+		return nil, invalidRepoURL("%q has no host", repoURL)
+	}
+	return nil, invalidRepoURL("unsupported repository host %q (expected GitHub, gitlab.com, or a host in GITLAB_HOSTS)", host)
+}
+
+// providerForHost returns the provider serving a lower-cased host name, or "" for hosts that aren't configured
+func providerForHost(host string) string {
+	switch {
+	case host == "":
+		return ""
+	case host == "github.com" || host == "www.github.com":
+		return ProviderGitHub
+	case host == "gitlab.com" || isConfiguredGitLabHost(host):
+		return ProviderGitLab
+	default:
+		return ""
+	}
+}
+
+// ForName returns a provider by its stored name; an empty name is GitHub, the original provider
+func (p *RepoProviders) ForName(name string) RepoProvider {
+	if name == ProviderGitLab {
+		return p.gitlab
+	}
+	return p.github
+}
+
+// repoURLHost returns the lower-cased host of an HTTP(S), scp-style SSH, or scheme-less repository URL
+func repoURLHost(repoURL string) string {
+	rest := strings.TrimSpace(repoURL)
+	if i := strings.Index(rest, "://"); i >= 0 {
+		rest = rest[i+3:]
+	} else if at := strings.Index(rest, "@"); at >= 0 && strings.Contains(rest[at:], ":") {
+		// git@host:owner/repo.git
+		rest = rest[at+1:]
+		rest = rest[:strings.Index(rest, ":")]
+	}
+
+	// Drop credentials, path, and port
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		rest = rest[at+1:]
+	}
+	if i := strings.IndexAny(rest, "/:"); i >= 0 {
+		rest = rest[:i]
+	}
+	return strings.ToLower(rest)
+}
+
+// isConfiguredGitLabHost reports whether GITLAB_HOSTS lists the host
+func isConfiguredGitLabHost(host string) bool {
+	for _, configured := range strings.Split(os.Getenv("GITLAB_HOSTS"), ",") {
+		if strings.EqualFold(strings.TrimSpace(configured), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitHubProvider adapts the GitHub service to the RepoProvider interface
+type gitHubProvider struct {
+	service GitHubService
+}
+
+func (p *gitHubProvider) Name() string {
+	return ProviderGitHub
+}
+
+// ParseURL accepts https://github.com/owner/repo[.git], git@github.com:owner/repo.git,
+// ssh://git@github.com/owner/repo.git, and github.com/owner/repo; anything after the repository
+// name, such as /tree/main, is refused rather than guessed at
+func (p *gitHubProvider) ParseURL(repoURL string) (*RepoRef, error) {
+	parts, err := splitRepoURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if providerForHost(parts.host) != ProviderGitHub {
+		return nil, invalidRepoURL("%q is not a GitHub host", parts.host)
+	}
+	switch {
+	case len(parts.path) < 2:
+		return nil, invalidRepoURL("missing the repository name (expected https://github.com/owner/repo)")
+	case len(parts.path) > 2:
+		return nil, invalidRepoURL("unexpected path after the repository name (expected https://github.com/owner/repo)")
+	}
+
+	owner, name := parts.path[0], strings.TrimSuffix(parts.path[1], ".git")
+	if err := validateGitHubName(owner, name); err != nil {
+		return nil, err
+	}
+	return &RepoRef{
+		Provider: ProviderGitHub,
+		BaseURL:  "https://github.com",
+		Owner:    owner,
+		Name:     name,
+	}, nil
+}
+
+func (p *gitHubProvider) FetchRepositoryInfo(ctx context.Context, ref *RepoRef) (*Repository, error) {
+	repo, err := p.service.FetchRepositoryInfo(ctx, ref.Owner, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	repo.Provider = ProviderGitHub
+	return repo, nil
+}
+
+func (p *gitHubProvider) AuthenticatedCloneURL(cloneURL string) (string, error) {
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	if githubToken == "" {
+		return "", fmt.Errorf("repository requires authentication but GITHUB_TOKEN environment variable is not set")
+	}
+
+	// For GitHub URLs, construct an authenticated URL with the token
+	if !strings.HasPrefix(cloneURL, "https://github.com") {
+		return "", fmt.Errorf("repository requires authentication but URL format is not supported for authentication")
+	}
+	return strings.Replace(cloneURL, "https://github.com", fmt.Sprintf("https://%s@github.com", githubToken), 1), nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+)
+
+// owaspCategoryReferenceURL returns the owasp.org page of an OWASP Top 10 2021 category such as "A03:2021",
+// or "" for "Other" and unknown categories
+func owaspCategoryReferenceURL(owaspCategory string) string {
+	for vulnType, reference := range owaspReferences {
+		if OWASPCategory(vulnType) == owaspCategory {
+			return reference.url
+		}
+	}
+	return ""
+}
+
+// CWEReferenceURL returns the MITRE definition page of a canonical CWE identifier such as "CWE-89"
+func CWEReferenceURL(cwe string) string {
+	id, ok := strings.CutPrefix(cwe, "CWE-")
+	if !ok || id == "" {
+		return ""
+	}
+	return "https://cwe.mitre.org/data/definitions/" + id + ".html"
+}
+
+// DefaultReferences returns the links a finding cites when the model gave none: the page of its OWASP
+// category and the definition of its CWE, whichever are known. It is never nil, since findings always store a list
+func DefaultReferences(owaspCategory, cwe string) []string {
+	references := []string{}
+	if reference := owaspCategoryReferenceURL(owaspCategory); reference != "" {
+		references = append(references, reference)
+	}
+	if reference := CWEReferenceURL(cwe); reference != "" {
+		references = append(references, reference)
+	}
+	return references
+}
+
+// applyReferenceDefaults fills in what the model left out of a finding's references: the CWE named by its
+// type (as in the CWE Top 25 taxonomy), and the default links for its OWASP category and CWE
+func applyReferenceDefaults(vuln *Vulnerability, owaspCategory string) {
+	if vuln.CWE == "" {
+		if cwe, err := baml.ParseCWE(string(vuln.Type)); err == nil {
+			vuln.CWE = cwe
+		}
+	}
+	if len(vuln.References) == 0 {
+		vuln.References = DefaultReferences(owaspCategory, vuln.CWE)
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDefaultReferences(t *testing.T) {
+	tests := []struct {
+		name          string
+		owaspCategory string
+		cwe           string
+		want          []string
+	}{
+		{name: "category", owaspCategory: "A03:2021", want: []string{"https://owasp.org/Top10/A03_2021-Injection/"}},
+		{name: "category and CWE", owaspCategory: "A10:2021", cwe: "CWE-918", want: []string{
+			"https://owasp.org/Top10/A10_2021-Server-Side_Request_Forgery_%28SSRF%29/",
+			"https://cwe.mitre.org/data/definitions/918.html",
+		}},
+		{name: "CWE outside OWASP", owaspCategory: "Other", cwe: "CWE-787", want: []string{"https://cwe.mitre.org/data/definitions/787.html"}},
+		{name: "nothing known", owaspCategory: "Other", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultReferences(tt.owaspCategory, tt.cwe)
+			if got == nil || !slices.Equal(got, tt.want) {
+				t.Errorf("DefaultReferences(%q, %q) = %#v, want %#v", tt.owaspCategory, tt.cwe, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyReferenceDefaults(t *testing.T) {
+	// Every OWASP category has a page to fall back to
+	for _, vulnType := range AllVulnerabilityTypes {
+		vuln := &Vulnerability{Type: vulnType}
+		applyReferenceDefaults(vuln, OWASPCategory(vulnType))
+		if len(vuln.References) != 1 || vuln.References[0] != OWASPReferenceURL(vulnType) {
+			t.Errorf("%s references = %v, want the OWASP page %s", vulnType, vuln.References, OWASPReferenceURL(vulnType))
+		}
+	}
+
+	// The model's own references and CWE are kept
+	vuln := &Vulnerability{Type: Injection, CWE: "CWE-89", References: []string{"https://example.com/sqli"}}
+	applyReferenceDefaults(vuln, "A03:2021")
+	if vuln.CWE != "CWE-89" || !slices.Equal(vuln.References, []string{"https://example.com/sqli"}) {
+		t.Errorf("model's references were replaced: %s %v", vuln.CWE, vuln.References)
+	}
+
+	// A CWE Top 25 type names its CWE, which links to its definition alongside the OWASP page
+	vuln = &Vulnerability{Type: "CWE-89: SQL Injection"}
+	applyReferenceDefaults(vuln, CWETop25Taxonomy.OWASPCategory(vuln.Type))
+	want := []string{"https://owasp.org/Top10/A03_2021-Injection/", "https://cwe.mitre.org/data/definitions/89.html"}
+	if vuln.CWE != "CWE-89" || !slices.Equal(vuln.References, want) {
+		t.Errorf("CWE Top 25 finding = %s %v, want CWE-89 %v", vuln.CWE, vuln.References, want)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// defaultRefreshTokenLifetime applies when REFRESH_TOKEN_TTL is unset
+const defaultRefreshTokenLifetime = 30 * 24 * time.Hour
+
+// ErrInvalidRefreshToken is returned for refresh tokens that are unknown, expired, already used, or revoked
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// RefreshToken is an opaque token that can be exchanged once for a new access token
+type RefreshToken struct {
+	Token     string    `json:"refresh_token"`
+	ExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// RefreshTokenService issues, rotates, and revokes refresh tokens
+// Tokens are random and only their hashes are stored, so a database leak doesn't expose usable tokens
+type RefreshTokenService interface {
+	// Issue creates a new refresh token for the user
+	Issue(ctx context.Context, userID string) (*RefreshToken, error)
+
+	// Rotate exchanges a refresh token for a new one, invalidating the old token
+	// It returns the token's user and ErrInvalidRefreshToken if the token can't be used
+	Rotate(ctx context.Context, token string) (user *User, next *RefreshToken, err error)
+
+	// Revoke deletes a refresh token; revoking an unknown token is not an error
+	Revoke(ctx context.Context, token string) error
+}
+
+// NewRefreshTokenService creates a new refresh token service instance
+func NewRefreshTokenService(dbQueries *db.Queries) RefreshTokenService {
+	return &refreshTokenService{
+		db: dbQueries,
+	}
+}
+
+// refreshTokenService implements the RefreshTokenService interface
+type refreshTokenService struct {
+	db *db.Queries
+}
+
+// refreshTokenLifetime reads REFRESH_TOKEN_TTL (a Go duration such as "720h")
+func refreshTokenLifetime() time.Duration {
+	value := os.Getenv("REFRESH_TOKEN_TTL")
+	if value == "" {
+		return defaultRefreshTokenLifetime
+	}
+	lifetime, err := time.ParseDuration(value)
+	if err != nil || lifetime <= 0 {
+		logger.Warn("Invalid REFRESH_TOKEN_TTL value, using default",
+			zap.String("value", value),
+			zap.Duration("default", defaultRefreshTokenLifetime))
+		return defaultRefreshTokenLifetime
+	}
+	return lifetime
+}
+
+// hashRefreshToken returns the stored form of a refresh token
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshToken generates a random refresh token and its expiry
+func newRefreshToken() (*RefreshToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return &RefreshToken{
+		Token:     base64.RawURLEncoding.EncodeToString(raw),
+		ExpiresAt: time.Now().Add(refreshTokenLifetime()).UTC(),
+	}, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// storeRefreshToken inserts the hash of a refresh token for the user
+func storeRefreshToken(ctx context.Context, conn execer, userID string, token *RefreshToken) error {
+	_, err := conn.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, hashRefreshToken(token.Token), token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *refreshTokenService) Issue(ctx context.Context, userID string) (*RefreshToken, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := storeRefreshToken(ctx, sqlDB, userID, token); err != nil {
+		return nil, err
+	}
+
+	// Expired tokens of this user are no longer useful
+	if _, err := sqlDB.ExecContext(ctx,
+		`DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at <= NOW()`, userID); err != nil {
+		logger.Warn("Failed to prune expired refresh tokens", zap.String("user_id", userID), zap.Error(err))
+	}
+
+	return token, nil
+}
+
+func (s *refreshTokenService) Rotate(ctx context.Context, token string) (*User, *RefreshToken, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, nil, fmt.Errorf("database connection not available")
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleting the old token in the same statement that checks it means two concurrent
+	// refreshes with the same token can't both succeed
+	user := &User{}
+	var name, avatarURL sql.NullString
+	err = tx.QueryRowContext(ctx,
+		`WITH used AS (
+			DELETE FROM refresh_tokens WHERE token_hash = $1 RETURNING user_id, expires_at
+		)
+		SELECT u.id, u.email, u.name, u.avatar_url, u.role FROM used
+		JOIN users u ON u.id = used.user_id
+		WHERE used.expires_at > NOW()`,
+		hashRefreshToken(token)).Scan(&user.ID, &user.Email, &name, &avatarURL, &user.Role)
+	if err == sql.ErrNoRows {
+		// Commit so an expired token that was found is still removed
+		tx.Commit()
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+	user.Name = name.String
+	user.AvatarURL = avatarURL.String
+
+	next, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := storeRefreshToken(ctx, tx, user.ID, next); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return user, next, nil
+}
+
+func (s *refreshTokenService) Revoke(ctx context.Context, token string) error {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	if _, err := sqlDB.ExecContext(ctx,
+		`DELETE FROM refresh_tokens WHERE token_hash = $1`, hashRefreshToken(token)); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// createTestUser inserts a user with a unique email and returns its ID
+func createTestUser(t *testing.T, queries *db.Queries) string {
+	t.Helper()
+	var userID string
+	if err := queries.GetDB().QueryRowContext(context.Background(),
+		`INSERT INTO users (email, name) VALUES ($1, 'Test User') RETURNING id`, uniqueEmail()).Scan(&userID); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	return userID
+}
+
+func TestRefreshTokenLifetime(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "unset", want: defaultRefreshTokenLifetime},
+		{name: "hours", value: "720h", want: 720 * time.Hour},
+		{name: "not a duration", value: "a month", want: defaultRefreshTokenLifetime},
+		{name: "negative", value: "-1h", want: defaultRefreshTokenLifetime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REFRESH_TOKEN_TTL", tt.value)
+			if got := refreshTokenLifetime(); got != tt.want {
+				t.Errorf("refreshTokenLifetime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	queries := newTestQueries(t)
+	service := NewRefreshTokenService(queries)
+	ctx := context.Background()
+	userID := createTestUser(t, queries)
+
+	// expire makes a stored token past its expiry
+	expire := func(t *testing.T, token string) {
+		t.Helper()
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`UPDATE refresh_tokens SET expires_at = NOW() - INTERVAL '1 minute' WHERE token_hash = $1`,
+			hashRefreshToken(token)); err != nil {
+			t.Fatalf("expire token: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		// use does what the case is about with a freshly issued token and returns the token to rotate
+		use     func(t *testing.T, token string) string
+		wantErr error
+	}{
+		{name: "fresh token rotates", use: func(t *testing.T, token string) string { return token }},
+		{
+			name: "rotated token can't be reused",
+			use: func(t *testing.T, token string) string {
+				if _, _, err := service.Rotate(ctx, token); err != nil {
+					t.Fatalf("first rotation returned error: %v", err)
+				}
+				return token
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "expired token",
+			use: func(t *testing.T, token string) string {
+				expire(t, token)
+				return token
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "revoked token",
+			use: func(t *testing.T, token string) string {
+				if err := service.Revoke(ctx, token); err != nil {
+					t.Fatalf("Revoke returned error: %v", err)
+				}
+				return token
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{name: "unknown token", use: func(t *testing.T, token string) string { return "not-" + token }, wantErr: ErrInvalidRefreshToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued, err := service.Issue(ctx, userID)
+			if err != nil {
+				t.Fatalf("Issue returned error: %v", err)
+			}
+
+			user, next, err := service.Rotate(ctx, tt.use(t, issued.Token))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Rotate error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rotate returned error: %v", err)
+			}
+			if user.ID != userID {
+				t.Errorf("rotated for user %s, want %s", user.ID, userID)
+			}
+			if next.Token == "" || next.Token == issued.Token {
+				t.Errorf("rotation returned token %q, want a new one", next.Token)
+			}
+
+			// The new token rotates in turn; the old one stays spent
+			if _, _, err := service.Rotate(ctx, next.Token); err != nil {
+				t.Errorf("rotating the new token returned error: %v", err)
+			}
+			if _, _, err := service.Rotate(ctx, issued.Token); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("reusing the old token: error = %v, want ErrInvalidRefreshToken", err)
+			}
+		})
+	}
+}
+
+func TestRevokeUnknownRefreshToken(t *testing.T) {
+	service := NewRefreshTokenService(newTestQueries(t))
+	if err := service.Revoke(context.Background(), "never-issued"); err != nil {
+		t.Errorf("Revoke of an unknown token returned error: %v", err)
+	}
+}
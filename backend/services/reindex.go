@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ReindexProgress reports what a reindex batch did and where the next batch should resume
+type ReindexProgress struct {
+	ScansProcessed         int    `json:"scans_processed"`         // Scans visited in this batch
+	ScansUpdated           int    `json:"scans_updated"`           // Scans whose summary changed
+	VulnerabilitiesUpdated int    `json:"vulnerabilities_updated"` // Findings whose derived fields changed
+	NextCursor             string `json:"next_cursor"`             // Pass back to resume after the last visited scan
+	Done                   bool   `json:"done"`                    // True once every scan has been visited
+}
+
+// ReindexService recomputes fields derived from stored findings
+// It is used to backfill fingerprints, OWASP categories, severity ranks, and scan summaries on legacy rows
+type ReindexService interface {
+	// ReindexBatch recomputes derived fields for up to batchSize scans after the cursor
+	// Running it again over the same rows changes nothing, so an interrupted reindex can simply resume
+	ReindexBatch(ctx context.Context, cursor string, batchSize int) (*ReindexProgress, error)
+
+	// RefreshScanSummary recomputes the vulnerability count and severity summary of one scan
+	// Findings marked excluded by path rules are left out of both
+	// It returns true if the stored summary changed
+	RefreshScanSummary(ctx context.Context, scanID string) (bool, error)
+}
+
+// NewReindexService creates a new reindex service instance
+func NewReindexService(dbQueries *db.Queries) ReindexService {
+	return &reindexService{
+		db: dbQueries,
+	}
+}
+
+// reindexService implements the ReindexService interface
+type reindexService struct {
+	db *db.Queries
+}
+
+func (s *reindexService) ReindexBatch(ctx context.Context, cursor string, batchSize int) (*ReindexProgress, error) {
+	log := logger.FromContext(ctx)
+
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+
+	// Walk scans in a stable order so the cursor can resume exactly where the last batch stopped
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id::text FROM scans WHERE id::text > $1 ORDER BY id::text LIMIT $2`,
+		cursor, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scans: %w", err)
+	}
+
+	var scanIDs []string
+	for rows.Next() {
+		var scanID string
+		if err := rows.Scan(&scanID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan scan row: %w", err)
+		}
+		scanIDs = append(scanIDs, scanID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over scan rows: %w", err)
+	}
+
+	progress := &ReindexProgress{
+		NextCursor: cursor,
+		Done:       len(scanIDs) < batchSize,
+	}
+
+	for _, scanID := range scanIDs {
+		updated, err := s.reindexScanVulnerabilities(ctx, sqlDB, scanID)
+		if err != nil {
+			return progress, err
+		}
+		progress.VulnerabilitiesUpdated += updated
+
+		changed, err := s.RefreshScanSummary(ctx, scanID)
+		if err != nil {
+			return progress, err
+		}
+		if changed {
+			progress.ScansUpdated++
+		}
+
+		progress.ScansProcessed++
+		progress.NextCursor = scanID
+	}
+
+	log.Info("Reindex batch completed",
+		zap.Int("scans_processed", progress.ScansProcessed),
+		zap.Int("scans_updated", progress.ScansUpdated),
+		zap.Int("vulnerabilities_updated", progress.VulnerabilitiesUpdated),
+		zap.String("next_cursor", progress.NextCursor),
+		zap.Bool("done", progress.Done))
+
+	return progress, nil
+}
+
+// reindexScanVulnerabilities recomputes the fingerprint, OWASP category (under the scan's taxonomy), and severity rank
+// of each finding in a scan
+// Only rows whose stored values differ are written, so correct rows are left untouched
+func (s *reindexService) reindexScanVulnerabilities(ctx context.Context, sqlDB *sql.DB, scanID string) (int, error) {
+	taxonomy, err := scanTaxonomy(ctx, sqlDB, scanID)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id, vulnerability_type, file_path, line_start, line_end, severity, code_snippet,
+			fingerprint, owasp_category, severity_rank
+		FROM vulnerabilities WHERE scan_id = $1`,
+		scanID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query vulnerabilities for scan %s: %w", scanID, err)
+	}
+
+	type derivedFields struct {
+		id           string
+		fingerprint  string
+		category     string
+		severityRank int
+	}
+
+	var pending []derivedFields
+	for rows.Next() {
+		vuln := &Vulnerability{}
+		var vulnerabilityType string
+		var codeSnippet, fingerprint, category sql.NullString
+		var severityRank int
+
+		if err := rows.Scan(&vuln.ID, &vulnerabilityType, &vuln.FilePath, &vuln.LineStart, &vuln.LineEnd,
+			&vuln.Severity, &codeSnippet, &fingerprint, &category, &severityRank); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan vulnerability row: %w", err)
+		}
+
+		vuln.Type = VulnerabilityType(vulnerabilityType)
+		vuln.Code = codeSnippet.String
+
+		want := derivedFields{
+			id:           vuln.ID,
+			fingerprint:  vuln.Fingerprint(),
+			category:     taxonomy.OWASPCategory(vuln.Type),
+			severityRank: SeverityRank(vuln.Severity),
+		}
+		if fingerprint.String != want.fingerprint || category.String != want.category || severityRank != want.severityRank {
+			pending = append(pending, want)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error while iterating over vulnerability rows: %w", err)
+	}
+
+	for _, fields := range pending {
+		_, err := sqlDB.ExecContext(ctx,
+			`UPDATE vulnerabilities SET fingerprint = $1, owasp_category = $2, severity_rank = $3, updated_at = NOW()
+			WHERE id = $4`,
+			fields.fingerprint, fields.category, fields.severityRank, fields.id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update vulnerability %s: %w", fields.id, err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+func (s *reindexService) RefreshScanSummary(ctx context.Context, scanID string) (bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return false, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT severity, COUNT(*) FROM vulnerabilities WHERE scan_id = $1 AND NOT excluded GROUP BY severity`,
+		scanID)
+	if err != nil {
+		return false, fmt.Errorf("failed to count vulnerabilities for scan %s: %w", scanID, err)
+	}
+	defer rows.Close()
+
+	total := 0
+	summary := map[string]int{}
+	for rows.Next() {
+		var severity string
+		var count int
+		if err := rows.Scan(&severity, &count); err != nil {
+			return false, fmt.Errorf("failed to scan severity count: %w", err)
+		}
+		summary[severity] = count
+		total += count
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("error while iterating over severity counts: %w", err)
+	}
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode severity summary: %w", err)
+	}
+
+	// Skip the write when nothing changed so repeated runs are no-ops
+	result, err := sqlDB.ExecContext(ctx,
+		`UPDATE scans SET vulnerability_count = $1, severity_summary = $2::jsonb
+		WHERE id = $3 AND (vulnerability_count IS DISTINCT FROM $1 OR severity_summary IS DISTINCT FROM $2::jsonb)`,
+		total, string(summaryJSON), scanID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update summary for scan %s: %w", scanID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, nil
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/testdb"
+)
+
+// newTestQueries returns queries on the test database, skipping the test when there is none
+func newTestQueries(t testing.TB) *db.Queries {
+	queries := db.NewQueries()
+	queries.SetDB(testdb.Open(t))
+	return queries
+}
+
+// createTestScan inserts a repository with a unique name and a completed scan of it, returning both IDs
+func createTestScan(t testing.TB, queries *db.Queries) (repoID, scanID string) {
+	t.Helper()
+	ctx := context.Background()
+	name := "repo-" + uuid.NewString()
+
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`INSERT INTO repositories (owner, name, url, clone_url) VALUES ('test-owner', $1, $2, $3) RETURNING id`,
+		name, "https://github.com/test-owner/"+name, "https://github.com/test-owner/"+name+".git").Scan(&repoID); err != nil {
+		t.Fatalf("insert repository: %v", err)
+	}
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, started_at, completed_at) VALUES ($1, 'completed', NOW(), NOW()) RETURNING id`,
+		repoID).Scan(&scanID); err != nil {
+		t.Fatalf("insert scan: %v", err)
+	}
+	return repoID, scanID
+}
+
+func TestFingerprintIgnoresIDAndWhitespace(t *testing.T) {
+	base := Vulnerability{ID: "a", Type: Injection, FilePath: "db.go", LineStart: 3, LineEnd: 4, Code: "query(x)"}
+
+	tests := []struct {
+		name     string
+		change   func(v *Vulnerability)
+		wantSame bool
+	}{
+		{name: "other ID", change: func(v *Vulnerability) { v.ID = "b" }, wantSame: true},
+		{name: "reformatted snippet", change: func(v *Vulnerability) { v.Code = "  query(x)\n" }, wantSame: true},
+		{name: "other file", change: func(v *Vulnerability) { v.FilePath = "api.go" }},
+		{name: "other lines", change: func(v *Vulnerability) { v.LineEnd = 5 }},
+		{name: "other type", change: func(v *Vulnerability) { v.Type = BrokenAccessControl }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			tt.change(&other)
+			if got := other.Fingerprint() == base.Fingerprint(); got != tt.wantSame {
+				t.Errorf("same fingerprint = %v, want %v", got, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestReindexFillsLegacyRowsAndKeepsCorrectOnes(t *testing.T) {
+	queries := newTestQueries(t)
+	sqlDB := queries.GetDB()
+	ctx := context.Background()
+	_, scanID := createTestScan(t, queries)
+
+	correct := Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 3, LineEnd: 4, Code: "query(x)"}
+	legacy := Vulnerability{Type: BrokenAccessControl, FilePath: "view.go", LineStart: 8, LineEnd: 8, Code: "write(input)"}
+	lastUpdated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	insert := `INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity,
+		description, code_snippet, fingerprint, owasp_category, severity_rank, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'finding', $7, $8, $9, $10, $11) RETURNING id`
+	if err := sqlDB.QueryRowContext(ctx, insert, scanID, correct.Type, correct.FilePath, correct.LineStart, correct.LineEnd,
+		"High", correct.Code, correct.Fingerprint(), OWASPCategory(correct.Type), SeverityRank("High"), lastUpdated).Scan(&correct.ID); err != nil {
+		t.Fatalf("insert correct finding: %v", err)
+	}
+	if err := sqlDB.QueryRowContext(ctx, insert, scanID, legacy.Type, legacy.FilePath, legacy.LineStart, legacy.LineEnd,
+		"Low", legacy.Code, nil, nil, 0, lastUpdated).Scan(&legacy.ID); err != nil {
+		t.Fatalf("insert legacy finding: %v", err)
+	}
+
+	service := NewReindexService(queries)
+	reindexAll := func() {
+		t.Helper()
+		cursor := ""
+		for {
+			progress, err := service.ReindexBatch(ctx, cursor, 100)
+			if err != nil {
+				t.Fatalf("ReindexBatch returned error: %v", err)
+			}
+			if progress.Done {
+				return
+			}
+			cursor = progress.NextCursor
+		}
+	}
+	reindexAll()
+
+	tests := []struct {
+		name        string
+		vuln        Vulnerability
+		wantUpdated bool
+	}{
+		{name: "correct row", vuln: correct},
+		{name: "legacy row", vuln: legacy, wantUpdated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fingerprint, category, severity string
+			var severityRank int
+			var updatedAt time.Time
+			if err := sqlDB.QueryRowContext(ctx,
+				`SELECT fingerprint, owasp_category, severity, severity_rank, updated_at FROM vulnerabilities WHERE id = $1`,
+				tt.vuln.ID).Scan(&fingerprint, &category, &severity, &severityRank, &updatedAt); err != nil {
+				t.Fatalf("read finding: %v", err)
+			}
+			if fingerprint != tt.vuln.Fingerprint() || category != OWASPCategory(tt.vuln.Type) || severityRank != SeverityRank(severity) {
+				t.Errorf("derived fields = %s, %s, %d, want %s, %s, %d", fingerprint, category, severityRank,
+					tt.vuln.Fingerprint(), OWASPCategory(tt.vuln.Type), SeverityRank(severity))
+			}
+			if updated := !updatedAt.Equal(lastUpdated); updated != tt.wantUpdated {
+				t.Errorf("row rewritten = %v, want %v", updated, tt.wantUpdated)
+			}
+		})
+	}
+
+	var count int
+	var summary string
+	if err := sqlDB.QueryRowContext(ctx,
+		`SELECT vulnerability_count, severity_summary::text FROM scans WHERE id = $1`, scanID).Scan(&count, &summary); err != nil {
+		t.Fatalf("read scan summary: %v", err)
+	}
+	if count != 2 || summary != `{"Low": 1, "High": 1}` {
+		t.Errorf("scan summary = %d %s, want 2 {\"Low\": 1, \"High\": 1}", count, summary)
+	}
+
+	// A second run over the same rows changes nothing
+	changed, err := service.RefreshScanSummary(ctx, scanID)
+	if err != nil {
+		t.Fatalf("RefreshScanSummary returned error: %v", err)
+	}
+	if changed {
+		t.Error("RefreshScanSummary changed a summary that was already up to date")
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// RemediationItem is one entry of a scan's remediation plan: every reported finding of one type
+type RemediationItem struct {
+	Priority        int      `json:"priority"`         // 1 is the issue to fix first
+	Type            string   `json:"type"`             // Vulnerability type
+	OWASPCategory   string   `json:"owasp_category"`   // OWASP Top 10 identifier, e.g. "A03:2021"
+	HighestSeverity string   `json:"highest_severity"` // Most severe finding of this type
+	FindingCount    int      `json:"finding_count"`    // Reported findings of this type
+	Files           []string `json:"files"`            // Affected files, sorted
+	Guidance        string   `json:"guidance"`         // General fix for the category
+	Steps           []string `json:"steps"`            // Distinct per-finding remediations, most common first
+	ReferenceURL    string   `json:"reference_url"`    // OWASP page for the category
+}
+
+// maxRemediationSteps caps the per-finding remediations listed for one issue
+const maxRemediationSteps = 5
+
+// owaspReference holds the OWASP Top 10 page and general fix for a vulnerability type
+type owaspReference struct {
+	url      string
+	guidance string
+}
+
+// owaspReferences maps each vulnerability type to its OWASP Top 10 2021 reference
+var owaspReferences = map[VulnerabilityType]owaspReference{
+	BrokenAccessControl: {
+		url:      "https://owasp.org/Top10/A01_2021-Broken_Access_Control/",
+		guidance: "Deny by default and enforce authorization on the server for every request, including ownership of the records accessed.",
+	},
+	CryptographicFailures: {
+		url:      "https://owasp.org/Top10/A02_2021-Cryptographic_Failures/",
+		guidance: "Encrypt sensitive data in transit and at rest with current algorithms, and keep keys and secrets out of source code.",
+	},
+	Injection: {
+		url:      "https://owasp.org/Top10/A03_2021-Injection/",
+		guidance: "Use parameterized queries or safe APIs instead of building commands from input, and validate input against allow-lists.",
+	},
+	InsecureDesign: {
+		url:      "https://owasp.org/Top10/A04_2021-Insecure_Design/",
+		guidance: "Threat-model the affected flows and add the missing controls, such as rate limits and server-side business rule checks.",
+	},
+	SecurityMisconfiguration: {
+		url:      "https://owasp.org/Top10/A05_2021-Security_Misconfiguration/",
+		guidance: "Harden defaults: disable debug output and unused features, set security headers, and review permissive settings such as CORS.",
+	},
+	VulnerableComponents: {
+		url:      "https://owasp.org/Top10/A06_2021-Vulnerable_and_Outdated_Components/",
+		guidance: "Upgrade or replace components with known vulnerabilities and track dependencies so updates are applied promptly.",
+	},
+	IdentificationAuthFailures: {
+		url:      "https://owasp.org/Top10/A07_2021-Identification_and_Authentication_Failures/",
+		guidance: "Use a vetted authentication library, hash passwords with a slow algorithm, and protect sessions and tokens.",
+	},
+	SoftwareIntegrityFailures: {
+		url:      "https://owasp.org/Top10/A08_2021-Software_and_Data_Integrity_Failures/",
+		guidance: "Verify signatures or checksums of code and data you load, and avoid deserializing untrusted input.",
+	},
+	SecurityLoggingFailures: {
+		url:      "https://owasp.org/Top10/A09_2021-Security_Logging_and_Monitoring_Failures/",
+		guidance: "Log security-relevant events without sensitive data, and make sure they are monitored and alerted on.",
+	},
+	ServerSideRequestForgery: {
+		url:      "https://owasp.org/Top10/A10_2021-Server-Side_Request_Forgery_%28SSRF%29/",
+		guidance: "Validate and allow-list outbound destinations, and block requests to internal and metadata addresses.",
+	},
+}
+
+// OWASPReferenceURL returns the OWASP Top 10 page for a vulnerability type
+// Types outside the Top 10 link to the Top 10 overview
+func OWASPReferenceURL(vulnType VulnerabilityType) string {
+	if reference, ok := owaspReferences[vulnType]; ok {
+		return reference.url
+	}
+	return "https://owasp.org/Top10/"
+}
+
+// BuildRemediationPlan groups reported findings by type into a prioritized list of issues to fix
+// Issues are ordered by their most severe finding, then by how many findings they have.
+// Excluded and baselined findings are left out, as they are from reported counts
+func BuildRemediationPlan(vulns []*Vulnerability) []*RemediationItem {
+	type issue struct {
+		item      *RemediationItem
+		rank      int
+		files     map[string]bool
+		stepCount map[string]int
+	}
+
+	issues := make(map[VulnerabilityType]*issue)
+	for _, vuln := range vulns {
+		if vuln.Excluded || vuln.Baselined {
+			continue
+		}
+
+		current, ok := issues[vuln.Type]
+		if !ok {
+			reference := owaspReferences[vuln.Type]
+			current = &issue{
+				item: &RemediationItem{
+					Type:          string(vuln.Type),
+					OWASPCategory: OWASPCategory(vuln.Type),
+					Guidance:      reference.guidance,
+					ReferenceURL:  OWASPReferenceURL(vuln.Type),
+				},
+				files:     make(map[string]bool),
+				stepCount: make(map[string]int),
+			}
+			issues[vuln.Type] = current
+		}
+
+		current.item.FindingCount++
+		current.files[vuln.FilePath] = true
+		if rank := SeverityRank(vuln.Severity); rank > current.rank || current.item.HighestSeverity == "" {
+			current.rank = rank
+			current.item.HighestSeverity = vuln.Severity
+		}
+		if step := strings.TrimSpace(vuln.Remediation); step != "" {
+			current.stepCount[step]++
+		}
+	}
+
+	plan := make([]*RemediationItem, 0, len(issues))
+	ranks := make(map[*RemediationItem]int, len(issues))
+	for _, current := range issues {
+		item := current.item
+
+		item.Files = make([]string, 0, len(current.files))
+		for file := range current.files {
+			item.Files = append(item.Files, file)
+		}
+		sort.Strings(item.Files)
+
+		// The remediations suggested most often come first
+		item.Steps = make([]string, 0, len(current.stepCount))
+		for step := range current.stepCount {
+			item.Steps = append(item.Steps, step)
+		}
+		sort.Slice(item.Steps, func(i, j int) bool {
+			ci, cj := current.stepCount[item.Steps[i]], current.stepCount[item.Steps[j]]
+			if ci != cj {
+				return ci > cj
+			}
+			return item.Steps[i] < item.Steps[j]
+		})
+		if len(item.Steps) > maxRemediationSteps {
+			item.Steps = item.Steps[:maxRemediationSteps]
+		}
+
+		ranks[item] = current.rank
+		plan = append(plan, item)
+	}
+
+	sort.Slice(plan, func(i, j int) bool {
+		if ranks[plan[i]] != ranks[plan[j]] {
+			return ranks[plan[i]] > ranks[plan[j]]
+		}
+		if plan[i].FindingCount != plan[j].FindingCount {
+			return plan[i].FindingCount > plan[j].FindingCount
+		}
+		return plan[i].Type < plan[j].Type
+	})
+	for i, item := range plan {
+		item.Priority = i + 1
+	}
+
+	return plan
+}
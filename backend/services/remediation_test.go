@@ -0,0 +1,67 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildRemediationPlanRanksBySeverityThenFrequency(t *testing.T) {
+	vulns := []*Vulnerability{
+		{Type: Injection, FilePath: "db/query.go", Severity: "High", Remediation: "Use parameterized queries"},
+		{Type: Injection, FilePath: "db/query.go", Severity: "Medium", Remediation: "Use parameterized queries"},
+		{Type: Injection, FilePath: "api/search.go", Severity: "High", Remediation: "Escape shell arguments"},
+		{Type: BrokenAccessControl, FilePath: "api/admin.go", Severity: "High"},
+		{Type: CryptographicFailures, FilePath: "auth/hash.go", Severity: "Critical", Remediation: "Use bcrypt"},
+		{Type: SecurityMisconfiguration, FilePath: "main.go", Severity: "Low"},
+		// Excluded and baselined findings are not part of the plan
+		{Type: ServerSideRequestForgery, FilePath: "fetch.go", Severity: "Critical", Excluded: true},
+		{Type: ServerSideRequestForgery, FilePath: "fetch.go", Severity: "Critical", Baselined: true},
+	}
+
+	plan := BuildRemediationPlan(vulns)
+
+	var order []string
+	for i, item := range plan {
+		order = append(order, item.Type)
+		if item.Priority != i+1 {
+			t.Errorf("%s has priority %d, want %d", item.Type, item.Priority, i+1)
+		}
+	}
+	// Critical outranks everything; among the High issues the more frequent one comes first
+	want := []string{string(CryptographicFailures), string(Injection), string(BrokenAccessControl), string(SecurityMisconfiguration)}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("plan order = %v, want %v", order, want)
+	}
+
+	injection := plan[1]
+	if injection.FindingCount != 3 || injection.HighestSeverity != "High" {
+		t.Errorf("injection issue = %d findings at %s, want 3 at High", injection.FindingCount, injection.HighestSeverity)
+	}
+	if wantFiles := []string{"api/search.go", "db/query.go"}; !reflect.DeepEqual(injection.Files, wantFiles) {
+		t.Errorf("injection files = %v, want %v", injection.Files, wantFiles)
+	}
+	if wantSteps := []string{"Use parameterized queries", "Escape shell arguments"}; !reflect.DeepEqual(injection.Steps, wantSteps) {
+		t.Errorf("injection steps = %v, want %v", injection.Steps, wantSteps)
+	}
+	if injection.OWASPCategory != "A03:2021" || injection.ReferenceURL != OWASPReferenceURL(Injection) || injection.Guidance == "" {
+		t.Errorf("injection reference = %s %s %q, want A03:2021 with guidance", injection.OWASPCategory, injection.ReferenceURL, injection.Guidance)
+	}
+}
+
+func TestBuildRemediationPlanOfACleanScan(t *testing.T) {
+	plan := BuildRemediationPlan(nil)
+	if plan == nil || len(plan) != 0 {
+		t.Errorf("BuildRemediationPlan(nil) = %v, want an empty plan", plan)
+	}
+}
+
+func TestOWASPReferencesCoverEveryType(t *testing.T) {
+	for _, vulnType := range AllVulnerabilityTypes {
+		if OWASPReferenceURL(vulnType) == "https://owasp.org/Top10/" {
+			t.Errorf("%s has no OWASP reference", vulnType)
+		}
+	}
+	if got := OWASPReferenceURL("Buffer Overflow"); got != "https://owasp.org/Top10/" {
+		t.Errorf("OWASPReferenceURL(unknown) = %s, want the Top 10 overview", got)
+	}
+}
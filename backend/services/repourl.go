@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidRepositoryURL is returned for repository URLs that are malformed or point somewhere
+// repositories may not be fetched from; the wrapping error says what is wrong with the URL
+var ErrInvalidRepositoryURL = errors.New("invalid repository URL")
+
+// invalidRepoURL returns an ErrInvalidRepositoryURL explaining why a URL was refused
+func invalidRepoURL(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRepositoryURL, fmt.Sprintf(format, args...))
+}
+
+var (
+	// hostNamePattern matches DNS host names; IPv6 literals and anything carrying a port or credentials don't match
+	hostNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+	// gitHubOwnerPattern matches GitHub user and organization names: letters, digits, and single
+	// hyphens that neither start nor end the name
+	gitHubOwnerPattern = regexp.MustCompile(`^[A-Za-z0-9](-?[A-Za-z0-9])*$`)
+
+	// gitHubRepoPattern matches GitHub repository names
+	gitHubRepoPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+	// gitLabPathPattern matches one segment of a GitLab group or project path, which may not start with a hyphen
+	gitLabPathPattern = regexp.MustCompile(`^[A-Za-z0-9_.][A-Za-z0-9_.-]*$`)
+)
+
+// Longest names GitHub and GitLab allow
+const (
+	maxGitHubOwnerLength = 39
+	maxGitHubRepoLength  = 100
+	maxGitLabPathLength  = 255
+)
+
+// repoURLParts is a repository URL taken apart, after the checks every provider shares
+type repoURLParts struct {
+	scheme string   // "https", "http", or "ssh"
+	host   string   // Lower-cased host name, without user or port
+	path   []string // Path segments; a trailing .git is left for the provider to strip
+}
+
+// splitRepoURL takes apart a repository URL in one of the accepted forms: https://host/path,
+// http://host/path, ssh://[user@]host[:port]/path, user@host:path (scp-like SSH), and host/path (HTTPS)
+// It refuses anything that has no business in a repository URL: credentials or a port in a web URL,
+// a query or fragment, percent-encoding, whitespace, and empty, "." or ".." path segments
+func splitRepoURL(raw string) (*repoURLParts, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "":
+		return nil, invalidRepoURL("the URL is empty")
+	case strings.IndexFunc(raw, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0:
+		return nil, invalidRepoURL("the URL contains whitespace or control characters")
+	case strings.ContainsAny(raw, `?#`):
+		return nil, invalidRepoURL("the URL must not have a query string or fragment")
+	case strings.ContainsAny(raw, `%\`):
+		return nil, invalidRepoURL("the URL must not contain percent-encoding or backslashes")
+	}
+
+	parts := &repoURLParts{scheme: "https"}
+	var authority, path string
+	if scheme, rest, found := strings.Cut(raw, "://"); found {
+		switch strings.ToLower(scheme) {
+		case "https", "http":
+			parts.scheme = strings.ToLower(scheme)
+		case "ssh", "git+ssh":
+			parts.scheme = "ssh"
+		default:
+			return nil, invalidRepoURL("unsupported scheme %q (expected https or ssh)", scheme)
+		}
+		authority, path, _ = strings.Cut(rest, "/")
+	} else if at, colon := strings.Index(raw, "@"), strings.Index(raw, ":"); at > 0 && colon > at && !strings.Contains(raw[:at], "/") {
+		// user@host:owner/repo.git
+		parts.scheme = "ssh"
+		authority, path = raw[:colon], raw[colon+1:]
+	} else {
+		authority, path, _ = strings.Cut(raw, "/")
+	}
+
+	host := authority
+	if at := strings.LastIndex(authority, "@"); at >= 0 {
+		user := authority[:at]
+		host = authority[at+1:]
+		// SSH logs in as a user, e.g. git@; web URLs must not carry credentials, which would be stored and logged
+		if parts.scheme != "ssh" || user == "" || strings.Contains(user, ":") {
+			return nil, invalidRepoURL("the URL must not contain credentials")
+		}
+	}
+	if hostOnly, port, found := strings.Cut(host, ":"); found {
+		// Self-hosted GitLab may serve SSH on another port; web URLs are looked up on the default one
+		if parts.scheme != "ssh" {
+			return nil, invalidRepoURL("the URL must not include a port")
+		}
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return nil, invalidRepoURL("invalid port %q", port)
+		}
+		host = hostOnly
+	}
+	parts.host = strings.ToLower(host)
+	if !hostNamePattern.MatchString(parts.host) {
+		return nil, invalidRepoURL("invalid host %q", host)
+	}
+
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return nil, invalidRepoURL("the URL has no repository path")
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, invalidRepoURL("the URL path must not contain empty, \".\" or \"..\" segments")
+		}
+		parts.path = append(parts.path, segment)
+	}
+	return parts, nil
+}
+
+// validateGitHubName checks a GitHub owner and repository name against the characters GitHub allows
+func validateGitHubName(owner, name string) error {
+	if len(owner) > maxGitHubOwnerLength || !gitHubOwnerPattern.MatchString(owner) {
+		return invalidRepoURL("invalid GitHub owner %q", owner)
+	}
+	if len(name) > maxGitHubRepoLength || !gitHubRepoPattern.MatchString(name) || name == "." || name == ".." {
+		return invalidRepoURL("invalid GitHub repository name %q", name)
+	}
+	return nil
+}
+
+// validateGitLabPath checks each segment of a GitLab group or project path
+func validateGitLabPath(segments []string) error {
+	for _, segment := range segments {
+		if len(segment) > maxGitLabPathLength || !gitLabPathPattern.MatchString(segment) {
+			return invalidRepoURL("invalid GitLab group or project name %q", segment)
+		}
+	}
+	return nil
+}
+
+// ValidateCloneURL checks that a clone URL points at a supported provider over HTTPS, HTTP, or SSH
+// Clone URLs are stored from provider API responses and webhook payloads; checking them again before
+// cloning keeps a tampered record from making the worker fetch local files or internal hosts
+func ValidateCloneURL(cloneURL string) error {
+	if !isSSHCloneURL(cloneURL) {
+		parsed, err := url.Parse(cloneURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return invalidRepoURL("clone URL must use https or ssh")
+		}
+		if parsed.User != nil {
+			return invalidRepoURL("clone URL must not contain credentials")
+		}
+	}
+	if providerForHost(repoURLHost(cloneURL)) == "" {
+		return invalidRepoURL("clone host %q is not a configured repository provider", repoURLHost(cloneURL))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// ScanCache stores model results per file so unchanged files are not sent to the model again
+// Entries are keyed by ScanCacheKey, so any change to the code, model, or requested types is a miss
+type ScanCache interface {
+	// Get returns the cached result for the key; the bool is false on a miss
+	Get(ctx context.Context, key string) (*baml.CodeScanResult, bool, error)
+
+	// Put stores a result, replacing any earlier entry for the key
+	Put(ctx context.Context, key string, result *baml.CodeScanResult) error
+}
+
+// ScanCacheKey derives the cache key of a file's model result
+// The prompt depends on the language and the requested types, and the answer on the model,
+// so all of them are part of the key; the order of the types does not matter
+func ScanCacheKey(code, language, model string, vulnTypes []string) string {
+	types := append([]string(nil), vulnTypes...)
+	sort.Strings(types)
+
+	contentHash := sha256.Sum256([]byte(code))
+	key := sha256.Sum256([]byte(strings.Join([]string{
+		hex.EncodeToString(contentHash[:]),
+		language,
+		model,
+		strings.Join(types, ","),
+	}, "\n")))
+	return hex.EncodeToString(key[:])
+}
+
+// NewScanCache creates a scan cache backed by the scan_cache table
+func NewScanCache(dbQueries *db.Queries) ScanCache {
+	return &dbScanCache{
+		db: dbQueries,
+	}
+}
+
+// dbScanCache implements the ScanCache interface on the scan_cache table
+type dbScanCache struct {
+	db *db.Queries
+}
+
+func (c *dbScanCache) Get(ctx context.Context, key string) (*baml.CodeScanResult, bool, error) {
+	sqlDB := c.db.GetDB()
+	if sqlDB == nil {
+		return nil, false, fmt.Errorf("database connection not available")
+	}
+
+	var stored []byte
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT result FROM scan_cache WHERE cache_key = $1`, key).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read scan cache: %w", err)
+	}
+
+	result := &baml.CodeScanResult{}
+	if err := json.Unmarshal(stored, result); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached scan result: %w", err)
+	}
+	return result, true, nil
+}
+
+func (c *dbScanCache) Put(ctx context.Context, key string, result *baml.CodeScanResult) error {
+	sqlDB := c.db.GetDB()
+	if sqlDB == nil {
+		return fmt.Errorf("database connection not available")
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode scan result: %w", err)
+	}
+
+	_, err = sqlDB.ExecContext(ctx,
+		`INSERT INTO scan_cache (cache_key, result) VALUES ($1, $2::jsonb)
+		ON CONFLICT (cache_key) DO UPDATE SET result = EXCLUDED.result, created_at = NOW()`,
+		key, string(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	return nil
+}
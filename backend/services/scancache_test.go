@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+)
+
+// memoryScanCache is a ScanCache kept in a map
+type memoryScanCache struct {
+	mu      sync.Mutex
+	entries map[string]*baml.CodeScanResult
+}
+
+func (c *memoryScanCache) Get(ctx context.Context, key string) (*baml.CodeScanResult, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, found := c.entries[key]
+	return result, found, nil
+}
+
+func (c *memoryScanCache) Put(ctx context.Context, key string, result *baml.CodeScanResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*baml.CodeScanResult)
+	}
+	c.entries[key] = result
+	return nil
+}
+
+func TestScanCacheKey(t *testing.T) {
+	base := ScanCacheKey("query(x)", "Go", "gpt-4o", []string{"Injection", "Cryptographic Failures"})
+
+	if got := ScanCacheKey("query(x)", "Go", "gpt-4o", []string{"Cryptographic Failures", "Injection"}); got != base {
+		t.Error("reordering the vulnerability types changed the key")
+	}
+	for name, key := range map[string]string{
+		"code":     ScanCacheKey("query(y)", "Go", "gpt-4o", []string{"Injection", "Cryptographic Failures"}),
+		"language": ScanCacheKey("query(x)", "Python", "gpt-4o", []string{"Injection", "Cryptographic Failures"}),
+		"model":    ScanCacheKey("query(x)", "Go", "gpt-4o-mini", []string{"Injection", "Cryptographic Failures"}),
+		"types":    ScanCacheKey("query(x)", "Go", "gpt-4o", []string{"Injection"}),
+	} {
+		if key == base {
+			t.Errorf("changing the %s kept the same key", name)
+		}
+	}
+}
+
+func TestScanRepositoryReusesCachedResults(t *testing.T) {
+	root := writeFixtureTree(t, []string{"main.go", "api/handler.go", "db/query.go"})
+	// Identical files share a cache entry, so give each its own content
+	for _, path := range []string{"main.go", "api/handler.go", "db/query.go"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(path)), []byte("package fixture\n\n// "+path+"\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	cache := &memoryScanCache{}
+
+	// scan runs a scan of the fixture and returns how many files were sent to the model
+	scan := func(t *testing.T, disableCache bool) (int, *ScanResult) {
+		t.Helper()
+		transport := &slowModelTransport{}
+		useModelTransport(t, transport)
+
+		result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+			VulnerabilityTypes: []VulnerabilityType{Injection},
+			FileExtensions:     []string{".go"},
+			Cache:              cache,
+			DisableCache:       disableCache,
+		})
+		if err != nil {
+			t.Fatalf("ScanRepository returned error: %v", err)
+		}
+		return transport.requests, result
+	}
+
+	if requests, result := scan(t, false); requests != 3 || result.CacheMisses != 3 || result.CacheHits != 0 {
+		t.Fatalf("first scan sent %d requests with %d hits and %d misses, want 3 misses", requests, result.CacheHits, result.CacheMisses)
+	}
+
+	requests, result := scan(t, false)
+	if requests != 0 || result.CacheHits != 3 {
+		t.Errorf("second scan sent %d requests with %d hits, want every file from the cache", requests, result.CacheHits)
+	}
+	if len(result.Vulnerabilities) != 3 {
+		t.Errorf("second scan reported %d findings, want the 3 cached ones", len(result.Vulnerabilities))
+	}
+
+	if requests, _ := scan(t, true); requests != 3 {
+		t.Errorf("scan with the cache disabled sent %d requests, want 3", requests)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "db", "query.go"), []byte("package fixture\n\nvar changed = true\n"), 0o644); err != nil {
+		t.Fatalf("change file: %v", err)
+	}
+	if requests, result := scan(t, false); requests != 1 || result.CacheHits != 2 {
+		t.Errorf("scan after one file changed sent %d requests with %d hits, want only the changed file sent", requests, result.CacheHits)
+	}
+}
+
+func TestDBScanCacheRoundTrip(t *testing.T) {
+	cache := NewScanCache(newTestQueries(t))
+	ctx := context.Background()
+	key := ScanCacheKey("query(x)", "Go", "gpt-4o", []string{"Injection"}) + "-test"
+
+	if _, found, err := cache.Get(ctx, key); err != nil || found {
+		t.Fatalf("Get before Put = found %v, error %v, want a miss", found, err)
+	}
+
+	for _, lineStart := range []int{3, 7} {
+		stored := &baml.CodeScanResult{Vulnerabilities: []baml.Vulnerability{{VulnerabilityType: "Injection", LineStart: lineStart, Severity: "High"}}}
+		if err := cache.Put(ctx, key, stored); err != nil {
+			t.Fatalf("Put returned error: %v", err)
+		}
+
+		cached, found, err := cache.Get(ctx, key)
+		if err != nil || !found {
+			t.Fatalf("Get after Put = found %v, error %v, want a hit", found, err)
+		}
+		if len(cached.Vulnerabilities) != 1 || cached.Vulnerabilities[0].LineStart != lineStart {
+			t.Errorf("cached result = %+v, want the latest stored one", cached)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// ErrScanNotFinished is returned when diffing a scan that is still queued or running
+var ErrScanNotFinished = errors.New("scan has not finished")
+
+// ScanDiff describes how a repository's findings changed between two of its scans
+type ScanDiff struct {
+	Added     []*Vulnerability `json:"added"`     // In the later scan only: introduced since the earlier one
+	Fixed     []*Vulnerability `json:"fixed"`     // In the earlier scan only: gone from the later one
+	Unchanged []*Vulnerability `json:"unchanged"` // In both scans, as reported by the later one
+}
+
+// DiffKey identifies a finding across scans of the same repository
+// Unlike Fingerprint it leaves out line numbers, which shift whenever code above the finding changes,
+// so a finding is matched by its type, file, and whitespace-normalized snippet
+func DiffKey(v *Vulnerability) string {
+	code := strings.Join(strings.Fields(v.Code), " ")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s", v.Type, v.FilePath, code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffFindings diffs the findings of an earlier scan against those of a later scan by DiffKey
+// Findings sharing a key are matched one-to-one, so a second copy of a finding still counts as added
+func DiffFindings(from, to []*Vulnerability) *ScanDiff {
+	comparison := matchFindings(from, to, DiffKey)
+	diff := &ScanDiff{
+		Added:     comparison.OnlyInB,
+		Fixed:     comparison.OnlyInA,
+		Unchanged: make([]*Vulnerability, 0, len(comparison.InBoth)),
+	}
+	for _, match := range comparison.InBoth {
+		diff.Unchanged = append(diff.Unchanged, match.B)
+	}
+	return diff
+}
+
+// ScanDiffService compares scans of one repository
+type ScanDiffService interface {
+	// DiffScans diffs the findings of two finished scans of a repository, fromScanID being the earlier one
+	// Findings excluded by path rules are left out unless includeExcluded is set.
+	// It returns ErrScanNotFound when either scan doesn't belong to the repository and
+	// ErrScanNotFinished when either is still queued or running
+	DiffScans(ctx context.Context, repoID, fromScanID, toScanID string, includeExcluded bool) (*ScanDiff, error)
+}
+
+// NewScanDiffService creates a new scan diff service instance
+func NewScanDiffService(dbQueries *db.Queries) ScanDiffService {
+	return &scanDiffService{
+		db: dbQueries,
+	}
+}
+
+// scanDiffService implements the ScanDiffService interface
+type scanDiffService struct {
+	db *db.Queries
+}
+
+func (s *scanDiffService) DiffScans(ctx context.Context, repoID, fromScanID, toScanID string, includeExcluded bool) (*ScanDiff, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	var findings [2][]*Vulnerability
+	for i, scanID := range []string{fromScanID, toScanID} {
+		var status string
+		err := sqlDB.QueryRowContext(ctx,
+			`SELECT status FROM scans WHERE id::text = $1 AND repository_id::text = $2`,
+			scanID, repoID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("scan %s: %w", scanID, ErrScanNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up scan %s: %w", scanID, err)
+		}
+		if IsActiveScanStatus(status) {
+			return nil, fmt.Errorf("scan %s is %s: %w", scanID, NormalizeScanStatus(status), ErrScanNotFinished)
+		}
+
+		vulns, err := scanVulnerabilities(ctx, sqlDB, scanID)
+		if err != nil {
+			return nil, err
+		}
+		findings[i] = make([]*Vulnerability, 0, len(vulns))
+		for _, vuln := range vulns {
+			if includeExcluded || !vuln.Excluded {
+				findings[i] = append(findings[i], vuln)
+			}
+		}
+	}
+
+	return DiffFindings(findings[0], findings[1]), nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDiffFindingsBetweenCommits(t *testing.T) {
+	injection := &Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 10, LineEnd: 10, Code: "db.Query(q)"}
+	accessControl := &Vulnerability{Type: BrokenAccessControl, FilePath: "admin.go", LineStart: 4, LineEnd: 6, Code: "if user != nil {"}
+	crypto := &Vulnerability{Type: CryptographicFailures, FilePath: "token.go", LineStart: 2, LineEnd: 2, Code: "md5.Sum(secret)"}
+
+	// The injection moved down and was reindented, the access control bug was fixed, and weak hashing is new
+	movedInjection := &Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 14, LineEnd: 14, Code: "\tdb.Query(q)\n"}
+	diff := DiffFindings([]*Vulnerability{injection, accessControl}, []*Vulnerability{movedInjection, crypto})
+
+	if len(diff.Added) != 1 || diff.Added[0] != crypto {
+		t.Errorf("added = %v, want the weak hashing finding", diff.Added)
+	}
+	if len(diff.Fixed) != 1 || diff.Fixed[0] != accessControl {
+		t.Errorf("fixed = %v, want the access control finding", diff.Fixed)
+	}
+	if len(diff.Unchanged) != 1 || diff.Unchanged[0] != movedInjection {
+		t.Errorf("unchanged = %v, want the injection as reported by the later scan", diff.Unchanged)
+	}
+}
+
+func TestDiffFindingsWithAnEmptyScan(t *testing.T) {
+	findings := []*Vulnerability{
+		{Type: Injection, FilePath: "db.go", Code: "db.Query(q)"},
+		{Type: Injection, FilePath: "db.go", Code: "db.Query(q)"}, // The same snippet twice in one file
+	}
+
+	diff := DiffFindings(nil, findings)
+	if len(diff.Added) != 2 || len(diff.Fixed) != 0 || len(diff.Unchanged) != 0 {
+		t.Errorf("first scan with findings: got %d added, %d fixed, %d unchanged; want 2, 0, 0",
+			len(diff.Added), len(diff.Fixed), len(diff.Unchanged))
+	}
+
+	diff = DiffFindings(findings, []*Vulnerability{})
+	if len(diff.Added) != 0 || len(diff.Fixed) != 2 || diff.Unchanged == nil {
+		t.Errorf("clean later scan: got %d added, %d fixed, unchanged %v; want 0, 2, []",
+			len(diff.Added), len(diff.Fixed), diff.Unchanged)
+	}
+}
+
+func TestDiffKeyIgnoresLinesButNotLocation(t *testing.T) {
+	base := Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 3, LineEnd: 4, Code: "query(x)"}
+
+	tests := []struct {
+		name     string
+		change   func(v *Vulnerability)
+		wantSame bool
+	}{
+		{name: "other lines", change: func(v *Vulnerability) { v.LineStart, v.LineEnd = 30, 31 }, wantSame: true},
+		{name: "reformatted snippet", change: func(v *Vulnerability) { v.Code = "  query(x)\n" }, wantSame: true},
+		{name: "other file", change: func(v *Vulnerability) { v.FilePath = "api.go" }},
+		{name: "other snippet", change: func(v *Vulnerability) { v.Code = "query(y)" }},
+		{name: "other type", change: func(v *Vulnerability) { v.Type = BrokenAccessControl }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			tt.change(&other)
+			if got := DiffKey(&other) == DiffKey(&base); got != tt.wantSame {
+				t.Errorf("same diff key = %v, want %v", got, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestDiffScansLoadsBothScans(t *testing.T) {
+	queries := newTestQueries(t)
+	sqlDB := queries.GetDB()
+	ctx := context.Background()
+	repoID, fromScanID := createTestScan(t, queries)
+
+	var toScanID, runningScanID string
+	for _, scan := range []struct {
+		id     *string
+		status string
+	}{{&toScanID, ScanStatusCompleted}, {&runningScanID, ScanStatusScanning}} {
+		if err := sqlDB.QueryRowContext(ctx,
+			`INSERT INTO scans (repository_id, status, started_at) VALUES ($1, $2, NOW()) RETURNING id`,
+			repoID, scan.status).Scan(scan.id); err != nil {
+			t.Fatalf("insert scan: %v", err)
+		}
+	}
+
+	store := func(scanID string, vulns ...*Vulnerability) {
+		tx, err := sqlDB.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := insertVulnerabilities(ctx, tx, scanID, OWASP2021Taxonomy, vulns, 10); err != nil {
+			tx.Rollback()
+			t.Fatalf("insert findings: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store(fromScanID,
+		&Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 3, LineEnd: 3, Severity: "High", Description: "sql", Code: "db.Query(q)"},
+		&Vulnerability{Type: BrokenAccessControl, FilePath: "admin.go", LineStart: 5, LineEnd: 5, Severity: "Medium", Description: "acl", Code: "allow()"})
+	store(toScanID,
+		&Vulnerability{Type: Injection, FilePath: "db.go", LineStart: 9, LineEnd: 9, Severity: "High", Description: "sql", Code: "db.Query(q)"},
+		&Vulnerability{Type: CryptographicFailures, FilePath: "token.go", LineStart: 1, LineEnd: 1, Severity: "Low", Description: "md5", Code: "md5.Sum(s)"})
+
+	service := NewScanDiffService(queries)
+	diff, err := service.DiffScans(ctx, repoID, fromScanID, toScanID, false)
+	if err != nil {
+		t.Fatalf("DiffScans: %v", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Type != CryptographicFailures {
+		t.Errorf("added = %v, want the weak hashing finding", diff.Added)
+	}
+	if len(diff.Fixed) != 1 || diff.Fixed[0].Type != BrokenAccessControl {
+		t.Errorf("fixed = %v, want the access control finding", diff.Fixed)
+	}
+	if len(diff.Unchanged) != 1 || diff.Unchanged[0].LineStart != 9 {
+		t.Errorf("unchanged = %v, want the injection at its new line", diff.Unchanged)
+	}
+
+	if _, err := service.DiffScans(ctx, repoID, fromScanID, runningScanID, false); !errors.Is(err, ErrScanNotFinished) {
+		t.Errorf("diff against a running scan = %v, want ErrScanNotFinished", err)
+	}
+	otherRepoID, otherScanID := createTestScan(t, queries)
+	if _, err := service.DiffScans(ctx, repoID, fromScanID, otherScanID, false); !errors.Is(err, ErrScanNotFound) {
+		t.Errorf("diff against another repository's scan = %v, want ErrScanNotFound", err)
+	}
+	if _, err := service.DiffScans(ctx, otherRepoID, otherScanID, otherScanID, false); err != nil {
+		t.Errorf("diff of a scan without findings: %v", err)
+	}
+}
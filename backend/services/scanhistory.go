@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+const (
+	// DefaultScanHistoryLimit is the page size when the caller does not pass one
+	DefaultScanHistoryLimit = 20
+
+	// MaxScanHistoryLimit caps the page size so one request can't load every scan at once
+	MaxScanHistoryLimit = 100
+)
+
+// ScanRecord summarizes one scan of a repository
+// Values the scan didn't record, such as the commit of a scan that never cloned, are null
+type ScanRecord struct {
+	ID                 string     `json:"id"`
+	Status             string     `json:"status"`
+	Ref                *string    `json:"ref"`
+	CommitSHA          *string    `json:"commit_sha"`
+	BaseCommitSHA      *string    `json:"base_commit_sha"` // Set for incremental scans
+	Model              *string    `json:"model"`
+	VulnerabilityCount int        `json:"vulnerability_count"` // Findings not excluded by path rules
+	CreatedAt          time.Time  `json:"created_at"`
+	StartedAt          *time.Time `json:"started_at"`
+	CompletedAt        *time.Time `json:"completed_at"`
+}
+
+// ScanHistoryService lists the scans recorded for a repository
+type ScanHistoryService interface {
+	// ListRepositoryScans returns one page of a repository's scans, newest first,
+	// together with the total number of scans so callers can page through them
+	ListRepositoryScans(ctx context.Context, repoID string, limit, offset int) ([]*ScanRecord, int, error)
+}
+
+// NewScanHistoryService creates a new scan history service instance
+func NewScanHistoryService(dbQueries *db.Queries) ScanHistoryService {
+	return &scanHistoryService{
+		db: dbQueries,
+	}
+}
+
+// scanHistoryService implements the ScanHistoryService interface
+type scanHistoryService struct {
+	db *db.Queries
+}
+
+func (s *scanHistoryService) ListRepositoryScans(ctx context.Context, repoID string, limit, offset int) ([]*ScanRecord, int, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, 0, fmt.Errorf("database connection not available")
+	}
+
+	if limit <= 0 {
+		limit = DefaultScanHistoryLimit
+	}
+	if limit > MaxScanHistoryLimit {
+		limit = MaxScanHistoryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var total int
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM scans WHERE repository_id::text = $1`, repoID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count scans for repository %s: %w", repoID, err)
+	}
+
+	// Counting in a subquery keeps scans without findings in the page with a count of zero;
+	// the id tiebreak keeps pages stable when two scans share a creation time
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT s.id::text, s.status, s.ref, s.commit_sha, s.base_commit_sha, s.model, s.created_at, s.started_at, s.completed_at,
+			(SELECT COUNT(*) FROM vulnerabilities v WHERE v.scan_id = s.id AND NOT v.excluded)
+		FROM scans s
+		WHERE s.repository_id::text = $1
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT $2 OFFSET $3`,
+		repoID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list scans for repository %s: %w", repoID, err)
+	}
+	defer rows.Close()
+
+	scans := []*ScanRecord{}
+	for rows.Next() {
+		scan := &ScanRecord{}
+		var ref, commitSHA, baseCommitSHA, model sql.NullString
+		var startedAt, completedAt sql.NullTime
+
+		if err := rows.Scan(&scan.ID, &scan.Status, &ref, &commitSHA, &baseCommitSHA, &model, &scan.CreatedAt,
+			&startedAt, &completedAt, &scan.VulnerabilityCount); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan scan row: %w", err)
+		}
+
+		scan.Ref = stringOrNil(ref)
+		scan.CommitSHA = stringOrNil(commitSHA)
+		scan.BaseCommitSHA = stringOrNil(baseCommitSHA)
+		scan.Model = stringOrNil(model)
+		if startedAt.Valid {
+			scan.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			scan.CompletedAt = &completedAt.Time
+		}
+
+		scans = append(scans, scan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error while iterating over scan rows: %w", err)
+	}
+
+	return scans, total, nil
+}
+
+// stringOrNil returns a pointer to the string, or nil when it is NULL or empty
+func stringOrNil(value sql.NullString) *string {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	return &value.String
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestListRepositoryScans(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, oldestID := createTestScan(t, queries)
+	_, otherRepoScanID := createTestScan(t, queries)
+
+	base := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := queries.GetDB().ExecContext(ctx,
+		`UPDATE scans SET created_at = $2 WHERE id = $1`, oldestID, base); err != nil {
+		t.Fatalf("date oldest scan: %v", err)
+	}
+
+	// Two later scans: one finished with a commit, one still running
+	var completedID, runningID string
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, created_at, started_at, completed_at, commit_sha)
+		VALUES ($1, 'completed', $2, $2, $2, 'a1b2c3d4') RETURNING id`,
+		repoID, base.Add(time.Hour)).Scan(&completedID); err != nil {
+		t.Fatalf("insert completed scan: %v", err)
+	}
+	if err := queries.GetDB().QueryRowContext(ctx,
+		`INSERT INTO scans (repository_id, status, created_at) VALUES ($1, 'running', $2) RETURNING id`,
+		repoID, base.Add(2*time.Hour)).Scan(&runningID); err != nil {
+		t.Fatalf("insert running scan: %v", err)
+	}
+
+	// Findings on the completed scan, one excluded by path rules; the other repository's don't count
+	for i, scanID := range []string{completedID, completedID, completedID, otherRepoScanID} {
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description, excluded)
+			VALUES ($1, 'Injection', 'main.go', $2, $2, 'High', 'finding', $3)`,
+			scanID, i+1, i == 2); err != nil {
+			t.Fatalf("insert finding: %v", err)
+		}
+	}
+
+	service := NewScanHistoryService(queries)
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		want   []string
+	}{
+		{name: "every scan newest first", limit: 10, want: []string{runningID, completedID, oldestID}},
+		{name: "first page", limit: 2, want: []string{runningID, completedID}},
+		{name: "second page", limit: 2, offset: 2, want: []string{oldestID}},
+		{name: "past the end", limit: 2, offset: 5, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scans, total, err := service.ListRepositoryScans(ctx, repoID, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("ListRepositoryScans returned error: %v", err)
+			}
+			if total != 3 {
+				t.Errorf("total = %d, want 3", total)
+			}
+			if len(scans) != len(tt.want) {
+				t.Fatalf("got %d scans, want %d", len(scans), len(tt.want))
+			}
+			for i, scan := range scans {
+				if scan.ID != tt.want[i] {
+					t.Errorf("scan %d = %s, want %s", i, scan.ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	scans, _, err := service.ListRepositoryScans(ctx, repoID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListRepositoryScans returned error: %v", err)
+	}
+	running, completed := scans[0], scans[1]
+	if completed.VulnerabilityCount != 2 {
+		t.Errorf("completed scan counts %d findings, want the 2 not excluded", completed.VulnerabilityCount)
+	}
+	if completed.CommitSHA == nil || *completed.CommitSHA != "a1b2c3d4" {
+		t.Errorf("completed scan commit = %v, want a1b2c3d4", completed.CommitSHA)
+	}
+	if running.VulnerabilityCount != 0 || running.StartedAt != nil || running.CompletedAt != nil || running.CommitSHA != nil {
+		t.Errorf("running scan = %+v, want no findings, times, or commit", running)
+	}
+}
@@ -2,11 +2,17 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -35,6 +41,48 @@ const (
 	ServerSideRequestForgery   VulnerabilityType = "Server-Side Request Forgery"                // A10:2021 - SSRF attacks
 )
 
+// AllVulnerabilityTypes lists every vulnerability type the scanner can be asked for, in OWASP order
+var AllVulnerabilityTypes = []VulnerabilityType{
+	BrokenAccessControl,
+	CryptographicFailures,
+	Injection,
+	InsecureDesign,
+	SecurityMisconfiguration,
+	VulnerableComponents,
+	IdentificationAuthFailures,
+	SoftwareIntegrityFailures,
+	SecurityLoggingFailures,
+	ServerSideRequestForgery,
+}
+
+// OWASPTop10Edition is the edition of the OWASP Top 10 the built-in vulnerability types and codes come from
+const OWASPTop10Edition = "2021"
+
+// VulnerabilityTypeInfo describes a vulnerability type the scanner looks for
+type VulnerabilityTypeInfo struct {
+	Name      string `json:"name"`       // Type name, as requested in scans and reported on findings
+	OWASPCode string `json:"owasp_code"` // OWASP Top 10 identifier, e.g. "A03:2021"
+}
+
+// SupportedVulnerabilityTypes lists AllVulnerabilityTypes with the OWASP codes findings are grouped under
+func SupportedVulnerabilityTypes() []VulnerabilityTypeInfo {
+	types := make([]VulnerabilityTypeInfo, len(AllVulnerabilityTypes))
+	for i, vulnType := range AllVulnerabilityTypes {
+		types[i] = VulnerabilityTypeInfo{Name: string(vulnType), OWASPCode: OWASPCategory(vulnType)}
+	}
+	return types
+}
+
+// IsKnownVulnerabilityType reports whether the name is one of AllVulnerabilityTypes
+func IsKnownVulnerabilityType(name string) bool {
+	for _, vulnType := range AllVulnerabilityTypes {
+		if string(vulnType) == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Vulnerability represents a detected security vulnerability
 // This struct stores all the information about a specific vulnerability found in the code
 type Vulnerability struct {
@@ -47,14 +95,191 @@ type Vulnerability struct {
 	Description string            // Human-readable description of the vulnerability
 	Remediation string            // Recommended fix for the vulnerability
 	Code        string            // The vulnerable code snippet
+	Excluded    bool              // True if the file matches a path exclude rule; hidden from default results
+	Baselined   bool              // True if the repository baseline accepted this finding; hidden from default results
+	Confidence  *float64          // Model's certainty that the finding is real, 0.0-1.0; nil when not reported
+	CWE         string            // Weakness the finding is an instance of, e.g. "CWE-89"; empty when unknown
+	References  []string          // Links about the issue: the model's, or the OWASP and CWE pages when it gave none
+}
+
+// Fingerprint returns a stable identifier for a finding that does not depend on its database ID
+// Two findings with the same type, location, and (whitespace-normalized) snippet share a fingerprint
+func (v *Vulnerability) Fingerprint() string {
+	code := strings.Join(strings.Fields(v.Code), " ")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%s", v.Type, v.FilePath, v.LineStart, v.LineEnd, code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// SeverityLevels lists the canonical severity labels, worst first
+var SeverityLevels = []string{"Critical", "High", "Medium", "Low"}
+
+// SeverityLevelInfo describes a severity label and the rank findings are ordered by
+type SeverityLevelInfo struct {
+	Name string `json:"name"`
+	Rank int    `json:"rank"` // Higher is worse; see SeverityRank
+}
+
+// SupportedSeverityLevels lists SeverityLevels with their ranks
+func SupportedSeverityLevels() []SeverityLevelInfo {
+	levels := make([]SeverityLevelInfo, len(SeverityLevels))
+	for i, severity := range SeverityLevels {
+		levels[i] = SeverityLevelInfo{Name: severity, Rank: SeverityRank(severity)}
+	}
+	return levels
+}
+
+// SeverityRank converts a severity label to a number that sorts worst first when descending
+// Matching is case-insensitive; unrecognized labels rank below Low
+func SeverityRank(severity string) int {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// NormalizeSeverity maps a severity label to its canonical form ("Critical", "High", "Medium", "Low")
+// Models are inconsistent about casing and wording, so "high", "HIGH", and "moderate" are all accepted;
+// unrecognized labels are returned trimmed but otherwise unchanged
+func NormalizeSeverity(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return "Critical"
+	case "high":
+		return "High"
+	case "medium", "moderate":
+		return "Medium"
+	case "low":
+		return "Low"
+	default:
+		return strings.TrimSpace(severity)
+	}
+}
+
+// OWASPCategory maps a vulnerability type to its OWASP Top 10 2021 identifier
+// Types outside the Top 10 are reported as "Other"
+func OWASPCategory(vulnType VulnerabilityType) string {
+	switch vulnType {
+	case Injection:
+		return "A03:2021"
+	case BrokenAccessControl:
+		return "A01:2021"
+	case CryptographicFailures:
+		return "A02:2021"
+	case InsecureDesign:
+		return "A04:2021"
+	case SecurityMisconfiguration:
+		return "A05:2021"
+	case VulnerableComponents:
+		return "A06:2021"
+	case IdentificationAuthFailures:
+		return "A07:2021"
+	case SoftwareIntegrityFailures:
+		return "A08:2021"
+	case SecurityLoggingFailures:
+		return "A09:2021"
+	case ServerSideRequestForgery:
+		return "A10:2021"
+	default:
+		return "Other"
+	}
 }
 
 // ScanResult represents the results of a vulnerability scan
 // This contains all vulnerabilities found in a repository and metadata about the scan
 type ScanResult struct {
-	RepositoryID    string           // ID of the repository that was scanned
-	Vulnerabilities []*Vulnerability // List of all vulnerabilities found
-	ScanTime        int64            // Unix timestamp when the scan was performed
+	RepositoryID      string           // ID of the repository that was scanned
+	Vulnerabilities   []*Vulnerability // List of all vulnerabilities found
+	ScanTime          int64            // Unix timestamp when the scan was performed
+	FilesScanned      int              // Number of files analyzed before the scan finished or stopped
+	FilesSkipped      int              // Number of eligible files left unscanned when the time budget ran out
+	FilesResumed      int              // Number of files skipped because an earlier attempt already completed them
+	FilesRemaining    int              // Number of eligible files left for a later batch
+	TimeBudgetReached bool             // True if the scan stopped early because the time budget was exhausted
+	BudgetExceeded    bool             // True if the scan stopped early because its token or cost budget was spent
+	CacheHits         int              // Files whose model result was reused from the scan cache
+	CacheMisses       int              // Files sent to the model because the cache had no result for them
+	DuplicatesMerged  int              // Findings dropped because they repeated another finding in the same file
+	FilesTimedOut     int              // Files whose model request ran past OPENAI_REQUEST_TIMEOUT; only local detectors covered them
+	FilesTooLarge     int              // Files skipped unread because they were larger than MaxFileSizeBytes
+	FilesParseFailed  int              // Files whose model reply couldn't be parsed even after repair; only local detectors covered them
+	FilesModelFailed  int              // Files whose model request failed; only local detectors covered them
+	Truncated         bool             // True if MaxFiles or a failed walk left eligible files out of the scan
+	TokensUsed        int              // Estimated prompt tokens sent to the model by this call
+	EstimatedCostUSD  float64          // Estimated price of those tokens
+}
+
+// Scan lifecycle statuses stored in the scans table and reported by the status endpoints
+// A scan moves queued -> cloning -> scanning and then into one of the terminal states
+const (
+	ScanStatusQueued    = "queued"    // Workflow started, clone not yet begun
+	ScanStatusCloning   = "cloning"   // Repository is being cloned
+	ScanStatusScanning  = "scanning"  // Files are being analyzed
+	ScanStatusCompleted = "completed" // Scan finished and results are stored
+	ScanStatusFailed    = "failed"    // Scan failed during clone or analysis
+	ScanStatusCanceled  = "canceled"  // Scan was canceled by the user before it finished
+
+	// ScanStatusTimeBudgetReached marks a scan that stopped early with partial results
+	ScanStatusTimeBudgetReached = "time_budget_reached"
+
+	// ScanStatusBudgetExceeded marks a scan that stopped early with partial results once its token or cost budget was spent
+	ScanStatusBudgetExceeded = "budget_exceeded"
+
+	// ScanStatusCompletedWithErrors marks a scan that scanned every file but couldn't store the findings of, or
+	// parse the model's reply for, more files than SCAN_MAX_INSERT_FAILURES allows; the files are listed in scan_errors
+	ScanStatusCompletedWithErrors = "completed_with_errors"
+)
+
+// NormalizeScanStatus maps legacy status values onto the current scan lifecycle
+// Older rows used "pending" for queued scans and "in_progress" for running ones
+func NormalizeScanStatus(status string) string {
+	switch status {
+	case "pending":
+		return ScanStatusQueued
+	case "in_progress":
+		return ScanStatusScanning
+	default:
+		return status
+	}
+}
+
+// IsActiveScanStatus reports whether the status belongs to a scan that has not finished yet
+func IsActiveScanStatus(status string) bool {
+	switch NormalizeScanStatus(status) {
+	case ScanStatusQueued, ScanStatusCloning, ScanStatusScanning:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTerminalScanStatus reports whether the status belongs to a scan that has finished and will not change again
+func IsTerminalScanStatus(status string) bool {
+	switch status {
+	case ScanStatusCompleted, ScanStatusFailed, ScanStatusCanceled, ScanStatusTimeBudgetReached, ScanStatusBudgetExceeded,
+		ScanStatusCompletedWithErrors:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsPartialScanStatus reports whether the status belongs to a scan that finished with incomplete results:
+// it stopped early but kept the results it had, or lost the findings of some files
+func IsPartialScanStatus(status string) bool {
+	return status == ScanStatusTimeBudgetReached || status == ScanStatusBudgetExceeded || status == ScanStatusCompletedWithErrors
+}
+
+// HasScanResults reports whether a scan with the status has stored results to return, complete or partial
+func HasScanResults(status string) bool {
+	return status == ScanStatusCompleted || IsPartialScanStatus(status)
 }
 
 // ScanOptions contains options for the vulnerability scanner
@@ -62,9 +287,280 @@ type ScanResult struct {
 type ScanOptions struct {
 	VulnerabilityTypes []VulnerabilityType // Types of vulnerabilities to scan for
 	MaxFiles           int                 // Maximum number of files to scan
+	MaxFileSizeBytes   int64               // Larger files are skipped without being read (0 uses DefaultMaxFileSizeBytes, negative disables)
+	BatchSize          int                 // Scan at most this many files not in CompletedFiles per call (0 = no limit)
+	Concurrency        int                 // Files scanned in parallel (0 uses DefaultScanConcurrency)
 	FileExtensions     []string            // File extensions to include in the scan
+	IncludeGlobs       []string            // When set, only files matching one of these globs are scanned, whatever their extension
+	MinSeverity        string              // Findings below this severity are dropped (empty keeps all)
+	TimeBudget         time.Duration       // Soft wall-clock limit; no new files are scanned once exceeded (0 = no limit)
+	ActivityTimeout    time.Duration       // Hard limit of the enclosing activity; the time budget is tightened to fit it
+	Budget             ScanBudget          // Token and cost limits of the whole scan; no new files are scanned once spent
+	TokensUsed         int                 // Estimated tokens already sent by earlier batches of the scan, counted against Budget
+	CompletedFiles     map[string]bool     // Relative paths finished by an earlier attempt; these are not rescanned
+	PathRules          *PathRules          // Include/exclude globs used to mark findings in third-party or generated code
+	Model              string              // LLM model to scan with; empty uses the client default
+	LLMDenylist        []string            // Path globs whose contents must never be sent to the LLM
+	LocalDetectors     []LocalDetector     // In-process detectors run on every file, including LLM-denied ones
+	ScanVendored       bool                // Walk vendor/ directories, for repositories that vendor first-party code
+	ScanSkippedDirs    []string            // Normally skipped directory names to walk anyway (e.g. "node_modules"); .git is always skipped
+	ExcludePatterns    []string            // Path globs skipped in addition to the built-in directories and .gitignore
+	IncludePatterns    []string            // Path globs scanned even if skipped by default, by .gitignore, or by ExcludePatterns
+	Cache              ScanCache           // Reuses model results for unchanged files (nil disables caching)
+	DisableCache       bool                // Send every file to the model even if a cached result exists
+	OnlyFiles          map[string]bool     // When non-nil, only these relative paths are considered (incremental scans)
+	DedupLineTolerance int                 // Lines apart same-type findings in a file may be and still be merged as one
+
+	// OnFileScanned is called with each file's findings as soon as that file finishes
+	// Returning an error aborts the scan so it can be retried and resumed from the recorded files
+	// Implementations must be safe for concurrent use
+	OnFileScanned func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error
+
+	// OnFileFailed is called after OnFileScanned for a file whose model request failed or whose reply couldn't
+	// be parsed, so the file can be marked as errored rather than clean. Returning an error aborts the scan like
+	// OnFileScanned
+	OnFileFailed func(ctx context.Context, relPath string, cause error) error
+
+	// OnProgress is called with the scan's progress before the first file starts and as each file starts
+	// and finishes. It is called from concurrent workers in order, so it must be quick and not block
+	OnProgress func(progress ScanProgress)
+}
+
+// dirsToSkip lists directories that are not walked by default (common dependency and non-application directories)
+// This improves performance by avoiding scanning of third-party code
+var dirsToSkip = map[string]bool{
+	".git":              true, // Git metadata
+	"node_modules":      true, // NPM dependencies
+	"vendor":            true, // Go vendor directory
+	"venv":              true, // Python virtual environment
+	"env":               true, // Python environment
+	"lib":               true, // Library code
+	"bin":               true, // Binary files
+	"dist":              true, // Distribution builds
+	"build":             true, // Build artifacts
+	"site-packages":     true, // Python packages
+	".github":           true, // GitHub configuration
+	"__pycache__":       true, // Python cache
+	".pytest_cache":     true, // Python test cache
+	".cache":            true, // Generic cache
+	"package-lock.json": true, // NPM lock file
+	"yarn.lock":         true, // Yarn lock file
 }
 
+// dependencyPathPatterns are skipped wherever they appear in a path, which catches nested dependencies
+var dependencyPathPatterns = []string{"site-packages", "node_modules", "vendor", ".cache"}
+
+// IsOverridableSkipDir reports whether a normally skipped directory may be re-enabled for a scan
+// Git metadata is never scanned
+func IsOverridableSkipDir(name string) bool {
+	return dirsToSkip[name] && name != ".git"
+}
+
+// findFilesToScan walks the repository and returns the absolute paths of the files the scan should analyze
+// truncated is true when MaxFiles stopped the walk with eligible files left over
+// Walk errors on individual paths are logged and skipped; the returned error is from the walk itself
+func findFilesToScan(log *zap.Logger, repoDir string, options *ScanOptions) (filesToScan []string, truncated bool, err error) {
+	log.Debug("Finding files to scan",
+		zap.Strings("extensions", options.FileExtensions),
+		zap.Strings("include_globs", options.IncludeGlobs))
+
+	// Directories the caller opted back into, e.g. vendored first-party code
+	reenabledDirs := reenabledSkipDirs(options)
+
+	// The repository's own .gitignore files, loaded as their directories are reached
+	gitignored := &ignoreRules{}
+
+	// Skipped directories that are still walked because an include pattern may match inside them;
+	// only files matching an include pattern are taken from these
+	var skippedButWalked []string
+
+	// Walk the repository directory tree to find eligible files
+	err = filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Warn("Error accessing path", zap.String("path", path), zap.Error(err))
+			return nil // Continue despite errors
+		}
+
+		relPath, _ := filepath.Rel(repoDir, path)
+		relPath = filepath.ToSlash(relPath)
+
+		if info.IsDir() {
+			if relPath == "." {
+				gitignored.load(repoDir, relPath)
+				return nil
+			}
+
+			// Git metadata is never scanned, whatever the include patterns say
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			if reason := skippedDirReason(options, reenabledDirs, gitignored, path, relPath); reason != "" {
+				if !mayMatchBelow(options.IncludePatterns, relPath) {
+					log.Debug("Skipping directory", zap.String("dir", relPath), zap.String("reason", reason))
+					return filepath.SkipDir
+				}
+				skippedButWalked = append(skippedButWalked, relPath)
+			}
+
+			gitignored.load(repoDir, relPath)
+			return nil
+		}
+
+		// Incremental scans only look at files changed since their base commit
+		if options.OnlyFiles != nil && !options.OnlyFiles[relPath] {
+			return nil
+		}
+
+		// Files ignored by .gitignore or an exclude pattern are left out unless an include pattern names them
+		if !MatchesAnyGlob(options.IncludePatterns, relPath) &&
+			(underAny(skippedButWalked, relPath) ||
+				gitignored.ignored(relPath, false) ||
+				MatchesAnyGlob(options.ExcludePatterns, relPath)) {
+			return nil
+		}
+
+		// Check if file has one of the target extensions
+		// Only scan files with extensions we're interested in
+		// Include globs take precedence, so a targeted scan can reach files outside the default extensions
+		ext := filepath.Ext(path)
+		if fileSelected(options, relPath, ext) {
+			// Skip minified JavaScript/CSS files, which are typically not sources of vulnerabilities
+			// and can be difficult for the AI to analyze effectively
+			if (ext == ".js" || ext == ".css") && strings.Contains(path, ".min.") {
+				return nil
+			}
+
+			// Skip test files as they often contain sample code that triggers false positives
+			// and typically don't run in production
+			if strings.Contains(path, "_test.go") ||
+				strings.Contains(path, "test_") ||
+				strings.Contains(path, "spec.") {
+				return nil
+			}
+
+			// Limit the number of files to scan to prevent excessive scanning time
+			// The scan then leaves this file and any after it unscanned, so it doesn't cover the whole tree
+			if options.MaxFiles > 0 && len(filesToScan) >= options.MaxFiles {
+				truncated = true
+				return filepath.SkipAll
+			}
+
+			// Add the file to our scan list
+			log.Debug("Adding file to scan list", zap.String("file", relPath))
+			filesToScan = append(filesToScan, path)
+		}
+
+		return nil
+	})
+
+	return filesToScan, truncated, err
+}
+
+// CollectFilesToScan returns the absolute paths of the files a repository scan analyzes, in scan order
+// It applies the scan's extensions, include globs, skipped directories, .gitignore rules, patterns, and file
+// limit. When the walk fails part way and finds nothing, common text and config files are collected instead.
+// Only a missing or unreadable repository directory is an error
+func CollectFilesToScan(ctx context.Context, repoDir string, options *ScanOptions) ([]string, error) {
+	filesToScan, _, err := collectFilesToScan(ctx, repoDir, options)
+	return filesToScan, err
+}
+
+// collectFilesToScan is CollectFilesToScan, also reporting whether the file list is cut short: MaxFiles left
+// eligible files out, or the walk failed part way
+func collectFilesToScan(ctx context.Context, repoDir string, options *ScanOptions) ([]string, bool, error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
+	}
+
+	filesToScan, truncated, err := findFilesToScan(log, repoDir, options)
+
+	// Handle errors or empty file lists
+	if err != nil {
+		log.Error("Error walking repository directory", zap.Error(err))
+		// Continue with any files found instead of failing completely, though they aren't the whole tree
+		truncated = true
+		if len(filesToScan) == 0 {
+			log.Warn("No files found to scan, checking if repository exists")
+			// Check if repo directory exists and has content
+			if _, statErr := os.Stat(repoDir); statErr != nil {
+				return nil, false, fmt.Errorf("repository directory not found or inaccessible: %w", statErr)
+			}
+
+			// Directory exists but no matching files found
+			// Try with broader extensions as fallback to find something to scan
+			fallbackExts := []string{".txt", ".md", ".json", ".yml", ".yaml", ".xml"}
+			log.Info("Trying fallback file types", zap.Strings("extensions", fallbackExts))
+
+			filepath.Walk(repoDir, func(path string, info os.FileInfo, walkErr error) error {
+				if walkErr != nil || info.IsDir() {
+					return nil
+				}
+				ext := filepath.Ext(path)
+				for _, fbExt := range fallbackExts {
+					if ext == fbExt {
+						filesToScan = append(filesToScan, path)
+						break
+					}
+				}
+				return nil
+			})
+		}
+	}
+
+	return filesToScan, truncated, nil
+}
+
+// skippedDirReason returns why a directory would not be walked, or "" when it should be
+// Directories are skipped when they are built-in dependency directories, ignored by the
+// repository's .gitignore, or match one of the scan's exclude patterns
+func skippedDirReason(options *ScanOptions, reenabledDirs map[string]bool, gitignored *ignoreRules, path, relPath string) string {
+	// Skip directories that are likely not application code
+	// This prevents scanning dependency directories
+	name := filepath.Base(path)
+	if dirsToSkip[name] && !reenabledDirs[name] {
+		return "dependency directory"
+	}
+
+	// Also skip directories that have paths containing common dependency patterns
+	// This catches nested dependencies
+	for _, pattern := range dependencyPathPatterns {
+		if strings.Contains(path, pattern) && !reenabledDirs[pattern] {
+			return "dependency path"
+		}
+	}
+
+	if gitignored.ignored(relPath, true) {
+		return "gitignore"
+	}
+	if MatchesAnyGlob(options.ExcludePatterns, relPath) {
+		return "exclude pattern"
+	}
+	return ""
+}
+
+// reenabledSkipDirs returns the normally skipped directory names the scan should walk anyway
+func reenabledSkipDirs(options *ScanOptions) map[string]bool {
+	reenabled := make(map[string]bool)
+	if options.ScanVendored {
+		reenabled["vendor"] = true
+	}
+	for _, name := range options.ScanSkippedDirs {
+		if IsOverridableSkipDir(name) {
+			reenabled[name] = true
+		}
+	}
+	return reenabled
+}
+
+// DefaultScanConcurrency is how many files a repository scan sends to the model at once
+const DefaultScanConcurrency = 4
+
+// LocalDetector finds vulnerabilities without sending code outside the process
+// It receives the repository-relative path and file contents
+type LocalDetector func(relPath, code string) []*Vulnerability
+
 // ScannerService defines the interface for vulnerability scanning
 // This interface allows for different scanner implementations
 type ScannerService interface {
@@ -103,6 +599,9 @@ func (s *scannerService) ScanRepository(ctx context.Context, repoDir string, opt
 
 	log.Info("Starting repository scan", zap.String("repo_dir", repoDir))
 
+	// Remember when the scan started so the time budget can be enforced between files
+	scanStart := time.Now()
+
 	// Create a scan record with a unique ID
 	scanID := uuid.New().String()
 
@@ -123,174 +622,393 @@ func (s *scannerService) ScanRepository(ctx context.Context, repoDir string, opt
 				ServerSideRequestForgery,
 			},
 			MaxFiles:       100, // Limit to 100 files to prevent excessive scanning time
-			FileExtensions: []string{".go", ".js", ".py", ".java", ".php", ".html", ".css", ".ts", ".jsx", ".tsx"},
+			FileExtensions: DefaultFileExtensions(),
 		}
 	}
 
 	// Find all eligible files for scanning
-	// We'll collect paths to all files that match our criteria
-	var filesToScan []string
-	log.Debug("Finding files to scan", zap.Strings("extensions", options.FileExtensions))
-
-	// Define directories to skip (common dependency and non-application directories)
-	// This improves performance by avoiding scanning of third-party code
-	dirsToSkip := map[string]bool{
-		".git":              true, // Git metadata
-		"node_modules":      true, // NPM dependencies
-		"vendor":            true, // Go vendor directory
-		"venv":              true, // Python virtual environment
-		"env":               true, // Python environment
-		"lib":               true, // Library code
-		"bin":               true, // Binary files
-		"dist":              true, // Distribution builds
-		"build":             true, // Build artifacts
-		"site-packages":     true, // Python packages
-		".github":           true, // GitHub configuration
-		"__pycache__":       true, // Python cache
-		".pytest_cache":     true, // Python test cache
-		".cache":            true, // Generic cache
-		"package-lock.json": true, // NPM lock file
-		"yarn.lock":         true, // Yarn lock file
+	// Scan previews select files the same way, so they show exactly what a scan would analyze
+	filesToScan, truncated, err := collectFilesToScan(ctx, repoDir, options)
+	if err != nil {
+		return nil, err
 	}
 
-	// Walk the repository directory tree to find eligible files
-	err := filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			log.Warn("Error accessing path", zap.String("path", path), zap.Error(err))
-			return nil // Continue despite errors
-		}
+	log.Info("Found files to scan", zap.Int("file_count", len(filesToScan)), zap.Bool("truncated", truncated))
 
-		if info.IsDir() {
-			// Skip directories that are likely not application code
-			// This prevents scanning dependency directories
-			if dirsToSkip[info.Name()] {
-				log.Debug("Skipping dependency directory", zap.String("dir", info.Name()))
-				return filepath.SkipDir
+	// Drop files that an interrupted earlier attempt already finished
+	filesResumed := 0
+	if len(options.CompletedFiles) > 0 {
+		remaining := filesToScan[:0]
+		for _, filePath := range filesToScan {
+			relPath, err := filepath.Rel(repoDir, filePath)
+			if err == nil && options.CompletedFiles[relPath] {
+				filesResumed++
+				continue
 			}
+			remaining = append(remaining, filePath)
+		}
+		filesToScan = remaining
 
-			// Also skip directories that have paths containing common dependency patterns
-			// This catches nested dependencies
-			if strings.Contains(path, "site-packages") ||
-				strings.Contains(path, "node_modules") ||
-				strings.Contains(path, "vendor") ||
-				strings.Contains(path, ".cache") {
-				log.Debug("Skipping dependency path", zap.String("path", path))
-				return filepath.SkipDir
-			}
+		log.Info("Resuming scan from recorded progress",
+			zap.Int("files_resumed", filesResumed),
+			zap.Int("files_remaining", len(filesToScan)))
+	}
 
-			return nil
+	// Scan only the next batch; the caller runs again for the rest once this batch is recorded
+	filesRemaining := 0
+	if options.BatchSize > 0 && len(filesToScan) > options.BatchSize {
+		filesRemaining = len(filesToScan) - options.BatchSize
+		filesToScan = filesToScan[:options.BatchSize]
+
+		log.Info("Scanning next batch of files",
+			zap.Int("batch_size", len(filesToScan)),
+			zap.Int("files_remaining", filesRemaining))
+	}
+
+	// Convert vulnerability types to strings for the BAML client
+	// BAML requires string input rather than our custom VulnerabilityType
+	var vulnTypeStrings []string
+	for _, vt := range options.VulnerabilityTypes {
+		vulnTypeStrings = append(vulnTypeStrings, string(vt))
+	}
+
+	// Use the requested model for this scan, leaving the shared client untouched
+	bamlClient := s.bamlClient.WithModel(options.Model)
+	log.Info("Scanning with model", zap.String("model", bamlClient.Model()))
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultScanConcurrency
+	}
+
+	// Files are scanned in rounds of `concurrency`, so every round timing out must still fit in the activity
+	timeBudget := options.TimeBudget
+	timeoutPlan := PlanScanTimeouts(options.ActivityTimeout, bamlClient.RequestTimeout(), options.TimeBudget, concurrency, len(filesToScan))
+	if !timeoutPlan.Fits {
+		timeBudget = timeoutPlan.TimeBudget
+		log.Warn("Worst-case scan time exceeds the activity timeout, tightening the time budget",
+			zap.Int("file_count", len(filesToScan)),
+			zap.Int("concurrency", concurrency),
+			zap.Int("max_files_at_worst_case", timeoutPlan.MaxFiles),
+			zap.Duration("request_timeout", bamlClient.RequestTimeout()),
+			zap.Duration("worst_case", timeoutPlan.WorstCase),
+			zap.Duration("activity_timeout", options.ActivityTimeout),
+			zap.Duration("time_budget", timeBudget))
+	}
+
+	// Scan files on a bounded pool of goroutines and collect all vulnerabilities
+	// The first progress-recording error cancels the remaining files so the scan can be retried
+	scanCtx, cancelScan := context.WithCancel(ctx)
+	defer cancelScan()
+
+	var (
+		mu                 sync.Mutex
+		wg                 sync.WaitGroup
+		allVulnerabilities []*Vulnerability
+		scanErr            error
+		filesScanned       int
+		timeBudgetReached  bool
+		budgetExceeded     bool
+		stats              scanStats
+	)
+	slots := make(chan struct{}, concurrency)
+
+	// Progress covers the whole scan, so files finished by earlier batches and attempts count as scanned
+	// reportProgress is called with mu held, which keeps the reports in order
+	progress := ScanProgress{Scanned: filesResumed, Total: filesResumed + len(filesToScan) + filesRemaining}
+	reportProgress := func() {
+		if options.OnProgress != nil {
+			options.OnProgress(progress)
 		}
+	}
+	reportProgress()
 
-		// Check if file has one of the target extensions
-		// Only scan files with extensions we're interested in
-		ext := filepath.Ext(path)
-		for _, targetExt := range options.FileExtensions {
-			if ext == targetExt {
-				// Skip minified JavaScript/CSS files, which are typically not sources of vulnerabilities
-				// and can be difficult for the AI to analyze effectively
-				if (ext == ".js" || ext == ".css") && strings.Contains(path, ".min.") {
-					return nil
-				}
+dispatch:
+	for i, filePath := range filesToScan {
+		// Wait for a free worker, or stop dispatching if the scan was aborted
+		select {
+		case slots <- struct{}{}:
+		case <-scanCtx.Done():
+			break dispatch
+		}
+		if scanCtx.Err() != nil {
+			<-slots
+			break
+		}
 
-				// Skip test files as they often contain sample code that triggers false positives
-				// and typically don't run in production
-				if strings.Contains(path, "_test.go") ||
-					strings.Contains(path, "test_") ||
-					strings.Contains(path, "spec.") {
-					return nil
-				}
+		// Stop picking up new files once the time budget is spent and return what we have
+		// This bounds cost on very large repositories instead of failing at the activity timeout
+		if timeBudget > 0 && time.Since(scanStart) >= timeBudget {
+			<-slots
+			timeBudgetReached = true
+			log.Warn("Scan time budget reached, returning partial results",
+				zap.Duration("time_budget", timeBudget),
+				zap.Int("files_scanned", filesScanned),
+				zap.Int("files_skipped", len(filesToScan)-i))
+			break
+		}
 
-				// Add the file to our scan list
-				relPath, _ := filepath.Rel(repoDir, path)
-				log.Debug("Adding file to scan list", zap.String("file", relPath))
-				filesToScan = append(filesToScan, path)
-				break
-			}
+		// Likewise stop once the scan's estimated token or cost budget is spent, counting earlier batches
+		// Files already in flight still finish, so the budget can be overrun by up to `concurrency` files
+		tokensUsed := options.TokensUsed + int(stats.tokens.Load())
+		costUSD := EstimateCostUSD(bamlClient.Model(), tokensUsed)
+		if options.Budget.Exceeded(tokensUsed, costUSD) {
+			<-slots
+			budgetExceeded = true
+			log.Warn("Scan budget exceeded, returning partial results",
+				zap.Int("estimated_tokens", tokensUsed),
+				zap.Float64("estimated_cost_usd", costUSD),
+				zap.Int("max_total_tokens", options.Budget.MaxTotalTokens),
+				zap.Float64("max_estimated_cost_usd", options.Budget.MaxEstimatedCostUSD),
+				zap.Int("files_scanned", filesScanned),
+				zap.Int("files_skipped", len(filesToScan)-i))
+			break
 		}
+		log.Debug("Scan usage estimate",
+			zap.Int("estimated_tokens", tokensUsed),
+			zap.Float64("estimated_cost_usd", costUSD))
+		filesScanned++
 
-		// Limit the number of files to scan to prevent excessive scanning time
-		if options.MaxFiles > 0 && len(filesToScan) >= options.MaxFiles {
-			return filepath.SkipDir
+		relPath, err := filepath.Rel(repoDir, filePath)
+		if err != nil {
+			relPath = filePath
 		}
+		mu.Lock()
+		progress.CurrentFile = relPath
+		reportProgress()
+		mu.Unlock()
 
-		return nil
-	})
+		wg.Add(1)
+		go func(filePath string) {
+			defer wg.Done()
+			defer func() { <-slots }()
 
-	// Handle errors or empty file lists
-	if err != nil {
-		log.Error("Error walking repository directory", zap.Error(err))
-		// Continue with any files found instead of failing completely
-		if len(filesToScan) == 0 {
-			log.Warn("No files found to scan, checking if repository exists")
-			// Check if repo directory exists and has content
-			if _, statErr := os.Stat(repoDir); statErr != nil {
-				return nil, fmt.Errorf("repository directory not found or inaccessible: %w", statErr)
+			fileVulnerabilities, err := s.scanRepositoryFile(scanCtx, bamlClient, repoDir, filePath, vulnTypeStrings, options, &stats)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if scanErr == nil {
+					scanErr = err
+					cancelScan()
+				}
+				return
 			}
+			allVulnerabilities = append(allVulnerabilities, fileVulnerabilities...)
+			progress.Scanned++
+			reportProgress()
+		}(filePath)
+	}
+	wg.Wait()
 
-			// Directory exists but no matching files found
-			// Try with broader extensions as fallback to find something to scan
-			fallbackExts := []string{".txt", ".md", ".json", ".yml", ".yaml", ".xml"}
-			log.Info("Trying fallback file types", zap.Strings("extensions", fallbackExts))
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("scan aborted after %d files: %w", filesScanned, err)
+	}
 
-			filepath.Walk(repoDir, func(path string, info os.FileInfo, walkErr error) error {
-				if walkErr != nil || info.IsDir() {
-					return nil
-				}
-				ext := filepath.Ext(path)
-				for _, fbExt := range fallbackExts {
-					if ext == fbExt {
-						filesToScan = append(filesToScan, path)
-						break
-					}
-				}
-				return nil
-			})
+	// Workers finish in any order, so sort for results that are stable across runs
+	sort.SliceStable(allVulnerabilities, func(i, j int) bool {
+		if allVulnerabilities[i].FilePath != allVulnerabilities[j].FilePath {
+			return allVulnerabilities[i].FilePath < allVulnerabilities[j].FilePath
 		}
+		return allVulnerabilities[i].LineStart < allVulnerabilities[j].LineStart
+	})
+
+	tokensUsed := int(stats.tokens.Load())
+	costUSD := EstimateCostUSD(bamlClient.Model(), tokensUsed)
+	log.Info("Scan completed",
+		zap.String("scan_id", scanID),
+		zap.Int("vulnerability_count", len(allVulnerabilities)),
+		zap.Int64("cache_hits", stats.hits.Load()),
+		zap.Int64("cache_misses", stats.misses.Load()),
+		zap.Int64("duplicate_findings_merged", stats.duplicates.Load()),
+		zap.Int64("files_timed_out", stats.timeouts.Load()),
+		zap.Int64("files_too_large", stats.largeFiles.Load()),
+		zap.Int64("files_parse_failed", stats.parseFailures.Load()),
+		zap.Int64("files_model_failed", stats.modelFailures.Load()),
+		zap.Int("estimated_tokens", tokensUsed),
+		zap.Float64("estimated_cost_usd", costUSD),
+		zap.Int("scan_estimated_tokens", options.TokensUsed+tokensUsed))
+
+	// Normally, you would save the scan results to a database here
+
+	return &ScanResult{
+		RepositoryID:      repoDir,
+		Vulnerabilities:   allVulnerabilities,
+		ScanTime:          time.Now().Unix(),
+		FilesScanned:      filesScanned,
+		FilesSkipped:      len(filesToScan) - filesScanned,
+		FilesResumed:      filesResumed,
+		FilesRemaining:    filesRemaining,
+		TimeBudgetReached: timeBudgetReached,
+		BudgetExceeded:    budgetExceeded,
+		CacheHits:         int(stats.hits.Load()),
+		CacheMisses:       int(stats.misses.Load()),
+		DuplicatesMerged:  int(stats.duplicates.Load()),
+		FilesTimedOut:     int(stats.timeouts.Load()),
+		FilesTooLarge:     int(stats.largeFiles.Load()),
+		FilesParseFailed:  int(stats.parseFailures.Load()),
+		FilesModelFailed:  int(stats.modelFailures.Load()),
+		Truncated:         truncated,
+		TokensUsed:        tokensUsed,
+		EstimatedCostUSD:  costUSD,
+	}, nil
+}
+
+// scanStats counts scan cache lookups, estimated model tokens, merged duplicate findings, timed-out
+// model requests, files skipped for their size, unparseable model replies, and failed model requests
+// across the concurrent file workers
+type scanStats struct {
+	hits          atomic.Int64
+	misses        atomic.Int64
+	tokens        atomic.Int64
+	duplicates    atomic.Int64
+	timeouts      atomic.Int64
+	largeFiles    atomic.Int64
+	parseFailures atomic.Int64
+	modelFailures atomic.Int64
+}
+
+// scanWithModel returns the model's findings for a file, reusing a cached result when the
+// file, model, and requested types are unchanged; successful model results are cached for next time
+func scanWithModel(ctx context.Context, bamlClient *baml.CodeScannerClient, code, language, relPath string, vulnTypeStrings []string, options *ScanOptions, stats *scanStats) (*baml.CodeScanResult, error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
 	}
 
-	log.Info("Found files to scan", zap.Int("file_count", len(filesToScan)))
+	// Count the prompt against the scan's budget before it is sent, estimated from its length
+	estimatedTokens := int64(estimateScanTokens(code, language, relPath, vulnTypeStrings))
 
-	// Convert vulnerability types to strings for the BAML client
-	// BAML requires string input rather than our custom VulnerabilityType
-	var vulnTypeStrings []string
-	for _, vt := range options.VulnerabilityTypes {
-		vulnTypeStrings = append(vulnTypeStrings, string(vt))
+	if options.Cache == nil || options.DisableCache {
+		stats.tokens.Add(estimatedTokens)
+		return bamlClient.ScanCode(ctx, code, language, relPath, vulnTypeStrings)
 	}
 
-	// Scan each file and collect all vulnerabilities
-	var allVulnerabilities []*Vulnerability
+	key := ScanCacheKey(code, language, bamlClient.Model(), vulnTypeStrings)
+	cached, found, err := options.Cache.Get(ctx, key)
+	if err != nil {
+		// A broken cache must not fail the scan; fall back to asking the model
+		log.Warn("Failed to read scan cache", zap.String("file", relPath), zap.Error(err))
+	}
+	if found {
+		stats.hits.Add(1)
+		log.Debug("Reusing cached scan result", zap.String("file", relPath))
+		return cached, nil
+	}
+	stats.misses.Add(1)
 
-	for _, filePath := range filesToScan {
-		// Calculate the relative path from the repo root for better reporting
-		relPath, err := filepath.Rel(repoDir, filePath)
-		if err != nil {
-			log.Warn("Could not get relative path", zap.String("file", filePath), zap.Error(err))
-			relPath = filePath
-		}
+	stats.tokens.Add(estimatedTokens)
+	result, err := bamlClient.ScanCode(ctx, code, language, relPath, vulnTypeStrings)
+	if err != nil {
+		return nil, err
+	}
+	if err := options.Cache.Put(ctx, key, result); err != nil {
+		log.Warn("Failed to write scan cache", zap.String("file", relPath), zap.Error(err))
+	}
+	return result, nil
+}
 
-		log.Debug("Scanning file", zap.String("file", relPath))
+// scanRepositoryFile scans one file of a repository scan and records its progress
+// Unreadable files and failed model calls are logged and yield no findings, except that a model request
+// that timed out skips only the model: the file keeps its local detector findings and counts as scanned.
+// Files over the size limit are recorded as done with no findings without being read.
+// Only a failure to record progress is returned, since the scan can't resume correctly without it
+func (s *scannerService) scanRepositoryFile(ctx context.Context, bamlClient *baml.CodeScannerClient, repoDir, filePath string, vulnTypeStrings []string, options *ScanOptions, stats *scanStats) ([]*Vulnerability, error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
+	}
 
-		// Read the file content for analysis
-		codeBytes, err := ioutil.ReadFile(filePath)
-		if err != nil {
-			log.Warn("Failed to read file", zap.String("file", relPath), zap.Error(err))
-			continue
+	// Calculate the relative path from the repo root for better reporting
+	relPath, err := filepath.Rel(repoDir, filePath)
+	if err != nil {
+		log.Warn("Could not get relative path", zap.String("file", filePath), zap.Error(err))
+		relPath = filePath
+	}
+
+	log.Debug("Scanning file", zap.String("file", relPath))
+
+	// Check the size first so an oversized file is never loaded; a stat error is left to the read below
+	limit := maxFileSize(options.MaxFileSizeBytes)
+	if size, tooLarge, err := exceedsMaxFileSize(filePath, limit); err == nil && tooLarge {
+		stats.largeFiles.Add(1)
+		log.Info("File exceeds the maximum file size, skipping",
+			zap.String("file", relPath),
+			zap.Int64("size_bytes", size),
+			zap.Int64("max_file_size_bytes", limit))
+
+		// Record it as done so later batches and retries don't pick it up again
+		if options.OnFileScanned != nil {
+			if err := options.OnFileScanned(ctx, relPath, nil); err != nil {
+				return nil, fmt.Errorf("failed to record progress for %s: %w", relPath, err)
+			}
 		}
+		return nil, nil
+	}
 
-		code := string(codeBytes)
-		language := getLanguageFromExt(filepath.Ext(filePath))
+	// Read the file content for analysis
+	codeBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		log.Warn("Failed to read file", zap.String("file", relPath), zap.Error(err))
+		return nil, nil
+	}
 
-		// Use BAML client to scan the code
-		result, err := s.bamlClient.ScanCode(ctx, code, language, relPath, vulnTypeStrings)
-		if err != nil {
-			log.Warn("Failed to scan file with BAML", zap.String("file", relPath), zap.Error(err))
-			continue
+	// Normalize line endings and drop any BOM so reported line numbers match the editor's
+	code := NormalizeSource(string(codeBytes))
+	lines := SourceLines(code)
+	language := DetectLanguage(relPath, code)
+
+	// Local detectors never send code anywhere, so they run on every file
+	var fileVulnerabilities []*Vulnerability
+	for _, detect := range options.LocalDetectors {
+		fileVulnerabilities = append(fileVulnerabilities, detect(relPath, code)...)
+	}
+
+	// modelErr is set when the model request failed or its reply wasn't a readable result, so the file is
+	// errored rather than clean
+	var modelErr error
+	if MatchesAnyGlob(options.LLMDenylist, relPath) {
+		// Data-governance rule: denied files must never be transmitted to the external model
+		log.Info("File is on the LLM denylist, skipping model scan", zap.String("file", relPath))
+	} else {
+		// Use BAML client to scan the code, or the cached result of an identical earlier scan
+		result, err := scanWithModel(ctx, bamlClient, code, language, relPath, vulnTypeStrings, options, stats)
+		switch {
+		case errors.Is(err, baml.ErrRequestTimeout) && ctx.Err() == nil:
+			// One slow file must not hold up the scan; move on without the model's findings for it
+			stats.timeouts.Add(1)
+			log.Warn("Model request timed out, skipping the model scan of this file",
+				zap.String("file", relPath),
+				zap.Duration("request_timeout", bamlClient.RequestTimeout()),
+				zap.Error(err))
+			result = &baml.CodeScanResult{}
+		case errors.Is(err, baml.ErrInvalidScanResult):
+			// Keep the local detectors' findings, but don't let the file pass as having no model findings
+			stats.parseFailures.Add(1)
+			log.Warn("Model reply could not be parsed, marking the file as errored",
+				zap.String("file", relPath),
+				zap.Error(err))
+			modelErr = err
+			result = &baml.CodeScanResult{}
+		case err != nil && ctx.Err() != nil:
+			// The scan itself stopped; leave the file unrecorded so a resumed scan picks it up
+			return nil, nil
+		case err != nil:
+			// Keep the local detectors' findings, and record the file as errored so the scan doesn't look clean
+			stats.modelFailures.Add(1)
+			log.Warn("Failed to scan file with BAML, marking the file as errored",
+				zap.String("file", relPath),
+				zap.Error(err))
+			modelErr = err
+			result = &baml.CodeScanResult{}
 		}
 
 		// Convert BAML vulnerabilities to our format
 		for _, v := range result.Vulnerabilities {
 			vuln := &Vulnerability{
-				ID:          uuid.New().String(),
 				Type:        VulnerabilityType(v.VulnerabilityType),
 				FilePath:    relPath,
 				LineStart:   v.LineStart,
@@ -299,22 +1017,47 @@ func (s *scannerService) ScanRepository(ctx context.Context, repoDir string, opt
 				Description: v.Description,
 				Remediation: v.Remediation,
 				Code:        v.CodeSnippet,
+				Confidence:  v.Confidence.Value(),
+				CWE:         string(v.CWE),
+				References:  v.References,
 			}
-			allVulnerabilities = append(allVulnerabilities, vuln)
+			fileVulnerabilities = append(fileVulnerabilities, vuln)
 		}
 	}
 
-	log.Info("Scan completed",
-		zap.String("scan_id", scanID),
-		zap.Int("vulnerability_count", len(allVulnerabilities)))
+	// Assign IDs and apply path rules to findings from every source
+	normalizeSeverities(fileVulnerabilities)
+	fileVulnerabilities = filterBySeverity(fileVulnerabilities, options.MinSeverity)
+	for _, vuln := range fileVulnerabilities {
+		if vuln.ID == "" {
+			vuln.ID = uuid.New().String()
+		}
+		vuln.FilePath = relPath
+		vuln.Excluded = options.PathRules.Excluded(relPath)
+		alignFindingLines(vuln, lines)
+	}
 
-	// Normally, you would save the scan results to a database here
+	// The model, overlapping passes over a large file, and local detectors can all report one issue more than once
+	reported := len(fileVulnerabilities)
+	fileVulnerabilities = DedupeFindings(fileVulnerabilities, options.DedupLineTolerance)
+	if merged := reported - len(fileVulnerabilities); merged > 0 {
+		stats.duplicates.Add(int64(merged))
+		log.Debug("Merged duplicate findings", zap.String("file", relPath), zap.Int("merged", merged))
+	}
 
-	return &ScanResult{
-		RepositoryID:    repoDir,
-		Vulnerabilities: allVulnerabilities,
-		ScanTime:        time.Now().Unix(),
-	}, nil
+	// Persist this file's progress before it counts as done so a retry can skip it
+	if options.OnFileScanned != nil {
+		if err := options.OnFileScanned(ctx, relPath, fileVulnerabilities); err != nil {
+			return nil, fmt.Errorf("failed to record progress for %s: %w", relPath, err)
+		}
+	}
+	if modelErr != nil && options.OnFileFailed != nil {
+		if err := options.OnFileFailed(ctx, relPath, modelErr); err != nil {
+			return nil, fmt.Errorf("failed to record error for %s: %w", relPath, err)
+		}
+	}
+
+	return fileVulnerabilities, nil
 }
 
 // ScanFile performs a vulnerability scan on a single file
@@ -332,8 +1075,10 @@ func (s *scannerService) ScanFile(ctx context.Context, filePath string, options
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	code := string(codeBytes)
-	language := getLanguageFromExt(filepath.Ext(filePath))
+	// Normalize line endings and drop any BOM so reported line numbers match the editor's
+	code := NormalizeSource(string(codeBytes))
+	lines := SourceLines(code)
+	language := DetectLanguage(filePath, code)
 
 	// Convert vulnerability types to strings
 	var vulnTypeStrings []string
@@ -341,14 +1086,32 @@ func (s *scannerService) ScanFile(ctx context.Context, filePath string, options
 		vulnTypeStrings = append(vulnTypeStrings, string(vt))
 	}
 
+	// Run local detectors first; they don't send code anywhere
+	var vulnerabilities []*Vulnerability
+	for _, detect := range options.LocalDetectors {
+		for _, vuln := range detect(filePath, code) {
+			if vuln.ID == "" {
+				vuln.ID = uuid.New().String()
+			}
+			vuln.FilePath = filePath
+			alignFindingLines(vuln, lines)
+			vulnerabilities = append(vulnerabilities, vuln)
+		}
+	}
+
+	// Denied files must never be transmitted to the external model
+	if MatchesAnyGlob(options.LLMDenylist, filePath) {
+		log.Info("File is on the LLM denylist, skipping model scan", zap.String("file", filePath))
+		return vulnerabilities, nil
+	}
+
 	// Use BAML client to scan the code
-	result, err := s.bamlClient.ScanCode(ctx, code, language, filePath, vulnTypeStrings)
+	result, err := s.bamlClient.WithModel(options.Model).ScanCode(ctx, code, language, filePath, vulnTypeStrings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan file with BAML: %w", err)
 	}
 
 	// Convert BAML vulnerabilities to our format
-	var vulnerabilities []*Vulnerability
 	for _, v := range result.Vulnerabilities {
 		vuln := &Vulnerability{
 			ID:          uuid.New().String(),
@@ -360,33 +1123,65 @@ func (s *scannerService) ScanFile(ctx context.Context, filePath string, options
 			Description: v.Description,
 			Remediation: v.Remediation,
 			Code:        v.CodeSnippet,
+			Confidence:  v.Confidence.Value(),
+			CWE:         string(v.CWE),
+			References:  v.References,
 		}
+		alignFindingLines(vuln, lines)
 		vulnerabilities = append(vulnerabilities, vuln)
 	}
 
-	return vulnerabilities, nil
-}
-
-// Helper function to determine language from file extension
-func getLanguageFromExt(ext string) string {
-	switch ext {
-	case ".go":
-		return "Go"
-	case ".js", ".jsx":
-		return "JavaScript"
-	case ".ts", ".tsx":
-		return "TypeScript"
-	case ".py":
-		return "Python"
-	case ".java":
-		return "Java"
-	case ".php":
-		return "PHP"
-	case ".html":
-		return "HTML"
-	case ".css":
-		return "CSS"
-	default:
-		return "Unknown"
+	normalizeSeverities(vulnerabilities)
+	return filterBySeverity(vulnerabilities, options.MinSeverity), nil
+}
+
+// normalizeSeverities rewrites each finding's severity to its canonical label before it is filtered or stored
+func normalizeSeverities(vulnerabilities []*Vulnerability) {
+	for _, vuln := range vulnerabilities {
+		vuln.Severity = NormalizeSeverity(vuln.Severity)
+	}
+}
+
+// filterBySeverity drops findings ranked below minSeverity; findings with an unrecognized severity rank lowest
+// An empty minSeverity keeps every finding
+func filterBySeverity(vulnerabilities []*Vulnerability, minSeverity string) []*Vulnerability {
+	minRank := SeverityRank(minSeverity)
+	if minRank == 0 {
+		return vulnerabilities
+	}
+
+	kept := vulnerabilities[:0]
+	for _, vuln := range vulnerabilities {
+		if SeverityRank(vuln.Severity) >= minRank {
+			kept = append(kept, vuln)
+		}
+	}
+	return kept
+}
+
+// CountFindingsAtOrAbove counts the reported findings ranked at or above a severity
+// Excluded and baselined findings are not reported, so they never count
+func CountFindingsAtOrAbove(vulnerabilities []*Vulnerability, severity string) int {
+	minRank := SeverityRank(severity)
+	count := 0
+	for _, vuln := range vulnerabilities {
+		if !vuln.Excluded && !vuln.Baselined && SeverityRank(vuln.Severity) >= minRank {
+			count++
+		}
+	}
+	return count
+}
+
+// fileSelected reports whether a file passes the scan's file selection
+// With include globs the file must match one of them; otherwise its extension must be a target extension
+func fileSelected(options *ScanOptions, relPath, ext string) bool {
+	if len(options.IncludeGlobs) > 0 {
+		return MatchesAnyGlob(options.IncludeGlobs, relPath)
+	}
+	for _, targetExt := range options.FileExtensions {
+		if ext == targetExt {
+			return true
+		}
 	}
+	return false
 }
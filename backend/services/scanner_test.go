@@ -0,0 +1,847 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+)
+
+func TestNormalizeScanStatus(t *testing.T) {
+	tests := []struct {
+		status     string
+		want       string
+		wantActive bool
+	}{
+		{status: "pending", want: ScanStatusQueued, wantActive: true},
+		{status: "in_progress", want: ScanStatusScanning, wantActive: true},
+		{status: ScanStatusQueued, want: ScanStatusQueued, wantActive: true},
+		{status: ScanStatusCloning, want: ScanStatusCloning, wantActive: true},
+		{status: ScanStatusScanning, want: ScanStatusScanning, wantActive: true},
+		{status: ScanStatusCompleted, want: ScanStatusCompleted},
+		{status: ScanStatusFailed, want: ScanStatusFailed},
+		{status: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := NormalizeScanStatus(tt.status); got != tt.want {
+				t.Errorf("NormalizeScanStatus(%q) = %q, want %q", tt.status, got, tt.want)
+			}
+			if got := IsActiveScanStatus(tt.status); got != tt.wantActive {
+				t.Errorf("IsActiveScanStatus(%q) = %v, want %v", tt.status, got, tt.wantActive)
+			}
+		})
+	}
+}
+
+// writeFixtureTree creates the files at the given slash-separated paths under a temporary directory
+func writeFixtureTree(t *testing.T, paths []string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, path := range paths {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(full, []byte("package fixture\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	return root
+}
+
+// slowModelTransport answers every model request after a delay with one finding
+type slowModelTransport struct {
+	delay time.Duration
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (s *slowModelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requests++
+	s.mu.Unlock()
+
+	time.Sleep(s.delay)
+	body := `{"choices": [{"message": {"role": "assistant", "content": ` +
+		`"{\"vulnerabilities\": [{\"vulnerability_type\": \"Injection\", \"line_start\": 1, \"line_end\": 1, \"severity\": \"High\"}]}"}}]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// useModelTransport sends the model requests of clients created during the test to transport
+func useModelTransport(t *testing.T, transport http.RoundTripper) {
+	t.Helper()
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	previous := http.DefaultTransport
+	http.DefaultTransport = transport
+	t.Cleanup(func() { http.DefaultTransport = previous })
+}
+
+func TestScanRepositoryStopsAtTheTimeBudget(t *testing.T) {
+	paths := make([]string, 10)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file%02d.go", i)
+	}
+	root := writeFixtureTree(t, paths)
+
+	tests := []struct {
+		name        string
+		budget      time.Duration
+		wantReached bool
+	}{
+		{name: "no budget", wantReached: false},
+		{name: "budget longer than the scan", budget: time.Minute, wantReached: false},
+		{name: "budget shorter than the scan", budget: 120 * time.Millisecond, wantReached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &slowModelTransport{delay: 30 * time.Millisecond}
+			useModelTransport(t, transport)
+
+			result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+				VulnerabilityTypes: []VulnerabilityType{Injection},
+				FileExtensions:     []string{".go"},
+				Concurrency:        1, // One file at a time, so the budget runs out partway through
+				TimeBudget:         tt.budget,
+			})
+			if err != nil {
+				t.Fatalf("ScanRepository returned error: %v", err)
+			}
+
+			if result.TimeBudgetReached != tt.wantReached {
+				t.Errorf("TimeBudgetReached = %v, want %v", result.TimeBudgetReached, tt.wantReached)
+			}
+			if result.FilesScanned+result.FilesSkipped != len(paths) {
+				t.Errorf("%d files scanned and %d skipped, want %d in all", result.FilesScanned, result.FilesSkipped, len(paths))
+			}
+			if tt.wantReached && (result.FilesScanned == 0 || result.FilesSkipped == 0) {
+				t.Errorf("%d files scanned and %d skipped, want the scan cut short with partial results", result.FilesScanned, result.FilesSkipped)
+			}
+			if !tt.wantReached && result.FilesScanned != len(paths) {
+				t.Errorf("FilesScanned = %d, want all %d", result.FilesScanned, len(paths))
+			}
+			// Findings of the scanned files are kept, and skipped files are never sent to the model
+			if len(result.Vulnerabilities) != result.FilesScanned || transport.requests != result.FilesScanned {
+				t.Errorf("%d findings from %d model requests, want one of each per scanned file (%d)",
+					len(result.Vulnerabilities), transport.requests, result.FilesScanned)
+			}
+		})
+	}
+}
+
+func TestScanRepositorySkipsFilesWhoseModelRequestTimesOut(t *testing.T) {
+	root := writeFixtureTree(t, []string{"fast1.go", "slow.go", "fast2.go"})
+
+	// The fake model answers at once, except for slow.go, which it holds until the client gives up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "File path: slow.go") {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": `+
+			`"{\"vulnerabilities\": [{\"vulnerability_type\": \"Injection\", \"line_start\": 1, \"line_end\": 1, \"severity\": \"High\"}]}"}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+	t.Setenv("OPENAI_REQUEST_TIMEOUT", "100ms")
+
+	var mu sync.Mutex
+	recorded := map[string]int{}
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		Concurrency:        1,
+		LocalDetectors: []LocalDetector{func(relPath, code string) []*Vulnerability {
+			return []*Vulnerability{{Type: SecurityMisconfiguration, LineStart: 1, LineEnd: 1, Severity: "Low"}}
+		}},
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			mu.Lock()
+			defer mu.Unlock()
+			recorded[relPath] = len(vulnerabilities)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	if result.FilesTimedOut != 1 || result.FilesScanned != 3 {
+		t.Errorf("%d files timed out of %d scanned, want 1 of 3", result.FilesTimedOut, result.FilesScanned)
+	}
+	// The slow file still counts as done, with only its local detector finding; the others get both
+	want := map[string]int{"fast1.go": 2, "slow.go": 1, "fast2.go": 2}
+	for path, count := range want {
+		if recorded[path] != count {
+			t.Errorf("recorded %d findings for %s, want %d (all recorded: %v)", recorded[path], path, count, recorded)
+		}
+	}
+	if len(result.Vulnerabilities) != 5 {
+		t.Errorf("got %d findings, want 5", len(result.Vulnerabilities))
+	}
+}
+
+func TestScanRepositoryMarksFilesWithUnreadableRepliesAsErrored(t *testing.T) {
+	root := writeFixtureTree(t, []string{"broken.go", "clean.go"})
+
+	// The fake model answers clean.go with no findings, and everything else, reformat requests included,
+	// with a reply cut off mid-finding
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		content := `{\"vulnerabilities\": [{\"vulnerability_type\": \"Injection\", \"line_start\": 1,`
+		if strings.Contains(string(body), "File path: clean.go") {
+			content = `{\"vulnerabilities\": []}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "`+content+`"}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	var mu sync.Mutex
+	recorded := map[string]int{}
+	failed := map[string]error{}
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		Concurrency:        1,
+		LocalDetectors: []LocalDetector{func(relPath, code string) []*Vulnerability {
+			return []*Vulnerability{{Type: SecurityMisconfiguration, LineStart: 1, LineEnd: 1, Severity: "Low"}}
+		}},
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			mu.Lock()
+			defer mu.Unlock()
+			recorded[relPath] = len(vulnerabilities)
+			return nil
+		},
+		OnFileFailed: func(ctx context.Context, relPath string, cause error) error {
+			mu.Lock()
+			defer mu.Unlock()
+			failed[relPath] = cause
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	if result.FilesParseFailed != 1 || result.FilesScanned != 2 {
+		t.Errorf("%d files failed to parse of %d scanned, want 1 of 2", result.FilesParseFailed, result.FilesScanned)
+	}
+	// Both files keep their local detector finding, but only the unreadable one is marked as errored
+	if recorded["broken.go"] != 1 || recorded["clean.go"] != 1 {
+		t.Errorf("recorded findings = %v, want the local finding for each file", recorded)
+	}
+	if len(failed) != 1 || !errors.Is(failed["broken.go"], baml.ErrInvalidScanResult) {
+		t.Errorf("failed files = %v, want broken.go with ErrInvalidScanResult", failed)
+	}
+}
+
+func TestScanRepositoryStopsAtTheTokenBudget(t *testing.T) {
+	paths := make([]string, 10)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file%02d.go", i)
+	}
+	root := writeFixtureTree(t, paths)
+	perFile := estimateScanTokens("package fixture\n", getLanguageFromExt(".go"), "file00.go", []string{string(Injection)})
+
+	tests := []struct {
+		name             string
+		budget           ScanBudget
+		tokensUsed       int // Spent by earlier batches
+		wantScanned      int
+		wantBudgetExceed bool
+	}{
+		{name: "no budget", wantScanned: len(paths)},
+		{name: "budget for every file", budget: ScanBudget{MaxTotalTokens: 100 * perFile}, wantScanned: len(paths)},
+		{name: "budget for three files", budget: ScanBudget{MaxTotalTokens: 3 * perFile}, wantScanned: 3, wantBudgetExceed: true},
+		{name: "earlier batches spent most of it", budget: ScanBudget{MaxTotalTokens: 3 * perFile}, tokensUsed: 2 * perFile, wantScanned: 1, wantBudgetExceed: true},
+		{name: "cost budget", budget: ScanBudget{MaxEstimatedCostUSD: EstimateCostUSD("gpt-4o", 4*perFile)}, wantScanned: 4, wantBudgetExceed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &slowModelTransport{}
+			useModelTransport(t, transport)
+			t.Setenv("MODEL_COST_PER_1K_TOKENS", "")
+
+			result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+				VulnerabilityTypes: []VulnerabilityType{Injection},
+				FileExtensions:     []string{".go"},
+				Concurrency:        1, // One file at a time, so usage is counted before each file is dispatched
+				Model:              "gpt-4o",
+				Budget:             tt.budget,
+				TokensUsed:         tt.tokensUsed,
+			})
+			if err != nil {
+				t.Fatalf("ScanRepository returned error: %v", err)
+			}
+
+			if result.BudgetExceeded != tt.wantBudgetExceed {
+				t.Errorf("BudgetExceeded = %v, want %v", result.BudgetExceeded, tt.wantBudgetExceed)
+			}
+			if result.FilesScanned != tt.wantScanned || result.FilesSkipped != len(paths)-tt.wantScanned {
+				t.Errorf("%d files scanned and %d skipped, want %d scanned", result.FilesScanned, result.FilesSkipped, tt.wantScanned)
+			}
+			// Partial results are kept, and files past the budget are never sent to the model
+			if len(result.Vulnerabilities) != tt.wantScanned || transport.requests != tt.wantScanned {
+				t.Errorf("%d findings from %d model requests, want one of each per scanned file (%d)",
+					len(result.Vulnerabilities), transport.requests, tt.wantScanned)
+			}
+			if result.TokensUsed != tt.wantScanned*perFile {
+				t.Errorf("TokensUsed = %d, want %d for %d files", result.TokensUsed, tt.wantScanned*perFile, tt.wantScanned)
+			}
+		})
+	}
+}
+
+func TestSeverityRankOrdersWorstFirst(t *testing.T) {
+	// Worst first, as findings are listed
+	order := []string{"Critical", "High", "Medium", "Low", "Informational"}
+	for i := 1; i < len(order); i++ {
+		if SeverityRank(order[i-1]) <= SeverityRank(order[i]) {
+			t.Errorf("%s ranks %d, not above %s (%d)", order[i-1], SeverityRank(order[i-1]), order[i], SeverityRank(order[i]))
+		}
+	}
+
+	tests := []struct {
+		severity string
+		want     int
+	}{
+		{severity: "critical", want: 4},
+		{severity: " HIGH ", want: 3},
+		{severity: "Medium", want: 2},
+		{severity: "low", want: 1},
+		{severity: "", want: 0},
+	}
+	for _, tt := range tests {
+		if got := SeverityRank(tt.severity); got != tt.want {
+			t.Errorf("SeverityRank(%q) = %d, want %d", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestScanRepositoryResumesFromRecordedFiles(t *testing.T) {
+	paths := make([]string, 6)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file%02d.go", i)
+	}
+	root := writeFixtureTree(t, paths)
+	transport := &slowModelTransport{}
+	useModelTransport(t, transport)
+	scanner := NewScannerService(nil)
+
+	// The first attempt is interrupted while recording its third file, after two were recorded
+	var mu sync.Mutex
+	recorded := map[string]bool{}
+	interrupted := errors.New("worker lost")
+	_, err := scanner.ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(recorded) == 2 {
+				return interrupted
+			}
+			recorded[relPath] = true
+			return nil
+		},
+	})
+	if !errors.Is(err, interrupted) {
+		t.Fatalf("first attempt error = %v, want the interruption", err)
+	}
+	if len(recorded) != 2 {
+		t.Fatalf("first attempt recorded %d files, want 2", len(recorded))
+	}
+
+	// The retry skips exactly the recorded files and scans every other one
+	transport.requests = 0
+	var rescanned []string
+	result, err := scanner.ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		CompletedFiles:     recorded,
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			mu.Lock()
+			defer mu.Unlock()
+			rescanned = append(rescanned, relPath)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("retry returned error: %v", err)
+	}
+	if result.FilesResumed != len(recorded) {
+		t.Errorf("FilesResumed = %d, want %d", result.FilesResumed, len(recorded))
+	}
+	if len(rescanned) != len(paths)-len(recorded) || transport.requests != len(rescanned) {
+		t.Errorf("retry scanned %v with %d model requests, want the %d unrecorded files", rescanned, transport.requests, len(paths)-len(recorded))
+	}
+	for _, path := range rescanned {
+		if recorded[path] {
+			t.Errorf("retry rescanned %s, which the first attempt recorded", path)
+		}
+	}
+}
+
+func TestScanRepositoryIncludeGlobs(t *testing.T) {
+	root := writeFixtureTree(t, []string{"main.go", "handlers/user.go", "handlers/admin.py", "db/schema.sql", "db/query.go"})
+
+	tests := []struct {
+		name  string
+		globs []string
+		want  []string
+	}{
+		{name: "no globs scans by extension", want: []string{"db/query.go", "handlers/admin.py", "handlers/user.go", "main.go"}},
+		{name: "directory glob", globs: []string{"handlers/**"}, want: []string{"handlers/admin.py", "handlers/user.go"}},
+		{name: "glob reaches other extensions", globs: []string{"**/*.sql"}, want: []string{"db/schema.sql"}},
+		{name: "any glob may match", globs: []string{"main.go", "db/*.go"}, want: []string{"db/query.go", "main.go"}},
+		{name: "nothing matches", globs: []string{"docs/**"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useModelTransport(t, &slowModelTransport{})
+
+			result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+				VulnerabilityTypes: []VulnerabilityType{Injection},
+				FileExtensions:     []string{".go", ".py"},
+				IncludeGlobs:       tt.globs,
+			})
+			if err != nil {
+				t.Fatalf("ScanRepository returned error: %v", err)
+			}
+
+			// The stubbed model reports one finding per file, so the findings name the files scanned
+			var scanned []string
+			for _, vuln := range result.Vulnerabilities {
+				scanned = append(scanned, vuln.FilePath)
+			}
+			sort.Strings(scanned)
+			if strings.Join(scanned, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("scanned %v, want %v", scanned, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterBySeverity(t *testing.T) {
+	findings := func() []*Vulnerability {
+		return []*Vulnerability{
+			{Description: "critical", Severity: "Critical"},
+			{Description: "high", Severity: "High"},
+			{Description: "medium", Severity: "Medium"},
+			{Description: "low", Severity: "Low"},
+			{Description: "unrated", Severity: "Unknown"},
+		}
+	}
+
+	tests := []struct {
+		minSeverity string
+		want        []string
+	}{
+		{minSeverity: "", want: []string{"critical", "high", "medium", "low", "unrated"}},
+		{minSeverity: "low", want: []string{"critical", "high", "medium", "low"}},
+		{minSeverity: "High", want: []string{"critical", "high"}},
+		{minSeverity: "critical", want: []string{"critical"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, vuln := range filterBySeverity(findings(), tt.minSeverity) {
+			got = append(got, vuln.Description)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("filterBySeverity(%q) kept %v, want %v", tt.minSeverity, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	for severity, want := range map[string]string{
+		"Critical":   "Critical",
+		"CRITICAL":   "Critical",
+		" high ":     "High",
+		"moderate":   "Medium",
+		"medium":     "Medium",
+		"low":        "Low",
+		" Unknown ":  "Unknown",
+		"negligible": "negligible",
+	} {
+		if got := NormalizeSeverity(severity); got != want {
+			t.Errorf("NormalizeSeverity(%q) = %q, want %q", severity, got, want)
+		}
+	}
+}
+
+func TestCountFindingsAtOrAbove(t *testing.T) {
+	findings := []*Vulnerability{
+		{Severity: "Critical"},
+		{Severity: "High"},
+		{Severity: "High", Excluded: true},
+		{Severity: "Critical", Baselined: true},
+		{Severity: "Medium"},
+		{Severity: "Low"},
+		{Severity: "Unknown"},
+	}
+
+	for failOn, want := range map[string]int{"critical": 1, "High": 2, "medium": 3, "low": 4} {
+		if got := CountFindingsAtOrAbove(findings, failOn); got != want {
+			t.Errorf("CountFindingsAtOrAbove(%q) = %d, want %d", failOn, got, want)
+		}
+	}
+}
+
+func TestIsKnownVulnerabilityType(t *testing.T) {
+	for _, vulnType := range AllVulnerabilityTypes {
+		if !IsKnownVulnerabilityType(string(vulnType)) {
+			t.Errorf("IsKnownVulnerabilityType(%q) = false", vulnType)
+		}
+	}
+	for _, name := range []string{"", "injection", "Buffer Overflow"} {
+		if IsKnownVulnerabilityType(name) {
+			t.Errorf("IsKnownVulnerabilityType(%q) = true", name)
+		}
+	}
+}
+
+func TestScanRepositoryScansOneBatchAtATime(t *testing.T) {
+	paths := make([]string, 7)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file%02d.go", i)
+	}
+	root := writeFixtureTree(t, paths)
+	useModelTransport(t, &slowModelTransport{})
+	scanner := NewScannerService(nil)
+
+	// Each call scans the next three unrecorded files until none remain
+	recorded := map[string]bool{}
+	var mu sync.Mutex
+	var remaining []int
+	for calls := 0; calls < 5; calls++ {
+		completed := make(map[string]bool, len(recorded))
+		for path := range recorded {
+			completed[path] = true
+		}
+		result, err := scanner.ScanRepository(context.Background(), root, &ScanOptions{
+			VulnerabilityTypes: []VulnerabilityType{Injection},
+			FileExtensions:     []string{".go"},
+			BatchSize:          3,
+			CompletedFiles:     completed,
+			OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if recorded[relPath] {
+					t.Errorf("%s was scanned by more than one batch", relPath)
+				}
+				recorded[relPath] = true
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("batch %d returned error: %v", calls, err)
+		}
+		remaining = append(remaining, result.FilesRemaining)
+		if result.FilesRemaining == 0 {
+			break
+		}
+	}
+
+	if want := []int{4, 1, 0}; fmt.Sprint(remaining) != fmt.Sprint(want) {
+		t.Errorf("files remaining after each batch = %v, want %v", remaining, want)
+	}
+	if len(recorded) != len(paths) {
+		t.Errorf("batches scanned %d files, want all %d", len(recorded), len(paths))
+	}
+}
+
+func TestScanRepositoryReenablesSkippedDirs(t *testing.T) {
+	root := writeFixtureTree(t, []string{
+		"main.go",
+		"vendor/example.com/internal/auth.go",
+		"lib/util.go",
+		"node_modules/pkg/index.js",
+		".git/hooks/pre-commit.go",
+	})
+
+	tests := []struct {
+		name        string
+		vendored    bool
+		skippedDirs []string
+		want        []string
+	}{
+		{name: "defaults skip dependency directories", want: []string{"main.go"}},
+		{name: "vendored first-party code", vendored: true, want: []string{"main.go", "vendor/example.com/internal/auth.go"}},
+		{name: "named skipped directory", skippedDirs: []string{"lib"}, want: []string{"lib/util.go", "main.go"}},
+		{name: "git metadata stays skipped", skippedDirs: []string{".git"}, want: []string{"main.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useModelTransport(t, &slowModelTransport{})
+
+			result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+				VulnerabilityTypes: []VulnerabilityType{Injection},
+				FileExtensions:     []string{".go", ".js"},
+				ScanVendored:       tt.vendored,
+				ScanSkippedDirs:    tt.skippedDirs,
+			})
+			if err != nil {
+				t.Fatalf("ScanRepository returned error: %v", err)
+			}
+
+			var scanned []string
+			for _, vuln := range result.Vulnerabilities {
+				scanned = append(scanned, vuln.FilePath)
+			}
+			sort.Strings(scanned)
+			if strings.Join(scanned, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("scanned %v, want %v", scanned, tt.want)
+			}
+		})
+	}
+}
+
+// concurrencyTransport answers model requests after a delay while tracking how many are in flight
+type concurrencyTransport struct {
+	delay time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	requests    int
+}
+
+func (c *concurrencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.requests++
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+
+	// Two findings per file, listed out of line order
+	body := `{"choices": [{"message": {"role": "assistant", "content": ` +
+		`"{\"vulnerabilities\": [{\"vulnerability_type\": \"Injection\", \"line_start\": 2, \"line_end\": 2, \"severity\": \"High\"}, ` +
+		`{\"vulnerability_type\": \"Injection\", \"line_start\": 1, \"line_end\": 1, \"severity\": \"High\"}]}"}}]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestScanRepositoryScansFilesConcurrently(t *testing.T) {
+	paths := make([]string, 12)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("pkg%d/file%02d.go", i%3, i)
+	}
+	root := writeFixtureTree(t, paths)
+
+	scan := func() ([]string, *concurrencyTransport) {
+		t.Helper()
+		transport := &concurrencyTransport{delay: 20 * time.Millisecond}
+		useModelTransport(t, transport)
+
+		result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+			VulnerabilityTypes: []VulnerabilityType{Injection},
+			FileExtensions:     []string{".go"},
+			Concurrency:        3,
+		})
+		if err != nil {
+			t.Fatalf("ScanRepository returned error: %v", err)
+		}
+		if result.FilesScanned != len(paths) {
+			t.Errorf("FilesScanned = %d, want %d", result.FilesScanned, len(paths))
+		}
+
+		var order []string
+		for _, vuln := range result.Vulnerabilities {
+			order = append(order, fmt.Sprintf("%s:%d", vuln.FilePath, vuln.LineStart))
+		}
+		return order, transport
+	}
+
+	first, transport := scan()
+	if transport.requests != len(paths) {
+		t.Errorf("sent %d model requests, want one per file (%d)", transport.requests, len(paths))
+	}
+	if transport.maxInFlight < 2 || transport.maxInFlight > 3 {
+		t.Errorf("at most %d requests were in flight, want between 2 and the concurrency of 3", transport.maxInFlight)
+	}
+
+	// Results are sorted by file and line, whatever order the workers finished in
+	if len(first) != 2*len(paths) || !sort.SliceIsSorted(first, func(i, j int) bool { return first[i] < first[j] }) {
+		t.Errorf("findings = %v, want two per file sorted by path and line", first)
+	}
+	second, _ := scan()
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("findings differ between runs:\n%v\n%v", first, second)
+	}
+}
+
+func TestScanRepositoryStopsDispatchingWhenCanceled(t *testing.T) {
+	paths := make([]string, 20)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file%02d.go", i)
+	}
+	root := writeFixtureTree(t, paths)
+	transport := &concurrencyTransport{delay: 20 * time.Millisecond}
+	useModelTransport(t, transport)
+
+	// Cancel once the first file is recorded
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := NewScannerService(nil).ScanRepository(ctx, root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		Concurrency:        2,
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			cancel()
+			return nil
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScanRepository error = %v, want context.Canceled", err)
+	}
+	if transport.requests >= len(paths) {
+		t.Errorf("sent %d model requests after the scan was canceled, want new files no longer dispatched", transport.requests)
+	}
+}
+
+func TestScanRepositoryReportsProgress(t *testing.T) {
+	paths := []string{"a.go", "b.go", "c.go", "d.go", "e.go"}
+	root := writeFixtureTree(t, paths)
+	useModelTransport(t, &slowModelTransport{})
+
+	// One file finished in an earlier batch and the batch leaves one for later, so progress spans all five
+	var reports []ScanProgress
+	_, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		BatchSize:          3,
+		Concurrency:        2,
+		CompletedFiles:     map[string]bool{"a.go": true},
+		OnProgress: func(progress ScanProgress) {
+			reports = append(reports, progress)
+		},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	if len(reports) == 0 || reports[0] != (ScanProgress{Scanned: 1, Total: 5}) {
+		t.Fatalf("first report = %+v, want 1 of 5 before any file starts", reports)
+	}
+	started := map[string]bool{}
+	for i, progress := range reports {
+		if progress.Total != 5 {
+			t.Errorf("report %d total = %d, want 5", i, progress.Total)
+		}
+		if i > 0 && progress.Scanned < reports[i-1].Scanned {
+			t.Errorf("scanned went back from %d to %d", reports[i-1].Scanned, progress.Scanned)
+		}
+		if progress.CurrentFile != "" {
+			started[progress.CurrentFile] = true
+		}
+	}
+	if last := reports[len(reports)-1]; last.Scanned != 4 {
+		t.Errorf("last report scanned = %d, want 4", last.Scanned)
+	}
+	if len(started) != 3 || started["a.go"] {
+		t.Errorf("files reported as current = %v, want the batch's three", started)
+	}
+}
+
+func TestScanRepositoryMarksFilesWhoseModelRequestFailedAsErrored(t *testing.T) {
+	root := writeFixtureTree(t, []string{"rejected.go", "clean.go"})
+
+	// The fake model answers clean.go with no findings and rejects the request for every other file
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "File path: clean.go") {
+			http.Error(w, `{"error": {"message": "invalid request"}}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "{\"vulnerabilities\": []}"}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	var mu sync.Mutex
+	recorded := map[string]int{}
+	failed := map[string]error{}
+	result, err := NewScannerService(nil).ScanRepository(context.Background(), root, &ScanOptions{
+		VulnerabilityTypes: []VulnerabilityType{Injection},
+		FileExtensions:     []string{".go"},
+		Concurrency:        1,
+		LocalDetectors: []LocalDetector{func(relPath, code string) []*Vulnerability {
+			return []*Vulnerability{{Type: SecurityMisconfiguration, LineStart: 1, LineEnd: 1, Severity: "Low"}}
+		}},
+		OnFileScanned: func(ctx context.Context, relPath string, vulnerabilities []*Vulnerability) error {
+			mu.Lock()
+			defer mu.Unlock()
+			recorded[relPath] = len(vulnerabilities)
+			return nil
+		},
+		OnFileFailed: func(ctx context.Context, relPath string, cause error) error {
+			mu.Lock()
+			defer mu.Unlock()
+			failed[relPath] = cause
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ScanRepository returned error: %v", err)
+	}
+
+	if result.FilesModelFailed != 1 || result.FilesScanned != 2 {
+		t.Errorf("%d files failed at the model of %d scanned, want 1 of 2", result.FilesModelFailed, result.FilesScanned)
+	}
+	// The rejected file keeps its local detector finding instead of being dropped, and is marked as errored
+	if recorded["rejected.go"] != 1 || recorded["clean.go"] != 1 {
+		t.Errorf("recorded findings = %v, want the local finding for each file", recorded)
+	}
+	if len(failed) != 1 || failed["rejected.go"] == nil {
+		t.Errorf("failed files = %v, want rejected.go", failed)
+	}
+	if len(result.Vulnerabilities) != 2 {
+		t.Errorf("got %d findings, want the 2 local ones", len(result.Vulnerabilities))
+	}
+}
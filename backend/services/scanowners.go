@@ -0,0 +1,55 @@
+package services
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// ScanOwnerAllowlist restricts the public scan endpoint to repositories of listed owners or organizations
+// A nil allowlist allows every owner
+type ScanOwnerAllowlist struct {
+	patterns []string // Lowercased owner names or globs such as "acme-*"
+}
+
+// ScanOwnerAllowlistFromEnv reads the comma-separated owners from ALLOWED_SCAN_OWNERS
+// It returns nil when the variable is unset or lists no owners, so public scans stay unrestricted
+func ScanOwnerAllowlistFromEnv() *ScanOwnerAllowlist {
+	return NewScanOwnerAllowlist(splitGlobList(os.Getenv("ALLOWED_SCAN_OWNERS")))
+}
+
+// NewScanOwnerAllowlist builds an allowlist of owner names and globs, or nil when there are none
+// Globs use "*", "?", and "[...]" as in path.Match; a pattern that isn't a valid glob matches nothing
+func NewScanOwnerAllowlist(patterns []string) *ScanOwnerAllowlist {
+	if len(patterns) == 0 {
+		return nil
+	}
+	allowlist := &ScanOwnerAllowlist{patterns: make([]string, len(patterns))}
+	for i, pattern := range patterns {
+		allowlist.patterns[i] = strings.ToLower(strings.Trim(strings.TrimSpace(pattern), "/"))
+	}
+	return allowlist
+}
+
+// Allows reports whether repositories of the owner may be scanned. Matching is case-insensitive,
+// and a GitLab subgroup such as "acme/platform" is allowed when its top-level group is
+func (a *ScanOwnerAllowlist) Allows(owner string) bool {
+	if a == nil {
+		return true
+	}
+
+	owner = strings.ToLower(strings.Trim(owner, "/"))
+	if owner == "" {
+		return false
+	}
+	group, _, _ := strings.Cut(owner, "/")
+	for _, pattern := range a.patterns {
+		if matched, err := path.Match(pattern, owner); err == nil && matched {
+			return true
+		}
+		if matched, err := path.Match(pattern, group); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
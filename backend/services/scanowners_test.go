@@ -0,0 +1,42 @@
+package services
+
+import "testing"
+
+func TestScanOwnerAllowlist(t *testing.T) {
+	allowlist := NewScanOwnerAllowlist([]string{"acme", "Widgets-*", "team-?", "[bad"})
+
+	tests := map[string]bool{
+		"acme":              true,
+		"ACME":              true,
+		"acme-labs":         false,
+		"widgets-internal":  true,
+		"WIDGETS-Platform":  true,
+		"widgets":           false,
+		"team-a":            true,
+		"team-ab":           false,
+		"acme/platform":     true, // GitLab subgroup of an allowed group
+		"widgets-x/sub/sub": true,
+		"other/acme":        false,
+		"octocat":           false,
+		"[bad":              false, // Invalid globs match nothing, not even themselves
+		"":                  false,
+	}
+	for owner, want := range tests {
+		if got := allowlist.Allows(owner); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", owner, got, want)
+		}
+	}
+}
+
+func TestScanOwnerAllowlistFromEnv(t *testing.T) {
+	t.Setenv("ALLOWED_SCAN_OWNERS", "")
+	if allowlist := ScanOwnerAllowlistFromEnv(); allowlist != nil || !allowlist.Allows("anyone") {
+		t.Error("an empty ALLOWED_SCAN_OWNERS restricts scans")
+	}
+
+	t.Setenv("ALLOWED_SCAN_OWNERS", " acme , ,example-* ")
+	allowlist := ScanOwnerAllowlistFromEnv()
+	if !allowlist.Allows("acme") || !allowlist.Allows("example-org") || allowlist.Allows("octocat") {
+		t.Errorf("allowlist from %q = %+v", "acme, example-*", allowlist)
+	}
+}
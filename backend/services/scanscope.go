@@ -0,0 +1,80 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// ScanScope holds the options that decide which files a scan covers and which of their findings it keeps
+// One scan's findings can only stand in for another's when their scopes match, so incremental scans compare
+// the scope stored with their base scan before carrying its findings forward
+type ScanScope struct {
+	FileExtensions  []string `json:"file_extensions"`
+	IncludeGlobs    []string `json:"include_globs,omitempty"`
+	ScanVendored    bool     `json:"scan_vendored,omitempty"`
+	ScanSkippedDirs []string `json:"scan_skipped_dirs,omitempty"`
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+	IncludePatterns []string `json:"include_patterns,omitempty"`
+	Taxonomy        string   `json:"taxonomy"`
+	VulnTypes       []string `json:"vuln_types"`
+	MinSeverity     string   `json:"min_severity,omitempty"`
+}
+
+// DefaultScanScope returns the scope of a scan started without options: the default extensions and skipped
+// directories, no globs or patterns, every category of the default taxonomy, and no severity threshold
+func DefaultScanScope() ScanScope {
+	return ScanScope{FileExtensions: DefaultFileExtensions()}
+}
+
+// Key returns the scope's canonical form, stored in scans.scan_scope
+// Scopes that select the same files and findings have the same key, however their options were ordered
+func (s ScanScope) Key() string {
+	key, _ := json.Marshal(s.normalized()) // Only strings and bools, which always encode
+	return string(key)
+}
+
+// IsDefault reports whether the scope is DefaultScanScope, so the scan covered everything a plain scan would
+func (s ScanScope) IsDefault() bool {
+	return s.Key() == DefaultScanScope().Key()
+}
+
+// normalized returns the scope with its lists sorted and deduplicated, and the taxonomy and categories the
+// scan activity resolves: an unregistered taxonomy falls back to the default, and no categories means all of them
+func (s ScanScope) normalized() ScanScope {
+	taxonomy := TaxonomyOrDefault(s.Taxonomy)
+	vulnTypes := s.VulnTypes
+	if len(vulnTypes) == 0 {
+		vulnTypes = taxonomy.CategoryNames()
+	}
+
+	return ScanScope{
+		FileExtensions:  sortedUnique(s.FileExtensions, strings.ToLower),
+		IncludeGlobs:    sortedUnique(s.IncludeGlobs, nil),
+		ScanVendored:    s.ScanVendored,
+		ScanSkippedDirs: sortedUnique(s.ScanSkippedDirs, nil),
+		ExcludePatterns: sortedUnique(s.ExcludePatterns, nil),
+		IncludePatterns: sortedUnique(s.IncludePatterns, nil),
+		Taxonomy:        taxonomy.Name,
+		VulnTypes:       sortedUnique(vulnTypes, nil),
+		MinSeverity:     strings.ToLower(strings.TrimSpace(s.MinSeverity)),
+	}
+}
+
+// sortedUnique returns the values, each passed through normalize when it is set, sorted without repeats
+// An empty list returns nil
+func sortedUnique(values []string, normalize func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if normalize != nil {
+			value = normalize(value)
+		}
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
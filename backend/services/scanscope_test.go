@@ -0,0 +1,72 @@
+package services
+
+import "testing"
+
+func TestScanScopeKey(t *testing.T) {
+	base := ScanScope{
+		FileExtensions: []string{".go", ".js"},
+		IncludeGlobs:   []string{"api/**", "web/**"},
+		Taxonomy:       DefaultTaxonomyName,
+		VulnTypes:      []string{string(Injection), string(BrokenAccessControl)},
+	}
+
+	tests := []struct {
+		name     string
+		scope    ScanScope
+		wantSame bool
+	}{
+		{name: "same options", scope: base, wantSame: true},
+		{name: "reordered and repeated", wantSame: true, scope: ScanScope{
+			FileExtensions: []string{".JS", ".go", ".go"},
+			IncludeGlobs:   []string{"web/**", "api/**"},
+			Taxonomy:       DefaultTaxonomyName,
+			VulnTypes:      []string{string(BrokenAccessControl), string(Injection)},
+		}},
+		{name: "other extensions", scope: ScanScope{
+			FileExtensions: []string{".go"},
+			IncludeGlobs:   base.IncludeGlobs,
+			Taxonomy:       base.Taxonomy,
+			VulnTypes:      base.VulnTypes,
+		}},
+		{name: "vendored code", scope: ScanScope{
+			FileExtensions: base.FileExtensions,
+			IncludeGlobs:   base.IncludeGlobs,
+			ScanVendored:   true,
+			Taxonomy:       base.Taxonomy,
+			VulnTypes:      base.VulnTypes,
+		}},
+		{name: "severity threshold", scope: ScanScope{
+			FileExtensions: base.FileExtensions,
+			IncludeGlobs:   base.IncludeGlobs,
+			Taxonomy:       base.Taxonomy,
+			VulnTypes:      base.VulnTypes,
+			MinSeverity:    "high",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := tt.scope.Key() == base.Key(); same != tt.wantSame {
+				t.Errorf("key %s matches %s = %v, want %v", tt.scope.Key(), base.Key(), same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestScanScopeIsDefault(t *testing.T) {
+	// Naming the default taxonomy and all of its categories is the same scope as leaving them out
+	explicit := ScanScope{
+		FileExtensions: DefaultFileExtensions(),
+		Taxonomy:       OWASP2021Taxonomy.Name,
+		VulnTypes:      OWASP2021Taxonomy.CategoryNames(),
+	}
+	if !explicit.IsDefault() {
+		t.Errorf("scope %s is not the default %s", explicit.Key(), DefaultScanScope().Key())
+	}
+
+	narrowed := DefaultScanScope()
+	narrowed.IncludeGlobs = []string{"handlers/**"}
+	if narrowed.IsDefault() {
+		t.Error("a scope limited by include globs is the default")
+	}
+}
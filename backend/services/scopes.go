@@ -0,0 +1,38 @@
+package services
+
+// Authorization scopes limit what a credential may do
+// Browser sessions (JWTs) carry every scope; API keys carry only the scopes they were issued with
+const (
+	ScopeAll        = "*"           // Every scope; granted to JWT sessions
+	ScopeRepoRead   = "repo:read"   // List repositories and read scan results
+	ScopeRepoWrite  = "repo:write"  // Add repositories
+	ScopeRepoDelete = "repo:delete" // Remove repositories
+	ScopeScanWrite  = "scan:write"  // Start scans
+	ScopeAdmin      = "admin"       // Admin endpoints; the user must also have the admin role
+)
+
+// KnownScopes lists every scope a credential may be issued with
+var KnownScopes = []string{ScopeRepoRead, ScopeRepoWrite, ScopeRepoDelete, ScopeScanWrite, ScopeAdmin}
+
+// DefaultAPIKeyScopes is the minimal scope set given to an API key when none are requested
+var DefaultAPIKeyScopes = []string{ScopeRepoRead}
+
+// IsKnownScope reports whether the scope can be granted to a credential
+func IsKnownScope(scope string) bool {
+	for _, known := range KnownScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the granted scopes include the required one
+func HasScope(granted []string, required string) bool {
+	for _, scope := range granted {
+		if scope == ScopeAll || scope == required {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package services
+
+import "testing"
+
+func TestDefaultAPIKeyScopesAreMinimal(t *testing.T) {
+	for _, scope := range DefaultAPIKeyScopes {
+		if !IsKnownScope(scope) {
+			t.Errorf("default scope %q is not a known scope", scope)
+		}
+	}
+	if HasScope(DefaultAPIKeyScopes, ScopeScanWrite) || HasScope(DefaultAPIKeyScopes, ScopeRepoDelete) || HasScope(DefaultAPIKeyScopes, ScopeAdmin) {
+		t.Errorf("default key scopes %q grant more than read access", DefaultAPIKeyScopes)
+	}
+	if IsKnownScope(ScopeAll) {
+		t.Error("the wildcard scope must not be grantable to a key")
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import "strings"
+
+// utf8BOM is the byte order mark some editors write at the start of UTF-8 files
+const utf8BOM = "\xef\xbb\xbf"
+
+// NormalizeSource strips a leading UTF-8 BOM and converts CRLF and lone CR line endings to LF
+// Scanning and snippet extraction both use the normalized text, so line numbers match what editors show
+func NormalizeSource(code string) string {
+	code = strings.TrimPrefix(code, utf8BOM)
+	if !strings.Contains(code, "\r") {
+		return code
+	}
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	return strings.ReplaceAll(code, "\r", "\n")
+}
+
+// SourceLines splits normalized source into lines; line N of the file is element N-1
+func SourceLines(code string) []string {
+	return strings.Split(NormalizeSource(code), "\n")
+}
+
+// alignFindingLines keeps a model-reported finding inside the file's real line range
+// Out-of-range or inverted line numbers are clamped, and a missing snippet is filled from the source
+func alignFindingLines(vuln *Vulnerability, lines []string) {
+	lineCount := len(lines)
+	if lineCount == 0 {
+		return
+	}
+
+	vuln.LineStart = clampLine(vuln.LineStart, lineCount)
+	vuln.LineEnd = clampLine(vuln.LineEnd, lineCount)
+	if vuln.LineEnd < vuln.LineStart {
+		vuln.LineEnd = vuln.LineStart
+	}
+
+	if strings.TrimSpace(vuln.Code) == "" {
+		vuln.Code = strings.Join(lines[vuln.LineStart-1:vuln.LineEnd], "\n")
+	}
+}
+
+// clampLine limits a 1-based line number to [1, lineCount]
+func clampLine(line, lineCount int) int {
+	if line < 1 {
+		return 1
+	}
+	if line > lineCount {
+		return lineCount
+	}
+	return line
+}
@@ -0,0 +1,69 @@
+package services
+
+import "testing"
+
+func TestSourceLinesWithBOMAndMixedLineEndings(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{name: "LF", code: "package main\nimport \"os\"\nfunc run() { os.Exit(1) }\n"},
+		{name: "CRLF", code: "package main\r\nimport \"os\"\r\nfunc run() { os.Exit(1) }\r\n"},
+		{name: "lone CR", code: "package main\rimport \"os\"\rfunc run() { os.Exit(1) }\r"},
+		{name: "BOM and CRLF", code: "\xef\xbb\xbfpackage main\r\nimport \"os\"\r\nfunc run() { os.Exit(1) }\r\n"},
+		{name: "BOM and mixed", code: "\xef\xbb\xbfpackage main\nimport \"os\"\r\nfunc run() { os.Exit(1) }\r"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := SourceLines(tt.code)
+			if lines[0] != "package main" {
+				t.Errorf("line 1 = %q, want it without a BOM or carriage return", lines[0])
+			}
+			if lines[2] != "func run() { os.Exit(1) }" {
+				t.Errorf("line 3 = %q", lines[2])
+			}
+
+			// A finding on line 3 without a snippet gets exactly that line
+			vuln := &Vulnerability{LineStart: 3, LineEnd: 3}
+			alignFindingLines(vuln, lines)
+			if vuln.Code != "func run() { os.Exit(1) }" {
+				t.Errorf("snippet = %q, want line 3", vuln.Code)
+			}
+		})
+	}
+}
+
+func TestAlignFindingLinesClampsModelLineNumbers(t *testing.T) {
+	lines := SourceLines("a\nb\nc")
+
+	tests := []struct {
+		name               string
+		start, end         int
+		wantStart, wantEnd int
+		wantCode           string
+	}{
+		{name: "in range", start: 2, end: 3, wantStart: 2, wantEnd: 3, wantCode: "b\nc"},
+		{name: "past the end", start: 7, end: 9, wantStart: 3, wantEnd: 3, wantCode: "c"},
+		{name: "zero", start: 0, end: 0, wantStart: 1, wantEnd: 1, wantCode: "a"},
+		{name: "inverted", start: 3, end: 1, wantStart: 3, wantEnd: 3, wantCode: "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vuln := &Vulnerability{LineStart: tt.start, LineEnd: tt.end}
+			alignFindingLines(vuln, lines)
+			if vuln.LineStart != tt.wantStart || vuln.LineEnd != tt.wantEnd || vuln.Code != tt.wantCode {
+				t.Errorf("got lines %d-%d %q, want %d-%d %q",
+					vuln.LineStart, vuln.LineEnd, vuln.Code, tt.wantStart, tt.wantEnd, tt.wantCode)
+			}
+		})
+	}
+
+	// A snippet supplied by the model is kept
+	vuln := &Vulnerability{LineStart: 1, LineEnd: 1, Code: "model snippet"}
+	alignFindingLines(vuln, lines)
+	if vuln.Code != "model snippet" {
+		t.Errorf("snippet = %q, want the model's snippet kept", vuln.Code)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestIsSSHCloneURL(t *testing.T) {
+	for cloneURL, want := range map[string]bool{
+		"git@github.com:octocat/hello-world.git":          true,
+		"ssh://git@github.com/octocat/hello-world.git":    true,
+		"git+ssh://git@github.com/octocat/hello-world":    true,
+		"deploy@gitlab.example.com:group/project.git":     true,
+		"https://github.com/octocat/hello-world.git":      false,
+		"https://token@github.com/octocat/hello-world":    false,
+		"http://gitlab.internal/team/app.git":             false,
+		"file:///tmp/repo":                                false,
+		"github.com/octocat/hello-world":                  false,
+		"https://github.com/octocat/hello-world.git?a=b:": false,
+	} {
+		if got := isSSHCloneURL(cloneURL); got != want {
+			t.Errorf("isSSHCloneURL(%q) = %v, want %v", cloneURL, got, want)
+		}
+	}
+}
+
+func TestSSHUser(t *testing.T) {
+	for cloneURL, want := range map[string]string{
+		"git@github.com:octocat/hello-world.git":       "git",
+		"deploy@gitlab.example.com:group/project.git":  "deploy",
+		"ssh://builder@git.internal:2222/team/app.git": "builder",
+		"ssh://git.internal/team/app.git":              "git",
+		"ssh://git.internal/team/user@app.git":         "git",
+	} {
+		if got := sshUser(cloneURL); got != want {
+			t.Errorf("sshUser(%q) = %q, want %q", cloneURL, got, want)
+		}
+	}
+}
+
+// writeSSHKey writes a new ed25519 private key in OpenSSH format, encrypted when passphrase is set
+func writeSSHKey(t *testing.T, passphrase string) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(key, "test")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "test", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCloneAuth(t *testing.T) {
+	plainKey := writeSSHKey(t, "")
+	lockedKey := writeSSHKey(t, "s3cret")
+	// No agent listens here, so agent auth fails to connect
+	t.Setenv("SSH_AUTH_SOCK", filepath.Join(t.TempDir(), "agent.sock"))
+
+	tests := []struct {
+		name       string
+		cloneURL   string
+		source     sshKeySource
+		wantAuth   bool
+		wantUser   string
+		wantErrMsg string
+	}{
+		{name: "https needs no separate auth", cloneURL: "https://github.com/octocat/hello-world.git", source: sshKeySource{KeyPath: plainKey}},
+		{name: "key file", cloneURL: "git@github.com:octocat/hello-world.git", source: sshKeySource{KeyPath: plainKey}, wantAuth: true, wantUser: "git"},
+		{name: "key file with the URL's user", cloneURL: "ssh://deploy@git.internal/team/app.git", source: sshKeySource{KeyPath: plainKey}, wantAuth: true, wantUser: "deploy"},
+		{name: "encrypted key with passphrase", cloneURL: "git@github.com:octocat/hello-world.git", source: sshKeySource{KeyPath: lockedKey, Passphrase: "s3cret"}, wantAuth: true, wantUser: "git"},
+		{name: "encrypted key without passphrase", cloneURL: "git@github.com:octocat/hello-world.git", source: sshKeySource{KeyPath: lockedKey}, wantErrMsg: "GIT_SSH_KEY_PASSPHRASE"},
+		{name: "wrong passphrase", cloneURL: "git@github.com:octocat/hello-world.git", source: sshKeySource{KeyPath: lockedKey, Passphrase: "wrong"}, wantErrMsg: "failed to load SSH key"},
+		{name: "missing key file", cloneURL: "git@github.com:octocat/hello-world.git", source: sshKeySource{KeyPath: filepath.Join(t.TempDir(), "nope")}, wantErrMsg: "failed to read SSH key"},
+		{name: "key file is preferred over the agent", cloneURL: "git@github.com:octocat/hello-world.git", source: sshKeySource{KeyPath: plainKey, AgentSock: "/tmp/agent"}, wantAuth: true, wantUser: "git"},
+		{name: "unreachable agent", cloneURL: "git@github.com:octocat/hello-world.git", source: sshKeySource{AgentSock: "/tmp/agent"}, wantErrMsg: "ssh-agent"},
+		{name: "no credentials", cloneURL: "git@github.com:octocat/hello-world.git", wantErrMsg: "GIT_SSH_KEY_PATH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := cloneAuth(tt.cloneURL, tt.source)
+			if tt.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("cloneAuth error = %v, want one mentioning %q", err, tt.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cloneAuth returned error: %v", err)
+			}
+			if (auth != nil) != tt.wantAuth {
+				t.Fatalf("auth = %v, want auth: %v", auth, tt.wantAuth)
+			}
+			if !tt.wantAuth {
+				return
+			}
+			keys, ok := auth.(*gitssh.PublicKeys)
+			if !ok {
+				t.Fatalf("auth is %T, want public keys", auth)
+			}
+			if keys.User != tt.wantUser {
+				t.Errorf("user = %q, want %q", keys.User, tt.wantUser)
+			}
+		})
+	}
+}
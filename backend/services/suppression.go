@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// ErrFindingNotInRepository is returned when suppressing a finding ID that belongs to no scan of the repository
+var ErrFindingNotInRepository = errors.New("finding does not belong to repository")
+
+// Suppression marks findings at one location as false positives
+// Findings of any scan of the repository with the same file, start line, and type are hidden
+type Suppression struct {
+	ID                string    `json:"id"`
+	FilePath          string    `json:"file_path"`
+	LineStart         int       `json:"line_start"`
+	VulnerabilityType string    `json:"vulnerability_type"`
+	Reason            string    `json:"reason,omitempty"`
+	CreatedBy         string    `json:"created_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// SuppressionService manages a repository's suppressed findings
+type SuppressionService interface {
+	// AddSuppression suppresses findings at the given location; suppressing a location again updates its reason
+	AddSuppression(ctx context.Context, repoID, userID string, suppression Suppression) (*Suppression, error)
+
+	// SuppressFinding suppresses the location of a stored finding
+	// It returns ErrFindingNotInRepository when the finding isn't from a scan of the repository
+	SuppressFinding(ctx context.Context, repoID, userID, vulnerabilityID, reason string) (*Suppression, error)
+
+	// ListSuppressions returns the repository's suppressions, newest first
+	ListSuppressions(ctx context.Context, repoID string) ([]*Suppression, error)
+
+	// RemoveSuppression deletes one suppression so its findings are reported again; it returns false if none matched
+	RemoveSuppression(ctx context.Context, repoID, suppressionID string) (bool, error)
+}
+
+// notSuppressedSQL is true for a finding v whose location its repository hasn't suppressed
+// Queries that use it must alias the vulnerabilities table as v
+const notSuppressedSQL = `NOT EXISTS (
+			SELECT 1 FROM suppressed_findings sf JOIN scans ss ON ss.repository_id = sf.repository_id
+			WHERE ss.id = v.scan_id AND sf.file_path = v.file_path AND sf.line_start = v.line_start
+				AND sf.vulnerability_type = v.vulnerability_type
+		)`
+
+// NewSuppressionService creates a new suppression service instance
+func NewSuppressionService(dbQueries *db.Queries) SuppressionService {
+	return &suppressionService{
+		db: dbQueries,
+	}
+}
+
+// suppressionService implements the SuppressionService interface
+type suppressionService struct {
+	db *db.Queries
+}
+
+// ValidateSuppression checks that a suppression names a complete location
+func ValidateSuppression(suppression Suppression) error {
+	switch {
+	case strings.TrimSpace(suppression.FilePath) == "":
+		return fmt.Errorf("file_path is required")
+	case suppression.LineStart < 1:
+		return fmt.Errorf("line_start must be a positive line number")
+	case strings.TrimSpace(suppression.VulnerabilityType) == "":
+		return fmt.Errorf("vulnerability_type is required")
+	}
+	return nil
+}
+
+func (s *suppressionService) AddSuppression(ctx context.Context, repoID, userID string, suppression Suppression) (*Suppression, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	if err := ValidateSuppression(suppression); err != nil {
+		return nil, err
+	}
+
+	stored := &Suppression{
+		FilePath:          strings.TrimSpace(suppression.FilePath),
+		LineStart:         suppression.LineStart,
+		VulnerabilityType: strings.TrimSpace(suppression.VulnerabilityType),
+		Reason:            strings.TrimSpace(suppression.Reason),
+		CreatedBy:         userID,
+	}
+	err := sqlDB.QueryRowContext(ctx,
+		`INSERT INTO suppressed_findings (repository_id, file_path, line_start, vulnerability_type, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (repository_id, file_path, line_start, vulnerability_type)
+		DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING id, created_at`,
+		repoID, stored.FilePath, stored.LineStart, stored.VulnerabilityType,
+		sql.NullString{String: stored.Reason, Valid: stored.Reason != ""},
+		sql.NullString{String: userID, Valid: userID != ""}).Scan(&stored.ID, &stored.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store suppression: %w", err)
+	}
+
+	return stored, nil
+}
+
+func (s *suppressionService) SuppressFinding(ctx context.Context, repoID, userID, vulnerabilityID, reason string) (*Suppression, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	suppression := Suppression{Reason: reason}
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT v.file_path, v.line_start, v.vulnerability_type
+		FROM vulnerabilities v JOIN scans s ON s.id = v.scan_id
+		WHERE v.id::text = $1 AND s.repository_id::text = $2`,
+		vulnerabilityID, repoID).Scan(&suppression.FilePath, &suppression.LineStart, &suppression.VulnerabilityType)
+	if err == sql.ErrNoRows {
+		return nil, ErrFindingNotInRepository
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up finding %s: %w", vulnerabilityID, err)
+	}
+
+	return s.AddSuppression(ctx, repoID, userID, suppression)
+}
+
+func (s *suppressionService) ListSuppressions(ctx context.Context, repoID string) ([]*Suppression, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id, file_path, line_start, vulnerability_type, reason, created_by, created_at
+		FROM suppressed_findings WHERE repository_id::text = $1
+		ORDER BY created_at DESC`,
+		repoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppressions: %w", err)
+	}
+	defer rows.Close()
+
+	suppressions := []*Suppression{}
+	for rows.Next() {
+		suppression := &Suppression{}
+		var reason, createdBy sql.NullString
+		if err := rows.Scan(&suppression.ID, &suppression.FilePath, &suppression.LineStart,
+			&suppression.VulnerabilityType, &reason, &createdBy, &suppression.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suppression row: %w", err)
+		}
+		suppression.Reason = reason.String
+		suppression.CreatedBy = createdBy.String
+		suppressions = append(suppressions, suppression)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over suppression rows: %w", err)
+	}
+
+	return suppressions, nil
+}
+
+func (s *suppressionService) RemoveSuppression(ctx context.Context, repoID, suppressionID string) (bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return false, fmt.Errorf("database connection not available")
+	}
+
+	result, err := sqlDB.ExecContext(ctx,
+		`DELETE FROM suppressed_findings WHERE id::text = $1 AND repository_id::text = $2`,
+		suppressionID, repoID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete suppression: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, nil
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateSuppression(t *testing.T) {
+	valid := Suppression{FilePath: "main.go", LineStart: 3, VulnerabilityType: "Injection"}
+	if err := ValidateSuppression(valid); err != nil {
+		t.Errorf("ValidateSuppression(%+v) = %v, want nil", valid, err)
+	}
+
+	for name, suppression := range map[string]Suppression{
+		"blank file":   {FilePath: " ", LineStart: 3, VulnerabilityType: "Injection"},
+		"line zero":    {FilePath: "main.go", VulnerabilityType: "Injection"},
+		"missing type": {FilePath: "main.go", LineStart: 3},
+	} {
+		if err := ValidateSuppression(suppression); err == nil {
+			t.Errorf("ValidateSuppression accepted a suppression with a %s", name)
+		}
+	}
+}
+
+func TestSuppressionsHideFindings(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, scanID := createTestScan(t, queries)
+	otherRepoID, otherScanID := createTestScan(t, queries)
+
+	insertFinding := func(scanID, file string, line int, confidence any) string {
+		t.Helper()
+		var id string
+		if err := queries.GetDB().QueryRowContext(ctx,
+			`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, description, confidence)
+			VALUES ($1, 'Injection', $2, $3, $3, 'High', 'finding', $4) RETURNING id`,
+			scanID, file, line, confidence).Scan(&id); err != nil {
+			t.Fatalf("insert finding: %v", err)
+		}
+		return id
+	}
+	falsePositive := insertFinding(scanID, "main.go", 3, 0.4)
+	insertFinding(scanID, "main.go", 10, nil)
+	otherFinding := insertFinding(otherScanID, "main.go", 3, nil)
+
+	github := NewGitHubService(queries)
+	suppressions := NewSuppressionService(queries)
+	findingIDs := func(scanID string) []string {
+		t.Helper()
+		vulnerabilities, err := github.GetVulnerabilitiesByScanID(ctx, scanID)
+		if err != nil {
+			t.Fatalf("GetVulnerabilitiesByScanID returned error: %v", err)
+		}
+		ids := []string{}
+		for _, vuln := range vulnerabilities {
+			ids = append(ids, vuln.ID)
+		}
+		return ids
+	}
+
+	if _, err := suppressions.SuppressFinding(ctx, repoID, "", otherFinding, "not ours"); !errors.Is(err, ErrFindingNotInRepository) {
+		t.Errorf("suppressing another repository's finding = %v, want ErrFindingNotInRepository", err)
+	}
+
+	suppression, err := suppressions.SuppressFinding(ctx, repoID, "", falsePositive, "input is a constant")
+	if err != nil {
+		t.Fatalf("SuppressFinding returned error: %v", err)
+	}
+	if suppression.FilePath != "main.go" || suppression.LineStart != 3 || suppression.VulnerabilityType != "Injection" {
+		t.Errorf("suppression = %+v, want the finding's location", suppression)
+	}
+
+	// Only the suppressed location of this repository is hidden
+	if ids := findingIDs(scanID); len(ids) != 1 || ids[0] == falsePositive {
+		t.Errorf("findings = %v, want only the unsuppressed one", ids)
+	}
+	if ids := findingIDs(otherScanID); len(ids) != 1 {
+		t.Errorf("other repository's findings = %v, want its finding at the same location kept", ids)
+	}
+
+	// Suppressing the location again updates the reason rather than adding a second suppression
+	if _, err := suppressions.AddSuppression(ctx, repoID, "", Suppression{FilePath: "main.go", LineStart: 3, VulnerabilityType: "Injection", Reason: "reviewed"}); err != nil {
+		t.Fatalf("AddSuppression returned error: %v", err)
+	}
+	listed, err := suppressions.ListSuppressions(ctx, repoID)
+	if err != nil || len(listed) != 1 || listed[0].Reason != "reviewed" {
+		t.Fatalf("ListSuppressions = %v, %v, want one suppression with the new reason", listed, err)
+	}
+
+	if removed, err := suppressions.RemoveSuppression(ctx, otherRepoID, suppression.ID); err != nil || removed {
+		t.Errorf("RemoveSuppression from another repository = %v, %v, want nothing removed", removed, err)
+	}
+	if removed, err := suppressions.RemoveSuppression(ctx, repoID, suppression.ID); err != nil || !removed {
+		t.Fatalf("RemoveSuppression = %v, %v, want it removed", removed, err)
+	}
+	if ids := findingIDs(scanID); len(ids) != 2 {
+		t.Errorf("findings after removing the suppression = %v, want both reported again", ids)
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// DefaultTaxonomyName is the taxonomy scans use when they don't select one
+const DefaultTaxonomyName = "owasp-2021"
+
+// CWETop25TaxonomyName names the built-in taxonomy of the 2023 CWE Top 25 weaknesses
+const CWETop25TaxonomyName = "cwe-top-25"
+
+// Taxonomy is a set of vulnerability categories a scan looks for and reports findings under
+// OWASP Top 10 2021 is the default; organizations following CWE or an internal scheme register their own
+type Taxonomy struct {
+	Name       string              // Identifier scans select it by, e.g. "cwe-top-25"
+	Categories []VulnerabilityType // Categories sent to the model, in display order
+
+	// MapToOWASP returns the OWASP Top 10 2021 identifier (e.g. "A03:2021") a category falls under,
+	// or "Other" when it has none, so findings group the same way whatever taxonomy found them
+	MapToOWASP func(vulnType VulnerabilityType) string
+}
+
+// HasCategory reports whether the name is one of the taxonomy's categories
+func (t *Taxonomy) HasCategory(name string) bool {
+	for _, category := range t.Categories {
+		if string(category) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CategoryNames returns the taxonomy's categories as strings, for scan requests and the model prompt
+func (t *Taxonomy) CategoryNames() []string {
+	names := make([]string, len(t.Categories))
+	for i, category := range t.Categories {
+		names[i] = string(category)
+	}
+	return names
+}
+
+// OWASPCategory maps a finding's type to its OWASP Top 10 2021 identifier under this taxonomy
+// Types the mapping doesn't know are reported as "Other"
+func (t *Taxonomy) OWASPCategory(vulnType VulnerabilityType) string {
+	if t.MapToOWASP == nil {
+		return "Other"
+	}
+	if category := t.MapToOWASP(vulnType); category != "" {
+		return category
+	}
+	return "Other"
+}
+
+// OWASP2021Taxonomy is the default taxonomy: the OWASP Top 10 2021 categories, which map onto themselves
+var OWASP2021Taxonomy = &Taxonomy{
+	Name:       DefaultTaxonomyName,
+	Categories: AllVulnerabilityTypes,
+	MapToOWASP: OWASPCategory,
+}
+
+// cweTop25ToOWASP maps each weakness of the 2023 CWE Top 25 to the OWASP Top 10 2021 category listing it
+// Memory-safety weaknesses have no OWASP category and are reported as "Other"
+var cweTop25ToOWASP = map[VulnerabilityType]string{
+	"CWE-787: Out-of-bounds Write":                          "Other",
+	"CWE-79: Cross-site Scripting":                          "A03:2021",
+	"CWE-89: SQL Injection":                                 "A03:2021",
+	"CWE-416: Use After Free":                               "Other",
+	"CWE-78: OS Command Injection":                          "A03:2021",
+	"CWE-20: Improper Input Validation":                     "A03:2021",
+	"CWE-125: Out-of-bounds Read":                           "Other",
+	"CWE-22: Path Traversal":                                "A01:2021",
+	"CWE-352: Cross-Site Request Forgery":                   "A01:2021",
+	"CWE-434: Unrestricted Upload of Dangerous File Type":   "A04:2021",
+	"CWE-862: Missing Authorization":                        "A01:2021",
+	"CWE-476: NULL Pointer Dereference":                     "Other",
+	"CWE-287: Improper Authentication":                      "A07:2021",
+	"CWE-190: Integer Overflow or Wraparound":               "Other",
+	"CWE-502: Deserialization of Untrusted Data":            "A08:2021",
+	"CWE-77: Command Injection":                             "A03:2021",
+	"CWE-119: Improper Restriction of Memory Buffer Bounds": "Other",
+	"CWE-798: Use of Hard-coded Credentials":                "A07:2021",
+	"CWE-918: Server-Side Request Forgery":                  "A10:2021",
+	"CWE-306: Missing Authentication for Critical Function": "A07:2021",
+	"CWE-362: Race Condition":                               "Other",
+	"CWE-269: Improper Privilege Management":                "A04:2021",
+	"CWE-94: Code Injection":                                "A03:2021",
+	"CWE-863: Incorrect Authorization":                      "A01:2021",
+	"CWE-276: Incorrect Default Permissions":                "A01:2021",
+}
+
+// CWETop25Taxonomy is the built-in example of a non-OWASP taxonomy: the 2023 CWE Top 25, in rank order
+var CWETop25Taxonomy = &Taxonomy{
+	Name: CWETop25TaxonomyName,
+	Categories: []VulnerabilityType{
+		"CWE-787: Out-of-bounds Write",
+		"CWE-79: Cross-site Scripting",
+		"CWE-89: SQL Injection",
+		"CWE-416: Use After Free",
+		"CWE-78: OS Command Injection",
+		"CWE-20: Improper Input Validation",
+		"CWE-125: Out-of-bounds Read",
+		"CWE-22: Path Traversal",
+		"CWE-352: Cross-Site Request Forgery",
+		"CWE-434: Unrestricted Upload of Dangerous File Type",
+		"CWE-862: Missing Authorization",
+		"CWE-476: NULL Pointer Dereference",
+		"CWE-287: Improper Authentication",
+		"CWE-190: Integer Overflow or Wraparound",
+		"CWE-502: Deserialization of Untrusted Data",
+		"CWE-77: Command Injection",
+		"CWE-119: Improper Restriction of Memory Buffer Bounds",
+		"CWE-798: Use of Hard-coded Credentials",
+		"CWE-918: Server-Side Request Forgery",
+		"CWE-306: Missing Authentication for Critical Function",
+		"CWE-362: Race Condition",
+		"CWE-269: Improper Privilege Management",
+		"CWE-94: Code Injection",
+		"CWE-863: Incorrect Authorization",
+		"CWE-276: Incorrect Default Permissions",
+	},
+	MapToOWASP: func(vulnType VulnerabilityType) string {
+		return cweTop25ToOWASP[vulnType]
+	},
+}
+
+// validTaxonomyName keeps taxonomy names short and safe to put in URLs and logs
+var validTaxonomyName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+var (
+	taxonomiesMu sync.RWMutex
+	taxonomies   = map[string]*Taxonomy{
+		OWASP2021Taxonomy.Name: OWASP2021Taxonomy,
+		CWETop25Taxonomy.Name:  CWETop25Taxonomy,
+	}
+)
+
+// RegisterTaxonomy makes a taxonomy selectable by scans under its name
+// The API server and the worker must register the same taxonomies, since both resolve them by name
+func RegisterTaxonomy(taxonomy *Taxonomy) error {
+	if !validTaxonomyName.MatchString(taxonomy.Name) {
+		return fmt.Errorf("invalid taxonomy name %q: use up to 64 lowercase letters, digits, or . _ -", taxonomy.Name)
+	}
+	if len(taxonomy.Categories) == 0 {
+		return fmt.Errorf("taxonomy %q has no categories", taxonomy.Name)
+	}
+	seen := make(map[VulnerabilityType]bool, len(taxonomy.Categories))
+	for _, category := range taxonomy.Categories {
+		if category == "" {
+			return fmt.Errorf("taxonomy %q has an empty category", taxonomy.Name)
+		}
+		if seen[category] {
+			return fmt.Errorf("taxonomy %q lists category %q twice", taxonomy.Name, category)
+		}
+		seen[category] = true
+	}
+	if taxonomy.MapToOWASP == nil {
+		return fmt.Errorf("taxonomy %q has no OWASP mapping", taxonomy.Name)
+	}
+
+	taxonomiesMu.Lock()
+	defer taxonomiesMu.Unlock()
+	if _, exists := taxonomies[taxonomy.Name]; exists {
+		return fmt.Errorf("taxonomy %q is already registered", taxonomy.Name)
+	}
+	taxonomies[taxonomy.Name] = taxonomy
+	return nil
+}
+
+// LookupTaxonomy returns the registered taxonomy with the name; an empty name is the default taxonomy
+func LookupTaxonomy(name string) (*Taxonomy, bool) {
+	if name == "" {
+		name = DefaultTaxonomyName
+	}
+	taxonomiesMu.RLock()
+	defer taxonomiesMu.RUnlock()
+	taxonomy, ok := taxonomies[name]
+	return taxonomy, ok
+}
+
+// TaxonomyOrDefault returns the registered taxonomy with the name, or the default taxonomy
+// when it isn't registered, e.g. for scans recorded before a custom taxonomy was removed
+func TaxonomyOrDefault(name string) *Taxonomy {
+	if taxonomy, ok := LookupTaxonomy(name); ok {
+		return taxonomy
+	}
+	return OWASP2021Taxonomy
+}
+
+// TaxonomyNames returns the names of the registered taxonomies, sorted
+func TaxonomyNames() []string {
+	taxonomiesMu.RLock()
+	defer taxonomiesMu.RUnlock()
+	names := make([]string, 0, len(taxonomies))
+	for name := range taxonomies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// taxonomyFileEntry is one taxonomy in a SCAN_TAXONOMIES_FILE
+type taxonomyFileEntry struct {
+	Name       string `json:"name"`
+	Categories []struct {
+		Name  string `json:"name"`  // Category sent to the model and stored on findings
+		OWASP string `json:"owasp"` // OWASP Top 10 2021 identifier it falls under; empty means "Other"
+	} `json:"categories"`
+}
+
+// LoadTaxonomiesFromEnv registers the custom taxonomies in the JSON file named by SCAN_TAXONOMIES_FILE, if set
+// The file holds a list of {"name": ..., "categories": [{"name": ..., "owasp": "A03:2021"}, ...]}
+func LoadTaxonomiesFromEnv() error {
+	path := os.Getenv("SCAN_TAXONOMIES_FILE")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read taxonomies file: %w", err)
+	}
+	var entries []taxonomyFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse taxonomies file %s: %w", path, err)
+	}
+
+	for _, entry := range entries {
+		taxonomy := &Taxonomy{Name: entry.Name}
+		mapping := make(map[VulnerabilityType]string, len(entry.Categories))
+		for _, category := range entry.Categories {
+			taxonomy.Categories = append(taxonomy.Categories, VulnerabilityType(category.Name))
+			mapping[VulnerabilityType(category.Name)] = category.OWASP
+		}
+		taxonomy.MapToOWASP = func(vulnType VulnerabilityType) string {
+			return mapping[vulnType]
+		}
+		if err := RegisterTaxonomy(taxonomy); err != nil {
+			return fmt.Errorf("taxonomies file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// rowQuerier runs single-row queries; both *sql.DB and *sql.Tx satisfy it
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanTaxonomy returns the taxonomy a scan selected, falling back to the default when it isn't registered
+func scanTaxonomy(ctx context.Context, q rowQuerier, scanID string) (*Taxonomy, error) {
+	var name string
+	if err := q.QueryRowContext(ctx, `SELECT taxonomy FROM scans WHERE id = $1`, scanID).Scan(&name); err != nil {
+		return nil, fmt.Errorf("failed to read taxonomy of scan %s: %w", scanID, err)
+	}
+	return TaxonomyOrDefault(name), nil
+}
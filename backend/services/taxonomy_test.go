@@ -0,0 +1,154 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// registerTestTaxonomy registers a taxonomy for the test and removes it again afterwards
+func registerTestTaxonomy(t *testing.T, taxonomy *Taxonomy) error {
+	t.Helper()
+	err := RegisterTaxonomy(taxonomy)
+	if err == nil {
+		t.Cleanup(func() {
+			taxonomiesMu.Lock()
+			delete(taxonomies, taxonomy.Name)
+			taxonomiesMu.Unlock()
+		})
+	}
+	return err
+}
+
+func TestRegisterTaxonomy(t *testing.T) {
+	mapping := func(VulnerabilityType) string { return "A03:2021" }
+	tests := []struct {
+		name     string
+		taxonomy *Taxonomy
+		wantErr  string
+	}{
+		{name: "valid", taxonomy: &Taxonomy{Name: "acme-v1", Categories: []VulnerabilityType{"ACME-1 Tainted Input"}, MapToOWASP: mapping}},
+		{name: "uppercase name", taxonomy: &Taxonomy{Name: "ACME", Categories: []VulnerabilityType{"x"}, MapToOWASP: mapping}, wantErr: "invalid taxonomy name"},
+		{name: "no categories", taxonomy: &Taxonomy{Name: "empty", MapToOWASP: mapping}, wantErr: "no categories"},
+		{name: "duplicate category", taxonomy: &Taxonomy{Name: "dup", Categories: []VulnerabilityType{"x", "x"}, MapToOWASP: mapping}, wantErr: "twice"},
+		{name: "no mapping", taxonomy: &Taxonomy{Name: "unmapped", Categories: []VulnerabilityType{"x"}}, wantErr: "no OWASP mapping"},
+		{name: "built-in name", taxonomy: &Taxonomy{Name: DefaultTaxonomyName, Categories: []VulnerabilityType{"x"}, MapToOWASP: mapping}, wantErr: "already registered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registerTestTaxonomy(t, tt.taxonomy)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("RegisterTaxonomy returned error: %v", err)
+				}
+				if got, ok := LookupTaxonomy(tt.taxonomy.Name); !ok || got != tt.taxonomy {
+					t.Errorf("LookupTaxonomy(%q) = %v, %v, want the registered taxonomy", tt.taxonomy.Name, got, ok)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RegisterTaxonomy error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTaxonomyMapsCategoriesToOWASP(t *testing.T) {
+	custom := &Taxonomy{
+		Name:       "acme-v2",
+		Categories: []VulnerabilityType{"ACME-1 Tainted Input", "ACME-9 Misc"},
+		MapToOWASP: func(vulnType VulnerabilityType) string {
+			if vulnType == "ACME-1 Tainted Input" {
+				return "A03:2021"
+			}
+			return ""
+		},
+	}
+	if err := registerTestTaxonomy(t, custom); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		taxonomy string
+		vulnType VulnerabilityType
+		want     string
+	}{
+		{taxonomy: "", vulnType: Injection, want: "A03:2021"},
+		{taxonomy: DefaultTaxonomyName, vulnType: ServerSideRequestForgery, want: "A10:2021"},
+		{taxonomy: DefaultTaxonomyName, vulnType: "CWE-89: SQL Injection", want: "Other"},
+		{taxonomy: CWETop25TaxonomyName, vulnType: "CWE-89: SQL Injection", want: "A03:2021"},
+		{taxonomy: CWETop25TaxonomyName, vulnType: "CWE-918: Server-Side Request Forgery", want: "A10:2021"},
+		{taxonomy: CWETop25TaxonomyName, vulnType: "CWE-787: Out-of-bounds Write", want: "Other"},
+		{taxonomy: CWETop25TaxonomyName, vulnType: Injection, want: "Other"},
+		{taxonomy: "acme-v2", vulnType: "ACME-1 Tainted Input", want: "A03:2021"},
+		{taxonomy: "acme-v2", vulnType: "ACME-9 Misc", want: "Other"},
+	}
+	for _, tt := range tests {
+		if got := TaxonomyOrDefault(tt.taxonomy).OWASPCategory(tt.vulnType); got != tt.want {
+			t.Errorf("%s: OWASPCategory(%q) = %q, want %q", tt.taxonomy, tt.vulnType, got, tt.want)
+		}
+	}
+}
+
+func TestCWETop25TaxonomyMapsEveryCategory(t *testing.T) {
+	if len(CWETop25Taxonomy.Categories) != 25 {
+		t.Errorf("CWE Top 25 has %d categories", len(CWETop25Taxonomy.Categories))
+	}
+	for _, category := range CWETop25Taxonomy.Categories {
+		if _, ok := cweTop25ToOWASP[category]; !ok {
+			t.Errorf("%q has no OWASP mapping", category)
+		}
+	}
+	if len(cweTop25ToOWASP) != len(CWETop25Taxonomy.Categories) {
+		t.Errorf("mapping has %d entries for %d categories", len(cweTop25ToOWASP), len(CWETop25Taxonomy.Categories))
+	}
+}
+
+func TestTaxonomyOrDefaultFallsBack(t *testing.T) {
+	if got := TaxonomyOrDefault("removed-taxonomy"); got != OWASP2021Taxonomy {
+		t.Errorf("TaxonomyOrDefault of an unregistered name = %s, want the default", got.Name)
+	}
+	if _, ok := LookupTaxonomy("removed-taxonomy"); ok {
+		t.Error("LookupTaxonomy found an unregistered taxonomy")
+	}
+}
+
+func TestLoadTaxonomiesFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "taxonomies.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "acme-file", "categories": [
+		{"name": "ACME-1 Tainted Input", "owasp": "A03:2021"},
+		{"name": "ACME-2 Weak Session"}
+	]}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCAN_TAXONOMIES_FILE", path)
+	t.Cleanup(func() {
+		taxonomiesMu.Lock()
+		delete(taxonomies, "acme-file")
+		taxonomiesMu.Unlock()
+	})
+
+	if err := LoadTaxonomiesFromEnv(); err != nil {
+		t.Fatalf("LoadTaxonomiesFromEnv returned error: %v", err)
+	}
+	taxonomy, ok := LookupTaxonomy("acme-file")
+	if !ok {
+		t.Fatal("taxonomy from the file was not registered")
+	}
+	if got := taxonomy.CategoryNames(); strings.Join(got, "|") != "ACME-1 Tainted Input|ACME-2 Weak Session" {
+		t.Errorf("categories = %v", got)
+	}
+	if got := taxonomy.OWASPCategory("ACME-1 Tainted Input"); got != "A03:2021" {
+		t.Errorf("ACME-1 maps to %q, want A03:2021", got)
+	}
+	if got := taxonomy.OWASPCategory("ACME-2 Weak Session"); got != "Other" {
+		t.Errorf("ACME-2 maps to %q, want Other", got)
+	}
+
+	// Loading the same file again must fail rather than silently replace the taxonomy
+	if err := LoadTaxonomiesFromEnv(); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("second load error = %v, want already registered", err)
+	}
+}
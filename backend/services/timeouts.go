@@ -0,0 +1,47 @@
+package services
+
+import "time"
+
+// ScanTimeoutPlan is the result of checking per-request timeouts against the activity timeout
+type ScanTimeoutPlan struct {
+	WorstCase  time.Duration // Time to scan every file if each request ran to its timeout
+	Fits       bool          // True if WorstCase fits inside the activity timeout
+	TimeBudget time.Duration // Time budget to use; tightened when needed so the last request ends in time
+	MaxFiles   int           // Files that fit within the activity timeout at worst-case speed
+}
+
+// PlanScanTimeouts checks that the worst-case scan time fits within the activity timeout
+// Files are scanned in rounds of `concurrency`, and each round can take up to requestTimeout.
+// When the worst case doesn't fit, the time budget is tightened so no new round starts later than
+// one request timeout before the activity deadline, and the scan returns partial results instead
+// of being killed by Temporal.
+func PlanScanTimeouts(activityTimeout, requestTimeout, timeBudget time.Duration, concurrency, fileCount int) ScanTimeoutPlan {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	plan := ScanTimeoutPlan{TimeBudget: timeBudget, MaxFiles: fileCount, Fits: true}
+	if activityTimeout <= 0 || requestTimeout <= 0 {
+		return plan
+	}
+
+	rounds := (fileCount + concurrency - 1) / concurrency
+	plan.WorstCase = time.Duration(rounds) * requestTimeout
+	plan.MaxFiles = int(activityTimeout/requestTimeout) * concurrency
+	if plan.MaxFiles > fileCount {
+		plan.MaxFiles = fileCount
+	}
+	plan.Fits = plan.WorstCase <= activityTimeout
+
+	if !plan.Fits {
+		latestStart := activityTimeout - requestTimeout
+		if latestStart < 0 {
+			latestStart = 0
+		}
+		if plan.TimeBudget <= 0 || plan.TimeBudget > latestStart {
+			plan.TimeBudget = latestStart
+		}
+	}
+
+	return plan
+}
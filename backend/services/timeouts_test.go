@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanScanTimeouts(t *testing.T) {
+	tests := []struct {
+		name            string
+		activityTimeout time.Duration
+		requestTimeout  time.Duration
+		timeBudget      time.Duration
+		concurrency     int
+		fileCount       int
+		want            ScanTimeoutPlan
+	}{
+		{
+			name:            "fits sequentially",
+			activityTimeout: 30 * time.Minute, requestTimeout: 2 * time.Minute, concurrency: 1, fileCount: 10,
+			want: ScanTimeoutPlan{WorstCase: 20 * time.Minute, Fits: true, MaxFiles: 10},
+		},
+		{
+			name:            "too many files tightens the budget",
+			activityTimeout: 30 * time.Minute, requestTimeout: 2 * time.Minute, concurrency: 1, fileCount: 100,
+			want: ScanTimeoutPlan{WorstCase: 200 * time.Minute, TimeBudget: 28 * time.Minute, MaxFiles: 15},
+		},
+		{
+			name:            "concurrency makes it fit",
+			activityTimeout: 30 * time.Minute, requestTimeout: 2 * time.Minute, concurrency: 8, fileCount: 100,
+			want: ScanTimeoutPlan{WorstCase: 26 * time.Minute, Fits: true, MaxFiles: 100},
+		},
+		{
+			name:            "a shorter budget is kept",
+			activityTimeout: 30 * time.Minute, requestTimeout: 2 * time.Minute, timeBudget: 10 * time.Minute, concurrency: 1, fileCount: 100,
+			want: ScanTimeoutPlan{WorstCase: 200 * time.Minute, TimeBudget: 10 * time.Minute, MaxFiles: 15},
+		},
+		{
+			name:            "a longer budget is cut to the latest start",
+			activityTimeout: 30 * time.Minute, requestTimeout: 2 * time.Minute, timeBudget: 29 * time.Minute, concurrency: 1, fileCount: 100,
+			want: ScanTimeoutPlan{WorstCase: 200 * time.Minute, TimeBudget: 28 * time.Minute, MaxFiles: 15},
+		},
+		{
+			name:            "request timeout longer than the activity",
+			activityTimeout: time.Minute, requestTimeout: 2 * time.Minute, concurrency: 1, fileCount: 3,
+			want: ScanTimeoutPlan{WorstCase: 6 * time.Minute, TimeBudget: 0, MaxFiles: 0},
+		},
+		{
+			name:           "no activity timeout",
+			requestTimeout: 2 * time.Minute, timeBudget: time.Hour, concurrency: 1, fileCount: 100,
+			want: ScanTimeoutPlan{Fits: true, TimeBudget: time.Hour, MaxFiles: 100},
+		},
+		{
+			name:            "zero concurrency is treated as one",
+			activityTimeout: 10 * time.Minute, requestTimeout: time.Minute, concurrency: 0, fileCount: 5,
+			want: ScanTimeoutPlan{WorstCase: 5 * time.Minute, Fits: true, MaxFiles: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanScanTimeouts(tt.activityTimeout, tt.requestTimeout, tt.timeBudget, tt.concurrency, tt.fileCount)
+			if got != tt.want {
+				t.Errorf("PlanScanTimeouts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultVulnerabilityPageLimit is the page size when the caller does not pass one
+	DefaultVulnerabilityPageLimit = 100
+
+	// MaxVulnerabilityPageLimit caps the page size so one request can't load every finding of a large scan
+	MaxVulnerabilityPageLimit = 500
+)
+
+// VulnerabilityFilter selects and pages the findings of a scan; every condition is applied in SQL
+// Empty lists and paths select everything
+type VulnerabilityFilter struct {
+	Severities       []string // Severity labels to keep, matched by rank so casing doesn't matter
+	Types            []string // Vulnerability types to keep, case-insensitively
+	FilePath         string   // A file to keep findings of, or a directory to keep everything under
+	IncludeExcluded  bool     // Keep findings in paths excluded by path rules
+	IncludeBaselined bool     // Keep findings accepted in the repository baseline
+	MinConfidence    *float64 // Drop findings the model was less sure of; findings without a confidence are kept
+	Limit            int
+	Offset           int
+}
+
+// VulnerabilityPage is one page of the findings matching a VulnerabilityFilter
+// The counts cover every matching finding, not just the page
+type VulnerabilityPage struct {
+	Vulnerabilities    []*Vulnerability
+	Total              int            // Findings the filter shows, across all pages
+	ReportedCount      int            // Shown findings that are neither excluded nor baselined
+	ExcludedCount      int            // Matching findings in excluded paths, shown or not
+	BaselinedCount     int            // Matching findings accepted in the baseline, shown or not
+	LowConfidenceCount int            // Matching findings dropped for falling below MinConfidence
+	TypeCounts         map[string]int // Shown findings per vulnerability type
+	SeverityCounts     map[string]int // Shown findings per severity
+}
+
+// NormalizeFindingPath cleans a file_path filter into the repository-relative form findings are stored in
+func NormalizeFindingPath(filePath string) string {
+	filePath = strings.TrimSpace(strings.ReplaceAll(filePath, "\\", "/"))
+	if filePath == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path.Clean("/"+filePath), "/")
+}
+
+// likeEscaper escapes the LIKE wildcards in a literal prefix
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// matchingFindingsSQL returns the SELECT of a scan's unsuppressed findings that match the filter's severity,
+// type, and path, classified as excluded, baselined, and low confidence, with the arguments it refers to
+func (f VulnerabilityFilter) matchingFindingsSQL(scanID string) (string, []any) {
+	args := []any{scanID, f.MinConfidence}
+	conditions := []string{"v.scan_id = $1", notSuppressedSQL}
+
+	if len(f.Severities) > 0 {
+		ranks := make([]int64, 0, len(f.Severities))
+		for _, severity := range f.Severities {
+			ranks = append(ranks, int64(SeverityRank(severity)))
+		}
+		args = append(args, pq.Array(ranks))
+		conditions = append(conditions, fmt.Sprintf("v.severity_rank = ANY($%d)", len(args)))
+	}
+	if len(f.Types) > 0 {
+		types := make([]string, 0, len(f.Types))
+		for _, vulnType := range f.Types {
+			types = append(types, strings.ToLower(strings.TrimSpace(vulnType)))
+		}
+		args = append(args, pq.Array(types))
+		conditions = append(conditions, fmt.Sprintf("LOWER(v.vulnerability_type) = ANY($%d)", len(args)))
+	}
+	if filePath := NormalizeFindingPath(f.FilePath); filePath != "" {
+		args = append(args, filePath, likeEscaper.Replace(filePath)+"/%")
+		conditions = append(conditions, fmt.Sprintf(`(v.file_path = $%d OR v.file_path LIKE $%d ESCAPE '\')`, len(args)-1, len(args)))
+	}
+
+	// Stored confidences are single precision, so 0.9 reads back a hair below the 0.9 threshold
+	query := `SELECT v.id, v.vulnerability_type, v.file_path, v.line_start, v.line_end, v.severity, v.severity_rank,
+			v.description, v.remediation, v.code_snippet, v.excluded, ` + baselinedColumnSQL + `, v.confidence, v.cwe, v.reference_urls,
+			COALESCE(v.confidence < $2::double precision - 1e-6, false) AS low_confidence
+		FROM vulnerabilities v WHERE ` + strings.Join(conditions, " AND ")
+	return query, args
+}
+
+func (s *gitHubService) QueryRepositoryVulnerabilities(ctx context.Context, repoID string, filter VulnerabilityFilter) (*VulnerabilityPage, error) {
+	page := &VulnerabilityPage{
+		Vulnerabilities: []*Vulnerability{},
+		TypeCounts:      make(map[string]int),
+		SeverityCounts:  make(map[string]int),
+	}
+
+	db := s.db.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultVulnerabilityPageLimit
+	}
+	if filter.Limit > MaxVulnerabilityPageLimit {
+		filter.Limit = MaxVulnerabilityPageLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	scanID, err := latestScanWithResults(ctx, db, repoID)
+	if err != nil {
+		return nil, err
+	}
+	if scanID == "" {
+		return page, nil
+	}
+
+	matching, args := filter.matchingFindingsSQL(scanID)
+
+	// The counts come from one grouped pass. A hidden finding counts under the first flag that hides it,
+	// so one both excluded and baselined counts as baselined only when excluded findings are shown
+	rows, err := db.QueryContext(ctx,
+		`WITH matching AS (`+matching+`)
+		SELECT vulnerability_type, severity, excluded, baselined, low_confidence, COUNT(*)
+		FROM matching GROUP BY vulnerability_type, severity, excluded, baselined, low_confidence`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count vulnerabilities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var vulnType, severity string
+		var excluded, baselined, lowConfidence bool
+		var count int
+		if err := rows.Scan(&vulnType, &severity, &excluded, &baselined, &lowConfidence, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan vulnerability count: %w", err)
+		}
+
+		if excluded {
+			page.ExcludedCount += count
+			if !filter.IncludeExcluded {
+				continue
+			}
+		}
+		if baselined {
+			page.BaselinedCount += count
+			if !filter.IncludeBaselined {
+				continue
+			}
+		}
+		if lowConfidence {
+			page.LowConfidenceCount += count
+			continue
+		}
+
+		page.Total += count
+		page.TypeCounts[vulnType] += count
+		page.SeverityCounts[severity] += count
+		if !excluded && !baselined {
+			page.ReportedCount += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over vulnerability counts: %w", err)
+	}
+
+	args = append(args, filter.IncludeExcluded, filter.IncludeBaselined, filter.Limit, filter.Offset)
+	n := len(args)
+	// The id tiebreak keeps pages stable when two findings share a location
+	pageRows, err := db.QueryContext(ctx, fmt.Sprintf(
+		`WITH matching AS (%s)
+		SELECT id, vulnerability_type, file_path, line_start, line_end, severity, description,
+			remediation, code_snippet, excluded, baselined, confidence, cwe, reference_urls
+		FROM matching
+		WHERE ($%d OR NOT excluded) AND ($%d OR NOT baselined) AND NOT low_confidence
+		ORDER BY severity_rank DESC, file_path, line_start, id
+		LIMIT $%d OFFSET $%d`,
+		matching, n-3, n-2, n-1, n),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vulnerabilities: %w", err)
+	}
+	page.Vulnerabilities, err = scanVulnerabilityRows(pageRows)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
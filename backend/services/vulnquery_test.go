@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryRepositoryVulnerabilities(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, scanID := createTestScan(t, queries)
+
+	findings := []struct {
+		vulnType string
+		filePath string
+		severity string
+		excluded bool
+	}{
+		{"Injection", "src/api/users.go", "Critical", false},
+		{"Injection", "src/api/orders.go", "High", false},
+		{"Injection", "src/api_test/fixture.go", "High", false},
+		{"Cross-Site Scripting", "web/app.js", "High", false},
+		{"Cross-Site Scripting", "web/view.js", "Medium", false},
+		{"Security Misconfiguration", "vendor/lib/config.go", "Low", true},
+	}
+	for i, f := range findings {
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`INSERT INTO vulnerabilities (scan_id, vulnerability_type, file_path, line_start, line_end, severity, severity_rank, description, excluded)
+			VALUES ($1, $2, $3, $4, $4, $5, $6, 'finding', $7)`,
+			scanID, f.vulnType, f.filePath, i+1, f.severity, SeverityRank(f.severity), f.excluded); err != nil {
+			t.Fatalf("insert finding: %v", err)
+		}
+	}
+
+	service := NewGitHubService(queries)
+	tests := []struct {
+		name           string
+		filter         VulnerabilityFilter
+		wantFiles      []string
+		wantTotal      int
+		wantExcluded   int
+		wantBySeverity map[string]int
+	}{
+		{
+			name:           "everything but the excluded finding, most severe first",
+			filter:         VulnerabilityFilter{},
+			wantFiles:      []string{"src/api/users.go", "src/api/orders.go", "src/api_test/fixture.go", "web/app.js", "web/view.js"},
+			wantTotal:      5,
+			wantExcluded:   1,
+			wantBySeverity: map[string]int{"Critical": 1, "High": 3, "Medium": 1},
+		},
+		{
+			name:           "severity filter ignores case",
+			filter:         VulnerabilityFilter{Severities: []string{"high"}},
+			wantFiles:      []string{"src/api/orders.go", "src/api_test/fixture.go", "web/app.js"},
+			wantTotal:      3,
+			wantBySeverity: map[string]int{"High": 3},
+		},
+		{
+			name:           "type filter",
+			filter:         VulnerabilityFilter{Types: []string{"cross-site scripting"}},
+			wantFiles:      []string{"web/app.js", "web/view.js"},
+			wantTotal:      2,
+			wantBySeverity: map[string]int{"High": 1, "Medium": 1},
+		},
+		{
+			name:           "directory filter doesn't match sibling prefixes",
+			filter:         VulnerabilityFilter{FilePath: "src/api"},
+			wantFiles:      []string{"src/api/users.go", "src/api/orders.go"},
+			wantTotal:      2,
+			wantBySeverity: map[string]int{"Critical": 1, "High": 1},
+		},
+		{
+			name:           "file filter",
+			filter:         VulnerabilityFilter{FilePath: "web/view.js"},
+			wantFiles:      []string{"web/view.js"},
+			wantTotal:      1,
+			wantBySeverity: map[string]int{"Medium": 1},
+		},
+		{
+			name:           "second page keeps totals for every page",
+			filter:         VulnerabilityFilter{Limit: 2, Offset: 2},
+			wantFiles:      []string{"src/api_test/fixture.go", "web/app.js"},
+			wantTotal:      5,
+			wantExcluded:   1,
+			wantBySeverity: map[string]int{"Critical": 1, "High": 3, "Medium": 1},
+		},
+		{
+			name:           "past the end",
+			filter:         VulnerabilityFilter{Limit: 2, Offset: 10},
+			wantFiles:      []string{},
+			wantTotal:      5,
+			wantExcluded:   1,
+			wantBySeverity: map[string]int{"Critical": 1, "High": 3, "Medium": 1},
+		},
+		{
+			name:           "excluded findings on request",
+			filter:         VulnerabilityFilter{Severities: []string{"Low"}, IncludeExcluded: true},
+			wantFiles:      []string{"vendor/lib/config.go"},
+			wantTotal:      1,
+			wantExcluded:   1,
+			wantBySeverity: map[string]int{"Low": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := service.QueryRepositoryVulnerabilities(ctx, repoID, tt.filter)
+			if err != nil {
+				t.Fatalf("QueryRepositoryVulnerabilities returned error: %v", err)
+			}
+
+			if len(page.Vulnerabilities) != len(tt.wantFiles) {
+				t.Fatalf("got %d findings, want %d", len(page.Vulnerabilities), len(tt.wantFiles))
+			}
+			for i, vuln := range page.Vulnerabilities {
+				if vuln.FilePath != tt.wantFiles[i] {
+					t.Errorf("finding %d in %s, want %s", i, vuln.FilePath, tt.wantFiles[i])
+				}
+			}
+			if page.Total != tt.wantTotal || page.ExcludedCount != tt.wantExcluded {
+				t.Errorf("total, excluded = %d, %d; want %d, %d", page.Total, page.ExcludedCount, tt.wantTotal, tt.wantExcluded)
+			}
+			if len(page.SeverityCounts) != len(tt.wantBySeverity) {
+				t.Errorf("severity counts = %v, want %v", page.SeverityCounts, tt.wantBySeverity)
+			}
+			for severity, count := range tt.wantBySeverity {
+				if page.SeverityCounts[severity] != count {
+					t.Errorf("severity counts = %v, want %v", page.SeverityCounts, tt.wantBySeverity)
+				}
+			}
+		})
+	}
+
+	// A repository that was never scanned has an empty page rather than an error
+	emptyRepoID, _ := createTestScan(t, queries)
+	if _, err := queries.GetDB().ExecContext(ctx, `DELETE FROM scans WHERE repository_id = $1`, emptyRepoID); err != nil {
+		t.Fatalf("delete scans: %v", err)
+	}
+	page, err := service.QueryRepositoryVulnerabilities(ctx, emptyRepoID, VulnerabilityFilter{})
+	if err != nil || page.Total != 0 || page.Vulnerabilities == nil {
+		t.Errorf("page for an unscanned repository = %+v, %v; want an empty page", page, err)
+	}
+}
+
+func TestNormalizeFindingPath(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		"  ":             "",
+		"src/api":        "src/api",
+		"./src/api/":     "src/api",
+		"/src//api":      "src/api",
+		`src\api\x.go`:   "src/api/x.go",
+		"../../etc/x.go": "etc/x.go",
+	}
+	for input, want := range tests {
+		if got := NormalizeFindingPath(input); got != want {
+			t.Errorf("NormalizeFindingPath(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
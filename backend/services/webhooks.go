@@ -0,0 +1,321 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidWebhookURL is wrapped by every webhook URL validation error, and by deliveries refused for their address
+var ErrInvalidWebhookURL = errors.New("invalid webhook URL")
+
+// ScanStatusEventPrefix prefixes scan lifecycle statuses to form webhook event names, e.g. "scan.cloning"
+const ScanStatusEventPrefix = "scan."
+
+// DefaultWebhookEvents are delivered when a subscription doesn't choose its events: only terminal states
+var DefaultWebhookEvents = []string{
+	ScanStatusEventPrefix + ScanStatusCompleted,
+	ScanStatusEventPrefix + ScanStatusFailed,
+	ScanStatusEventPrefix + ScanStatusTimeBudgetReached,
+	ScanStatusEventPrefix + ScanStatusBudgetExceeded,
+	ScanStatusEventPrefix + ScanStatusCompletedWithErrors,
+	ScanStatusEventPrefix + ScanStatusCanceled,
+}
+
+// WebhookEvents lists every event a subscription may select; intermediate states enable live dashboards
+var WebhookEvents = []string{
+	ScanStatusEventPrefix + ScanStatusQueued,
+	ScanStatusEventPrefix + ScanStatusCloning,
+	ScanStatusEventPrefix + ScanStatusScanning,
+	ScanStatusEventPrefix + ScanStatusCompleted,
+	ScanStatusEventPrefix + ScanStatusFailed,
+	ScanStatusEventPrefix + ScanStatusTimeBudgetReached,
+	ScanStatusEventPrefix + ScanStatusBudgetExceeded,
+	ScanStatusEventPrefix + ScanStatusCompletedWithErrors,
+	ScanStatusEventPrefix + ScanStatusCanceled,
+}
+
+// Webhook is a subscription to scan events
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the webhook is created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScanStatusEvent is the payload posted to webhooks when a scan changes state
+type ScanStatusEvent struct {
+	Event        string    `json:"event"`
+	ScanID       string    `json:"scan_id"`
+	RepositoryID string    `json:"repository_id"`
+	Status       string    `json:"status"`
+	Message      string    `json:"message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// WebhookService manages webhook subscriptions and delivers scan events to them
+type WebhookService interface {
+	// CreateWebhook registers a subscription; empty events select DefaultWebhookEvents
+	CreateWebhook(ctx context.Context, userID, targetURL string, events []string) (*Webhook, error)
+
+	// ListWebhooks returns a user's subscriptions without their secrets
+	ListWebhooks(ctx context.Context, userID string) ([]*Webhook, error)
+
+	// DeleteWebhook removes one of the user's subscriptions; it returns false if none matched
+	DeleteWebhook(ctx context.Context, userID, webhookID string) (bool, error)
+
+	// DeliverScanStatus posts the event to every subscription for the repository that selected it
+	// It returns the number of successful deliveries; individual failures are logged, not returned
+	DeliverScanStatus(ctx context.Context, event ScanStatusEvent) (int, error)
+}
+
+// NewWebhookService creates a new webhook service instance
+func NewWebhookService(dbQueries *db.Queries) WebhookService {
+	return &webhookService{
+		db:         dbQueries,
+		httpClient: newPublicHTTPClient(10*time.Second, invalidWebhookURL),
+	}
+}
+
+// webhookService implements the WebhookService interface
+type webhookService struct {
+	db         *db.Queries
+	httpClient *http.Client
+}
+
+// invalidWebhookURL formats a validation error that matches ErrInvalidWebhookURL
+func invalidWebhookURL(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidWebhookURL, fmt.Sprintf(format, args...))
+}
+
+// ValidateWebhookURL checks that a subscription URL is an https URL whose host resolves only to public addresses,
+// as callback URLs must. Deliveries refuse private addresses again when they connect, since DNS answers can change
+func ValidateWebhookURL(ctx context.Context, targetURL string) error {
+	return validatePublicHTTPSURL(ctx, targetURL, invalidWebhookURL)
+}
+
+// SignWebhookPayload returns the X-SAST-Signature header value for a payload
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, userID, targetURL string, events []string) (*Webhook, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	if err := ValidateWebhookURL(ctx, targetURL); err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		events = DefaultWebhookEvents
+	}
+	for _, event := range events {
+		if !isWebhookEvent(event) {
+			return nil, fmt.Errorf("unknown webhook event %q", event)
+		}
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	webhook := &Webhook{
+		ID:     uuid.New().String(),
+		URL:    targetURL,
+		Events: events,
+		Secret: hex.EncodeToString(secretBytes),
+	}
+
+	err := sqlDB.QueryRowContext(ctx,
+		`INSERT INTO webhooks (id, user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`,
+		webhook.ID, userID, webhook.URL, webhook.Secret, pq.Array(webhook.Events)).Scan(&webhook.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context, userID string) ([]*Webhook, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id, url, events, created_at FROM webhooks WHERE user_id = $1 ORDER BY created_at`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		webhook := &Webhook{}
+		if err := rows.Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.Events), &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over webhook rows: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, userID, webhookID string) (bool, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return false, fmt.Errorf("database connection not available")
+	}
+
+	result, err := sqlDB.ExecContext(ctx,
+		`DELETE FROM webhooks WHERE id::text = $1 AND user_id = $2`,
+		webhookID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, nil
+	}
+	return affected > 0, nil
+}
+
+func (s *webhookService) DeliverScanStatus(ctx context.Context, event ScanStatusEvent) (int, error) {
+	log := logger.FromContext(ctx)
+	if log == nil {
+		log = logger.Get()
+	}
+
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	// Subscriptions belong to users; deliver to those who can see the repository and chose this event
+	rows, err := sqlDB.QueryContext(ctx,
+		`SELECT id, url, secret FROM webhooks
+		WHERE $2 = ANY(events)
+		AND user_id IN (
+			SELECT user_id FROM user_repositories WHERE repository_id::text = $1
+			UNION
+			SELECT created_by FROM repositories WHERE id::text = $1 AND created_by IS NOT NULL
+		)`,
+		event.RepositoryID, event.Event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find webhooks for %s: %w", event.Event, err)
+	}
+
+	type target struct {
+		id, url, secret string
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.id, &t.url, &t.secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error while iterating over webhook rows: %w", err)
+	}
+
+	if len(targets) == 0 {
+		return 0, nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	delivered := 0
+	for _, t := range targets {
+		if err := s.post(ctx, t.url, t.secret, event.Event, payload); err != nil {
+			log.Warn("Webhook delivery failed",
+				zap.String("webhook_id", t.id),
+				zap.String("event", event.Event),
+				zap.String("scan_id", event.ScanID),
+				zap.Error(err))
+			continue
+		}
+		delivered++
+	}
+
+	log.Info("Delivered scan status webhooks",
+		zap.String("event", event.Event),
+		zap.String("scan_id", event.ScanID),
+		zap.Int("delivered", delivered),
+		zap.Int("subscribed", len(targets)))
+
+	return delivered, nil
+}
+
+// post sends one signed webhook request and treats any non-2xx response as a failure
+// Subscriptions stored before https was required are refused rather than posted in the clear
+func (s *webhookService) post(ctx context.Context, targetURL, secret, event string, payload []byte) error {
+	parsed, err := url.Parse(targetURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return invalidWebhookURL("must be an absolute https URL")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SAST-Event", event)
+	req.Header.Set("X-SAST-Signature", SignWebhookPayload(secret, payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// isWebhookEvent reports whether the event can be subscribed to
+func isWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	previous := callbackResolver
+	callbackResolver = fakeCallbackResolver{
+		"hooks.example.com":    {"93.184.216.34"},
+		"internal.example.com": {"10.0.0.12"},
+		"metadata.example.com": {"169.254.169.254"},
+	}
+	t.Cleanup(func() { callbackResolver = previous })
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://hooks.example.com/sast"},
+		{url: "http://hooks.example.com/sast", wantErr: true},
+		{url: "ftp://hooks.example.com/sast", wantErr: true},
+		{url: "/sast", wantErr: true},
+		{url: "hooks.example.com/sast", wantErr: true},
+		{url: "https://internal.example.com/sast", wantErr: true},
+		{url: "https://metadata.example.com/latest/meta-data", wantErr: true},
+		{url: "https://127.0.0.1:8080/admin", wantErr: true},
+		{url: "https://localhost/sast", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := ValidateWebhookURL(context.Background(), tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWebhookURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidWebhookURL) {
+				t.Errorf("ValidateWebhookURL(%q) = %v, want it to match ErrInvalidWebhookURL", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestWebhookPostSignsThePayload(t *testing.T) {
+	payload := []byte(`{"event":"scan.cloning"}`)
+
+	var gotSignature, gotEvent string
+	var gotBody []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-SAST-Signature")
+		gotEvent = r.Header.Get("X-SAST-Event")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	service := &webhookService{httpClient: server.Client()}
+	if err := service.post(context.Background(), server.URL, "shh", "scan.cloning", payload); err != nil {
+		t.Fatalf("post returned error: %v", err)
+	}
+
+	if string(gotBody) != string(payload) || gotEvent != "scan.cloning" {
+		t.Errorf("received %s event with body %s", gotEvent, gotBody)
+	}
+	if gotSignature != SignWebhookPayload("shh", payload) || gotSignature == SignWebhookPayload("other", payload) {
+		t.Errorf("signature %q does not verify with the subscription secret", gotSignature)
+	}
+}
+
+func TestWebhookPostFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	service := &webhookService{httpClient: server.Client()}
+	if err := service.post(context.Background(), server.URL, "shh", "scan.failed", []byte(`{}`)); err == nil {
+		t.Error("post succeeded against a 502 response")
+	}
+}
+
+func TestWebhookPostRefusesPrivateAndPlainHTTPTargets(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The real client refuses private addresses when it connects, whatever the URL looked like when validated
+	guarded := NewWebhookService(nil).(*webhookService)
+	if err := guarded.post(context.Background(), server.URL, "shh", "scan.completed", []byte(`{}`)); !errors.Is(err, ErrInvalidWebhookURL) {
+		t.Errorf("post to a loopback address = %v, want it refused", err)
+	}
+
+	// A subscription stored before https was required isn't posted to
+	service := &webhookService{httpClient: server.Client()}
+	plain := strings.Replace(server.URL, "https://", "http://", 1)
+	if err := service.post(context.Background(), plain, "shh", "scan.completed", []byte(`{}`)); !errors.Is(err, ErrInvalidWebhookURL) {
+		t.Errorf("post to %s = %v, want it refused", plain, err)
+	}
+}
+
+func TestDeliverScanStatusReachesSubscribersOfTheEvent(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	repoID, scanID := createTestScan(t, queries)
+
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event ScanStatusEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		received = append(received, r.URL.Path+" "+event.Status)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	// One user subscribes to every transition, another only to the default terminal events
+	subscribe := func(path string, events []string) {
+		var userID string
+		if err := queries.GetDB().QueryRowContext(ctx,
+			`INSERT INTO users (email, name) VALUES ($1, 'Webhook Test') RETURNING id`,
+			uuid.NewString()+"@example.com").Scan(&userID); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`INSERT INTO user_repositories (user_id, repository_id) VALUES ($1, $2)`, userID, repoID); err != nil {
+			t.Fatalf("link repository: %v", err)
+		}
+		if _, err := queries.GetDB().ExecContext(ctx,
+			`INSERT INTO webhooks (user_id, url, secret, events) VALUES ($1, $2, 'secret', $3)`,
+			userID, server.URL+path, pq.Array(events)); err != nil {
+			t.Fatalf("insert webhook: %v", err)
+		}
+	}
+	subscribe("/all", WebhookEvents)
+	subscribe("/terminal", DefaultWebhookEvents)
+
+	service := &webhookService{db: queries, httpClient: server.Client()}
+	for _, status := range []string{ScanStatusQueued, ScanStatusCloning, ScanStatusScanning, ScanStatusCompleted} {
+		if _, err := service.DeliverScanStatus(ctx, ScanStatusEvent{
+			Event:        ScanStatusEventPrefix + status,
+			ScanID:       scanID,
+			RepositoryID: repoID,
+			Status:       status,
+			Timestamp:    time.Now(),
+		}); err != nil {
+			t.Fatalf("deliver %s: %v", status, err)
+		}
+	}
+
+	want := []string{"/all queued", "/all cloning", "/all scanning", "/all completed", "/terminal completed"}
+	if len(received) != len(want) {
+		t.Fatalf("received %v, want %v", received, want)
+	}
+	// Deliveries of one event may go to subscribers in any order, so only the sequence per subscriber is fixed
+	var all []string
+	for _, delivery := range received {
+		if strings.HasPrefix(delivery, "/all ") {
+			all = append(all, delivery)
+		}
+	}
+	for i, delivery := range all {
+		if delivery != want[i] {
+			t.Errorf("subscriber for every event received %v, want %v", all, want[:4])
+			break
+		}
+	}
+}
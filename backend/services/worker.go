@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+)
+
+// workerPausedSetting is the system_settings key holding whether new scans may start
+const workerPausedSetting = "scan_worker_paused"
+
+// WorkerState reports whether the scan worker is paused and who last changed it
+type WorkerState struct {
+	Paused    bool      `json:"paused"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// WorkerControlService pauses and resumes scan processing for maintenance
+// The state is stored in the database so it survives restarts and applies to every worker.
+// While paused, new scans stay queued; scans that already started run to completion
+type WorkerControlService interface {
+	// State returns the current pause state; a worker that was never paused reports Paused=false
+	State(ctx context.Context) (*WorkerState, error)
+
+	// SetPaused pauses or resumes scan processing on behalf of the given user
+	SetPaused(ctx context.Context, paused bool, userID string) (*WorkerState, error)
+}
+
+// NewWorkerControlService creates a new worker control service instance
+func NewWorkerControlService(dbQueries *db.Queries) WorkerControlService {
+	return &workerControlService{
+		db: dbQueries,
+	}
+}
+
+// workerControlService implements the WorkerControlService interface
+type workerControlService struct {
+	db *db.Queries
+}
+
+func (s *workerControlService) State(ctx context.Context) (*WorkerState, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	var value string
+	var updatedBy sql.NullString
+	state := &WorkerState{}
+	err := sqlDB.QueryRowContext(ctx,
+		`SELECT value, updated_by::text, updated_at FROM system_settings WHERE key = $1`,
+		workerPausedSetting).Scan(&value, &updatedBy, &state.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worker state: %w", err)
+	}
+
+	state.Paused, _ = strconv.ParseBool(value)
+	state.UpdatedBy = updatedBy.String
+	return state, nil
+}
+
+func (s *workerControlService) SetPaused(ctx context.Context, paused bool, userID string) (*WorkerState, error) {
+	sqlDB := s.db.GetDB()
+	if sqlDB == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	_, err := sqlDB.ExecContext(ctx,
+		`INSERT INTO system_settings (key, value, updated_by, updated_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		workerPausedSetting, strconv.FormatBool(paused), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store worker state: %w", err)
+	}
+
+	return s.State(ctx)
+}
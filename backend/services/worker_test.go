@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWorkerPauseStateIsPersisted(t *testing.T) {
+	queries := newTestQueries(t)
+	ctx := context.Background()
+	if _, err := queries.GetDB().ExecContext(ctx, `DELETE FROM system_settings WHERE key = $1`, workerPausedSetting); err != nil {
+		t.Fatalf("clear worker state: %v", err)
+	}
+	control := NewWorkerControlService(queries)
+
+	state, err := control.State(ctx)
+	if err != nil {
+		t.Fatalf("State returned error: %v", err)
+	}
+	if state.Paused {
+		t.Error("a worker that was never paused reports Paused=true")
+	}
+
+	if _, err := control.SetPaused(ctx, true, ""); err != nil {
+		t.Fatalf("SetPaused(true) returned error: %v", err)
+	}
+	// A fresh service reads the state back from the database, as another worker would
+	state, err = NewWorkerControlService(queries).State(ctx)
+	if err != nil {
+		t.Fatalf("State returned error: %v", err)
+	}
+	if !state.Paused {
+		t.Error("pause was not persisted")
+	}
+
+	state, err = control.SetPaused(ctx, false, "")
+	if err != nil {
+		t.Fatalf("SetPaused(false) returned error: %v", err)
+	}
+	if state.Paused {
+		t.Error("resume did not clear the pause")
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultWorkspaceTTL is how old a scan workspace must be before the worker's startup reaper removes it
+// It is far longer than any scan runs, so only workspaces a crashed or killed worker left behind qualify
+const DefaultWorkspaceTTL = 24 * time.Hour
+
+// WorkspaceDir returns the directory repositories are cloned under, one subdirectory per scan
+// SCAN_WORKSPACE_DIR overrides the default of a "repos" directory under the system temp directory
+func WorkspaceDir() string {
+	if dir := os.Getenv("SCAN_WORKSPACE_DIR"); dir != "" {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(os.TempDir(), "repos")
+}
+
+// ScanWorkspaceDir returns the directory a scan clones its repository into
+func ScanWorkspaceDir(scanID string) string {
+	return filepath.Join(WorkspaceDir(), scanID)
+}
+
+// IsWorkspaceDir reports whether dir is a scan's directory under WorkspaceDir, so cleanup never
+// removes anything else
+func IsWorkspaceDir(dir string) bool {
+	rel, err := filepath.Rel(WorkspaceDir(), filepath.Clean(dir))
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel)
+}
+
+// ReapWorkspaces removes the scan workspaces under root last modified more than ttl ago and returns how many
+// it removed. A missing root is not an error. It keeps going past workspaces it can't remove and returns
+// the first such error
+func ReapWorkspaces(root string, ttl time.Duration) (int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read workspace directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	removed := 0
+	var firstErr error
+	for _, entry := range entries {
+		// Scans only ever create directories here
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to remove workspace %s: %w", entry.Name(), err)
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanWorkspaceDirHonorsTheConfiguredRoot(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("SCAN_WORKSPACE_DIR", root)
+
+	dir := ScanWorkspaceDir("scan-1")
+	if dir != filepath.Join(root, "scan-1") {
+		t.Errorf("ScanWorkspaceDir(\"scan-1\") = %q, want it under %s", dir, root)
+	}
+	if !IsWorkspaceDir(dir) {
+		t.Errorf("IsWorkspaceDir(%q) = false, want true", dir)
+	}
+	for _, other := range []string{root, filepath.Dir(root), filepath.Join(root, "..", "elsewhere")} {
+		if IsWorkspaceDir(other) {
+			t.Errorf("IsWorkspaceDir(%q) = true, want only scan directories under the root", other)
+		}
+	}
+
+	t.Setenv("SCAN_WORKSPACE_DIR", "")
+	if got, want := WorkspaceDir(), filepath.Join(os.TempDir(), "repos"); got != want {
+		t.Errorf("WorkspaceDir() = %q without SCAN_WORKSPACE_DIR, want %q", got, want)
+	}
+}
+
+func TestReapWorkspacesRemovesOnlyStaleWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	ages := map[string]time.Duration{
+		"scan-stale":  48 * time.Hour,
+		"scan-old":    25 * time.Hour,
+		"scan-recent": time.Hour,
+		"scan-active": 0,
+	}
+	for name, age := range ages {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(-age)
+		if err := os.Chtimes(dir, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only scan directories are reaped, whatever their age
+	stray := filepath.Join(root, "notes.txt")
+	if err := os.WriteFile(stray, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(stray, now.Add(-72*time.Hour), now.Add(-72*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := ReapWorkspaces(root, DefaultWorkspaceTTL)
+	if err != nil {
+		t.Fatalf("ReapWorkspaces returned error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d workspaces, want 2", removed)
+	}
+	for name, age := range ages {
+		_, statErr := os.Stat(filepath.Join(root, name))
+		if stale := age > DefaultWorkspaceTTL; stale != os.IsNotExist(statErr) {
+			t.Errorf("%s (%v old): stat error %v, want removed = %v", name, age, statErr, stale)
+		}
+	}
+	if _, err := os.Stat(stray); err != nil {
+		t.Errorf("file in the workspace directory was removed: %v", err)
+	}
+}
+
+func TestReapWorkspacesWithoutAWorkspaceDirectory(t *testing.T) {
+	removed, err := ReapWorkspaces(filepath.Join(t.TempDir(), "missing"), DefaultWorkspaceTTL)
+	if err != nil || removed != 0 {
+		t.Errorf("ReapWorkspaces = %d, %v, want nothing to do", removed, err)
+	}
+}
@@ -3,15 +3,25 @@ package temporal
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/metrics"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/tracing"
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.opentelemetry.io/otel/attribute"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 	"go.uber.org/zap"
 )
 
@@ -19,58 +29,310 @@ import (
 // It contains the required information to clone a Git repository
 type CloneActivityInput struct {
 	RepositoryID string // Unique identifier for the repository
+	ScanID       string // Scan record whose status tracks the clone progress
 	CloneURL     string // Git URL to clone the repository (HTTPS or SSH)
+	Ref          string // Branch, tag, or commit to check out; empty uses the default branch
+	MaxAttempts  int32  // The retry policy's MaximumAttempts, so the last attempt knows to fail the scan; 0 is the default
+
+	// IncrementalSince is the commit an incremental scan diffs against; empty scans the full tree
+	// The clone then fetches full history so the commit is available
+	IncrementalSince string
+
+	// Scope is the scan's file and finding options; only a scan of the base commit with the same scope
+	// can have its findings carried forward
+	Scope services.ScanScope
 }
 
 // CloneActivityOutput represents the output from the clone repository activity
 // It provides information about the cloned repository, including the local directory
 type CloneActivityOutput struct {
-	RepositoryID string // Repository identifier (for correlation)
-	RepoDir      string // Local file system path where the repository was cloned
+	RepositoryID  string // Repository identifier (for correlation)
+	RepoDir       string // Local file system path where the repository was cloned
+	Ref           string // Branch or tag ref that was checked out, e.g. refs/heads/main; empty for a pinned commit
+	CommitSHA     string // Commit the clone is at, stored with the scan for reproducibility
+	BaseCommitSHA string // Last successfully scanned commit of the ref; empty for a first-seen branch
+
+	// Set when an incremental scan was requested and a completed scan of the base commit exists;
+	// otherwise the scan falls back to the full tree and these are empty
+	IncrementalBaseSHA string   // Full SHA of the commit the scan diffs against
+	BaseScanID         string   // Completed scan of that commit whose findings are carried forward
+	ChangedFiles       []string // Paths changed since the base, including deleted and renamed-away ones
 }
 
 // ScanActivityInput represents the input for the scan repository activity
 // It contains all parameters required to perform a security scan on the cloned repo
 type ScanActivityInput struct {
-	RepositoryID   string   // Unique identifier for the repository
-	RepoDir        string   // Directory path where the repository was cloned
-	VulnTypes      []string // Types of vulnerabilities to scan for
-	FileExtensions []string // File extensions to include in the scan
-	NotifyEmail    bool     // Whether to send an email notification when scan completes
-	Email          string   // Email address to notify when scan completes
+	RepositoryID    string   // Unique identifier for the repository
+	ScanID          string   // Scan record created when the scan was queued (generated if empty)
+	RepoDir         string   // Directory path where the repository was cloned
+	Ref             string   // Branch ref of the clone; recorded with CommitSHA once the scan completes
+	CommitSHA       string   // Commit being scanned
+	VulnTypes       []string // Types of vulnerabilities to scan for
+	Taxonomy        string   // Registered taxonomy VulnTypes come from; empty is OWASP Top 10 2021
+	FileExtensions  []string // File extensions to include in the scan
+	IncludeGlobs    []string // When set, only files matching one of these globs are scanned
+	MinSeverity     string   // Findings below this severity are dropped (empty keeps all)
+	ScanVendored    bool     // Walk vendor/ directories, which are skipped by default
+	ScanSkippedDirs []string // Other normally skipped directory names to walk anyway
+	ExcludePatterns []string // Path globs skipped in addition to the defaults and .gitignore
+	IncludePatterns []string // Path globs scanned even if otherwise skipped
+	DisableCache    bool     // Send every file to the model instead of reusing cached results
+	NotifyEmail     bool     // Whether to send an email notification when scan completes
+	Email           string   // Email address to notify when scan completes
+	Model           string   // LLM model to scan with; empty uses the default
+	BatchSize       int      // Scan at most this many not-yet-recorded files in this call (0 scans them all)
+	MaxFiles        int      // Most files the scan covers; 0 uses SCAN_MAX_FILES
+	MaxAttempts     int32    // The retry policy's MaximumAttempts, so the last attempt knows to fail the scan; 0 is the default
+
+	// Budget caps the estimated model usage of the whole scan; TokensUsed is what earlier batches spent of it
+	Budget     services.ScanBudget
+	TokensUsed int
+
+	// ActivityTimeout is this activity's StartToCloseTimeout, which the time budget must fit in; 0 means ScanActivityTimeout
+	ActivityTimeout time.Duration
+
+	// Incremental scans only analyze ChangedFiles and copy the rest of their findings from BaseScanID
+	IncrementalBaseSHA string   // Commit the scan diffs against; empty for a full scan
+	BaseScanID         string   // Scan of IncrementalBaseSHA; empty for a full scan
+	ChangedFiles       []string // Paths changed since IncrementalBaseSHA
 }
 
+// scope returns the options that decide which files the scan covers and which findings it keeps
+func (input ScanActivityInput) scope() services.ScanScope {
+	return services.ScanScope{
+		FileExtensions:  input.FileExtensions,
+		IncludeGlobs:    input.IncludeGlobs,
+		ScanVendored:    input.ScanVendored,
+		ScanSkippedDirs: input.ScanSkippedDirs,
+		ExcludePatterns: input.ExcludePatterns,
+		IncludePatterns: input.IncludePatterns,
+		Taxonomy:        input.Taxonomy,
+		VulnTypes:       input.VulnTypes,
+		MinSeverity:     input.MinSeverity,
+	}
+}
+
+// ScanCanceledErrorType is the ApplicationError type the scan activity fails with when the scan was
+// canceled before it started scanning
+const ScanCanceledErrorType = "ScanCanceled"
+
 // ScanActivityOutput represents the output from the scan repository activity
 // It contains the results of the security scan, including detected vulnerabilities
 type ScanActivityOutput struct {
 	RepositoryID         string                   // Repository identifier (for correlation)
 	ScanID               string                   // Unique identifier for this scan
+	Status               string                   // Final scan status (completed, time_budget_reached, budget_exceeded, or completed_with_errors), or scanning while files remain
+	FilesRemaining       int                      // Eligible files left for later batches; 0 once the scan is finished
+	TokensUsed           int                      // Estimated prompt tokens this call sent to the model
+	InsertFailures       int                      // Files of the whole scan whose findings couldn't be stored; set once the scan is finished
 	VulnCount            int                      // Total count of vulnerabilities found
 	VulnerabilitiesFound []services.Vulnerability // List of detected vulnerabilities
 	ScanTimestamp        time.Time                // When the scan was performed
 }
 
+// ScanStatusNotification describes a scan state transition to announce to webhook subscribers
+type ScanStatusNotification struct {
+	RepositoryID string // Repository being scanned
+	ScanID       string // Scan record that changed state
+	Status       string // New lifecycle status, e.g. "cloning"
+	Message      string // Optional detail, such as a failure reason
+}
+
+// NotifyScanStatusActivity posts a scan status event to the webhooks subscribed to it
+// Delivery problems are logged rather than returned so notifications never fail a scan
+func NotifyScanStatusActivity(ctx context.Context, input ScanStatusNotification) error {
+	log := logger.Get()
+
+	event := services.ScanStatusEvent{
+		Event:        services.ScanStatusEventPrefix + input.Status,
+		ScanID:       input.ScanID,
+		RepositoryID: input.RepositoryID,
+		Status:       input.Status,
+		Message:      input.Message,
+		Timestamp:    time.Now().UTC(),
+	}
+
+	webhookService := services.NewWebhookService(db.NewQueries())
+	if _, err := webhookService.DeliverScanStatus(ctx, event); err != nil {
+		log.Warn("Failed to deliver scan status webhooks",
+			zap.String("scan_id", input.ScanID),
+			zap.String("status", input.Status),
+			zap.Error(err))
+	}
+
+	return nil
+}
+
+// ScanCallbackInput describes a finished scan to post to the callback URL it was started with
+type ScanCallbackInput struct {
+	CallbackURL  string // https URL validated when the scan was requested
+	RepositoryID string // Repository that was scanned
+	ScanID       string // Scan record that finished
+	Status       string // Final status, e.g. "completed" or "failed"
+	Message      string // Human-readable outcome, such as a failure reason
+}
+
+// SendScanCallbackActivity posts a signed summary of a finished scan to its callback URL and records the
+// outcome on the scan. A failed delivery is returned so Temporal retries it; refused URLs and a missing
+// signing secret are not retried
+func SendScanCallbackActivity(ctx context.Context, input ScanCallbackInput) error {
+	log := logger.Get()
+
+	callbackService := services.NewScanCallbackService(db.NewQueries())
+	summary, err := callbackService.Summary(ctx, input.ScanID)
+	if err != nil {
+		// The outcome is still worth delivering without the counts
+		log.Warn("Failed to summarize scan findings for callback", zap.String("scan_id", input.ScanID), zap.Error(err))
+	}
+
+	deliveryErr := callbackService.Deliver(ctx, input.CallbackURL, &services.ScanCallbackPayload{
+		Event:        services.ScanStatusEventPrefix + input.Status,
+		ScanID:       input.ScanID,
+		RepositoryID: input.RepositoryID,
+		Status:       input.Status,
+		Message:      input.Message,
+		Summary:      summary,
+		ResultsURL:   services.ScanResultsURL(input.ScanID),
+		Timestamp:    time.Now().UTC(),
+	})
+
+	attempt := int(activity.GetInfo(ctx).Attempt)
+	if err := callbackService.RecordDelivery(ctx, input.ScanID, attempt, deliveryErr); err != nil {
+		log.Warn("Failed to record scan callback delivery", zap.String("scan_id", input.ScanID), zap.Error(err))
+	}
+
+	if deliveryErr != nil {
+		log.Warn("Scan callback delivery failed",
+			zap.String("scan_id", input.ScanID),
+			zap.Int("attempt", attempt),
+			zap.Error(deliveryErr))
+		if errors.Is(deliveryErr, services.ErrInvalidCallbackURL) || errors.Is(deliveryErr, services.ErrCallbacksDisabled) {
+			return temporal.NewNonRetryableApplicationError(deliveryErr.Error(), "CallbackRefused", deliveryErr)
+		}
+		return deliveryErr
+	}
+
+	log.Info("Delivered scan callback", zap.String("scan_id", input.ScanID), zap.String("status", input.Status))
+	return nil
+}
+
+// CheckWorkerPausedActivity reports whether an operator has paused scan processing
+// If the state can't be read the worker is treated as running, so an outage never strands queued scans
+func CheckWorkerPausedActivity(ctx context.Context) (bool, error) {
+	log := logger.Get()
+
+	dbQueries := db.NewQueries()
+	if dbQueries.GetDB() == nil {
+		return false, nil
+	}
+
+	state, err := services.NewWorkerControlService(dbQueries).State(ctx)
+	if err != nil {
+		log.Warn("Failed to read worker pause state, continuing", zap.Error(err))
+		return false, nil
+	}
+
+	return state.Paused, nil
+}
+
+// RemoveUploadActivity deletes the directory an uploaded archive was extracted to once its scan is over
+// Only directories under services.UploadsDir are removed, whatever the workflow input says
+func RemoveUploadActivity(ctx context.Context, uploadDir string) error {
+	log := logger.Get()
+
+	if !services.IsUploadDir(uploadDir) {
+		log.Error("Refusing to remove a directory outside the uploads directory", zap.String("upload_dir", uploadDir))
+		return temporal.NewNonRetryableApplicationError("not an upload directory: "+uploadDir, "InvalidUploadDir", nil)
+	}
+	if err := os.RemoveAll(uploadDir); err != nil {
+		return fmt.Errorf("failed to remove upload directory: %w", err)
+	}
+
+	log.Info("Removed uploaded archive", zap.String("upload_dir", uploadDir))
+	return nil
+}
+
+// RemoveWorkspaceActivity deletes the directory a scan cloned its repository into once the scan is over
+// Only directories under services.WorkspaceDir are removed, whatever the workflow input says
+func RemoveWorkspaceActivity(ctx context.Context, repoDir string) error {
+	log := logger.Get()
+
+	if !services.IsWorkspaceDir(repoDir) {
+		log.Error("Refusing to remove a directory outside the scan workspace directory", zap.String("repo_dir", repoDir))
+		return temporal.NewNonRetryableApplicationError("not a scan workspace: "+repoDir, "InvalidWorkspaceDir", nil)
+	}
+	if err := os.RemoveAll(repoDir); err != nil {
+		return fmt.Errorf("failed to remove scan workspace: %w", err)
+	}
+
+	log.Info("Removed scan workspace", zap.String("repo_dir", repoDir))
+	return nil
+}
+
 // CloneRepositoryActivity clones a GitHub repository to the local filesystem
 // This activity is responsible for downloading the source code from Git repositories
 // It handles both public and private repositories, using authentication when needed
-func CloneRepositoryActivity(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+func CloneRepositoryActivity(ctx context.Context, input CloneActivityInput) (output *CloneActivityOutput, err error) {
 	log := logger.Get()
 	log.Info("Starting clone repository activity", zap.String("repo_id", input.RepositoryID))
 
+	ctx, span := tracing.Start(ctx, "CloneRepositoryActivity",
+		tracing.RepositoryIDKey.String(input.RepositoryID),
+		tracing.ScanIDKey.String(input.ScanID),
+		attribute.String("git.ref", input.Ref))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	// Check if database is available and initialize services
 	dbQueries := db.NewQueries()
 	gitHubService := services.NewGitHubService(dbQueries)
 
+	// Move the scan from queued to cloning, and mark it canceled if this attempt is canceled, or failed once
+	// the error won't be retried; until then the scan stays cloning while Temporal retries the clone
+	updateScanStatus(ctx, dbQueries, input.ScanID, services.ScanStatusCloning, "")
+	defer func() {
+		if err == nil {
+			return
+		}
+		status := services.ScanStatusFailed
+		if errors.Is(ctx.Err(), context.Canceled) {
+			status = services.ScanStatusCanceled
+		} else if !lastAttempt(ctx, ScanTimeouts{CloneMaxAttempts: input.MaxAttempts}.withDefaults().CloneMaxAttempts, err) {
+			log.Warn("Clone attempt failed, leaving the scan cloning for the retry",
+				zap.String("scan_id", input.ScanID),
+				zap.Int32("attempt", activity.GetInfo(ctx).Attempt),
+				zap.Error(err))
+			return
+		}
+		// The activity context may already be canceled, which would abort the status update itself
+		updateScanStatus(context.WithoutCancel(ctx), dbQueries, input.ScanID, status, err.Error())
+	}()
+
+	// Only clone from the configured providers; retrying won't make a refused URL acceptable
+	if err := services.ValidateCloneURL(input.CloneURL); err != nil {
+		log.Error("Refusing to clone from repository URL", zap.String("repo_id", input.RepositoryID), zap.Error(err))
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "InvalidCloneURL", err)
+	}
+
+	// Heartbeat while cloning so a cancel request reaches this activity and interrupts the clone
+	stopHeartbeat := heartbeatUntilDone(ctx, cloneHeartbeatInterval)
+	defer stopHeartbeat()
+
 	// Create a repository object for the clone operation
 	repo := &services.Repository{
 		ID:       input.RepositoryID,
 		CloneURL: input.CloneURL,
 	}
 
-	// Create a temporary directory for the repository
-	// The repository will be cloned into a unique subdirectory
-	tmpDir := os.TempDir()
-	repoDir := fmt.Sprintf("%s/repos/%s", tmpDir, input.RepositoryID)
+	// Clone into the scan's own workspace so concurrent scans of a repository don't share a directory
+	// The workflow removes it once the scan ends
+	workspaceKey := input.ScanID
+	if workspaceKey == "" {
+		workspaceKey = input.RepositoryID
+	}
+	repoDir := services.ScanWorkspaceDir(workspaceKey)
 
 	// Check if the repository directory already exists
 	// If it does, remove it to ensure a clean clone
@@ -95,51 +357,65 @@ func CloneRepositoryActivity(ctx context.Context, input CloneActivityInput) (*Cl
 		return nil, fmt.Errorf("failed to create repository directory: %w", err)
 	}
 
+	// Don't leave a partial clone behind when the clone fails or is canceled
+	defer func() {
+		if err != nil {
+			if removeErr := os.RemoveAll(repoDir); removeErr != nil {
+				log.Warn("Failed to remove partial clone",
+					zap.String("repo_dir", repoDir),
+					zap.Error(removeErr))
+				return
+			}
+			log.Info("Removed partial clone", zap.String("repo_dir", repoDir))
+		}
+	}()
+
 	log.Info("Cloning repository",
 		zap.String("repo_id", input.RepositoryID),
 		zap.String("clone_url", input.CloneURL),
+		zap.String("ref", input.Ref),
 		zap.String("repo_dir", repoDir))
 
 	// First try without authentication (for public repos)
 	// This will succeed for public repositories without requiring credentials
-	err := gitHubService.CloneRepository(ctx, repo, repoDir)
+	// Incremental scans need the history back to their base commit, so they can't clone shallow
+	fullHistory := input.IncrementalSince != ""
+	checkedOutRef, err := gitHubService.CloneRepositoryAtRef(ctx, repo, repoDir, input.Ref, fullHistory)
 	if err != nil {
-		// If we get an authentication error, check if GITHUB_TOKEN is set
+		// If we get an authentication error, retry with the hosting provider's token (GITHUB_TOKEN or GITLAB_TOKEN)
 		// This handles private repositories that require authentication
 		if strings.Contains(err.Error(), "authentication required") || strings.Contains(err.Error(), "Invalid username or password") {
-			log.Info("Authentication required, checking for GitHub token")
-			githubToken := os.Getenv("GITHUB_TOKEN")
+			providers := services.NewRepoProviders(gitHubService)
+			provider, providerErr := providers.ForURL(input.CloneURL)
+			if providerErr != nil {
+				provider = providers.ForName(services.ProviderGitHub)
+			}
+			log.Info("Authentication required, checking for access token", zap.String("provider", provider.Name()))
 
-			if githubToken == "" {
-				log.Warn("Repository requires authentication but GITHUB_TOKEN is not set")
-				return nil, fmt.Errorf("repository requires authentication but GITHUB_TOKEN environment variable is not set")
+			// The authenticated URL includes the access token, so it is never logged
+			authenticatedURL, authErr := provider.AuthenticatedCloneURL(input.CloneURL)
+			if authErr != nil {
+				log.Warn("Repository requires authentication but cannot authenticate", zap.Error(authErr))
+				return nil, authErr
 			}
+			log.Info("Retrying with authenticated URL")
 
-			// For GitHub URLs, construct an authenticated URL with the token
-			// This modifies the URL to include the access token for authentication
-			if strings.HasPrefix(input.CloneURL, "https://github.com") {
-				authenticatedURL := strings.Replace(input.CloneURL, "https://github.com", fmt.Sprintf("https://%s@github.com", githubToken), 1)
-				log.Info("Retrying with authenticated URL")
-
-				// Create a new repo object with the authenticated URL
-				authRepo := &services.Repository{
-					ID:       input.RepositoryID,
-					CloneURL: authenticatedURL,
-				}
-
-				// Try cloning again with authentication
-				err = gitHubService.CloneRepository(ctx, authRepo, repoDir)
-				if err != nil {
-					log.Error("Failed to clone repository with authentication",
-						zap.String("repo_id", input.RepositoryID),
-						zap.Error(err))
-					return nil, fmt.Errorf("failed to clone repository with authentication: %w", err)
-				}
-
-				log.Info("Repository cloned successfully with authenticated URL")
-			} else {
-				return nil, fmt.Errorf("repository requires authentication but URL format is not supported for authentication")
+			// Create a new repo object with the authenticated URL
+			authRepo := &services.Repository{
+				ID:       input.RepositoryID,
+				CloneURL: authenticatedURL,
+			}
+
+			// Try cloning again with authentication
+			checkedOutRef, err = gitHubService.CloneRepositoryAtRef(ctx, authRepo, repoDir, input.Ref, fullHistory)
+			if err != nil {
+				log.Error("Failed to clone repository with authentication",
+					zap.String("repo_id", input.RepositoryID),
+					zap.Error(err))
+				return nil, fmt.Errorf("failed to clone repository with authentication: %w", err)
 			}
+
+			log.Info("Repository cloned successfully with authenticated URL")
 		} else {
 			log.Error("Failed to clone repository",
 				zap.String("repo_id", input.RepositoryID),
@@ -152,37 +428,262 @@ func CloneRepositoryActivity(ctx context.Context, input CloneActivityInput) (*Cl
 
 	log.Info("Repository cloned successfully", zap.String("repo_dir", repoDir))
 
-	// Return the output with the repository directory where the code was cloned
-	return &CloneActivityOutput{
+	output = &CloneActivityOutput{
 		RepositoryID: input.RepositoryID,
 		RepoDir:      repoDir,
-	}, nil
+	}
+
+	// Note which commit is being scanned and the base it can be compared against
+	// Branch state is best effort: a scan still runs without it
+	ref, sha, headErr := services.HeadCommit(repoDir)
+	if headErr != nil {
+		log.Warn("Failed to resolve cloned commit", zap.String("repo_id", input.RepositoryID), zap.Error(headErr))
+		return output, nil
+	}
+	output.CommitSHA = sha
+
+	if input.IncrementalSince != "" {
+		if err := resolveIncrementalBase(ctx, dbQueries, input, output); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tags leave HEAD detached, so use the reference the clone checked out
+	// A pinned commit belongs to no branch and has no base to track
+	switch {
+	case services.IsCommitRef(input.Ref):
+		log.Info("Cloned pinned commit", zap.String("repo_id", input.RepositoryID), zap.String("commit_sha", sha))
+		return output, nil
+	case checkedOutRef != "":
+		ref = checkedOutRef
+	}
+	output.Ref = ref
+
+	if dbQueries.GetDB() != nil {
+		baseSHA, found, stateErr := services.NewBranchStateService(dbQueries).LastScannedCommit(ctx, input.RepositoryID, ref)
+		switch {
+		case stateErr != nil:
+			log.Warn("Failed to read branch state", zap.String("repo_id", input.RepositoryID), zap.Error(stateErr))
+		case !found:
+			log.Info("First scan of branch", zap.String("repo_id", input.RepositoryID), zap.String("ref", ref))
+		default:
+			output.BaseCommitSHA = baseSHA
+			log.Info("Found last scanned commit of branch",
+				zap.String("repo_id", input.RepositoryID),
+				zap.String("ref", ref),
+				zap.String("base_sha", baseSHA),
+				zap.String("head_sha", sha))
+		}
+	}
+
+	// Return the output with the repository directory where the code was cloned
+	return output, nil
+}
+
+// resolveIncrementalBase fills in what an incremental scan needs: the files changed since the
+// requested base commit and the completed scan of that commit to carry findings forward from
+// A base commit missing from the repository is an error; a base that was never fully scanned
+// leaves the fields empty so the scan covers the full tree instead
+func resolveIncrementalBase(ctx context.Context, dbQueries *db.Queries, input CloneActivityInput, output *CloneActivityOutput) error {
+	log := logger.Get()
+
+	changes, err := services.ChangedFiles(output.RepoDir, input.IncrementalSince)
+	if err != nil {
+		log.Error("Failed to diff against incremental base",
+			zap.String("repo_id", input.RepositoryID),
+			zap.String("incremental_since", input.IncrementalSince),
+			zap.Error(err))
+		return fmt.Errorf("failed to diff against incremental base: %w", err)
+	}
+
+	if dbQueries.GetDB() == nil {
+		log.Warn("Database unavailable, scanning full tree instead of incrementally",
+			zap.String("repo_id", input.RepositoryID))
+		return nil
+	}
+
+	baseScanID, found, err := services.NewBranchStateService(dbQueries).CompletedScanAtCommit(ctx, input.RepositoryID, changes.BaseSHA, input.Scope.Key())
+	if err != nil {
+		log.Warn("Failed to look up scan of incremental base, scanning full tree",
+			zap.String("repo_id", input.RepositoryID),
+			zap.String("base_sha", changes.BaseSHA),
+			zap.Error(err))
+		return nil
+	}
+	if !found {
+		log.Warn("No completed, untruncated scan of incremental base with the same scope, scanning full tree",
+			zap.String("repo_id", input.RepositoryID),
+			zap.String("base_sha", changes.BaseSHA),
+			zap.String("scan_scope", input.Scope.Key()))
+		return nil
+	}
+
+	output.IncrementalBaseSHA = changes.BaseSHA
+	output.BaseScanID = baseScanID
+	output.ChangedFiles = changes.Paths
+	log.Info("Scanning incrementally",
+		zap.String("repo_id", input.RepositoryID),
+		zap.String("base_sha", changes.BaseSHA),
+		zap.String("base_scan_id", baseScanID),
+		zap.Int("changed_files", len(changes.Paths)))
+	return nil
+}
+
+// lastAttempt reports whether Temporal won't retry an activity that failed with err: the error is
+// non-retryable or this was the last of the retry policy's maxAttempts
+func lastAttempt(ctx context.Context, maxAttempts int32, err error) bool {
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.NonRetryable() {
+		return true
+	}
+	return activity.GetInfo(ctx).Attempt >= maxAttempts
+}
+
+// cloneHeartbeatInterval and scanHeartbeatInterval are how often the clone and scan activities heartbeat
+// They must stay well under the activities' HeartbeatTimeout
+const (
+	cloneHeartbeatInterval = 10 * time.Second
+	scanHeartbeatInterval  = 20 * time.Second
+)
+
+// recordHeartbeat reports an activity's liveness to Temporal; tests replace it to observe heartbeats
+var recordHeartbeat = activity.RecordHeartbeat
+
+// heartbeatUntilDone records activity heartbeats every interval until stop is called or ctx ends
+// Temporal only delivers cancellation to activities that heartbeat
+func heartbeatUntilDone(ctx context.Context, interval time.Duration) (stop func()) {
+	return everyUntilDone(ctx, interval, func() { recordHeartbeat(ctx) })
+}
+
+// everyUntilDone calls fn every interval until stop is called or ctx ends
+func everyUntilDone(ctx context.Context, interval time.Duration, fn func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// scanProgressReporter keeps the latest progress of a scan, which its heartbeats carry to Temporal and save to
+// the scans row for the status endpoint. Saving only on heartbeats, and only when the progress changed,
+// keeps it to one write per heartbeat interval however fast files finish
+type scanProgressReporter struct {
+	mu       sync.Mutex
+	progress services.ScanProgress
+	saved    services.ScanProgress
+	save     func(ctx context.Context, progress services.ScanProgress) error // nil when there is nowhere to save
+}
+
+// update replaces the latest progress; it is the scanner's OnProgress hook
+func (r *scanProgressReporter) update(progress services.ScanProgress) {
+	r.mu.Lock()
+	r.progress = progress
+	r.mu.Unlock()
+}
+
+// saveWith sets where progress is saved
+func (r *scanProgressReporter) saveWith(save func(ctx context.Context, progress services.ScanProgress) error) {
+	r.mu.Lock()
+	r.save = save
+	r.mu.Unlock()
+}
+
+// heartbeat records a heartbeat with the latest progress as its details, and saves the progress if it changed
+func (r *scanProgressReporter) heartbeat(ctx context.Context) {
+	r.mu.Lock()
+	progress, save := r.progress, r.save
+	changed := progress != r.saved
+	r.mu.Unlock()
+
+	recordHeartbeat(ctx, progress)
+	if !changed || save == nil {
+		return
+	}
+	if err := save(ctx, progress); err != nil {
+		// Progress is informational, so a failed save never fails the scan
+		logger.Warn("Failed to save scan progress", zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	r.saved = progress
+	r.mu.Unlock()
 }
 
 // ScanRepositoryActivity scans a repository for vulnerabilities
 // This activity analyzes the source code to detect security issues and vulnerabilities
 // It processes the code using AI models to identify OWASP Top 10 security risks
-func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (output *ScanActivityOutput, err error) {
 	log := logger.Get()
 	log.Info("Starting repository scan activity",
 		zap.String("repo_id", input.RepositoryID),
 		zap.String("repo_dir", input.RepoDir))
 
+	ctx, span := tracing.Start(ctx, "ScanRepositoryActivity",
+		tracing.RepositoryIDKey.String(input.RepositoryID),
+		tracing.ScanIDKey.String(input.ScanID),
+		tracing.ModelKey.String(scanModel(input.Model)),
+		attribute.String("git.commit_sha", input.CommitSHA))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	// Heartbeat throughout so Temporal notices a batch whose worker died without waiting out its timeout
+	// Each heartbeat carries how far the scan has got
+	progressReporter := &scanProgressReporter{}
+	stopHeartbeat := everyUntilDone(ctx, scanHeartbeatInterval, func() { progressReporter.heartbeat(ctx) })
+	defer stopHeartbeat()
+
+	activityTimeout := input.ActivityTimeout
+	if activityTimeout <= 0 {
+		activityTimeout = ScanActivityTimeout
+	}
+
 	// Create instances of required services
 	// These services handle the various aspects of the scanning process
 	dbQueries := db.NewQueries()
 	githubService := services.NewGitHubService(dbQueries)
 	scannerService := services.NewScannerService(githubService)
 
-	// Generate a unique scan ID to track this specific scan operation
-	scanID := uuid.New().String()
+	// Use the scan record created when the scan was queued, or generate a new ID
+	// for callers that start the workflow without one
+	scanID := input.ScanID
+	if scanID == "" {
+		scanID = uuid.New().String()
+	}
+
+	// A taxonomy this worker doesn't have registered falls back to the default rather than failing the scan
+	taxonomy, ok := services.LookupTaxonomy(input.Taxonomy)
+	if !ok {
+		log.Warn("Scan taxonomy is not registered on this worker, using the default",
+			zap.String("scan_id", scanID),
+			zap.String("taxonomy", input.Taxonomy))
+		taxonomy = services.OWASP2021Taxonomy
+	}
 
 	// Get the database connection to record scan information
 	sqlDB := dbQueries.GetDB()
 
+	// When the scan first started, for the duration metric; batched scans span several activity calls,
+	// so this is replaced by the stored start time when the database has one
+	scanStartedAt := time.Now()
+
 	// Check if database connection is available to persist scan results
 	var databaseAvailable bool = false
 	var submitterEmail string    // Track the email of the user who submitted the scan
+	var submitterOptedOut bool   // The submitter turned emails off or gets a digest instead
 	var createdBy sql.NullString // Define createdBy at a broader scope
 	if sqlDB != nil {
 		databaseAvailable = true
@@ -201,27 +702,54 @@ func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*Scan
 		} else if createdBy.Valid && createdBy.String != "" {
 			// If we have a creator ID, try to get their email for later notification
 			// This email will be used to send scan results if notifications are enabled
+			var receiveNotifications bool
+			var notificationFrequency string
 			err = sqlDB.QueryRowContext(ctx,
-				`SELECT email FROM users WHERE id = $1 AND email IS NOT NULL AND email != ''`,
-				createdBy.String).Scan(&submitterEmail)
+				`SELECT email, receive_notifications, notification_frequency FROM users
+				WHERE id = $1 AND email IS NOT NULL AND email != ''`,
+				createdBy.String).Scan(&submitterEmail, &receiveNotifications, &notificationFrequency)
 
 			if err != nil {
 				log.Warn("Failed to get submitter email",
 					zap.String("user_id", createdBy.String),
 					zap.Error(err))
 				// Continue anyway, the email notification will be skipped
+			} else if !receiveNotifications || notificationFrequency != services.NotificationFrequencyImmediate {
+				// Digest users hear about this scan in their next digest instead
+				log.Info("Submitter does not receive an email per scan",
+					zap.String("user_id", createdBy.String),
+					zap.Bool("receive_notifications", receiveNotifications),
+					zap.String("notification_frequency", notificationFrequency))
+				submitterEmail = ""
+				submitterOptedOut = true
 			} else {
 				log.Info("Found submitter email for notifications",
 					zap.String("email", submitterEmail))
 			}
 		}
 
-		// Create a scan record in the database to track the scan progress
+		// Create or advance the scan record to the scanning state
 		// This record will be updated when the scan completes or fails
-		_, err = sqlDB.ExecContext(ctx,
-			`INSERT INTO scans (id, repository_id, status, started_at, created_by, error_message)
-			VALUES ($1, $2, $3, NOW(), $4, $5)`,
-			scanID, input.RepositoryID, "in_progress", createdBy, "")
+		err = sqlDB.QueryRowContext(ctx,
+			`INSERT INTO scans (id, repository_id, status, started_at, created_by, error_message, model, ref, commit_sha, base_commit_sha, taxonomy, scan_scope)
+			VALUES ($1, $2, $3, NOW(), $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, $11)
+			ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, error_message = EXCLUDED.error_message,
+				started_at = COALESCE(scans.started_at, EXCLUDED.started_at),
+				ref = COALESCE(EXCLUDED.ref, scans.ref), commit_sha = COALESCE(EXCLUDED.commit_sha, scans.commit_sha),
+				base_commit_sha = COALESCE(EXCLUDED.base_commit_sha, scans.base_commit_sha),
+				taxonomy = EXCLUDED.taxonomy, scan_scope = EXCLUDED.scan_scope, updated_at = NOW()
+			WHERE scans.status <> 'canceled'
+			RETURNING started_at`,
+			scanID, input.RepositoryID, services.ScanStatusScanning, createdBy, "", scanModel(input.Model), input.Ref, input.CommitSHA,
+			input.IncrementalBaseSHA, taxonomy.Name, input.scope().Key()).
+			Scan(&scanStartedAt)
+		if err == sql.ErrNoRows {
+			// Canceled while cloning, after the clone finished; the workflow's cancellation is on its way
+			log.Info("Scan was canceled before scanning started",
+				zap.String("scan_id", scanID),
+				zap.String("repo_id", input.RepositoryID))
+			return nil, temporal.NewNonRetryableApplicationError("scan was canceled", ScanCanceledErrorType, nil)
+		}
 		if err != nil {
 			log.Error("Failed to create scan record in database",
 				zap.String("scan_id", scanID),
@@ -239,23 +767,99 @@ func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*Scan
 			zap.String("repo_id", input.RepositoryID))
 	}
 
-	// Convert string vulnerability types to the enum type
+	// Convert string vulnerability types to the enum type; without any, look for every category of the taxonomy
 	var vulnerabilityTypes []services.VulnerabilityType
 	for _, vulnType := range input.VulnTypes {
 		vulnerabilityTypes = append(vulnerabilityTypes, services.VulnerabilityType(vulnType))
 	}
+	if len(vulnerabilityTypes) == 0 {
+		vulnerabilityTypes = taxonomy.Categories
+	}
 
 	// Configure scan options
 	scanOptions := &services.ScanOptions{
 		VulnerabilityTypes: vulnerabilityTypes,
 		FileExtensions:     input.FileExtensions,
-		MaxFiles:           100, // Limit the number of files to scan
+		IncludeGlobs:       input.IncludeGlobs,
+		MinSeverity:        input.MinSeverity,
+		ScanVendored:       input.ScanVendored,
+		ScanSkippedDirs:    input.ScanSkippedDirs,
+		ExcludePatterns:    input.ExcludePatterns,
+		IncludePatterns:    input.IncludePatterns,
+		DisableCache:       input.DisableCache,
+		MaxFiles:           input.MaxFiles,                     // Limit the number of files to scan
+		MaxFileSizeBytes:   services.MaxFileSizeBytesFromEnv(), // Larger files are skipped without being read
+		Budget:             input.Budget,                       // Stop early with partial results once the token or cost budget is spent
+		TokensUsed:         input.TokensUsed,                   // Spent by earlier batches, so the budget covers the whole scan
+		Concurrency:        scanConcurrency(),                  // Files sent to the model in parallel
+		TimeBudget:         scanTimeBudget(activityTimeout),    // Stop early with partial results before the activity times out
+		ActivityTimeout:    activityTimeout,
+		PathRules:          services.PathRulesFromEnv(),
+		Model:              input.Model,
+		LLMDenylist:        services.LLMDenylistFromEnv(), // Files that must never reach the external model
+		OnProgress:         progressReporter.update,
+		DedupLineTolerance: services.DedupLineToleranceFromEnv(), // Same-type findings this close in a file are merged
+	}
+
+	// Scans started before the limit was carried in the workflow input read it here
+	if scanOptions.MaxFiles <= 0 {
+		scanOptions.MaxFiles = ScanMaxFiles()
+	}
+
+	// Incremental scans only analyze what changed; findings for the rest are carried forward below
+	incremental := input.BaseScanID != "" && databaseAvailable
+	if incremental {
+		scanOptions.OnlyFiles = make(map[string]bool, len(input.ChangedFiles))
+		for _, path := range input.ChangedFiles {
+			scanOptions.OnlyFiles[path] = true
+		}
+	}
+
+	// Record each file as it finishes so a retried activity resumes instead of starting over
+	progressService := services.NewScanProgressService(dbQueries)
+	if databaseAvailable {
+		completedFiles, err := progressService.CompletedFiles(ctx, scanID)
+		if err != nil {
+			log.Warn("Failed to load recorded scan progress, scanning all files",
+				zap.String("scan_id", scanID),
+				zap.Error(err))
+		} else {
+			scanOptions.CompletedFiles = completedFiles
+		}
+
+		// Unchanged files reuse the model result stored by an earlier scan
+		scanOptions.Cache = services.NewScanCache(dbQueries)
+
+		scanOptions.OnFileScanned = func(ctx context.Context, relPath string, vulnerabilities []*services.Vulnerability) error {
+			return recordScannedFile(ctx, progressService, scanID, relPath, vulnerabilities)
+		}
+		// A file whose model request failed or reply couldn't be parsed is listed in scan_errors instead of passing as clean
+		scanOptions.OnFileFailed = func(ctx context.Context, relPath string, cause error) error {
+			return progressService.RecordFileFailure(ctx, scanID, relPath, cause)
+		}
+		progressReporter.saveWith(func(ctx context.Context, progress services.ScanProgress) error {
+			return progressService.SaveProgress(ctx, scanID, progress)
+		})
+
+		// Batching relies on recorded progress to know where the next batch starts,
+		// so without the database the whole scan runs in this one call
+		scanOptions.BatchSize = input.BatchSize
 	}
 
 	log.Info("Starting code scan",
 		zap.String("scan_id", scanID),
 		zap.Strings("vuln_types", input.VulnTypes),
-		zap.Strings("file_extensions", input.FileExtensions))
+		zap.Strings("file_extensions", input.FileExtensions),
+		zap.Strings("include_globs", input.IncludeGlobs),
+		zap.Bool("scan_vendored", input.ScanVendored),
+		zap.Strings("scan_skipped_dirs", input.ScanSkippedDirs),
+		zap.Strings("exclude_patterns", input.ExcludePatterns),
+		zap.Strings("include_patterns", input.IncludePatterns))
+
+	// Count the scan as started only on the first attempt of its first batch, not on batches or retries
+	if len(scanOptions.CompletedFiles) == 0 && activity.GetInfo(ctx).Attempt == 1 {
+		metrics.ScanStarted()
+	}
 
 	// Perform the scan
 	scanResult, err := scannerService.ScanRepository(ctx, input.RepoDir, scanOptions)
@@ -264,90 +868,125 @@ func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*Scan
 			zap.String("repo_id", input.RepositoryID),
 			zap.Error(err))
 
-		// Update scan status to failed if database is available
-		if databaseAvailable && sqlDB != nil {
-			errMsg := err.Error()
-			if errMsg == "" {
-				errMsg = "Unknown scan error occurred"
+		// Mark the scan canceled if this attempt was canceled, or failed once the error won't be retried;
+		// until then the scan stays scanning while Temporal retries the batch, which resumes from recorded progress
+		// Only a scan that failed for good counts as failed, so retries can't push failures past starts
+		errMsg := err.Error()
+		if errMsg == "" {
+			errMsg = "Unknown scan error occurred"
+		}
+		maxAttempts := ScanTimeouts{ScanMaxAttempts: input.MaxAttempts}.withDefaults().ScanMaxAttempts
+		switch {
+		case errors.Is(ctx.Err(), context.Canceled):
+			if databaseAvailable {
+				// The activity context is canceled, which would abort the status update itself
+				updateScanStatus(context.WithoutCancel(ctx), dbQueries, scanID, services.ScanStatusCanceled, errMsg)
 			}
-
-			_, updateErr := sqlDB.ExecContext(ctx,
-				`UPDATE scans SET status = $1, error_message = $2, completed_at = NOW() WHERE id = $3`,
-				"failed", errMsg, scanID)
-			if updateErr != nil {
-				log.Error("Failed to update scan status",
-					zap.String("scan_id", scanID),
-					zap.Error(updateErr))
+		case lastAttempt(ctx, maxAttempts, err):
+			metrics.ScanFailed()
+			if databaseAvailable {
+				updateScanStatus(ctx, dbQueries, scanID, services.ScanStatusFailed, errMsg)
 			}
+		default:
+			log.Warn("Scan attempt failed, leaving the scan scanning for the retry",
+				zap.String("scan_id", scanID),
+				zap.Int32("attempt", activity.GetInfo(ctx).Attempt),
+				zap.Int32("max_attempts", maxAttempts))
 		}
 
 		return nil, fmt.Errorf("failed to scan repository: %w", err)
 	}
 
-	// Store the vulnerabilities in the database if available
-	var vulnList []services.Vulnerability
-	var dbErrors []error
+	// Save where the batch ended rather than where the last heartbeat saw it
+	progressReporter.heartbeat(ctx)
 
-	if databaseAvailable && sqlDB != nil && scanResult != nil && len(scanResult.Vulnerabilities) > 0 {
-		log.Info("Storing vulnerability findings in database",
+	log.Info("Scan cache usage",
+		zap.String("scan_id", scanID),
+		zap.Bool("cache_disabled", input.DisableCache),
+		zap.Int("cache_hits", scanResult.CacheHits),
+		zap.Int("cache_misses", scanResult.CacheMisses))
+	if scanResult.FilesTimedOut > 0 {
+		log.Warn("Model requests timed out for some files; only local detectors covered them",
 			zap.String("scan_id", scanID),
-			zap.Int("vuln_count", len(scanResult.Vulnerabilities)))
+			zap.Int("files_timed_out", scanResult.FilesTimedOut))
+	}
+	if scanResult.FilesTooLarge > 0 {
+		log.Info("Skipped files over the maximum file size",
+			zap.String("scan_id", scanID),
+			zap.Int("files_too_large", scanResult.FilesTooLarge))
+	}
+	if scanResult.FilesParseFailed > 0 {
+		log.Warn("Model replies could not be parsed for some files; they were recorded in scan_errors",
+			zap.String("scan_id", scanID),
+			zap.Int("files_parse_failed", scanResult.FilesParseFailed))
+	}
+	if scanResult.FilesModelFailed > 0 {
+		log.Warn("Model requests failed for some files; they were recorded in scan_errors",
+			zap.String("scan_id", scanID),
+			zap.Int("files_model_failed", scanResult.FilesModelFailed))
+	}
 
-		// Prepare a transaction for bulk inserts
-		tx, err := sqlDB.BeginTx(ctx, nil)
-		if err != nil {
-			log.Error("Failed to begin transaction for storing vulnerabilities",
-				zap.String("scan_id", scanID),
-				zap.Error(err))
-		} else {
-			// Insert vulnerabilities within the transaction
-			for _, vuln := range scanResult.Vulnerabilities {
-				vulnID := uuid.New().String()
-				_, err := tx.ExecContext(ctx,
-					`INSERT INTO vulnerabilities (
-						id, scan_id, vulnerability_type, file_path,
-						line_start, line_end, severity, description,
-						remediation, code_snippet, created_at, updated_at
-					) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())`,
-					vulnID, scanID, string(vuln.Type), vuln.FilePath,
-					vuln.LineStart, vuln.LineEnd, vuln.Severity, vuln.Description,
-					vuln.Remediation, vuln.Code)
-
-				if err != nil {
-					dbErrors = append(dbErrors, err)
-					log.Error("Failed to insert vulnerability",
-						zap.String("scan_id", scanID),
-						zap.String("vuln_type", string(vuln.Type)),
-						zap.Error(err))
-					continue
-				}
-
-				// Add to the list of vulnerabilities to return
-				vulnWithID := services.Vulnerability{
-					ID:          vulnID,
-					Type:        vuln.Type,
-					FilePath:    vuln.FilePath,
-					LineStart:   vuln.LineStart,
-					LineEnd:     vuln.LineEnd,
-					Severity:    vuln.Severity,
-					Description: vuln.Description,
-					Remediation: vuln.Remediation,
-					Code:        vuln.Code,
-				}
-				vulnList = append(vulnList, vulnWithID)
-			}
+	scanTokens := input.TokensUsed + scanResult.TokensUsed
+	log.Info("Scan usage estimate",
+		zap.String("scan_id", scanID),
+		zap.Int("batch_estimated_tokens", scanResult.TokensUsed),
+		zap.Float64("batch_estimated_cost_usd", scanResult.EstimatedCostUSD),
+		zap.Int("scan_estimated_tokens", scanTokens),
+		zap.Float64("scan_estimated_cost_usd", services.EstimateCostUSD(scanModel(input.Model), scanTokens)))
+
+	// More batches to go: progress is already recorded per file, so leave finalizing to the last batch
+	if scanResult.FilesRemaining > 0 && !scanResult.TimeBudgetReached && !scanResult.BudgetExceeded {
+		log.Info("Scan batch completed",
+			zap.String("scan_id", scanID),
+			zap.Int("files_scanned", scanResult.FilesScanned),
+			zap.Int("files_remaining", scanResult.FilesRemaining))
+
+		return &ScanActivityOutput{
+			RepositoryID:   input.RepositoryID,
+			ScanID:         scanID,
+			Status:         services.ScanStatusScanning,
+			FilesRemaining: scanResult.FilesRemaining,
+			TokensUsed:     scanResult.TokensUsed,
+			ScanTimestamp:  time.Now(),
+		}, nil
+	}
+
+	// Collect the findings to return with the activity output
+	var vulnList []services.Vulnerability
 
-			// Commit the transaction
-			if err := tx.Commit(); err != nil {
-				log.Error("Failed to commit transaction for storing vulnerabilities",
+	if databaseAvailable && sqlDB != nil && scanResult != nil {
+		if incremental {
+			carried, err := progressService.CarryForwardFindings(ctx, scanID, input.BaseScanID, input.ChangedFiles)
+			if err != nil {
+				log.Error("Failed to carry findings forward",
 					zap.String("scan_id", scanID),
+					zap.String("base_scan_id", input.BaseScanID),
 					zap.Error(err))
-			} else {
-				log.Info("Successfully stored vulnerabilities in database",
-					zap.String("scan_id", scanID),
-					zap.Int("vuln_count", len(vulnList)))
+				return nil, fmt.Errorf("failed to carry findings forward: %w", err)
 			}
+			log.Info("Carried findings forward from base scan",
+				zap.String("scan_id", scanID),
+				zap.String("base_scan_id", input.BaseScanID),
+				zap.Int("changed_files", len(input.ChangedFiles)),
+				zap.Int("findings_carried", carried))
+		}
+
+		// Findings were stored file by file during the scan; reload the full set so
+		// files completed by an earlier interrupted attempt are included
+		storedVulns, err := progressService.ScanVulnerabilities(ctx, scanID)
+		if err != nil {
+			log.Error("Failed to load stored vulnerabilities",
+				zap.String("scan_id", scanID),
+				zap.Error(err))
 		}
+		for _, vuln := range storedVulns {
+			vulnList = append(vulnList, *vuln)
+		}
+
+		log.Info("Vulnerability findings stored in database",
+			zap.String("scan_id", scanID),
+			zap.Int("vuln_count", len(vulnList)),
+			zap.Int("files_resumed", scanResult.FilesResumed))
 	} else if scanResult != nil {
 		// Database unavailable, but we still have scan results, so include them in the output
 		log.Info("Database unavailable for storing vulnerabilities, returning only in memory",
@@ -366,16 +1005,57 @@ func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*Scan
 				Description: vuln.Description,
 				Remediation: vuln.Remediation,
 				Code:        vuln.Code,
+				Excluded:    vuln.Excluded,
 			}
 			vulnList = append(vulnList, vulnWithID)
 		}
 	}
 
+	// Findings in excluded paths are returned but don't count toward the reported total
+	reportedCount := countReportedFindings(vulnList)
+
+	// Scans cut short by the time or cost budget keep their partial results but get a distinct status
+	// A budget stop also leaves a note on the scan saying how much was spent, and lost findings say how many files
+	finalStatus := services.ScanStatusCompleted
+	statusNote := ""
+	if scanResult != nil && scanResult.TimeBudgetReached {
+		finalStatus = services.ScanStatusTimeBudgetReached
+	} else if scanResult != nil && scanResult.BudgetExceeded {
+		finalStatus = services.ScanStatusBudgetExceeded
+		statusNote = fmt.Sprintf("%s: scan stopped after an estimated %d tokens ($%.2f)",
+			services.ScanStatusBudgetExceeded, scanTokens, services.EstimateCostUSD(scanModel(input.Model), scanTokens))
+	}
+
+	// A scan that lost the findings of too many files says so, since its results can't be trusted as complete
+	insertFailures := 0
+	if databaseAvailable && sqlDB != nil {
+		var countErr error
+		insertFailures, countErr = progressService.InsertFailures(ctx, scanID)
+		if countErr != nil {
+			log.Error("Failed to count insert failures",
+				zap.String("scan_id", scanID),
+				zap.Error(countErr))
+		}
+	}
+	if insertFailures > 0 {
+		log.Warn("Findings of some files could not be stored",
+			zap.String("scan_id", scanID),
+			zap.Int("insert_failures", insertFailures),
+			zap.Int("max_insert_failures", maxInsertFailures()))
+	}
+	if status := insertFailureStatus(finalStatus, insertFailures); status != finalStatus {
+		finalStatus = status
+		statusNote = fmt.Sprintf("%s: findings of %d files could not be stored", status, insertFailures)
+	}
+
+	metrics.ScanFinished(finalStatus, time.Since(scanStartedAt), findingsBySeverity(vulnList))
+
 	// Update scan status to completed
 	if databaseAvailable && sqlDB != nil {
 		_, err = sqlDB.ExecContext(ctx,
-			`UPDATE scans SET status = $1, completed_at = NOW(), results_available = true WHERE id = $2`,
-			"completed", scanID)
+			`UPDATE scans SET status = $1, error_message = $2, completed_at = NOW(), results_available = true, truncated = $4
+			WHERE id = $3`,
+			finalStatus, statusNote, scanID, scanResult.Truncated)
 		if err != nil {
 			log.Error("Failed to update scan status",
 				zap.String("scan_id", scanID),
@@ -383,14 +1063,36 @@ func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*Scan
 			return nil, fmt.Errorf("failed to update scan status: %w", err)
 		}
 
-		log.Info("Updated scan status to completed and set results_available flag",
-			zap.String("scan_id", scanID))
+		log.Info("Updated scan status and set results_available flag",
+			zap.String("scan_id", scanID),
+			zap.String("status", finalStatus))
 
-		// Send email notification to the scan submitter
+		// Store the per-severity summary alongside the scan record
+		if _, err := services.NewReindexService(dbQueries).RefreshScanSummary(ctx, scanID); err != nil {
+			log.Error("Failed to store scan summary",
+				zap.String("scan_id", scanID),
+				zap.Error(err))
+		}
+
+		// Only a complete scan of the default scope moves the branch's base; partial or narrowed results
+		// would hide unscanned changes
+		if finalStatus == services.ScanStatusCompleted && input.scope().IsDefault() && !scanResult.Truncated &&
+			input.Ref != "" && input.CommitSHA != "" {
+			err := services.NewBranchStateService(dbQueries).RecordScannedCommit(ctx, input.RepositoryID, input.Ref, input.CommitSHA, scanID)
+			if err != nil {
+				log.Error("Failed to record scanned commit",
+					zap.String("scan_id", scanID),
+					zap.String("ref", input.Ref),
+					zap.Error(err))
+			}
+		}
+
+		// Send email notification to the scan submitter and the repository's additional recipients
 		var repoName string
+		notifyEmails := []string{}
 		err = sqlDB.QueryRowContext(ctx,
-			`SELECT name FROM repositories WHERE id = $1`,
-			input.RepositoryID).Scan(&repoName)
+			`SELECT name, notify_emails FROM repositories WHERE id = $1`,
+			input.RepositoryID).Scan(&repoName, pq.Array(&notifyEmails))
 
 		if err != nil {
 			log.Error("Failed to fetch repository name for email notification",
@@ -399,42 +1101,46 @@ func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*Scan
 			repoName = "Unknown Repository"
 		}
 
-		// Initialize email service for sending notifications
-		emailService := services.NewEmailService(dbQueries)
+		// Initialize email service for sending notifications; a configuration problem is reported when sending
+		emailService, emailConfigErr := services.NewEmailService(dbQueries)
 
-		vulnCount := 0
-		if scanResult != nil {
-			vulnCount = len(scanResult.Vulnerabilities)
-		}
+		vulnCount := reportedCount
 
 		// First try to use the email from the database
 		emailToNotify := submitterEmail
 
 		// If no email found in database, try using the one provided in the input
-		if emailToNotify == "" && input.Email != "" {
+		if emailToNotify == "" && input.Email != "" && !submitterOptedOut {
 			emailToNotify = input.Email
 			log.Info("Using email from scan input for notification",
 				zap.String("email", emailToNotify))
 		}
 
 		// Send email if we have an email address and notification is requested
-		shouldSendEmail := input.NotifyEmail || emailToNotify != ""
+		// Additional recipients configured on the repository are always included
+		recipients := services.ScanNotificationRecipients(emailToNotify, notifyEmails)
+		shouldSendEmail := input.NotifyEmail || len(recipients) > 0
 
-		if shouldSendEmail && emailToNotify != "" {
-			err = emailService.SendScanCompletionEmail(
-				emailToNotify,
-				repoName,
-				input.RepositoryID,
-				vulnCount)
+		if services.ScanEmailsDisabled() {
+			log.Info("Scan emails are disabled, skipping email notification",
+				zap.String("repo_id", input.RepositoryID))
+		} else if shouldSendEmail && len(recipients) > 0 {
+			if emailConfigErr != nil {
+				err = emailConfigErr
+			} else if len(recipients) == 1 {
+				err = emailService.SendScanCompletionEmail(recipients[0], repoName, input.RepositoryID, vulnCount)
+			} else {
+				err = emailService.SendBulkScanCompletionEmail(recipients, repoName, input.RepositoryID, vulnCount)
+			}
 
 			if err != nil {
 				log.Error("Failed to send scan completion email",
-					zap.String("email", emailToNotify),
+					zap.Strings("emails", recipients),
 					zap.String("repo_name", repoName),
 					zap.Error(err))
 			} else {
 				log.Info("Scan completion email sent successfully",
-					zap.String("email", emailToNotify),
+					zap.Strings("emails", recipients),
 					zap.String("repo_name", repoName))
 			}
 		} else {
@@ -490,13 +1196,166 @@ func ScanRepositoryActivity(ctx context.Context, input ScanActivityInput) (*Scan
 
 	log.Info("Repository scan completed and data stored",
 		zap.String("scan_id", scanID),
-		zap.Int("vulnerability_count", len(scanResult.Vulnerabilities)))
+		zap.Int("vulnerability_count", reportedCount))
 
 	return &ScanActivityOutput{
 		RepositoryID:         input.RepositoryID,
 		ScanID:               scanID,
-		VulnCount:            len(scanResult.Vulnerabilities),
+		Status:               finalStatus,
+		TokensUsed:           scanResult.TokensUsed,
+		InsertFailures:       insertFailures,
+		VulnCount:            reportedCount,
 		VulnerabilitiesFound: vulnList,
 		ScanTimestamp:        time.Now(),
 	}, nil
 }
+
+// scanModel returns the model a scan runs with, resolving an empty request to the default
+func scanModel(model string) string {
+	if model == "" {
+		return baml.ConfiguredModel()
+	}
+	return model
+}
+
+// findingsBySeverity counts reported findings per severity for the metrics
+// Severities outside low, medium, high, and critical are counted as "unknown" so model output can't add label values
+func findingsBySeverity(vulns []services.Vulnerability) map[string]int {
+	counts := make(map[string]int)
+	for _, vuln := range vulns {
+		if vuln.Excluded {
+			continue
+		}
+		severity := strings.ToLower(vuln.Severity)
+		if services.SeverityRank(severity) == 0 {
+			severity = "unknown"
+		}
+		counts[severity]++
+	}
+	return counts
+}
+
+// countReportedFindings counts findings that are not marked excluded by path rules
+func countReportedFindings(vulns []services.Vulnerability) int {
+	count := 0
+	for _, vuln := range vulns {
+		if !vuln.Excluded {
+			count++
+		}
+	}
+	return count
+}
+
+// ScanMaxFiles returns the most files one repository scan covers, read from SCAN_MAX_FILES (default 100)
+// It is read when a scan is started and carried in its workflow input, so every batch uses the same limit
+func ScanMaxFiles() int {
+	if value, err := strconv.Atoi(os.Getenv("SCAN_MAX_FILES")); err == nil && value > 0 {
+		return value
+	}
+	return 100
+}
+
+// scanConcurrency returns how many files a scan sends to the model in parallel
+// It is read from SCAN_CONCURRENCY and defaults to services.DefaultScanConcurrency
+func scanConcurrency() int {
+	if value, err := strconv.Atoi(os.Getenv("SCAN_CONCURRENCY")); err == nil && value > 0 {
+		return value
+	}
+	return services.DefaultScanConcurrency
+}
+
+// recordScannedFile stores the findings of a scanned file with its progress marker
+// When the findings won't insert the file is dead-lettered to scan_errors instead of failing the scan;
+// if even that can't be written the database is likely down, so the error aborts the scan and the retry resumes
+func recordScannedFile(ctx context.Context, progress services.ScanProgressService, scanID, relPath string, vulnerabilities []*services.Vulnerability) error {
+	log := logger.Get()
+
+	err := progress.RecordFile(ctx, scanID, relPath, vulnerabilities)
+	if err == nil {
+		return nil
+	}
+	if recordErr := progress.RecordFileFailure(ctx, scanID, relPath, err); recordErr != nil {
+		log.Error("Failed to record scan error",
+			zap.String("scan_id", scanID),
+			zap.String("file", relPath),
+			zap.Error(recordErr))
+		return err
+	}
+
+	log.Warn("Failed to store findings, recorded the file in scan_errors",
+		zap.String("scan_id", scanID),
+		zap.String("file", relPath),
+		zap.Int("vuln_count", len(vulnerabilities)),
+		zap.Error(err))
+	return nil
+}
+
+// insertFailureStatus returns the final status of a scan that couldn't store the findings of insertFailures files
+// A completed scan with more failures than SCAN_MAX_INSERT_FAILURES becomes completed_with_errors; a scan
+// already stopped by its budget keeps that status
+func insertFailureStatus(status string, insertFailures int) string {
+	if status == services.ScanStatusCompleted && insertFailures > maxInsertFailures() {
+		return services.ScanStatusCompletedWithErrors
+	}
+	return status
+}
+
+// maxInsertFailures returns how many files a scan may fail to store findings for, or parse the model's reply for,
+// and still be completed
+// It is read from SCAN_MAX_INSERT_FAILURES and defaults to 0, so any lost findings mark the scan completed_with_errors
+func maxInsertFailures() int {
+	if value, err := strconv.Atoi(os.Getenv("SCAN_MAX_INSERT_FAILURES")); err == nil && value >= 0 {
+		return value
+	}
+	return 0
+}
+
+// scanTimeBudget returns the soft wall-clock budget for a single repository scan batch
+// It is read from SCAN_TIME_BUDGET (a Go duration such as "20m") and defaults to five sixths of the
+// activity timeout (25 minutes of the default 30), which leaves headroom before the activity times out.
+// A budget longer than the activity timeout is cut to the default, since the activity would be killed first
+func scanTimeBudget(activityTimeout time.Duration) time.Duration {
+	budget := activityTimeout * 5 / 6
+
+	if value := os.Getenv("SCAN_TIME_BUDGET"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 || parsed > activityTimeout {
+			logger.Warn("Invalid SCAN_TIME_BUDGET value, using default",
+				zap.String("value", value),
+				zap.Duration("default", budget))
+			return budget
+		}
+		budget = parsed
+	}
+
+	return budget
+}
+
+// updateScanStatus records a scan lifecycle transition in the scans table
+// A canceled scan stays canceled, e.g. one canceled while queued whose clone starts before the cancel reaches it
+// Failures are logged rather than returned so status bookkeeping never fails an activity
+func updateScanStatus(ctx context.Context, dbQueries *db.Queries, scanID, status, errMsg string) {
+	log := logger.Get()
+
+	sqlDB := dbQueries.GetDB()
+	if scanID == "" || sqlDB == nil {
+		return
+	}
+
+	_, err := sqlDB.ExecContext(ctx,
+		`UPDATE scans SET status = $1, error_message = $2, updated_at = NOW(),
+			completed_at = CASE WHEN $1 IN ('completed', 'failed', 'canceled') THEN NOW() ELSE completed_at END
+		WHERE id = $3 AND (status <> 'canceled' OR $1 = 'canceled')`,
+		status, errMsg, scanID)
+	if err != nil {
+		log.Error("Failed to update scan status",
+			zap.String("scan_id", scanID),
+			zap.String("status", status),
+			zap.Error(err))
+		return
+	}
+
+	log.Info("Updated scan status",
+		zap.String("scan_id", scanID),
+		zap.String("status", status))
+}
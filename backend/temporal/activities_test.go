@@ -0,0 +1,352 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/baml"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestScanTimeBudget(t *testing.T) {
+	tests := []struct {
+		name            string
+		value           string
+		activityTimeout time.Duration
+		want            time.Duration
+	}{
+		{name: "unset", activityTimeout: ScanActivityTimeout, want: 25 * time.Minute},
+		{name: "unset with a longer timeout", activityTimeout: 90 * time.Minute, want: 75 * time.Minute},
+		{name: "duration", value: "90s", activityTimeout: ScanActivityTimeout, want: 90 * time.Second},
+		{name: "zero turns the budget off", value: "0", activityTimeout: ScanActivityTimeout, want: 0},
+		{name: "negative", value: "-5m", activityTimeout: ScanActivityTimeout, want: 25 * time.Minute},
+		{name: "not a duration", value: "soon", activityTimeout: ScanActivityTimeout, want: 25 * time.Minute},
+		{name: "longer than the timeout", value: "40m", activityTimeout: ScanActivityTimeout, want: 25 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCAN_TIME_BUDGET", tt.value)
+			if got := scanTimeBudget(tt.activityTimeout); got != tt.want {
+				t.Errorf("scanTimeBudget(%v) with %q = %v, want %v", tt.activityTimeout, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountReportedFindingsLeavesOutExcluded(t *testing.T) {
+	vulns := []services.Vulnerability{{FilePath: "main.go"}, {FilePath: "third_party/lib.go", Excluded: true}, {FilePath: "api.go"}}
+	if got := countReportedFindings(vulns); got != 2 {
+		t.Errorf("countReportedFindings = %d, want 2", got)
+	}
+}
+
+func TestFindingsBySeverityBoundsTheLabels(t *testing.T) {
+	vulns := []services.Vulnerability{
+		{Severity: "High"},
+		{Severity: "high"},
+		{Severity: "Critical", Excluded: true},
+		{Severity: "Catastrophic"},
+		{Severity: "Low"},
+	}
+
+	got := findingsBySeverity(vulns)
+	want := map[string]int{"high": 2, "unknown": 1, "low": 1}
+	if len(got) != len(want) {
+		t.Fatalf("findingsBySeverity = %v, want %v", got, want)
+	}
+	for severity, count := range want {
+		if got[severity] != count {
+			t.Errorf("findingsBySeverity[%s] = %d, want %d", severity, got[severity], count)
+		}
+	}
+}
+
+func TestScanModelDefaultsWhenUnset(t *testing.T) {
+	t.Setenv("OPENAI_MODEL", "")
+	if got := scanModel(""); got != baml.DefaultModel {
+		t.Errorf("scanModel(\"\") = %q, want %q", got, baml.DefaultModel)
+	}
+	if got := scanModel("gpt-4o-mini"); got != "gpt-4o-mini" {
+		t.Errorf("scanModel(\"gpt-4o-mini\") = %q, want it unchanged", got)
+	}
+}
+
+func TestScanModelDefaultsToTheConfiguredModel(t *testing.T) {
+	t.Setenv("OPENAI_MODEL", "gpt-4o")
+	if got := scanModel(""); got != "gpt-4o" {
+		t.Errorf("scanModel(\"\") = %q, want OPENAI_MODEL", got)
+	}
+	if got := scanModel("gpt-4o-mini"); got != "gpt-4o-mini" {
+		t.Errorf("scanModel(\"gpt-4o-mini\") = %q, want the requested model over OPENAI_MODEL", got)
+	}
+}
+
+func TestCloneRepositoryActivityAbortsAndCleansUpWhenCanceled(t *testing.T) {
+	// Clones land under the temp directory, so give this test its own
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("GITHUB_TOKEN", "")
+	// The test server stands in for a self-hosted GitLab instance
+	t.Setenv("GITLAB_HOSTS", "127.0.0.1")
+
+	// A git server that never answers, like a clone hung on a flaky network
+	requested := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-requested
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := CloneRepositoryActivity(ctx, CloneActivityInput{RepositoryID: "repo-cancel", CloneURL: server.URL + "/octo/repo.git"})
+	if err == nil {
+		t.Fatal("clone succeeded, want it canceled")
+	}
+	// Cancellation must not wait out the retry loop's pauses
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("canceled clone took %v to return", elapsed)
+	}
+
+	repoDir := filepath.Join(os.TempDir(), "repos", "repo-cancel")
+	if _, statErr := os.Stat(repoDir); !os.IsNotExist(statErr) {
+		t.Errorf("partial clone %s was left behind (stat error %v)", repoDir, statErr)
+	}
+}
+
+func TestLastAttempt(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int32
+		err         error
+		want        bool
+	}{
+		{name: "retries left", maxAttempts: 3, err: errors.New("connection reset")},
+		{name: "last attempt", maxAttempts: 1, err: errors.New("connection reset"), want: true},
+		{name: "non-retryable error", maxAttempts: 3, err: temporal.NewNonRetryableApplicationError("refused", "InvalidCloneURL", nil), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The check reads the attempt from the activity context, which is the first attempt here
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestActivityEnvironment()
+			check := func(ctx context.Context) (bool, error) {
+				return lastAttempt(ctx, tt.maxAttempts, tt.err), nil
+			}
+			env.RegisterActivity(check)
+
+			value, err := env.ExecuteActivity(check)
+			if err != nil {
+				t.Fatalf("activity returned error: %v", err)
+			}
+			var got bool
+			if err := value.Get(&got); err != nil {
+				t.Fatalf("decode result: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("lastAttempt = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveUploadActivityOnlyRemovesUploads(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	uploadDir := filepath.Join(services.UploadsDir(), "scan-1")
+	if err := os.MkdirAll(filepath.Join(uploadDir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveUploadActivity(context.Background(), uploadDir); err != nil {
+		t.Fatalf("RemoveUploadActivity: %v", err)
+	}
+	if _, err := os.Stat(uploadDir); !os.IsNotExist(err) {
+		t.Errorf("upload directory still exists (err = %v)", err)
+	}
+
+	other := t.TempDir()
+	if err := RemoveUploadActivity(context.Background(), other); err == nil {
+		t.Error("RemoveUploadActivity removed a directory outside the uploads directory")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("directory outside the uploads directory was touched: %v", err)
+	}
+}
+
+func TestRemoveWorkspaceActivityOnlyRemovesWorkspaces(t *testing.T) {
+	t.Setenv("SCAN_WORKSPACE_DIR", t.TempDir())
+
+	repoDir := services.ScanWorkspaceDir("scan-1")
+	if err := os.MkdirAll(filepath.Join(repoDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveWorkspaceActivity(context.Background(), repoDir); err != nil {
+		t.Fatalf("RemoveWorkspaceActivity: %v", err)
+	}
+	if _, err := os.Stat(repoDir); !os.IsNotExist(err) {
+		t.Errorf("workspace still exists (err = %v)", err)
+	}
+
+	for _, dir := range []string{services.WorkspaceDir(), t.TempDir(), filepath.Join(services.WorkspaceDir(), "..")} {
+		if err := RemoveWorkspaceActivity(context.Background(), dir); err == nil {
+			t.Errorf("RemoveWorkspaceActivity removed %s, outside any scan workspace", dir)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("directory %s outside any scan workspace was touched: %v", dir, err)
+		}
+	}
+}
+
+// failingProgress is a scan progress store whose finding inserts fail, recording what gets dead-lettered
+type failingProgress struct {
+	services.ScanProgressService
+	deadLetterErr error             // Returned by RecordFileFailure, e.g. when the database is down
+	deadLettered  map[string]string // File path -> recorded error
+}
+
+func (f *failingProgress) RecordFile(ctx context.Context, scanID, filePath string, vulnerabilities []*services.Vulnerability) error {
+	return errors.New("value too long for type character varying(50)")
+}
+
+func (f *failingProgress) RecordFileFailure(ctx context.Context, scanID, filePath string, cause error) error {
+	if f.deadLetterErr != nil {
+		return f.deadLetterErr
+	}
+	f.deadLettered[filePath] = cause.Error()
+	return nil
+}
+
+func TestRecordScannedFileDeadLettersFailedInserts(t *testing.T) {
+	progress := &failingProgress{deadLettered: map[string]string{}}
+	findings := []*services.Vulnerability{{Type: services.Injection, FilePath: "db.go"}}
+
+	// The scan goes on without the file, which is recorded with its error
+	if err := recordScannedFile(context.Background(), progress, "scan-1", "db.go", findings); err != nil {
+		t.Fatalf("recordScannedFile = %v, want the failure dead-lettered", err)
+	}
+	if got := progress.deadLettered["db.go"]; got != "value too long for type character varying(50)" {
+		t.Errorf("dead-lettered error = %q, want the insert error", got)
+	}
+
+	// Without anywhere to record the failure, the insert error aborts the scan so the retry resumes
+	progress.deadLetterErr = errors.New("connection refused")
+	if err := recordScannedFile(context.Background(), progress, "scan-1", "api.go", findings); err == nil {
+		t.Error("recordScannedFile = nil, want the insert error when the failure can't be recorded")
+	}
+}
+
+func TestInsertFailureStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxFailures string
+		status      string
+		failures    int
+		want        string
+	}{
+		{name: "no failures", status: services.ScanStatusCompleted, want: services.ScanStatusCompleted},
+		{name: "any failure by default", status: services.ScanStatusCompleted, failures: 1, want: services.ScanStatusCompletedWithErrors},
+		{name: "within the threshold", maxFailures: "3", status: services.ScanStatusCompleted, failures: 3, want: services.ScanStatusCompleted},
+		{name: "over the threshold", maxFailures: "3", status: services.ScanStatusCompleted, failures: 4, want: services.ScanStatusCompletedWithErrors},
+		{name: "budget stop kept", status: services.ScanStatusBudgetExceeded, failures: 5, want: services.ScanStatusBudgetExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCAN_MAX_INSERT_FAILURES", tt.maxFailures)
+			if got := insertFailureStatus(tt.status, tt.failures); got != tt.want {
+				t.Errorf("insertFailureStatus(%s, %d) = %s, want %s", tt.status, tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeartbeatUntilDoneHeartbeatsUntilStopped(t *testing.T) {
+	var mu sync.Mutex
+	beats := 0
+	recordHeartbeat = func(ctx context.Context, details ...interface{}) {
+		mu.Lock()
+		beats++
+		mu.Unlock()
+	}
+	t.Cleanup(func() { recordHeartbeat = activity.RecordHeartbeat })
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return beats
+	}
+
+	stop := heartbeatUntilDone(context.Background(), 5*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	if count() < 3 {
+		t.Fatalf("%d heartbeats recorded, want a steady stream while running", count())
+	}
+
+	// Let a tick that was already in flight land, then make sure no more follow
+	time.Sleep(20 * time.Millisecond)
+	stopped := count()
+	time.Sleep(30 * time.Millisecond)
+	if count() != stopped {
+		t.Errorf("%d heartbeats recorded after stop, want none", count()-stopped)
+	}
+}
+
+func TestScanProgressReporterHeartbeatsAndSavesChanges(t *testing.T) {
+	var details []interface{}
+	recordHeartbeat = func(ctx context.Context, d ...interface{}) { details = append(details, d...) }
+	t.Cleanup(func() { recordHeartbeat = activity.RecordHeartbeat })
+
+	var saved []services.ScanProgress
+	saveErr := errors.New("database is down")
+	reporter := &scanProgressReporter{}
+	reporter.saveWith(func(ctx context.Context, progress services.ScanProgress) error {
+		if saveErr != nil {
+			return saveErr
+		}
+		saved = append(saved, progress)
+		return nil
+	})
+	ctx := context.Background()
+
+	first := services.ScanProgress{Scanned: 3, Total: 10, CurrentFile: "api/users.go"}
+	reporter.update(first)
+	reporter.heartbeat(ctx) // the save fails, so it is tried again on the next heartbeat
+	saveErr = nil
+	reporter.heartbeat(ctx)
+	reporter.heartbeat(ctx) // nothing changed, so nothing is written
+
+	second := services.ScanProgress{Scanned: 4, Total: 10, CurrentFile: "api/orders.go"}
+	reporter.update(services.ScanProgress{Scanned: 3, Total: 10, CurrentFile: "api/items.go"})
+	reporter.update(second) // only the latest progress between heartbeats is saved
+	reporter.heartbeat(ctx)
+
+	if want := []interface{}{first, first, first, second}; fmt.Sprint(details) != fmt.Sprint(want) {
+		t.Errorf("heartbeat details = %v, want %v", details, want)
+	}
+	if want := []services.ScanProgress{first, second}; fmt.Sprint(saved) != fmt.Sprint(want) {
+		t.Errorf("saved progress = %v, want %v", saved, want)
+	}
+}
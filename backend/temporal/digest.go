@@ -0,0 +1,133 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/db"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"go.uber.org/zap"
+)
+
+// DigestWorkflowID identifies the recurring digest workflow; one runs per task queue
+const DigestWorkflowID = "scan-digest-workflow"
+
+// DefaultDigestSchedule checks for due digests at the top of every hour
+const DefaultDigestSchedule = "0 * * * *"
+
+// DigestResult reports what one digest run did
+type DigestResult struct {
+	Sent    int // Digests emailed
+	Empty   int // Due digests with no finished scans, advanced without an email
+	Failed  int // Digests that could not be sent; they are retried by the next run
+	Skipped bool
+}
+
+// DigestWorkflow sends the scan digests that are due
+// It is started with a cron schedule, so each run handles one check and the schedule starts the next
+func DigestWorkflow(ctx workflow.Context) (*DigestResult, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+
+	var result DigestResult
+	err := workflow.ExecuteActivity(ctx, SendScanDigestsActivity).Get(ctx, &result)
+	return &result, err
+}
+
+// SendScanDigestsActivity emails every due digest and records it as sent
+// A digest that fails to send is not recorded, so the next run tries it again with the same scans
+func SendScanDigestsActivity(ctx context.Context) (*DigestResult, error) {
+	log := logger.Get()
+	result := &DigestResult{}
+
+	if services.ScanEmailsDisabled() {
+		log.Info("Scan emails are disabled, skipping digests")
+		result.Skipped = true
+		return result, nil
+	}
+
+	dbQueries := db.NewQueries()
+	if dbQueries.GetDB() == nil {
+		log.Warn("Database unavailable, skipping digests")
+		result.Skipped = true
+		return result, nil
+	}
+
+	digestService := services.NewDigestService(dbQueries)
+	now := time.Now().UTC()
+	due, err := digestService.DueDigests(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(due) == 0 {
+		return result, nil
+	}
+
+	emailService, err := services.NewEmailService(dbQueries)
+	if err != nil {
+		// A misconfiguration won't fix itself on retry, so report it and wait for the next run
+		log.Error("Cannot send scan digests", zap.Error(err))
+		result.Skipped = true
+		return result, nil
+	}
+
+	for _, recipient := range due {
+		if len(recipient.Scans) > 0 {
+			if err := emailService.SendDigestEmail(recipient.Email, recipient.Scans); err != nil {
+				result.Failed++
+				continue
+			}
+			result.Sent++
+		} else {
+			result.Empty++
+		}
+
+		if err := digestService.MarkDigestSent(ctx, recipient.UserID, now); err != nil {
+			log.Error("Failed to record digest", zap.String("user_id", recipient.UserID), zap.Error(err))
+		}
+	}
+
+	log.Info("Scan digests processed",
+		zap.Int("sent", result.Sent),
+		zap.Int("empty", result.Empty),
+		zap.Int("failed", result.Failed))
+
+	return result, nil
+}
+
+// DigestSchedule returns the cron schedule of the digest workflow, read from DIGEST_SCHEDULE
+func DigestSchedule() string {
+	if schedule := strings.TrimSpace(os.Getenv("DIGEST_SCHEDULE")); schedule != "" {
+		return schedule
+	}
+	return DefaultDigestSchedule
+}
+
+// StartDigestWorkflow starts the recurring digest workflow on the task queue
+// A digest workflow that is already scheduled is left as it is
+func StartDigestWorkflow(ctx context.Context, c client.Client, taskQueue string) error {
+	_, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:                                       DigestWorkflowID,
+		TaskQueue:                                taskQueue,
+		CronSchedule:                             DigestSchedule(),
+		WorkflowExecutionErrorWhenAlreadyStarted: true,
+	}, DigestWorkflow)
+
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	if errors.As(err, &alreadyStarted) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package temporal
+
+import (
+	"strings"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+)
+
+// ScanTimelineEvent is one step of a scan workflow as shown to users
+// It carries only what helps diagnose a scan, not the raw Temporal payloads
+type ScanTimelineEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`             // e.g. "clone_started", "scan_failed", "workflow_completed"
+	Stage   string    `json:"stage,omitempty"`   // "clone", "scan", or "workflow"
+	Attempt int32     `json:"attempt,omitempty"` // Activity attempt number; above 1 means the activity was retried
+	Message string    `json:"message,omitempty"` // Failure message, if any
+}
+
+// BuildScanTimeline condenses a scan workflow's history into a readable timeline
+// Only lifecycle events are kept: workflow start/finish and each activity's start, retries, and outcome
+func BuildScanTimeline(events []*historypb.HistoryEvent) []ScanTimelineEvent {
+	timeline := []ScanTimelineEvent{}
+
+	// Activity events reference their scheduled event, which is the only one that names the activity
+	stages := make(map[int64]string)
+
+	for _, event := range events {
+		eventTime := event.GetEventTime().AsTime()
+
+		switch event.GetEventType() {
+		case enums.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED:
+			timeline = append(timeline, ScanTimelineEvent{Time: eventTime, Event: "workflow_started", Stage: "workflow"})
+
+		case enums.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED:
+			attrs := event.GetActivityTaskScheduledEventAttributes()
+			stages[event.GetEventId()] = activityStage(attrs.GetActivityType().GetName())
+
+		case enums.EVENT_TYPE_ACTIVITY_TASK_STARTED:
+			attrs := event.GetActivityTaskStartedEventAttributes()
+			stage := stages[attrs.GetScheduledEventId()]
+
+			// Temporal records only the final attempt's start; earlier attempts show up as the attempt count
+			// and the last failure that caused the retry
+			if attrs.GetAttempt() > 1 {
+				timeline = append(timeline, ScanTimelineEvent{
+					Time:    eventTime,
+					Event:   stage + "_retried",
+					Stage:   stage,
+					Attempt: attrs.GetAttempt(),
+					Message: attrs.GetLastFailure().GetMessage(),
+				})
+			}
+			timeline = append(timeline, ScanTimelineEvent{
+				Time:    eventTime,
+				Event:   stage + "_started",
+				Stage:   stage,
+				Attempt: attrs.GetAttempt(),
+			})
+
+		case enums.EVENT_TYPE_ACTIVITY_TASK_COMPLETED:
+			stage := stages[event.GetActivityTaskCompletedEventAttributes().GetScheduledEventId()]
+			timeline = append(timeline, ScanTimelineEvent{Time: eventTime, Event: stage + "_finished", Stage: stage})
+
+		case enums.EVENT_TYPE_ACTIVITY_TASK_FAILED:
+			attrs := event.GetActivityTaskFailedEventAttributes()
+			stage := stages[attrs.GetScheduledEventId()]
+			timeline = append(timeline, ScanTimelineEvent{
+				Time:    eventTime,
+				Event:   stage + "_failed",
+				Stage:   stage,
+				Message: attrs.GetFailure().GetMessage(),
+			})
+
+		case enums.EVENT_TYPE_ACTIVITY_TASK_TIMED_OUT:
+			attrs := event.GetActivityTaskTimedOutEventAttributes()
+			stage := stages[attrs.GetScheduledEventId()]
+			timeline = append(timeline, ScanTimelineEvent{
+				Time:    eventTime,
+				Event:   stage + "_timed_out",
+				Stage:   stage,
+				Message: attrs.GetFailure().GetMessage(),
+			})
+
+		case enums.EVENT_TYPE_WORKFLOW_EXECUTION_COMPLETED:
+			timeline = append(timeline, ScanTimelineEvent{Time: eventTime, Event: "workflow_completed", Stage: "workflow"})
+
+		case enums.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED:
+			timeline = append(timeline, ScanTimelineEvent{
+				Time:    eventTime,
+				Event:   "workflow_failed",
+				Stage:   "workflow",
+				Message: event.GetWorkflowExecutionFailedEventAttributes().GetFailure().GetMessage(),
+			})
+
+		case enums.EVENT_TYPE_WORKFLOW_EXECUTION_TIMED_OUT:
+			timeline = append(timeline, ScanTimelineEvent{Time: eventTime, Event: "workflow_timed_out", Stage: "workflow"})
+
+		case enums.EVENT_TYPE_WORKFLOW_EXECUTION_TERMINATED:
+			timeline = append(timeline, ScanTimelineEvent{
+				Time:    eventTime,
+				Event:   "workflow_terminated",
+				Stage:   "workflow",
+				Message: event.GetWorkflowExecutionTerminatedEventAttributes().GetReason(),
+			})
+
+		case enums.EVENT_TYPE_WORKFLOW_EXECUTION_CANCELED:
+			timeline = append(timeline, ScanTimelineEvent{Time: eventTime, Event: "workflow_canceled", Stage: "workflow"})
+		}
+	}
+
+	return timeline
+}
+
+// activityStage maps an activity type name to the short stage name used in the timeline
+func activityStage(activityType string) string {
+	switch activityType {
+	case "CloneRepositoryActivity":
+		return "clone"
+	case "ScanRepositoryActivity":
+		return "scan"
+	default:
+		return strings.ToLower(strings.TrimSuffix(activityType, "Activity"))
+	}
+}
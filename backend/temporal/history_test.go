@@ -0,0 +1,117 @@
+package temporal
+
+import (
+	"testing"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// scanHistory is a mocked workflow history: the clone succeeds and the scan fails once, is retried, then fails for good
+func scanHistory(start time.Time) []*historypb.HistoryEvent {
+	at := func(seconds int) *timestamppb.Timestamp {
+		return timestamppb.New(start.Add(time.Duration(seconds) * time.Second))
+	}
+	scheduled := func(id int64, seconds int, activity string) *historypb.HistoryEvent {
+		return &historypb.HistoryEvent{
+			EventId:   id,
+			EventTime: at(seconds),
+			EventType: enums.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED,
+			Attributes: &historypb.HistoryEvent_ActivityTaskScheduledEventAttributes{
+				ActivityTaskScheduledEventAttributes: &historypb.ActivityTaskScheduledEventAttributes{
+					ActivityType: &commonpb.ActivityType{Name: activity},
+				},
+			},
+		}
+	}
+
+	return []*historypb.HistoryEvent{
+		{
+			EventId: 1, EventTime: at(0), EventType: enums.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED,
+			Attributes: &historypb.HistoryEvent_WorkflowExecutionStartedEventAttributes{
+				WorkflowExecutionStartedEventAttributes: &historypb.WorkflowExecutionStartedEventAttributes{
+					// Raw inputs must not leak into the timeline
+					Input: &commonpb.Payloads{Payloads: []*commonpb.Payload{{Data: []byte(`{"CloneURL": "https://token@github.com/o/r"}`)}}},
+				},
+			},
+		},
+		{EventId: 2, EventTime: at(0), EventType: enums.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED},
+		scheduled(5, 1, "CloneRepositoryActivity"),
+		{
+			EventId: 6, EventTime: at(2), EventType: enums.EVENT_TYPE_ACTIVITY_TASK_STARTED,
+			Attributes: &historypb.HistoryEvent_ActivityTaskStartedEventAttributes{
+				ActivityTaskStartedEventAttributes: &historypb.ActivityTaskStartedEventAttributes{ScheduledEventId: 5, Attempt: 1},
+			},
+		},
+		{
+			EventId: 7, EventTime: at(10), EventType: enums.EVENT_TYPE_ACTIVITY_TASK_COMPLETED,
+			Attributes: &historypb.HistoryEvent_ActivityTaskCompletedEventAttributes{
+				ActivityTaskCompletedEventAttributes: &historypb.ActivityTaskCompletedEventAttributes{ScheduledEventId: 5},
+			},
+		},
+		scheduled(11, 11, "ScanRepositoryActivity"),
+		{
+			EventId: 12, EventTime: at(40), EventType: enums.EVENT_TYPE_ACTIVITY_TASK_STARTED,
+			Attributes: &historypb.HistoryEvent_ActivityTaskStartedEventAttributes{
+				ActivityTaskStartedEventAttributes: &historypb.ActivityTaskStartedEventAttributes{
+					ScheduledEventId: 11,
+					Attempt:          2,
+					LastFailure:      &failurepb.Failure{Message: "model request timed out"},
+				},
+			},
+		},
+		{
+			EventId: 13, EventTime: at(70), EventType: enums.EVENT_TYPE_ACTIVITY_TASK_FAILED,
+			Attributes: &historypb.HistoryEvent_ActivityTaskFailedEventAttributes{
+				ActivityTaskFailedEventAttributes: &historypb.ActivityTaskFailedEventAttributes{
+					ScheduledEventId: 11,
+					Failure:          &failurepb.Failure{Message: "rate limited"},
+				},
+			},
+		},
+		{
+			EventId: 17, EventTime: at(71), EventType: enums.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED,
+			Attributes: &historypb.HistoryEvent_WorkflowExecutionFailedEventAttributes{
+				WorkflowExecutionFailedEventAttributes: &historypb.WorkflowExecutionFailedEventAttributes{
+					Failure: &failurepb.Failure{Message: "activity error"},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildScanTimeline(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	timeline := BuildScanTimeline(scanHistory(start))
+
+	want := []ScanTimelineEvent{
+		{Time: start, Event: "workflow_started", Stage: "workflow"},
+		{Time: start.Add(2 * time.Second), Event: "clone_started", Stage: "clone", Attempt: 1},
+		{Time: start.Add(10 * time.Second), Event: "clone_finished", Stage: "clone"},
+		{Time: start.Add(40 * time.Second), Event: "scan_retried", Stage: "scan", Attempt: 2, Message: "model request timed out"},
+		{Time: start.Add(40 * time.Second), Event: "scan_started", Stage: "scan", Attempt: 2},
+		{Time: start.Add(70 * time.Second), Event: "scan_failed", Stage: "scan", Message: "rate limited"},
+		{Time: start.Add(71 * time.Second), Event: "workflow_failed", Stage: "workflow", Message: "activity error"},
+	}
+
+	if len(timeline) != len(want) {
+		t.Fatalf("timeline has %d events, want %d: %+v", len(timeline), len(want), timeline)
+	}
+	for i := range want {
+		if !timeline[i].Time.Equal(want[i].Time) || timeline[i].Event != want[i].Event || timeline[i].Stage != want[i].Stage ||
+			timeline[i].Attempt != want[i].Attempt || timeline[i].Message != want[i].Message {
+			t.Errorf("event %d = %+v, want %+v", i, timeline[i], want[i])
+		}
+	}
+}
+
+func TestBuildScanTimelineOfAnEmptyHistory(t *testing.T) {
+	timeline := BuildScanTimeline(nil)
+	if timeline == nil || len(timeline) != 0 {
+		t.Errorf("timeline = %#v, want an empty list", timeline)
+	}
+}
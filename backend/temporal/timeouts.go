@@ -0,0 +1,139 @@
+package temporal
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultCloneTimeout is the StartToCloseTimeout of the clone activity for repositories of unknown size
+	DefaultCloneTimeout = 60 * time.Minute
+
+	// MinCloneTimeout and MaxCloneTimeout bound the clone timeout, whether estimated or configured
+	MinCloneTimeout = 5 * time.Minute
+	MaxCloneTimeout = 3 * time.Hour
+
+	// MinScanActivityTimeout and MaxScanActivityTimeout bound the configured scan activity timeout
+	MinScanActivityTimeout = 5 * time.Minute
+	MaxScanActivityTimeout = 2 * time.Hour
+
+	// DefaultCloneMaxAttempts and DefaultScanMaxAttempts are how often each activity is tried before the scan fails
+	DefaultCloneMaxAttempts = 3
+	DefaultScanMaxAttempts  = 2
+
+	// MaxActivityAttempts caps the configured attempts, so a broken repository can't be retried for hours
+	MaxActivityAttempts = 10
+
+	// CloneHeartbeatTimeout and ScanHeartbeatTimeout are how long Temporal waits for a heartbeat before
+	// treating the activity as lost, e.g. because its worker died, and retrying it elsewhere
+	CloneHeartbeatTimeout = time.Minute
+	ScanHeartbeatTimeout  = 2 * time.Minute
+)
+
+// cloneBaseTimeout and cloneThroughputKBps estimate how long a clone takes: a fixed allowance for
+// connecting and checking out, plus the repository's size at a conservative transfer rate
+const (
+	cloneBaseTimeout    = 5 * time.Minute
+	cloneThroughputKBps = 512
+)
+
+// ScanTimeouts are the timeouts and retry limits of a scan's clone and scan activities
+// They are resolved when the scan is queued and carried in the workflow input, because workflow code
+// can't read the environment deterministically. Zero values fall back to the defaults
+type ScanTimeouts struct {
+	Clone            time.Duration // StartToCloseTimeout of the clone activity
+	CloneMaxAttempts int32         // Attempts at the clone before the scan fails
+	Scan             time.Duration // StartToCloseTimeout of each scan batch
+	ScanMaxAttempts  int32         // Attempts at each scan batch before the scan fails
+}
+
+// withDefaults fills the unset timeouts and attempts, e.g. of workflows queued before they were resolved
+func (t ScanTimeouts) withDefaults() ScanTimeouts {
+	if t.Clone <= 0 {
+		t.Clone = DefaultCloneTimeout
+	}
+	if t.CloneMaxAttempts <= 0 {
+		t.CloneMaxAttempts = DefaultCloneMaxAttempts
+	}
+	if t.Scan <= 0 {
+		t.Scan = ScanActivityTimeout
+	}
+	if t.ScanMaxAttempts <= 0 {
+		t.ScanMaxAttempts = DefaultScanMaxAttempts
+	}
+	return t
+}
+
+// ResolveScanTimeouts returns the activity timeouts for scanning a repository of sizeKB (0 when unknown)
+// SCAN_CLONE_TIMEOUT sets the clone timeout outright; otherwise it is estimated from the size, so small
+// repositories fail fast and monorepos get the time they need. SCAN_ACTIVITY_TIMEOUT sets the timeout of each
+// scan batch, which scans a fixed number of files whatever the repository's size, and SCAN_CLONE_MAX_ATTEMPTS
+// and SCAN_MAX_ATTEMPTS the retries. Every value is clamped to sane bounds
+func ResolveScanTimeouts(sizeKB int64) ScanTimeouts {
+	clone, ok := durationFromEnv("SCAN_CLONE_TIMEOUT")
+	if !ok {
+		clone = estimateCloneTimeout(sizeKB)
+	}
+	scan, ok := durationFromEnv("SCAN_ACTIVITY_TIMEOUT")
+	if !ok {
+		scan = ScanActivityTimeout
+	}
+
+	return ScanTimeouts{
+		Clone:            clampDuration(clone, MinCloneTimeout, MaxCloneTimeout),
+		CloneMaxAttempts: attemptsFromEnv("SCAN_CLONE_MAX_ATTEMPTS", DefaultCloneMaxAttempts),
+		Scan:             clampDuration(scan, MinScanActivityTimeout, MaxScanActivityTimeout),
+		ScanMaxAttempts:  attemptsFromEnv("SCAN_MAX_ATTEMPTS", DefaultScanMaxAttempts),
+	}
+}
+
+// estimateCloneTimeout returns the clone timeout for a repository of sizeKB, or the default when the size is unknown
+func estimateCloneTimeout(sizeKB int64) time.Duration {
+	if sizeKB <= 0 {
+		return DefaultCloneTimeout
+	}
+	return cloneBaseTimeout + time.Duration(sizeKB/cloneThroughputKBps)*time.Second
+}
+
+// clampDuration bounds value to [min, max]
+func clampDuration(value, min, max time.Duration) time.Duration {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// durationFromEnv reads a positive Go duration such as "45m" from the environment
+// The second result is false when the variable is unset or invalid; invalid values are logged
+func durationFromEnv(name string) (time.Duration, bool) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, false
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		logger.Warn("Invalid duration, using default", zap.String("name", name), zap.String("value", value))
+		return 0, false
+	}
+	return parsed, true
+}
+
+// attemptsFromEnv reads a retry limit from the environment, capped at MaxActivityAttempts
+// Unset or invalid values give defaultValue
+func attemptsFromEnv(name string, defaultValue int32) int32 {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	if value > MaxActivityAttempts {
+		return MaxActivityAttempts
+	}
+	return int32(value)
+}
@@ -0,0 +1,73 @@
+package temporal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveScanTimeouts(t *testing.T) {
+	tests := []struct {
+		name   string
+		sizeKB int64
+		env    map[string]string
+		want   ScanTimeouts
+	}{
+		{
+			name: "unknown size",
+			want: ScanTimeouts{Clone: DefaultCloneTimeout, CloneMaxAttempts: 3, Scan: ScanActivityTimeout, ScanMaxAttempts: 2},
+		},
+		{
+			name:   "small repository fails fast",
+			sizeKB: 2048,
+			want:   ScanTimeouts{Clone: 5*time.Minute + 4*time.Second, CloneMaxAttempts: 3, Scan: ScanActivityTimeout, ScanMaxAttempts: 2},
+		},
+		{
+			name:   "monorepo gets more time",
+			sizeKB: 2 << 20, // 2 GB
+			want:   ScanTimeouts{Clone: 5*time.Minute + 4096*time.Second, CloneMaxAttempts: 3, Scan: ScanActivityTimeout, ScanMaxAttempts: 2},
+		},
+		{
+			name:   "huge repository is capped",
+			sizeKB: 50 << 20,
+			want:   ScanTimeouts{Clone: MaxCloneTimeout, CloneMaxAttempts: 3, Scan: ScanActivityTimeout, ScanMaxAttempts: 2},
+		},
+		{
+			name:   "configured values win over the estimate",
+			sizeKB: 2 << 20,
+			env: map[string]string{"SCAN_CLONE_TIMEOUT": "20m", "SCAN_ACTIVITY_TIMEOUT": "45m",
+				"SCAN_CLONE_MAX_ATTEMPTS": "5", "SCAN_MAX_ATTEMPTS": "1"},
+			want: ScanTimeouts{Clone: 20 * time.Minute, CloneMaxAttempts: 5, Scan: 45 * time.Minute, ScanMaxAttempts: 1},
+		},
+		{
+			name: "configured values are clamped",
+			env: map[string]string{"SCAN_CLONE_TIMEOUT": "10s", "SCAN_ACTIVITY_TIMEOUT": "12h",
+				"SCAN_CLONE_MAX_ATTEMPTS": "100"},
+			want: ScanTimeouts{Clone: MinCloneTimeout, CloneMaxAttempts: MaxActivityAttempts, Scan: MaxScanActivityTimeout, ScanMaxAttempts: 2},
+		},
+		{
+			name: "invalid values are ignored",
+			env: map[string]string{"SCAN_CLONE_TIMEOUT": "an hour", "SCAN_ACTIVITY_TIMEOUT": "-5m",
+				"SCAN_CLONE_MAX_ATTEMPTS": "0", "SCAN_MAX_ATTEMPTS": "two"},
+			want: ScanTimeouts{Clone: DefaultCloneTimeout, CloneMaxAttempts: 3, Scan: ScanActivityTimeout, ScanMaxAttempts: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"SCAN_CLONE_TIMEOUT", "SCAN_ACTIVITY_TIMEOUT", "SCAN_CLONE_MAX_ATTEMPTS", "SCAN_MAX_ATTEMPTS"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := ResolveScanTimeouts(tt.sizeKB); got != tt.want {
+				t.Errorf("ResolveScanTimeouts(%d) = %+v, want %+v", tt.sizeKB, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanTimeoutsDefaultUnsetValues(t *testing.T) {
+	got := ScanTimeouts{Scan: time.Hour}.withDefaults()
+	want := ScanTimeouts{Clone: DefaultCloneTimeout, CloneMaxAttempts: 3, Scan: time.Hour, ScanMaxAttempts: 2}
+	if got != want {
+		t.Errorf("withDefaults() = %+v, want %+v", got, want)
+	}
+}
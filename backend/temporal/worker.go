@@ -0,0 +1,62 @@
+package temporal
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Worker defaults, used when the WORKER_* and SCAN_TASK_QUEUE settings are unset
+const (
+	DefaultScanTaskQueue          = "SCAN_TASK_QUEUE"
+	DefaultWorkerMaxActivities    = 5
+	DefaultWorkerMaxWorkflowTasks = 10
+)
+
+// WorkerConfig holds the scan worker's task queue and concurrency limits
+type WorkerConfig struct {
+	TaskQueue        string // Queue scan workflows are started on and the worker polls
+	MaxActivities    int    // Activities (clones and scans) the worker runs at once
+	MaxWorkflowTasks int    // Workflow tasks the worker runs at once
+}
+
+// ScanTaskQueue returns the task queue scan workflows run on, read from SCAN_TASK_QUEUE
+// Deployments sharing a Temporal cluster set different queues so their workers don't take each other's scans
+func ScanTaskQueue() string {
+	if queue := strings.TrimSpace(os.Getenv("SCAN_TASK_QUEUE")); queue != "" {
+		return queue
+	}
+	return DefaultScanTaskQueue
+}
+
+// WorkerConfigFromEnv reads the worker configuration from SCAN_TASK_QUEUE, WORKER_MAX_ACTIVITIES,
+// and WORKER_MAX_WORKFLOW_TASKS
+// Unset limits use the defaults; a limit that isn't a positive integer is an error
+func WorkerConfigFromEnv() (WorkerConfig, error) {
+	config := WorkerConfig{TaskQueue: ScanTaskQueue()}
+
+	var err error
+	if config.MaxActivities, err = workerLimit("WORKER_MAX_ACTIVITIES", os.Getenv("WORKER_MAX_ACTIVITIES"), DefaultWorkerMaxActivities); err != nil {
+		return WorkerConfig{}, err
+	}
+	if config.MaxWorkflowTasks, err = workerLimit("WORKER_MAX_WORKFLOW_TASKS", os.Getenv("WORKER_MAX_WORKFLOW_TASKS"), DefaultWorkerMaxWorkflowTasks); err != nil {
+		return WorkerConfig{}, err
+	}
+
+	return config, nil
+}
+
+// workerLimit parses one concurrency limit, returning def when value is empty
+func workerLimit(name, value string, def int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, value)
+	}
+	return limit, nil
+}
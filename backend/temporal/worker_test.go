@@ -0,0 +1,45 @@
+package temporal
+
+import "testing"
+
+func TestWorkerConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name          string
+		queue         string
+		activities    string
+		workflowTasks string
+		want          WorkerConfig
+		wantErr       bool
+	}{
+		{
+			name: "defaults",
+			want: WorkerConfig{TaskQueue: DefaultScanTaskQueue, MaxActivities: DefaultWorkerMaxActivities, MaxWorkflowTasks: DefaultWorkerMaxWorkflowTasks},
+		},
+		{
+			name:          "configured",
+			queue:         " staging-scans ",
+			activities:    "20",
+			workflowTasks: " 40 ",
+			want:          WorkerConfig{TaskQueue: "staging-scans", MaxActivities: 20, MaxWorkflowTasks: 40},
+		},
+		{name: "zero activities", activities: "0", wantErr: true},
+		{name: "negative workflow tasks", workflowTasks: "-3", wantErr: true},
+		{name: "not a number", activities: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCAN_TASK_QUEUE", tt.queue)
+			t.Setenv("WORKER_MAX_ACTIVITIES", tt.activities)
+			t.Setenv("WORKER_MAX_WORKFLOW_TASKS", tt.workflowTasks)
+
+			got, err := WorkerConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WorkerConfigFromEnv() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("WorkerConfigFromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package temporal
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
@@ -11,16 +13,91 @@ import (
 // ScanWorkflowInput represents the input for the scan workflow
 // This struct contains all the information needed to start a repository scan
 type ScanWorkflowInput struct {
-	RepositoryID   string   // Unique identifier for the repository
-	Owner          string   // GitHub repository owner (username or organization)
-	Name           string   // GitHub repository name
-	CloneURL       string   // URL to clone the repository (HTTPS or SSH)
-	VulnTypes      []string // Types of vulnerabilities to scan for (e.g., "INJECTION", "XSS")
-	FileExtensions []string // File extensions to include in the scan (e.g., ".go", ".js")
-	NotifyEmail    bool     // Indicates whether email notification should be sent
-	Email          string   // Store the submitter's email address
+	RepositoryID    string   // Unique identifier for the repository
+	ScanID          string   // Scan record created by the handler in the queued state
+	Owner           string   // GitHub repository owner (username or organization)
+	Name            string   // GitHub repository name
+	CloneURL        string   // URL to clone the repository (HTTPS or SSH)
+	UploadDir       string   // Extracted upload to scan in place of a clone; removed when the scan ends
+	Ref             string   // Branch, tag, or commit to scan; empty scans the default branch
+	VulnTypes       []string // Types of vulnerabilities to scan for (e.g., "INJECTION", "XSS")
+	Taxonomy        string   // Registered taxonomy VulnTypes come from; empty is OWASP Top 10 2021
+	FileExtensions  []string // File extensions to include in the scan (e.g., ".go", ".js")
+	IncludeGlobs    []string // When set, only files matching one of these globs are scanned (e.g., "handlers/**")
+	MinSeverity     string   // Findings below this severity are dropped (empty keeps all)
+	ScanVendored    bool     // Walk vendor/ directories, which are skipped by default
+	ScanSkippedDirs []string // Other normally skipped directory names to walk anyway
+	ExcludePatterns []string // Path globs skipped in addition to the defaults and .gitignore
+	IncludePatterns []string // Path globs scanned even if otherwise skipped
+	DisableCache    bool     // Send every file to the model instead of reusing cached results
+	NotifyEmail     bool     // Indicates whether email notification should be sent
+	Email           string   // Store the submitter's email address
+	CallbackURL     string   // https URL the finished scan's signed summary is posted to; empty sends none
+	Model           string   // LLM model to scan with; empty uses the default
+	BatchSize       int      // Files scanned per activity call; 0 uses ScanBatchSize
+	MaxFiles        int      // Most files the scan covers; 0 uses SCAN_MAX_FILES
+
+	// Budget caps the estimated tokens and cost of the whole scan; once spent, the scan ends as budget_exceeded
+	// with the results it has. The zero value has no limit
+	Budget services.ScanBudget
+
+	// Timeouts of the clone and scan activities, resolved from the repository's size when the scan is queued
+	Timeouts ScanTimeouts
+
+	// IncrementalSince is a commit SHA; when set, only files changed since it are analyzed and the
+	// findings of the completed scan of that commit are kept for the rest. Without such a scan the
+	// full tree is scanned
+	IncrementalSince string
+
+	// Continuation is set when a long scan continues as a new run; nil for the first run
+	Continuation *ScanContinuation
 }
 
+// scope returns the options that decide which files the scan covers and which findings it keeps
+func (input ScanWorkflowInput) scope() services.ScanScope {
+	return services.ScanScope{
+		FileExtensions:  input.FileExtensions,
+		IncludeGlobs:    input.IncludeGlobs,
+		ScanVendored:    input.ScanVendored,
+		ScanSkippedDirs: input.ScanSkippedDirs,
+		ExcludePatterns: input.ExcludePatterns,
+		IncludePatterns: input.IncludePatterns,
+		Taxonomy:        input.Taxonomy,
+		VulnTypes:       input.VulnTypes,
+		MinSeverity:     input.MinSeverity,
+	}
+}
+
+// ScanContinuation carries a scan's state from one workflow run to the next
+// Which files are done is not carried here: it is read from the scan_files table
+type ScanContinuation struct {
+	RepoDir     string    // Where the first run cloned the repository; later runs expect the same worker host
+	Ref         string    // Branch ref of the clone
+	CommitSHA   string    // Commit being scanned
+	StartTime   time.Time // When the first run started, so the reported duration covers every run
+	BatchesDone int       // Batches completed by earlier runs
+	TokensUsed  int       // Estimated tokens earlier runs sent to the model, counted against the budget
+
+	// Incremental scan state from the clone; empty for a full scan
+	IncrementalBaseSHA string   // Commit the scan diffs against
+	BaseScanID         string   // Scan of that commit whose findings are carried forward
+	ChangedFiles       []string // Paths changed since the base commit
+}
+
+// ScanBatchSize is the default number of files each scan activity call scans
+const ScanBatchSize = 25
+
+// scanBatchesChange is the workflow.GetVersion change ID of scanning in batches and continuing as new runs
+const scanBatchesChange = "scan-in-batches"
+
+// ScanBatchesPerRun is how many batches one workflow run scans before continuing as a new run
+// Each batch adds a handful of history events, so this keeps every run's history small
+const ScanBatchesPerRun = 20
+
+// ScanActivityTimeout is the default StartToCloseTimeout of the scan activity; SCAN_ACTIVITY_TIMEOUT overrides it
+// The scanner sizes its time budget against the timeout so per-file requests can't overrun it
+const ScanActivityTimeout = 30 * time.Minute
+
 // ScanWorkflowOutput represents the output from the scan workflow
 // This struct contains the results of the scan, including any vulnerabilities found
 type ScanWorkflowOutput struct {
@@ -37,71 +114,187 @@ type ScanWorkflowOutput struct {
 // This is the main workflow that coordinates the entire scanning process
 // It follows these steps:
 // 1. Clone the repository
-// 2. Scan the repository for vulnerabilities
+// 2. Scan the repository for vulnerabilities, one batch of files per activity
 // 3. Return the scan results
-func ScanWorkflow(ctx workflow.Context, input ScanWorkflowInput) (*ScanWorkflowOutput, error) {
+// Large repositories continue as a new run every ScanBatchesPerRun batches so the history stays bounded;
+// the clone, start time, and per-file progress (kept in scan_files) carry over to the next run
+// An uploaded archive is scanned where it was extracted instead of being cloned; the upload or clone
+// is removed once the scan ends, however it ends
+func ScanWorkflow(ctx workflow.Context, input ScanWorkflowInput) (output *ScanWorkflowOutput, err error) {
 	logger := workflow.GetLogger(ctx)
-	logger.Info("Starting repository scan workflow", "repository", input.Owner+"/"+input.Name)
 
-	// Record workflow start time for tracking scan duration
-	startTime := workflow.Now(ctx)
-
-	// Step 1: Clone repository
-	// This executes the CloneRepositoryActivity to download the repository code
-	var cloneOutput CloneActivityOutput
-	cloneCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		StartToCloseTimeout: 60 * time.Minute, // Allow up to 60 minutes for cloning (large repos may take time)
-		RetryPolicy: &temporal.RetryPolicy{
-			MaximumAttempts: 3, // Retry up to 3 times if cloning fails
-		},
-	})
+	if input.UploadDir != "" {
+		defer func() {
+			// The next run still needs the files
+			if workflow.IsContinueAsNewError(err) {
+				return
+			}
+			removeUpload(ctx, input.UploadDir)
+		}()
+	}
 
-	// Execute the clone activity and wait for it to complete
-	cloneErr := workflow.ExecuteActivity(cloneCtx, CloneRepositoryActivity, CloneActivityInput{
-		RepositoryID: input.RepositoryID,
-		CloneURL:     input.CloneURL,
-	}).Get(ctx, &cloneOutput)
+	// Post the outcome to the requester's callback URL once the scan ends, however it ends
+	if input.CallbackURL != "" {
+		defer func() {
+			if workflow.IsContinueAsNewError(err) {
+				return
+			}
+			sendScanCallback(ctx, input, output, err)
+		}()
+	}
 
-	// If cloning fails, return an error result
-	if cloneErr != nil {
+	// Report progress to status queries in every run, not just the final one
+	batchesDone := 0
+	workflow.SetQueryHandler(ctx, "scan_result", func() (*ScanWorkflowOutput, error) {
 		return &ScanWorkflowOutput{
 			RepositoryID: input.RepositoryID,
-			Status:       "failed",
-			Message:      "Failed to clone repository: " + cloneErr.Error(),
-			StartTime:    startTime,
-			EndTime:      workflow.Now(ctx),
-		}, cloneErr
+			ScanID:       input.ScanID,
+			Status:       services.ScanStatusScanning,
+			Message:      fmt.Sprintf("Scan in progress, %d batches completed", batchesDone),
+		}, nil
+	})
+
+	continuation := input.Continuation
+	if continuation == nil {
+		logger.Info("Starting repository scan workflow", "repository", input.Owner+"/"+input.Name)
+
+		// Record workflow start time for tracking scan duration
+		startTime := workflow.Now(ctx)
+
+		output, cloneOutput, err := prepareScan(ctx, input, startTime)
+		if err != nil {
+			return output, err
+		}
+
+		continuation = &ScanContinuation{
+			RepoDir:   cloneOutput.RepoDir,
+			Ref:       cloneOutput.Ref,
+			CommitSHA: cloneOutput.CommitSHA,
+			StartTime: startTime,
+
+			IncrementalBaseSHA: cloneOutput.IncrementalBaseSHA,
+			BaseScanID:         cloneOutput.BaseScanID,
+			ChangedFiles:       cloneOutput.ChangedFiles,
+		}
+	} else {
+		logger.Info("Continuing repository scan workflow",
+			"repository", input.Owner+"/"+input.Name,
+			"batches_done", continuation.BatchesDone)
+	}
+	// A clone is removed once the scan ends, however it ends; the next run still needs it
+	if input.UploadDir == "" && continuation.RepoDir != "" {
+		repoDir := continuation.RepoDir
+		defer func() {
+			if workflow.IsContinueAsNewError(err) {
+				return
+			}
+			removeWorkspace(ctx, repoDir)
+		}()
+	}
+
+	batchesDone = continuation.BatchesDone
+	tokensUsed := continuation.TokensUsed
+	startTime := continuation.StartTime
+
+	batchSize := input.BatchSize
+	if batchSize <= 0 {
+		batchSize = ScanBatchSize
+	}
+	// Scans started before batching scanned every file in one activity call; a batch size of 0 still does,
+	// so they replay that single call and never continue as a new run
+	if workflow.GetVersion(ctx, scanBatchesChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		batchSize = 0
 	}
 
 	// Step 2: Scan repository for vulnerabilities
 	// This executes the ScanRepositoryActivity to analyze the code for security issues
 	var scanOutput ScanActivityOutput
+	timeouts := input.Timeouts.withDefaults()
 	scanCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		StartToCloseTimeout: 30 * time.Minute, // Allow up to 30 minutes for scanning
+		StartToCloseTimeout: timeouts.Scan,
+		HeartbeatTimeout:    ScanHeartbeatTimeout, // Detects a batch whose worker died long before its timeout
 		RetryPolicy: &temporal.RetryPolicy{
-			MaximumAttempts: 2, // Retry up to 2 times if scanning fails
+			MaximumAttempts: timeouts.ScanMaxAttempts,
 		},
 	})
 
-	// Execute the scan activity and wait for it to complete
-	scanErr := workflow.ExecuteActivity(scanCtx, ScanRepositoryActivity, ScanActivityInput{
-		RepositoryID:   input.RepositoryID,
-		RepoDir:        cloneOutput.RepoDir,
-		VulnTypes:      input.VulnTypes,
-		FileExtensions: input.FileExtensions,
-		NotifyEmail:    input.NotifyEmail,
-		Email:          input.Email,
-	}).Get(ctx, &scanOutput)
-
-	// If scanning fails, return an error result
-	if scanErr != nil {
-		return &ScanWorkflowOutput{
-			RepositoryID: input.RepositoryID,
-			Status:       "failed",
-			Message:      "Failed to scan repository: " + scanErr.Error(),
-			StartTime:    startTime,
-			EndTime:      workflow.Now(ctx),
-		}, scanErr
+	for batch := 0; ; batch++ {
+		// Hand the remaining files to a fresh run before this one's history grows too large
+		if batch == ScanBatchesPerRun {
+			logger.Info("Continuing scan as new run", "batches_done", batchesDone)
+			next := input
+			nextContinuation := *continuation
+			nextContinuation.BatchesDone = batchesDone
+			nextContinuation.TokensUsed = tokensUsed
+			next.Continuation = &nextContinuation
+			return nil, workflow.NewContinueAsNewError(ctx, ScanWorkflow, next)
+		}
+
+		// Execute the scan activity and wait for it to complete
+		// Files recorded by earlier batches are skipped, so each call scans the next batch
+		scanErr := workflow.ExecuteActivity(scanCtx, ScanRepositoryActivity, ScanActivityInput{
+			RepositoryID:    input.RepositoryID,
+			ScanID:          input.ScanID,
+			RepoDir:         continuation.RepoDir,
+			Ref:             continuation.Ref,
+			CommitSHA:       continuation.CommitSHA,
+			VulnTypes:       input.VulnTypes,
+			Taxonomy:        input.Taxonomy,
+			FileExtensions:  input.FileExtensions,
+			IncludeGlobs:    input.IncludeGlobs,
+			MinSeverity:     input.MinSeverity,
+			ScanVendored:    input.ScanVendored,
+			ScanSkippedDirs: input.ScanSkippedDirs,
+			ExcludePatterns: input.ExcludePatterns,
+			IncludePatterns: input.IncludePatterns,
+			DisableCache:    input.DisableCache,
+			NotifyEmail:     input.NotifyEmail,
+			Email:           input.Email,
+			Model:           input.Model,
+			BatchSize:       batchSize,
+			MaxFiles:        input.MaxFiles,
+			MaxAttempts:     timeouts.ScanMaxAttempts,
+			Budget:          input.Budget,
+			TokensUsed:      tokensUsed,
+			ActivityTimeout: timeouts.Scan,
+
+			IncrementalBaseSHA: continuation.IncrementalBaseSHA,
+			BaseScanID:         continuation.BaseScanID,
+			ChangedFiles:       continuation.ChangedFiles,
+		}).Get(ctx, &scanOutput)
+
+		// A scan canceled once its clone finished, before the cancel reached the workflow, ends as canceled
+		if scanErr != nil && (temporal.IsCanceledError(scanErr) || isScanCanceled(scanErr)) {
+			notifyCtx, _ := workflow.NewDisconnectedContext(ctx)
+			notifyScanStatus(notifyCtx, input, services.ScanStatusCanceled, "Scan canceled")
+			return &ScanWorkflowOutput{
+				RepositoryID: input.RepositoryID,
+				ScanID:       input.ScanID,
+				Status:       services.ScanStatusCanceled,
+				Message:      "Scan canceled",
+				StartTime:    startTime,
+				EndTime:      workflow.Now(ctx),
+			}, scanErr
+		}
+
+		// If scanning fails, return an error result
+		if scanErr != nil {
+			notifyScanStatus(ctx, input, services.ScanStatusFailed, "Failed to scan repository: "+scanErr.Error())
+			return &ScanWorkflowOutput{
+				RepositoryID: input.RepositoryID,
+				ScanID:       input.ScanID,
+				Status:       services.ScanStatusFailed,
+				Message:      "Failed to scan repository: " + scanErr.Error(),
+				StartTime:    startTime,
+				EndTime:      workflow.Now(ctx),
+			}, scanErr
+		}
+
+		batchesDone++
+		tokensUsed += scanOutput.TokensUsed
+		if scanOutput.FilesRemaining == 0 || scanOutput.Status != services.ScanStatusScanning {
+			break
+		}
 	}
 
 	// Convert the vulnerabilities from the activity output to the workflow output format
@@ -122,28 +315,289 @@ func ScanWorkflow(ctx workflow.Context, input ScanWorkflowInput) (*ScanWorkflowO
 		vulnerabilities = append(vulnerabilities, vuln)
 	}
 
+	// Report a scan that stopped at its time or cost budget, or lost findings, as such rather than as fully completed
+	finalStatus := services.ScanStatusCompleted
+	finalMessage := "Scan completed successfully"
+	switch scanOutput.Status {
+	case services.ScanStatusTimeBudgetReached:
+		finalStatus = services.ScanStatusTimeBudgetReached
+		finalMessage = "Scan time budget reached, returning partial results"
+	case services.ScanStatusBudgetExceeded:
+		finalStatus = services.ScanStatusBudgetExceeded
+		finalMessage = fmt.Sprintf("Scan budget exceeded after an estimated %d tokens, returning partial results", tokensUsed)
+	case services.ScanStatusCompletedWithErrors:
+		finalStatus = services.ScanStatusCompletedWithErrors
+		finalMessage = fmt.Sprintf("Scan completed, but the findings of %d files could not be stored", scanOutput.InsertFailures)
+	}
+
 	// Register query handler to expose results
 	// This allows external systems to query the current status of the workflow
 	workflow.SetQueryHandler(ctx, "scan_result", func() (*ScanWorkflowOutput, error) {
 		return &ScanWorkflowOutput{
 			RepositoryID:    input.RepositoryID,
 			ScanID:          scanOutput.ScanID,
-			Status:          "completed",
-			Message:         "Scan completed successfully",
+			Status:          finalStatus,
+			Message:         finalMessage,
 			StartTime:       startTime,
 			EndTime:         workflow.Now(ctx),
 			Vulnerabilities: vulnerabilities,
 		}, nil
 	})
 
+	notifyScanStatus(ctx, input, finalStatus, finalMessage)
+
 	// Successfully completed - return the final scan results
 	return &ScanWorkflowOutput{
 		RepositoryID:    input.RepositoryID,
 		ScanID:          scanOutput.ScanID,
-		Status:          "completed",
-		Message:         "Scan completed successfully",
+		Status:          finalStatus,
+		Message:         finalMessage,
 		StartTime:       startTime,
 		EndTime:         workflow.Now(ctx),
 		Vulnerabilities: vulnerabilities,
 	}, nil
 }
+
+// isScanCanceled reports whether the scan activity refused to start because the scan was already canceled
+func isScanCanceled(err error) bool {
+	var appErr *temporal.ApplicationError
+	return errors.As(err, &appErr) && appErr.Type() == ScanCanceledErrorType
+}
+
+// prepareScan runs the first run's steps before scanning: waiting out a worker pause and cloning
+// On failure it returns the output and error the workflow should finish with
+func prepareScan(ctx workflow.Context, input ScanWorkflowInput, startTime time.Time) (*ScanWorkflowOutput, *CloneActivityOutput, error) {
+	// Announce each state transition to webhook subscribers as the scan progresses
+	notifyScanStatus(ctx, input, services.ScanStatusQueued, "")
+
+	// Stay queued while an operator has paused scan processing
+	if err := waitWhileWorkerPaused(ctx); err != nil {
+		notifyCtx, _ := workflow.NewDisconnectedContext(ctx)
+		notifyScanStatus(notifyCtx, input, services.ScanStatusCanceled, "Scan canceled while queued")
+		return &ScanWorkflowOutput{
+			RepositoryID: input.RepositoryID,
+			ScanID:       input.ScanID,
+			Status:       services.ScanStatusCanceled,
+			Message:      "Scan canceled while queued",
+			StartTime:    startTime,
+			EndTime:      workflow.Now(ctx),
+		}, nil, err
+	}
+
+	// An uploaded archive was extracted before the workflow started, so there is nothing to clone
+	if input.UploadDir != "" {
+		notifyScanStatus(ctx, input, services.ScanStatusScanning, "")
+		return nil, &CloneActivityOutput{RepositoryID: input.RepositoryID, RepoDir: input.UploadDir}, nil
+	}
+
+	notifyScanStatus(ctx, input, services.ScanStatusCloning, "")
+
+	// Step 1: Clone repository
+	// This executes the CloneRepositoryActivity to download the repository code
+	var cloneOutput CloneActivityOutput
+	timeouts := input.Timeouts.withDefaults()
+	cloneCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: timeouts.Clone,        // Estimated from the repository's size when it is known
+		HeartbeatTimeout:    CloneHeartbeatTimeout, // Lets a cancel request reach a hung clone promptly
+		WaitForCancellation: true,                  // Wait for the activity to remove its partial clone before finishing
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: timeouts.CloneMaxAttempts,
+		},
+	})
+
+	// Execute the clone activity and wait for it to complete
+	cloneErr := workflow.ExecuteActivity(cloneCtx, CloneRepositoryActivity, CloneActivityInput{
+		RepositoryID: input.RepositoryID,
+		ScanID:       input.ScanID,
+		CloneURL:     input.CloneURL,
+		Ref:          input.Ref,
+		MaxAttempts:  timeouts.CloneMaxAttempts,
+
+		IncrementalSince: input.IncrementalSince,
+		Scope:            input.scope(),
+	}).Get(ctx, &cloneOutput)
+
+	// A scan canceled while cloning ends as canceled rather than failed
+	if cloneErr != nil && temporal.IsCanceledError(cloneErr) {
+		// The workflow context is canceled too, so notify from a disconnected one
+		notifyCtx, _ := workflow.NewDisconnectedContext(ctx)
+		notifyScanStatus(notifyCtx, input, services.ScanStatusCanceled, "Scan canceled while cloning")
+		return &ScanWorkflowOutput{
+			RepositoryID: input.RepositoryID,
+			ScanID:       input.ScanID,
+			Status:       services.ScanStatusCanceled,
+			Message:      "Scan canceled while cloning",
+			StartTime:    startTime,
+			EndTime:      workflow.Now(ctx),
+		}, nil, cloneErr
+	}
+
+	// If cloning fails, return an error result
+	if cloneErr != nil {
+		notifyScanStatus(ctx, input, services.ScanStatusFailed, "Failed to clone repository: "+cloneErr.Error())
+		return &ScanWorkflowOutput{
+			RepositoryID: input.RepositoryID,
+			ScanID:       input.ScanID,
+			Status:       services.ScanStatusFailed,
+			Message:      "Failed to clone repository: " + cloneErr.Error(),
+			StartTime:    startTime,
+			EndTime:      workflow.Now(ctx),
+		}, nil, cloneErr
+	}
+
+	notifyScanStatus(ctx, input, services.ScanStatusScanning, "")
+	return nil, &cloneOutput, nil
+}
+
+// removeUpload deletes an uploaded archive's extracted files
+// It runs from a disconnected context so a canceled scan still cleans up; a failure is only logged
+func removeUpload(ctx workflow.Context, uploadDir string) {
+	cleanupCtx, _ := workflow.NewDisconnectedContext(ctx)
+	cleanupCtx = workflow.WithActivityOptions(cleanupCtx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+
+	if err := workflow.ExecuteActivity(cleanupCtx, RemoveUploadActivity, uploadDir).Get(cleanupCtx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to remove uploaded archive", "upload_dir", uploadDir, "error", err)
+	}
+}
+
+// ScanCallbackMaxAttempts is how many times a scan callback is attempted before its delivery is given up
+const ScanCallbackMaxAttempts = 5
+
+// sendScanCallback posts the scan's final status and finding summary to its callback URL
+// It runs from a disconnected context so a canceled scan is reported too; retries back off from
+// 10 seconds, and a delivery that still fails is only logged, since the scan itself has finished
+func sendScanCallback(ctx workflow.Context, input ScanWorkflowInput, output *ScanWorkflowOutput, scanErr error) {
+	status, message := services.ScanStatusFailed, ""
+	if output != nil {
+		status, message = output.Status, output.Message
+	} else if scanErr != nil {
+		message = scanErr.Error()
+	}
+
+	callbackCtx, _ := workflow.NewDisconnectedContext(ctx)
+	callbackCtx = workflow.WithActivityOptions(callbackCtx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    10 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumAttempts:    ScanCallbackMaxAttempts,
+		},
+	})
+
+	err := workflow.ExecuteActivity(callbackCtx, SendScanCallbackActivity, ScanCallbackInput{
+		CallbackURL:  input.CallbackURL,
+		RepositoryID: input.RepositoryID,
+		ScanID:       input.ScanID,
+		Status:       status,
+		Message:      message,
+	}).Get(callbackCtx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Scan callback was not delivered", "scan_id", input.ScanID, "error", err)
+	}
+}
+
+// scanWorkspaceCleanupChange is the workflow.GetVersion change ID of removing the clone once the scan ends
+// Scans started before workspaces were removed recorded no cleanup activity, so they replay without it
+const scanWorkspaceCleanupChange = "remove-scan-workspace"
+
+// removeWorkspace deletes the directory a scan cloned its repository into
+// Like removeUpload, it runs from a disconnected context and only logs a failure; the worker's startup
+// reaper removes whatever it leaves behind
+func removeWorkspace(ctx workflow.Context, repoDir string) {
+	if workflow.GetVersion(ctx, scanWorkspaceCleanupChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return
+	}
+
+	cleanupCtx, _ := workflow.NewDisconnectedContext(ctx)
+	cleanupCtx = workflow.WithActivityOptions(cleanupCtx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+
+	if err := workflow.ExecuteActivity(cleanupCtx, RemoveWorkspaceActivity, repoDir).Get(cleanupCtx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to remove scan workspace", "repo_dir", repoDir, "error", err)
+	}
+}
+
+// WorkerPausePollInterval is how often a queued scan rechecks whether scan processing was resumed
+const WorkerPausePollInterval = 30 * time.Second
+
+// workerPauseChange is the workflow.GetVersion change ID of the pause check before cloning
+// Scans started before scan processing could be paused replay without checking
+const workerPauseChange = "wait-while-worker-paused"
+
+// waitWhileWorkerPaused blocks the workflow until scan processing is not paused
+// Scans already past this point are unaffected by a pause, so in-flight work finishes.
+// It only returns an error if the workflow is canceled while waiting
+func waitWhileWorkerPaused(ctx workflow.Context) error {
+	if workflow.GetVersion(ctx, workerPauseChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return nil
+	}
+
+	checkCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+
+	logged := false
+	for {
+		var paused bool
+		if err := workflow.ExecuteActivity(checkCtx, CheckWorkerPausedActivity).Get(ctx, &paused); err != nil {
+			if temporal.IsCanceledError(err) {
+				return err
+			}
+			// Not knowing the state shouldn't hold scans back
+			workflow.GetLogger(ctx).Warn("Failed to check worker pause state, continuing", "error", err)
+			return nil
+		}
+		if !paused {
+			return nil
+		}
+
+		if !logged {
+			workflow.GetLogger(ctx).Info("Scan processing is paused, waiting to start")
+			logged = true
+		}
+		if err := workflow.Sleep(ctx, WorkerPausePollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// scanStatusNotificationsChange is the workflow.GetVersion change ID of the status notifications
+// Scans started before notifications existed recorded none, so they replay without them
+const scanStatusNotificationsChange = "scan-status-notifications"
+
+// notifyScanStatus runs NotifyScanStatusActivity for a state transition
+// It waits for delivery so events arrive in order, but never fails the workflow
+func notifyScanStatus(ctx workflow.Context, input ScanWorkflowInput, status, message string) {
+	if workflow.GetVersion(ctx, scanStatusNotificationsChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return
+	}
+
+	notifyCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 1, // Deliveries are best effort; retrying would resend to every subscriber
+		},
+	})
+
+	err := workflow.ExecuteActivity(notifyCtx, NotifyScanStatusActivity, ScanStatusNotification{
+		RepositoryID: input.RepositoryID,
+		ScanID:       input.ScanID,
+		Status:       status,
+		Message:      message,
+	}).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Scan status notification failed", "status", status, "error", err)
+	}
+}
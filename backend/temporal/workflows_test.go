@@ -0,0 +1,1096 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ritikarora108/ai-powered-sast-tool/backend/services"
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestScanWorkflowPassesTheScanToItsActivities(t *testing.T) {
+	input := ScanWorkflowInput{
+		RepositoryID:     "repo-1",
+		ScanID:           "scan-1",
+		Owner:            "octo",
+		Name:             "repo",
+		CloneURL:         "https://github.com/octo/repo.git",
+		Ref:              "main",
+		FileExtensions:   []string{".go"},
+		IncludeGlobs:     []string{"api/**"},
+		MinSeverity:      "high",
+		MaxFiles:         40,
+		IncrementalSince: "abc123",
+		Timeouts:         ScanTimeouts{CloneMaxAttempts: 4, ScanMaxAttempts: 3},
+	}
+	cloneOutput := &CloneActivityOutput{
+		RepositoryID:       "repo-1",
+		RepoDir:            "/tmp/repo",
+		Ref:                "refs/heads/main",
+		CommitSHA:          "def456",
+		IncrementalBaseSHA: "abc123",
+		BaseScanID:         "scan-0",
+		ChangedFiles:       []string{"api/handler.go"},
+	}
+
+	tests := []struct {
+		name       string
+		cloneErr   error
+		wantStatus string // Status of the workflow's output; empty when the workflow fails
+	}{
+		{name: "scan completes", wantStatus: services.ScanStatusCompleted},
+		{name: "clone fails", cloneErr: temporal.NewNonRetryableApplicationError("repository not found", "CloneError", nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterActivity(CloneRepositoryActivity)
+			env.RegisterActivity(ScanRepositoryActivity)
+			env.RegisterActivity(NotifyScanStatusActivity)
+			env.RegisterActivity(CheckWorkerPausedActivity)
+			env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+			env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+
+			var cloneInputs []CloneActivityInput
+			var scanInputs []ScanActivityInput
+			env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+					cloneInputs = append(cloneInputs, input)
+					if tt.cloneErr != nil {
+						return nil, tt.cloneErr
+					}
+					return cloneOutput, nil
+				})
+			env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+					scanInputs = append(scanInputs, input)
+					return &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID, Status: services.ScanStatusCompleted}, nil
+				})
+
+			env.ExecuteWorkflow(ScanWorkflow, input)
+			if !env.IsWorkflowCompleted() {
+				t.Fatal("workflow did not finish")
+			}
+
+			// The clone gets the scan's record, source, retry limit, and scope
+			if len(cloneInputs) != 1 {
+				t.Fatalf("clone activity ran %d times, want once", len(cloneInputs))
+			}
+			clone := cloneInputs[0]
+			if clone.RepositoryID != "repo-1" || clone.ScanID != "scan-1" || clone.CloneURL != input.CloneURL ||
+				clone.Ref != "main" || clone.IncrementalSince != "abc123" || clone.MaxAttempts != 4 {
+				t.Errorf("clone input = %+v, want the workflow's repository, scan, clone URL, ref, base, and 4 attempts", clone)
+			}
+			if clone.Scope.Key() != input.scope().Key() {
+				t.Errorf("clone scope = %s, want %s", clone.Scope.Key(), input.scope().Key())
+			}
+
+			if tt.wantStatus == "" {
+				if len(scanInputs) != 0 {
+					t.Errorf("scan activity ran %d times after the clone failed", len(scanInputs))
+				}
+				if env.GetWorkflowError() == nil {
+					t.Error("workflow succeeded, want the clone error")
+				}
+				return
+			}
+
+			// The scan gets the workflow's options and where and what the clone checked out
+			if len(scanInputs) != 1 {
+				t.Fatalf("scan activity ran %d times, want once", len(scanInputs))
+			}
+			scan := scanInputs[0]
+			if scan.ScanID != "scan-1" || scan.RepoDir != cloneOutput.RepoDir || scan.Ref != cloneOutput.Ref ||
+				scan.CommitSHA != cloneOutput.CommitSHA || scan.MaxFiles != 40 || scan.MinSeverity != "high" || scan.MaxAttempts != 3 {
+				t.Errorf("scan input = %+v, want scan-1 at the clone's directory, ref, and commit with the workflow's options and 3 attempts", scan)
+			}
+			if scan.scope().Key() != input.scope().Key() {
+				t.Errorf("scan scope = %s, want %s", scan.scope().Key(), input.scope().Key())
+			}
+			if scan.IncrementalBaseSHA != "abc123" || scan.BaseScanID != "scan-0" || strings.Join(scan.ChangedFiles, ",") != "api/handler.go" {
+				t.Errorf("scan incremental base = %q, %q, %v; want the clone's abc123, scan-0, [api/handler.go]",
+					scan.IncrementalBaseSHA, scan.BaseScanID, scan.ChangedFiles)
+			}
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("workflow returned error: %v", err)
+			}
+			var output ScanWorkflowOutput
+			if err := env.GetWorkflowResult(&output); err != nil {
+				t.Fatalf("decode workflow result: %v", err)
+			}
+			if output.Status != tt.wantStatus || output.ScanID != "scan-1" {
+				t.Errorf("workflow output = %s for %q, want %s for scan-1", output.Status, output.ScanID, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestScanWorkflowNotifiesEachStatusTransition(t *testing.T) {
+	tests := []struct {
+		name         string
+		cloneErr     error
+		scanErr      error
+		wantStatuses []string
+	}{
+		{
+			name: "scan completes",
+			wantStatuses: []string{
+				services.ScanStatusQueued, services.ScanStatusCloning, services.ScanStatusScanning, services.ScanStatusCompleted,
+			},
+		},
+		{
+			name:         "clone fails",
+			cloneErr:     temporal.NewNonRetryableApplicationError("repository not found", "CloneError", nil),
+			wantStatuses: []string{services.ScanStatusQueued, services.ScanStatusCloning, services.ScanStatusFailed},
+		},
+		{
+			name:    "scan fails",
+			scanErr: temporal.NewNonRetryableApplicationError("model unavailable", "ScanError", nil),
+			wantStatuses: []string{
+				services.ScanStatusQueued, services.ScanStatusCloning, services.ScanStatusScanning, services.ScanStatusFailed,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterActivity(CloneRepositoryActivity)
+			env.RegisterActivity(ScanRepositoryActivity)
+			env.RegisterActivity(NotifyScanStatusActivity)
+			env.RegisterActivity(CheckWorkerPausedActivity)
+			env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+
+			env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+					if tt.cloneErr != nil {
+						return nil, tt.cloneErr
+					}
+					return &CloneActivityOutput{RepositoryID: input.RepositoryID, RepoDir: "/tmp/repo"}, nil
+				})
+			env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+					if tt.scanErr != nil {
+						return nil, tt.scanErr
+					}
+					return &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID}, nil
+				})
+
+			// The subscriber sees every transition the notification activity is asked to announce
+			var notifications []ScanStatusNotification
+			env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanStatusNotification) error {
+					notifications = append(notifications, input)
+					return nil
+				})
+
+			env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", Owner: "octo", Name: "repo"})
+			if !env.IsWorkflowCompleted() {
+				t.Fatal("workflow did not finish")
+			}
+
+			var statuses []string
+			for _, notification := range notifications {
+				if notification.ScanID != "scan-1" || notification.RepositoryID != "repo-1" {
+					t.Errorf("notification %+v is not for scan-1 of repo-1", notification)
+				}
+				statuses = append(statuses, notification.Status)
+			}
+			if strings.Join(statuses, ",") != strings.Join(tt.wantStatuses, ",") {
+				t.Errorf("statuses = %v, want %v", statuses, tt.wantStatuses)
+			}
+			if last := notifications[len(notifications)-1]; last.Status == services.ScanStatusFailed && last.Message == "" {
+				t.Error("failure notification has no message")
+			}
+		})
+	}
+}
+
+func TestScanWorkflowStartedBeforeNotificationsSendsNone(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repo"}, nil)
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&ScanActivityOutput{RepositoryID: "repo-1", ScanID: "scan-1"}, nil)
+
+	// A history recorded before notifications existed has no version marker for them
+	env.OnGetVersion(scanStatusNotificationsChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	notified := 0
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanStatusNotification) error {
+			notified++
+			return nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	if notified != 0 {
+		t.Errorf("sent %d notifications, want none for a scan started before they existed", notified)
+	}
+}
+
+func TestScanWorkflowSurvivesFailedNotifications(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repo"}, nil)
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&ScanActivityOutput{RepositoryID: "repo-1", ScanID: "scan-1"}, nil)
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).
+		Return(errors.New("subscriber unreachable"))
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow failed because of notifications: %v", err)
+	}
+	var output ScanWorkflowOutput
+	if err := env.GetWorkflowResult(&output); err != nil {
+		t.Fatalf("decode workflow result: %v", err)
+	}
+	if output.Status != services.ScanStatusCompleted {
+		t.Errorf("status = %s, want %s", output.Status, services.ScanStatusCompleted)
+	}
+}
+
+func TestScanWorkflowPassesTheClonedCommitToTheScan(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(&CloneActivityOutput{
+		RepositoryID:  "repo-1",
+		RepoDir:       "/tmp/repo",
+		Ref:           "refs/heads/main",
+		CommitSHA:     "bbb222",
+		BaseCommitSHA: "aaa111",
+	}, nil)
+
+	var scanInput ScanActivityInput
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+			scanInput = input
+			return &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID}, nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	// The scan activity records this commit as the branch's new base once it completes
+	if scanInput.Ref != "refs/heads/main" || scanInput.CommitSHA != "bbb222" {
+		t.Errorf("scan ran on %q at %q, want refs/heads/main at bbb222", scanInput.Ref, scanInput.CommitSHA)
+	}
+}
+
+func TestScanWorkflowAppliesTheResolvedTimeouts(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+
+	var cloneInfo, scanInfo activity.Info
+	var scanInput ScanActivityInput
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+			cloneInfo = activity.GetInfo(ctx)
+			return &CloneActivityOutput{RepositoryID: input.RepositoryID, RepoDir: "/tmp/repo"}, nil
+		})
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+			scanInfo = activity.GetInfo(ctx)
+			scanInput = input
+			return &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID}, nil
+		})
+
+	timeouts := ScanTimeouts{Clone: 12 * time.Minute, CloneMaxAttempts: 4, Scan: 50 * time.Minute, ScanMaxAttempts: 3}
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", Timeouts: timeouts})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+
+	if got := cloneInfo.Deadline.Sub(cloneInfo.StartedTime); got != 12*time.Minute || cloneInfo.HeartbeatTimeout != CloneHeartbeatTimeout {
+		t.Errorf("clone timeout = %v, heartbeat %v, want 12m, %v", got, cloneInfo.HeartbeatTimeout, CloneHeartbeatTimeout)
+	}
+	if got := scanInfo.Deadline.Sub(scanInfo.StartedTime); got != 50*time.Minute || scanInfo.HeartbeatTimeout != ScanHeartbeatTimeout {
+		t.Errorf("scan timeout = %v, heartbeat %v, want 50m, %v", got, scanInfo.HeartbeatTimeout, ScanHeartbeatTimeout)
+	}
+	// The activity sizes its time budget from the timeout it runs under
+	if scanInput.ActivityTimeout != 50*time.Minute {
+		t.Errorf("scan activity got timeout %v, want 50m", scanInput.ActivityTimeout)
+	}
+}
+
+func TestScanWorkflowCanceledWhileCloning(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(nil, temporal.NewCanceledError())
+	scanned := false
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+			scanned = true
+			return &ScanActivityOutput{}, nil
+		})
+	var statuses []string
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanStatusNotification) error {
+			statuses = append(statuses, input.Status)
+			return nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err == nil {
+		t.Fatal("workflow succeeded, want the cancellation")
+	}
+	if scanned {
+		t.Error("scan ran after the clone was canceled")
+	}
+	if len(statuses) == 0 || statuses[len(statuses)-1] != services.ScanStatusCanceled {
+		t.Errorf("statuses = %v, want the scan announced as canceled", statuses)
+	}
+}
+
+func TestScanWorkflowCanceledAfterTheClone(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+
+	// The clone finished just as the scan was canceled, so the scan activity finds the record canceled
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(&CloneActivityOutput{RepoDir: "/tmp/repo"}, nil)
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+		nil, temporal.NewNonRetryableApplicationError("scan was canceled", ScanCanceledErrorType, nil))
+	env.OnActivity(RemoveWorkspaceActivity, mock.Anything, mock.Anything).Return(nil)
+	var statuses []string
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanStatusNotification) error {
+			statuses = append(statuses, input.Status)
+			return nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err == nil {
+		t.Fatal("workflow succeeded, want the cancellation")
+	}
+	want := []string{services.ScanStatusQueued, services.ScanStatusCloning, services.ScanStatusScanning, services.ScanStatusCanceled}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestScanWorkflowWaitsWhilePausedAndStartsOnResume(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+
+	// The worker is paused for the first three checks and then resumed
+	checks := 0
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(
+		func(ctx context.Context) (bool, error) {
+			checks++
+			return checks <= 3, nil
+		})
+	started := env.Now()
+	var clonedAt time.Time
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+			clonedAt = env.Now()
+			return &CloneActivityOutput{RepositoryID: input.RepositoryID, RepoDir: "/tmp/repo"}, nil
+		})
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(&ScanActivityOutput{}, nil)
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	if checks != 4 {
+		t.Errorf("pause state checked %d times, want 4", checks)
+	}
+	if clonedAt.IsZero() {
+		t.Fatal("scan never started after the worker was resumed")
+	}
+	if waited := clonedAt.Sub(started); waited < 3*WorkerPausePollInterval {
+		t.Errorf("clone started after %v, want it held back for %v while paused", waited, 3*WorkerPausePollInterval)
+	}
+}
+
+func TestScanWorkflowCanceledWhilePaused(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(true, nil)
+
+	cloned := false
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+			cloned = true
+			return &CloneActivityOutput{}, nil
+		})
+	var statuses []string
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanStatusNotification) error {
+			statuses = append(statuses, input.Status)
+			return nil
+		})
+	env.RegisterDelayedCallback(env.CancelWorkflow, 5*WorkerPausePollInterval)
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err == nil {
+		t.Fatal("workflow succeeded, want the cancellation")
+	}
+	if cloned {
+		t.Error("a queued scan was cloned while the worker was paused")
+	}
+	want := []string{services.ScanStatusQueued, services.ScanStatusCanceled}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestScanWorkflowStartedBeforePausingDoesNotCheck(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repo"}, nil)
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&ScanActivityOutput{RepositoryID: "repo-1", ScanID: "scan-1"}, nil)
+
+	// A history recorded before pausing existed went straight to the clone
+	env.OnGetVersion(workerPauseChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	checks := 0
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(
+		func(ctx context.Context) (bool, error) {
+			checks++
+			return true, nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	if checks != 0 {
+		t.Errorf("checked the pause state %d times, want none for a scan started before pausing existed", checks)
+	}
+}
+
+func TestScanWorkflowContinuesLargeScansAsNewRuns(t *testing.T) {
+	// Enough batches that the scan needs a second run to finish
+	totalBatches := ScanBatchesPerRun + 3
+	batchesScanned := 0
+	clones := 0
+	var repoDirs []string
+
+	runScan := func(input ScanWorkflowInput) (*testsuite.TestWorkflowEnvironment, error) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(CloneRepositoryActivity)
+		env.RegisterActivity(ScanRepositoryActivity)
+		env.RegisterActivity(NotifyScanStatusActivity)
+		env.RegisterActivity(CheckWorkerPausedActivity)
+		env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+		env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+		env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+				clones++
+				return &CloneActivityOutput{RepositoryID: input.RepositoryID, RepoDir: "/tmp/repo-1", CommitSHA: "abc123"}, nil
+			})
+		env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+				batchesScanned++
+				repoDirs = append(repoDirs, input.RepoDir)
+				output := &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID, Status: services.ScanStatusScanning}
+				if output.FilesRemaining = (totalBatches - batchesScanned) * input.BatchSize; output.FilesRemaining == 0 {
+					output.Status = services.ScanStatusCompleted
+					output.VulnCount = 1
+					output.VulnerabilitiesFound = []services.Vulnerability{{ID: "vuln-1", Type: services.Injection}}
+				}
+				return output, nil
+			})
+
+		env.ExecuteWorkflow(ScanWorkflow, input)
+		if !env.IsWorkflowCompleted() {
+			t.Fatal("workflow did not finish")
+		}
+		return env, env.GetWorkflowError()
+	}
+
+	first := ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", BatchSize: 10}
+	_, err := runScan(first)
+	var continueAsNew *workflow.ContinueAsNewError
+	if !errors.As(err, &continueAsNew) {
+		t.Fatalf("first run error = %v, want it to continue as a new run", err)
+	}
+	if batchesScanned != ScanBatchesPerRun {
+		t.Errorf("first run scanned %d batches, want %d", batchesScanned, ScanBatchesPerRun)
+	}
+
+	var next ScanWorkflowInput
+	if err := converter.GetDefaultDataConverter().FromPayloads(continueAsNew.Input, &next); err != nil {
+		t.Fatalf("decode continuation input: %v", err)
+	}
+	if next.Continuation == nil || next.Continuation.BatchesDone != ScanBatchesPerRun || next.Continuation.CommitSHA != "abc123" {
+		t.Fatalf("continuation = %+v, want the clone and %d completed batches carried over", next.Continuation, ScanBatchesPerRun)
+	}
+
+	env, err := runScan(next)
+	if err != nil {
+		t.Fatalf("second run returned error: %v", err)
+	}
+	var output ScanWorkflowOutput
+	if err := env.GetWorkflowResult(&output); err != nil {
+		t.Fatalf("read workflow result: %v", err)
+	}
+
+	if clones != 1 {
+		t.Errorf("repository cloned %d times, want once across runs", clones)
+	}
+	if batchesScanned != totalBatches {
+		t.Errorf("scanned %d batches, want %d", batchesScanned, totalBatches)
+	}
+	for _, dir := range repoDirs {
+		if dir != "/tmp/repo-1" {
+			t.Errorf("batch scanned %q, want the first run's clone", dir)
+		}
+	}
+	if output.Status != services.ScanStatusCompleted || len(output.Vulnerabilities) != 1 {
+		t.Errorf("output = %+v, want a completed scan with the final batch's findings", output)
+	}
+	if !output.StartTime.Equal(next.Continuation.StartTime) {
+		t.Errorf("StartTime = %v, want the first run's %v", output.StartTime, next.Continuation.StartTime)
+	}
+}
+
+func TestScanWorkflowStartedBeforeBatchingScansInOneCall(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repo"}, nil)
+
+	// A history recorded before batching has one scan activity call, which scanned every file
+	env.OnGetVersion(scanBatchesChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	var batchSizes []int
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+			batchSizes = append(batchSizes, input.BatchSize)
+			return &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID, Status: services.ScanStatusCompleted}, nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", BatchSize: 10})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	if len(batchSizes) != 1 || batchSizes[0] != 0 {
+		t.Errorf("scan activity batch sizes = %v, want one call scanning every file", batchSizes)
+	}
+}
+
+func TestScanWorkflowStopsWhenTheBudgetIsSpent(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+		&CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repo-1"}, nil)
+	var statuses []string
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanStatusNotification) error {
+			statuses = append(statuses, input.Status)
+			return nil
+		})
+
+	// Each batch spends 400 tokens; the activity reports the budget spent on the third
+	budget := services.ScanBudget{MaxTotalTokens: 1000}
+	var tokensSeen []int
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+			tokensSeen = append(tokensSeen, input.TokensUsed)
+			if input.Budget != budget || input.MaxFiles != 40 {
+				t.Errorf("activity got budget %+v and max files %d, want the workflow's", input.Budget, input.MaxFiles)
+			}
+			output := &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID, TokensUsed: 400,
+				Status: services.ScanStatusScanning, FilesRemaining: 10}
+			if input.Budget.Exceeded(input.TokensUsed+output.TokensUsed, 0) {
+				output.Status = services.ScanStatusBudgetExceeded
+			}
+			return output, nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", BatchSize: 10, MaxFiles: 40, Budget: budget})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	var output ScanWorkflowOutput
+	if err := env.GetWorkflowResult(&output); err != nil {
+		t.Fatalf("read workflow result: %v", err)
+	}
+
+	// Every batch is told what the earlier ones spent, so the budget covers the whole scan
+	if want := []int{0, 400, 800}; len(tokensSeen) != len(want) || tokensSeen[1] != want[1] || tokensSeen[2] != want[2] {
+		t.Errorf("batches started with %v tokens used, want %v", tokensSeen, want)
+	}
+	if output.Status != services.ScanStatusBudgetExceeded {
+		t.Errorf("output status = %s, want %s", output.Status, services.ScanStatusBudgetExceeded)
+	}
+	if last := statuses[len(statuses)-1]; last != services.ScanStatusBudgetExceeded {
+		t.Errorf("last notified status = %s, want %s", last, services.ScanStatusBudgetExceeded)
+	}
+}
+
+func TestScanWorkflowReportsLostFindings(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+		&CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repo-1"}, nil)
+	var statuses []string
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input ScanStatusNotification) error {
+			statuses = append(statuses, input.Status)
+			return nil
+		})
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+		&ScanActivityOutput{RepositoryID: "repo-1", ScanID: "scan-1", Status: services.ScanStatusCompletedWithErrors, InsertFailures: 2}, nil)
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	var output ScanWorkflowOutput
+	if err := env.GetWorkflowResult(&output); err != nil {
+		t.Fatalf("read workflow result: %v", err)
+	}
+
+	if output.Status != services.ScanStatusCompletedWithErrors {
+		t.Errorf("output status = %s, want %s", output.Status, services.ScanStatusCompletedWithErrors)
+	}
+	if !strings.Contains(output.Message, "2 files") {
+		t.Errorf("output message = %q, want the number of files whose findings were lost", output.Message)
+	}
+	if last := statuses[len(statuses)-1]; last != services.ScanStatusCompletedWithErrors {
+		t.Errorf("last notified status = %s, want %s", last, services.ScanStatusCompletedWithErrors)
+	}
+}
+
+func TestScanWorkflowScansAnUploadWithoutCloning(t *testing.T) {
+	tests := []struct {
+		name           string
+		scanErr        error
+		filesRemaining int // Files left after each batch; enough of them makes the run continue as new
+		wantRemoved    bool
+	}{
+		{name: "scan completes", wantRemoved: true},
+		{name: "scan fails", scanErr: temporal.NewNonRetryableApplicationError("model unavailable", "ScanError", nil), wantRemoved: true},
+		{name: "scan continues as a new run", filesRemaining: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterActivity(CloneRepositoryActivity)
+			env.RegisterActivity(ScanRepositoryActivity)
+			env.RegisterActivity(NotifyScanStatusActivity)
+			env.RegisterActivity(CheckWorkerPausedActivity)
+			env.RegisterActivity(RemoveUploadActivity)
+			env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+
+			cloned := false
+			env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+					cloned = true
+					return &CloneActivityOutput{RepoDir: "/tmp/repo"}, nil
+				})
+			var scannedDirs []string
+			env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+					scannedDirs = append(scannedDirs, input.RepoDir)
+					if tt.scanErr != nil {
+						return nil, tt.scanErr
+					}
+					output := &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID, FilesRemaining: tt.filesRemaining}
+					if tt.filesRemaining > 0 {
+						output.Status = services.ScanStatusScanning
+					}
+					return output, nil
+				})
+			var statuses []string
+			env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanStatusNotification) error {
+					statuses = append(statuses, input.Status)
+					return nil
+				})
+			var removed []string
+			env.OnActivity(RemoveUploadActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, uploadDir string) error {
+					removed = append(removed, uploadDir)
+					return nil
+				})
+
+			env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", UploadDir: "/tmp/uploads/scan-1"})
+			if !env.IsWorkflowCompleted() {
+				t.Fatal("workflow did not finish")
+			}
+			err := env.GetWorkflowError()
+			if tt.scanErr == nil && tt.filesRemaining == 0 && err != nil {
+				t.Fatalf("workflow returned error: %v", err)
+			}
+			if tt.scanErr != nil && err == nil {
+				t.Error("workflow succeeded, want the scan error")
+			}
+
+			if cloned {
+				t.Error("upload scan ran the clone activity")
+			}
+			if len(scannedDirs) == 0 {
+				t.Fatal("upload was not scanned")
+			}
+			for _, dir := range scannedDirs {
+				if dir != "/tmp/uploads/scan-1" {
+					t.Errorf("scan ran on %q, want the extracted upload", dir)
+				}
+			}
+			for _, status := range statuses {
+				if status == services.ScanStatusCloning {
+					t.Errorf("statuses = %v, want no cloning stage for an upload", statuses)
+				}
+			}
+
+			if !tt.wantRemoved {
+				if len(removed) != 0 {
+					t.Errorf("upload removed before the next run scanned it: %v", removed)
+				}
+				return
+			}
+			if len(removed) != 1 || removed[0] != "/tmp/uploads/scan-1" {
+				t.Errorf("removed %v, want the extracted upload removed once", removed)
+			}
+		})
+	}
+}
+
+func TestScanWorkflowRemovesTheCloneWhenTheScanEnds(t *testing.T) {
+	tests := []struct {
+		name           string
+		cloneErr       error
+		scanErr        error
+		filesRemaining int // Files left after each batch; enough of them makes the run continue as new
+		wantRemoved    bool
+	}{
+		{name: "scan completes", wantRemoved: true},
+		{name: "scan fails", scanErr: temporal.NewNonRetryableApplicationError("model unavailable", "ScanError", nil), wantRemoved: true},
+		{name: "scan continues as a new run", filesRemaining: 1000},
+		{name: "clone fails", cloneErr: temporal.NewNonRetryableApplicationError("repository not found", "CloneError", nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterActivity(CloneRepositoryActivity)
+			env.RegisterActivity(ScanRepositoryActivity)
+			env.RegisterActivity(NotifyScanStatusActivity)
+			env.RegisterActivity(CheckWorkerPausedActivity)
+			env.RegisterActivity(RemoveWorkspaceActivity)
+			env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+			env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+
+			env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input CloneActivityInput) (*CloneActivityOutput, error) {
+					if tt.cloneErr != nil {
+						return nil, tt.cloneErr
+					}
+					return &CloneActivityOutput{RepositoryID: input.RepositoryID, RepoDir: "/tmp/repos/" + input.ScanID}, nil
+				})
+			env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+					if tt.scanErr != nil {
+						return nil, tt.scanErr
+					}
+					output := &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID, FilesRemaining: tt.filesRemaining}
+					if tt.filesRemaining > 0 {
+						output.Status = services.ScanStatusScanning
+					}
+					return output, nil
+				})
+			var removed []string
+			env.OnActivity(RemoveWorkspaceActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, repoDir string) error {
+					removed = append(removed, repoDir)
+					return nil
+				})
+
+			env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", Owner: "octo", Name: "repo"})
+			if !env.IsWorkflowCompleted() {
+				t.Fatal("workflow did not finish")
+			}
+
+			if !tt.wantRemoved {
+				if len(removed) != 0 {
+					t.Errorf("removed %v, want the clone kept", removed)
+				}
+				return
+			}
+			if len(removed) != 1 || removed[0] != "/tmp/repos/scan-1" {
+				t.Errorf("removed %v, want the scan's clone removed once", removed)
+			}
+		})
+	}
+}
+
+func TestScanWorkflowStartedBeforeWorkspaceCleanupKeepsTheClone(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CloneRepositoryActivity)
+	env.RegisterActivity(ScanRepositoryActivity)
+	env.RegisterActivity(NotifyScanStatusActivity)
+	env.RegisterActivity(CheckWorkerPausedActivity)
+	env.RegisterActivity(RemoveWorkspaceActivity)
+	env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+	env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repos/scan-1"}, nil)
+	env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).
+		Return(&ScanActivityOutput{RepositoryID: "repo-1", ScanID: "scan-1"}, nil)
+
+	// A history recorded before workspaces were removed has no version marker for the cleanup
+	env.OnGetVersion(scanWorkspaceCleanupChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	removed := 0
+	env.OnActivity(RemoveWorkspaceActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, repoDir string) error {
+			removed++
+			return nil
+		})
+
+	env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1"})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow returned error: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("ran the workspace cleanup %d times, want none for a scan started before it existed", removed)
+	}
+}
+
+func TestScanWorkflowReplaysAHistoryFromBeforeWorkspaceCleanup(t *testing.T) {
+	// The history of a scan that finished before any of the versioned changes existed: clone, one scan call, done
+	payloads := func(values ...interface{}) *commonpb.Payloads {
+		p, err := converter.GetDefaultDataConverter().ToPayloads(values...)
+		if err != nil {
+			t.Fatalf("encoding %v: %v", values, err)
+		}
+		return p
+	}
+	workflowTask := func(id int64) []*historypb.HistoryEvent {
+		return []*historypb.HistoryEvent{
+			{EventId: id, EventType: enums.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED, Attributes: &historypb.HistoryEvent_WorkflowTaskScheduledEventAttributes{
+				WorkflowTaskScheduledEventAttributes: &historypb.WorkflowTaskScheduledEventAttributes{TaskQueue: &taskqueuepb.TaskQueue{Name: "scan"}},
+			}},
+			{EventId: id + 1, EventType: enums.EVENT_TYPE_WORKFLOW_TASK_STARTED, Attributes: &historypb.HistoryEvent_WorkflowTaskStartedEventAttributes{
+				WorkflowTaskStartedEventAttributes: &historypb.WorkflowTaskStartedEventAttributes{ScheduledEventId: id},
+			}},
+			{EventId: id + 2, EventType: enums.EVENT_TYPE_WORKFLOW_TASK_COMPLETED, Attributes: &historypb.HistoryEvent_WorkflowTaskCompletedEventAttributes{
+				WorkflowTaskCompletedEventAttributes: &historypb.WorkflowTaskCompletedEventAttributes{ScheduledEventId: id, StartedEventId: id + 1},
+			}},
+		}
+	}
+	// Activity IDs are the event IDs their ActivityTaskScheduled events were recorded at
+	activityRun := func(id int64, activityType string, result interface{}) []*historypb.HistoryEvent {
+		return []*historypb.HistoryEvent{
+			{EventId: id, EventType: enums.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED, Attributes: &historypb.HistoryEvent_ActivityTaskScheduledEventAttributes{
+				ActivityTaskScheduledEventAttributes: &historypb.ActivityTaskScheduledEventAttributes{
+					ActivityId:   strconv.FormatInt(id, 10),
+					ActivityType: &commonpb.ActivityType{Name: activityType},
+					TaskQueue:    &taskqueuepb.TaskQueue{Name: "scan"},
+				},
+			}},
+			{EventId: id + 1, EventType: enums.EVENT_TYPE_ACTIVITY_TASK_STARTED, Attributes: &historypb.HistoryEvent_ActivityTaskStartedEventAttributes{
+				ActivityTaskStartedEventAttributes: &historypb.ActivityTaskStartedEventAttributes{ScheduledEventId: id},
+			}},
+			{EventId: id + 2, EventType: enums.EVENT_TYPE_ACTIVITY_TASK_COMPLETED, Attributes: &historypb.HistoryEvent_ActivityTaskCompletedEventAttributes{
+				ActivityTaskCompletedEventAttributes: &historypb.ActivityTaskCompletedEventAttributes{
+					ScheduledEventId: id,
+					StartedEventId:   id + 1,
+					Result:           payloads(result),
+				},
+			}},
+		}
+	}
+
+	events := []*historypb.HistoryEvent{
+		{EventId: 1, EventType: enums.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED, Attributes: &historypb.HistoryEvent_WorkflowExecutionStartedEventAttributes{
+			WorkflowExecutionStartedEventAttributes: &historypb.WorkflowExecutionStartedEventAttributes{
+				WorkflowType: &commonpb.WorkflowType{Name: "ScanWorkflow"},
+				TaskQueue:    &taskqueuepb.TaskQueue{Name: "scan"},
+				Input:        payloads(ScanWorkflowInput{RepositoryID: "repo-1", ScanID: "scan-1", Owner: "octo", Name: "repo"}),
+			},
+		}},
+	}
+	events = append(events, workflowTask(2)...)
+	events = append(events, activityRun(5, "CloneRepositoryActivity", CloneActivityOutput{RepositoryID: "repo-1", RepoDir: "/tmp/repos/scan-1"})...)
+	events = append(events, workflowTask(8)...)
+	events = append(events, activityRun(11, "ScanRepositoryActivity", ScanActivityOutput{RepositoryID: "repo-1", ScanID: "scan-1"})...)
+	events = append(events, workflowTask(14)...)
+	events = append(events, &historypb.HistoryEvent{
+		EventId:   17,
+		EventType: enums.EVENT_TYPE_WORKFLOW_EXECUTION_COMPLETED,
+		Attributes: &historypb.HistoryEvent_WorkflowExecutionCompletedEventAttributes{
+			WorkflowExecutionCompletedEventAttributes: &historypb.WorkflowExecutionCompletedEventAttributes{WorkflowTaskCompletedEventId: 16},
+		},
+	})
+
+	replayer := worker.NewWorkflowReplayer()
+	replayer.RegisterWorkflow(ScanWorkflow)
+	if err := replayer.ReplayWorkflowHistory(nil, &historypb.History{Events: events}); err != nil {
+		t.Errorf("replaying a scan recorded before the workspace cleanup: %v", err)
+	}
+}
+
+func TestScanWorkflowPostsTheCallbackWhenTheScanEnds(t *testing.T) {
+	tests := []struct {
+		name           string
+		scanErr        error
+		filesRemaining int // Files left after each batch; enough of them makes the run continue as new
+		wantStatus     string
+	}{
+		{name: "scan completes", wantStatus: services.ScanStatusCompleted},
+		{name: "scan fails", scanErr: temporal.NewNonRetryableApplicationError("model unavailable", "ScanError", nil), wantStatus: services.ScanStatusFailed},
+		{name: "scan continues as a new run", filesRemaining: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterActivity(CloneRepositoryActivity)
+			env.RegisterActivity(ScanRepositoryActivity)
+			env.RegisterActivity(NotifyScanStatusActivity)
+			env.RegisterActivity(CheckWorkerPausedActivity)
+			env.RegisterActivity(RemoveWorkspaceActivity)
+			env.RegisterActivity(SendScanCallbackActivity)
+			env.OnActivity(CheckWorkerPausedActivity, mock.Anything).Return(false, nil)
+			env.OnActivity(NotifyScanStatusActivity, mock.Anything, mock.Anything).Return(nil)
+			env.OnActivity(RemoveWorkspaceActivity, mock.Anything, mock.Anything).Return(nil)
+			env.OnActivity(CloneRepositoryActivity, mock.Anything, mock.Anything).Return(&CloneActivityOutput{RepoDir: "/tmp/repos/scan-1"}, nil)
+			env.OnActivity(ScanRepositoryActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanActivityInput) (*ScanActivityOutput, error) {
+					if tt.scanErr != nil {
+						return nil, tt.scanErr
+					}
+					output := &ScanActivityOutput{RepositoryID: input.RepositoryID, ScanID: input.ScanID, FilesRemaining: tt.filesRemaining}
+					if tt.filesRemaining > 0 {
+						output.Status = services.ScanStatusScanning
+					}
+					return output, nil
+				})
+
+			// The first attempt fails, so the delivery is only recorded once it is retried
+			var callbacks []ScanCallbackInput
+			attempts := 0
+			env.OnActivity(SendScanCallbackActivity, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, input ScanCallbackInput) error {
+					attempts++
+					if attempts == 1 {
+						return errors.New("receiver unavailable")
+					}
+					callbacks = append(callbacks, input)
+					return nil
+				})
+
+			env.ExecuteWorkflow(ScanWorkflow, ScanWorkflowInput{
+				RepositoryID: "repo-1",
+				ScanID:       "scan-1",
+				CallbackURL:  "https://hooks.example.com/sast",
+			})
+			if !env.IsWorkflowCompleted() {
+				t.Fatal("workflow did not finish")
+			}
+
+			if tt.wantStatus == "" {
+				if attempts != 0 {
+					t.Errorf("callback posted %d times before the scan ended, want none", attempts)
+				}
+				return
+			}
+			if len(callbacks) != 1 {
+				t.Fatalf("callback delivered %d times, want once after a retry", len(callbacks))
+			}
+			got := callbacks[0]
+			if got.CallbackURL != "https://hooks.example.com/sast" || got.ScanID != "scan-1" || got.Status != tt.wantStatus {
+				t.Errorf("callback = %+v, want status %s for scan-1", got, tt.wantStatus)
+			}
+		})
+	}
+}